@@ -8,17 +8,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jqnote/goviking/pkg/agfs"
 	"github.com/jqnote/goviking/pkg/client"
 	"github.com/jqnote/goviking/pkg/config"
+	"github.com/jqnote/goviking/pkg/core"
+	"github.com/jqnote/goviking/pkg/llm"
 	"github.com/jqnote/goviking/pkg/server"
+	"github.com/jqnote/goviking/pkg/service"
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 var (
@@ -39,13 +51,23 @@ semantic search, and automatic memory extraction.`,
 		Version: Version,
 	}
 
+	rootCmd.PersistentFlags().StringVar(&userFlag, "user", "", "User ID sent with requests (default from cli.user)")
+	rootCmd.PersistentFlags().StringVar(&sessionFlag, "session", "", "Session ID sent with requests (default from cli.session, then the last used session)")
+
 	// Add subcommands
 	rootCmd.AddCommand(contextCmd())
 	rootCmd.AddCommand(sessionCmd())
 	rootCmd.AddCommand(fsCmd())
 	rootCmd.AddCommand(searchCmd())
+	rootCmd.AddCommand(memoryCmd())
+	rootCmd.AddCommand(windowCmd())
 	rootCmd.AddCommand(configCmd())
 	rootCmd.AddCommand(serverCmd())
+	rootCmd.AddCommand(exportCmd())
+	rootCmd.AddCommand(importCmd())
+	rootCmd.AddCommand(fsckCmd())
+	rootCmd.AddCommand(pruneCmd())
+	rootCmd.AddCommand(healthCmd())
 	rootCmd.AddCommand(versionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
@@ -54,6 +76,12 @@ semantic search, and automatic memory extraction.`,
 	}
 }
 
+// Global --user and --session flags.
+var (
+	userFlag    string
+	sessionFlag string
+)
+
 func getClient() (*client.Client, error) {
 	// Get server address from config
 	cfg, err := config.LoadDefault()
@@ -61,8 +89,103 @@ func getClient() (*client.Client, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	user, sessionID := resolveIdentity(cfg)
+	if sessionFlag != "" {
+		rememberSession(sessionFlag)
+	}
+
 	serverAddr := fmt.Sprintf("http://%s:%d", cfg.Server.Host, cfg.Server.Port)
-	return client.NewClient(serverAddr)
+	return newCLIClient(serverAddr, user, sessionID)
+}
+
+// newCLIClient creates a client that sends user and sessionID, when set,
+// with every request.
+func newCLIClient(serverAddr, user, sessionID string) (*client.Client, error) {
+	var opts []client.Option
+	if user != "" {
+		opts = append(opts, client.WithUser(user))
+	}
+	if sessionID != "" {
+		opts = append(opts, client.WithSession(sessionID))
+	}
+	return client.NewClient(serverAddr, opts...)
+}
+
+// resolveIdentity returns the user and session for this invocation. Flags
+// take precedence over config, and the session falls back to the last one
+// used.
+func resolveIdentity(cfg *config.Config) (user, sessionID string) {
+	user = userFlag
+	if user == "" {
+		user = cfg.CLI.User
+	}
+
+	sessionID = sessionFlag
+	if sessionID == "" {
+		sessionID = cfg.CLI.Session
+	}
+	if sessionID == "" {
+		if state, err := loadState(); err == nil {
+			sessionID = state.LastSession
+		}
+	}
+	return user, sessionID
+}
+
+// cliState is local CLI state kept between invocations.
+type cliState struct {
+	LastSession string `json:"last_session,omitempty"`
+}
+
+// statePath returns the location of the CLI state file.
+func statePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".goviking", "state.json")
+}
+
+// loadState reads the CLI state file. A missing file yields empty state.
+func loadState() (*cliState, error) {
+	state := &cliState{}
+	data, err := os.ReadFile(statePath())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+	return state, nil
+}
+
+// saveState writes the CLI state file.
+func saveState(state *cliState) error {
+	path := statePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// rememberSession records id as the last used session. Failures only
+// lose the convenience default, so they are reported but not fatal.
+func rememberSession(id string) {
+	state, err := loadState()
+	if err != nil {
+		state = &cliState{}
+	}
+	if state.LastSession == id {
+		return
+	}
+	state.LastSession = id
+	if err := saveState(state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save session state: %v\n", err)
+	}
 }
 
 func versionCmd() *cobra.Command {
@@ -92,7 +215,7 @@ func contextCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			contexts, err := c.ListContexts(ctx)
+			contexts, err := c.Contexts.List(ctx)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -112,10 +235,15 @@ func contextCmd() *cobra.Command {
 		},
 	})
 
-	cmd.AddCommand(&cobra.Command{
+	var preview int
+	var full bool
+
+	showCmd := &cobra.Command{
 		Use:   "show [id]",
 		Short: "Show a context by ID",
-		Args:  cobra.ExactArgs(1),
+		Long: `Show a context by ID as JSON. The content and abstract are cut to
+--preview characters; --full shows them whole.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			c, err := getClient()
 			if err != nil {
@@ -124,20 +252,21 @@ func contextCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			context, err := c.GetContext(ctx, args[0])
+			context, err := c.Contexts.Get(ctx, args[0])
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			data, err := json.MarshalIndent(context, "", "  ")
-			if err != nil {
+			if err := printContext(os.Stdout, context, previewLength(preview, full)); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Println(string(data))
 		},
-	})
+	}
+
+	addPreviewFlags(showCmd, &preview, &full)
+	cmd.AddCommand(showCmd)
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "create [path]",
@@ -194,7 +323,7 @@ func contextCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
-			result, err := c.CreateContext(context.Background(), ctx)
+			result, err := c.Contexts.Create(context.Background(), ctx)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -224,7 +353,7 @@ func sessionCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			sessions, err := c.ListSessions(ctx)
+			sessions, err := c.Sessions.List(ctx)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -256,7 +385,7 @@ func sessionCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			session, err := c.GetSession(ctx, args[0], true)
+			session, err := c.Sessions.Get(ctx, args[0], true)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -285,7 +414,7 @@ func sessionCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			session, err := c.GetSession(ctx, args[0], true)
+			session, err := c.Sessions.Get(ctx, args[0], true)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -293,6 +422,7 @@ func sessionCmd() *cobra.Command {
 
 			// Update state to active
 			session.State = "active"
+			rememberSession(args[0])
 
 			data, err := json.MarshalIndent(session, "", "  ")
 			if err != nil {
@@ -323,7 +453,7 @@ func fsCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			contexts, err := c.ListContexts(ctx)
+			contexts, err := c.Contexts.List(ctx)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -369,7 +499,7 @@ func fsCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			contexts, err := c.ListContexts(ctx)
+			contexts, err := c.Contexts.List(ctx)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
@@ -401,13 +531,54 @@ func fsCmd() *cobra.Command {
 }
 
 func searchCmd() *cobra.Command {
-	return &cobra.Command{
+	var trace bool
+	var traceOut string
+	var local bool
+	var dbPath string
+	var limit int
+	var preview int
+	var full bool
+
+	cmd := &cobra.Command{
 		Use:   "search [query]",
 		Short: "Search contexts",
-		Args:  cobra.ExactArgs(1),
+		Long: `Search contexts. With --trace the query runs through the server's
+retriever and the command prints the directories it visited as a tree, with
+their scores and where the search converged or pruned a branch. --trace-out
+also saves the trajectory as JSON and implies --trace.
+
+With --local or --db the command searches the local database directly,
+using full-text search over context names, abstracts and descriptions.
+"Double quoted" words match as a phrase.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			query := args[0]
 
+			if local || dbPath != "" {
+				cfg, err := config.LoadDefault()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+					os.Exit(1)
+				}
+				store, err := openStorage(cfg, dbPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+					os.Exit(1)
+				}
+				defer store.Close()
+				searcher, ok := store.(storage.ContextSearcher)
+				if !ok {
+					fmt.Fprintf(os.Stderr, "Error: local search is not supported by the %s backend\n", cfg.Storage.Type)
+					os.Exit(1)
+				}
+
+				if err := runLocalSearch(context.Background(), os.Stdout, searcher, query, limit, previewLength(preview, full)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+
 			c, err := getClient()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -415,23 +586,20 @@ func searchCmd() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			contexts, err := c.ListContexts(ctx)
+			if trace || traceOut != "" {
+				if err := runSearchTrace(ctx, os.Stdout, c, query, traceOut); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			results, err := c.Search.Contexts(ctx, query)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Simple text search (in a real implementation, this would use semantic search)
-			var results []client.Context
-			lowerQuery := toLower(query)
-			for _, c := range contexts {
-				if contains(toLower(c.Name), lowerQuery) ||
-					contains(toLower(c.Content), lowerQuery) ||
-					contains(toLower(c.URI), lowerQuery) {
-					results = append(results, c)
-				}
-			}
-
 			if len(results) == 0 {
 				fmt.Printf("No results found for: %s\n", query)
 				return
@@ -439,43 +607,568 @@ func searchCmd() *cobra.Command {
 
 			fmt.Printf("Search results for: %s\n\n", query)
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintf(w, "NAME\tTYPE\tID\n")
+			fmt.Fprintf(w, "NAME\tTYPE\tID\tABSTRACT\n")
+			n := previewLength(preview, full)
 			for _, r := range results {
-				fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Type, r.ID)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, r.Type, r.ID, previewLine(r.Abstract, n))
 			}
 			w.Flush()
 		},
 	}
+
+	cmd.Flags().BoolVar(&trace, "trace", false, "Print the retrieval trajectory as a tree")
+	cmd.Flags().StringVar(&traceOut, "trace-out", "", "Save the retrieval trajectory as JSON to this file")
+	cmd.Flags().BoolVar(&local, "local", false, "Search the local database instead of the server")
+	cmd.Flags().StringVar(&dbPath, "db", "", "Local database to search (default from config, implies --local)")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of local results, 0 for all")
+	addPreviewFlags(cmd, &preview, &full)
+
+	return cmd
+}
+
+// runLocalSearch runs a full-text search against a local store and prints
+// the matches, best first, with their abstracts cut to preview characters.
+func runLocalSearch(ctx context.Context, out io.Writer, searcher storage.ContextSearcher, query string, limit, preview int) error {
+	results, err := searcher.SearchContextsFTS(ctx, query, limit)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(out, "No results found for: %s\n", query)
+		return nil
+	}
+
+	fmt.Fprintf(out, "Search results for: %s\n\n", query)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tTYPE\tID\tABSTRACT\n")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, r.Type, r.ID, previewLine(r.Abstract, preview))
+	}
+	return w.Flush()
+}
+
+// defaultPreviewLength is the default of the --preview flag.
+const defaultPreviewLength = 200
+
+// addPreviewFlags adds the --preview and --full flags to cmd.
+func addPreviewFlags(cmd *cobra.Command, preview *int, full *bool) {
+	cmd.Flags().IntVar(preview, "preview", defaultPreviewLength, "Cut content and abstracts to this many characters")
+	cmd.Flags().BoolVar(full, "full", false, "Show content and abstracts in full, ignoring --preview")
+}
+
+// previewLength returns the preview length the flags select, 0 for none.
+func previewLength(preview int, full bool) int {
+	if full || preview < 0 {
+		return 0
+	}
+	return preview
+}
+
+// previewText cuts text to n characters, marking the cut with an
+// ellipsis. A non-positive n leaves text whole.
+func previewText(text string, n int) string {
+	runes := []rune(text)
+	if n <= 0 || len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
+
+// previewLine is previewText for table cells, with line breaks flattened.
+func previewLine(text string, n int) string {
+	return previewText(strings.Join(strings.Fields(text), " "), n)
+}
+
+// printContext prints c as JSON with its content and abstract cut to
+// preview characters.
+func printContext(out io.Writer, c *client.Context, preview int) error {
+	shown := *c
+	shown.Content = previewText(c.Content, preview)
+	shown.Abstract = previewText(c.Abstract, preview)
+
+	data, err := json.MarshalIndent(shown, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// runSearchTrace explains query on the server, saves the explanation to
+// traceOut when set, and prints each traversal as a tree.
+func runSearchTrace(ctx context.Context, out io.Writer, c *client.Client, query, traceOut string) error {
+	explanation, err := c.Search.Explain(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if traceOut != "" {
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(traceOut, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write trace: %w", err)
+		}
+	}
+
+	fmt.Fprintf(out, "Trajectory for: %s\n", query)
+	for _, t := range explanation.Traversals {
+		fmt.Fprintf(out, "\n%s:\n", t.ContextType)
+		renderTraversal(out, t)
+	}
+	return nil
+}
+
+// traversalMarks labels the events shown next to directories in a trace.
+var traversalMarks = map[string]string{
+	"converged":               "converged",
+	"below_threshold":         "pruned: below threshold",
+	"max_depth":               "pruned: max depth",
+	"max_directories_visited": "stopped: max directories visited",
+}
+
+// traversalMark returns the label shown for a traversal event type.
+func traversalMark(eventType string) string {
+	if label, ok := traversalMarks[eventType]; ok {
+		return label
+	}
+	return eventType
+}
+
+// renderTraversal prints the visited directories of t as a tree indented
+// by parent, each with its score. Convergence and traversal limits are
+// marked on the directory where they happened; branches pruned without
+// being visited are listed under their parent.
+func renderTraversal(out io.Writer, t client.Traversal) {
+	if len(t.Steps) == 0 {
+		fmt.Fprintln(out, "  (no directories visited)")
+		return
+	}
+
+	visited := make(map[string]bool)
+	for _, step := range t.Steps {
+		visited[step.URI] = true
+	}
+
+	var roots []string
+	scores := make(map[string]float64)
+	children := make(map[string][]string)
+	for _, step := range t.Steps {
+		scores[step.URI] = step.Score
+		if step.Parent != "" && visited[step.Parent] {
+			children[step.Parent] = append(children[step.Parent], step.URI)
+		} else {
+			roots = append(roots, step.URI)
+		}
+	}
+
+	marks := make(map[string][]string)
+	pruned := make(map[string][]client.TraversalEvent)
+	for _, e := range t.Events {
+		if !visited[e.URI] && visited[e.Parent] {
+			pruned[e.Parent] = append(pruned[e.Parent], e)
+			continue
+		}
+		marks[e.URI] = append(marks[e.URI], traversalMark(e.Type))
+	}
+
+	var render func(uri string, level int)
+	render = func(uri string, level int) {
+		indent := strings.Repeat("  ", level+1)
+		fmt.Fprintf(out, "%s%s (%.3f)", indent, uri, scores[uri])
+		for _, mark := range marks[uri] {
+			fmt.Fprintf(out, " [%s]", mark)
+		}
+		fmt.Fprintln(out)
+		for _, child := range children[uri] {
+			render(child, level+1)
+		}
+		for _, e := range pruned[uri] {
+			fmt.Fprintf(out, "%s  %s (%.3f) [%s]\n", indent, e.URI, e.Score, traversalMark(e.Type))
+		}
+	}
+	for _, root := range roots {
+		render(root, 0)
+	}
+}
+
+func memoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Work with memory extraction",
+	}
+
+	var category string
+	var dedup bool
+
+	extractCmd := &cobra.Command{
+		Use:   "extract [transcript.json]",
+		Short: "Extract memories from a transcript file (or - for stdin)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var r io.Reader = os.Stdin
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error opening transcript: %v\n", err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				r = f
+			}
+
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+
+			provider, err := newLLMProvider(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer provider.Close()
+
+			_, sessionID := resolveIdentity(cfg)
+			if sessionFlag != "" {
+				rememberSession(sessionFlag)
+			}
+
+			if err := runMemoryExtract(context.Background(), provider, r, os.Stdout, sessionID, category, dedup); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	extractCmd.Flags().StringVar(&category, "category", "", "Only extract memories of this category (e.g. profile, preference)")
+	extractCmd.Flags().BoolVar(&dedup, "dedup", false, "Remove duplicate memories")
+	cmd.AddCommand(extractCmd)
+
+	var dbPath, since, until string
+
+	backfillCmd := &cobra.Command{
+		Use:   "backfill [session-id...]",
+		Short: "Re-run extraction over stored sessions and add new memories",
+		Long: `Replay the stored messages of the given sessions, or of every session
+when none are given, through memory extraction. Memories that duplicate
+an existing one are skipped, so backfill can be re-run safely. Use
+--since and --until (RFC 3339 or YYYY-MM-DD) to replay only messages
+from that range.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts service.BackfillOptions
+			var err error
+			if opts.Since, err = parseTimeFlag(since); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
+				os.Exit(1)
+			}
+			if opts.Until, err = parseTimeFlag(until); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --until: %v\n", err)
+				os.Exit(1)
+			}
+
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+			provider, err := newLLMProvider(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer provider.Close()
+
+			store, err := openStorage(cfg, dbPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+
+			if err := runMemoryBackfill(context.Background(), os.Stdout, store, provider, args, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	backfillCmd.Flags().StringVar(&dbPath, "db", "", "Storage database (default from config)")
+	backfillCmd.Flags().StringVar(&since, "since", "", "Only replay messages created at or after this time")
+	backfillCmd.Flags().StringVar(&until, "until", "", "Only replay messages created before this time")
+	cmd.AddCommand(backfillCmd)
+
+	return cmd
+}
+
+// runMemoryBackfill backfills memories for sessionIDs, or every session,
+// with an extractor built on provider and prints the counts per session.
+func runMemoryBackfill(ctx context.Context, out io.Writer, store storage.StorageInterface, provider llm.Provider, sessionIDs []string, opts service.BackfillOptions) error {
+	extractor := session.NewLLMExtractor(provider, session.DefaultExtractorConfig(""))
+	report, err := service.BackfillMemories(ctx, sessionIDs, auditedStore(store), extractor, session.NewDeduper(0), opts)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range report.Sessions {
+		fmt.Fprintf(out, "%s: %d messages, %d extracted, %d duplicates, %d added\n",
+			s.SessionID, s.Messages, s.Extracted, s.Duplicates, s.Added)
+	}
+	fmt.Fprintf(out, "\nAdded %d memories from %d sessions\n", report.Added, len(report.Sessions))
+	return nil
+}
+
+// parseTimeFlag parses an RFC 3339 timestamp or a YYYY-MM-DD date. An empty
+// value is the zero time.
+func parseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// runMemoryExtract reads a transcript from r, extracts memories with
+// provider and writes them to w as a JSON array. Memories are attributed
+// to sessionID.
+func runMemoryExtract(ctx context.Context, provider llm.Provider, r io.Reader, w io.Writer, sessionID, category string, dedup bool) error {
+	messages, err := readTranscript(r)
+	if err != nil {
+		return err
+	}
+
+	extractor := session.NewLLMExtractor(provider, session.DefaultExtractorConfig(sessionID))
+
+	var memories []*session.ExtractedMemory
+	if category != "" {
+		memories, err = extractor.ExtractByCategory(ctx, messages, session.Category(category))
+	} else {
+		memories, err = extractor.Extract(ctx, messages)
+	}
+	if err != nil {
+		return err
+	}
+
+	if dedup {
+		memories = session.NewDeduper(0).Dedup(memories)
+	}
+	if memories == nil {
+		memories = []*session.ExtractedMemory{}
+	}
+
+	data, err := json.MarshalIndent(memories, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// readTranscript decodes a transcript, given either as a JSON array of
+// messages or as an object with a "messages" field.
+func readTranscript(r io.Reader) ([]*session.Message, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	var messages []*session.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		var wrapped struct {
+			Messages []*session.Message `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid transcript: %w", err)
+		}
+		messages = wrapped.Messages
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("transcript has no messages")
+	}
+	return messages, nil
+}
+
+func windowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "window",
+		Short: "Inspect and tune a session's context window",
+	}
+
+	var dataDir string
+	var maxTokens int
+	cmd.PersistentFlags().StringVar(&dataDir, "data-dir", core.DefaultPersistenceConfig().StoragePath, "Directory holding persisted session contexts (default from config)")
+	cmd.PersistentFlags().IntVar(&maxTokens, "max-tokens", 0, "Token budget (default from window config)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "inspect [session]",
+		Short: "Show how a session's contexts fill the window",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			backend, closeBackend, err := openPersistenceBackend(dataDir, cmd.Flags().Changed("data-dir"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer closeBackend()
+
+			if err := runWindowInspect(os.Stdout, backend, args[0], maxTokens); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "fit [session]",
+		Short: "Show which contexts would be kept under a token budget",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			backend, closeBackend, err := openPersistenceBackend(dataDir, cmd.Flags().Changed("data-dir"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer closeBackend()
+
+			if err := runWindowFit(os.Stdout, backend, args[0], maxTokens); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	})
+
+	return cmd
 }
 
-func toLower(s string) string {
-	result := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c += 'a' - 'A'
+// openPersistenceBackend returns the backend holding persisted session
+// contexts, chosen by the persistence section of the config. An explicit
+// data directory always selects files in it.
+func openPersistenceBackend(dataDir string, dataDirSet bool) (core.PersistenceBackend, func(), error) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if dataDirSet {
+		return core.NewFileBackend(dataDir), func() {}, nil
+	}
+
+	switch cfg.Persistence.Backend {
+	case "", "file":
+		if cfg.Persistence.Path != "" {
+			dataDir = cfg.Persistence.Path
 		}
-		result[i] = c
+		return core.NewFileBackend(dataDir), func() {}, nil
+	case "storage":
+		store, err := openStorage(cfg, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open storage: %w", err)
+		}
+		snapshots, ok := store.(storage.SnapshotStore)
+		if !ok {
+			store.Close()
+			return nil, nil, fmt.Errorf("storage backend %s does not store snapshots", cfg.Storage.Type)
+		}
+		return storage.NewSnapshotBackend(snapshots), func() { store.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported persistence backend: %s", cfg.Persistence.Backend)
+	}
+}
+
+// loadSessionWindow restores a session's persisted contexts into a window
+// with the given token budget.
+func loadSessionWindow(backend core.PersistenceBackend, sessionID string, maxTokens int) (*core.ContextWindow, *core.TieredContext, error) {
+	tc := core.NewTieredContext()
+	handler := core.NewPersistenceHandler(&core.PersistenceConfig{Backend: backend}, tc, sessionID)
+	if !handler.Exists() {
+		return nil, nil, fmt.Errorf("no persisted contexts for session %s", sessionID)
+	}
+	if err := handler.Load(); err != nil {
+		return nil, nil, err
+	}
+
+	windowConfig := core.DefaultContextWindowConfig()
+	if maxTokens > 0 {
+		windowConfig.MaxTokens = maxTokens
 	}
-	return string(result)
+	return core.NewContextWindow(windowConfig, tc, nil), tc, nil
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (len(substr) == 0 || findSubstring(s, substr))
+// tierName returns the display name of a context tier.
+func tierName(tier core.ContextTier) string {
+	return fmt.Sprintf("L%d", int(tier))
+}
+
+// runWindowInspect prints the tier breakdown of a session's window.
+func runWindowInspect(out io.Writer, backend core.PersistenceBackend, sessionID string, maxTokens int) error {
+	window, _, err := loadSessionWindow(backend, sessionID, maxTokens)
+	if err != nil {
+		return err
+	}
+
+	info := window.GetWindowInfo()
+	fmt.Fprintf(out, "Max tokens:  %d\n", info.MaxTokens)
+	fmt.Fprintf(out, "Current:     %d (%.1f%%)\n", info.CurrentTotal, info.UsagePercent)
+	fmt.Fprintf(out, "Approaching: %v\n\n", info.ApproachingLimit)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIER\tCONTEXTS\tTOKENS\n")
+	for _, tier := range []core.ContextTier{core.TierL0, core.TierL1, core.TierL2} {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", tierName(tier), info.TierCounts[tier], info.TierTokens[tier])
+	}
+	return w.Flush()
 }
 
-func findSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// runWindowFit prints which of a session's contexts FitInWindow keeps and
+// drops under the token budget.
+func runWindowFit(out io.Writer, backend core.PersistenceBackend, sessionID string, maxTokens int) error {
+	window, tc, err := loadSessionWindow(backend, sessionID, maxTokens)
+	if err != nil {
+		return err
+	}
+
+	kept, err := window.FitInWindow()
+	if err != nil {
+		return err
+	}
+	keptURIs := make(map[string]bool, len(kept))
+	for _, ctx := range kept {
+		keptURIs[ctx.URI] = true
+	}
+
+	counter := core.NewSimpleTokenCounter()
+	keptTokens := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "STATUS\tTIER\tTOKENS\tURI\n")
+	for _, ctx := range tc.GetAll() {
+		tokens := counter.CountTokens(ctx.Abstract)
+		status := "drop"
+		if keptURIs[ctx.URI] {
+			status = "keep"
+			keptTokens += tokens
 		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", status, tierName(ctx.Tier), tokens, ctx.URI)
 	}
-	return false
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nKept %d of %d contexts (%d tokens)\n", len(kept), tc.Count(), keptTokens)
+	return nil
 }
 
 func serverCmd() *cobra.Command {
 	var host string
 	var port int
+	var root string
+	var planQueries bool
+	var expandQueries bool
+	var shutdownTimeout time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "server",
@@ -499,38 +1192,511 @@ func serverCmd() *cobra.Command {
 			addr := fmt.Sprintf("%s:%d", host, port)
 			fmt.Printf("Starting GoViking server at %s...\n", addr)
 
-			s := server.New()
-			s.SetAddr(addr)
+			store, err := openStorage(cfg, "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+
+			// Move content written before content_dir was set out of the database
+			externalizer, ok := store.(contentExternalizer)
+			if ok && cfg.Storage.ContentDir != "" {
+				moved, err := externalizer.ExternalizeContent(context.Background())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error externalizing content: %v\n", err)
+					os.Exit(1)
+				}
+				if moved > 0 {
+					fmt.Printf("Moved content of %d contexts to %s\n", moved, cfg.Storage.ContentDir)
+				}
+			}
 
-			// Handle graceful shutdown
-			go func() {
-				if err := s.Start(addr); err != nil && err != http.ErrServerClosed {
-					fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+			search := service.NewSearchServiceFromConfig(cfg.Retrieval, nil, nil)
+			if err := search.FusionWeights().Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid retrieval config: %v\n", err)
+				os.Exit(1)
+			}
+			search.SetHotnessSource(service.NewStoreHotness(store))
+			search.SetContentSource(service.NewStoreContent(store))
+			if planQueries || expandQueries {
+				provider, err := newLLMProvider(cfg)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 					os.Exit(1)
 				}
-			}()
+				defer provider.Close()
+				if planQueries {
+					search.SetQueryPlanner(service.NewLLMQueryPlanner(provider))
+				}
+				if expandQueries {
+					search.SetQueryExpander(service.NewLLMQueryExpander(provider))
+				}
+			}
+
+			fs, err := agfs.NewClient(agfs.Config{RootPath: root})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening AGFS: %v\n", err)
+				os.Exit(1)
+			}
+
+			s := server.New(store, fs)
+			s.SetAddr(addr)
+			s.SetSearchService(search)
+
+			if shutdownTimeout == 0 {
+				shutdownTimeout = cfg.Server.ShutdownTimeout
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+				os.Exit(1)
+			}
 
-			// Wait for interrupt signal
+			// Wait for interrupt signal, then shut down gracefully
 			quit := make(chan os.Signal, 1)
 			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-			<-quit
 
-			fmt.Println("\nShutting down server...")
-			ctx, cancel := context.WithTimeout(context.Background(), 5)
-			defer cancel()
-			if err := s.Shutdown(ctx); err != nil {
-				fmt.Fprintf(os.Stderr, "Server forced to shutdown: %v\n", err)
+			if err := serveUntilSignal(s, ln, quit, shutdownTimeout, os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+				os.Exit(1)
 			}
-			fmt.Println("Server exited")
 		},
 	}
 
 	cmd.Flags().StringVar(&host, "host", "", "Server host (default from config)")
 	cmd.Flags().IntVar(&port, "port", 0, "Server port (default from config)")
+	cmd.Flags().StringVar(&root, "root", agfs.DefaultConfig().RootPath, "AGFS root directory served by the FS routes")
+	cmd.Flags().BoolVar(&planQueries, "plan-queries", false, "Plan /api/v1/find queries with the configured LLM")
+	cmd.Flags().BoolVar(&expandQueries, "expand-queries", false, "Expand search queries with the configured LLM instead of the configured synonyms")
+	cmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 0, "How long to let in-flight requests finish on shutdown (default from config, 5s)")
+
+	return cmd
+}
+
+// serveUntilSignal serves s on ln until a signal arrives on quit, then shuts
+// it down, giving in-flight requests up to timeout to finish. Connections
+// still open after the timeout are closed and an error is returned.
+func serveUntilSignal(s *server.Server, ln net.Listener, quit <-chan os.Signal, timeout time.Duration, out io.Writer) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case <-quit:
+	}
+
+	fmt.Fprintln(out, "\nShutting down server...")
+	open := s.ActiveConnections()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		remaining := s.ActiveConnections()
+		s.Close()
+		return fmt.Errorf("shutdown timed out after %s, closed %d of %d connections: %w", timeout, remaining, open, err)
+	}
+	fmt.Fprintf(out, "Drained %d connections\n", open)
+	fmt.Fprintln(out, "Server exited")
+	return nil
+}
+
+func exportCmd() *cobra.Command {
+	var all bool
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export contexts as NDJSON",
+		Run: func(cmd *cobra.Command, args []string) {
+			c, err := getClient()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			w := os.Stdout
+			if output != "" && output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error creating file: %v\n", err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := c.Export(context.Background(), w, all); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Include memories and relations")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file (default stdout)")
+
+	return cmd
+}
+
+func importCmd() *cobra.Command {
+	var onConflict string
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import contexts from an NDJSON export",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+
+			c, err := getClient()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			result, err := c.Import(context.Background(), f, onConflict)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Imported %d contexts, %d memories, %d relations\n",
+				result.Imported.Contexts, result.Imported.Memories, result.Imported.Relations)
+			if len(result.Conflicts) > 0 {
+				fmt.Printf("Skipped %d conflicting records\n", len(result.Conflicts))
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&onConflict, "on-conflict", "skip", "How to handle existing records (skip, overwrite)")
+
+	return cmd
+}
+
+func fsckCmd() *cobra.Command {
+	var root, dbPath string
+	var opts service.ReconcileOptions
+
+	cmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "Check that AGFS files and storage rows agree",
+		Long: `Check that AGFS files and the storage files and contexts tables agree,
+reporting orphan files, dangling rows and checksum mismatches. Without
+repair flags nothing is changed and the command fails if problems are found.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+
+			fs, err := agfs.New(agfs.Config{RootPath: root})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			store, err := openStorage(cfg, dbPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+
+			report, err := runFsck(context.Background(), os.Stdout, service.NewReconciler(fs, auditedStore(store), opts))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if report.Unrepaired() > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&root, "root", agfs.DefaultConfig().RootPath, "AGFS root directory")
+	cmd.Flags().StringVar(&dbPath, "db", "", "Storage database (default from config)")
+	cmd.Flags().BoolVar(&opts.RecreateRows, "recreate-rows", false, "Add rows for orphan files and update mismatched checksums")
+	cmd.Flags().BoolVar(&opts.DeleteOrphans, "delete-orphans", false, "Delete orphan files")
+	cmd.Flags().BoolVar(&opts.DeleteDangling, "delete-dangling", false, "Delete rows whose files are missing")
+
+	return cmd
+}
+
+// runFsck reconciles AGFS and storage and prints the report.
+func runFsck(ctx context.Context, out io.Writer, r *service.Reconciler) (service.ReconcileReport, error) {
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, uri := range report.OrphanFiles {
+		fmt.Fprintf(out, "orphan file:      %s\n", uri)
+	}
+	for _, uri := range report.DanglingFiles {
+		fmt.Fprintf(out, "dangling file:    %s\n", uri)
+	}
+	for _, uri := range report.DanglingContexts {
+		fmt.Fprintf(out, "dangling context: %s\n", uri)
+	}
+	for _, m := range report.ChecksumMismatches {
+		fmt.Fprintf(out, "checksum mismatch: %s (stored %s, actual %s)\n", m.URI, m.Stored, m.Actual)
+	}
+
+	if report.Clean() {
+		fmt.Fprintln(out, "No inconsistencies found.")
+		return report, nil
+	}
+	fmt.Fprintf(out, "\nFound %d orphan files, %d dangling files, %d dangling contexts, %d checksum mismatches; repaired %d\n",
+		len(report.OrphanFiles), len(report.DanglingFiles), len(report.DanglingContexts),
+		len(report.ChecksumMismatches), report.Repaired)
+	return report, nil
+}
+
+func pruneCmd() *cobra.Command {
+	var dbPath, unusedSince string
+	var opts service.PruneOptions
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete low-importance memories and stale unused contexts",
+		Long: `Delete memories whose importance is below --min-importance and contexts
+that were never used and have not been modified within --unused-since.
+Set either threshold to 0 to skip it. Deleting requires --yes; use
+--dry-run to list what would be removed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			opts.UnusedSince, err = utils.ParseDuration(unusedSince)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+
+			store, err := openStorage(cfg, dbPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+
+			ctx := service.WithActor(context.Background(), userFlag)
+			if _, err := runPrune(ctx, os.Stdout, store, opts, yes); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Storage database (default from config)")
+	cmd.Flags().Float64Var(&opts.MinImportance, "min-importance", 0.3, "Delete memories with importance below this")
+	cmd.Flags().StringVar(&unusedSince, "unused-since", "90d", "Delete unused contexts not modified within this duration (e.g. 90d, 36h)")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "List what would be deleted without deleting")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm deletion")
 
 	return cmd
 }
 
+// runPrune prunes store, recording each deletion in its audit log, and
+// prints what was, or would be, removed. Unless opts.DryRun is set it
+// refuses to run without yes.
+func runPrune(ctx context.Context, out io.Writer, store storage.StorageInterface, opts service.PruneOptions, yes bool) (service.PruneReport, error) {
+	if !opts.DryRun && !yes {
+		return service.PruneReport{}, fmt.Errorf("prune deletes data; pass --yes to confirm or --dry-run to preview")
+	}
+
+	pruner := service.NewPruner(auditedStore(store), opts)
+	report, err := pruner.Prune(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	verb := "deleted"
+	if report.DryRun {
+		verb = "would delete"
+	}
+	for _, m := range report.Memories {
+		fmt.Fprintf(out, "%s memory:  %s (importance %.2f) %s\n", verb, m.ID, m.Importance, utils.TruncateString(m.Content, 60))
+	}
+	for _, c := range report.Contexts {
+		fmt.Fprintf(out, "%s context: %s\n", verb, c.URI)
+	}
+	if report.DryRun {
+		fmt.Fprintf(out, "\nWould delete %d memories and %d contexts\n", len(report.Memories), len(report.Contexts))
+	} else {
+		fmt.Fprintf(out, "\nDeleted %d memories and %d contexts\n", len(report.Memories), len(report.Contexts))
+	}
+	return report, nil
+}
+
+// auditedStore wraps store so that the contexts, memories and sessions a
+// command changes are recorded in its audit log.
+func auditedStore(store storage.StorageInterface) storage.StorageInterface {
+	return service.NewAuditedStore(store, service.NewAuditLogger(store))
+}
+
+// openStorage opens the storage backend configured in cfg. A non-empty
+// dbPath, as given by --db, overrides the configured SQLite database path.
+func openStorage(cfg *config.Config, dbPath string) (storage.StorageInterface, error) {
+	storeCfg := storage.DefaultConfig()
+	storeCfg.DBPath = cfg.Storage.Path
+	if dbPath != "" {
+		storeCfg.DBPath = dbPath
+	}
+	storeCfg.DSN = cfg.Storage.DSN
+	storeCfg.ContentDir = cfg.Storage.ContentDir
+	storeCfg.ContentInlineLimit = cfg.Storage.ContentInlineLimit
+
+	backend := storage.BackendType(cfg.Storage.Type)
+	if backend == "" {
+		backend = storage.BackendSQLite
+	}
+	return storage.NewStorage(backend, storeCfg)
+}
+
+// contentExternalizer is implemented by storage that can move content
+// written inline before a content directory was set out to it.
+type contentExternalizer interface {
+	ExternalizeContent(ctx context.Context) (int, error)
+}
+
+// newLLMProvider creates the configured LLM provider, retrying transient
+// failures behind a circuit breaker so that a failing endpoint is not
+// called over and over.
+func newLLMProvider(cfg *config.Config) (llm.Provider, error) {
+	provider, err := llm.NewProvider(llm.Config{
+		Type:    llm.ProviderType(cfg.LLM.Provider),
+		APIKey:  cfg.LLM.APIKey,
+		BaseURL: cfg.LLM.BaseURL,
+		Model:   cfg.LLM.Model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	retrying := llm.NewRetryingProvider(provider, llm.DefaultRetryConfig())
+	return llm.NewCircuitBreaker(retrying, llm.DefaultBreakerConfig()), nil
+}
+
+func healthCmd() *cobra.Command {
+	var serverURL string
+	var wait time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Check that a server is healthy and ready",
+		Long: `Check /health and /health/ready on a server and print the result as
+JSON. The command exits 1 unless the server is healthy and ready. With
+--wait it polls until the server is ready or the duration has passed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var c *client.Client
+			var err error
+			if serverURL != "" {
+				c, err = client.NewClient(serverURL)
+			} else {
+				c, err = getClient()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			ok, err := runHealth(context.Background(), os.Stdout, c, wait, time.Second)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&serverURL, "url", "", "Server URL (default from config)")
+	cmd.Flags().DurationVar(&wait, "wait", 0, "Poll until the server is ready or this much time has passed")
+
+	return cmd
+}
+
+// healthReport is the JSON printed by the health command.
+type healthReport struct {
+	Healthy   bool                 `json:"healthy"`
+	Ready     bool                 `json:"ready"`
+	Health    *client.HealthStatus `json:"health,omitempty"`
+	Readiness *client.HealthStatus `json:"readiness,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// runHealth checks the server behind c, polling every interval for up to
+// wait until it is healthy and ready, then prints the last result to out.
+// It reports whether the server ended up healthy and ready.
+func runHealth(ctx context.Context, out io.Writer, c *client.Client, wait, interval time.Duration) (bool, error) {
+	deadline := time.Now().Add(wait)
+
+	var report healthReport
+	for {
+		report = checkHealth(ctx, c)
+		if report.Healthy && report.Ready || !time.Now().Before(deadline) {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintln(out, string(data)); err != nil {
+		return false, err
+	}
+	return report.Healthy && report.Ready, nil
+}
+
+// checkHealth queries both health endpoints once.
+func checkHealth(ctx context.Context, c *client.Client) healthReport {
+	var report healthReport
+
+	health, err := c.Health(ctx)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Health = health
+	report.Healthy = health.OK()
+
+	ready, err := c.Ready(ctx)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Readiness = ready
+	report.Ready = ready.OK()
+	return report
+}
+
 func configCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
@@ -587,6 +1753,7 @@ func configCmd() *cobra.Command {
 			defaultConfig := `server:
   host: localhost
   port: 8080
+  shutdown_timeout: 5s
 
 storage:
   type: sqlite
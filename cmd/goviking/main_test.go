@@ -0,0 +1,981 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/agfs"
+	"github.com/jqnote/goviking/pkg/client"
+	"github.com/jqnote/goviking/pkg/config"
+	"github.com/jqnote/goviking/pkg/core"
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/server"
+	"github.com/jqnote/goviking/pkg/service"
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// mockProvider answers every chat request with a fixed response.
+type mockProvider struct {
+	response string
+	requests []*llm.ChatRequest
+}
+
+func (m *mockProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	m.requests = append(m.requests, req)
+	return &llm.ChatResponse{
+		Choices: []llm.Choice{{Message: llm.Message{Role: llm.RoleAssistant, Content: m.response}}},
+	}, nil
+}
+
+func (m *mockProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (llm.StreamReader, error) {
+	return nil, nil
+}
+
+func (m *mockProvider) Embed(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	return &llm.EmbeddingResponse{}, nil
+}
+
+func (m *mockProvider) Close() error { return nil }
+
+const duplicateMemories = `[
+  {"content": "User is a data engineer named Alice", "importance": 0.9, "category": "profile"},
+  {"content": "User prefers Python over Java", "importance": 0.8, "category": "preference"},
+  {"content": "User prefers Python", "importance": 0.7, "category": "preference"}
+]`
+
+func runExtract(t *testing.T, provider llm.Provider, category string, dedup bool) []session.ExtractedMemory {
+	t.Helper()
+	f, err := os.Open("testdata/transcript.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	if err := runMemoryExtract(context.Background(), provider, f, &out, "", category, dedup); err != nil {
+		t.Fatalf("runMemoryExtract failed: %v", err)
+	}
+
+	var memories []session.ExtractedMemory
+	if err := json.Unmarshal(out.Bytes(), &memories); err != nil {
+		t.Fatalf("Output is not a JSON array of memories: %v\n%s", err, out.String())
+	}
+	return memories
+}
+
+func TestMemoryExtract(t *testing.T) {
+	provider := &mockProvider{response: duplicateMemories}
+	memories := runExtract(t, provider, "", false)
+
+	if len(memories) != 3 {
+		t.Fatalf("Expected 3 memories, got %d", len(memories))
+	}
+	if memories[0].Content != "User is a data engineer named Alice" {
+		t.Errorf("Unexpected first memory %q", memories[0].Content)
+	}
+	prompt := provider.requests[0].Messages[1].Content
+	if !strings.Contains(prompt, "I prefer Python over Java") {
+		t.Error("Expected the transcript to be included in the prompt")
+	}
+}
+
+func TestMemoryExtractDedup(t *testing.T) {
+	memories := runExtract(t, &mockProvider{response: duplicateMemories}, "", true)
+
+	if len(memories) != 2 {
+		t.Fatalf("Expected 2 memories after dedup, got %d", len(memories))
+	}
+	for _, m := range memories {
+		if m.Content == "User prefers Python" {
+			t.Error("Expected the duplicate preference to be removed")
+		}
+	}
+}
+
+func TestMemoryExtractCategory(t *testing.T) {
+	provider := &mockProvider{response: `[{"content": "User is named Alice", "importance": 1.0}]`}
+	memories := runExtract(t, provider, "profile", false)
+
+	if len(memories) != 1 || memories[0].Category != "profile" {
+		t.Fatalf("Expected one profile memory, got %+v", memories)
+	}
+}
+
+func TestReadTranscriptArray(t *testing.T) {
+	messages, err := readTranscript(strings.NewReader(`[{"role": "user", "content": "hello"}]`))
+	if err != nil {
+		t.Fatalf("readTranscript failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "hello" {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+
+	if _, err := readTranscript(strings.NewReader(`{}`)); err == nil {
+		t.Error("Expected error for empty transcript")
+	}
+}
+
+// seedWindowSession persists one context per tier for a session and returns
+// the data directory. Abstracts are sized to 10, 10 and 20 tokens.
+func seedWindowSession(t *testing.T, sessionID string) string {
+	t.Helper()
+	dir := t.TempDir()
+	tc := core.NewTieredContext()
+	for _, seed := range []struct {
+		uri      string
+		tier     core.ContextTier
+		abstract string
+	}{
+		{"viking://user/memories/profile", core.TierL0, strings.Repeat("a", 40)},
+		{"viking://resources/docs/guide", core.TierL1, strings.Repeat("b", 40)},
+		{"viking://resources/docs/archive", core.TierL2, strings.Repeat("c", 80)},
+	} {
+		ctx := core.NewContext(seed.uri)
+		ctx.Tier = seed.tier
+		ctx.Abstract = seed.abstract
+		tc.Add(ctx)
+	}
+	handler := core.NewPersistenceHandler(&core.PersistenceConfig{StoragePath: dir}, tc, sessionID)
+	if err := handler.Save(); err != nil {
+		t.Fatalf("Failed to seed session: %v", err)
+	}
+	return dir
+}
+
+func TestRunWindowInspect(t *testing.T) {
+	dir := seedWindowSession(t, "sess-1")
+
+	var out bytes.Buffer
+	if err := runWindowInspect(&out, core.NewFileBackend(dir), "sess-1", 100); err != nil {
+		t.Fatalf("runWindowInspect failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Max tokens:  100",
+		"Current:     40 (40.0%)",
+		"L0    1         10",
+		"L1    1         10",
+		"L2    1         20",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunWindowFitTightBudget(t *testing.T) {
+	dir := seedWindowSession(t, "sess-1")
+
+	var out bytes.Buffer
+	if err := runWindowFit(&out, core.NewFileBackend(dir), "sess-1", 25); err != nil {
+		t.Fatalf("runWindowFit failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"keep    L0    10      viking://user/memories/profile",
+		"keep    L1    10      viking://resources/docs/guide",
+		"drop    L2    20      viking://resources/docs/archive",
+		"Kept 2 of 3 contexts (20 tokens)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunWindowUnknownSession(t *testing.T) {
+	if err := runWindowInspect(&bytes.Buffer{}, core.NewFileBackend(t.TempDir()), "missing", 0); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
+
+// fsckStore serves fixed files and contexts rows to the reconciler.
+type fsckStore struct {
+	storage.StorageInterface
+	files    []storage.File
+	contexts []storage.Context
+}
+
+func (s *fsckStore) QueryFiles(ctx context.Context, opts storage.QueryOptions) ([]storage.File, error) {
+	return s.files, nil
+}
+
+func (s *fsckStore) QueryContexts(ctx context.Context, opts storage.QueryOptions) ([]storage.Context, error) {
+	return s.contexts, nil
+}
+
+func TestRunFsck(t *testing.T) {
+	fs, err := agfs.New(agfs.Config{RootPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	if err := fs.Write("viking://resources/orphan.md", []byte("orphan")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	store := &fsckStore{
+		files: []storage.File{{ID: "f1", URI: "viking://resources/gone.md"}},
+	}
+
+	var out bytes.Buffer
+	report, err := runFsck(context.Background(), &out, service.NewReconciler(fs, store, service.ReconcileOptions{}))
+	if err != nil {
+		t.Fatalf("runFsck failed: %v", err)
+	}
+	if report.Clean() {
+		t.Error("Expected inconsistencies to be reported")
+	}
+	for _, want := range []string{
+		"orphan file:      viking://resources/orphan.md",
+		"dangling file:    viking://resources/gone.md",
+		"Found 1 orphan files, 1 dangling files, 0 dangling contexts, 0 checksum mismatches; repaired 0",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMemoryExtractSession(t *testing.T) {
+	f, err := os.Open("testdata/transcript.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	provider := &mockProvider{response: duplicateMemories}
+	if err := runMemoryExtract(context.Background(), provider, f, &out, "sess-1", "", false); err != nil {
+		t.Fatalf("runMemoryExtract failed: %v", err)
+	}
+
+	var memories []session.ExtractedMemory
+	if err := json.Unmarshal(out.Bytes(), &memories); err != nil {
+		t.Fatalf("Output is not a JSON array of memories: %v", err)
+	}
+	for _, m := range memories {
+		if m.SessionID != "sess-1" {
+			t.Errorf("Expected session sess-1, got %q", m.SessionID)
+		}
+	}
+}
+
+// setIdentityFlags sets the global identity flags for the test.
+func setIdentityFlags(t *testing.T, user, sessionID string) {
+	t.Helper()
+	userFlag, sessionFlag = user, sessionID
+	t.Cleanup(func() { userFlag, sessionFlag = "", "" })
+}
+
+func TestIdentityFlagsPropagate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setIdentityFlags(t, "alice", "sess-1")
+
+	var requests []*http.Request
+	var created client.Context
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Method == "POST" {
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(created)
+			return
+		}
+		json.NewEncoder(w).Encode([]client.Context{})
+	}))
+	defer srv.Close()
+
+	user, sessionID := resolveIdentity(&config.Config{CLI: config.CLIConfig{User: "bob", Session: "sess-cfg"}})
+	c, err := newCLIClient(srv.URL, user, sessionID)
+	if err != nil {
+		t.Fatalf("newCLIClient failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := c.Contexts.Create(ctx, &client.Context{Name: "notes"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := c.Search.Contexts(ctx, "notes"); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if created.UserID != "alice" || created.SessionID != "sess-1" {
+		t.Errorf("Expected alice/sess-1 in created context, got %q/%q", created.UserID, created.SessionID)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if got := r.Header.Get(client.HeaderUser); got != "alice" {
+			t.Errorf("Expected user header alice on %s, got %q", r.Method, got)
+		}
+		if got := r.Header.Get(client.HeaderSession); got != "sess-1" {
+			t.Errorf("Expected session header sess-1 on %s, got %q", r.Method, got)
+		}
+	}
+}
+
+func TestResolveIdentity(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{CLI: config.CLIConfig{User: "bob", Session: "sess-cfg"}}
+
+	user, sessionID := resolveIdentity(cfg)
+	if user != "bob" || sessionID != "sess-cfg" {
+		t.Errorf("Expected config defaults bob/sess-cfg, got %q/%q", user, sessionID)
+	}
+
+	rememberSession("sess-last")
+	user, sessionID = resolveIdentity(&config.Config{})
+	if user != "" || sessionID != "sess-last" {
+		t.Errorf("Expected last session sess-last, got %q/%q", user, sessionID)
+	}
+
+	setIdentityFlags(t, "alice", "sess-flag")
+	user, sessionID = resolveIdentity(cfg)
+	if user != "alice" || sessionID != "sess-flag" {
+		t.Errorf("Expected flags alice/sess-flag, got %q/%q", user, sessionID)
+	}
+}
+
+func TestOpenStorage(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Storage: config.StorageConfig{Type: "sqlite", Path: filepath.Join(dir, "config.db")}}
+
+	store, err := openStorage(cfg, filepath.Join(dir, "flag.db"))
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	store.Close()
+	if _, err := os.Stat(filepath.Join(dir, "flag.db")); err != nil {
+		t.Errorf("Expected --db to override the configured path: %v", err)
+	}
+
+	cfg.Storage.Type = "postgres"
+	if _, err := openStorage(cfg, ""); err == nil {
+		t.Error("Expected an error for postgres without a dsn")
+	}
+
+	cfg.Storage.Type = "unknown"
+	if _, err := openStorage(cfg, ""); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestNewLLMProvider(t *testing.T) {
+	provider, err := newLLMProvider(&config.Config{LLM: config.LLMConfig{Provider: "openai", Model: "gpt-4"}})
+	if err != nil {
+		t.Fatalf("newLLMProvider failed: %v", err)
+	}
+	defer provider.Close()
+	if _, ok := provider.(*llm.CircuitBreaker); !ok {
+		t.Errorf("Expected a circuit breaker, got %T", provider)
+	}
+
+	if _, err := newLLMProvider(&config.Config{LLM: config.LLMConfig{Provider: "unknown"}}); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
+
+func TestRememberSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	state, err := loadState()
+	if err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if state.LastSession != "" {
+		t.Errorf("Expected empty state, got %q", state.LastSession)
+	}
+
+	rememberSession("sess-1")
+	if _, err := os.Stat(filepath.Join(home, ".goviking", "state.json")); err != nil {
+		t.Fatalf("Expected state file: %v", err)
+	}
+	state, err = loadState()
+	if err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if state.LastSession != "sess-1" {
+		t.Errorf("Expected sess-1, got %q", state.LastSession)
+	}
+}
+
+// pruneStore serves fixed memories and contexts and records deletions and
+// their audit entries.
+type pruneStore struct {
+	storage.StorageInterface
+	memories []storage.Memory
+	contexts []storage.Context
+	deleted  []string
+	audit    []storage.AuditEntry
+}
+
+func (s *pruneStore) CreateAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+func (s *pruneStore) QueryMemories(ctx context.Context, opts storage.QueryOptions) ([]storage.Memory, error) {
+	return s.memories, nil
+}
+
+func (s *pruneStore) QueryContexts(ctx context.Context, opts storage.QueryOptions) ([]storage.Context, error) {
+	return s.contexts, nil
+}
+
+func (s *pruneStore) GetMemory(ctx context.Context, id string) (*storage.Memory, error) {
+	for _, m := range s.memories {
+		if m.ID == id {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *pruneStore) GetContext(ctx context.Context, id string) (*storage.Context, error) {
+	for _, c := range s.contexts {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *pruneStore) DeleteMemory(ctx context.Context, id string) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *pruneStore) DeleteContext(ctx context.Context, id string) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func newPruneStore() *pruneStore {
+	old := time.Now().AddDate(0, 0, -200)
+	return &pruneStore{
+		memories: []storage.Memory{
+			{ID: "m1", Content: "trivia", Importance: 0.1},
+			{ID: "m2", Content: "profile", Importance: 0.9},
+		},
+		contexts: []storage.Context{
+			{ID: "c1", URI: "viking://resources/stale", CreatedAt: old, UpdatedAt: old},
+			{ID: "c2", URI: "viking://resources/fresh", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		},
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	opts := service.PruneOptions{MinImportance: 0.3, UnusedSince: 90 * 24 * time.Hour}
+
+	store := newPruneStore()
+	var out bytes.Buffer
+	if _, err := runPrune(context.Background(), &out, store, opts, false); err == nil {
+		t.Error("Expected prune without --yes to fail")
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("Expected nothing deleted without --yes, got %v", store.deleted)
+	}
+
+	opts.DryRun = true
+	if _, err := runPrune(context.Background(), &out, store, opts, false); err != nil {
+		t.Fatalf("runPrune dry run failed: %v", err)
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("Expected dry run to delete nothing, got %v", store.deleted)
+	}
+	for _, want := range []string{
+		"would delete memory:  m1 (importance 0.10) trivia",
+		"would delete context: viking://resources/stale",
+		"Would delete 1 memories and 1 contexts",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	opts.DryRun = false
+	out.Reset()
+	if _, err := runPrune(context.Background(), &out, store, opts, true); err != nil {
+		t.Fatalf("runPrune failed: %v", err)
+	}
+	if want := []string{"m1", "c1"}; !reflect.DeepEqual(store.deleted, want) {
+		t.Errorf("Expected %v deleted, got %v", want, store.deleted)
+	}
+	if !strings.Contains(out.String(), "Deleted 1 memories and 1 contexts") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+	if len(store.audit) != 2 || store.audit[0].EntityID != "m1" || store.audit[1].Action != service.AuditDelete {
+		t.Errorf("Expected both deletions audited, got %+v", store.audit)
+	}
+}
+
+// newHealthServer serves /health and answers /health/ready with 503 until
+// it has been polled readyAfter times.
+func newHealthServer(t *testing.T, readyAfter int) (*client.Client, *int) {
+	t.Helper()
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health/ready" {
+			polls++
+			if polls <= readyAfter {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"status": "unavailable"}`))
+				return
+			}
+			w.Write([]byte(`{"status": "ready"}`))
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := client.NewClient(srv.URL)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return c, &polls
+}
+
+func TestRunHealth(t *testing.T) {
+	c, _ := newHealthServer(t, 0)
+
+	var out bytes.Buffer
+	ok, err := runHealth(context.Background(), &out, c, 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("runHealth failed: %v", err)
+	}
+	if !ok {
+		t.Error("Expected a healthy server to pass")
+	}
+
+	var report healthReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, out.String())
+	}
+	if !report.Healthy || !report.Ready || report.Readiness.Status != "ready" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestRunHealthUnhealthy(t *testing.T) {
+	c, polls := newHealthServer(t, 1000)
+
+	var out bytes.Buffer
+	ok, err := runHealth(context.Background(), &out, c, 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("runHealth failed: %v", err)
+	}
+	if ok {
+		t.Error("Expected a server that is not ready to fail")
+	}
+	if *polls != 1 {
+		t.Errorf("Expected a single check without --wait, got %d", *polls)
+	}
+	if !strings.Contains(out.String(), `"ready": false`) {
+		t.Errorf("Expected not ready in output, got:\n%s", out.String())
+	}
+}
+
+func TestRunHealthUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c, _ := client.NewClient(srv.URL)
+
+	var out bytes.Buffer
+	ok, err := runHealth(context.Background(), &out, c, 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("runHealth failed: %v", err)
+	}
+	if ok {
+		t.Error("Expected an unreachable server to fail")
+	}
+	var report healthReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not JSON: %v", err)
+	}
+	if report.Error == "" {
+		t.Error("Expected the connection error to be reported")
+	}
+}
+
+func TestRunHealthWait(t *testing.T) {
+	c, polls := newHealthServer(t, 3)
+
+	var out bytes.Buffer
+	ok, err := runHealth(context.Background(), &out, c, 10*time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("runHealth failed: %v", err)
+	}
+	if !ok {
+		t.Error("Expected the server to become ready while waiting")
+	}
+	if *polls != 4 {
+		t.Errorf("Expected 4 readiness checks, got %d", *polls)
+	}
+}
+
+func TestRunHealthWaitTimeout(t *testing.T) {
+	c, polls := newHealthServer(t, 1000)
+
+	var out bytes.Buffer
+	ok, err := runHealth(context.Background(), &out, c, 50*time.Millisecond, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("runHealth failed: %v", err)
+	}
+	if ok {
+		t.Error("Expected the wait to time out")
+	}
+	if *polls < 2 {
+		t.Errorf("Expected repeated checks while waiting, got %d", *polls)
+	}
+}
+
+// backfillStore serves one stored session and records created memories
+// and their audit entries.
+type backfillStore struct {
+	storage.StorageInterface
+	memories []storage.Memory
+	audit    []storage.AuditEntry
+}
+
+func (s *backfillStore) CreateAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+func (s *backfillStore) QuerySessions(ctx context.Context, opts storage.QueryOptions) ([]storage.Session, error) {
+	return []storage.Session{{ID: "1", SessionID: "s1", UserID: "alice"}}, nil
+}
+
+func (s *backfillStore) GetSessionMessages(ctx context.Context, sessionID string) ([]storage.SessionMessage, error) {
+	return []storage.SessionMessage{
+		{ID: "1", SessionID: sessionID, Role: "user", Content: "I prefer Python over Java"},
+	}, nil
+}
+
+func (s *backfillStore) QueryMemories(ctx context.Context, opts storage.QueryOptions) ([]storage.Memory, error) {
+	return s.memories, nil
+}
+
+func (s *backfillStore) CreateMemory(ctx context.Context, memory *storage.Memory) error {
+	s.memories = append(s.memories, *memory)
+	return nil
+}
+
+func TestRunMemoryBackfill(t *testing.T) {
+	store := &backfillStore{
+		memories: []storage.Memory{{ID: "m1", Content: "User is a data engineer named Alice"}},
+	}
+
+	var out bytes.Buffer
+	err := runMemoryBackfill(context.Background(), &out, store, &mockProvider{response: duplicateMemories}, nil, service.BackfillOptions{})
+	if err != nil {
+		t.Fatalf("runMemoryBackfill failed: %v", err)
+	}
+
+	if len(store.memories) != 2 || store.memories[1].Content != "User prefers Python over Java" {
+		t.Errorf("Expected only the new preference to be added, got %+v", store.memories)
+	}
+	if !strings.Contains(out.String(), "s1: 1 messages, 3 extracted, 2 duplicates, 1 added") {
+		t.Errorf("Expected per-session counts, got:\n%s", out.String())
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != service.AuditCreate || store.audit[0].EntityID != store.memories[1].ID {
+		t.Errorf("Expected the added memory to be audited, got %+v", store.audit)
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	day, err := parseTimeFlag("2026-03-01")
+	if err != nil || !day.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2026-03-01, got %v (%v)", day, err)
+	}
+	if _, err := parseTimeFlag("2026-03-01T12:00:00Z"); err != nil {
+		t.Errorf("Expected RFC 3339 to parse, got %v", err)
+	}
+	if _, err := parseTimeFlag("last week"); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+	if zero, _ := parseTimeFlag(""); !zero.IsZero() {
+		t.Errorf("Expected zero time for empty flag, got %v", zero)
+	}
+}
+
+// slowStore makes readiness checks take delay, signalling entered when a
+// check starts.
+type slowStore struct {
+	storage.StorageInterface
+	delay   time.Duration
+	entered chan struct{}
+}
+
+func (s *slowStore) Ping(ctx context.Context) error {
+	s.entered <- struct{}{}
+	time.Sleep(s.delay)
+	return nil
+}
+
+// startSlowRequest serves a server backed by a slowStore and issues a
+// readiness request against it. It returns once the request is in flight.
+func startSlowRequest(t *testing.T, delay, timeout time.Duration) (respc chan int, done chan error, out *bytes.Buffer) {
+	t.Helper()
+	store := &slowStore{delay: delay, entered: make(chan struct{}, 1)}
+	s := server.New(store, nil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM)
+	t.Cleanup(func() { signal.Stop(quit) })
+
+	out = &bytes.Buffer{}
+	done = make(chan error, 1)
+	go func() {
+		done <- serveUntilSignal(s, ln, quit, timeout, out)
+	}()
+
+	respc = make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/health/ready")
+		if err != nil {
+			respc <- 0
+			return
+		}
+		resp.Body.Close()
+		respc <- resp.StatusCode
+	}()
+	<-store.entered
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to send SIGTERM: %v", err)
+	}
+	return respc, done, out
+}
+
+func TestServeUntilSignalDrains(t *testing.T) {
+	respc, done, out := startSlowRequest(t, 200*time.Millisecond, 5*time.Second)
+
+	if code := <-respc; code != http.StatusOK {
+		t.Errorf("Expected the in-flight request to complete with 200, got %d", code)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Drained 1 connections") {
+		t.Errorf("Expected drained connections to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestServeUntilSignalTimeout(t *testing.T) {
+	respc, done, _ := startSlowRequest(t, time.Second, 50*time.Millisecond)
+
+	err := <-done
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a shutdown timeout error, got %v", err)
+	}
+	if code := <-respc; code == http.StatusOK {
+		t.Error("Expected the request to be cut off after the timeout")
+	}
+}
+
+// explainResponse is a stub explain response: resources visited from the
+// root down to docs/api, where the search converged, with one branch below
+// the threshold and one beyond the maximum depth.
+const explainResponse = `{
+  "query": "rate limits",
+  "results": [{"uri": "viking://resources/docs/api/limits.md", "score": 0.81}],
+  "traversals": [
+    {
+      "context_type": "resource",
+      "steps": [
+        {"uri": "viking://resources", "depth": 0, "score": 0},
+        {"uri": "viking://resources/docs", "parent": "viking://resources", "depth": 1, "score": 0.62},
+        {"uri": "viking://resources/docs/api", "parent": "viking://resources/docs", "depth": 2, "score": 0.74},
+        {"uri": "viking://resources/blog", "parent": "viking://resources", "depth": 1, "score": 0.41}
+      ],
+      "events": [
+        {"type": "below_threshold", "uri": "viking://resources/archive", "parent": "viking://resources", "score": 0.12, "message": "Excluded"},
+        {"type": "max_depth", "uri": "viking://resources/docs/api/v1", "parent": "viking://resources/docs/api", "score": 0.7, "message": "Not expanding"},
+        {"type": "converged", "uri": "viking://resources/docs/api", "message": "Search converged"}
+      ]
+    },
+    {"context_type": "memory", "steps": []}
+  ]
+}`
+
+func TestRunSearchTrace(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/search/explain" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query().Get("q")
+		w.Write([]byte(explainResponse))
+	}))
+	defer srv.Close()
+
+	c, err := client.NewClient(srv.URL)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	traceOut := filepath.Join(t.TempDir(), "trace.json")
+	var out bytes.Buffer
+	if err := runSearchTrace(context.Background(), &out, c, "rate limits", traceOut); err != nil {
+		t.Fatalf("runSearchTrace failed: %v", err)
+	}
+	if gotQuery != "rate limits" {
+		t.Errorf("Expected query %q, got %q", "rate limits", gotQuery)
+	}
+
+	want := `Trajectory for: rate limits
+
+resource:
+  viking://resources (0.000)
+    viking://resources/docs (0.620)
+      viking://resources/docs/api (0.740) [converged]
+        viking://resources/docs/api/v1 (0.700) [pruned: max depth]
+    viking://resources/blog (0.410)
+    viking://resources/archive (0.120) [pruned: below threshold]
+
+memory:
+  (no directories visited)
+`
+	if out.String() != want {
+		t.Errorf("Unexpected trace:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	data, err := os.ReadFile(traceOut)
+	if err != nil {
+		t.Fatalf("Expected the trace to be saved: %v", err)
+	}
+	var saved client.Explanation
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Saved trace is not JSON: %v", err)
+	}
+	if len(saved.Traversals) != 2 || len(saved.Traversals[0].Steps) != 4 || len(saved.Traversals[0].Events) != 3 {
+		t.Errorf("Expected the saved trace to match the trajectory, got %+v", saved)
+	}
+}
+
+// fakeSearcher returns canned full-text search results.
+type fakeSearcher struct {
+	results  []storage.Context
+	gotQuery string
+	gotLimit int
+}
+
+func (f *fakeSearcher) SearchContextsFTS(ctx context.Context, query string, limit int) ([]storage.Context, error) {
+	f.gotQuery, f.gotLimit = query, limit
+	return f.results, nil
+}
+
+func TestRunLocalSearch(t *testing.T) {
+	searcher := &fakeSearcher{results: []storage.Context{
+		{ID: "guide", Name: "Goroutine guide", Type: storage.ContextTypeFile, Abstract: "How goroutines\nare scheduled"},
+		{ID: "channels", Name: "Channels", Type: storage.ContextTypeFile, Abstract: "Channels"},
+	}}
+
+	var out bytes.Buffer
+	if err := runLocalSearch(context.Background(), &out, searcher, `"goroutine guide"`, 5, 10); err != nil {
+		t.Fatalf("runLocalSearch failed: %v", err)
+	}
+	if searcher.gotQuery != `"goroutine guide"` || searcher.gotLimit != 5 {
+		t.Errorf("Expected the query and limit passed through, got %q, %d", searcher.gotQuery, searcher.gotLimit)
+	}
+	want := `Search results for: "goroutine guide"
+
+NAME             TYPE  ID        ABSTRACT
+Goroutine guide  file  guide     How gorout...
+Channels         file  channels  Channels
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := runLocalSearch(context.Background(), &out, &fakeSearcher{}, "nothing", 5, 10); err != nil {
+		t.Fatalf("runLocalSearch failed: %v", err)
+	}
+	if out.String() != "No results found for: nothing\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+func TestPreviewText(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"abcdef", 5, "abcde..."},
+		{"abcde", 5, "abcde"},
+		{"abcd", 5, "abcd"},
+		{"héllo wörld", 7, "héllo w..."},
+		{"abcdef", 0, "abcdef"},
+	}
+	for _, tt := range tests {
+		if got := previewText(tt.text, tt.n); got != tt.want {
+			t.Errorf("previewText(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+
+	if n := previewLength(5, true); n != 0 {
+		t.Errorf("Expected --full to disable the preview, got %d", n)
+	}
+	if n := previewLength(5, false); n != 5 {
+		t.Errorf("Expected --preview 5, got %d", n)
+	}
+}
+
+func TestPrintContextPreview(t *testing.T) {
+	c := &client.Context{ID: "big", Name: "Big", Abstract: "A long abstract", Content: strings.Repeat("x", 50)}
+
+	var out bytes.Buffer
+	if err := printContext(&out, c, 10); err != nil {
+		t.Fatalf("printContext failed: %v", err)
+	}
+	var shown client.Context
+	if err := json.Unmarshal(out.Bytes(), &shown); err != nil {
+		t.Fatalf("Output is not JSON: %v", err)
+	}
+	if shown.Content != strings.Repeat("x", 10)+"..." || shown.Abstract != "A long abs..." {
+		t.Errorf("Expected content and abstract cut to 10 characters, got %q and %q", shown.Content, shown.Abstract)
+	}
+	if len(c.Content) != 50 {
+		t.Error("Expected the context itself left untouched")
+	}
+
+	out.Reset()
+	if err := printContext(&out, c, previewLength(10, true)); err != nil {
+		t.Fatalf("printContext failed: %v", err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &shown); err != nil {
+		t.Fatalf("Output is not JSON: %v", err)
+	}
+	if shown.Content != c.Content || shown.Abstract != c.Abstract {
+		t.Errorf("Expected --full to show everything, got %q and %q", shown.Content, shown.Abstract)
+	}
+}
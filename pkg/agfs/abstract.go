@@ -0,0 +1,337 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Languages recognised by the code abstract generator.
+const (
+	LanguageGo         = "go"
+	LanguagePython     = "python"
+	LanguageJavaScript = "javascript"
+)
+
+// AbstractConfig configures abstracts generated for code files.
+type AbstractConfig struct {
+	// Code summarises Go, Python and JavaScript files by their top-level
+	// declarations and doc comments instead of their first paragraph.
+	Code bool
+	// MaxDeclarations caps the declarations listed in a code abstract.
+	MaxDeclarations int
+	// MaxLines is the number of leading lines used for files in other
+	// languages, or code that cannot be summarised.
+	MaxLines int
+}
+
+// DefaultAbstractConfig returns the default abstract configuration.
+func DefaultAbstractConfig() AbstractConfig {
+	return AbstractConfig{
+		Code:            true,
+		MaxDeclarations: 20,
+		MaxLines:        5,
+	}
+}
+
+// DetectLanguage returns the programming language of a file from its
+// extension, or from a shebang line for files without one. It returns ""
+// for anything that is not Go, Python or JavaScript.
+func DetectLanguage(name, content string) string {
+	switch strings.ToLower(path.Ext(strings.TrimSuffix(name, "/"))) {
+	case ".go":
+		return LanguageGo
+	case ".py", ".pyw":
+		return LanguagePython
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return LanguageJavaScript
+	case "":
+		if strings.HasPrefix(content, "#!") {
+			line, _, _ := strings.Cut(content, "\n")
+			switch {
+			case strings.Contains(line, "python"):
+				return LanguagePython
+			case strings.Contains(line, "node"):
+				return LanguageJavaScript
+			}
+		}
+	}
+	return ""
+}
+
+// GenerateAbstract summarises a code file as one line per top-level
+// declaration, each followed by the first sentence of its doc comment.
+// Files in other languages, and code with no declarations, fall back to
+// their first config.MaxLines non-blank lines.
+func GenerateAbstract(name, content string, config AbstractConfig) string {
+	defaults := DefaultAbstractConfig()
+	if config.MaxDeclarations <= 0 {
+		config.MaxDeclarations = defaults.MaxDeclarations
+	}
+	if config.MaxLines <= 0 {
+		config.MaxLines = defaults.MaxLines
+	}
+
+	var decls []string
+	switch DetectLanguage(name, content) {
+	case LanguageGo:
+		decls = goDeclarations(content)
+	case LanguagePython:
+		decls = pythonDeclarations(content)
+	case LanguageJavaScript:
+		decls = javaScriptDeclarations(content)
+	}
+	if len(decls) == 0 {
+		return firstLines(content, config.MaxLines)
+	}
+
+	if len(decls) > config.MaxDeclarations {
+		more := len(decls) - config.MaxDeclarations
+		decls = append(decls[:config.MaxDeclarations], "... and "+pluralize(more, "more declaration"))
+	}
+	return strings.Join(decls, "\n")
+}
+
+// goDeclarations lists the exported top-level declarations of a Go file,
+// headed by its package clause.
+func goDeclarations(content string) []string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	decls := []string{"package " + file.Name.Name + withDoc(file.Doc.Text())}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() || (d.Recv != nil && !exportedReceiver(d.Recv)) {
+				continue
+			}
+			end := d.End()
+			if d.Body != nil {
+				end = d.Body.Lbrace
+			}
+			signature := content[fset.Position(d.Pos()).Offset:fset.Position(end).Offset]
+			decls = append(decls, collapse(signature)+withDoc(d.Doc.Text()))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				doc := d.Doc.Text()
+				var line string
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if !s.Name.IsExported() {
+						continue
+					}
+					line = "type " + s.Name.Name
+					if s.Doc != nil {
+						doc = s.Doc.Text()
+					}
+				case *ast.ValueSpec:
+					var names []string
+					for _, n := range s.Names {
+						if n.IsExported() {
+							names = append(names, n.Name)
+						}
+					}
+					if len(names) == 0 {
+						continue
+					}
+					line = d.Tok.String() + " " + strings.Join(names, ", ")
+					if s.Doc != nil {
+						doc = s.Doc.Text()
+					}
+				default:
+					continue
+				}
+				decls = append(decls, line+withDoc(doc))
+			}
+		}
+	}
+	if len(decls) == 1 && file.Doc == nil {
+		return nil
+	}
+	return decls
+}
+
+// exportedReceiver reports whether a method's receiver type is exported.
+func exportedReceiver(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	t := recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	switch r := t.(type) {
+	case *ast.Ident:
+		return r.IsExported()
+	case *ast.IndexExpr:
+		id, ok := r.X.(*ast.Ident)
+		return ok && id.IsExported()
+	case *ast.IndexListExpr:
+		id, ok := r.X.(*ast.Ident)
+		return ok && id.IsExported()
+	}
+	return false
+}
+
+var (
+	pythonDeclRe = regexp.MustCompile(`^((?:async\s+)?def\s+\w+\s*\([^)]*\)|class\s+\w+(?:\([^)]*\))?)`)
+
+	jsFunctionRe = regexp.MustCompile(`^(?:export\s+(?:default\s+)?)?((?:async\s+)?function\s*\*?\s*\w+\s*\([^)]*\)|class\s+\w+(?:\s+extends\s+[\w.]+)?)`)
+	// jsArrowRe matches functions assigned to a top-level variable.
+	jsArrowRe = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(async\s+)?(?:function\s*\*?\s*\w*\s*(\([^)]*\))|(\([^)]*\))\s*=>|(\w+)\s*=>)`)
+)
+
+// pythonDeclarations lists top-level defs and classes with the first
+// sentence of their docstrings, headed by the module docstring.
+func pythonDeclarations(content string) []string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var decls []string
+	if doc := pythonDocstring(lines, 0); doc != "" {
+		decls = append(decls, "module"+withDoc(doc))
+	}
+	for i, line := range lines {
+		m := pythonDeclRe.FindString(line)
+		if m == "" {
+			continue
+		}
+		decls = append(decls, collapse(m)+withDoc(pythonDocstring(lines, i+1)))
+	}
+	return decls
+}
+
+// pythonDocstring returns the docstring starting at the first non-blank,
+// non-comment line at or after start, or "" if there is none.
+func pythonDocstring(lines []string, start int) string {
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		quote := ""
+		for _, q := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(line, q) {
+				quote = q
+			}
+		}
+		if quote == "" {
+			return ""
+		}
+		var doc []string
+		line = strings.TrimPrefix(line, quote)
+		for j := i; j < len(lines); j++ {
+			if j > i {
+				line = strings.TrimSpace(lines[j])
+			}
+			if before, _, found := strings.Cut(line, quote); found {
+				return strings.Join(append(doc, before), " ")
+			}
+			doc = append(doc, line)
+		}
+		return strings.Join(doc, " ")
+	}
+	return ""
+}
+
+// javaScriptDeclarations lists top-level functions, classes and arrow
+// functions with the first sentence of the comment above each.
+func javaScriptDeclarations(content string) []string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var decls []string
+	for i, line := range lines {
+		var decl string
+		if m := jsFunctionRe.FindStringSubmatch(line); m != nil {
+			decl = m[1]
+		} else if m := jsArrowRe.FindStringSubmatch(line); m != nil {
+			params := m[3] + m[4]
+			if m[5] != "" {
+				params = "(" + m[5] + ")"
+			}
+			decl = m[2] + "function " + m[1] + params
+		} else {
+			continue
+		}
+		decls = append(decls, collapse(decl)+withDoc(jsComment(lines, i)))
+	}
+	return decls
+}
+
+// jsComment returns the text of the // or /** */ comment directly above
+// line i.
+func jsComment(lines []string, i int) string {
+	var doc []string
+	j := i - 1
+	if j >= 0 && strings.HasSuffix(strings.TrimSpace(lines[j]), "*/") {
+		for ; j >= 0; j-- {
+			line := strings.TrimSpace(lines[j])
+			done := strings.HasPrefix(line, "/*")
+			line = strings.TrimSuffix(line, "*/")
+			line = strings.TrimLeft(line, "/*")
+			doc = append([]string{strings.TrimSpace(line)}, doc...)
+			if done {
+				break
+			}
+		}
+		return strings.Join(doc, " ")
+	}
+	for ; j >= 0; j-- {
+		line := strings.TrimSpace(lines[j])
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		doc = append([]string{strings.TrimSpace(strings.TrimPrefix(line, "//"))}, doc...)
+	}
+	return strings.Join(doc, " ")
+}
+
+// withDoc formats the first sentence of a doc comment as a suffix for a
+// declaration line, or "" when there is no doc comment.
+func withDoc(doc string) string {
+	doc = collapse(doc)
+	if doc == "" {
+		return ""
+	}
+	if i := strings.Index(doc, ". "); i >= 0 {
+		doc = doc[:i+1]
+	}
+	return ": " + doc
+}
+
+// firstLines returns the first n non-blank lines of text.
+func firstLines(text string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+		if len(lines) == n {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapse joins the fields of s with single spaces.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// pluralize formats n with noun, adding an "s" unless n is 1.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
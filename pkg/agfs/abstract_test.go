@@ -0,0 +1,192 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"strings"
+	"testing"
+)
+
+const goSource = `// Package cache provides an in-memory LRU cache.
+package cache
+
+import "sync"
+
+// DefaultSize is the capacity used when none is given.
+const DefaultSize = 128
+
+// Cache is a fixed-size LRU cache. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	items map[string]string
+}
+
+type entry struct{}
+
+// New creates a cache holding up to size items.
+func New(size int) *Cache {
+	return &Cache{items: make(map[string]string, size)}
+}
+
+// Get returns the value stored under key.
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.items[key]
+	return v, ok
+}
+
+func (c *Cache) evict() {}
+
+func helper() {}
+`
+
+const pythonSource = `"""Utilities for parsing invoices."""
+
+import re
+
+
+class Invoice(Base):
+    """An invoice with line items.
+
+    Totals are computed lazily.
+    """
+
+    def total(self):
+        return 0
+
+
+def parse(text, strict=False):
+    """Parse an invoice from text."""
+    return Invoice()
+
+
+async def fetch(url):
+    return None
+`
+
+func TestGenerateAbstractGo(t *testing.T) {
+	got := GenerateAbstract("cache.go", goSource, DefaultAbstractConfig())
+	want := strings.Join([]string{
+		"package cache: Package cache provides an in-memory LRU cache.",
+		"const DefaultSize: DefaultSize is the capacity used when none is given.",
+		"type Cache: Cache is a fixed-size LRU cache.",
+		"func New(size int) *Cache: New creates a cache holding up to size items.",
+		"func (c *Cache) Get(key string) (string, bool): Get returns the value stored under key.",
+	}, "\n")
+	if got != want {
+		t.Errorf("GenerateAbstract =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateAbstractPython(t *testing.T) {
+	got := GenerateAbstract("invoice.py", pythonSource, DefaultAbstractConfig())
+	want := strings.Join([]string{
+		"module: Utilities for parsing invoices.",
+		"class Invoice(Base): An invoice with line items.",
+		"def parse(text, strict=False): Parse an invoice from text.",
+		"async def fetch(url)",
+	}, "\n")
+	if got != want {
+		t.Errorf("GenerateAbstract =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateAbstractJavaScript(t *testing.T) {
+	source := `import x from "y";
+
+/**
+ * Formats a price for display.
+ */
+export function formatPrice(amount, currency) {
+  return amount;
+}
+
+// Shopping cart state.
+export default class Cart extends Store {}
+
+export const add = (a, b) => a + b;
+const inc = async n => n + 1;
+`
+	got := GenerateAbstract("cart.js", source, DefaultAbstractConfig())
+	want := strings.Join([]string{
+		"function formatPrice(amount, currency): Formats a price for display.",
+		"class Cart extends Store: Shopping cart state.",
+		"function add(a, b)",
+		"async function inc(n)",
+	}, "\n")
+	if got != want {
+		t.Errorf("GenerateAbstract =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateAbstractFallback(t *testing.T) {
+	source := "line one\n\nline two\nline three\nline four\n"
+	config := AbstractConfig{MaxLines: 2}
+
+	if got := GenerateAbstract("notes.txt", source, config); got != "line one\nline two" {
+		t.Errorf("Expected the first 2 lines for unknown languages, got %q", got)
+	}
+	// Code that does not parse also falls back
+	if got := GenerateAbstract("broken.go", source, config); got != "line one\nline two" {
+		t.Errorf("Expected the first 2 lines for unparsable Go, got %q", got)
+	}
+}
+
+func TestGenerateAbstractMaxDeclarations(t *testing.T) {
+	got := GenerateAbstract("cache.go", goSource, AbstractConfig{MaxDeclarations: 2})
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 || lines[2] != "... and 3 more declarations" {
+		t.Errorf("Expected 2 declarations and a count of the rest, got %q", got)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"main.go", "", LanguageGo},
+		{"viking://resources/app.PY/", "", LanguagePython},
+		{"index.mjs", "", LanguageJavaScript},
+		{"tool", "#!/usr/bin/env python3\nprint()", LanguagePython},
+		{"cli", "#!/usr/bin/env node\n", LanguageJavaScript},
+		{"README.md", "", ""},
+		{"script", "#!/bin/sh\n", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.name, tt.content); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteContextGeneratesCodeAbstract(t *testing.T) {
+	config := DefaultConfig()
+	config.RootPath = t.TempDir()
+	agfs, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	uri := "viking://resources/project/invoice.py"
+	if err := agfs.WriteContext(uri, "", "", pythonSource, true); err != nil {
+		t.Fatalf("WriteContext failed: %v", err)
+	}
+	abstract, err := agfs.ReadAbstract(uri)
+	if err != nil {
+		t.Fatalf("ReadAbstract failed: %v", err)
+	}
+	if !strings.Contains(abstract, "def parse(text, strict=False)") {
+		t.Errorf("Expected a declaration abstract, got %q", abstract)
+	}
+
+	// Disabled, nothing is generated
+	config.Abstract.Code = false
+	config.RootPath = t.TempDir()
+	agfs, _ = New(config)
+	agfs.WriteContext(uri, "", "", pythonSource, true)
+	if abstract, _ := agfs.ReadAbstract(uri); abstract != "" {
+		t.Errorf("Expected no abstract with code abstracts disabled, got %q", abstract)
+	}
+}
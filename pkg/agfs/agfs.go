@@ -13,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 var (
@@ -28,6 +30,12 @@ var (
 	ErrInvalidURI = errors.New("invalid URI")
 	// ErrNotImplemented is returned when a feature is not yet implemented.
 	ErrNotImplemented = errors.New("not implemented")
+	// ErrInvalidPattern is returned when a search pattern does not compile.
+	ErrInvalidPattern = errors.New("invalid pattern")
+	// ErrChecksumMismatch is returned when a file does not match its checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrNoChecksum is returned when a file has no stored checksum to verify.
+	ErrNoChecksum = errors.New("no stored checksum")
 )
 
 // FileType represents the type of context file.
@@ -64,8 +72,15 @@ type TreeEntry struct {
 	Children []*TreeEntry `json:"children,omitempty"`
 	Abstract string      `json:"abstract,omitempty"`
 	Overview string      `json:"overview,omitempty"`
+	// Truncated marks the placeholder entry ending a list cut short by
+	// Config.TreeMaxEntries.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
+// TruncatedEntryName is the name of the placeholder entry Tree appends where
+// it stopped listing entries.
+const TruncatedEntryName = "..."
+
 // RelationEntry represents a relation between directories.
 type RelationEntry struct {
 	ID        string   `json:"id"`
@@ -86,6 +101,35 @@ type Config struct {
 	EnableResources bool
 	// EnableSkills enables skill file support.
 	EnableSkills bool
+	// DeriveMissingSummaries makes ReadContext fill a missing abstract from
+	// the overview or content, and a missing overview from the abstract.
+	DeriveMissingSummaries bool
+	// Abstract configures abstracts generated for code files that are
+	// written or read without one.
+	Abstract AbstractConfig
+	// StatCacheTTL caches Stat, Exists and IsDir lookups for this long.
+	// Writes through AGFS invalidate the cache; zero disables it.
+	StatCacheTTL time.Duration
+	// StatCacheEntries caps the paths the stat cache holds, evicting the
+	// least recently used first.
+	StatCacheEntries int
+	// RollupOnDelete regenerates the abstract of a deleted context's
+	// parent from its remaining children (see SetRollupSummarizer).
+	RollupOnDelete bool
+	// FileSystem stores the tree below RootPath. Nil uses the local disk;
+	// tests may pass a MemFileSystem.
+	FileSystem FileSystem
+	// TreeMaxDepth is the depth Tree uses when called with maxDepth <= 0.
+	TreeMaxDepth int
+	// TreeMaxEntries caps the entries Tree returns. Once reached, each
+	// list left incomplete ends in a placeholder entry marked Truncated.
+	TreeMaxEntries int
+	// Versioning makes Write keep the contents it replaces under a hidden
+	// .versions directory next to the file (see ListVersions).
+	Versioning bool
+	// MaxVersions caps the versions kept per file, pruning the oldest
+	// first; zero keeps every version.
+	MaxVersions int
 }
 
 // DefaultConfig returns a default AGFS configuration.
@@ -96,15 +140,43 @@ func DefaultConfig() Config {
 		EnableMemories: true,
 		EnableResources: true,
 		EnableSkills:   true,
+		Abstract:       DefaultAbstractConfig(),
+		TreeMaxDepth:   DefaultTreeMaxDepth,
+		TreeMaxEntries: DefaultTreeMaxEntries,
+		StatCacheEntries: DefaultStatCacheEntries,
 	}
 }
 
+const (
+	// DefaultTreeMaxDepth is the default Config.TreeMaxDepth.
+	DefaultTreeMaxDepth = 10
+	// DefaultTreeMaxEntries is the default Config.TreeMaxEntries.
+	DefaultTreeMaxEntries = 10000
+	// DefaultStatCacheEntries is the default Config.StatCacheEntries.
+	DefaultStatCacheEntries = 4096
+)
+
+// newFileSystem returns the FileSystem used when Config.FileSystem is nil,
+// replaceable in tests to run them in memory.
+var newFileSystem = func() FileSystem { return OSFileSystem{} }
+
 // AGFS represents the Agent Graph File System.
 type AGFS struct {
 	config    Config
 	rootPath  string
 	uriPrefix string
+	fs        FileSystem
+	statCache *statCache
 	mu        sync.RWMutex
+
+	// rollup regenerates parent abstracts on delete; rollingUp holds
+	// the parents being rolled up
+	rollupMu  sync.Mutex
+	rollup    RollupSummarizer
+	rollingUp map[string]bool
+
+	// clock names file versions
+	clock utils.Clock
 }
 
 // New creates a new AGFS instance with the given configuration.
@@ -115,11 +187,26 @@ func New(config Config) (*AGFS, error) {
 	if config.URIPrefix == "" {
 		config.URIPrefix = DefaultConfig().URIPrefix
 	}
+	if config.FileSystem == nil {
+		config.FileSystem = newFileSystem()
+	}
+	if config.TreeMaxDepth <= 0 {
+		config.TreeMaxDepth = DefaultTreeMaxDepth
+	}
+	if config.TreeMaxEntries <= 0 {
+		config.TreeMaxEntries = DefaultTreeMaxEntries
+	}
+	if config.StatCacheEntries <= 0 {
+		config.StatCacheEntries = DefaultStatCacheEntries
+	}
 
 	agfs := &AGFS{
 		config:    config,
 		rootPath:  config.RootPath,
 		uriPrefix: config.URIPrefix,
+		fs:        config.FileSystem,
+		statCache: newStatCache(config.StatCacheTTL, config.StatCacheEntries, config.FileSystem),
+		clock:     utils.RealClock{},
 	}
 
 	// Ensure root directories exist
@@ -146,7 +233,7 @@ func (a *AGFS) ensureRootDirs() error {
 
 	for _, dir := range dirs {
 		path := filepath.Join(a.rootPath, dir)
-		if err := os.MkdirAll(path, 0755); err != nil {
+		if err := a.fs.MkdirAll(path, 0755); err != nil {
 			return err
 		}
 	}
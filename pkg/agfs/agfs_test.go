@@ -4,9 +4,17 @@
 package agfs
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 func TestNew(t *testing.T) {
@@ -39,12 +47,10 @@ func TestNew(t *testing.T) {
 
 	for _, dir := range expectedDirs {
 		path := filepath.Join(tmpDir, dir)
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := agfs.fs.Stat(path); os.IsNotExist(err) {
 			t.Errorf("Expected directory %s to exist", dir)
 		}
 	}
-
-	_ = agfs // Use the variable
 }
 
 func TestURIToPath(t *testing.T) {
@@ -354,3 +360,848 @@ func TestFileTypeFromURI(t *testing.T) {
 		}
 	}
 }
+
+func TestReadContextDerivesSummaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{
+		RootPath:               tmpDir,
+		URIPrefix:              "viking://",
+		DeriveMissingSummaries: true,
+	}
+
+	agfs, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	leafURI := "viking://resources/notes.md"
+	content := "# Deployment notes\n\nDeploy with the blue-green\nstrategy behind the load balancer.\n\nRollback steps follow."
+	if err := agfs.Write(leafURI, []byte(content)); err != nil {
+		t.Fatalf("Failed to write leaf: %v", err)
+	}
+
+	ctx, err := agfs.ReadContext(leafURI)
+	if err != nil {
+		t.Fatalf("Failed to read context: %v", err)
+	}
+	want := "Deploy with the blue-green strategy behind the load balancer."
+	if ctx.Abstract != want {
+		t.Errorf("Abstract = %q; want %q", ctx.Abstract, want)
+	}
+	if ctx.Overview != want {
+		t.Errorf("Overview = %q; want %q", ctx.Overview, want)
+	}
+	if ctx.Content != content {
+		t.Errorf("Content = %q; want %q", ctx.Content, content)
+	}
+}
+
+func TestReadContextWithoutDerivation(t *testing.T) {
+	tmpDir := t.TempDir()
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	leafURI := "viking://resources/notes.md"
+	if err := agfs.Write(leafURI, []byte("Only content here.")); err != nil {
+		t.Fatalf("Failed to write leaf: %v", err)
+	}
+
+	ctx, err := agfs.ReadContext(leafURI)
+	if err != nil {
+		t.Fatalf("Failed to read context: %v", err)
+	}
+	if ctx.Abstract != "" || ctx.Overview != "" {
+		t.Errorf("Expected no derived summaries, got abstract %q overview %q", ctx.Abstract, ctx.Overview)
+	}
+}
+
+func TestDeriveAbstract(t *testing.T) {
+	long := ""
+	for i := 0; i < 50; i++ {
+		long += "word "
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"heading only", "# Title", "Title"},
+		{"headings only", "## Guide\n\n### Setup", "Guide"},
+		{"heading then paragraph", "# Title\n\nBody text.", "Body text."},
+		{"first paragraph", "First para.\n\nSecond para.", "First para."},
+		{"truncated", long, long[:199] + "..."},
+	}
+
+	for _, tt := range tests {
+		if got := deriveAbstract(tt.text); got != tt.want {
+			t.Errorf("%s: deriveAbstract = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteContextTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	root := "viking://resources/kb"
+	entries := []ContextFile{
+		{URI: "", Abstract: "Knowledge base", Overview: "All team docs"},
+		{URI: "guides", Abstract: "Guides", Overview: "How-to guides"},
+		{URI: "guides/deploy", Abstract: "Deploy guide", Overview: "Deploying services", Content: "Run make deploy.", IsLeaf: true},
+		{URI: "viking://resources/kb/faq", Abstract: "FAQ", Content: "Ask in chat."},
+	}
+	if err := agfs.WriteContextTree(root, entries); err != nil {
+		t.Fatalf("Failed to write context tree: %v", err)
+	}
+
+	for _, tt := range []struct {
+		uri      string
+		abstract string
+		overview string
+		content  string
+	}{
+		{root, "Knowledge base", "All team docs", ""},
+		{root + "/guides", "Guides", "How-to guides", ""},
+		{root + "/guides/deploy", "Deploy guide", "Deploying services", "Run make deploy."},
+		{root + "/faq", "FAQ", "", "Ask in chat."},
+	} {
+		ctx, err := agfs.ReadContext(tt.uri)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.uri, err)
+		}
+		if ctx.Abstract != tt.abstract || ctx.Overview != tt.overview || ctx.Content != tt.content {
+			t.Errorf("%s = (%q, %q, %q); want (%q, %q, %q)", tt.uri,
+				ctx.Abstract, ctx.Overview, ctx.Content, tt.abstract, tt.overview, tt.content)
+		}
+	}
+}
+
+func TestWriteContextTreeRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	root := "viking://resources/kb"
+	if err := agfs.WriteContext(root, "Original", "", "", false); err != nil {
+		t.Fatalf("Failed to write context: %v", err)
+	}
+	// A regular file where a directory is needed makes the last entry fail
+	if err := agfs.Write(root+"/blocker", []byte("not a directory")); err != nil {
+		t.Fatalf("Failed to write blocker: %v", err)
+	}
+
+	entries := []ContextFile{
+		{URI: "", Abstract: "Replaced"},
+		{URI: "guides/deploy", Abstract: "Deploy guide"},
+		{URI: "blocker/child", Abstract: "Unreachable"},
+	}
+	if err := agfs.WriteContextTree(root, entries); err == nil {
+		t.Fatal("Expected error writing below a regular file")
+	}
+
+	if abs, _ := agfs.ReadAbstract(root); abs != "Original" {
+		t.Errorf("Abstract = %q; want restored %q", abs, "Original")
+	}
+	if _, err := agfs.fs.Stat(filepath.Join(tmpDir, "resources", "kb", "guides")); !os.IsNotExist(err) {
+		t.Errorf("Expected created directories to be removed, got %v", err)
+	}
+}
+
+func TestWriteContextTreeOutsideRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+
+	entries := []ContextFile{
+		{URI: "ok", Abstract: "Fine"},
+		{URI: "viking://resources/other", Abstract: "Elsewhere"},
+	}
+	if err := agfs.WriteContextTree("viking://resources/kb", entries); err == nil {
+		t.Fatal("Expected error for entry outside root")
+	}
+	if _, err := agfs.fs.Stat(filepath.Join(tmpDir, "resources", "kb")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written, got %v", err)
+	}
+}
+
+// statCountingFS counts the Stat calls made through it.
+type statCountingFS struct {
+	FileSystem
+	calls int
+}
+
+func (f *statCountingFS) Stat(name string) (os.FileInfo, error) {
+	f.calls++
+	return f.FileSystem.Stat(name)
+}
+
+func TestStatCacheInvalidatedByWrites(t *testing.T) {
+	tmpDir := t.TempDir()
+	counter := &statCountingFS{FileSystem: newFileSystem()}
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://", StatCacheTTL: time.Hour, FileSystem: counter})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	counter.calls = 0
+
+	uri := "viking://resources/cached.txt"
+	if agfs.Exists(uri) {
+		t.Fatal("File should not exist yet")
+	}
+	if agfs.IsDir(uri) {
+		t.Error("Missing file should not be a directory")
+	}
+	if counter.calls != 1 {
+		t.Errorf("Expected 1 stat for repeated lookups, got %d", counter.calls)
+	}
+
+	// A write must not leave the cached not-found result behind
+	if err := agfs.Write(uri, []byte("hello")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if !agfs.Exists(uri) {
+		t.Error("File should exist after write")
+	}
+	entry, err := agfs.Stat(uri)
+	if err != nil || entry.Size != 5 {
+		t.Errorf("Expected size 5 after write, got %v (err %v)", entry, err)
+	}
+
+	if err := agfs.Write(uri, []byte("hello world")); err != nil {
+		t.Fatalf("Failed to rewrite: %v", err)
+	}
+	if entry, _ := agfs.Stat(uri); entry == nil || entry.Size != 11 {
+		t.Errorf("Expected size 11 after rewrite, got %v", entry)
+	}
+
+	if err := agfs.Move(uri, "viking://resources/moved.txt"); err != nil {
+		t.Fatalf("Failed to move: %v", err)
+	}
+	if agfs.Exists(uri) {
+		t.Error("File should not exist at old URI after move")
+	}
+	if !agfs.Exists("viking://resources/moved.txt") {
+		t.Error("File should exist at new URI after move")
+	}
+
+	if err := agfs.Delete("viking://resources/moved.txt", false); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if agfs.Exists("viking://resources/moved.txt") {
+		t.Error("File should not exist after delete")
+	}
+}
+
+func TestStatCacheExpires(t *testing.T) {
+	tmpDir := t.TempDir()
+	agfs, err := New(Config{RootPath: tmpDir, URIPrefix: "viking://", StatCacheTTL: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	clock := utils.NewFakeClock(time.Now())
+	agfs.statCache.clock = clock
+
+	uri := "viking://resources/external.txt"
+	if agfs.Exists(uri) {
+		t.Fatal("File should not exist yet")
+	}
+
+	// Changes made outside AGFS show up once the entry expires
+	if err := agfs.fs.WriteFile(filepath.Join(tmpDir, "resources", "external.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if agfs.Exists(uri) {
+		t.Error("Expected cached not-found result within the TTL")
+	}
+	clock.Advance(time.Second)
+	if !agfs.Exists(uri) {
+		t.Error("Expected fresh result after the TTL")
+	}
+}
+
+func TestStatCacheEvictsLeastRecent(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://", StatCacheTTL: time.Hour, StatCacheEntries: 2})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	for _, name := range []string{"a", "b", "a", "c"} {
+		agfs.Exists("viking://resources/" + name)
+	}
+	if n := agfs.statCache.len(); n != 2 {
+		t.Errorf("Expected 2 cached paths, got %d", n)
+	}
+	for name, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		path := agfs.URIToPath("viking://resources/" + name)
+		if _, ok := agfs.statCache.entries.Get(path, time.Now()); ok != want {
+			t.Errorf("Expected %s cached to be %v", name, want)
+		}
+	}
+}
+
+func BenchmarkStatLookups(b *testing.B) {
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		name := "uncached"
+		if ttl > 0 {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			counter := &statCountingFS{FileSystem: newFileSystem()}
+			agfs, err := New(Config{RootPath: b.TempDir(), URIPrefix: "viking://", StatCacheTTL: ttl, FileSystem: counter})
+			if err != nil {
+				b.Fatalf("Failed to create AGFS: %v", err)
+			}
+			uri := "viking://resources/bench.txt"
+			if err := agfs.Write(uri, []byte("bench")); err != nil {
+				b.Fatalf("Failed to write: %v", err)
+			}
+			counter.calls = 0
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				agfs.Exists(uri)
+				agfs.IsDir(uri)
+				agfs.Stat(uri)
+			}
+			b.ReportMetric(float64(counter.calls)/float64(b.N), "stats/op")
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	if err := agfs.WriteContext("viking://resources/doc", "abstract", "overview", "content", true); err != nil {
+		t.Fatalf("Failed to write context: %v", err)
+	}
+	if err := agfs.Write("viking://agent/skills/search.md", []byte("skill")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	files, err := agfs.Files("viking://")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+
+	var uris []string
+	for _, f := range files {
+		uris = append(uris, f.URI)
+	}
+	expected := []string{"viking://agent/skills/search.md", "viking://resources/doc/content.md"}
+	if len(uris) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, uris)
+	}
+	for i := range expected {
+		if uris[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], uris[i])
+		}
+	}
+
+	if _, err := agfs.Files("viking://missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTreeTruncatesWideTrees(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://", TreeMaxEntries: 5})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		uri := fmt.Sprintf("viking://resources/wide/f%02d.txt", i)
+		if err := agfs.Write(uri, []byte("x")); err != nil {
+			t.Fatalf("Failed to write %s: %v", uri, err)
+		}
+	}
+
+	tree, err := agfs.Tree("viking://resources/wide", 1)
+	if err != nil {
+		t.Fatalf("Failed to get tree: %v", err)
+	}
+	if len(tree) != 6 {
+		t.Fatalf("Expected 5 entries and a marker, got %d", len(tree))
+	}
+	for _, entry := range tree[:5] {
+		if entry.Truncated {
+			t.Errorf("Expected %s not marked truncated", entry.Name)
+		}
+	}
+	if last := tree[5]; !last.Truncated || last.Name != TruncatedEntryName {
+		t.Errorf("Expected a truncated marker last, got %+v", last)
+	}
+
+	// The cap counts nested entries too
+	tree, err = agfs.Tree("viking://resources", 2)
+	if err != nil {
+		t.Fatalf("Failed to get tree: %v", err)
+	}
+	if len(tree) != 1 || len(tree[0].Children) != 5 || !tree[0].Children[4].Truncated {
+		t.Errorf("Expected wide with 4 children and a marker, got %+v", tree)
+	}
+}
+
+func TestTreeDepthLimits(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://", TreeMaxDepth: 3})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	if err := agfs.Mkdir("viking://resources/a/b/c/d/e", 0755, false); err != nil {
+		t.Fatalf("Failed to mkdir: %v", err)
+	}
+
+	depth := func(entries []TreeEntry) int {
+		n := 0
+		for len(entries) == 1 {
+			n++
+			if len(entries[0].Children) == 0 {
+				break
+			}
+			next := make([]TreeEntry, 0, len(entries[0].Children))
+			for _, child := range entries[0].Children {
+				next = append(next, *child)
+			}
+			entries = next
+		}
+		return n
+	}
+
+	for _, tt := range []struct {
+		maxDepth int
+		want     int
+	}{
+		{2, 2},
+		{0, 3},
+		{-1, 3},
+		{10, 5},
+	} {
+		tree, err := agfs.Tree("viking://resources", tt.maxDepth)
+		if err != nil {
+			t.Fatalf("Failed to get tree: %v", err)
+		}
+		if got := depth(tree); got != tt.want {
+			t.Errorf("Expected maxDepth %d to return %d levels, got %d", tt.maxDepth, tt.want, got)
+		}
+	}
+}
+
+func TestGrepRegex(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	files := map[string]string{
+		"viking://resources/code/main.go":  "package main\n\nfunc Run(x int) {}\nfunc helper() {}\n// func in a comment\n",
+		"viking://resources/code/notes.md": "TODO: tidy up\nFIXME later\nnothing here\n",
+	}
+	for uri, content := range files {
+		if err := agfs.Write(uri, []byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", uri, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name            string
+		pattern         string
+		caseInsensitive bool
+		want            []string
+	}{
+		{"capture", `func \w+\(`, false, []string{"main.go:3:func Run(", "main.go:4:func helper("}},
+		{"alternation", `TODO|FIXME`, false, []string{"notes.md:1:TODO", "notes.md:2:FIXME"}},
+		{"anchors", `^func`, false, []string{"main.go:3:func", "main.go:4:func"}},
+		{"end anchor", `\(\) \{\}$`, false, []string{"main.go:4:() {}"}},
+		{"case sensitive", `todo`, false, nil},
+		{"case insensitive", `todo|fixme`, true, []string{"notes.md:1:TODO", "notes.md:2:FIXME"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := agfs.GrepRegex("viking://resources/code", tt.pattern, tt.caseInsensitive)
+			if err != nil {
+				t.Fatalf("GrepRegex failed: %v", err)
+			}
+			var got []string
+			for _, m := range matches {
+				got = append(got, fmt.Sprintf("%s:%d:%s", filepath.Base(m.URI), m.Line, m.Match))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := agfs.GrepRegex("viking://resources/code", `func (`, false); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected ErrInvalidPattern for a bad regex, got %v", err)
+	}
+
+	// Substring search keeps the original line when ignoring case
+	matches, err := agfs.Grep("viking://resources/code", "fixme", true)
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Content != "FIXME later" || matches[0].Match != "" {
+		t.Errorf("Expected the FIXME line unchanged, got %+v", matches)
+	}
+}
+
+func TestGrepAndLink(t *testing.T) {
+	client, err := NewClient(Config{RootPath: t.TempDir(), URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	files := map[string]string{
+		"viking://resources/docs/auth.md":      "login flow\ntoken refresh\nthe token expires\n",
+		"viking://resources/docs/api/users.md": "GET /users needs a token\n",
+		"viking://resources/docs/intro.md":     "welcome\n",
+	}
+	for uri, content := range files {
+		if err := client.AGFS().Write(uri, []byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", uri, err)
+		}
+	}
+
+	linked, err := client.GrepAndLink("viking://resources/docs", "token", "mentions tokens")
+	if err != nil {
+		t.Fatalf("GrepAndLink failed: %v", err)
+	}
+	sort.Strings(linked)
+	want := []string{"viking://resources/docs/api/users.md", "viking://resources/docs/auth.md"}
+	if !reflect.DeepEqual(linked, want) {
+		t.Errorf("Expected %v linked, got %v", want, linked)
+	}
+	links, err := client.GetLinks("viking://resources/docs")
+	if err != nil {
+		t.Fatalf("GetLinks failed: %v", err)
+	}
+	if len(links) != 1 || links[0].Reason != "mentions tokens" || len(links[0].URIs) != 2 {
+		t.Errorf("Expected one relation to both files, got %+v", links)
+	}
+
+	// Re-running links nothing new
+	linked, err = client.GrepAndLink("viking://resources/docs", "token", "mentions tokens")
+	if err != nil {
+		t.Fatalf("GrepAndLink failed: %v", err)
+	}
+	if len(linked) != 0 {
+		t.Errorf("Expected no new links, got %v", linked)
+	}
+
+	// Only files not linked yet are added
+	linked, err = client.GrepAndLink("viking://resources/docs", "e", "contains e")
+	if err != nil {
+		t.Fatalf("GrepAndLink failed: %v", err)
+	}
+	if len(linked) != 1 || linked[0] != "viking://resources/docs/intro.md" {
+		t.Errorf("Expected only intro.md newly linked, got %v", linked)
+	}
+	uris, err := client.GetLinkedURIs("viking://resources/docs")
+	if err != nil {
+		t.Fatalf("GetLinkedURIs failed: %v", err)
+	}
+	if len(uris) != 3 {
+		t.Errorf("Expected 3 distinct linked URIs, got %v", uris)
+	}
+
+	if _, err := client.GrepAndLink("viking://resources/docs/intro.md", "e", ""); !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("Expected ErrNotADirectory for a file, got %v", err)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	for _, name := range []string{
+		"main.go", "README.md", "file1.txt", "file2.txt", "file10.txt",
+		"docs/guide.md", "docs/api/index.md", "pkg/util.go",
+	} {
+		if err := agfs.Write("viking://resources/proj/"+name, []byte("x")); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"main.go"}},
+		{"**/*.go", []string{"main.go", "pkg/util.go"}},
+		{"**/*.md", []string{"README.md", "docs/api/index.md", "docs/guide.md"}},
+		{"docs/*", []string{"docs/api", "docs/guide.md"}},
+		{"docs/**", []string{"docs", "docs/api", "docs/api/index.md", "docs/guide.md"}},
+		{"file?.txt", []string{"file1.txt", "file2.txt"}},
+		{"file[2-9]*.txt", []string{"file2.txt"}},
+		{"nothing/*", nil},
+	} {
+		uris, err := agfs.Glob("viking://resources/proj", tt.pattern)
+		if err != nil {
+			t.Fatalf("Glob(%q) failed: %v", tt.pattern, err)
+		}
+		var got []string
+		for _, uri := range uris {
+			got = append(got, strings.TrimPrefix(uri, "viking://resources/proj/"))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expected %q to match %v, got %v", tt.pattern, tt.want, got)
+		}
+	}
+
+	if _, err := agfs.Glob("viking://resources/proj", "[a-"); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected ErrInvalidPattern for a bad pattern, got %v", err)
+	}
+}
+
+func TestChecksumVerify(t *testing.T) {
+	dir := t.TempDir()
+	client, err := NewClient(Config{RootPath: dir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	agfs := client.AGFS()
+	uri := "viking://resources/docs/guide.md"
+	if err := agfs.Write(uri, []byte("hello")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	// SHA-256 of "hello"
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if sum, err := client.FileChecksum(uri); err != nil || sum != want {
+		t.Errorf("Expected checksum %s, got %s (%v)", want, sum, err)
+	}
+	if sum, ok := agfs.StoredChecksum(uri); !ok || sum != want {
+		t.Errorf("Expected stored checksum %s, got %s", want, sum)
+	}
+	if err := client.VerifyFile(uri, ""); err != nil {
+		t.Errorf("Expected the file to verify, got %v", err)
+	}
+	if err := client.VerifyFile(uri, strings.ToUpper(want)); err != nil {
+		t.Errorf("Expected an uppercase checksum to verify, got %v", err)
+	}
+
+	// Corrupt the file behind AGFS's back
+	if err := os.WriteFile(filepath.Join(dir, "resources", "docs", "guide.md"), []byte("hellO"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt file: %v", err)
+	}
+	if err := client.VerifyFile(uri, ""); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch after corruption, got %v", err)
+	}
+	if err := client.VerifyFile(uri, want); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch against the expected checksum, got %v", err)
+	}
+
+	// Stored checksums survive a restart
+	if err := agfs.Write(uri, []byte("hello")); err != nil {
+		t.Fatalf("Failed to rewrite: %v", err)
+	}
+	reopened, err := New(Config{RootPath: dir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to reopen AGFS: %v", err)
+	}
+	if sum, ok := reopened.StoredChecksum(uri); !ok || sum != want {
+		t.Errorf("Expected stored checksum %s after reopening, got %s", want, sum)
+	}
+
+	// Moves carry the checksum along, deletes drop it
+	if err := agfs.Move(uri, "viking://resources/docs/renamed.md"); err != nil {
+		t.Fatalf("Failed to move: %v", err)
+	}
+	if err := client.VerifyFile("viking://resources/docs/renamed.md", ""); err != nil {
+		t.Errorf("Expected the renamed file to verify, got %v", err)
+	}
+	if _, ok := agfs.StoredChecksum(uri); ok {
+		t.Error("Expected no checksum left at the old name")
+	}
+	if err := agfs.Move("viking://resources/docs/renamed.md", uri); err != nil {
+		t.Fatalf("Failed to move back: %v", err)
+	}
+	if err := agfs.Move("viking://resources/docs", "viking://resources/moved"); err != nil {
+		t.Fatalf("Failed to move: %v", err)
+	}
+	if err := client.VerifyFile("viking://resources/moved/guide.md", ""); err != nil {
+		t.Errorf("Expected the moved file to verify, got %v", err)
+	}
+	if err := agfs.Delete("viking://resources/moved", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, ok := agfs.StoredChecksum("viking://resources/moved/guide.md"); ok {
+		t.Error("Expected the checksum dropped with the file")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "resources", "untracked.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := client.VerifyFile("viking://resources/untracked.txt", ""); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Expected ErrNoChecksum for a file written outside AGFS, got %v", err)
+	}
+}
+
+func TestChecksumsSkipVersions(t *testing.T) {
+	dir := t.TempDir()
+	agfs, err := New(Config{RootPath: dir, URIPrefix: "viking://", Versioning: true})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	uri := "viking://resources/guide.md"
+	for _, data := range []string{"v1", "v2"} {
+		if err := agfs.Write(uri, []byte(data)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	versions, err := agfs.ListVersions(uri)
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("Expected one version, got %v (%v)", versions, err)
+	}
+	sums, err := filepath.Glob(filepath.Join(dir, "resources", versionsDir, "guide.md", checksumsDir, "*"))
+	if err != nil {
+		t.Fatalf("Failed to glob: %v", err)
+	}
+	if len(sums) != 0 {
+		t.Errorf("Expected no checksums kept for versions, got %v", sums)
+	}
+	if err := agfs.Verify(uri, ""); err != nil {
+		t.Errorf("Expected the current file to verify, got %v", err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://", Versioning: true, MaxVersions: 3})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	agfs.SetClock(clock)
+
+	uri := "viking://resources/notes.md"
+	for _, content := range []string{"v1", "v2", "v3"} {
+		if err := agfs.Write(uri, []byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", content, err)
+		}
+		clock.Advance(time.Second)
+	}
+
+	versions, err := agfs.ListVersions(uri)
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].Name != "20260101T000001.000000000Z" {
+		t.Errorf("Expected the oldest version first, got %s", versions[0].Name)
+	}
+	data, err := agfs.ReadVersion(uri, versions[0].Name)
+	if err != nil || string(data) != "v1" {
+		t.Errorf("Expected v1, got %q (%v)", data, err)
+	}
+
+	// Reverting keeps the replaced contents as a version too
+	if err := agfs.Revert(uri, versions[0].Name); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if data, _ := agfs.Read(uri, 0, 0); string(data) != "v1" {
+		t.Errorf("Expected v1 after revert, got %q", data)
+	}
+	versions, _ = agfs.ListVersions(uri)
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions after revert, got %d", len(versions))
+	}
+	if data, _ := agfs.ReadVersion(uri, versions[2].Name); string(data) != "v3" {
+		t.Errorf("Expected v3 as the newest version, got %q", data)
+	}
+
+	// MaxVersions prunes the oldest
+	clock.Advance(time.Second)
+	if err := agfs.Write(uri, []byte("v4")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	versions, _ = agfs.ListVersions(uri)
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions after pruning, got %d", len(versions))
+	}
+	if data, _ := agfs.ReadVersion(uri, versions[0].Name); string(data) != "v2" {
+		t.Errorf("Expected v1 pruned, got oldest %q", data)
+	}
+
+	if _, err := agfs.ReadVersion(uri, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing version, got %v", err)
+	}
+	if _, err := agfs.ReadVersion(uri, "../notes.md"); !errors.Is(err, ErrInvalidURI) {
+		t.Errorf("Expected ErrInvalidURI for a version outside the file's versions, got %v", err)
+	}
+
+	// Versions stay out of listings and searches
+	entries, err := agfs.List("viking://resources", false)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the file listed, got %d entries", len(entries))
+	}
+	matches, err := agfs.Grep("viking://resources", "v2", false)
+	if err != nil || len(matches) != 0 {
+		t.Errorf("Expected no grep matches in versions, got %v (%v)", matches, err)
+	}
+}
+
+func TestVersionsFollowFile(t *testing.T) {
+	root := t.TempDir()
+	agfs, err := New(Config{RootPath: root, URIPrefix: "viking://", Versioning: true})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := agfs.Write("viking://resources/a.md", []byte(content)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	if err := agfs.Move("viking://resources/a.md", "viking://resources/b.md"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if versions, _ := agfs.ListVersions("viking://resources/a.md"); len(versions) != 0 {
+		t.Errorf("Expected no versions left at the old name, got %d", len(versions))
+	}
+	versions, err := agfs.ListVersions("viking://resources/b.md")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("Expected the version moved with the file, got %d (%v)", len(versions), err)
+	}
+	if data, _ := agfs.ReadVersion("viking://resources/b.md", versions[0].Name); string(data) != "v1" {
+		t.Errorf("Expected v1, got %q", data)
+	}
+
+	if err := agfs.Delete("viking://resources/b.md", false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := agfs.fs.Stat(filepath.Join(root, "resources", versionsDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected the versions deleted with the file, got %v", err)
+	}
+}
+
+func TestVersioningDisabled(t *testing.T) {
+	agfs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	uri := "viking://resources/notes.md"
+	for _, content := range []string{"v1", "v2"} {
+		if err := agfs.Write(uri, []byte(content)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	versions, err := agfs.ListVersions(uri)
+	if err != nil || len(versions) != 0 {
+		t.Errorf("Expected no versions, got %d (%v)", len(versions), err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// fileChecksum returns the hex SHA-256 of data, the format stored in the
+// files table.
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Checksum returns the hex SHA-256 of the file at the given URI as it is
+// now.
+func (a *AGFS) Checksum(uri string) (string, error) {
+	data, err := a.Read(uri, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return fileChecksum(data), nil
+}
+
+// StoredChecksum returns the checksum recorded when the file at the given
+// URI was last written through AGFS, if any.
+func (a *AGFS) StoredChecksum(uri string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	path := a.URIToPath(a.normalizeURI(uri))
+	if path == "" {
+		return "", false
+	}
+	data, err := a.fs.ReadFile(checksumPath(path))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// Verify checks the file at the given URI against expected, or against its
+// stored checksum when expected is empty. It returns ErrChecksumMismatch
+// when the contents differ and ErrNoChecksum when there is nothing to
+// compare against.
+func (a *AGFS) Verify(uri, expected string) error {
+	if expected == "" {
+		sum, ok := a.StoredChecksum(uri)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNoChecksum, uri)
+		}
+		expected = sum
+	}
+
+	actual, err := a.Checksum(uri)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w: %s: expected %s, got %s", ErrChecksumMismatch, uri, expected, actual)
+	}
+	return nil
+}
+
+// checksumsDir is the hidden directory, next to a file written through
+// AGFS, holding one checksum file per file name.
+const checksumsDir = ".checksums"
+
+// checksumPath returns the path of the checksum kept for the file at path.
+func checksumPath(path string) string {
+	return filepath.Join(filepath.Dir(path), checksumsDir, filepath.Base(path))
+}
+
+// isVersionFile reports whether path is a kept version of another file,
+// which is never rewritten and so needs no checksum of its own.
+func isVersionFile(path string) bool {
+	return filepath.Base(filepath.Dir(filepath.Dir(path))) == versionsDir
+}
+
+// writeFile writes data to path atomically and records its checksum next
+// to it, so the checksum survives restarts.
+func (a *AGFS) writeFile(path string, data []byte) error {
+	if err := writeFileAtomic(a.fs, path, data, 0644); err != nil {
+		return err
+	}
+	if isVersionFile(path) {
+		return nil
+	}
+
+	sumPath := checksumPath(path)
+	if err := a.fs.MkdirAll(filepath.Dir(sumPath), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(a.fs, sumPath, []byte(fileChecksum(data)), 0644)
+}
+
+// forgetChecksums drops the checksum of the file at path. Checksums of
+// files below a removed directory went with it.
+func (a *AGFS) forgetChecksums(path string) {
+	sumPath := checksumPath(path)
+	if err := a.fs.Remove(sumPath); err != nil {
+		return
+	}
+	// Drop the checksums directory once it is empty
+	a.fs.Remove(filepath.Dir(sumPath))
+}
+
+// moveChecksums moves the checksum of the file at oldPath to newPath.
+// Checksums of files below a moved directory moved with it.
+func (a *AGFS) moveChecksums(oldPath, newPath string) {
+	oldSum := checksumPath(oldPath)
+	if _, err := a.fs.Stat(oldSum); err != nil {
+		return
+	}
+	newSum := checksumPath(newPath)
+	if err := a.fs.MkdirAll(filepath.Dir(newSum), 0755); err != nil {
+		return
+	}
+	if err := a.fs.Rename(oldSum, newSum); err != nil {
+		return
+	}
+	a.fs.Remove(filepath.Dir(oldSum))
+}
@@ -5,7 +5,7 @@ package agfs
 
 import (
 	"encoding/json"
-	"os"
+	"time"
 )
 
 // Client provides a high-level API for the AGFS.
@@ -44,7 +44,7 @@ func (c *Client) Close() error {
 
 // Ping checks if the filesystem is accessible.
 func (c *Client) Ping() error {
-	_, err := os.Stat(c.agfs.rootPath)
+	_, err := c.agfs.fs.Stat(c.agfs.rootPath)
 	return err
 }
 
@@ -102,6 +102,22 @@ func (c *Client) GetTree(uri string, maxDepth int) ([]TreeEntry, error) {
 	return c.agfs.Tree(uri, maxDepth)
 }
 
+// FileChecksum returns the SHA-256 of a file's contents.
+func (c *Client) FileChecksum(uri string) (string, error) {
+	return c.agfs.Checksum(uri)
+}
+
+// VerifyFile checks a file against expected, or against the checksum
+// stored when it was written if expected is empty.
+func (c *Client) VerifyFile(uri, expected string) error {
+	return c.agfs.Verify(uri, expected)
+}
+
+// TreeModTime returns the newest modification time within the tree.
+func (c *Client) TreeModTime(uri string, maxDepth int) (time.Time, error) {
+	return c.agfs.TreeModTime(uri, maxDepth)
+}
+
 // CreateDir creates a new directory.
 func (c *Client) CreateDir(uri string) error {
 	return c.agfs.Mkdir(uri, 0755, false)
@@ -182,6 +198,11 @@ func (c *Client) SetContext(uri, abstract, overview, content string, isLeaf bool
 	return c.agfs.WriteContext(uri, abstract, overview, content, isLeaf)
 }
 
+// SetContextTree writes the context levels of many nodes below root at once.
+func (c *Client) SetContextTree(root string, entries []ContextFile) error {
+	return c.agfs.WriteContextTree(root, entries)
+}
+
 // Search performs a grep search in a directory.
 func (c *Client) Search(uri, pattern string) ([]GrepMatch, error) {
 	return c.agfs.Grep(uri, pattern, false)
@@ -192,6 +213,11 @@ func (c *Client) SearchCI(uri, pattern string) ([]GrepMatch, error) {
 	return c.agfs.Grep(uri, pattern, true)
 }
 
+// SearchRegex performs a regular expression search in a directory.
+func (c *Client) SearchRegex(uri, pattern string, caseInsensitive bool) ([]GrepMatch, error) {
+	return c.agfs.GrepRegex(uri, pattern, caseInsensitive)
+}
+
 // Glob performs pattern matching on files.
 func (c *Client) Glob(uri, pattern string) ([]string, error) {
 	return c.agfs.Glob(uri, pattern)
@@ -202,6 +228,12 @@ func (c *Client) Link(fromURI string, uris []string, reason string) error {
 	return c.relations.Link(fromURI, uris, reason)
 }
 
+// GrepAndLink links the directory at uri to the files below it matching
+// pattern, skipping files already linked, and returns the new links.
+func (c *Client) GrepAndLink(uri, pattern, reason string) ([]string, error) {
+	return c.relations.GrepAndLink(uri, pattern, reason)
+}
+
 // Unlink removes a relation between directories.
 func (c *Client) Unlink(fromURI, targetURI string) error {
 	return c.relations.Unlink(fromURI, targetURI)
@@ -4,8 +4,11 @@
 package agfs
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -33,6 +36,10 @@ func (a *AGFS) WriteContext(uri, abstract, overview, content string, isLeaf bool
 		return err
 	}
 
+	if abstract == "" {
+		abstract = a.codeAbstract(uri, content)
+	}
+
 	// Write abstract file
 	if abstract != "" {
 		abstractPath := filepath.Join(path, ".abstract.md")
@@ -60,8 +67,156 @@ func (a *AGFS) WriteContext(uri, abstract, overview, content string, isLeaf bool
 	return nil
 }
 
+// WriteContextTree writes many context nodes below root under a single lock.
+// Entry URIs are either relative to root or full URIs inside root; an empty
+// URI addresses root itself. Every entry is written as a directory holding
+// its abstract, overview and content files, as WriteContext does. If any
+// write fails, directories and files created by the call are removed and
+// overwritten files are restored.
+func (a *AGFS) WriteContextTree(root string, entries []ContextFile) error {
+	defer a.statCache.invalidate()
+
+	root = strings.TrimSuffix(a.normalizeURI(root), "/")
+	rootPath := a.URIToPath(root)
+	if rootPath == "" {
+		return ErrInvalidURI
+	}
+
+	// Resolve every path before touching the filesystem, filling in code
+	// abstracts on a copy so the caller's entries are left alone
+	entries = append([]ContextFile(nil), entries...)
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		uri := entry.URI
+		if !strings.HasPrefix(uri, a.uriPrefix) {
+			uri = root + "/" + strings.TrimPrefix(uri, "/")
+		}
+		path := a.URIToPath(strings.TrimSuffix(uri, "/"))
+		if path == "" || (path != rootPath && !strings.HasPrefix(path, rootPath+string(filepath.Separator))) {
+			return fmt.Errorf("%w: %s is not inside %s", ErrInvalidURI, entry.URI, root)
+		}
+		paths[i] = path
+		if entry.Abstract == "" {
+			entries[i].Abstract = a.codeAbstract(uri, entry.Content)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tx := &treeWrite{agfs: a, fs: a.fs}
+	for i, entry := range entries {
+		if err := tx.writeEntry(paths[i], entry); err != nil {
+			tx.rollback()
+			return fmt.Errorf("failed to write context %s: %w", entry.URI, err)
+		}
+	}
+	return nil
+}
+
+// treeWrite records the changes made by WriteContextTree so they can be
+// undone.
+type treeWrite struct {
+	agfs        *AGFS
+	fs          FileSystem
+	createdDirs []string
+	files       []fileBackup
+}
+
+// fileBackup holds a file's contents from before it was overwritten.
+type fileBackup struct {
+	path    string
+	data    []byte
+	existed bool
+}
+
+// writeEntry writes the files of a single context node.
+func (tx *treeWrite) writeEntry(path string, entry ContextFile) error {
+	if err := tx.mkdirAll(path); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data string
+	}{
+		{".abstract.md", entry.Abstract},
+		{".overview.md", entry.Overview},
+		{"content.md", entry.Content},
+	}
+	for _, f := range files {
+		if f.data == "" {
+			continue
+		}
+		if err := tx.writeFile(filepath.Join(path, f.name), []byte(f.data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mkdirAll creates path and any missing parents, recording each directory
+// it creates.
+func (tx *treeWrite) mkdirAll(path string) error {
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := tx.fs.Stat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	if err := tx.fs.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		tx.createdDirs = append(tx.createdDirs, missing[i])
+	}
+	return nil
+}
+
+// writeFile writes a file, first saving any previous contents.
+func (tx *treeWrite) writeFile(path string, data []byte) error {
+	backup := fileBackup{path: path}
+	if old, err := tx.fs.ReadFile(path); err == nil {
+		backup.data = old
+		backup.existed = true
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Record the backup first: a failed write may still have replaced the
+	// file before its checksum failed
+	tx.files = append(tx.files, backup)
+	return tx.agfs.writeFile(path, data)
+}
+
+// rollback restores overwritten files, then removes created files and
+// directories, newest first.
+func (tx *treeWrite) rollback() {
+	for i := len(tx.files) - 1; i >= 0; i-- {
+		f := tx.files[i]
+		if f.existed {
+			tx.agfs.writeFile(f.path, f.data)
+		} else {
+			tx.fs.Remove(f.path)
+			tx.agfs.forgetChecksums(f.path)
+		}
+	}
+	for i := len(tx.createdDirs) - 1; i >= 0; i-- {
+		tx.fs.Remove(tx.createdDirs[i])
+	}
+}
+
 // WriteFile is an alias for Write that takes a filesystem path.
 func (a *AGFS) WriteFile(path string, data []byte) error {
+	defer a.statCache.invalidate()
+
 	// Ensure parent directory exists
 	parent := filepath.Dir(path)
 	if err := a.Mkdir(a.PathToURI(parent), 0755, true); err != nil {
@@ -71,7 +226,7 @@ func (a *AGFS) WriteFile(path string, data []byte) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	return os.WriteFile(path, data, 0644)
+	return a.writeFile(path, data)
 }
 
 // ReadAbstract reads the abstract (L0) content of a directory.
@@ -83,7 +238,7 @@ func (a *AGFS) ReadAbstract(uri string) (string, error) {
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return "", err
 	}
@@ -92,7 +247,7 @@ func (a *AGFS) ReadAbstract(uri string) (string, error) {
 	}
 
 	abstractPath := filepath.Join(path, ".abstract.md")
-	data, err := os.ReadFile(abstractPath)
+	data, err := a.fs.ReadFile(abstractPath)
 	if err != nil {
 		return "", ErrNotFound
 	}
@@ -109,7 +264,7 @@ func (a *AGFS) ReadOverview(uri string) (string, error) {
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return "", err
 	}
@@ -118,7 +273,7 @@ func (a *AGFS) ReadOverview(uri string) (string, error) {
 	}
 
 	overviewPath := filepath.Join(path, ".overview.md")
-	data, err := os.ReadFile(overviewPath)
+	data, err := a.fs.ReadFile(overviewPath)
 	if err != nil {
 		return "", ErrNotFound
 	}
@@ -134,7 +289,7 @@ func (a *AGFS) ReadContent(uri string) (string, error) {
 		return "", ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return "", err
 	}
@@ -146,7 +301,7 @@ func (a *AGFS) ReadContent(uri string) (string, error) {
 		contentPath = path
 	}
 
-	data, err := os.ReadFile(contentPath)
+	data, err := a.fs.ReadFile(contentPath)
 	if err != nil {
 		return "", err
 	}
@@ -184,11 +339,85 @@ func (a *AGFS) ReadContext(uri string) (*ContextFile, error) {
 		ctx.Content = content
 	}
 
+	if a.config.DeriveMissingSummaries {
+		if strings.TrimSpace(ctx.Abstract) == "" {
+			ctx.Abstract = a.codeAbstract(uri, ctx.Content)
+		}
+		ctx.deriveMissingSummaries()
+	}
+
 	return ctx, nil
 }
 
+// codeAbstract summarises content by its declarations when code abstracts
+// are enabled and uri names a Go, Python or JavaScript file. It returns ""
+// otherwise.
+func (a *AGFS) codeAbstract(uri, content string) string {
+	if !a.config.Abstract.Code || strings.TrimSpace(content) == "" || DetectLanguage(uri, content) == "" {
+		return ""
+	}
+	return GenerateAbstract(uri, content, a.config.Abstract)
+}
+
+// maxDerivedAbstractLength caps the length, in runes, of a derived abstract.
+const maxDerivedAbstractLength = 200
+
+// deriveMissingSummaries fills an empty abstract from the first paragraph
+// of the overview or content, then an empty overview from the abstract.
+func (c *ContextFile) deriveMissingSummaries() {
+	if strings.TrimSpace(c.Abstract) == "" {
+		source := c.Overview
+		if strings.TrimSpace(source) == "" {
+			source = c.Content
+		}
+		c.Abstract = deriveAbstract(source)
+	}
+	if strings.TrimSpace(c.Overview) == "" {
+		c.Overview = c.Abstract
+	}
+}
+
+// deriveAbstract returns the first paragraph of text, skipping markdown
+// headings, with whitespace collapsed and truncated to
+// maxDerivedAbstractLength runes. Text made only of headings yields the
+// first heading without its "#" markers.
+func deriveAbstract(text string) string {
+	var paragraph string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if paragraph == "" {
+			paragraph = stripHeadingMarkers(p)
+		}
+		if !strings.HasPrefix(p, "#") {
+			paragraph = p
+			break
+		}
+	}
+
+	paragraph = strings.Join(strings.Fields(paragraph), " ")
+	if runes := []rune(paragraph); len(runes) > maxDerivedAbstractLength {
+		paragraph = strings.TrimSpace(string(runes[:maxDerivedAbstractLength])) + "..."
+	}
+	return paragraph
+}
+
+// stripHeadingMarkers removes the leading "#" markers from each line of a
+// markdown heading paragraph.
+func stripHeadingMarkers(p string) string {
+	lines := strings.Split(p, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimLeft(strings.TrimSpace(line), "#")
+	}
+	return strings.Join(lines, "\n")
+}
+
 // WriteAbstract writes the abstract (L0) content for a directory.
 func (a *AGFS) WriteAbstract(uri, abstract string) error {
+	defer a.statCache.invalidate()
+
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
@@ -201,11 +430,13 @@ func (a *AGFS) WriteAbstract(uri, abstract string) error {
 	}
 
 	abstractPath := filepath.Join(path, ".abstract.md")
-	return os.WriteFile(abstractPath, []byte(abstract), 0644)
+	return a.writeFile(abstractPath, []byte(abstract))
 }
 
 // WriteOverview writes the overview (L1) content for a directory.
 func (a *AGFS) WriteOverview(uri, overview string) error {
+	defer a.statCache.invalidate()
+
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
@@ -218,18 +449,20 @@ func (a *AGFS) WriteOverview(uri, overview string) error {
 	}
 
 	overviewPath := filepath.Join(path, ".overview.md")
-	return os.WriteFile(overviewPath, []byte(overview), 0644)
+	return a.writeFile(overviewPath, []byte(overview))
 }
 
 // WriteContent writes the content (L2) for a directory or file.
 func (a *AGFS) WriteContent(uri, content string) error {
+	defer a.statCache.invalidate()
+
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
 		return ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -245,18 +478,53 @@ func (a *AGFS) WriteContent(uri, content string) error {
 		contentPath = path
 	}
 
-	return os.WriteFile(contentPath, []byte(content), 0644)
+	return a.writeFile(contentPath, []byte(content))
 }
 
 // Grep searches for a pattern in files within a directory.
 func (a *AGFS) Grep(uri, pattern string, caseInsensitive bool) ([]GrepMatch, error) {
+	match := func(line string) (string, bool) {
+		return "", strings.Contains(line, pattern)
+	}
+	if caseInsensitive {
+		lower := strings.ToLower(pattern)
+		match = func(line string) (string, bool) {
+			return "", strings.Contains(strings.ToLower(line), lower)
+		}
+	}
+	return a.grep(uri, match)
+}
+
+// GrepRegex searches files within a directory for lines matching the
+// regular expression pattern, in RE2 syntax. Each match records the first
+// matched substring of its line.
+func (a *AGFS) GrepRegex(uri, pattern string, caseInsensitive bool) ([]GrepMatch, error) {
+	expr := pattern
+	if caseInsensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern, err)
+	}
+	return a.grep(uri, func(line string) (string, bool) {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			return "", false
+		}
+		return line[loc[0]:loc[1]], true
+	})
+}
+
+// grep searches the files within the directory at uri line by line.
+func (a *AGFS) grep(uri string, match lineMatcher) ([]GrepMatch, error) {
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +534,7 @@ func (a *AGFS) Grep(uri, pattern string, caseInsensitive bool) ([]GrepMatch, err
 	}
 
 	var matches []GrepMatch
-	err = a.grepRecursive(path, a.PathToURI(path), pattern, caseInsensitive, &matches)
+	err = a.grepRecursive(path, a.PathToURI(path), match, &matches)
 	if err != nil {
 		return nil, err
 	}
@@ -279,18 +547,26 @@ type GrepMatch struct {
 	URI     string `json:"uri"`
 	Line    int    `json:"line"`
 	Content string `json:"content"`
+	// Match is the matched substring; only GrepRegex sets it.
+	Match string `json:"match,omitempty"`
 }
 
+// lineMatcher reports whether a line matches, and the matched substring
+// if known.
+type lineMatcher func(line string) (string, bool)
+
 // grepRecursive recursively searches for a pattern.
-func (a *AGFS) grepRecursive(dirPath, dirURI, pattern string, caseInsensitive bool, matches *[]GrepMatch) error {
-	entries, err := os.ReadDir(dirPath)
+func (a *AGFS) grepRecursive(dirPath, dirURI string, match lineMatcher, matches *[]GrepMatch) error {
+	entries, err := a.fs.ReadDir(dirPath)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
 		name := entry.Name()
-		if name == "." || name == ".." {
+		// Skip file versions, which would repeat current matches, and
+		// checksums
+		if name == "." || name == ".." || name == versionsDir || name == checksumsDir {
 			continue
 		}
 
@@ -298,7 +574,7 @@ func (a *AGFS) grepRecursive(dirPath, dirURI, pattern string, caseInsensitive bo
 		entryURI := a.PathToURI(entryPath)
 
 		if entry.IsDir() {
-			if err := a.grepRecursive(entryPath, entryURI, pattern, caseInsensitive, matches); err != nil {
+			if err := a.grepRecursive(entryPath, entryURI, match, matches); err != nil {
 				return err
 			}
 		} else {
@@ -307,7 +583,7 @@ func (a *AGFS) grepRecursive(dirPath, dirURI, pattern string, caseInsensitive bo
 				continue
 			}
 
-			if err := a.grepFile(entryPath, entryURI, pattern, caseInsensitive, matches); err != nil {
+			if err := a.grepFile(entryPath, entryURI, match, matches); err != nil {
 				return err
 			}
 		}
@@ -317,25 +593,21 @@ func (a *AGFS) grepRecursive(dirPath, dirURI, pattern string, caseInsensitive bo
 }
 
 // grepFile searches for a pattern in a single file.
-func (a *AGFS) grepFile(filePath, fileURI, pattern string, caseInsensitive bool, matches *[]GrepMatch) error {
-	data, err := os.ReadFile(filePath)
+func (a *AGFS) grepFile(filePath, fileURI string, match lineMatcher, matches *[]GrepMatch) error {
+	data, err := a.fs.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	content := string(data)
-	if caseInsensitive {
-		content = strings.ToLower(content)
-		pattern = strings.ToLower(pattern)
-	}
-
-	lines := strings.Split(content, "\n")
+	lines := strings.Split(string(data), "\n")
 	for i, line := range lines {
-		if strings.Contains(line, pattern) {
+		line = strings.TrimRight(line, "\r")
+		if matched, ok := match(line); ok {
 			*matches = append(*matches, GrepMatch{
 				URI:     fileURI,
 				Line:    i + 1,
-				Content: strings.TrimRight(lines[i], "\r"),
+				Content: line,
+				Match:   matched,
 			})
 		}
 	}
@@ -343,15 +615,26 @@ func (a *AGFS) grepFile(filePath, fileURI, pattern string, caseInsensitive bool,
 	return nil
 }
 
-// Glob performs pattern matching on files in a directory.
+// Glob returns the URIs of the files and directories below uri whose path
+// relative to uri matches pattern. Patterns are slash-separated: within a
+// segment, * matches any run of characters, ? matches one character and
+// [...] matches a character class, as in path.Match; a ** segment matches
+// any number of segments, including none.
 func (a *AGFS) Glob(uri, pattern string) ([]string, error) {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for _, seg := range segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern, err)
+		}
+	}
+
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -361,7 +644,7 @@ func (a *AGFS) Glob(uri, pattern string) ([]string, error) {
 	}
 
 	var results []string
-	err = a.globRecursive(path, uri, pattern, &results)
+	err = a.globRecursive(path, "", segments, &results)
 	if err != nil {
 		return nil, err
 	}
@@ -369,29 +652,32 @@ func (a *AGFS) Glob(uri, pattern string) ([]string, error) {
 	return results, nil
 }
 
-// globRecursive recursively performs pattern matching.
-func (a *AGFS) globRecursive(dirPath, dirURI, pattern string, results *[]string) error {
-	entries, err := os.ReadDir(dirPath)
+// globRecursive recursively matches the entries below dirPath, whose path
+// relative to the search root is rel, against the pattern segments.
+func (a *AGFS) globRecursive(dirPath, rel string, segments []string, results *[]string) error {
+	entries, err := a.fs.ReadDir(dirPath)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
 		name := entry.Name()
-		if name == "." || name == ".." {
+		if name == "." || name == ".." || name == versionsDir || name == checksumsDir {
 			continue
 		}
 
 		entryPath := filepath.Join(dirPath, name)
-		entryURI := a.PathToURI(entryPath)
+		entryRel := name
+		if rel != "" {
+			entryRel = rel + "/" + name
+		}
 
-		// Simple pattern matching (supports * and ?)
-		if matchPattern(name, pattern) {
-			*results = append(*results, entryURI)
+		if matchGlob(segments, strings.Split(entryRel, "/")) {
+			*results = append(*results, a.PathToURI(entryPath))
 		}
 
 		if entry.IsDir() {
-			if err := a.globRecursive(entryPath, entryURI, pattern, results); err != nil {
+			if err := a.globRecursive(entryPath, entryRel, segments, results); err != nil {
 				return err
 			}
 		}
@@ -400,40 +686,44 @@ func (a *AGFS) globRecursive(dirPath, dirURI, pattern string, results *[]string)
 	return nil
 }
 
-// matchPattern performs simple glob pattern matching.
-func matchPattern(name, pattern string) bool {
-	// Simple implementation - just support * wildcard
-	parts := strings.Split(pattern, "*")
-	if len(parts) == 1 {
-		return name == pattern
-	}
-
-	idx := 0
-	for _, part := range parts {
-		if part == "" {
-			continue
+// matchGlob reports whether the path segments match the pattern segments.
+// The pattern segments must be valid path.Match patterns.
+func matchGlob(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Let ** absorb each possible number of segments
+			for i := 0; i <= len(segments); i++ {
+				if matchGlob(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
 		}
-		pos := strings.Index(name[idx:], part)
-		if pos == -1 {
+		if len(segments) == 0 {
 			return false
 		}
-		idx += pos + len(part)
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
 	}
-	return true
+	return len(segments) == 0
 }
 
 // Touch updates the modification time of a file or creates it if it doesn't exist.
 func (a *AGFS) Touch(uri string) error {
+	defer a.statCache.invalidate()
+
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
 		return ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if os.IsNotExist(err) {
 		// Create empty file
-		return os.WriteFile(path, []byte{}, 0644)
+		return a.writeFile(path, []byte{})
 	}
 	if err != nil {
 		return err
@@ -443,5 +733,5 @@ func (a *AGFS) Touch(uri string) error {
 		return ErrIsDirectory
 	}
 
-	return os.Chtimes(path, time.Now(), time.Now())
+	return a.fs.Chtimes(path, time.Now(), time.Now())
 }
@@ -6,10 +6,13 @@ package agfs
 import (
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Mkdir creates a new directory at the given URI.
 func (a *AGFS) Mkdir(uri string, mode os.FileMode, existOk bool) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -19,7 +22,7 @@ func (a *AGFS) Mkdir(uri string, mode os.FileMode, existOk bool) error {
 		return ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err == nil {
 		if existOk && info.IsDir() {
 			return nil
@@ -31,11 +34,21 @@ func (a *AGFS) Mkdir(uri string, mode os.FileMode, existOk bool) error {
 		return err
 	}
 
-	return os.MkdirAll(path, mode)
+	return a.fs.MkdirAll(path, mode)
 }
 
-// Rmdir removes a directory at the given URI.
+// Rmdir removes a directory at the given URI. With Config.RollupOnDelete
+// the parent's abstract is then regenerated from its remaining children.
 func (a *AGFS) Rmdir(uri string, recursive bool) error {
+	if err := a.rmdir(uri, recursive); err != nil {
+		return err
+	}
+	return a.rollupParent(uri)
+}
+
+func (a *AGFS) rmdir(uri string, recursive bool) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -45,7 +58,7 @@ func (a *AGFS) Rmdir(uri string, recursive bool) error {
 		return ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ErrNotFound
@@ -58,10 +71,15 @@ func (a *AGFS) Rmdir(uri string, recursive bool) error {
 	}
 
 	if recursive {
-		return os.RemoveAll(path)
+		err = a.fs.RemoveAll(path)
+	} else {
+		err = a.fs.Remove(path)
 	}
-
-	return os.Remove(path)
+	if err != nil {
+		return err
+	}
+	a.forgetChecksums(path)
+	return nil
 }
 
 // List lists the contents of a directory at the given URI.
@@ -75,7 +93,7 @@ func (a *AGFS) List(uri string, showHidden bool) ([]Entry, error) {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotFound
@@ -87,7 +105,7 @@ func (a *AGFS) List(uri string, showHidden bool) ([]Entry, error) {
 		return nil, ErrNotADirectory
 	}
 
-	entries, err := os.ReadDir(path)
+	entries, err := a.fs.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +119,7 @@ func (a *AGFS) List(uri string, showHidden bool) ([]Entry, error) {
 		}
 
 		entryPath := filepath.Join(path, name)
-		entryInfo, err := os.Stat(entryPath)
+		entryInfo, err := a.fs.Stat(entryPath)
 		if err != nil {
 			continue
 		}
@@ -124,7 +142,9 @@ func (a *AGFS) List(uri string, showHidden bool) ([]Entry, error) {
 	return result, nil
 }
 
-// Tree returns the directory tree starting from the given URI.
+// Tree returns the directory tree starting from the given URI, maxDepth
+// levels deep. A maxDepth <= 0 uses Config.TreeMaxDepth. At most
+// Config.TreeMaxEntries entries are returned; see TreeEntry.Truncated.
 func (a *AGFS) Tree(uri string, maxDepth int) ([]TreeEntry, error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -135,7 +155,7 @@ func (a *AGFS) Tree(uri string, maxDepth int) ([]TreeEntry, error) {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotFound
@@ -147,21 +167,151 @@ func (a *AGFS) Tree(uri string, maxDepth int) ([]TreeEntry, error) {
 		return nil, ErrNotADirectory
 	}
 
+	if maxDepth <= 0 {
+		maxDepth = a.config.TreeMaxDepth
+	}
+	budget := a.config.TreeMaxEntries
+
 	var entries []TreeEntry
-	if err := a.walkTree(path, uri, 0, maxDepth, &entries); err != nil {
+	if err := a.walkTree(path, uri, 0, maxDepth, &budget, &entries); err != nil {
 		return nil, err
 	}
 
 	return entries, nil
 }
 
-// walkTree recursively walks the directory tree.
-func (a *AGFS) walkTree(path, uri string, depth, maxDepth int, entries *[]TreeEntry) error {
-	if maxDepth > 0 && depth >= maxDepth {
+// TreeModTime returns the newest modification time among the directory at
+// uri and everything Tree(uri, maxDepth) reads, which includes the summary
+// files of directories on the last level. A maxDepth <= 0 uses
+// Config.TreeMaxDepth, as Tree does.
+func (a *AGFS) TreeModTime(uri string, maxDepth int) (time.Time, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	uri = a.normalizeURI(uri)
+	path := a.URIToPath(uri)
+	if path == "" {
+		return time.Time{}, ErrInvalidURI
+	}
+
+	info, err := a.fs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, ErrNotFound
+		}
+		return time.Time{}, err
+	}
+	if !info.IsDir() {
+		return time.Time{}, ErrNotADirectory
+	}
+
+	if maxDepth <= 0 {
+		maxDepth = a.config.TreeMaxDepth
+	}
+	newest := info.ModTime()
+	if err := a.newestBelow(path, maxDepth+1, &newest); err != nil {
+		return time.Time{}, err
+	}
+	return newest, nil
+}
+
+// newestBelow raises newest to the modification time of any entry up to
+// levels levels below path.
+func (a *AGFS) newestBelow(path string, levels int, newest *time.Time) error {
+	if levels <= 0 {
 		return nil
 	}
+	entries, err := a.fs.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(*newest) {
+			*newest = info.ModTime()
+		}
+		if entry.IsDir() {
+			if err := a.newestBelow(filepath.Join(path, entry.Name()), levels-1, newest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Files returns every regular file below the given URI, recursively.
+// Hidden files such as .abstract.md and hidden directories are skipped.
+func (a *AGFS) Files(uri string) ([]Entry, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
-	dirEntries, err := os.ReadDir(path)
+	uri = a.normalizeURI(uri)
+	root := a.URIToPath(uri)
+	if root == "" {
+		return nil, ErrInvalidURI
+	}
+
+	info, err := a.fs.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, ErrNotADirectory
+	}
+
+	var files []Entry
+	err = walkDir(a.fs, root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != root && len(name) > 0 && name[0] == '.' {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fileURI := a.PathToURI(path)
+		files = append(files, Entry{
+			Name:     name,
+			Path:     path,
+			URI:      fileURI,
+			Size:     info.Size(),
+			Mode:     info.Mode(),
+			ModTime:  info.ModTime(),
+			FileType: determineFileType(fileURI, false),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// walkTree recursively walks the directory tree, spending one unit of
+// budget per entry.
+func (a *AGFS) walkTree(path, uri string, depth, maxDepth int, budget *int, entries *[]TreeEntry) error {
+	if depth >= maxDepth {
+		return nil
+	}
+
+	dirEntries, err := a.fs.ReadDir(path)
 	if err != nil {
 		return err
 	}
@@ -176,6 +326,12 @@ func (a *AGFS) walkTree(path, uri string, depth, maxDepth int, entries *[]TreeEn
 			continue
 		}
 
+		if *budget <= 0 {
+			*entries = append(*entries, TreeEntry{Name: TruncatedEntryName, URI: uri, Truncated: true})
+			return nil
+		}
+		*budget--
+
 		entryPath := filepath.Join(path, name)
 		entryURI := a.PathToURI(entryPath)
 
@@ -196,7 +352,7 @@ func (a *AGFS) walkTree(path, uri string, depth, maxDepth int, entries *[]TreeEn
 			}
 
 			var children []TreeEntry
-			a.walkTree(entryPath, entryURI, depth+1, maxDepth, &children)
+			a.walkTree(entryPath, entryURI, depth+1, maxDepth, budget, &children)
 			for i := range children {
 				treeEntry.Children = append(treeEntry.Children, &children[i])
 			}
@@ -211,7 +367,7 @@ func (a *AGFS) walkTree(path, uri string, depth, maxDepth int, entries *[]TreeEn
 // readAbstractFile reads the .abstract.md file from a directory.
 func (a *AGFS) readAbstractFile(dirPath string) (string, error) {
 	abstractPath := filepath.Join(dirPath, ".abstract.md")
-	data, err := os.ReadFile(abstractPath)
+	data, err := a.fs.ReadFile(abstractPath)
 	if err != nil {
 		return "", err
 	}
@@ -221,7 +377,7 @@ func (a *AGFS) readAbstractFile(dirPath string) (string, error) {
 // readOverviewFile reads the .overview.md file from a directory.
 func (a *AGFS) readOverviewFile(dirPath string) (string, error) {
 	overviewPath := filepath.Join(dirPath, ".overview.md")
-	data, err := os.ReadFile(overviewPath)
+	data, err := a.fs.ReadFile(overviewPath)
 	if err != nil {
 		return "", err
 	}
@@ -4,9 +4,9 @@
 package agfs
 
 import (
-	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Read reads the contents of a file at the given URI.
@@ -20,7 +20,7 @@ func (a *AGFS) Read(uri string, offset int64, size int64) ([]byte, error) {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotFound
@@ -32,31 +32,19 @@ func (a *AGFS) Read(uri string, offset int64, size int64) ([]byte, error) {
 		return nil, ErrIsDirectory
 	}
 
-	file, err := os.Open(path)
+	data, err := a.fs.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
 	if offset > 0 {
-		if _, err := file.Seek(offset, io.SeekStart); err != nil {
-			return nil, err
+		if offset >= int64(len(data)) {
+			return []byte{}, nil
 		}
+		data = data[offset:]
 	}
-
-	var data []byte
-	if size > 0 {
-		data = make([]byte, size)
-		n, err := file.Read(data)
-		if err != nil && err != io.EOF {
-			return nil, err
-		}
-		data = data[:n]
-	} else {
-		data, err = io.ReadAll(file)
-		if err != nil {
-			return nil, err
-		}
+	if size > 0 && size < int64(len(data)) {
+		data = data[:size]
 	}
 
 	return data, nil
@@ -64,6 +52,8 @@ func (a *AGFS) Read(uri string, offset int64, size int64) ([]byte, error) {
 
 // Write writes data to a file at the given URI.
 func (a *AGFS) Write(uri string, data []byte) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -75,15 +65,23 @@ func (a *AGFS) Write(uri string, data []byte) error {
 
 	// Ensure parent directory exists
 	parent := filepath.Dir(path)
-	if err := os.MkdirAll(parent, 0755); err != nil {
+	if err := a.fs.MkdirAll(parent, 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	if a.config.Versioning {
+		if err := a.saveVersion(path); err != nil {
+			return err
+		}
+	}
+
+	return a.writeFile(path, data)
 }
 
 // Append appends data to a file at the given URI.
 func (a *AGFS) Append(uri string, data []byte) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -94,53 +92,75 @@ func (a *AGFS) Append(uri string, data []byte) error {
 	}
 
 	// Check if file exists
-	existing, err := os.ReadFile(path)
+	existing, err := a.fs.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
 	// Ensure parent directory exists
 	parent := filepath.Dir(path)
-	if err := os.MkdirAll(parent, 0755); err != nil {
+	if err := a.fs.MkdirAll(parent, 0755); err != nil {
 		return err
 	}
 
 	// Append data
 	combined := append(existing, data...)
-	return os.WriteFile(path, combined, 0644)
+	return a.writeFile(path, combined)
 }
 
-// Delete deletes a file at the given URI.
+// Delete deletes a file at the given URI. With Config.RollupOnDelete,
+// deleting a child context regenerates the parent's abstract from its
+// remaining children; deleting a plain file such as content.md leaves it.
 func (a *AGFS) Delete(uri string, recursive bool) error {
+	wasContext, err := a.delete(uri, recursive)
+	if err != nil {
+		return err
+	}
+	if !wasContext {
+		return nil
+	}
+	return a.rollupParent(uri)
+}
+
+// delete removes the file or directory at uri, reporting whether it was a
+// child context directory.
+func (a *AGFS) delete(uri string, recursive bool) (bool, error) {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	uri = a.normalizeURI(uri)
 	path := a.URIToPath(uri)
 	if path == "" {
-		return ErrInvalidURI
+		return false, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.fs.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ErrNotFound
+			return false, ErrNotFound
 		}
-		return err
+		return false, err
 	}
 
-	if info.IsDir() {
-		if recursive {
-			return os.RemoveAll(path)
-		}
-		return os.Remove(path)
+	if info.IsDir() && recursive {
+		err = a.fs.RemoveAll(path)
+	} else {
+		err = a.fs.Remove(path)
 	}
-
-	return os.Remove(path)
+	if err != nil {
+		return false, err
+	}
+	a.forgetChecksums(path)
+	a.forgetVersions(path)
+	return info.IsDir() && !strings.HasPrefix(info.Name(), "."), nil
 }
 
 // Move moves a file or directory from one URI to another.
 func (a *AGFS) Move(oldURI, newURI string) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -155,7 +175,7 @@ func (a *AGFS) Move(oldURI, newURI string) error {
 	}
 
 	// Check source exists
-	_, err := os.Stat(oldPath)
+	_, err := a.fs.Stat(oldPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ErrNotFound
@@ -165,20 +185,27 @@ func (a *AGFS) Move(oldURI, newURI string) error {
 
 	// Ensure destination parent directory exists
 	parent := filepath.Dir(newPath)
-	if err := os.MkdirAll(parent, 0755); err != nil {
+	if err := a.fs.MkdirAll(parent, 0755); err != nil {
 		return err
 	}
 
 	// Check if destination already exists
-	if _, err := os.Stat(newPath); err == nil {
+	if _, err := a.fs.Stat(newPath); err == nil {
 		return ErrAlreadyExists
 	}
 
-	return os.Rename(oldPath, newPath)
+	if err := a.fs.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	a.moveChecksums(oldPath, newPath)
+	a.moveVersions(oldPath, newPath)
+	return nil
 }
 
 // Copy copies a file or directory from one URI to another.
 func (a *AGFS) Copy(oldURI, newURI string) error {
+	defer a.statCache.invalidate()
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -192,7 +219,7 @@ func (a *AGFS) Copy(oldURI, newURI string) error {
 		return ErrInvalidURI
 	}
 
-	info, err := os.Stat(oldPath)
+	info, err := a.fs.Stat(oldPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ErrNotFound
@@ -202,7 +229,7 @@ func (a *AGFS) Copy(oldURI, newURI string) error {
 
 	// Ensure destination parent directory exists
 	parent := filepath.Dir(newPath)
-	if err := os.MkdirAll(parent, 0755); err != nil {
+	if err := a.fs.MkdirAll(parent, 0755); err != nil {
 		return err
 	}
 
@@ -215,16 +242,20 @@ func (a *AGFS) Copy(oldURI, newURI string) error {
 
 // copyDir recursively copies a directory.
 func (a *AGFS) copyDir(src, dst string) error {
-	entries, err := os.ReadDir(src)
+	entries, err := a.fs.ReadDir(src)
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	if err := a.fs.MkdirAll(dst, 0755); err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
+		// Copied files record their own checksums
+		if entry.Name() == checksumsDir {
+			continue
+		}
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
@@ -244,20 +275,11 @@ func (a *AGFS) copyDir(src, dst string) error {
 
 // copyFile copies a single file.
 func (a *AGFS) copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
+	data, err := a.fs.ReadFile(src)
 	if err != nil {
 		return err
 	}
-	defer srcFile.Close()
-
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer dstFile.Close()
-
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	return a.writeFile(dst, data)
 }
 
 // Stat returns information about a file or directory at the given URI.
@@ -271,7 +293,7 @@ func (a *AGFS) Stat(uri string) (*Entry, error) {
 		return nil, ErrInvalidURI
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.statCache.stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotFound
@@ -300,7 +322,7 @@ func (a *AGFS) Exists(uri string) bool {
 		return false
 	}
 
-	_, err := os.Stat(path)
+	_, err := a.statCache.stat(path)
 	return err == nil
 }
 
@@ -312,7 +334,7 @@ func (a *AGFS) IsDir(uri string) bool {
 		return false
 	}
 
-	info, err := os.Stat(path)
+	info, err := a.statCache.stat(path)
 	if err != nil {
 		return false
 	}
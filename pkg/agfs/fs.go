@@ -0,0 +1,429 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// FileSystem is the storage AGFS reads and writes paths through. Errors
+// follow the os package, so os.IsNotExist works on them. OSFileSystem uses
+// the local disk and MemFileSystem keeps everything in memory.
+type FileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Sync(name string) error
+}
+
+// OSFileSystem is a FileSystem over the local disk.
+type OSFileSystem struct{}
+
+// Stat returns the FileInfo of name.
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+// ReadFile reads the named file.
+func (OSFileSystem) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) }
+
+// WriteFile writes data to the named file, creating it if necessary.
+func (OSFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// ReadDir reads the named directory, sorted by file name.
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+// MkdirAll creates a directory along with any missing parents.
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+
+// Remove removes the named file or empty directory.
+func (OSFileSystem) Remove(name string) error { return os.Remove(name) }
+
+// RemoveAll removes path and anything it contains.
+func (OSFileSystem) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// Rename renames oldpath to newpath.
+func (OSFileSystem) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+// Chtimes changes the access and modification times of the named file.
+func (OSFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// Sync commits the named file or directory to stable storage.
+func (OSFileSystem) Sync(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFileAtomic writes data to name through a temporary file in the same
+// directory that is then renamed over name, so readers see either the old
+// or the new contents in full. The temporary file is synced before the
+// rename and the directory after it, so the new contents survive a crash.
+// An existing file keeps its mode bits. A symlink at name is replaced by
+// the file rather than written through; AGFS never creates links itself.
+func writeFileAtomic(fsys FileSystem, name string, data []byte, perm fs.FileMode) error {
+	if info, err := fsys.Stat(name); err == nil && info.Mode().IsRegular() {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(name)
+	tmp := filepath.Join(dir, "."+filepath.Base(name)+".tmp-"+utils.GenerateID()[:8])
+	if err := fsys.WriteFile(tmp, data, perm); err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+	if err := fsys.Sync(tmp); err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+	if err := fsys.Rename(tmp, name); err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+	return fsys.Sync(dir)
+}
+
+// memNode is a file or directory of a MemFileSystem.
+type memNode struct {
+	dir     bool
+	data    []byte
+	perm    fs.FileMode
+	modTime time.Time
+}
+
+// MemFileSystem is a FileSystem held in memory, for tests that should not
+// touch the disk. Modification times come from its clock.
+type MemFileSystem struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+	clock utils.Clock
+}
+
+// NewMemFileSystem creates an empty in-memory filesystem.
+func NewMemFileSystem() *MemFileSystem {
+	return &MemFileSystem{
+		nodes: make(map[string]*memNode),
+		clock: utils.RealClock{},
+	}
+}
+
+// SetClock sets the clock used to timestamp files.
+func (m *MemFileSystem) SetClock(clock utils.Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock
+}
+
+// Stat returns the FileInfo of name.
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memFileInfo{name: filepath.Base(name), node: node}, nil
+}
+
+// ReadFile reads the named file.
+func (m *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+// WriteFile writes data to the named file, creating it if necessary. Its
+// directory must exist.
+func (m *MemFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if err := m.checkParent("open", name); err != nil {
+		return err
+	}
+	node, ok := m.nodes[name]
+	if ok && node.dir {
+		return &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+	if !ok {
+		node = &memNode{perm: perm.Perm()}
+		m.nodes[name] = node
+	}
+	node.data = append([]byte(nil), data...)
+	node.modTime = m.clock.Now()
+	return nil
+}
+
+// ReadDir reads the named directory, sorted by file name.
+func (m *MemFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	var entries []fs.DirEntry
+	for p, child := range m.nodes {
+		if p != name && filepath.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: filepath.Base(p), node: child}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// MkdirAll creates a directory along with any missing parents.
+func (m *MemFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if node, ok := m.nodes[dir]; ok {
+			if !node.dir {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: syscall.ENOTDIR}
+			}
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	now := m.clock.Now()
+	for _, dir := range missing {
+		m.nodes[dir] = &memNode{dir: true, perm: perm.Perm(), modTime: now}
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+func (m *MemFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.dir && m.hasChildren(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+// RemoveAll removes path and anything it contains. A missing path is not
+// an error.
+func (m *MemFileSystem) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	for p := range m.nodes {
+		if p == path || isBelow(p, path) {
+			delete(m.nodes, p)
+		}
+	}
+	return nil
+}
+
+// Rename renames oldpath, and anything below it, to newpath, replacing a
+// file or empty directory already there.
+func (m *MemFileSystem) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	if _, ok := m.nodes[oldpath]; !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if err := m.checkParent("rename", newpath); err != nil {
+		return err
+	}
+	if existing, ok := m.nodes[newpath]; ok && existing.dir && m.hasChildren(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.ENOTEMPTY}
+	}
+
+	moved := make(map[string]*memNode)
+	for p, node := range m.nodes {
+		if p == oldpath || isBelow(p, oldpath) {
+			moved[newpath+strings.TrimPrefix(p, oldpath)] = node
+			delete(m.nodes, p)
+		}
+	}
+	for p, node := range moved {
+		m.nodes[p] = node
+	}
+	return nil
+}
+
+// Chtimes changes the modification time of the named file. Access times
+// are not tracked.
+func (m *MemFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	node.modTime = mtime
+	return nil
+}
+
+// Sync does nothing, as memory is never flushed; it fails only when name
+// does not exist.
+func (m *MemFileSystem) Sync(name string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	if _, ok := m.nodes[name]; !ok {
+		return &fs.PathError{Op: "sync", Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// checkParent returns an error unless the directory of name exists. The
+// caller must hold the lock.
+func (m *MemFileSystem) checkParent(op, name string) error {
+	parent, ok := m.nodes[filepath.Dir(name)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !parent.dir {
+		return &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+// hasChildren reports whether anything lies below dir. The caller must
+// hold the lock.
+func (m *MemFileSystem) hasChildren(dir string) bool {
+	for p := range m.nodes {
+		if isBelow(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBelow reports whether path lies strictly below dir.
+func isBelow(path, dir string) bool {
+	if dir == string(filepath.Separator) {
+		return path != dir && strings.HasPrefix(path, dir)
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// memFileInfo describes a memNode.
+type memFileInfo struct {
+	name string
+	node *memNode
+}
+
+func (fi memFileInfo) Name() string { return fi.name }
+
+func (fi memFileInfo) Size() int64 { return int64(len(fi.node.data)) }
+
+func (fi memFileInfo) Mode() fs.FileMode {
+	if fi.node.dir {
+		return fs.ModeDir | fi.node.perm
+	}
+	return fi.node.perm
+}
+
+func (fi memFileInfo) ModTime() time.Time { return fi.node.modTime }
+
+func (fi memFileInfo) IsDir() bool { return fi.node.dir }
+
+func (fi memFileInfo) Sys() any { return nil }
+
+// walkDir walks the tree rooted at root like filepath.WalkDir, reading it
+// through fsys.
+func walkDir(fsys FileSystem, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(fsys FileSystem, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Give fn a second chance to skip the unreadable directory
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDirEntry(fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// TestSuiteInMemory runs the AGFS tests against a MemFileSystem. Tests
+// counting OS stat calls are left out.
+func TestSuiteInMemory(t *testing.T) {
+	orig := newFileSystem
+	newFileSystem = func() FileSystem { return NewMemFileSystem() }
+	defer func() { newFileSystem = orig }()
+
+	for _, tt := range []struct {
+		name string
+		test func(*testing.T)
+	}{
+		{"New", TestNew},
+		{"MkdirAndList", TestMkdirAndList},
+		{"WriteAndRead", TestWriteAndRead},
+		{"Stat", TestStat},
+		{"Delete", TestDelete},
+		{"Move", TestMove},
+		{"ContextFiles", TestContextFiles},
+		{"ReadContextDerivesSummaries", TestReadContextDerivesSummaries},
+		{"ReadContextWithoutDerivation", TestReadContextWithoutDerivation},
+		{"WriteContextTree", TestWriteContextTree},
+		{"WriteContextTreeRollback", TestWriteContextTreeRollback},
+		{"WriteContextTreeOutsideRoot", TestWriteContextTreeOutsideRoot},
+		{"StatCacheExpires", TestStatCacheExpires},
+		{"StatCacheEvictsLeastRecent", TestStatCacheEvictsLeastRecent},
+		{"Files", TestFiles},
+		{"WriteContextGeneratesCodeAbstract", TestWriteContextGeneratesCodeAbstract},
+		{"DeleteRollsUpParentAbstract", TestDeleteRollsUpParentAbstract},
+		{"DeleteFileKeepsParentAbstract", TestDeleteFileKeepsParentAbstract},
+		{"RollupChildFileTypes", TestRollupChildFileTypes},
+		{"DeleteRollupWithoutSummarizer", TestDeleteRollupWithoutSummarizer},
+		{"DeleteRollupDisabled", TestDeleteRollupDisabled},
+		{"DeleteRollupDoesNotCascade", TestDeleteRollupDoesNotCascade},
+		{"TreeTruncatesWideTrees", TestTreeTruncatesWideTrees},
+		{"TreeDepthLimits", TestTreeDepthLimits},
+		{"GrepRegex", TestGrepRegex},
+		{"GrepAndLink", TestGrepAndLink},
+		{"Glob", TestGlob},
+		{"Versions", TestVersions},
+		{"VersionsFollowFile", TestVersionsFollowFile},
+		{"VersioningDisabled", TestVersioningDisabled},
+	} {
+		t.Run(tt.name, tt.test)
+	}
+}
+
+// newMemAGFS returns an AGFS over a fresh MemFileSystem rooted at /viking.
+func newMemAGFS(t *testing.T, fsys FileSystem) *AGFS {
+	t.Helper()
+	agfs, err := New(Config{RootPath: "/viking", URIPrefix: "viking://", FileSystem: fsys})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	return agfs
+}
+
+func TestMemFileSystemStaysInMemory(t *testing.T) {
+	mem := NewMemFileSystem()
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	mem.SetClock(clock)
+	agfs := newMemAGFS(t, mem)
+
+	if err := agfs.Write("viking://resources/docs/a.txt", []byte("alpha")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if _, err := os.Stat("/viking"); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing on disk, got %v", err)
+	}
+
+	entry, err := agfs.Stat("viking://resources/docs/a.txt")
+	if err != nil {
+		t.Fatalf("Failed to stat: %v", err)
+	}
+	if entry.Size != 5 || !entry.ModTime.Equal(clock.Now()) {
+		t.Errorf("Expected 5 bytes written at %v, got %+v", clock.Now(), entry)
+	}
+	if data, _ := agfs.Read("viking://resources/docs/a.txt", 1, 3); string(data) != "lph" {
+		t.Errorf("Expected a ranged read of lph, got %q", data)
+	}
+
+	if err := agfs.Copy("viking://resources/docs", "viking://resources/copy"); err != nil {
+		t.Fatalf("Failed to copy: %v", err)
+	}
+	if err := agfs.Rmdir("viking://resources/docs", false); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if err := agfs.Rmdir("viking://resources/docs", true); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+
+	entries, err := agfs.List("viking://resources", false)
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"copy"}) {
+		t.Errorf("Expected only the copy left, got %v", names)
+	}
+	if data, _ := agfs.Read("viking://resources/copy/a.txt", 0, -1); string(data) != "alpha" {
+		t.Errorf("Expected the copied file, got %q", data)
+	}
+}
+
+// failingFS fails writes to one file name, including the temporary files
+// written in its place.
+type failingFS struct {
+	FileSystem
+	name string
+	err  error
+}
+
+func (f *failingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if strings.Contains(filepath.Base(name), f.name) {
+		return f.err
+	}
+	return f.FileSystem.WriteFile(name, data, perm)
+}
+
+func TestWriteErrorInjected(t *testing.T) {
+	diskFull := errors.New("no space left on device")
+	mem := NewMemFileSystem()
+	agfs := newMemAGFS(t, &failingFS{FileSystem: mem, name: "content.md", err: diskFull})
+
+	if err := agfs.Write("viking://resources/content.md", []byte("x")); !errors.Is(err, diskFull) {
+		t.Errorf("Expected the injected error from Write, got %v", err)
+	}
+
+	// The tree write fails on the first content file and rolls back
+	entries := []ContextFile{
+		{URI: "", Abstract: "Knowledge base"},
+		{URI: "faq", Abstract: "FAQ", Content: "Ask in chat."},
+	}
+	if err := agfs.WriteContextTree("viking://resources/kb", entries); !errors.Is(err, diskFull) {
+		t.Fatalf("Expected the injected error from WriteContextTree, got %v", err)
+	}
+	if _, err := mem.Stat("/viking/resources/kb"); !os.IsNotExist(err) {
+		t.Errorf("Expected the tree rolled back, got %v", err)
+	}
+}
+
+// checksumFailingFS fails writes to checksum files, after the file they
+// describe has been written.
+type checksumFailingFS struct {
+	FileSystem
+	err error
+}
+
+func (f checksumFailingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if filepath.Base(filepath.Dir(name)) == checksumsDir {
+		return f.err
+	}
+	return f.FileSystem.WriteFile(name, data, perm)
+}
+
+func TestWriteContextTreeRestoresPartialWrite(t *testing.T) {
+	mem := NewMemFileSystem()
+	agfs := newMemAGFS(t, mem)
+	if err := agfs.WriteContextTree("viking://resources/kb", []ContextFile{{URI: "", Content: "old"}}); err != nil {
+		t.Fatalf("Failed to write tree: %v", err)
+	}
+
+	diskFull := errors.New("no space left on device")
+	agfs.fs = checksumFailingFS{FileSystem: mem, err: diskFull}
+	if err := agfs.WriteContextTree("viking://resources/kb", []ContextFile{{URI: "", Content: "new"}}); !errors.Is(err, diskFull) {
+		t.Fatalf("Expected the injected error, got %v", err)
+	}
+	if data, _ := mem.ReadFile("/viking/resources/kb/content.md"); string(data) != "old" {
+		t.Errorf("Expected the old content restored, got %q", data)
+	}
+}
+
+// renameFailingFS fails every rename, as a crash between writing a
+// temporary file and renaming it would.
+type renameFailingFS struct {
+	FileSystem
+}
+
+func (f renameFailingFS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errors.New("crashed")}
+}
+
+// opRecordingFS records the renames and syncs made through it.
+type opRecordingFS struct {
+	FileSystem
+	ops []string
+}
+
+func (f *opRecordingFS) Rename(oldpath, newpath string) error {
+	f.ops = append(f.ops, "rename "+filepath.Base(oldpath)+" "+filepath.Base(newpath))
+	return f.FileSystem.Rename(oldpath, newpath)
+}
+
+func (f *opRecordingFS) Sync(name string) error {
+	f.ops = append(f.ops, "sync "+filepath.Base(name))
+	return f.FileSystem.Sync(name)
+}
+
+func TestWriteFileAtomicSyncs(t *testing.T) {
+	mem := NewMemFileSystem()
+	if err := mem.MkdirAll("/data", 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	rec := &opRecordingFS{FileSystem: mem}
+	if err := writeFileAtomic(rec, "/data/a.txt", []byte("x"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	if len(rec.ops) != 3 {
+		t.Fatalf("Expected a sync, a rename and a sync, got %v", rec.ops)
+	}
+	tmp := strings.Fields(rec.ops[0])[1]
+	want := []string{"sync " + tmp, "rename " + tmp + " a.txt", "sync data"}
+	if !reflect.DeepEqual(rec.ops, want) {
+		t.Errorf("Expected %v, got %v", want, rec.ops)
+	}
+}
+
+func TestWriteIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	agfs, err := New(Config{RootPath: dir, URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	path := filepath.Join(dir, "resources", "big.txt")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1<<18)
+	if err := agfs.Write("viking://resources/big.txt", large); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if !bytes.Equal(data, large) {
+		t.Errorf("Expected all %d bytes written, got %d", len(large), len(data))
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 kept, got %v", info.Mode().Perm())
+	}
+	for _, uri := range []string{"viking://resources/kb", "viking://resources/kb/faq"} {
+		if err := agfs.WriteAbstract(uri, "abstract"); err != nil {
+			t.Fatalf("Failed to write abstract: %v", err)
+		}
+		if err := agfs.WriteOverview(uri, "overview"); err != nil {
+			t.Fatalf("Failed to write overview: %v", err)
+		}
+	}
+	if err := agfs.WriteContent("viking://resources/kb", "content"); err != nil {
+		t.Fatalf("Failed to write content: %v", err)
+	}
+	walkErr := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && strings.Contains(d.Name(), ".tmp-") {
+			t.Errorf("Expected no temporary files left, found %s", p)
+		}
+		return err
+	})
+	if walkErr != nil {
+		t.Fatalf("Failed to walk: %v", walkErr)
+	}
+
+	// A write interrupted before the rename leaves the old contents whole
+	mem := NewMemFileSystem()
+	memAGFS := newMemAGFS(t, mem)
+	if err := memAGFS.Write("viking://resources/a.txt", []byte("old")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	memAGFS.fs = renameFailingFS{mem}
+	if err := memAGFS.Write("viking://resources/a.txt", large); err == nil {
+		t.Fatal("Expected the interrupted write to fail")
+	}
+	if data, _ := mem.ReadFile("/viking/resources/a.txt"); string(data) != "old" {
+		t.Errorf("Expected the old contents, got %d bytes", len(data))
+	}
+	entries, _ := mem.ReadDir("/viking/resources")
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !reflect.DeepEqual(names, []string{checksumsDir, "a.txt"}) {
+		t.Errorf("Expected only a.txt and its checksum left, got %v", names)
+	}
+}
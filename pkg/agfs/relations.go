@@ -33,7 +33,7 @@ func (r *RelationManager) Link(fromURI string, uris []string, reason string) err
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(path)
+	info, err := r.agfs.fs.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -63,6 +63,54 @@ func (r *RelationManager) Link(fromURI string, uris []string, reason string) err
 	return r.writeRelationTable(path, relations)
 }
 
+// GrepAndLink searches the files below the directory at uri for pattern
+// and links the directory to each matching file it is not already linked
+// to, with reason. It returns the newly linked URIs.
+func (r *RelationManager) GrepAndLink(uri, pattern, reason string) ([]string, error) {
+	matches, err := r.agfs.Grep(uri, pattern, false)
+	if err != nil {
+		return nil, err
+	}
+
+	r.agfs.mu.Lock()
+	defer r.agfs.mu.Unlock()
+
+	path := r.agfs.URIToPath(r.agfs.normalizeURI(uri))
+	relations, err := r.readRelationTable(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A file matching on several lines, or linked before, is linked once
+	linked := make(map[string]bool)
+	for _, entry := range relations {
+		for _, u := range entry.URIs {
+			linked[u] = true
+		}
+	}
+	var uris []string
+	for _, m := range matches {
+		if !linked[m.URI] {
+			linked[m.URI] = true
+			uris = append(uris, m.URI)
+		}
+	}
+	if len(uris) == 0 {
+		return nil, nil
+	}
+
+	relations = append(relations, RelationEntry{
+		ID:        generateLinkID(relations),
+		URIs:      uris,
+		Reason:    reason,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	if err := r.writeRelationTable(path, relations); err != nil {
+		return nil, err
+	}
+	return uris, nil
+}
+
 // Unlink removes a relation from a directory.
 func (r *RelationManager) Unlink(fromURI, targetURI string) error {
 	r.agfs.mu.Lock()
@@ -142,7 +190,7 @@ func (r *RelationManager) GetRelatedURIs(uri string) ([]string, error) {
 // readRelationTable reads the relation table from a directory.
 func (r *RelationManager) readRelationTable(dirPath string) ([]RelationEntry, error) {
 	relPath := filepath.Join(dirPath, ".relations.json")
-	data, err := os.ReadFile(relPath)
+	data, err := r.agfs.fs.ReadFile(relPath)
 	if os.IsNotExist(err) {
 		return make([]RelationEntry, 0), nil
 	}
@@ -166,7 +214,9 @@ func (r *RelationManager) writeRelationTable(dirPath string, relations []Relatio
 		return err
 	}
 
-	return os.WriteFile(relPath, data, 0644)
+	defer r.agfs.statCache.invalidate()
+
+	return r.agfs.writeFile(relPath, data)
 }
 
 // generateLinkID generates a unique ID for a new link.
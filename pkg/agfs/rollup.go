@@ -0,0 +1,137 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RollupSummarizer writes a directory's abstract from the abstracts of its
+// child contexts.
+type RollupSummarizer interface {
+	SummarizeChildren(uri string, children []ContextFile) (string, error)
+}
+
+// SetRollupSummarizer sets the summarizer used to regenerate a parent's
+// abstract when Config.RollupOnDelete is set. Without one, the abstract
+// lists each remaining child with its abstract.
+func (a *AGFS) SetRollupSummarizer(summarizer RollupSummarizer) {
+	a.rollupMu.Lock()
+	defer a.rollupMu.Unlock()
+	a.rollup = summarizer
+}
+
+// rollupParent regenerates the abstract of the directory holding uri from
+// its remaining children, or removes it when none are left. Parents
+// without an abstract are left alone. A parent is rolled up once at a
+// time: deletes made while its rollup runs, for instance by the
+// summarizer, do not start another, so rollups cannot cascade forever.
+func (a *AGFS) rollupParent(uri string) error {
+	if !a.config.RollupOnDelete {
+		return nil
+	}
+	parent := a.parentURI(a.normalizeURI(uri))
+	if parent == "" {
+		return nil
+	}
+
+	a.rollupMu.Lock()
+	if a.rollingUp[parent] {
+		a.rollupMu.Unlock()
+		return nil
+	}
+	if a.rollingUp == nil {
+		a.rollingUp = make(map[string]bool)
+	}
+	a.rollingUp[parent] = true
+	summarizer := a.rollup
+	a.rollupMu.Unlock()
+	defer func() {
+		a.rollupMu.Lock()
+		delete(a.rollingUp, parent)
+		a.rollupMu.Unlock()
+	}()
+
+	if _, err := a.ReadAbstract(parent); err != nil {
+		return nil
+	}
+	children, err := a.childContexts(parent)
+	if err != nil {
+		return fmt.Errorf("failed to roll up %s: %w", parent, err)
+	}
+
+	if len(children) == 0 {
+		defer a.statCache.invalidate()
+		if err := a.fs.Remove(filepath.Join(a.URIToPath(parent), ".abstract.md")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to roll up %s: %w", parent, err)
+		}
+		return nil
+	}
+
+	var abstract string
+	if summarizer != nil {
+		abstract, err = summarizer.SummarizeChildren(parent, children)
+		if err != nil {
+			return fmt.Errorf("failed to roll up %s: %w", parent, err)
+		}
+	} else {
+		abstract = listChildren(children)
+	}
+	return a.WriteAbstract(parent, abstract)
+}
+
+// parentURI returns the URI of the directory holding uri, or "" for the
+// root and top-level directories.
+func (a *AGFS) parentURI(uri string) string {
+	remainder := strings.Trim(strings.TrimPrefix(uri, a.uriPrefix), "/")
+	dir := path.Dir(remainder)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return a.uriPrefix + dir
+}
+
+// childContexts returns the child directories of uri with their abstracts,
+// in name order.
+func (a *AGFS) childContexts(uri string) ([]ContextFile, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	dirPath := a.URIToPath(uri)
+	entries, err := a.fs.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []ContextFile
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		abstract, _ := a.readAbstractFile(filepath.Join(dirPath, entry.Name()))
+		childURI := uri + "/" + entry.Name()
+		children = append(children, ContextFile{
+			URI:      childURI,
+			Abstract: abstract,
+			FileType: FileTypeFromURI(childURI),
+		})
+	}
+	return children, nil
+}
+
+// listChildren formats children as one "name: abstract" line each.
+func listChildren(children []ContextFile) string {
+	lines := make([]string, len(children))
+	for i, child := range children {
+		lines[i] = path.Base(child.URI)
+		if abstract := collapse(child.Abstract); abstract != "" {
+			lines[i] += ": " + abstract
+		}
+	}
+	return strings.Join(lines, "\n")
+}
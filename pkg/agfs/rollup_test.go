@@ -0,0 +1,169 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// joinSummarizer summarizes children by joining their abstracts, recording
+// each call. onSummarize, when set, runs before summarizing.
+type joinSummarizer struct {
+	calls       []string
+	onSummarize func(uri string)
+}
+
+func (s *joinSummarizer) SummarizeChildren(uri string, children []ContextFile) (string, error) {
+	s.calls = append(s.calls, uri)
+	if s.onSummarize != nil {
+		s.onSummarize(uri)
+	}
+	abstracts := make([]string, len(children))
+	for i, child := range children {
+		abstracts[i] = child.Abstract
+	}
+	return "Covers " + strings.Join(abstracts, " and "), nil
+}
+
+// newRollupAGFS returns an AGFS with rollup on delete and a guides
+// directory holding two child contexts.
+func newRollupAGFS(t *testing.T) *AGFS {
+	t.Helper()
+	fs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://", RollupOnDelete: true})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	if err := fs.WriteAbstract("viking://resources/guides", "Covers goroutines and channels"); err != nil {
+		t.Fatalf("Failed to write abstract: %v", err)
+	}
+	for _, child := range []struct{ name, abstract string }{
+		{"goroutines", "goroutines"},
+		{"channels", "channels"},
+	} {
+		if err := fs.WriteContext("viking://resources/guides/"+child.name, child.abstract, "", "", true); err != nil {
+			t.Fatalf("Failed to write context: %v", err)
+		}
+	}
+	return fs
+}
+
+func TestDeleteRollsUpParentAbstract(t *testing.T) {
+	fs := newRollupAGFS(t)
+	summarizer := &joinSummarizer{}
+	fs.SetRollupSummarizer(summarizer)
+
+	if err := fs.Delete("viking://resources/guides/channels", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	abstract, err := fs.ReadAbstract("viking://resources/guides")
+	if err != nil {
+		t.Fatalf("Failed to read abstract: %v", err)
+	}
+	if abstract != "Covers goroutines" {
+		t.Errorf("Expected the abstract to cover only the survivor, got %q", abstract)
+	}
+	if len(summarizer.calls) != 1 || summarizer.calls[0] != "viking://resources/guides" {
+		t.Errorf("Expected one rollup of the parent, got %v", summarizer.calls)
+	}
+
+	// Deleting the last child clears the abstract
+	if err := fs.Rmdir("viking://resources/guides/goroutines", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := fs.ReadAbstract("viking://resources/guides"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the abstract to be cleared, got %v", err)
+	}
+}
+
+func TestDeleteFileKeepsParentAbstract(t *testing.T) {
+	fs := newRollupAGFS(t)
+	summarizer := &joinSummarizer{}
+	fs.SetRollupSummarizer(summarizer)
+
+	if err := fs.Write("viking://resources/guides/content.md", []byte("notes")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := fs.Delete("viking://resources/guides/content.md", false); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if abstract, _ := fs.ReadAbstract("viking://resources/guides"); abstract != "Covers goroutines and channels" {
+		t.Errorf("Expected the abstract unchanged, got %q", abstract)
+	}
+	if len(summarizer.calls) != 0 {
+		t.Errorf("Expected no rollup after deleting a plain file, got %v", summarizer.calls)
+	}
+}
+
+func TestRollupChildFileTypes(t *testing.T) {
+	fs, err := New(Config{RootPath: t.TempDir(), URIPrefix: "viking://"})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	if err := fs.WriteContext("viking://user/memories", "memories", "", "", false); err != nil {
+		t.Fatalf("Failed to write context: %v", err)
+	}
+
+	children, err := fs.childContexts("viking://user")
+	if err != nil {
+		t.Fatalf("Failed to list children: %v", err)
+	}
+	if len(children) != 1 || children[0].FileType != FileTypeMemory {
+		t.Errorf("Expected the child typed by its own URI, got %+v", children)
+	}
+}
+
+func TestDeleteRollupWithoutSummarizer(t *testing.T) {
+	fs := newRollupAGFS(t)
+
+	if err := fs.Delete("viking://resources/guides/goroutines", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	abstract, err := fs.ReadAbstract("viking://resources/guides")
+	if err != nil {
+		t.Fatalf("Failed to read abstract: %v", err)
+	}
+	if abstract != "channels: channels" {
+		t.Errorf("Expected the survivor listed, got %q", abstract)
+	}
+}
+
+func TestDeleteRollupDisabled(t *testing.T) {
+	fs := newRollupAGFS(t)
+	fs.config.RollupOnDelete = false
+	summarizer := &joinSummarizer{}
+	fs.SetRollupSummarizer(summarizer)
+
+	if err := fs.Delete("viking://resources/guides/channels", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if abstract, _ := fs.ReadAbstract("viking://resources/guides"); abstract != "Covers goroutines and channels" {
+		t.Errorf("Expected the abstract unchanged, got %q", abstract)
+	}
+	if len(summarizer.calls) != 0 {
+		t.Errorf("Expected no rollup, got %v", summarizer.calls)
+	}
+}
+
+func TestDeleteRollupDoesNotCascade(t *testing.T) {
+	fs := newRollupAGFS(t)
+	summarizer := &joinSummarizer{}
+	// A summarizer that deletes inside the directory it is rolling up
+	summarizer.onSummarize = func(uri string) {
+		if len(summarizer.calls) == 1 {
+			if err := fs.Delete(uri+"/goroutines", true); err != nil {
+				t.Errorf("Failed to delete from summarizer: %v", err)
+			}
+		}
+	}
+	fs.SetRollupSummarizer(summarizer)
+
+	if err := fs.Delete("viking://resources/guides/channels", true); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if len(summarizer.calls) != 1 {
+		t.Errorf("Expected the nested delete not to roll up again, got %v", summarizer.calls)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// statResult is a cached Stat outcome.
+type statResult struct {
+	info os.FileInfo
+	err  error
+}
+
+// statCache caches Stat results, including not-found errors, for a short
+// TTL, evicting the least recently used paths beyond maxEntries. A zero TTL
+// disables caching.
+type statCache struct {
+	ttl     time.Duration
+	fs      FileSystem
+	clock   utils.Clock
+	mu      sync.Mutex
+	entries *utils.LRU[string, statResult]
+}
+
+// newStatCache creates a statCache over fsys with the given TTL, holding
+// at most maxEntries paths.
+func newStatCache(ttl time.Duration, maxEntries int, fsys FileSystem) *statCache {
+	return &statCache{
+		ttl:     ttl,
+		fs:      fsys,
+		clock:   utils.RealClock{},
+		entries: utils.NewLRU[string, statResult](maxEntries),
+	}
+}
+
+// stat returns the cached result for path, calling Stat on a miss.
+func (c *statCache) stat(path string) (os.FileInfo, error) {
+	if c.ttl <= 0 {
+		return c.fs.Stat(path)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if r, ok := c.entries.Get(path, now); ok {
+		return r.info, r.err
+	}
+
+	info, err := c.fs.Stat(path)
+	c.entries.Put(path, statResult{info: info, err: err}, now.Add(c.ttl))
+	return info, err
+}
+
+// len returns the number of cached results.
+func (c *statCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.Len()
+}
+
+// invalidate drops every cached result. Mutations clear the whole cache
+// since moving or deleting a directory affects every path below it.
+func (c *statCache) invalidate() {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.Clear()
+}
+
+// InvalidateStatCache drops cached stat results, for callers that change
+// the tree outside AGFS.
+func (a *AGFS) InvalidateStatCache() {
+	a.statCache.invalidate()
+}
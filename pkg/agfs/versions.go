@@ -0,0 +1,194 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package agfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// versionsDir is the hidden directory, next to a versioned file, holding
+// one directory of versions per file name.
+const versionsDir = ".versions"
+
+// versionTimeFormat names versions by the UTC time they were replaced, so
+// names sort oldest first.
+const versionTimeFormat = "20060102T150405.000000000Z"
+
+// SetClock sets the clock used to name file versions.
+func (a *AGFS) SetClock(clock utils.Clock) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clock = clock
+}
+
+// versionDir returns the directory holding the versions of the file at
+// path.
+func versionDir(path string) string {
+	return filepath.Join(filepath.Dir(path), versionsDir, filepath.Base(path))
+}
+
+// forgetVersions removes the versions of the file at path, and the
+// versions directory once it is empty. The caller must hold the lock.
+func (a *AGFS) forgetVersions(path string) {
+	dir := versionDir(path)
+	if err := a.fs.RemoveAll(dir); err != nil {
+		return
+	}
+	a.fs.Remove(filepath.Dir(dir))
+}
+
+// moveVersions moves the versions of the file at oldPath to follow it to
+// newPath. The caller must hold the lock.
+func (a *AGFS) moveVersions(oldPath, newPath string) {
+	oldDir := versionDir(oldPath)
+	if _, err := a.fs.Stat(oldDir); err != nil {
+		return
+	}
+	newDir := versionDir(newPath)
+	if err := a.fs.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		return
+	}
+	if err := a.fs.Rename(oldDir, newDir); err != nil {
+		return
+	}
+	a.fs.Remove(filepath.Dir(oldDir))
+}
+
+// saveVersion keeps the current contents of the file at path as a version
+// before it is overwritten, then prunes versions beyond Config.MaxVersions.
+// Missing files and directories have nothing to keep. The caller must hold
+// the lock.
+func (a *AGFS) saveVersion(path string) error {
+	info, err := a.fs.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	data, err := a.fs.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	dir := versionDir(path)
+	if err := a.fs.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := a.clock.Now().UTC().Format(versionTimeFormat)
+	versionPath := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := a.fs.Stat(versionPath); os.IsNotExist(err) {
+			break
+		}
+		versionPath = filepath.Join(dir, fmt.Sprintf("%s-%d", name, i))
+	}
+	if err := a.writeFile(versionPath, data); err != nil {
+		return err
+	}
+
+	if a.config.MaxVersions <= 0 {
+		return nil
+	}
+	versions, err := a.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for len(versions) > a.config.MaxVersions {
+		oldest := filepath.Join(dir, versions[0].Name())
+		if err := a.fs.Remove(oldest); err != nil {
+			return err
+		}
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// ListVersions returns the kept versions of the file at the given URI,
+// oldest first. Each entry is named by its version.
+func (a *AGFS) ListVersions(uri string) ([]Entry, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	uri = a.normalizeURI(uri)
+	path := a.URIToPath(uri)
+	if path == "" {
+		return nil, ErrInvalidURI
+	}
+
+	dir := versionDir(path)
+	entries, err := a.fs.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Entry{}, nil
+		}
+		return nil, err
+	}
+
+	versions := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		versionPath := filepath.Join(dir, entry.Name())
+		versions = append(versions, Entry{
+			Name:     entry.Name(),
+			Path:     versionPath,
+			URI:      a.PathToURI(versionPath),
+			Size:     info.Size(),
+			Mode:     info.Mode(),
+			ModTime:  info.ModTime(),
+			FileType: determineFileType(uri, false),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Name < versions[j].Name })
+	return versions, nil
+}
+
+// ReadVersion returns the contents of a version of the file at the given
+// URI, as named by ListVersions.
+func (a *AGFS) ReadVersion(uri, version string) ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	versionPath, err := a.versionPath(uri, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.fs.ReadFile(versionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Revert restores the file at the given URI to a version. The contents
+// being replaced are kept as a new version when versioning is enabled, so
+// a revert can itself be undone.
+func (a *AGFS) Revert(uri, version string) error {
+	data, err := a.ReadVersion(uri, version)
+	if err != nil {
+		return err
+	}
+	return a.Write(uri, data)
+}
+
+// versionPath returns the path of a version of the file at uri.
+func (a *AGFS) versionPath(uri, version string) (string, error) {
+	path := a.URIToPath(a.normalizeURI(uri))
+	if path == "" {
+		return "", ErrInvalidURI
+	}
+	if version == "" || version == "." || version == ".." || strings.ContainsAny(version, `/\`) {
+		return "", fmt.Errorf("%w: invalid version %q", ErrInvalidURI, version)
+	}
+	return filepath.Join(versionDir(path), version), nil
+}
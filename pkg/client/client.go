@@ -11,15 +11,58 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
-// Client is a synchronous client for GoViking.
+// Client is a synchronous client for GoViking. Operations are grouped into
+// services by resource, e.g. client.Contexts.Get.
 type Client struct {
 	baseURL  string
 	httpClient *http.Client
+
+	// ETag cache for conditional GETs, keyed by request path and
+	// bounded to cacheSize entries, least recently used first out
+	cache     *utils.LRU[string, cachedResponse]
+	cacheSize int
+	cacheMu   sync.Mutex
+
+	// Default identity sent with every request
+	userID    string
+	sessionID string
+
+	// Reuse a single struct instead of allocating one per service
+	common service
+
+	Contexts  *ContextService
+	Sessions  *SessionService
+	Search    *SearchService
+	Relations *RelationService
 }
 
+// cachedResponse is a response body remembered together with its ETag.
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// DefaultCacheSize is the default number of responses kept for
+// conditional GETs.
+const DefaultCacheSize = 256
+
+// Headers carrying the default user and session of a client.
+const (
+	HeaderUser    = utils.HeaderUser
+	HeaderSession = utils.HeaderSession
+)
+
+// HeaderTotalCount carries the number of matching items of a list
+// response, regardless of limit and offset.
+const HeaderTotalCount = utils.HeaderTotalCount
+
 // Option is a client option.
 type Option func(*Client)
 
@@ -30,6 +73,30 @@ func WithHTTPClient(c *http.Client) Option {
 	}
 }
 
+// WithUser sets the default user sent with every request and used for
+// contexts created without an explicit user.
+func WithUser(id string) Option {
+	return func(client *Client) {
+		client.userID = id
+	}
+}
+
+// WithSession sets the default session sent with every request and used
+// for contexts created without an explicit session.
+func WithSession(id string) Option {
+	return func(client *Client) {
+		client.sessionID = id
+	}
+}
+
+// WithCacheSize bounds the number of responses kept for conditional GETs.
+// A size of zero or less disables the cache.
+func WithCacheSize(n int) Option {
+	return func(client *Client) {
+		client.cacheSize = n
+	}
+}
+
 // NewClient creates a new GoViking client.
 func NewClient(baseURL string, opts ...Option) (*Client, error) {
 	if baseURL == "" {
@@ -41,18 +108,24 @@ func NewClient(baseURL string, opts ...Option) (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		cacheSize: DefaultCacheSize,
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.cache = utils.NewLRU[string, cachedResponse](c.cacheSize)
+
+	c.common.client = c
+	c.Contexts = (*ContextService)(&c.common)
+	c.Sessions = (*SessionService)(&c.common)
+	c.Search = (*SearchService)(&c.common)
+	c.Relations = (*RelationService)(&c.common)
 
 	return c, nil
 }
 
-// ContextService provides context operations.
-type ContextService service
-
+// service holds the client shared by all services.
 type service struct {
 	client *Client
 }
@@ -63,8 +136,11 @@ type Context struct {
 	URI         string                 `json:"uri"`
 	Type        string                 `json:"type"`
 	Name        string                 `json:"name"`
+	Abstract    string                 `json:"abstract,omitempty"`
 	Content     string                 `json:"content"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	UserID      string                 `json:"user_id,omitempty"`
+	SessionID   string                 `json:"session_id,omitempty"`
 	CreatedAt   time.Time             `json:"created_at"`
 	UpdatedAt   time.Time             `json:"updated_at"`
 }
@@ -82,93 +158,130 @@ type Session struct {
 }
 
 // CreateContext creates a new context.
+//
+// Deprecated: Use Client.Contexts.Create.
 func (c *Client) CreateContext(ctx context.Context, req *Context) (*Context, error) {
-	resp, err := c.doRequest(ctx, "POST", "/api/v1/contexts", req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create context failed: %d", resp.StatusCode)
-	}
-
-	var result Context
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return &result, nil
+	return c.Contexts.Create(ctx, req)
 }
 
 // GetContext retrieves a context by ID.
+//
+// Deprecated: Use Client.Contexts.Get.
 func (c *Client) GetContext(ctx context.Context, id string) (*Context, error) {
-	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/contexts/%s", id), nil)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get context failed: %d", resp.StatusCode)
-	}
-
-	var result Context
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+	return c.Contexts.Get(ctx, id)
+}
 
-	return &result, nil
+// ContextExists checks if a context exists.
+//
+// Deprecated: Use Client.Contexts.Exists.
+func (c *Client) ContextExists(ctx context.Context, id string) (bool, error) {
+	return c.Contexts.Exists(ctx, id)
 }
 
 // ListContexts lists all contexts.
+//
+// Deprecated: Use Client.Contexts.List.
 func (c *Client) ListContexts(ctx context.Context) ([]Context, error) {
-	resp, err := c.doRequest(ctx, "GET", "/api/v1/contexts", nil)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.Contexts.List(ctx)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("list contexts failed: %d", resp.StatusCode)
-	}
+// DeleteContext deletes a context.
+//
+// Deprecated: Use Client.Contexts.Delete.
+func (c *Client) DeleteContext(ctx context.Context, id string) error {
+	return c.Contexts.Delete(ctx, id)
+}
 
-	var result []Context
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+// CreateSession creates a new session.
+//
+// Deprecated: Use Client.Sessions.Create.
+func (c *Client) CreateSession(ctx context.Context, req *Session) (*Session, error) {
+	return c.Sessions.Create(ctx, req)
+}
 
-	return result, nil
+// GetSession retrieves a session by ID.
+//
+// Deprecated: Use Client.Sessions.Get.
+func (c *Client) GetSession(ctx context.Context, id string, mustExist bool) (*Session, error) {
+	return c.Sessions.Get(ctx, id, mustExist)
 }
 
-// DeleteContext deletes a context.
-func (c *Client) DeleteContext(ctx context.Context, id string) error {
-	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v1/contexts/%s", id), nil)
+// SessionExists checks if a session exists.
+//
+// Deprecated: Use Client.Sessions.Exists.
+func (c *Client) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	return c.Sessions.Exists(ctx, sessionID)
+}
+
+// ListSessions lists all sessions.
+//
+// Deprecated: Use Client.Sessions.List.
+func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
+	return c.Sessions.List(ctx)
+}
+
+// ImportStats counts records of each kind.
+type ImportStats struct {
+	Contexts  int `json:"contexts"`
+	Memories  int `json:"memories"`
+	Relations int `json:"relations"`
+}
+
+// ImportResult reports the outcome of an import.
+type ImportResult struct {
+	Imported  ImportStats `json:"imported"`
+	Skipped   ImportStats `json:"skipped"`
+	Conflicts []string    `json:"conflicts,omitempty"`
+}
+
+// Export streams a NDJSON backup of the store to w. When all is set,
+// memories and relations are included alongside contexts.
+func (c *Client) Export(ctx context.Context, w io.Writer, all bool) error {
+	path := "/api/v1/export?format=ndjson"
+	if all {
+		path += "&include=all"
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("delete context failed: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("export failed: %d", resp.StatusCode)
 	}
 
-	return nil
+	_, err = io.Copy(w, resp.Body)
+	return err
 }
 
-// CreateSession creates a new session.
-func (c *Client) CreateSession(ctx context.Context, req *Session) (*Session, error) {
-	resp, err := c.doRequest(ctx, "POST", "/api/v1/sessions", req)
+// Import restores a NDJSON backup produced by Export. onConflict is either
+// "skip" or "overwrite"; empty means skip.
+func (c *Client) Import(ctx context.Context, r io.Reader, onConflict string) (*ImportResult, error) {
+	path := "/api/v1/import"
+	if onConflict != "" {
+		path += "?on_conflict=" + url.QueryEscape(onConflict)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	c.setIdentity(req)
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create session failed: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("import failed: %d", resp.StatusCode)
 	}
 
-	var result Session
+	var result ImportResult
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
@@ -176,65 +289,60 @@ func (c *Client) CreateSession(ctx context.Context, req *Session) (*Session, err
 	return &result, nil
 }
 
-// GetSession retrieves a session by ID.
-func (c *Client) GetSession(ctx context.Context, id string, mustExist bool) (*Session, error) {
-	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/sessions/%s?must_exist=%v", id, mustExist), nil)
+// ReadFile reads the raw content of a file.
+func (c *Client) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	body, status, err := c.doCachedGet(ctx, "/api/v1/fs/read?raw=true&path="+url.QueryEscape(path))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound && !mustExist {
-		return nil, nil
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get session failed: %d", resp.StatusCode)
-	}
 
-	var result Session
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("read file failed: %d", status)
 	}
 
-	return &result, nil
+	return body, nil
 }
 
-// SessionExists checks if a session exists.
-func (c *Client) SessionExists(ctx context.Context, sessionID string) (bool, error) {
-	resp, err := c.doRequest(ctx, "HEAD", fmt.Sprintf("/api/v1/sessions/%s", sessionID), nil)
+// doCachedGet performs a conditional GET. It sends the ETag of any cached
+// response as If-None-Match and serves the cached body on 304 Not Modified.
+func (c *Client) doCachedGet(ctx context.Context, path string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
 	if err != nil {
-		return false, err
+		return nil, 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		return true, nil
+	c.cacheMu.Lock()
+	cached, ok := c.cache.Get(path, time.Time{})
+	c.cacheMu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
 	}
-	if resp.StatusCode == http.StatusNotFound {
-		return false, nil
-	}
-	return false, fmt.Errorf("check session exists failed: %d", resp.StatusCode)
-}
+	c.setIdentity(req)
 
-// ListSessions lists all sessions.
-func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
-	resp, err := c.doRequest(ctx, "GET", "/api/v1/sessions", nil)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("list sessions failed: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && ok {
+		return cached.body, http.StatusOK, nil
 	}
 
-	var result []Session
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.cacheMu.Lock()
+			c.cache.Put(path, cachedResponse{etag: etag, body: body}, time.Time{})
+			c.cacheMu.Unlock()
+		}
 	}
 
-	return result, nil
+	return body, resp.StatusCode, nil
 }
 
 // doRequest performs an HTTP request.
@@ -248,19 +356,28 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
-	if err != nil {
-		return nil, err
+	var bodyReader io.Reader
+	if reqBody != nil {
+		bodyReader = bytes.NewReader(reqBody)
 	}
 
-	if reqBody != nil {
-		req.Body = nil
-		req.GetBody = func() (io.ReadCloser, error) {
-			return io.NopCloser(bytes.NewReader(reqBody)), nil
-		}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
+	if err != nil {
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	c.setIdentity(req)
 
 	return c.httpClient.Do(req)
 }
+
+// setIdentity adds the default user and session headers to req.
+func (c *Client) setIdentity(req *http.Request) {
+	if c.userID != "" {
+		req.Header.Set(HeaderUser, c.userID)
+	}
+	if c.sessionID != "" {
+		req.Header.Set(HeaderSession, c.sessionID)
+	}
+}
@@ -4,8 +4,13 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -38,3 +43,181 @@ func TestClientWithHTTPClient(t *testing.T) {
 		t.Error("Custom HTTP client not set")
 	}
 }
+
+func TestClientConditionalGet(t *testing.T) {
+	content := []byte("cached content")
+	etag := `"v1"`
+	var notModified int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		data, err := client.ReadFile(context.Background(), "notes.txt")
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if string(data) != string(content) {
+			t.Errorf("Expected %q, got %q", content, data)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("Expected 1 not-modified response, got %d", notModified)
+	}
+
+	content = []byte("new content")
+	etag = `"v2"`
+	data, err := client.ReadFile(context.Background(), "notes.txt")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != "new content" {
+		t.Errorf("Expected refreshed content, got %q", data)
+	}
+}
+
+func TestClientCacheEvictsLeastRecent(t *testing.T) {
+	var conditional int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, WithCacheSize(2))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt", "a.txt", "c.txt"} {
+		if _, err := client.ReadFile(context.Background(), name); err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+	}
+	if conditional != 1 {
+		t.Errorf("Expected 1 conditional request, got %d", conditional)
+	}
+	if n := client.cache.Len(); n != 2 {
+		t.Errorf("Expected 2 cached responses, got %d", n)
+	}
+	if _, ok := client.cache.Get("/api/v1/fs/read?raw=true&path=b.txt", time.Time{}); ok {
+		t.Error("Expected b.txt to be evicted")
+	}
+
+	// a.txt survived the eviction and is still revalidated.
+	if _, err := client.ReadFile(context.Background(), "a.txt"); err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if conditional != 2 {
+		t.Errorf("Expected 2 conditional requests, got %d", conditional)
+	}
+}
+
+func TestClientHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "unavailable", "error": "database is locked"}`))
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient(srv.URL)
+	ctx := context.Background()
+
+	health, err := c.Health(ctx)
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if !health.OK() || health.Status != "ok" {
+		t.Errorf("Expected healthy status, got %+v", health)
+	}
+
+	ready, err := c.Ready(ctx)
+	if err != nil {
+		t.Fatalf("Ready failed: %v", err)
+	}
+	if ready.OK() || ready.Code != http.StatusServiceUnavailable || ready.Error != "database is locked" {
+		t.Errorf("Expected unavailable status, got %+v", ready)
+	}
+}
+
+func TestClientSendsRequestBody(t *testing.T) {
+	var method string
+	var contentLength int64
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentLength = r.ContentLength
+		body, _ = io.ReadAll(r.Body)
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"id":"ctx-1"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient(srv.URL)
+	ctx := context.Background()
+
+	req := &Context{
+		URI:      "viking://resources/notes",
+		Type:     "file",
+		Name:     "notes",
+		Content:  "hello",
+		Metadata: map[string]interface{}{"lang": "en"},
+	}
+	if _, err := c.CreateContext(ctx, req); err != nil {
+		t.Fatalf("CreateContext failed: %v", err)
+	}
+	var got Context
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("Expected a JSON body, got %q: %v", body, err)
+	}
+	if got.URI != req.URI || got.Type != req.Type || got.Name != req.Name || got.Content != req.Content || got.Metadata["lang"] != "en" {
+		t.Errorf("Expected body %+v, got %+v", req, got)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected Content-Length %d, got %d", len(body), contentLength)
+	}
+
+	// Requests without a body still go through with an empty one
+	if _, err := c.Contexts.Get(ctx, "ctx-1"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if method != http.MethodGet || contentLength != 0 || len(body) != 0 {
+		t.Errorf("Expected an empty GET, got %s with %d bytes", method, len(body))
+	}
+	if err := c.DeleteContext(ctx, "ctx-1"); err != nil {
+		t.Fatalf("DeleteContext failed: %v", err)
+	}
+	if method != http.MethodDelete || contentLength != 0 || len(body) != 0 {
+		t.Errorf("Expected an empty DELETE, got %s with %d bytes", method, len(body))
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ContextService provides context operations.
+type ContextService service
+
+// Create creates a new context. An empty user or session is filled in from
+// the client defaults.
+func (s *ContextService) Create(ctx context.Context, req *Context) (*Context, error) {
+	if req != nil && (req.UserID == "" || req.SessionID == "") {
+		withIdentity := *req
+		if withIdentity.UserID == "" {
+			withIdentity.UserID = s.client.userID
+		}
+		if withIdentity.SessionID == "" {
+			withIdentity.SessionID = s.client.sessionID
+		}
+		req = &withIdentity
+	}
+
+	resp, err := s.client.doRequest(ctx, "POST", "/api/v1/contexts", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create context failed: %d", resp.StatusCode)
+	}
+
+	var result Context
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Get retrieves a context by ID.
+func (s *ContextService) Get(ctx context.Context, id string) (*Context, error) {
+	body, status, err := s.client.doCachedGet(ctx, fmt.Sprintf("/api/v1/contexts/%s", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("get context failed: %d", status)
+	}
+
+	var result Context
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Exists checks if a context exists without fetching it.
+func (s *ContextService) Exists(ctx context.Context, id string) (bool, error) {
+	resp, err := s.client.doRequest(ctx, "HEAD", fmt.Sprintf("/api/v1/contexts/%s", id), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return true, nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, fmt.Errorf("check context exists failed: %d", resp.StatusCode)
+}
+
+// List lists all contexts.
+func (s *ContextService) List(ctx context.Context) ([]Context, error) {
+	resp, err := s.client.doRequest(ctx, "GET", "/api/v1/contexts", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list contexts failed: %d", resp.StatusCode)
+	}
+
+	var result []Context
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ContextPage is one page of contexts together with the number of
+// contexts across all pages.
+type ContextPage struct {
+	Items []Context
+	Total int
+}
+
+// ListPaged lists up to limit contexts starting at offset. A limit <= 0
+// lists all contexts from offset.
+func (s *ContextService) ListPaged(ctx context.Context, limit, offset int) (*ContextPage, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	path := "/api/v1/contexts"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	resp, err := s.client.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list contexts failed: %d", resp.StatusCode)
+	}
+
+	page := &ContextPage{}
+	if err := json.NewDecoder(resp.Body).Decode(&page.Items); err != nil {
+		return nil, err
+	}
+	if page.Total, err = strconv.Atoi(resp.Header.Get(HeaderTotalCount)); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", HeaderTotalCount, err)
+	}
+
+	return page, nil
+}
+
+// Delete deletes a context.
+func (s *ContextService) Delete(ctx context.Context, id string) error {
+	resp, err := s.client.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v1/contexts/%s", id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("delete context failed: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// HealthStatus is the response of a health endpoint.
+type HealthStatus struct {
+	// Code is the HTTP status code of the response.
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+	Time   string `json:"time,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// OK reports whether the endpoint answered 200 OK.
+func (s *HealthStatus) OK() bool {
+	return s.Code == http.StatusOK
+}
+
+// Health checks that the server is up. An error means the server could not
+// be reached; an unhealthy server is reported through the status.
+func (c *Client) Health(ctx context.Context) (*HealthStatus, error) {
+	return c.getHealth(ctx, "/health")
+}
+
+// Ready checks that the server and its storage can serve requests.
+func (c *Client) Ready(ctx context.Context) (*HealthStatus, error) {
+	return c.getHealth(ctx, "/health/ready")
+}
+
+func (c *Client) getHealth(ctx context.Context, path string) (*HealthStatus, error) {
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	status := &HealthStatus{}
+	// Proxies in front of an unhealthy server may not answer JSON, so a
+	// body that does not decode still yields the status code.
+	json.NewDecoder(resp.Body).Decode(status)
+	status.Code = resp.StatusCode
+	return status, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RelationService provides relation operations.
+type RelationService service
+
+// Relation is a link from a source URI to a target URI.
+type Relation struct {
+	ID            string    `json:"id"`
+	Source        string    `json:"source"`
+	Target        string    `json:"target"`
+	Type          string    `json:"type,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Bidirectional bool      `json:"bidirectional"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Create links source to target. Both URIs must exist on the server.
+func (s *RelationService) Create(ctx context.Context, req *Relation) (*Relation, error) {
+	resp, err := s.client.doRequest(ctx, "POST", "/api/v1/relations", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create relation failed: %d", resp.StatusCode)
+	}
+
+	var result Relation
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// List lists the relations of uri in direction: "outgoing", "incoming" or
+// "both". An empty direction means both.
+func (s *RelationService) List(ctx context.Context, uri, direction string) ([]Relation, error) {
+	params := url.Values{"uri": {uri}}
+	if direction != "" {
+		params.Set("direction", direction)
+	}
+	resp, err := s.client.doRequest(ctx, "GET", "/api/v1/relations?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list relations failed: %d", resp.StatusCode)
+	}
+
+	var result []Relation
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete deletes a relation by ID.
+func (s *RelationService) Delete(ctx context.Context, id string) error {
+	return s.delete(ctx, url.Values{"id": {id}})
+}
+
+// DeleteBetween deletes every relation from source to target.
+func (s *RelationService) DeleteBetween(ctx context.Context, source, target string) error {
+	return s.delete(ctx, url.Values{"source": {source}, "target": {target}})
+}
+
+func (s *RelationService) delete(ctx context.Context, params url.Values) error {
+	resp, err := s.client.doRequest(ctx, "DELETE", "/api/v1/relations?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("delete relation failed: %d", resp.StatusCode)
+	}
+
+	return nil
+}
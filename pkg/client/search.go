@@ -0,0 +1,192 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearchService provides search operations.
+type SearchService service
+
+// Contexts returns the contexts whose name, content or URI contain query,
+// ignoring case. Matching happens on the client over the full context list.
+func (s *SearchService) Contexts(ctx context.Context, query string) ([]Context, error) {
+	contexts, err := s.client.Contexts.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	var results []Context
+	for _, c := range contexts {
+		if strings.Contains(strings.ToLower(c.Name), query) ||
+			strings.Contains(strings.ToLower(c.Content), query) ||
+			strings.Contains(strings.ToLower(c.URI), query) {
+			results = append(results, c)
+		}
+	}
+
+	return results, nil
+}
+
+// SearchResult is a context found by a server-side search.
+type SearchResult struct {
+	ID       string         `json:"id"`
+	URI      string         `json:"uri"`
+	Title    string         `json:"title"`
+	Content  string         `json:"content"`
+	Score    float64        `json:"score"`
+	RawScore float64        `json:"raw_score"`
+	Type     string         `json:"type"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// TraversalStep is a directory the retriever visited. Parent is empty for
+// starting points.
+type TraversalStep struct {
+	URI    string  `json:"uri"`
+	Parent string  `json:"parent,omitempty"`
+	Depth  int     `json:"depth"`
+	Score  float64 `json:"score"`
+}
+
+// TraversalEvent records where the retriever converged ("converged") or
+// pruned the tree ("below_threshold", "max_depth" or
+// "max_directories_visited").
+type TraversalEvent struct {
+	Type    string  `json:"type"`
+	URI     string  `json:"uri,omitempty"`
+	Parent  string  `json:"parent,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+	Message string  `json:"message"`
+}
+
+// Traversal is the path the retriever took for one context type.
+type Traversal struct {
+	ContextType string           `json:"context_type"`
+	Steps       []TraversalStep  `json:"steps"`
+	Events      []TraversalEvent `json:"events,omitempty"`
+}
+
+// Explanation is a search's results together with the traversals that
+// produced them.
+type Explanation struct {
+	Query      string         `json:"query"`
+	Results    []SearchResult `json:"results"`
+	Traversals []Traversal    `json:"traversals"`
+}
+
+// Explain runs query on the server and returns the results along with the
+// directories the retriever visited to find them.
+func (s *SearchService) Explain(ctx context.Context, query string) (*Explanation, error) {
+	resp, err := s.client.doRequest(ctx, "GET", "/api/v1/search/explain?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("explain search failed: %d", resp.StatusCode)
+	}
+
+	var result Explanation
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// FindRequest is a search whose results are grouped by context type.
+type FindRequest struct {
+	Query     string `json:"query"`
+	SessionID string `json:"session_id,omitempty"`
+	// ContextTypes limits the search to "memory", "resource" or "skill";
+	// empty searches all three.
+	ContextTypes []string `json:"context_types,omitempty"`
+	// Limit caps the results in each bucket; zero uses the server default.
+	Limit int `json:"limit,omitempty"`
+	// Trace asks for the query plan and per-query results.
+	Trace bool `json:"trace,omitempty"`
+}
+
+// MatchedContext is a context found by Find.
+type MatchedContext struct {
+	URI         string  `json:"uri"`
+	ContextType string  `json:"context_type"`
+	IsLeaf      bool    `json:"is_leaf"`
+	Abstract    string  `json:"abstract"`
+	Overview    string  `json:"overview,omitempty"`
+	Category    string  `json:"category"`
+	Score       float64 `json:"score"`
+	RawScore    float64 `json:"raw_score"`
+	MatchReason string  `json:"match_reason,omitempty"`
+}
+
+// TypedQuery is a query the server ran against one context type.
+type TypedQuery struct {
+	Query       string `json:"query"`
+	ContextType string `json:"context_type"`
+	Intent      string `json:"intent"`
+	Priority    int    `json:"priority"`
+}
+
+// QueryPlan is the set of typed queries a find ran.
+type QueryPlan struct {
+	Queries   []TypedQuery `json:"queries"`
+	Reasoning string       `json:"reasoning"`
+}
+
+// QueryResult is what one typed query of a find matched. ThinkingTrace is
+// the retriever's raw decision trace, if it kept one.
+type QueryResult struct {
+	Query           TypedQuery       `json:"query"`
+	MatchedContexts []MatchedContext `json:"matched_contexts"`
+	ThinkingTrace   json.RawMessage  `json:"thinking_trace,omitempty"`
+}
+
+// FindResult holds a find's matches grouped by context type. QueryPlan and
+// QueryResults are set when the request asked for a trace.
+type FindResult struct {
+	Memories     []MatchedContext `json:"memories"`
+	Resources    []MatchedContext `json:"resources"`
+	Skills       []MatchedContext `json:"skills"`
+	QueryPlan    *QueryPlan       `json:"query_plan,omitempty"`
+	QueryResults []QueryResult    `json:"query_results,omitempty"`
+	Total        int              `json:"total"`
+}
+
+// Find searches memories, resources and skills in one call and returns the
+// matches grouped by type. An empty session is filled in from the client
+// default.
+func (c *Client) Find(ctx context.Context, req *FindRequest) (*FindResult, error) {
+	if req.SessionID == "" && c.sessionID != "" {
+		withSession := *req
+		withSession.SessionID = c.sessionID
+		req = &withSession
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/find", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("find failed: %d", resp.StatusCode)
+	}
+
+	var result FindResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
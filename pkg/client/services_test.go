@@ -0,0 +1,357 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newServiceTestServer starts an in-memory contexts and sessions API.
+func newServiceTestServer(t *testing.T) *Client {
+	t.Helper()
+
+	var mu sync.Mutex
+	contexts := map[string]Context{}
+	var contextOrder []string
+	sessions := map[string]Session{}
+	var sessionOrder []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/contexts", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "POST":
+			var c Context
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.ID = "ctx-" + c.Name
+			contexts[c.ID] = c
+			contextOrder = append(contextOrder, c.ID)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(c)
+		case "GET":
+			list := []Context{}
+			for _, id := range contextOrder {
+				if c, ok := contexts[id]; ok {
+					list = append(list, c)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		}
+	})
+	mux.HandleFunc("/api/v1/contexts/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/contexts/")
+		c, ok := contexts[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(c)
+		case "HEAD":
+		case "DELETE":
+			delete(contexts, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.HandleFunc("/api/v1/sessions", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "POST":
+			var s Session
+			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.ID = "sess-" + s.UserID
+			sessions[s.ID] = s
+			sessionOrder = append(sessionOrder, s.ID)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(s)
+		case "GET":
+			list := []Session{}
+			for _, id := range sessionOrder {
+				list = append(list, sessions[id])
+			}
+			json.NewEncoder(w).Encode(list)
+		}
+	})
+	mux.HandleFunc("/api/v1/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		s, ok := sessions[strings.TrimPrefix(r.URL.Path, "/api/v1/sessions/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "HEAD" {
+			return
+		}
+		json.NewEncoder(w).Encode(s)
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestContextService(t *testing.T) {
+	c := newServiceTestServer(t)
+	ctx := context.Background()
+
+	created, err := c.Contexts.Create(ctx, &Context{Name: "notes", URI: "viking://resources/notes", Content: "hello"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID != "ctx-notes" {
+		t.Errorf("Expected ID ctx-notes, got %s", created.ID)
+	}
+
+	got, err := c.Contexts.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Content != "hello" {
+		t.Errorf("Expected content hello, got %q", got.Content)
+	}
+
+	exists, err := c.Contexts.Exists(ctx, created.ID)
+	if err != nil || !exists {
+		t.Errorf("Expected context to exist, got %v, %v", exists, err)
+	}
+	exists, err = c.ContextExists(ctx, "missing")
+	if err != nil || exists {
+		t.Errorf("Expected context not to exist, got %v, %v", exists, err)
+	}
+
+	list, err := c.Contexts.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("Expected 1 context, got %d", len(list))
+	}
+
+	if err := c.Contexts.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Contexts.Get(ctx, created.ID); err == nil {
+		t.Error("Expected error getting deleted context")
+	}
+}
+
+func TestSessionService(t *testing.T) {
+	c := newServiceTestServer(t)
+	ctx := context.Background()
+
+	created, err := c.Sessions.Create(ctx, &Session{UserID: "alice"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	got, err := c.Sessions.Get(ctx, created.ID, true)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.UserID != "alice" {
+		t.Errorf("Expected user alice, got %q", got.UserID)
+	}
+
+	missing, err := c.Sessions.Get(ctx, "missing", false)
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil session without error, got %v, %v", missing, err)
+	}
+	if _, err := c.Sessions.Get(ctx, "missing", true); err == nil {
+		t.Error("Expected error for missing session with mustExist")
+	}
+
+	exists, err := c.Sessions.Exists(ctx, created.ID)
+	if err != nil || !exists {
+		t.Errorf("Expected session to exist, got %v, %v", exists, err)
+	}
+	exists, err = c.Sessions.Exists(ctx, "missing")
+	if err != nil || exists {
+		t.Errorf("Expected session not to exist, got %v, %v", exists, err)
+	}
+
+	list, err := c.Sessions.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("Expected 1 session, got %d", len(list))
+	}
+}
+
+func TestSearchServiceContexts(t *testing.T) {
+	c := newServiceTestServer(t)
+	ctx := context.Background()
+
+	for _, req := range []*Context{
+		{Name: "Golang", URI: "viking://resources/go", Content: "concurrency"},
+		{Name: "python", URI: "viking://resources/py", Content: "Scripting with GO bindings"},
+		{Name: "rust", URI: "viking://resources/rust", Content: "ownership"},
+	} {
+		if _, err := c.Contexts.Create(ctx, req); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	results, err := c.Search.Contexts(ctx, "go")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "Golang" || results[1].Name != "python" {
+		t.Errorf("Unexpected results: %s, %s", results[0].Name, results[1].Name)
+	}
+
+	results, err = c.Search.Contexts(ctx, "resources/rust")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "rust" {
+		t.Errorf("Expected URI match on rust, got %v", results)
+	}
+}
+
+func TestSearchServiceExplain(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/search/explain" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query().Get("q")
+		w.Write([]byte(`{"query":"go routines","results":[{"uri":"viking://resources/go","score":0.9}],
+			"traversals":[{"context_type":"resource","steps":[{"uri":"viking://resources","depth":0,"score":0}],
+			"events":[{"type":"converged","uri":"viking://resources","message":"Search converged"}]}]}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	explanation, err := c.Search.Explain(context.Background(), "go routines")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if gotQuery != "go routines" {
+		t.Errorf("Expected query %q, got %q", "go routines", gotQuery)
+	}
+	if len(explanation.Results) != 1 || len(explanation.Traversals) != 1 {
+		t.Fatalf("Expected 1 result and 1 traversal, got %+v", explanation)
+	}
+	if events := explanation.Traversals[0].Events; len(events) != 1 || events[0].Type != "converged" {
+		t.Errorf("Expected a convergence event, got %+v", events)
+	}
+}
+
+func TestClientFind(t *testing.T) {
+	var got FindRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/find" || r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"memories":[{"uri":"viking://user/memories/editor","context_type":"memory","score":0.8}],
+			"resources":[{"uri":"viking://resources/go","context_type":"resource","score":0.9}],
+			"skills":[],"query_plan":{"queries":[{"query":"go","context_type":"resource"}]},"total":2}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, WithSession("sess-1"))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	result, err := c.Find(context.Background(), &FindRequest{Query: "go", ContextTypes: []string{"memory", "resource"}, Trace: true})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if got.Query != "go" || got.SessionID != "sess-1" || len(got.ContextTypes) != 2 || !got.Trace {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if len(result.Memories) != 1 || len(result.Resources) != 1 || result.Total != 2 {
+		t.Errorf("Expected 1 memory and 1 resource, got %+v", result)
+	}
+	if result.QueryPlan == nil || len(result.QueryPlan.Queries) != 1 {
+		t.Errorf("Expected the query plan, got %+v", result.QueryPlan)
+	}
+}
+
+func TestFlatMethodsDelegate(t *testing.T) {
+	c := newServiceTestServer(t)
+	ctx := context.Background()
+
+	created, err := c.CreateContext(ctx, &Context{Name: "legacy"})
+	if err != nil {
+		t.Fatalf("CreateContext failed: %v", err)
+	}
+	got, err := c.Contexts.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Name != "legacy" {
+		t.Errorf("Expected legacy, got %q", got.Name)
+	}
+}
+
+func TestDefaultIdentity(t *testing.T) {
+	var gotUser, gotSession string
+	var body Context
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = r.Header.Get(HeaderUser)
+		gotSession = r.Header.Get(HeaderSession)
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	c, _ := NewClient(srv.URL, WithUser("alice"), WithSession("sess-1"))
+	ctx := context.Background()
+
+	req := &Context{Name: "notes"}
+	if _, err := c.Contexts.Create(ctx, req); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if gotUser != "alice" || gotSession != "sess-1" {
+		t.Errorf("Expected alice/sess-1 headers, got %q/%q", gotUser, gotSession)
+	}
+	if body.UserID != "alice" || body.SessionID != "sess-1" {
+		t.Errorf("Expected alice/sess-1 in body, got %q/%q", body.UserID, body.SessionID)
+	}
+	if req.UserID != "" {
+		t.Errorf("Expected caller's request to be unchanged, got user %q", req.UserID)
+	}
+
+	if _, err := c.Contexts.Create(ctx, &Context{Name: "other", UserID: "bob"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if body.UserID != "bob" || body.SessionID != "sess-1" {
+		t.Errorf("Expected explicit user to win, got %q/%q", body.UserID, body.SessionID)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SessionService provides session operations.
+type SessionService service
+
+// Create creates a new session.
+func (s *SessionService) Create(ctx context.Context, req *Session) (*Session, error) {
+	resp, err := s.client.doRequest(ctx, "POST", "/api/v1/sessions", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create session failed: %d", resp.StatusCode)
+	}
+
+	var result Session
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Get retrieves a session by ID. If mustExist is false, a missing session
+// returns nil without error.
+func (s *SessionService) Get(ctx context.Context, id string, mustExist bool) (*Session, error) {
+	resp, err := s.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/sessions/%s?must_exist=%v", id, mustExist), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && !mustExist {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get session failed: %d", resp.StatusCode)
+	}
+
+	var result Session
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Fork creates a new session holding the messages of sessionID up to and
+// including uptoMessageIndex. The fork's metadata names its parent under
+// parent_session_id.
+func (s *SessionService) Fork(ctx context.Context, sessionID string, uptoMessageIndex int) (*Session, error) {
+	req := map[string]int{"upto_message_index": uptoMessageIndex}
+	resp, err := s.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/sessions/%s/fork", sessionID), req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("fork session failed: %d", resp.StatusCode)
+	}
+
+	var result Session
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Exists checks if a session exists.
+func (s *SessionService) Exists(ctx context.Context, sessionID string) (bool, error) {
+	resp, err := s.client.doRequest(ctx, "HEAD", fmt.Sprintf("/api/v1/sessions/%s", sessionID), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return true, nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, fmt.Errorf("check session exists failed: %d", resp.StatusCode)
+}
+
+// List lists all sessions.
+func (s *SessionService) List(ctx context.Context) ([]Session, error) {
+	resp, err := s.client.doRequest(ctx, "GET", "/api/v1/sessions", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list sessions failed: %d", resp.StatusCode)
+	}
+
+	var result []Session
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -25,19 +26,53 @@ type Config struct {
 
 	// Retrieval configuration
 	Retrieval RetrievalConfig `mapstructure:"retrieval"`
+
+	// Persistence configuration
+	Persistence PersistenceConfig `mapstructure:"persistence"`
+
+	// CLI configuration
+	CLI CLIConfig `mapstructure:"cli"`
+}
+
+// CLIConfig holds defaults for the command line client.
+type CLIConfig struct {
+	// User and Session identify the caller when --user and --session
+	// are not given.
+	User    string `mapstructure:"user"`
+	Session string `mapstructure:"session"`
 }
 
 // ServerConfig holds server configuration.
 type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+
+	// ShutdownTimeout is how long in-flight requests may run after a
+	// shutdown signal before the server closes their connections.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 // StorageConfig holds storage configuration.
 type StorageConfig struct {
+	// Type is the storage backend, "sqlite" for the database file at Path
+	// or "postgres" for the server at DSN.
 	Type     string `mapstructure:"type"`
 	Path     string `mapstructure:"path"`
+	DSN      string `mapstructure:"dsn"`
 	InMemory bool   `mapstructure:"in_memory"`
+
+	// ContentDir stores context content larger than ContentInlineLimit
+	// bytes outside the database. Empty keeps all content inline.
+	ContentDir         string `mapstructure:"content_dir"`
+	ContentInlineLimit int    `mapstructure:"content_inline_limit"`
+}
+
+// PersistenceConfig holds where session contexts are persisted.
+type PersistenceConfig struct {
+	// Backend is "file" for JSON files under Path, or "storage" for the
+	// context_snapshots table of the configured storage.
+	Backend string `mapstructure:"backend"`
+	Path    string `mapstructure:"path"`
 }
 
 // LLMConfig holds LLM provider configuration.
@@ -50,9 +85,47 @@ type LLMConfig struct {
 
 // RetrievalConfig holds retrieval configuration.
 type RetrievalConfig struct {
-	EmbeddingModel string  `mapstructure:"embedding_model"`
-	Similarity    float64 `mapstructure:"similarity_threshold"`
-	MaxResults    int     `mapstructure:"max_results"`
+	EmbeddingModel      string  `mapstructure:"embedding_model"`
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
+	MaxResults          int     `mapstructure:"max_results"`
+	Tokenizer           string  `mapstructure:"tokenizer"`
+
+	// EmbeddingDimension is the vector size of the zero vectors used when
+	// no embedder is configured
+	EmbeddingDimension int `mapstructure:"embedding_dimension"`
+
+	// MaxResultsCap is the most results one search may ask for; larger
+	// requested limits are clamped to it. 0 removes the cap.
+	MaxResultsCap int `mapstructure:"max_results_cap"`
+
+	// StrictEmbedding fails a search when its query cannot be embedded,
+	// instead of falling back to keyword matching
+	StrictEmbedding bool `mapstructure:"strict_embedding"`
+
+	// KeywordWeight and HotnessWeight, between 0 and 1, set the share of
+	// a search score given to keyword relevance and to access hotness.
+	KeywordWeight float64 `mapstructure:"keyword_weight"`
+	HotnessWeight float64 `mapstructure:"hotness_weight"`
+
+	// IndexSource picks the text indexed for keyword search: "abstract",
+	// "content", or "both", where abstract terms count AbstractBoost times
+	IndexSource   string `mapstructure:"index_source"`
+	AbstractBoost int    `mapstructure:"abstract_boost"`
+
+	// QueryExpansion searches synonyms of the query's words alongside it
+	QueryExpansion QueryExpansionConfig `mapstructure:"query_expansion"`
+}
+
+// QueryExpansionConfig holds query expansion configuration.
+type QueryExpansionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Weight, between 0 and 1, is the share of its score a match found
+	// only by an expanded query keeps.
+	Weight float64 `mapstructure:"weight"`
+
+	// Synonyms maps lowercase words to the words added after them
+	Synonyms map[string][]string `mapstructure:"synonyms"`
 }
 
 // Load loads configuration from file and environment variables.
@@ -62,14 +135,32 @@ func Load(configPath string) (*Config, error) {
 	// Set defaults
 	v.SetDefault("server.host", "localhost")
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.shutdown_timeout", "5s")
 	v.SetDefault("storage.type", "sqlite")
 	v.SetDefault("storage.path", "openviking.db")
+	v.SetDefault("storage.dsn", "")
 	v.SetDefault("storage.in_memory", false)
+	v.SetDefault("storage.content_dir", "")
+	v.SetDefault("storage.content_inline_limit", 4096)
+	v.SetDefault("persistence.backend", "file")
+	v.SetDefault("persistence.path", "./data")
 	v.SetDefault("llm.provider", "openai")
 	v.SetDefault("llm.model", "gpt-4")
 	v.SetDefault("retrieval.embedding_model", "text-embedding-3-small")
+	v.SetDefault("retrieval.strict_embedding", false)
+	v.SetDefault("retrieval.embedding_dimension", 1536)
 	v.SetDefault("retrieval.similarity_threshold", 0.7)
 	v.SetDefault("retrieval.max_results", 10)
+	v.SetDefault("retrieval.max_results_cap", 100)
+	v.SetDefault("retrieval.tokenizer", "raw")
+	v.SetDefault("retrieval.keyword_weight", 0.5)
+	v.SetDefault("retrieval.hotness_weight", 0.2)
+	v.SetDefault("retrieval.index_source", "abstract")
+	v.SetDefault("retrieval.abstract_boost", 2)
+	v.SetDefault("retrieval.query_expansion.enabled", false)
+	v.SetDefault("retrieval.query_expansion.weight", 0.8)
+	v.SetDefault("cli.user", "")
+	v.SetDefault("cli.session", "")
 
 	// If config path provided, use it
 	if configPath != "" {
@@ -6,6 +6,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadDefault(t *testing.T) {
@@ -30,12 +31,27 @@ func TestConfigDefaults(t *testing.T) {
 	if cfg.Server.Port != 8080 {
 		t.Errorf("Expected server.port 8080, got %d", cfg.Server.Port)
 	}
+	if cfg.Server.ShutdownTimeout != 5*time.Second {
+		t.Errorf("Expected server.shutdown_timeout 5s, got %v", cfg.Server.ShutdownTimeout)
+	}
 	if cfg.Storage.Type != "sqlite" {
 		t.Errorf("Expected storage.type 'sqlite', got '%s'", cfg.Storage.Type)
 	}
 	if cfg.LLM.Provider != "openai" {
 		t.Errorf("Expected llm.provider 'openai', got '%s'", cfg.LLM.Provider)
 	}
+	if cfg.Persistence.Backend != "file" {
+		t.Errorf("Expected persistence.backend 'file', got '%s'", cfg.Persistence.Backend)
+	}
+	if cfg.Retrieval.QueryExpansion.Enabled || cfg.Retrieval.QueryExpansion.Weight != 0.8 {
+		t.Errorf("Expected query expansion off with weight 0.8, got %+v", cfg.Retrieval.QueryExpansion)
+	}
+	if cfg.Retrieval.EmbeddingDimension != 1536 {
+		t.Errorf("Expected retrieval.embedding_dimension 1536, got %d", cfg.Retrieval.EmbeddingDimension)
+	}
+	if cfg.Retrieval.MaxResultsCap != 100 {
+		t.Errorf("Expected retrieval.max_results_cap 100, got %d", cfg.Retrieval.MaxResultsCap)
+	}
 }
 
 func TestSaveAndLoad(t *testing.T) {
@@ -46,6 +62,7 @@ func TestSaveAndLoad(t *testing.T) {
 		},
 		Storage: StorageConfig{
 			Type:     "memory",
+			DSN:      "postgres://localhost/goviking",
 			InMemory: true,
 		},
 		LLM: LLMConfig{
@@ -81,6 +98,9 @@ func TestSaveAndLoad(t *testing.T) {
 	if loaded.Storage.Type != "memory" {
 		t.Errorf("Expected storage.type 'memory', got '%s'", loaded.Storage.Type)
 	}
+	if loaded.Storage.DSN != "postgres://localhost/goviking" {
+		t.Errorf("Expected storage.dsn 'postgres://localhost/goviking', got '%s'", loaded.Storage.DSN)
+	}
 	if loaded.LLM.Provider != "anthropic" {
 		t.Errorf("Expected provider 'anthropic', got '%s'", loaded.LLM.Provider)
 	}
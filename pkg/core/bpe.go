@@ -0,0 +1,249 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenizerDirEnv names the environment variable pointing at the directory
+// holding BPE vocabularies, one <encoding>.tiktoken file per encoding. It
+// defaults to ~/.goviking/tokenizers.
+const TokenizerDirEnv = "GOVIKING_TOKENIZER_DIR"
+
+// modelEncodings maps model name prefixes to the encoding they use.
+var modelEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", "o200k_base"},
+	{"gpt-4", "cl100k_base"},
+	{"gpt-3.5-turbo", "cl100k_base"},
+	{"text-embedding-ada-002", "cl100k_base"},
+	{"text-embedding-3", "cl100k_base"},
+}
+
+// BPETokenCounter counts tokens with byte pair encoding over a
+// tiktoken-format vocabulary, splitting text the way cl100k_base does.
+type BPETokenCounter struct {
+	ranks map[string]int
+}
+
+// NewBPETokenCounter reads a vocabulary in tiktoken format: one token per
+// line, base64 encoded, followed by its rank.
+func NewBPETokenCounter(r io.Reader) (*BPETokenCounter, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid vocabulary line %d", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on vocabulary line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on vocabulary line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("empty vocabulary")
+	}
+	return &BPETokenCounter{ranks: ranks}, nil
+}
+
+// LoadBPETokenCounter reads a tiktoken-format vocabulary file.
+func LoadBPETokenCounter(path string) (*BPETokenCounter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewBPETokenCounter(f)
+}
+
+// NewBPETokenCounterForModel loads the vocabulary used by model from the
+// tokenizer directory (see TokenizerDirEnv).
+func NewBPETokenCounterForModel(model string) (*BPETokenCounter, error) {
+	encoding := ""
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(model, m.prefix) {
+			encoding = m.encoding
+			break
+		}
+	}
+	if encoding == "" {
+		return nil, fmt.Errorf("unknown model %q", model)
+	}
+	// Other encodings split text differently
+	if encoding != "cl100k_base" {
+		return nil, fmt.Errorf("unsupported encoding %s for model %q", encoding, model)
+	}
+
+	dir := os.Getenv(TokenizerDirEnv)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find tokenizer directory: %w", err)
+		}
+		dir = filepath.Join(home, ".goviking", "tokenizers")
+	}
+	return LoadBPETokenCounter(filepath.Join(dir, encoding+".tiktoken"))
+}
+
+// CountTokens returns the number of tokens text encodes to.
+func (c *BPETokenCounter) CountTokens(text string) int {
+	count := 0
+	for _, piece := range splitPieces(text) {
+		count += c.countPiece([]byte(piece))
+	}
+	return count
+}
+
+// countPiece byte-pair encodes a piece of text, repeatedly merging the
+// adjacent pair with the lowest rank, and returns the number of tokens.
+func (c *BPETokenCounter) countPiece(piece []byte) int {
+	if _, ok := c.ranks[string(piece)]; ok {
+		return 1
+	}
+
+	// bounds[i] is where the i-th part starts; parts start as single bytes
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := c.ranks[string(piece[bounds[i]:bounds[i+2]])]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+// splitPieces splits text into the pieces cl100k_base encodes separately,
+// matching its pattern:
+//
+//	'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?+\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]++[\r\n]*|\s*[\r\n]|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead or possessive quantifiers, so the
+// alternatives are tried by hand, in order.
+func splitPieces(text string) []string {
+	runes := []rune(text)
+	var pieces []string
+	for i := 0; i < len(runes); {
+		n := matchPiece(runes, i)
+		pieces = append(pieces, string(runes[i:i+n]))
+		i += n
+	}
+	return pieces
+}
+
+// matchPiece returns the length in runes of the piece starting at i.
+func matchPiece(runes []rune, i int) int {
+	at := func(j int) rune {
+		if j < len(runes) {
+			return runes[j]
+		}
+		return utf8.RuneError
+	}
+	inText := func(j int) bool { return j < len(runes) }
+	isNewline := func(r rune) bool { return r == '\r' || r == '\n' }
+
+	// '(?i:[sdmt]|ll|ve|re)
+	if runes[i] == '\'' && inText(i+1) {
+		switch unicode.ToLower(at(i + 1)) {
+		case 's', 'd', 'm', 't':
+			return 2
+		}
+		if inText(i + 2) {
+			switch strings.ToLower(string(runes[i+1 : i+3])) {
+			case "ll", "ve", "re":
+				return 3
+			}
+		}
+	}
+
+	// [^\r\n\p{L}\p{N}]?+\p{L}+
+	j := i
+	if !isNewline(runes[j]) && !unicode.IsLetter(runes[j]) && !unicode.IsNumber(runes[j]) {
+		j++
+	}
+	if inText(j) && unicode.IsLetter(runes[j]) {
+		for inText(j) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		return j - i
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(runes[i]) {
+		j := i
+		for inText(j) && j-i < 3 && unicode.IsNumber(runes[j]) {
+			j++
+		}
+		return j - i
+	}
+
+	// ?[^\s\p{L}\p{N}]++[\r\n]*
+	isPunct := func(r rune) bool { return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r) }
+	j = i
+	if runes[j] == ' ' && inText(j+1) && isPunct(runes[j+1]) {
+		j++
+	}
+	if isPunct(runes[j]) {
+		for inText(j) && isPunct(runes[j]) {
+			j++
+		}
+		for inText(j) && isNewline(runes[j]) {
+			j++
+		}
+		return j - i
+	}
+
+	// The rest are whitespace runs
+	end := i
+	for inText(end) && unicode.IsSpace(runes[end]) {
+		end++
+	}
+
+	// \s*[\r\n]
+	for k := end - 1; k >= i; k-- {
+		if isNewline(runes[k]) {
+			return k + 1 - i
+		}
+	}
+
+	// \s+(?!\S), leaving the last space to prefix the next word
+	if end == len(runes) || end-i == 1 {
+		return end - i
+	}
+	return end - 1 - i
+}
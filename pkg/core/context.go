@@ -5,20 +5,39 @@
 package core
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // ContextType represents the type of context.
 type ContextType string
 
 const (
-	ContextTypeSkill   ContextType = "skill"
-	ContextTypeMemory  ContextType = "memory"
-	ContextTypeResource ContextType = "resource"
+	ContextTypeSkill    ContextType = utils.ContextTypeSkill
+	ContextTypeMemory   ContextType = utils.ContextTypeMemory
+	ContextTypeResource ContextType = utils.ContextTypeResource
 )
 
+// Valid reports whether t is a known context type.
+func (t ContextType) Valid() bool {
+	return utils.IsValidContextType(string(t))
+}
+
+// ParseContextType converts a context type name, such as the storage
+// context_type column, to a ContextType. Case is ignored; unknown names
+// return utils.ErrInvalidContextType.
+func ParseContextType(s string) (ContextType, error) {
+	name, err := utils.ParseContextType(s)
+	if err != nil {
+		return "", err
+	}
+	return ContextType(name), nil
+}
+
 // Category represents the category of context.
 type Category string
 
@@ -62,7 +81,11 @@ type Context struct {
 	URI          string            `json:"uri"`
 	ParentURI    string            `json:"parent_uri,omitempty"`
 	IsLeaf       bool              `json:"is_leaf"`
+	Name         string            `json:"name,omitempty"`
+	Description  string            `json:"description,omitempty"`
 	Abstract     string            `json:"abstract"`
+	Overview     string            `json:"overview,omitempty"`
+	Content      string            `json:"content,omitempty"`
 	ContextType  ContextType       `json:"context_type"`
 	Category     Category          `json:"category,omitempty"`
 	CreatedAt    time.Time         `json:"created_at"`
@@ -77,10 +100,27 @@ type Context struct {
 	Tier         ContextTier       `json:"tier"`
 }
 
+// ContextOption sets an optional field of a new Context.
+type ContextOption func(*Context)
+
+// WithName sets the context's display name.
+func WithName(name string) ContextOption {
+	return func(c *Context) {
+		c.Name = name
+	}
+}
+
+// WithDescription sets the context's description.
+func WithDescription(description string) ContextOption {
+	return func(c *Context) {
+		c.Description = description
+	}
+}
+
 // NewContext creates a new Context with default values.
-func NewContext(uri string) *Context {
+func NewContext(uri string, opts ...ContextOption) *Context {
 	now := time.Now().UTC()
-	return &Context{
+	c := &Context{
 		ID:          uuid.New().String(),
 		URI:         uri,
 		IsLeaf:      false,
@@ -94,6 +134,10 @@ func NewContext(uri string) *Context {
 		Vectorize:   Vectorize{},
 		Tier:        TierL1, // Default to L1, can be changed
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // deriveContextType derives the context type from URI prefix.
@@ -145,7 +189,16 @@ func (c *Context) GetVectorizationText() string {
 	return c.Vectorize.Text
 }
 
-// ToMap converts context to map for storage.
+// ToMap converts context to map for storage. The map survives a JSON round
+// trip: FromMap restores the same context from either the map itself or
+// its JSON decoding.
+//
+// Timestamps are RFC 3339 strings with nanoseconds, active_count an int64
+// and tier an int; FromMap also accepts them as float64 or json.Number, as
+// encoding/json decodes them. Meta values are canonically JSON types:
+// numbers are float64, objects map[string]any and arrays []any. FromMap
+// converts Meta to that form, so set Meta numbers as float64 to have them
+// compare equal after a round trip.
 func (c *Context) ToMap() map[string]any {
 	result := map[string]any{
 		"id":           c.ID,
@@ -153,10 +206,12 @@ func (c *Context) ToMap() map[string]any {
 		"parent_uri":   c.ParentURI,
 		"is_leaf":      c.IsLeaf,
 		"abstract":     c.Abstract,
+		"overview":     c.Overview,
+		"content":      c.Content,
 		"context_type": string(c.ContextType),
 		"category":     string(c.Category),
-		"created_at":   c.CreatedAt.Format(time.RFC3339),
-		"updated_at":   c.UpdatedAt.Format(time.RFC3339),
+		"created_at":   c.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":   c.UpdatedAt.Format(time.RFC3339Nano),
 		"active_count": c.ActiveCount,
 		"vector":       c.Vector,
 		"meta":         c.Meta,
@@ -165,27 +220,46 @@ func (c *Context) ToMap() map[string]any {
 		"tier":         int(c.Tier),
 	}
 
+	if c.Vectorize.Text != "" {
+		result["vectorize_text"] = c.Vectorize.Text
+	}
+
 	if c.UserID != "" {
 		result["user_id"] = c.UserID
 	}
-
-	// Add skill-specific fields from meta
-	if c.ContextType == ContextTypeSkill {
-		result["name"] = c.Meta["name"]
-		result["description"] = c.Meta["description"]
+	if name := c.displayField(c.Name, "name"); name != "" {
+		result["name"] = name
+	}
+	if description := c.displayField(c.Description, "description"); description != "" {
+		result["description"] = description
 	}
 
 	return result
 }
 
-// FromMap creates a Context from a map.
+// displayField returns value, or for contexts written before Name and
+// Description were fields, the string stored under key in Meta.
+func (c *Context) displayField(value, key string) string {
+	if value != "" {
+		return value
+	}
+	s, _ := c.Meta[key].(string)
+	return s
+}
+
+// FromMap creates a Context from a map. A missing name or description is
+// taken from meta["name"] or meta["description"].
 func FromMap(data map[string]any) *Context {
 	c := &Context{
 		ID:          getString(data, "id", ""),
 		URI:         getString(data, "uri", ""),
 		ParentURI:   getString(data, "parent_uri", ""),
 		IsLeaf:      getBool(data, "is_leaf", false),
+		Name:        getString(data, "name", ""),
+		Description: getString(data, "description", ""),
 		Abstract:    getString(data, "abstract", ""),
+		Overview:    getString(data, "overview", ""),
+		Content:     getString(data, "content", ""),
 		ContextType: ContextType(getString(data, "context_type", "")),
 		Category:    Category(getString(data, "category", "")),
 		ActiveCount: getInt64(data, "active_count", 0),
@@ -198,14 +272,17 @@ func FromMap(data map[string]any) *Context {
 		Vectorize:   Vectorize{Text: getString(data, "vectorize_text", "")},
 	}
 
-	// Parse timestamps
+	c.Name = c.displayField(c.Name, "name")
+	c.Description = c.displayField(c.Description, "description")
+
+	// Parse timestamps; RFC3339Nano also accepts times without fractions
 	if createdAt := getString(data, "created_at", ""); createdAt != "" {
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
 			c.CreatedAt = t
 		}
 	}
 	if updatedAt := getString(data, "updated_at", ""); updatedAt != "" {
-		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+		if t, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
 			c.UpdatedAt = t
 		}
 	}
@@ -261,36 +338,70 @@ func getBool(m map[string]any, key string, def bool) bool {
 }
 
 func getInt(m map[string]any, key string, def int) int {
+	return int(getInt64(m, key, int64(def)))
+}
+
+func getInt64(m map[string]any, key string, def int64) int64 {
 	if v, ok := m[key]; ok {
-		switch n := v.(type) {
-		case int:
+		if n, ok := toInt64(v); ok {
 			return n
-		case int64:
-			return int(n)
-		case float64:
-			return int(n)
 		}
 	}
 	return def
 }
 
-func getInt64(m map[string]any, key string, def int64) int64 {
-	if v, ok := m[key]; ok {
-		switch n := v.(type) {
-		case int:
-			return int64(n)
-		case int64:
-			return n
-		case float64:
-			return int64(n)
+// toInt64 converts any Go or JSON-decoded number to an int64. Floats are
+// truncated.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float32:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return int64(f), true
 		}
 	}
-	return def
+	return 0, false
+}
+
+// toFloat64 converts any Go or JSON-decoded number to a float64.
+func toFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
 }
 
 func getStringSlice(m map[string]any, key string) []string {
 	if v, ok := m[key]; ok {
-		if slice, ok := v.([]any); ok {
+		switch slice := v.(type) {
+		case []string:
+			return append([]string{}, slice...)
+		case []any:
 			result := make([]string, len(slice))
 			for i, item := range slice {
 				if s, ok := item.(string); ok {
@@ -305,15 +416,22 @@ func getStringSlice(m map[string]any, key string) []string {
 
 func getFloat64Slice(m map[string]any, key string) []float64 {
 	if v, ok := m[key]; ok {
-		if slice, ok := v.([]any); ok {
+		switch slice := v.(type) {
+		case []float64:
+			if slice == nil {
+				return nil
+			}
+			return append([]float64{}, slice...)
+		case []float32:
+			result := make([]float64, len(slice))
+			for i, f := range slice {
+				result[i] = float64(f)
+			}
+			return result
+		case []any:
 			result := make([]float64, len(slice))
 			for i, item := range slice {
-				switch n := item.(type) {
-				case float64:
-					result[i] = n
-				case int:
-					result[i] = float64(n)
-				}
+				result[i], _ = toFloat64(item)
 			}
 			return result
 		}
@@ -321,11 +439,28 @@ func getFloat64Slice(m map[string]any, key string) []float64 {
 	return nil
 }
 
+// getMap returns the map under key in its canonical JSON form, so that it
+// is the same whether or not data went through encoding/json.
 func getMap(m map[string]any, key string) map[string]any {
-	if v, ok := m[key]; ok {
-		if m, ok := v.(map[string]any); ok {
-			return m
+	if v, ok := m[key]; ok && v != nil {
+		if canonical, ok := canonicalJSON(v).(map[string]any); ok {
+			return canonical
 		}
 	}
 	return make(map[string]any)
 }
+
+// canonicalJSON converts v to the types encoding/json decodes into an any:
+// float64 numbers, map[string]any objects and []any arrays. Values that do
+// not encode are returned unchanged.
+func canonicalJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -4,9 +4,20 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 func TestContextCreation(t *testing.T) {
@@ -57,6 +68,64 @@ func TestTieredContext(t *testing.T) {
 	}
 }
 
+func TestTieredContextMoveToTier(t *testing.T) {
+	tests := []struct {
+		name   string
+		uri    string
+		tier   ContextTier
+		moved  bool
+		counts [3]int
+	}{
+		{"L0 to L2", "viking://test/l0", TierL2, true, [3]int{0, 1, 2}},
+		{"L2 to L0", "viking://test/l2", TierL0, true, [3]int{2, 1, 0}},
+		{"no-op", "viking://test/l1", TierL1, true, [3]int{1, 1, 1}},
+		{"unknown uri", "viking://test/missing", TierL0, false, [3]int{1, 1, 1}},
+		{"unknown tier", "viking://test/l0", ContextTier(7), false, [3]int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTieredContext()
+			for i, uri := range []string{"viking://test/l0", "viking://test/l1", "viking://test/l2"} {
+				ctx := NewContext(uri)
+				ctx.Tier = ContextTier(i)
+				tc.Add(ctx)
+			}
+
+			if moved := tc.MoveToTier(tt.uri, tt.tier); moved != tt.moved {
+				t.Fatalf("expected moved %v, got %v", tt.moved, moved)
+			}
+			for tier, want := range tt.counts {
+				if got := tc.CountByTier(ContextTier(tier)); got != want {
+					t.Errorf("expected %d contexts in tier %d, got %d", want, tier, got)
+				}
+			}
+			if tc.Count() != 3 {
+				t.Errorf("expected 3 contexts, got %d", tc.Count())
+			}
+			if !tt.moved {
+				return
+			}
+
+			ctx := tc.GetByURI(tt.uri)
+			if ctx == nil {
+				t.Fatalf("expected %s to still be retrievable", tt.uri)
+			}
+			if ctx.Tier != tt.tier {
+				t.Errorf("expected tier %d, got %d", tt.tier, ctx.Tier)
+			}
+			found := false
+			for _, c := range tc.GetByTier(tt.tier) {
+				if c == ctx {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected %s in tier %d", tt.uri, tt.tier)
+			}
+		})
+	}
+}
+
 func TestContextBuilder(t *testing.T) {
 	// Create test contexts
 	memories := []*Context{
@@ -180,6 +249,152 @@ func TestWindowManagement(t *testing.T) {
 	}
 }
 
+// newTokenSourceWindow holds one context with a short abstract and large
+// content, counted with the given token source.
+func newTokenSourceWindow(source TokenSource) *ContextWindow {
+	tc := NewTieredContext()
+	ctx := NewContext("viking://resources/big")
+	ctx.Abstract = "short summary"
+	ctx.Overview = "an overview of the document in a handful of words"
+	ctx.Content = strings.Repeat("lots of content words ", 50)
+	ctx.Tier = TierL1
+	tc.Add(ctx)
+
+	config := DefaultContextWindowConfig()
+	config.MaxTokens = 50
+	config.TokenSource = source
+	return NewContextWindow(config, tc, NewSimpleTokenCounter())
+}
+
+func TestWindowTokenSource(t *testing.T) {
+	abstract := newTokenSourceWindow(TokenSourceAbstract).CurrentTokens()
+	overview := newTokenSourceWindow(TokenSourceOverview).CurrentTokens()
+	content := newTokenSourceWindow(TokenSourceContent).CurrentTokens()
+
+	if !(abstract < overview && overview < content) {
+		t.Errorf("expected abstract < overview < content tokens, got %d, %d, %d", abstract, overview, content)
+	}
+	if w := newTokenSourceWindow(""); w.CurrentTokens() != abstract {
+		t.Errorf("expected unset token source to count the abstract only, got %d", w.CurrentTokens())
+	}
+}
+
+func TestWindowTokenSourceBudget(t *testing.T) {
+	// Counting abstracts, the big context fits and another one can be added
+	window := newTokenSourceWindow(TokenSourceAbstract)
+	if fitted, _ := window.FitInWindow(); len(fitted) != 1 {
+		t.Errorf("expected the context to fit by abstract, got %d contexts", len(fitted))
+	}
+	if err := window.AddContext(NewContext("viking://resources/small")); err != nil {
+		t.Errorf("expected add to succeed by abstract, got %v", err)
+	}
+
+	// Counting content, the same context blows the budget
+	window = newTokenSourceWindow(TokenSourceContent)
+	if window.WithinLimit() {
+		t.Error("expected the window to be over budget when content is counted")
+	}
+	if fitted, _ := window.FitInWindow(); len(fitted) != 0 {
+		t.Errorf("expected the context to be trimmed by content, got %d contexts", len(fitted))
+	}
+	if err := window.AddContext(NewContext("viking://resources/small")); err == nil {
+		t.Error("expected add to fail when content is counted")
+	}
+}
+
+// firstSentenceSummarizer summarizes a message by its first sentence,
+// recording the texts it was asked to summarize.
+type firstSentenceSummarizer struct {
+	session.Summarizer
+	inputs []string
+	err    error
+}
+
+func (s *firstSentenceSummarizer) Summarize(ctx context.Context, messages []*session.Message) (string, error) {
+	s.inputs = append(s.inputs, messages[0].Content)
+	if s.err != nil {
+		return "", s.err
+	}
+	first, _, _ := strings.Cut(messages[0].Content, ". ")
+	return first + ".", nil
+}
+
+// newCompressWindow holds one context per tier with a multi-sentence
+// abstract.
+func newCompressWindow() *ContextWindow {
+	tc := NewTieredContext()
+	for _, tier := range []ContextTier{TierL0, TierL1, TierL2} {
+		ctx := NewContext(fmt.Sprintf("viking://resources/l%d", tier))
+		ctx.Abstract = fmt.Sprintf("Tier %d notes on goroutines and channels. ", tier) +
+			strings.Repeat("Further detail about scheduling and memory use. ", 4)
+		ctx.Tier = tier
+		tc.Add(ctx)
+	}
+	return NewContextWindow(DefaultContextWindowConfig(), tc, NewSimpleTokenCounter())
+}
+
+func TestWindowCompressWithSummarizer(t *testing.T) {
+	window := newCompressWindow()
+	summarizer := &firstSentenceSummarizer{}
+	window.SetSummarizer(summarizer)
+	before := window.CurrentTokensByTier()
+
+	saved, err := window.Compress(context.Background())
+	if err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+	after := window.CurrentTokensByTier()
+	if after[TierL0] != before[TierL0] {
+		t.Errorf("expected L0 untouched, got %d tokens from %d", after[TierL0], before[TierL0])
+	}
+	for _, tier := range []ContextTier{TierL1, TierL2} {
+		if after[tier] > before[tier]/2 {
+			t.Errorf("expected L%d to halve, got %d tokens from %d", tier, after[tier], before[tier])
+		}
+	}
+	if want := before[TierL1] + before[TierL2] - after[TierL1] - after[TierL2]; saved != want {
+		t.Errorf("expected %d tokens saved, got %d", want, saved)
+	}
+
+	if len(summarizer.inputs) != 2 || !strings.HasPrefix(summarizer.inputs[0], "Tier 2") {
+		t.Errorf("expected L2 then L1 to be summarized, got %q", summarizer.inputs)
+	}
+	if got := window.tc.GetL2()[0].Abstract; got != "Tier 2 notes on goroutines and channels." {
+		t.Errorf("expected the summary as abstract, got %q", got)
+	}
+}
+
+func TestWindowCompressFallsBackToTruncation(t *testing.T) {
+	for _, summarizer := range []session.Summarizer{nil, &firstSentenceSummarizer{err: fmt.Errorf("unavailable")}} {
+		window := newCompressWindow()
+		window.SetSummarizer(summarizer)
+		before := window.CurrentTokens()
+		original := window.tc.GetL1()[0].Abstract
+
+		saved, err := window.Compress(context.Background())
+		if err != nil {
+			t.Fatalf("compress failed: %v", err)
+		}
+		if saved <= 0 || window.CurrentTokens() != before-saved {
+			t.Errorf("expected tokens saved, got %d of %d", saved, before)
+		}
+		// Truncated abstracts stay readable prefixes of the original
+		got := window.tc.GetL1()[0].Abstract
+		if !strings.HasSuffix(got, "...") || !strings.HasPrefix(original, strings.TrimSuffix(got, "...")) {
+			t.Errorf("expected a truncated prefix of %q, got %q", original, got)
+		}
+	}
+
+	// A cancelled context stops compression
+	window := newCompressWindow()
+	window.SetSummarizer(&firstSentenceSummarizer{err: context.Canceled})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := window.Compress(ctx); err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+}
+
 func TestBuildingTree(t *testing.T) {
 	tree := NewBuildingTree()
 
@@ -261,6 +476,156 @@ func TestFromMap(t *testing.T) {
 	}
 }
 
+// roundTripContext returns a context using every field ToMap writes, with
+// Meta in its canonical JSON form.
+func roundTripContext() *Context {
+	created := time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	return &Context{
+		ID:          "ctx-1",
+		URI:         "viking://agent/skills/bash",
+		ParentURI:   "viking://agent/skills",
+		IsLeaf:      true,
+		Name:        "bash",
+		Description: "Shell access",
+		Abstract:    "Run shell commands",
+		Overview:    "Overview",
+		Content:     "Content",
+		ContextType: ContextTypeSkill,
+		Category:    CategoryPatterns,
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Millisecond),
+		ActiveCount: 1 << 40,
+		RelatedURI:  []string{"viking://resources/shell"},
+		Meta: map[string]any{
+			"name":    "bash",
+			"weight":  0.25,
+			"retries": float64(3),
+			"limits":  map[string]any{"timeout": 1.5, "tags": []any{"a", float64(2)}},
+		},
+		SessionID: "sess-1",
+		UserID:    "alice",
+		Vector:    []float64{0.1, -2.5, 3},
+		Vectorize: Vectorize{Text: "bash shell"},
+		Tier:      TierL2,
+	}
+}
+
+func TestContextNameDescription(t *testing.T) {
+	c := NewContext("viking://agent/skills/bash", WithName("bash"), WithDescription("Shell access"))
+	if c.Name != "bash" || c.Description != "Shell access" {
+		t.Fatalf("expected name and description from options, got %q, %q", c.Name, c.Description)
+	}
+
+	m := c.ToMap()
+	if m["name"] != "bash" || m["description"] != "Shell access" {
+		t.Errorf("expected name and description in map, got %v, %v", m["name"], m["description"])
+	}
+	got := FromMap(m)
+	if got.Name != "bash" || got.Description != "Shell access" {
+		t.Errorf("expected name and description after FromMap, got %q, %q", got.Name, got.Description)
+	}
+
+	// Contexts that kept them in meta still expose them
+	old := NewContext("viking://resources/guide.md")
+	old.Meta["name"] = "Guide"
+	old.Meta["description"] = "How to start"
+	m = old.ToMap()
+	if m["name"] != "Guide" || m["description"] != "How to start" {
+		t.Errorf("expected name and description from meta, got %v, %v", m["name"], m["description"])
+	}
+	delete(m, "name")
+	delete(m, "description")
+	got = FromMap(m)
+	if got.Name != "Guide" || got.Description != "How to start" {
+		t.Errorf("expected name and description from meta after FromMap, got %q, %q", got.Name, got.Description)
+	}
+}
+
+func TestPersistenceNameDescription(t *testing.T) {
+	tc := NewTieredContext()
+	tc.Add(NewContext("viking://agent/skills/bash", WithName("bash"), WithDescription("Shell access")))
+
+	dir := t.TempDir()
+	if err := NewPersistenceHandler(&PersistenceConfig{StoragePath: dir}, tc, "names").Save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	tc2 := NewTieredContext()
+	if err := NewPersistenceHandler(&PersistenceConfig{StoragePath: dir}, tc2, "names").Load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	got := tc2.GetByURI("viking://agent/skills/bash")
+	if got == nil || got.Name != "bash" || got.Description != "Shell access" {
+		t.Errorf("expected name and description after load, got %+v", got)
+	}
+}
+
+func TestContextJSONRoundTrip(t *testing.T) {
+	want := roundTripContext()
+
+	data, err := json.Marshal(want.ToMap())
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if got := FromMap(decoded); !reflect.DeepEqual(got, want) {
+		t.Errorf("context changed in JSON round trip:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Encoding the map again gives the same bytes
+	again, _ := json.Marshal(FromMap(decoded).ToMap())
+	if string(again) != string(data) {
+		t.Errorf("expected deterministic encoding:\n%s\n%s", data, again)
+	}
+}
+
+func TestContextMapRoundTrip(t *testing.T) {
+	want := roundTripContext()
+	if got := FromMap(want.ToMap()); !reflect.DeepEqual(got, want) {
+		t.Errorf("context changed in map round trip:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Meta numbers set as ints come back in canonical float64 form
+	c := roundTripContext()
+	c.Meta = map[string]any{"retries": 3, "nested": map[string]int{"n": 1}}
+	meta := FromMap(c.ToMap()).Meta
+	if meta["retries"] != float64(3) {
+		t.Errorf("expected retries as float64(3), got %T %v", meta["retries"], meta["retries"])
+	}
+	if nested, ok := meta["nested"].(map[string]any); !ok || nested["n"] != float64(1) {
+		t.Errorf("expected nested map[string]any with float64, got %#v", meta["nested"])
+	}
+}
+
+func TestFromMapJSONNumbers(t *testing.T) {
+	data, _ := json.Marshal(map[string]any{
+		"active_count": int64(1<<60 + 1),
+		"tier":         2,
+		"vector":       []float64{0.5, 1},
+	})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded map[string]any
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	c := FromMap(decoded)
+	if c.ActiveCount != 1<<60+1 {
+		t.Errorf("expected exact active_count from json.Number, got %d", c.ActiveCount)
+	}
+	if c.Tier != TierL2 {
+		t.Errorf("expected tier L2, got %d", c.Tier)
+	}
+	if !reflect.DeepEqual(c.Vector, []float64{0.5, 1}) {
+		t.Errorf("expected vector [0.5 1], got %v", c.Vector)
+	}
+}
+
 func ExampleContext() {
 	ctx := NewContext("viking://agent/skills/bash")
 	ctx.Abstract = "Execute shell commands"
@@ -302,3 +667,263 @@ func ExampleTieredContext() {
 	// L0 count: 1
 	// L1 count: 1
 }
+
+func TestAutoSaverSaveIfDue(t *testing.T) {
+	tc := NewTieredContext()
+	ctx := NewContext("viking://test/autosave")
+	tc.Add(ctx)
+
+	handler := NewPersistenceHandler(&PersistenceConfig{StoragePath: t.TempDir()}, tc, "autosave-session")
+	saver := NewAutoSaver(time.Minute, handler)
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	saver.SetClock(clock)
+
+	if saved, err := saver.SaveIfDue(); err != nil || saved {
+		t.Fatalf("expected no save before the interval, got saved=%v err=%v", saved, err)
+	}
+	if handler.Exists() {
+		t.Fatal("nothing should be persisted yet")
+	}
+
+	clock.Advance(time.Minute)
+	if saved, err := saver.SaveIfDue(); err != nil || !saved {
+		t.Fatalf("expected save after one interval, got saved=%v err=%v", saved, err)
+	}
+	if !handler.Exists() {
+		t.Error("context should be persisted")
+	}
+
+	// The next save is due one interval after the previous schedule
+	clock.Advance(30 * time.Second)
+	if saved, _ := saver.SaveIfDue(); saved {
+		t.Error("expected no save halfway through the interval")
+	}
+	clock.Advance(30 * time.Second)
+	if saved, _ := saver.SaveIfDue(); !saved {
+		t.Error("expected save at the next interval")
+	}
+}
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"I'm sure they'VE won", []string{"I", "'m", " sure", " they", "'VE", " won"}},
+		{"1234567 apples", []string{"123", "456", "7", " apples"}},
+		{"a  b\n\n  c ", []string{"a", " ", " b", "\n\n", " ", " c", " "}},
+		{"x = [1];\n", []string{"x", " =", " [", "1", "];\n"}},
+		{"你好，世界", []string{"你好", "，世界"}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPieces(%q): expected %q, got %q", tt.text, tt.want, got)
+		}
+	}
+}
+
+// testVocabulary builds a tiktoken-format vocabulary from tokens, ranked
+// in order.
+func testVocabulary(tokens ...string) string {
+	var sb strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	return sb.String()
+}
+
+func TestBPETokenCounter(t *testing.T) {
+	counter, err := NewBPETokenCounter(strings.NewReader(testVocabulary(
+		"a", "b", "c", "d", " ", "ab", "cd", "abcd", " ab")))
+	if err != nil {
+		t.Fatalf("failed to load vocabulary: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		// "ab" and "cd" merge first, then "abcd"
+		{"abcdab", 2},
+		{"ab ab", 2},
+		// "abcd" merges before " ab" can, and " abcd" is not a token
+		{"ab abcd", 3},
+		{"dcba", 4},
+	}
+	for _, tt := range tests {
+		if got := counter.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q): expected %d, got %d", tt.text, tt.want, got)
+		}
+	}
+
+	if _, err := NewBPETokenCounter(strings.NewReader("YQ== zero\n")); err == nil {
+		t.Error("expected an error for an invalid rank")
+	}
+	if _, err := NewBPETokenCounterForModel("llama-3"); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}
+
+// TestBPETokenCounterCL100K compares counts with the cl100k_base
+// vocabulary in GOVIKING_TOKENIZER_DIR against tiktoken's.
+func TestBPETokenCounterCL100K(t *testing.T) {
+	dir := os.Getenv(TokenizerDirEnv)
+	if dir == "" {
+		t.Skip(TokenizerDirEnv + " not set")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cl100k_base.tiktoken")); err != nil {
+		t.Skip("cl100k_base.tiktoken not found")
+	}
+	counter, err := NewBPETokenCounterForModel("gpt-4")
+	if err != nil {
+		t.Fatalf("failed to load vocabulary: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello world", 2},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"I'm sure they've said it's 12345678 o'clock", 14},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}\n", 10},
+		{"x = [1, 2, 3]; y := map[string]int{}", 17},
+		{"你好，世界！今天天气很好。", 16},
+		{"naïve café résumé", 7},
+		{"emoji 🎉🚀 test", 8},
+	}
+	for _, tt := range tests {
+		if got := counter.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q): expected %d, got %d", tt.text, tt.want, got)
+		}
+	}
+}
+
+// writeImportTree writes a small project with two overview files and two
+// sources of similar size.
+func writeImportTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	body := strings.Repeat("word ", 10)
+	files := map[string]string{
+		"README.md":           "project readme " + body,
+		"docs/overview.md":    "docs overview " + body,
+		"src/main.go":         "package main " + body,
+		"src/util/strings.go": "package util " + body,
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestImportTreeTierRules(t *testing.T) {
+	root := writeImportTree(t)
+	rules := TierRules{
+		{Pattern: "README*", Tier: TierL0},
+		{Pattern: "overview*", Tier: TierL0},
+		{Pattern: "src/*", Tier: TierL1},
+		{Pattern: "*.go", Tier: TierL2},
+	}
+
+	tree, err := ImportTree(root, "viking://resources/project", ImportOptions{TierRules: rules})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	want := map[string]ContextTier{
+		"viking://resources/project":                     TierL1,
+		"viking://resources/project/README.md":           TierL0,
+		"viking://resources/project/docs":                TierL1,
+		"viking://resources/project/docs/overview.md":    TierL0,
+		"viking://resources/project/src":                 TierL1,
+		"viking://resources/project/src/main.go":         TierL1,
+		"viking://resources/project/src/util":            TierL1,
+		"viking://resources/project/src/util/strings.go": TierL2,
+	}
+	if tree.Len() != len(want) {
+		t.Errorf("expected %d contexts, got %d", len(want), tree.Len())
+	}
+	for uri, tier := range want {
+		ctx := tree.Get(uri)
+		if ctx == nil {
+			t.Errorf("expected %s to be imported", uri)
+			continue
+		}
+		if ctx.Tier != tier {
+			t.Errorf("expected %s in L%d, got L%d", uri, tier, ctx.Tier)
+		}
+	}
+	if readme := tree.Get("viking://resources/project/README.md"); readme == nil || !readme.IsLeaf || readme.ParentURI != "viking://resources/project" {
+		t.Errorf("expected README.md as a leaf under the root, got %+v", readme)
+	}
+	if p := tree.Parent("viking://resources/project/src/util/strings.go"); p == nil || p.URI != "viking://resources/project/src/util" {
+		t.Errorf("expected strings.go under src/util, got %+v", p)
+	}
+
+	// Without rules everything defaults to L1
+	tree, err = ImportTree(root, "viking://resources/project", ImportOptions{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	for _, ctx := range tree.Contexts() {
+		if ctx.Tier != TierL1 {
+			t.Errorf("expected %s in L1 without rules, got L%d", ctx.URI, ctx.Tier)
+		}
+	}
+
+	if _, err := ImportTree(root, "viking://resources/project", ImportOptions{TierRules: TierRules{{Pattern: "[", Tier: TierL0}}}); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
+
+func TestImportTreeFitInWindowPrioritizesL0(t *testing.T) {
+	rules := TierRules{
+		{Pattern: "README*", Tier: TierL0},
+		{Pattern: "overview*", Tier: TierL0},
+		{Tier: TierL2},
+	}
+	tree, err := ImportTree(writeImportTree(t), "viking://resources/project", ImportOptions{TierRules: rules})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	tc := NewTieredContext()
+	for _, ctx := range tree.GetLeafContexts() {
+		tc.Add(ctx)
+	}
+
+	// Room for the two overview files but not the sources
+	config := DefaultContextWindowConfig()
+	config.TokenSource = TokenSourceContent
+	window := NewContextWindow(config, tc, nil)
+	config.MaxTokens = 0
+	for _, ctx := range tc.GetL0() {
+		config.MaxTokens += window.contextTokens(ctx)
+	}
+	kept, err := window.FitInWindow()
+	if err != nil {
+		t.Fatalf("FitInWindow failed: %v", err)
+	}
+
+	var uris []string
+	for _, ctx := range kept {
+		uris = append(uris, ctx.URI)
+	}
+	if len(kept) != 2 {
+		t.Fatalf("expected only the two L0 files to fit, got %v", uris)
+	}
+	for _, ctx := range kept {
+		if ctx.Tier != TierL0 {
+			t.Errorf("expected only L0 contexts kept, got %s in L%d", ctx.URI, ctx.Tier)
+		}
+	}
+}
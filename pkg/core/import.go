@@ -0,0 +1,135 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TierRule assigns a tier to the imported contexts it matches.
+type TierRule struct {
+	// Pattern is a path.Match glob. Patterns containing a slash match the
+	// path relative to the import root, others match the base name alone.
+	// An empty pattern matches every path.
+	Pattern string
+	// ContextType, when set, restricts the rule to contexts of that type.
+	ContextType ContextType
+	Tier        ContextTier
+}
+
+// matches reports whether the rule applies to the context at relPath.
+func (r TierRule) matches(relPath string, ctxType ContextType) bool {
+	if r.ContextType != "" && r.ContextType != ctxType {
+		return false
+	}
+	if r.Pattern == "" {
+		return true
+	}
+	name := relPath
+	if !strings.Contains(r.Pattern, "/") {
+		name = path.Base(relPath)
+	}
+	ok, _ := path.Match(r.Pattern, name)
+	return ok
+}
+
+// TierRules is an ordered list of tier rules; the first match wins.
+type TierRules []TierRule
+
+// Validate checks that every pattern is well formed.
+func (rules TierRules) Validate() error {
+	for _, r := range rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid tier rule pattern %q: %w", r.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// TierFor returns the tier of the first rule matching relPath and ctxType,
+// or TierL1 when none does.
+func (rules TierRules) TierFor(relPath string, ctxType ContextType) ContextTier {
+	for _, r := range rules {
+		if r.matches(relPath, ctxType) {
+			return r.Tier
+		}
+	}
+	return TierL1
+}
+
+// ImportOptions configures ImportTree.
+type ImportOptions struct {
+	// TierRules assign the tiers of imported contexts. Contexts no rule
+	// matches get TierL1.
+	TierRules TierRules
+}
+
+// ImportTree imports the directory root as a tree of contexts below
+// baseURI: one context per directory and one leaf context per file, whose
+// content is the file's and whose abstract is its first non-blank line.
+func ImportTree(root, baseURI string, opts ImportOptions) (*BuildingTree, error) {
+	if err := opts.TierRules.Validate(); err != nil {
+		return nil, err
+	}
+	baseURI = strings.TrimSuffix(baseURI, "/")
+
+	tree := NewBuildingTree()
+	tree.SetSourcePath(root)
+	tree.SetSourceFormat("directory")
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		uri, parentURI := baseURI, ""
+		if rel != "." {
+			uri = baseURI + "/" + rel
+			parentURI = baseURI
+			if dir := path.Dir(rel); dir != "." {
+				parentURI = baseURI + "/" + dir
+			}
+		}
+
+		ctx := NewContext(uri, WithName(d.Name()))
+		ctx.ParentURI = parentURI
+		ctx.IsLeaf = !d.IsDir()
+		if !d.IsDir() {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			ctx.Content = string(data)
+			ctx.Abstract = firstLine(ctx.Content)
+		}
+		ctx.Tier = opts.TierRules.TierFor(rel, ctx.ContextType)
+
+		tree.AddContext(ctx)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", root, err)
+	}
+	return tree, nil
+}
+
+// firstLine returns the first non-blank line of text, trimmed.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -5,10 +5,15 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // PersistenceConfig holds configuration for persistence.
@@ -16,6 +21,10 @@ type PersistenceConfig struct {
 	StoragePath    string
 	AutoSave       bool
 	AutoSaveInterval time.Duration
+
+	// Backend stores the serialized contexts. Nil keeps them as JSON
+	// files under StoragePath.
+	Backend PersistenceBackend
 }
 
 // DefaultPersistenceConfig returns a default configuration.
@@ -27,20 +36,114 @@ func DefaultPersistenceConfig() *PersistenceConfig {
 	}
 }
 
+// PersistenceBackend stores serialized context bundles by key.
+// Implementations report a missing key from Load and LastModified with an
+// error wrapping fs.ErrNotExist; deleting a missing key is not an error.
+type PersistenceBackend interface {
+	Save(key string, data []byte) error
+	Load(key string) ([]byte, error)
+	Delete(key string) error
+	Exists(key string) (bool, error)
+	LastModified(key string) (time.Time, error)
+}
+
+// FileBackend stores each bundle as a JSON file in a directory.
+type FileBackend struct {
+	dir string
+}
+
+// NewFileBackend creates a backend storing bundles under dir.
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+// Save writes data to the key's file, creating the directory if needed.
+func (b *FileBackend) Save(key string, data []byte) error {
+	if b.dir == "" {
+		return fmt.Errorf("storage path not configured")
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	if err := os.WriteFile(b.filename(key), data, 0644); err != nil {
+		return fmt.Errorf("failed to write context file: %w", err)
+	}
+	return nil
+}
+
+// Load reads the key's file.
+func (b *FileBackend) Load(key string) ([]byte, error) {
+	if b.dir == "" {
+		return nil, fmt.Errorf("storage path not configured")
+	}
+	data, err := os.ReadFile(b.filename(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read context file: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the key's file.
+func (b *FileBackend) Delete(key string) error {
+	if b.dir == "" {
+		return fmt.Errorf("storage path not configured")
+	}
+	if err := os.Remove(b.filename(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete context file: %w", err)
+	}
+	return nil
+}
+
+// Exists reports whether the key's file exists.
+func (b *FileBackend) Exists(key string) (bool, error) {
+	if b.dir == "" {
+		return false, nil
+	}
+	_, err := os.Stat(b.filename(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// LastModified returns the modification time of the key's file.
+func (b *FileBackend) LastModified(key string) (time.Time, error) {
+	if b.dir == "" {
+		return time.Time{}, fmt.Errorf("storage path not configured")
+	}
+	info, err := os.Stat(b.filename(key))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func (b *FileBackend) filename(key string) string {
+	return filepath.Join(b.dir, fmt.Sprintf("context_%s.json", key))
+}
+
 // PersistenceHandler handles context persistence and restoration.
 type PersistenceHandler struct {
 	config   *PersistenceConfig
+	backend  PersistenceBackend
 	tc       *TieredContext
 	sessionID string
 }
 
-// NewPersistenceHandler creates a new PersistenceHandler.
+// NewPersistenceHandler creates a new PersistenceHandler. Contexts are
+// stored through config.Backend, or as files under config.StoragePath
+// when it is nil, keyed by session ID.
 func NewPersistenceHandler(config *PersistenceConfig, tc *TieredContext, sessionID string) *PersistenceHandler {
 	if config == nil {
 		config = DefaultPersistenceConfig()
 	}
+	backend := config.Backend
+	if backend == nil {
+		backend = NewFileBackend(config.StoragePath)
+	}
 	return &PersistenceHandler{
 		config:   config,
+		backend:  backend,
 		tc:       tc,
 		sessionID: sessionID,
 	}
@@ -48,38 +151,17 @@ func NewPersistenceHandler(config *PersistenceConfig, tc *TieredContext, session
 
 // Save persists context to storage.
 func (p *PersistenceHandler) Save() error {
-	if p.config.StoragePath == "" {
-		return fmt.Errorf("storage path not configured")
-	}
-
-	// Ensure directory exists
-	if err := os.MkdirAll(p.config.StoragePath, 0755); err != nil {
-		return fmt.Errorf("failed to create storage directory: %w", err)
-	}
-
-	filename := p.getFilename()
-	data := p.marshalContext()
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write context file: %w", err)
-	}
-
-	return nil
+	return p.backend.Save(p.sessionID, p.marshalContext())
 }
 
 // Load restores context from storage.
 func (p *PersistenceHandler) Load() error {
-	if p.config.StoragePath == "" {
-		return fmt.Errorf("storage path not configured")
-	}
-
-	filename := p.getFilename()
-	data, err := os.ReadFile(filename)
+	data, err := p.backend.Load(p.sessionID)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No file exists yet, not an error
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Nothing persisted yet, not an error
 		}
-		return fmt.Errorf("failed to read context file: %w", err)
+		return err
 	}
 
 	contexts, err := p.unmarshalContext(data)
@@ -97,48 +179,18 @@ func (p *PersistenceHandler) Load() error {
 
 // Delete removes persisted context from storage.
 func (p *PersistenceHandler) Delete() error {
-	if p.config.StoragePath == "" {
-		return fmt.Errorf("storage path not configured")
-	}
-
-	filename := p.getFilename()
-	if err := os.Remove(filename); err != nil {
-		if os.IsNotExist(err) {
-			return nil // File doesn't exist, nothing to delete
-		}
-		return fmt.Errorf("failed to delete context file: %w", err)
-	}
-
-	return nil
+	return p.backend.Delete(p.sessionID)
 }
 
 // Exists checks if persisted context exists.
 func (p *PersistenceHandler) Exists() bool {
-	if p.config.StoragePath == "" {
-		return false
-	}
-	filename := p.getFilename()
-	_, err := os.Stat(filename)
-	return err == nil
+	ok, err := p.backend.Exists(p.sessionID)
+	return ok && err == nil
 }
 
 // GetLastModified returns the last modification time of persisted context.
 func (p *PersistenceHandler) GetLastModified() (time.Time, error) {
-	if p.config.StoragePath == "" {
-		return time.Time{}, fmt.Errorf("storage path not configured")
-	}
-
-	filename := p.getFilename()
-	info, err := os.Stat(filename)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	return info.ModTime(), nil
-}
-
-func (p *PersistenceHandler) getFilename() string {
-	return filepath.Join(p.config.StoragePath, fmt.Sprintf("context_%s.json", p.sessionID))
+	return p.backend.LastModified(p.sessionID)
 }
 
 func (p *PersistenceHandler) marshalContext() []byte {
@@ -149,7 +201,11 @@ func (p *PersistenceHandler) marshalContext() []byte {
 		URI          string            `json:"uri"`
 		ParentURI    string            `json:"parent_uri,omitempty"`
 		IsLeaf       bool              `json:"is_leaf"`
+		Name         string            `json:"name,omitempty"`
+		Description  string            `json:"description,omitempty"`
 		Abstract     string            `json:"abstract"`
+		Overview     string            `json:"overview,omitempty"`
+		Content      string            `json:"content,omitempty"`
 		ContextType  string            `json:"context_type"`
 		Category     string            `json:"category,omitempty"`
 		CreatedAt    string            `json:"created_at"`
@@ -171,7 +227,11 @@ func (p *PersistenceHandler) marshalContext() []byte {
 			URI:         ctx.URI,
 			ParentURI:   ctx.ParentURI,
 			IsLeaf:      ctx.IsLeaf,
+			Name:        ctx.Name,
+			Description: ctx.Description,
 			Abstract:    ctx.Abstract,
+			Overview:    ctx.Overview,
+			Content:     ctx.Content,
 			ContextType: string(ctx.ContextType),
 			Category:    string(ctx.Category),
 			CreatedAt:   ctx.CreatedAt.Format(time.RFC3339),
@@ -197,7 +257,11 @@ func (p *PersistenceHandler) unmarshalContext(data []byte) ([]*Context, error) {
 		URI          string            `json:"uri"`
 		ParentURI    string            `json:"parent_uri,omitempty"`
 		IsLeaf       bool              `json:"is_leaf"`
+		Name         string            `json:"name,omitempty"`
+		Description  string            `json:"description,omitempty"`
 		Abstract     string            `json:"abstract"`
+		Overview     string            `json:"overview,omitempty"`
+		Content      string            `json:"content,omitempty"`
 		ContextType  string            `json:"context_type"`
 		Category     string            `json:"category,omitempty"`
 		CreatedAt    string            `json:"created_at"`
@@ -227,7 +291,11 @@ func (p *PersistenceHandler) unmarshalContext(data []byte) ([]*Context, error) {
 			URI:         s.URI,
 			ParentURI:   s.ParentURI,
 			IsLeaf:      s.IsLeaf,
+			Name:        s.Name,
+			Description: s.Description,
 			Abstract:    s.Abstract,
+			Overview:    s.Overview,
+			Content:     s.Content,
 			ContextType: ContextType(s.ContextType),
 			Category:    Category(s.Category),
 			CreatedAt:   createdAt,
@@ -256,22 +324,60 @@ type Persistable interface {
 type AutoSaver struct {
 	interval time.Duration
 	handler  *PersistenceHandler
+	clock    utils.Clock
+	nextSave time.Time
+	mu       sync.Mutex
 	stopCh   chan struct{}
 	doneCh   chan struct{}
 }
 
 // NewAutoSaver creates a new AutoSaver.
 func NewAutoSaver(interval time.Duration, handler *PersistenceHandler) *AutoSaver {
+	clock := utils.RealClock{}
 	return &AutoSaver{
 		interval: interval,
 		handler:  handler,
+		clock:    clock,
+		nextSave: clock.Now().Add(interval),
 		stopCh:   make(chan struct{}),
 		doneCh:   make(chan struct{}),
 	}
 }
 
+// SetClock sets the clock used to decide when a save is due. The next save
+// is rescheduled one interval after the clock's current time.
+func (as *AutoSaver) SetClock(clock utils.Clock) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.clock = clock
+	as.nextSave = clock.Now().Add(as.interval)
+}
+
+// SaveIfDue saves when at least one interval has passed since the last
+// scheduled save, reporting whether it saved.
+func (as *AutoSaver) SaveIfDue() (bool, error) {
+	as.mu.Lock()
+	now := as.clock.Now()
+	if now.Before(as.nextSave) {
+		as.mu.Unlock()
+		return false, nil
+	}
+	// Keep to the original schedule unless saves fell behind
+	as.nextSave = as.nextSave.Add(as.interval)
+	if !as.nextSave.After(now) {
+		as.nextSave = now.Add(as.interval)
+	}
+	as.mu.Unlock()
+
+	return true, as.handler.Save()
+}
+
 // Start starts the auto-saver.
 func (as *AutoSaver) Start() {
+	as.mu.Lock()
+	as.nextSave = as.clock.Now().Add(as.interval)
+	as.mu.Unlock()
+
 	go func() {
 		ticker := time.NewTicker(as.interval)
 		defer ticker.Stop()
@@ -279,7 +385,7 @@ func (as *AutoSaver) Start() {
 		for {
 			select {
 			case <-ticker.C:
-				if err := as.handler.Save(); err != nil {
+				if _, err := as.SaveIfDue(); err != nil {
 					fmt.Printf("AutoSave error: %v\n", err)
 				}
 			case <-as.stopCh:
@@ -143,58 +143,44 @@ func removeFromSlice(slice *[]*Context, uri string) bool {
 	return false
 }
 
-// MoveToTier moves a context to a different tier.
+// MoveToTier moves a context to a different tier. It returns false, leaving
+// the context where it is, when uri is not found or tier is unknown.
 func (tc *TieredContext) MoveToTier(uri string, tier ContextTier) bool {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
 
-	// Remove from current tier
-	var ctx *Context
-	if removeFromSlice(&tc.L0, uri) {
-		ctx = findContextByURIInSlices([][]*Context{tc.L0}, uri)
-		// Need to re-find - it was already removed
-	} else if removeFromSlice(&tc.L1, uri) {
-		// Already removed
-	} else if removeFromSlice(&tc.L2, uri) {
-		// Already removed
-	} else {
+	target := tc.tierSlice(tier)
+	if target == nil {
 		return false
 	}
 
-	// Actually find and remove the context
-	if ctx == nil {
-		ctx = findContextByURIInSlices([][]*Context{tc.L0, tc.L1, tc.L2}, uri)
-		if ctx == nil {
-			return false
+	for _, current := range []*[]*Context{&tc.L0, &tc.L1, &tc.L2} {
+		for i, ctx := range *current {
+			if ctx.URI != uri {
+				continue
+			}
+			if current == target {
+				ctx.Tier = tier
+				return true
+			}
+			*current = append((*current)[:i], (*current)[i+1:]...)
+			ctx.Tier = tier
+			*target = append(*target, ctx)
+			return true
 		}
 	}
+	return false
+}
 
-	// Remove from all slices to be safe
-	removeFromSlice(&tc.L0, uri)
-	removeFromSlice(&tc.L1, uri)
-	removeFromSlice(&tc.L2, uri)
-
-	// Add to new tier
-	ctx.Tier = tier
+// tierSlice returns the slice holding tier, or nil for an unknown tier.
+func (tc *TieredContext) tierSlice(tier ContextTier) *[]*Context {
 	switch tier {
 	case TierL0:
-		tc.L0 = append(tc.L0, ctx)
+		return &tc.L0
 	case TierL1:
-		tc.L1 = append(tc.L1, ctx)
+		return &tc.L1
 	case TierL2:
-		tc.L2 = append(tc.L2, ctx)
-	}
-
-	return true
-}
-
-func findContextByURIInSlices(slices [][]*Context, uri string) *Context {
-	for _, slice := range slices {
-		for _, ctx := range slice {
-			if ctx.URI == uri {
-				return ctx
-			}
-		}
+		return &tc.L2
 	}
 	return nil
 }
@@ -164,6 +164,8 @@ func (bt *BuildingTree) buildDir(uri string) map[string]any {
 		title = semanticTitle
 	} else if sourceTitle, ok := ctx.Meta["source_title"].(string); ok && sourceTitle != "" {
 		title = sourceTitle
+	} else if ctx.Name != "" {
+		title = ctx.Name
 	} else if ctx.Meta != nil && ctx.Meta["name"] != nil {
 		title = fmt.Sprintf("%v", ctx.Meta["name"])
 	} else {
@@ -4,9 +4,26 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
+
+	"github.com/jqnote/goviking/pkg/session"
+)
+
+// TokenSource selects the parts of a context counted against the window
+// budget.
+type TokenSource string
+
+const (
+	// TokenSourceAbstract counts only the abstract.
+	TokenSourceAbstract TokenSource = "abstract"
+	// TokenSourceOverview counts the abstract and the overview.
+	TokenSourceOverview TokenSource = "overview"
+	// TokenSourceContent counts the abstract, overview and full content.
+	TokenSourceContent TokenSource = "content"
 )
 
 // ContextWindowConfig holds configuration for context window management.
@@ -15,6 +32,10 @@ type ContextWindowConfig struct {
 	MinL0Retention    int      // Minimum L0 contexts to always keep
 	CompressionRatio  float64  // Ratio to compress when approaching limit
 	PriorityTiers     []ContextTier // Tier priority order
+	TokenSource       TokenSource   // Parts of a context counted toward MaxTokens
+	// CompressedShare is the share of its tokens an abstract keeps when
+	// compressed, between 0 and 1.
+	CompressedShare float64
 }
 
 // DefaultContextWindowConfig returns a default configuration.
@@ -24,6 +45,8 @@ func DefaultContextWindowConfig() *ContextWindowConfig {
 		MinL0Retention: 1,
 		CompressionRatio: 0.9,
 		PriorityTiers: []ContextTier{TierL0, TierL1, TierL2},
+		TokenSource: TokenSourceAbstract,
+		CompressedShare: 0.5,
 	}
 }
 
@@ -32,6 +55,8 @@ type ContextWindow struct {
 	config   *ContextWindowConfig
 	tc       *TieredContext
 	tokenCnt TokenCounter
+	// summarizer shortens abstracts in Compress; nil truncates them
+	summarizer session.Summarizer
 	mu       sync.RWMutex
 }
 
@@ -50,6 +75,14 @@ func NewContextWindow(config *ContextWindowConfig, tc *TieredContext, tokenCnt T
 	}
 }
 
+// SetSummarizer sets the summarizer Compress uses to shorten abstracts. A
+// nil summarizer truncates them instead.
+func (w *ContextWindow) SetSummarizer(summarizer session.Summarizer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.summarizer = summarizer
+}
+
 // CurrentTokens returns the current token count.
 func (w *ContextWindow) CurrentTokens() int {
 	w.mu.RLock()
@@ -58,7 +91,7 @@ func (w *ContextWindow) CurrentTokens() int {
 	contexts := w.tc.GetAll()
 	total := 0
 	for _, ctx := range contexts {
-		total += w.tokenCnt.CountTokens(ctx.Abstract)
+		total += w.contextTokens(ctx)
 	}
 	return total
 }
@@ -73,7 +106,7 @@ func (w *ContextWindow) CurrentTokensByTier() map[ContextTier]int {
 		contexts := w.tc.GetByTier(tier)
 		total := 0
 		for _, ctx := range contexts {
-			total += w.tokenCnt.CountTokens(ctx.Abstract)
+			total += w.contextTokens(ctx)
 		}
 		result[tier] = total
 	}
@@ -123,7 +156,7 @@ func (w *ContextWindow) FitInWindow() ([]*Context, error) {
 	// Calculate current tokens
 	currentTokens := 0
 	for _, ctx := range prioritized {
-		currentTokens += w.tokenCnt.CountTokens(ctx.Abstract)
+		currentTokens += w.contextTokens(ctx)
 	}
 
 	// If within limit, return all
@@ -136,7 +169,7 @@ func (w *ContextWindow) FitInWindow() ([]*Context, error) {
 	currentTokens = 0
 
 	for _, ctx := range prioritized {
-		tokens := w.tokenCnt.CountTokens(ctx.Abstract)
+		tokens := w.contextTokens(ctx)
 		if currentTokens+tokens <= w.config.MaxTokens {
 			result = append(result, ctx)
 			currentTokens += tokens
@@ -150,27 +183,79 @@ func (w *ContextWindow) FitInWindow() ([]*Context, error) {
 	return result, nil
 }
 
-// Compress reduces context size using compression.
-func (w *ContextWindow) Compress() (int, error) {
+// Compress shortens the abstracts of L2 and then L1 contexts to about
+// CompressedShare of their tokens, summarizing them when a summarizer is
+// set and truncating them at a word boundary otherwise, or when the
+// summarizer fails. It returns the number of tokens saved.
+func (w *ContextWindow) Compress(ctx context.Context) (int, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	compressed := 0
+	share := w.config.CompressedShare
+	if share <= 0 || share >= 1 {
+		share = DefaultContextWindowConfig().CompressedShare
+	}
 
-	// Compress L2 first, then L1
-	tiers := []ContextTier{TierL2, TierL1}
+	saved := 0
+	for _, tier := range []ContextTier{TierL2, TierL1} {
+		for _, c := range w.tc.GetByTier(tier) {
+			before := w.tokenCnt.CountTokens(c.Abstract)
+			target := int(float64(before) * share)
+			if target < 1 {
+				continue
+			}
+
+			abstract, err := w.shortenAbstract(ctx, c.Abstract, target)
+			if err != nil {
+				return saved, err
+			}
+			c.Abstract = abstract
+			saved += before - w.tokenCnt.CountTokens(abstract)
+		}
+	}
 
-	for _, tier := range tiers {
-		contexts := w.tc.GetByTier(tier)
-		for _, ctx := range contexts {
-			originalLen := len(ctx.Abstract)
-			compressedText := CompressText(ctx.Abstract)
-			ctx.Abstract = compressedText
-			compressed += originalLen - len(compressedText)
+	return saved, nil
+}
+
+// shortenAbstract returns abstract in at most target tokens.
+func (w *ContextWindow) shortenAbstract(ctx context.Context, abstract string, target int) (string, error) {
+	if w.summarizer != nil {
+		summary, err := w.summarizer.Summarize(ctx, []*session.Message{{Role: session.RoleUser, Content: abstract}})
+		if err != nil && ctx.Err() != nil {
+			return "", err
+		}
+		summary = strings.TrimSpace(summary)
+		if err == nil && summary != "" {
+			// Summaries over budget are truncated rather than discarded
+			abstract = summary
 		}
 	}
+	return w.truncateTokens(abstract, target), nil
+}
 
-	return compressed, nil
+// truncateTokens cuts text at a word boundary to at most maxTokens tokens,
+// marking the cut with an ellipsis. Text whose first word alone is over
+// budget is returned unchanged.
+func (w *ContextWindow) truncateTokens(text string, maxTokens int) string {
+	if w.tokenCnt.CountTokens(text) <= maxTokens {
+		return text
+	}
+	words := strings.Fields(text)
+	kept := ""
+	for _, word := range words {
+		next := word
+		if kept != "" {
+			next = kept + " " + word
+		}
+		if w.tokenCnt.CountTokens(next+"...") > maxTokens {
+			break
+		}
+		kept = next
+	}
+	if kept == "" {
+		return text
+	}
+	return kept + "..."
 }
 
 // AddContext adds a context to the window.
@@ -180,7 +265,7 @@ func (w *ContextWindow) AddContext(ctx *Context) error {
 
 	// Check if adding would exceed limit
 	currentTokens := w.currentTokensUnsafe()
-	newTokens := w.tokenCnt.CountTokens(ctx.Abstract)
+	newTokens := w.contextTokens(ctx)
 
 	if currentTokens+newTokens > w.config.MaxTokens {
 		return fmt.Errorf("context would exceed window limit: current=%d new=%d max=%d",
@@ -215,7 +300,7 @@ func (w *ContextWindow) GetWindowInfo() *WindowInfo {
 		info.TierCounts[tier] = len(contexts)
 		info.TierTokens[tier] = 0
 		for _, ctx := range contexts {
-			info.TierTokens[tier] += w.tokenCnt.CountTokens(ctx.Abstract)
+			info.TierTokens[tier] += w.contextTokens(ctx)
 		}
 	}
 
@@ -225,11 +310,26 @@ func (w *ContextWindow) GetWindowInfo() *WindowInfo {
 	return info
 }
 
+// contextTokens counts the tokens of ctx according to the configured token
+// source. Contexts with a short abstract but large content only count as
+// large when the content is included.
+func (w *ContextWindow) contextTokens(ctx *Context) int {
+	tokens := w.tokenCnt.CountTokens(ctx.Abstract)
+	switch w.config.TokenSource {
+	case TokenSourceContent:
+		tokens += w.tokenCnt.CountTokens(ctx.Content)
+		fallthrough
+	case TokenSourceOverview:
+		tokens += w.tokenCnt.CountTokens(ctx.Overview)
+	}
+	return tokens
+}
+
 func (w *ContextWindow) currentTokensUnsafe() int {
 	contexts := w.tc.GetAll()
 	total := 0
 	for _, ctx := range contexts {
-		total += w.tokenCnt.CountTokens(ctx.Abstract)
+		total += w.contextTokens(ctx)
 	}
 	return total
 }
@@ -290,14 +390,14 @@ func (w *ContextWindow) fitInWindowUnsafe() ([]*Context, error) {
 
 	// Add L0 contexts
 	for _, ctx := range w.tc.L0 {
-		tokens := w.tokenCnt.CountTokens(ctx.Abstract)
+		tokens := w.contextTokens(ctx)
 		result = append(result, ctx)
 		currentTokens += tokens
 	}
 
 	// Add L1 contexts if space permits
 	for _, ctx := range w.tc.L1 {
-		tokens := w.tokenCnt.CountTokens(ctx.Abstract)
+		tokens := w.contextTokens(ctx)
 		if currentTokens+tokens <= w.config.MaxTokens {
 			result = append(result, ctx)
 			currentTokens += tokens
@@ -306,7 +406,7 @@ func (w *ContextWindow) fitInWindowUnsafe() ([]*Context, error) {
 
 	// Add L2 contexts if space permits
 	for _, ctx := range w.tc.L2 {
-		tokens := w.tokenCnt.CountTokens(ctx.Abstract)
+		tokens := w.contextTokens(ctx)
 		if currentTokens+tokens <= w.config.MaxTokens {
 			result = append(result, ctx)
 			currentTokens += tokens
@@ -77,7 +77,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRe
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(respBody))
+		return nil, newAPIError(resp, respBody)
 	}
 
 	var result struct {
@@ -163,7 +163,7 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest) (S
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error: %s", string(respBody))
+		return nil, newAPIError(resp, respBody)
 	}
 
 	return &anthropicStreamReader{reader: resp.Body}, nil
@@ -0,0 +1,180 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// ErrCircuitOpen is returned without calling the provider while a circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("llm provider unavailable: circuit breaker open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed passes calls through to the provider.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails calls immediately with ErrCircuitOpen.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets one trial call through after the cooldown.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerConfig holds circuit breaker configuration.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker.
+	FailureThreshold int
+
+	// Cooldown is how long the breaker stays open before letting a trial
+	// call through.
+	Cooldown time.Duration
+}
+
+// DefaultBreakerConfig returns default circuit breaker configuration.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// CircuitBreaker wraps a Provider and stops calling it after repeated
+// failures, so callers fail fast and can fall back to paths that do not
+// need the LLM. Cancelled or expired contexts do not count as failures.
+type CircuitBreaker struct {
+	provider Provider
+	config   BreakerConfig
+	clock    utils.Clock
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial call is in flight
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker around provider.
+func NewCircuitBreaker(provider Provider, config BreakerConfig) *CircuitBreaker {
+	defaults := DefaultBreakerConfig()
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaults.Cooldown
+	}
+	return &CircuitBreaker{
+		provider: provider,
+		config:   config,
+		clock:    utils.RealClock{},
+		state:    BreakerClosed,
+	}
+}
+
+// SetClock sets the clock used to time the cooldown.
+func (b *CircuitBreaker) SetClock(clock utils.Clock) {
+	b.clock = clock
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.clock.Now().Sub(b.openedAt) >= b.config.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Chat creates a chat completion unless the breaker is open.
+func (b *CircuitBreaker) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := b.provider.Chat(ctx, req)
+	b.record(ctx, err)
+	return resp, err
+}
+
+// ChatStream creates a streaming chat completion unless the breaker is
+// open. Only opening the stream is tracked, not errors while reading it.
+func (b *CircuitBreaker) ChatStream(ctx context.Context, req *ChatRequest) (StreamReader, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	stream, err := b.provider.ChatStream(ctx, req)
+	b.record(ctx, err)
+	return stream, err
+}
+
+// Embed creates embeddings unless the breaker is open.
+func (b *CircuitBreaker) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := b.provider.Embed(ctx, req)
+	b.record(ctx, err)
+	return resp, err
+}
+
+// Close closes the wrapped provider.
+func (b *CircuitBreaker) Close() error {
+	return b.provider.Close()
+}
+
+// allow reports whether a call may go through. After the cooldown a single
+// trial call is let through while the others keep failing fast.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.config.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a call.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.trial = false
+	}
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; that says nothing about the provider
+		return
+	}
+
+	if err == nil {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.clock.Now()
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// flakyProvider fails every call while down is set and counts calls.
+type flakyProvider struct {
+	down  bool
+	calls int
+}
+
+func (p *flakyProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	p.calls++
+	if p.down {
+		return nil, errors.New("connection refused")
+	}
+	return &ChatResponse{Choices: []Choice{{Message: Message{Content: "ok"}}}}, nil
+}
+
+func (p *flakyProvider) ChatStream(ctx context.Context, req *ChatRequest) (StreamReader, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *flakyProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	p.calls++
+	if p.down {
+		return nil, errors.New("connection refused")
+	}
+	return &EmbeddingResponse{}, nil
+}
+
+func (p *flakyProvider) Close() error { return nil }
+
+func newTestBreaker(p Provider) (*CircuitBreaker, *utils.FakeClock) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewCircuitBreaker(p, BreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+	b.SetClock(clock)
+	return b, clock
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	p := &flakyProvider{down: true}
+	b, _ := newTestBreaker(p)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.Chat(ctx, &ChatRequest{}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Call %d: expected the provider error, got %v", i, err)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected breaker to be open, got %s", b.State())
+	}
+
+	if _, err := b.Chat(ctx, &ChatRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := b.Embed(ctx, &EmbeddingRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen for Embed, got %v", err)
+	}
+	if p.calls != 3 {
+		t.Errorf("Expected the open breaker to skip the provider, got %d calls", p.calls)
+	}
+}
+
+func TestCircuitBreakerRecovers(t *testing.T) {
+	p := &flakyProvider{down: true}
+	b, clock := newTestBreaker(p)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		b.Chat(ctx, &ChatRequest{})
+	}
+
+	// A failed trial after the cooldown reopens the breaker
+	clock.Advance(time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected half-open after cooldown, got %s", b.State())
+	}
+	if _, err := b.Chat(ctx, &ChatRequest{}); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the trial call to reach the provider, got %v", err)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected failed trial to reopen the breaker, got %s", b.State())
+	}
+
+	p.down = false
+	clock.Advance(time.Minute)
+	if _, err := b.Chat(ctx, &ChatRequest{}); err != nil {
+		t.Fatalf("Expected trial call to succeed, got %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected successful trial to close the breaker, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerIgnoresCancellation(t *testing.T) {
+	p := &flakyProvider{down: true}
+	b, _ := newTestBreaker(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 5; i++ {
+		b.Chat(ctx, &ChatRequest{})
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected cancelled calls not to open the breaker, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	p := &flakyProvider{}
+	b, _ := newTestBreaker(p)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		p.down = i%2 == 0
+		b.Chat(ctx, &ChatRequest{})
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected interleaved successes to keep the breaker closed, got %s", b.State())
+	}
+}
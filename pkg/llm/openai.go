@@ -63,7 +63,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(body))
+		return nil, newAPIError(resp, body)
 	}
 
 	var result ChatResponse
@@ -103,7 +103,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest) (Stre
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error: %s", string(respBody))
+		return nil, newAPIError(resp, respBody)
 	}
 
 	return &openAIStreamReader{reader: resp.Body}, nil
@@ -134,7 +134,7 @@ func (p *OpenAIProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*Emb
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(respBody))
+		return nil, newAPIError(resp, respBody)
 	}
 
 	var result EmbeddingResponse
@@ -0,0 +1,216 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// APIError is a non-200 response from a provider's API.
+type APIError struct {
+	StatusCode int
+	Body       string
+
+	// RetryAfter is the wait the provider asked for with a Retry-After
+	// header, zero when it sent none.
+	RetryAfter time.Duration
+}
+
+// newAPIError builds an APIError from a response and its body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	err := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && secs > 0 {
+		err.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return err
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s", e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate
+// limits, timeouts and server errors are, other client errors are not.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= 500
+}
+
+// IsRetryable reports whether err is transient. API errors are classified
+// by status code; cancelled or expired contexts and an open circuit
+// breaker are not retryable, and any other error, such as a dropped
+// connection, is.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
+
+// RetryConfig holds retry configuration.
+type RetryConfig struct {
+	// MaxAttempts is the most calls made for one request, the first
+	// included.
+	MaxAttempts int
+
+	// BaseDelay is the wait before the first retry, doubling for each
+	// retry after it up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter, between 0 and 1, randomizes each wait by up to this
+	// fraction of it, so clients do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryConfig returns default retry configuration.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// RetryingProvider wraps a Provider and retries calls that fail with a
+// retryable error, backing off exponentially between attempts. A retry is
+// not attempted when its wait would run past the context's deadline.
+type RetryingProvider struct {
+	provider Provider
+	config   RetryConfig
+	clock    utils.Clock
+	// sleep waits for d unless ctx is done first
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryingProvider creates a new RetryingProvider around provider.
+func NewRetryingProvider(provider Provider, config RetryConfig) *RetryingProvider {
+	defaults := DefaultRetryConfig()
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = defaults.BaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = defaults.MaxDelay
+	}
+	if config.Jitter < 0 {
+		config.Jitter = 0
+	}
+	return &RetryingProvider{
+		provider: provider,
+		config:   config,
+		clock:    utils.RealClock{},
+		sleep:    sleepContext,
+	}
+}
+
+// SetClock sets the clock used to check waits against deadlines.
+func (r *RetryingProvider) SetClock(clock utils.Clock) {
+	r.clock = clock
+}
+
+// Chat creates a chat completion, retrying transient failures.
+func (r *RetryingProvider) Chat(ctx context.Context, req *ChatRequest) (resp *ChatResponse, err error) {
+	err = r.do(ctx, func() error {
+		resp, err = r.provider.Chat(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream creates a streaming chat completion, retrying transient
+// failures to open the stream. Errors while reading it are not retried.
+func (r *RetryingProvider) ChatStream(ctx context.Context, req *ChatRequest) (stream StreamReader, err error) {
+	err = r.do(ctx, func() error {
+		stream, err = r.provider.ChatStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
+
+// Embed creates embeddings, retrying transient failures.
+func (r *RetryingProvider) Embed(ctx context.Context, req *EmbeddingRequest) (resp *EmbeddingResponse, err error) {
+	err = r.do(ctx, func() error {
+		resp, err = r.provider.Embed(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// Close closes the wrapped provider.
+func (r *RetryingProvider) Close() error {
+	return r.provider.Close()
+}
+
+// do calls fn until it succeeds, fails permanently or runs out of
+// attempts, returning its last error.
+func (r *RetryingProvider) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || ctx.Err() != nil || attempt >= r.config.MaxAttempts {
+			return err
+		}
+
+		wait := r.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && r.clock.Now().Add(wait).After(deadline) {
+			return err
+		}
+		if sleepErr := r.sleep(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the wait after the given attempt: BaseDelay doubled per
+// earlier retry, capped at MaxDelay, then jittered.
+func (r *RetryingProvider) backoff(attempt int) time.Duration {
+	wait := r.config.BaseDelay
+	for i := 1; i < attempt && wait < r.config.MaxDelay; i++ {
+		wait *= 2
+	}
+	if wait > r.config.MaxDelay {
+		wait = r.config.MaxDelay
+	}
+	if r.config.Jitter > 0 {
+		spread := float64(wait) * r.config.Jitter
+		wait += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+	return wait
+}
+
+// sleepContext waits for d, returning early with ctx's error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// failingProvider fails the first failures calls with err, then succeeds.
+type failingProvider struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *failingProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return nil, p.err
+	}
+	return &ChatResponse{Choices: []Choice{{Message: Message{Content: "ok"}}}}, nil
+}
+
+func (p *failingProvider) ChatStream(ctx context.Context, req *ChatRequest) (StreamReader, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *failingProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return nil, p.err
+	}
+	return &EmbeddingResponse{Data: []Embedding{{Embedding: []float64{1}}}}, nil
+}
+
+func (p *failingProvider) Close() error { return nil }
+
+// newTestRetrying returns a RetryingProvider without jitter whose sleeps
+// advance a fake clock and are recorded in waits.
+func newTestRetrying(p Provider, config RetryConfig) (*RetryingProvider, *[]time.Duration) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewRetryingProvider(p, config)
+	r.SetClock(clock)
+	var waits []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock.Advance(d)
+		return nil
+	}
+	return r, &waits
+}
+
+func TestRetryingProviderRetriesTransientErrors(t *testing.T) {
+	p := &failingProvider{failures: 2, err: &APIError{StatusCode: http.StatusServiceUnavailable, Body: "overloaded"}}
+	r, waits := newTestRetrying(p, RetryConfig{MaxAttempts: 3, BaseDelay: time.Second})
+
+	resp, err := r.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Errorf("Expected the provider's response, got %+v", resp)
+	}
+	if p.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", p.calls)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(*waits, want) {
+		t.Errorf("Expected backoff %v, got %v", want, *waits)
+	}
+
+	// Embed retries the same way
+	p = &failingProvider{failures: 1, err: errors.New("send request: connection reset")}
+	r, _ = newTestRetrying(p, RetryConfig{MaxAttempts: 3, BaseDelay: time.Second})
+	if _, err := r.Embed(context.Background(), &EmbeddingRequest{}); err != nil || p.calls != 2 {
+		t.Errorf("Expected Embed to succeed on the second call, got %v after %d", err, p.calls)
+	}
+}
+
+func TestRetryingProviderGivesUp(t *testing.T) {
+	// Client errors are not retried
+	p := &failingProvider{failures: 5, err: &APIError{StatusCode: http.StatusBadRequest, Body: "bad request"}}
+	r, _ := newTestRetrying(p, RetryConfig{MaxAttempts: 3})
+	if _, err := r.Chat(context.Background(), &ChatRequest{}); err == nil || p.calls != 1 {
+		t.Errorf("Expected a 400 to fail after 1 call, got %v after %d", err, p.calls)
+	}
+
+	// Retries stop after MaxAttempts with the last error
+	p = &failingProvider{failures: 5, err: &APIError{StatusCode: http.StatusTooManyRequests, Body: "slow down"}}
+	r, _ = newTestRetrying(p, RetryConfig{MaxAttempts: 3})
+	var apiErr *APIError
+	if _, err := r.Chat(context.Background(), &ChatRequest{}); !errors.As(err, &apiErr) || p.calls != 3 {
+		t.Errorf("Expected the 429 after 3 calls, got %v after %d", err, p.calls)
+	}
+
+	// A wait past the deadline is not attempted
+	p = &failingProvider{failures: 5, err: &APIError{StatusCode: http.StatusBadGateway}}
+	r, waits := newTestRetrying(p, RetryConfig{MaxAttempts: 3, BaseDelay: time.Minute})
+	ctx, cancel := context.WithDeadline(context.Background(), r.clock.Now().Add(30*time.Second))
+	defer cancel()
+	if _, err := r.Chat(ctx, &ChatRequest{}); err == nil || p.calls != 1 || len(*waits) != 0 {
+		t.Errorf("Expected no retry past the deadline, got %v after %d calls", err, p.calls)
+	}
+}
+
+func TestRetryingProviderHonorsRetryAfter(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}}
+	p := &failingProvider{failures: 1, err: newAPIError(resp, []byte("rate limited"))}
+	r, waits := newTestRetrying(p, RetryConfig{MaxAttempts: 2, BaseDelay: time.Second})
+
+	if _, err := r.Chat(context.Background(), &ChatRequest{}); err != nil {
+		t.Fatalf("Expected success after retry, got %v", err)
+	}
+	if want := []time.Duration{7 * time.Second}; !reflect.DeepEqual(*waits, want) {
+		t.Errorf("Expected the Retry-After wait %v, got %v", want, *waits)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusInternalServerError}, true},
+		{fmt.Errorf("failed to summarize: %w", &APIError{StatusCode: http.StatusServiceUnavailable}), true},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{&APIError{StatusCode: http.StatusUnauthorized}, false},
+		{errors.New("send request: connection refused"), true},
+		{context.DeadlineExceeded, false},
+		{ErrCircuitOpen, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, expected %v", tt.err, got, tt.want)
+		}
+	}
+}
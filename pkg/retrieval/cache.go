@@ -0,0 +1,201 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// Retriever runs a typed query against the context store.
+// *HierarchicalRetriever and *CachingRetriever implement this interface.
+type Retriever interface {
+	Retrieve(ctx context.Context, query TypedQuery, opts SearchOptions) (*QueryResult, error)
+}
+
+// CacheConfig holds configuration for CachingRetriever.
+type CacheConfig struct {
+	TTL        time.Duration // How long a result stays valid, default 30s
+	MaxEntries int           // Results kept before evicting the least recent, default 256
+}
+
+// DefaultCacheConfig returns default cache configuration.
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		TTL:        30 * time.Second,
+		MaxEntries: 256,
+	}
+}
+
+// CachingRetriever caches the results of another Retriever by query and
+// options. Cached results expire after the TTL and are dropped whenever
+// Invalidate is called, typically from a ChangeSource.
+type CachingRetriever struct {
+	inner  Retriever
+	config CacheConfig
+	clock  utils.Clock
+
+	mu      sync.Mutex
+	results *utils.LRU[string, *QueryResult]
+	// generation increases on every Invalidate so that results computed
+	// from data that changed mid-query are not cached
+	generation uint64
+}
+
+// NewCachingRetriever creates a new CachingRetriever wrapping inner.
+func NewCachingRetriever(inner Retriever, config CacheConfig) *CachingRetriever {
+	defaults := DefaultCacheConfig()
+	if config.TTL <= 0 {
+		config.TTL = defaults.TTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaults.MaxEntries
+	}
+	return &CachingRetriever{
+		inner:   inner,
+		config:  config,
+		clock:   utils.RealClock{},
+		results: utils.NewLRU[string, *QueryResult](config.MaxEntries),
+	}
+}
+
+// SetClock sets the clock used to expire cached results.
+func (c *CachingRetriever) SetClock(clock utils.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clock
+}
+
+// Watch invalidates the cache whenever src reports a change.
+func (c *CachingRetriever) Watch(src ChangeSource) {
+	src.Subscribe(c.Invalidate)
+}
+
+// Invalidate drops every cached result.
+func (c *CachingRetriever) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results.Clear()
+	c.generation++
+}
+
+// Len returns the number of cached results, including expired ones not
+// yet evicted.
+func (c *CachingRetriever) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.results.Len()
+}
+
+// Retrieve returns the cached result for an identical query and options,
+// or runs the wrapped retriever and caches its result. Errors are not
+// cached.
+func (c *CachingRetriever) Retrieve(ctx context.Context, query TypedQuery, opts SearchOptions) (*QueryResult, error) {
+	key, err := cacheKey(query, opts)
+	if err != nil {
+		return c.inner.Retrieve(ctx, query, opts)
+	}
+
+	c.mu.Lock()
+	if cached, ok := c.results.Get(key, c.clock.Now()); ok {
+		c.mu.Unlock()
+		return copyQueryResult(cached), nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	result, err := c.inner.Retrieve(ctx, query, opts)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation {
+		c.results.Put(key, copyQueryResult(result), c.clock.Now().Add(c.config.TTL))
+	}
+	return result, nil
+}
+
+// cacheKey hashes the query, which includes its context type, and options.
+func cacheKey(query TypedQuery, opts SearchOptions) (string, error) {
+	data, err := json.Marshal(struct {
+		Query   TypedQuery
+		Options SearchOptions
+	}{query, opts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// copyQueryResult copies r so callers cannot modify cached matches.
+func copyQueryResult(r *QueryResult) *QueryResult {
+	cp := *r
+	cp.MatchedContexts = append([]MatchedContext(nil), r.MatchedContexts...)
+	cp.SearchedDirectories = append([]string(nil), r.SearchedDirectories...)
+	return &cp
+}
+
+// ChangeSource reports changes to the data a retriever searches.
+type ChangeSource interface {
+	// Subscribe registers fn to be called after each change.
+	Subscribe(fn func())
+}
+
+// NotifyingVectorStore is a VectorStore that notifies subscribers after
+// every successful Add or Delete, so caches over it can be invalidated.
+type NotifyingVectorStore struct {
+	VectorStore
+
+	mu          sync.RWMutex
+	subscribers []func()
+}
+
+// NewNotifyingVectorStore wraps store with change notifications.
+func NewNotifyingVectorStore(store VectorStore) *NotifyingVectorStore {
+	return &NotifyingVectorStore{VectorStore: store}
+}
+
+// Subscribe implements ChangeSource.
+func (s *NotifyingVectorStore) Subscribe(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Add implements VectorStore.
+func (s *NotifyingVectorStore) Add(ctx context.Context, vectors []SearchResult) error {
+	if err := s.VectorStore.Add(ctx, vectors); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Delete implements VectorStore.
+func (s *NotifyingVectorStore) Delete(ctx context.Context, uris []string) error {
+	if err := s.VectorStore.Delete(ctx, uris); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// notify calls every subscriber.
+func (s *NotifyingVectorStore) notify() {
+	s.mu.RLock()
+	subscribers := append([]func(){}, s.subscribers...)
+	s.mu.RUnlock()
+	for _, fn := range subscribers {
+		fn()
+	}
+}
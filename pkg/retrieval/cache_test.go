@@ -0,0 +1,244 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// countingEmbedder counts Embed calls.
+type countingEmbedder struct {
+	fixedEmbedder
+	calls int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) (*EmbedResult, error) {
+	e.calls++
+	return e.fixedEmbedder.Embed(ctx, text)
+}
+
+func newCachingTestRetriever(config CacheConfig) (*CachingRetriever, *countingEmbedder, *NotifyingVectorStore) {
+	embedder := &countingEmbedder{fixedEmbedder: fixedEmbedder{vector: []float64{1}}}
+	store := NewNotifyingVectorStore(&chainStore{width: 0, maxLevel: 1})
+	cache := NewCachingRetriever(NewHierarchicalRetriever(embedder, store, DefaultRetrieverConfig()), config)
+	cache.Watch(store)
+	return cache, embedder, store
+}
+
+// searches returns the directory searches made by the caching test
+// retriever's store, one per computed result.
+func searches(store *NotifyingVectorStore) int {
+	chain := store.VectorStore.(*chainStore)
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	return chain.searches
+}
+
+func cacheTestOptions() SearchOptions {
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://root-0000"}
+	return opts
+}
+
+func TestCachingRetrieverReusesResult(t *testing.T) {
+	cache, embedder, store := newCachingTestRetriever(DefaultCacheConfig())
+	ctx := context.Background()
+	query := TypedQuery{Query: "leaf", ContextType: ContextTypeResource}
+
+	first, err := cache.Retrieve(ctx, query, cacheTestOptions())
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	second, err := cache.Retrieve(ctx, query, cacheTestOptions())
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if n := searches(store); n != 1 {
+		t.Errorf("Expected 1 search, got %d", n)
+	}
+	if len(second.MatchedContexts) != 1 || second.MatchedContexts[0].URI != first.MatchedContexts[0].URI {
+		t.Errorf("Expected cached result to match, got %+v", second.MatchedContexts)
+	}
+
+	// Changing the context type or options is a different query
+	if _, err := cache.Retrieve(ctx, TypedQuery{Query: "leaf", ContextType: ContextTypeMemory}, cacheTestOptions()); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	opts := cacheTestOptions()
+	opts.Limit = 1
+	if _, err := cache.Retrieve(ctx, query, opts); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if n := searches(store); n != 3 {
+		t.Errorf("Expected 3 searches, got %d", n)
+	}
+	// The query text is embedded once across them
+	if embedder.calls != 1 {
+		t.Errorf("Expected 1 embed call, got %d", embedder.calls)
+	}
+}
+
+func TestCachingRetrieverInvalidatesOnChange(t *testing.T) {
+	cache, embedder, store := newCachingTestRetriever(DefaultCacheConfig())
+	ctx := context.Background()
+	query := TypedQuery{Query: "leaf"}
+
+	cache.Retrieve(ctx, query, cacheTestOptions())
+	if err := store.Add(ctx, []SearchResult{{URI: "viking://root-0000/new"}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected empty cache after store change, got %d entries", cache.Len())
+	}
+	cache.Retrieve(ctx, query, cacheTestOptions())
+	if n := searches(store); n != 2 {
+		t.Errorf("Expected recomputation after invalidation, got %d searches", n)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("Expected the query embedding reused, got %d embed calls", embedder.calls)
+	}
+}
+
+func TestCachingRetrieverExpiry(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache, _, store := newCachingTestRetriever(CacheConfig{TTL: time.Minute})
+	cache.SetClock(clock)
+	ctx := context.Background()
+	query := TypedQuery{Query: "leaf"}
+
+	cache.Retrieve(ctx, query, cacheTestOptions())
+	clock.Advance(30 * time.Second)
+	cache.Retrieve(ctx, query, cacheTestOptions())
+	if n := searches(store); n != 1 {
+		t.Errorf("Expected cached result within TTL, got %d searches", n)
+	}
+
+	clock.Advance(time.Minute)
+	cache.Retrieve(ctx, query, cacheTestOptions())
+	if n := searches(store); n != 2 {
+		t.Errorf("Expected recomputation after TTL, got %d searches", n)
+	}
+}
+
+func TestCachingRetrieverSizeCap(t *testing.T) {
+	cache, _, store := newCachingTestRetriever(CacheConfig{MaxEntries: 2})
+	ctx := context.Background()
+
+	for _, q := range []string{"a", "b", "c"} {
+		cache.Retrieve(ctx, TypedQuery{Query: q}, cacheTestOptions())
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached results, got %d", cache.Len())
+	}
+
+	// "a" was least recently used and has been evicted
+	cache.Retrieve(ctx, TypedQuery{Query: "c"}, cacheTestOptions())
+	cache.Retrieve(ctx, TypedQuery{Query: "a"}, cacheTestOptions())
+	if n := searches(store); n != 4 {
+		t.Errorf("Expected 4 searches, got %d", n)
+	}
+}
+
+func TestCachingRetrieverCopiesResults(t *testing.T) {
+	cache, _, _ := newCachingTestRetriever(DefaultCacheConfig())
+	ctx := context.Background()
+	query := TypedQuery{Query: "leaf"}
+
+	first, _ := cache.Retrieve(ctx, query, cacheTestOptions())
+	first.MatchedContexts[0].URI = "modified"
+	second, _ := cache.Retrieve(ctx, query, cacheTestOptions())
+	if second.MatchedContexts[0].URI == "modified" {
+		t.Error("Expected cached result to be unaffected by caller changes")
+	}
+}
+
+func TestEmbeddingCacheRepeatedQuery(t *testing.T) {
+	embedder := &countingEmbedder{fixedEmbedder: fixedEmbedder{vector: []float64{1}}}
+	config := DefaultRetrieverConfig()
+	config.EmbeddingModel = "model-a"
+	hr := NewHierarchicalRetriever(embedder, &chainStore{width: 0, maxLevel: 1}, config)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := hr.Retrieve(ctx, TypedQuery{Query: "golang"}, cacheTestOptions()); err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+	}
+	if embedder.calls != 1 {
+		t.Errorf("Expected the query embedded once, got %d embed calls", embedder.calls)
+	}
+	stats := hr.EmbeddingCache().Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+	if rate := stats.HitRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected a hit rate of 2/3, got %f", rate)
+	}
+
+	// Embeddings are kept per model
+	hr.EmbeddingCache().Put("model-b", "golang", &EmbedResult{DenseVector: []float64{2}})
+	if result, ok := hr.EmbeddingCache().Get("model-a", "golang"); !ok || result.DenseVector[0] != 1 {
+		t.Errorf("Expected model-a's embedding, got %+v", result)
+	}
+
+	// Callers get copies they may modify
+	result, _ := hr.EmbeddingCache().Get("model-b", "golang")
+	result.DenseVector[0] = 3
+	if again, _ := hr.EmbeddingCache().Get("model-b", "golang"); again.DenseVector[0] != 2 {
+		t.Errorf("Expected the cached embedding unaffected by caller changes, got %+v", again)
+	}
+}
+
+func TestEmbeddingCacheEviction(t *testing.T) {
+	cache := NewEmbeddingCache(2, 0)
+	for _, q := range []string{"a", "b"} {
+		cache.Put("m", q, &EmbedResult{DenseVector: []float64{1}})
+	}
+	// Using "a" leaves "b" least recently used
+	if _, ok := cache.Get("m", "a"); !ok {
+		t.Fatal("Expected a cached")
+	}
+	cache.Put("m", "c", &EmbedResult{DenseVector: []float64{1}})
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached embeddings at capacity, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("m", "b"); ok {
+		t.Error("Expected b evicted")
+	}
+	for _, q := range []string{"a", "c"} {
+		if _, ok := cache.Get("m", q); !ok {
+			t.Errorf("Expected %s still cached", q)
+		}
+	}
+}
+
+func TestEmbeddingCacheExpiry(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewEmbeddingCache(4, time.Minute)
+	cache.SetClock(clock)
+	cache.Put("m", "q", &EmbedResult{DenseVector: []float64{1}})
+
+	clock.Advance(30 * time.Second)
+	if _, ok := cache.Get("m", "q"); !ok {
+		t.Error("Expected the embedding valid within its TTL")
+	}
+	clock.Advance(time.Minute)
+	if _, ok := cache.Get("m", "q"); ok {
+		t.Error("Expected the embedding expired after its TTL")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected the expired embedding dropped, got %d", cache.Len())
+	}
+
+	var disabled *EmbeddingCache
+	disabled.Put("m", "q", &EmbedResult{})
+	if _, ok := disabled.Get("m", "q"); ok || disabled.Stats() != (EmbeddingCacheStats{}) {
+		t.Error("Expected a nil cache to cache nothing")
+	}
+}
@@ -44,6 +44,11 @@ type DirectoryTraverser struct {
 
 	// Maximum file size in bytes (0 = no limit)
 	MaxFileSize int64
+
+	// Content types by file extension (e.g., {".vue": "text/x-vue"}),
+	// taking precedence over the built-in ones. The leading dot is
+	// optional and extensions match case-insensitively.
+	ContentTypeOverrides map[string]string
 }
 
 // NewDirectoryTraverser creates a new DirectoryTraverser with default options.
@@ -193,9 +198,19 @@ func (dt *DirectoryTraverser) matchesAnyPattern(path string, patterns []string)
 	return false
 }
 
-// detectContentType detects content type from file extension.
+// detectContentType detects content type from file extension, consulting
+// ContentTypeOverrides first.
 func (dt *DirectoryTraverser) detectContentType(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
+	for key, contentType := range dt.ContentTypeOverrides {
+		key = strings.ToLower(key)
+		if !strings.HasPrefix(key, ".") {
+			key = "." + key
+		}
+		if key == ext {
+			return contentType
+		}
+	}
 	switch ext {
 	case ".go":
 		return "text/x-go"
@@ -127,3 +127,42 @@ func TestPatternMatcher(t *testing.T) {
 		}
 	}
 }
+
+func TestDirectoryTraverserContentTypeOverrides(t *testing.T) {
+	traverser := NewDirectoryTraverser()
+	traverser.ContentTypeOverrides = map[string]string{
+		".tsx":    "text/typescript-react",
+		"vue":     "text/x-vue",
+		".SVELTE": "text/x-svelte",
+		".md":     "text/x-markdown",
+	}
+
+	tests := map[string]string{
+		"App.tsx":       "text/typescript-react",
+		"Page.vue":      "text/x-vue",
+		"Widget.svelte": "text/x-svelte",
+		"README.MD":     "text/x-markdown",
+		"main.go":       "text/x-go",
+		"index.ts":      "text/typescript",
+		"data.unknown":  "text/plain",
+		"Makefile":      "text/plain",
+	}
+	for path, want := range tests {
+		if got := traverser.detectContentType(path); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, path, got)
+		}
+	}
+
+	// Overrides reach traversed entries
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "Page.vue"), []byte("<template/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := traverser.Traverse(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Traverse failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ContentType != "text/x-vue" {
+		t.Errorf("Expected Page.vue as text/x-vue, got %+v", entries)
+	}
+}
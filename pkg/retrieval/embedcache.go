@@ -0,0 +1,131 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// EmbeddingCacheStats counts the lookups of an EmbeddingCache.
+type EmbeddingCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// HitRate returns the share of lookups served from the cache, or 0 before
+// any lookup.
+func (s EmbeddingCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// embeddingKey identifies a query embedding.
+type embeddingKey struct {
+	model string
+	query string
+}
+
+// EmbeddingCache keeps the embeddings of recent queries by model and
+// query text, evicting the least recently used beyond its capacity. It is
+// safe for concurrent use, and a nil *EmbeddingCache caches nothing.
+type EmbeddingCache struct {
+	capacity int
+	ttl      time.Duration
+	clock    utils.Clock
+
+	mu         sync.Mutex
+	embeddings *utils.LRU[embeddingKey, *EmbedResult]
+	hits       int64
+	misses     int64
+}
+
+// NewEmbeddingCache creates a cache holding up to capacity embeddings,
+// each valid for ttl; a ttl of 0 keeps embeddings until evicted.
+func NewEmbeddingCache(capacity int, ttl time.Duration) *EmbeddingCache {
+	return &EmbeddingCache{
+		capacity:   capacity,
+		ttl:        ttl,
+		clock:      utils.RealClock{},
+		embeddings: utils.NewLRU[embeddingKey, *EmbedResult](capacity),
+	}
+}
+
+// SetClock sets the clock used to expire embeddings.
+func (c *EmbeddingCache) SetClock(clock utils.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clock
+}
+
+// Get returns a copy of the cached embedding of query by model, if still
+// valid.
+func (c *EmbeddingCache) Get(model, query string) (*EmbedResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.embeddings.Get(embeddingKey{model, query}, c.clock.Now())
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return copyEmbedResult(result), true
+}
+
+// Put caches a copy of the embedding of query by model.
+func (c *EmbeddingCache) Put(model, query string, result *EmbedResult) {
+	if c == nil || c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.clock.Now().Add(c.ttl)
+	}
+	c.embeddings.Put(embeddingKey{model, query}, copyEmbedResult(result), expires)
+}
+
+// Len returns the number of cached embeddings, including expired ones not
+// yet evicted.
+func (c *EmbeddingCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.embeddings.Len()
+}
+
+// Stats returns the hits and misses so far and the number of entries.
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	if c == nil {
+		return EmbeddingCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return EmbeddingCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.embeddings.Len()}
+}
+
+// copyEmbedResult copies r so callers cannot modify cached vectors.
+func copyEmbedResult(r *EmbedResult) *EmbedResult {
+	cp := &EmbedResult{DenseVector: append([]float64(nil), r.DenseVector...)}
+	if r.SparseVector != nil {
+		cp.SparseVector = make(map[string]float64, len(r.SparseVector))
+		for k, v := range r.SparseVector {
+			cp.SparseVector[k] = v
+		}
+	}
+	return cp
+}
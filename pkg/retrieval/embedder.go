@@ -41,6 +41,61 @@ type Embedder interface {
 	Close() error
 }
 
+// DefaultNoopDimension is the vector dimension of a NoopEmbedder created
+// without one, matching text-embedding-3-small.
+const DefaultNoopDimension = 1536
+
+// NoopEmbedder stands in for a missing embedding provider. It returns zero
+// vectors of a fixed dimension, which carry no similarity, so code
+// expecting vectors keeps working while components holding one skip
+// vector-based ranking; see IsNoopEmbedder.
+type NoopEmbedder struct {
+	dimension int
+}
+
+// NewNoopEmbedder creates a NoopEmbedder producing vectors of dimension,
+// or DefaultNoopDimension when dimension is not positive.
+func NewNoopEmbedder(dimension int) *NoopEmbedder {
+	if dimension <= 0 {
+		dimension = DefaultNoopDimension
+	}
+	return &NoopEmbedder{dimension: dimension}
+}
+
+// Embed returns a zero vector.
+func (e *NoopEmbedder) Embed(ctx context.Context, text string) (*EmbedResult, error) {
+	return &EmbedResult{DenseVector: make([]float64, e.dimension)}, nil
+}
+
+// EmbedBatch returns a zero vector for each text.
+func (e *NoopEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*EmbedResult, error) {
+	results := make([]*EmbedResult, len(texts))
+	for i := range texts {
+		results[i] = &EmbedResult{DenseVector: make([]float64, e.dimension)}
+	}
+	return results, nil
+}
+
+// GetDimension returns the vector dimension.
+func (e *NoopEmbedder) GetDimension() int {
+	return e.dimension
+}
+
+// Close releases nothing.
+func (e *NoopEmbedder) Close() error {
+	return nil
+}
+
+// IsNoopEmbedder reports whether e yields no usable vectors, being nil or
+// a *NoopEmbedder.
+func IsNoopEmbedder(e Embedder) bool {
+	if e == nil {
+		return true
+	}
+	_, ok := e.(*NoopEmbedder)
+	return ok
+}
+
 // DenseEmbedder defines interface for dense vector embedding.
 type DenseEmbedder interface {
 	Embedder
@@ -0,0 +1,71 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// FusionWeights balances the signals blended into a search score. Each
+// weight is the share of the score given to its signal; the rest goes to
+// semantic similarity.
+type FusionWeights struct {
+	// Keyword weighs keyword relevance against semantic similarity
+	Keyword float64 `json:"keyword_weight"`
+
+	// Hotness weighs access frequency and recency against relevance
+	Hotness float64 `json:"hotness_weight"`
+}
+
+// DefaultFusionWeights returns the default blend: keyword and semantic
+// matches count equally, and hotness contributes a fifth of the score.
+func DefaultFusionWeights() FusionWeights {
+	return FusionWeights{
+		Keyword: 0.5,
+		Hotness: 0.2,
+	}
+}
+
+// Validate checks that both weights are between 0 and 1.
+func (w FusionWeights) Validate() error {
+	if w.Keyword < 0 || w.Keyword > 1 {
+		return fmt.Errorf("keyword weight must be between 0 and 1, got %v", w.Keyword)
+	}
+	if w.Hotness < 0 || w.Hotness > 1 {
+		return fmt.Errorf("hotness weight must be between 0 and 1, got %v", w.Hotness)
+	}
+	return nil
+}
+
+// KeywordScores scores each text against query with BM25 and scales the
+// scores so that the best match scores 1. All scores are 0 when no text
+// matches.
+func KeywordScores(tokenizer *Tokenizer, query string, texts []string) []float64 {
+	idx := NewIndexWithTokenizer(tokenizer)
+	for i, text := range texts {
+		idx.AddDocument(strconv.Itoa(i), text, nil)
+	}
+	idx.BuildIDF()
+
+	scores := make([]float64, len(texts))
+	for _, r := range NewKeywordSearch().Search(context.Background(), query, idx, 0, nil) {
+		i, _ := strconv.Atoi(r.URI)
+		scores[i] = r.Score
+	}
+
+	max := 0.0
+	for _, s := range scores {
+		if s > max {
+			max = s
+		}
+	}
+	if max > 0 {
+		for i := range scores {
+			scores[i] /= max
+		}
+	}
+	return scores
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFusionWeightsValidate(t *testing.T) {
+	if err := DefaultFusionWeights().Validate(); err != nil {
+		t.Errorf("Expected default weights to be valid, got %v", err)
+	}
+	for _, w := range []FusionWeights{{Keyword: -0.1}, {Keyword: 1.1}, {Hotness: 2}} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", w)
+		}
+	}
+}
+
+func TestKeywordScores(t *testing.T) {
+	tokenizer := NewTokenizer(EnglishTokenizerConfig())
+	scores := KeywordScores(tokenizer, "testing", []string{
+		"golang concurrency patterns",
+		"golang testing guide",
+		"tests and more tests",
+	})
+
+	if scores[0] != 0 {
+		t.Errorf("Expected no match for the first text, got %f", scores[0])
+	}
+	if scores[1] != 1 && scores[2] != 1 {
+		t.Errorf("Expected the best match to score 1, got %v", scores)
+	}
+	if scores[1] <= 0 || scores[2] <= 0 {
+		t.Errorf("Expected stemmed matches to score, got %v", scores)
+	}
+
+	for _, s := range KeywordScores(tokenizer, "rust", []string{"golang", "python"}) {
+		if s != 0 {
+			t.Errorf("Expected zero scores without matches, got %f", s)
+		}
+	}
+}
+
+func TestHybridSearchRRFIgnoresAlpha(t *testing.T) {
+	search := func(alpha float64) []string {
+		hs := NewHybridSearch(newTestSemanticSearch(), alpha)
+		hs.IndexDocuments(context.Background(), []SearchResult{
+			{URI: "viking://resources/a", Abstract: "golang concurrency patterns"},
+			{URI: "viking://resources/c", Abstract: "golang testing guide"},
+		})
+		results, err := hs.Search(context.Background(), "testing", 10, nil)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		var uris []string
+		for _, r := range results {
+			uris = append(uris, r.URI)
+		}
+		return uris
+	}
+
+	high, low := search(0.9), search(0.1)
+	if strings.Join(high, ",") != strings.Join(low, ",") {
+		t.Errorf("Expected the same RRF order for any alpha, got %v and %v", high, low)
+	}
+}
+
+func TestHybridSearchFusionModes(t *testing.T) {
+	search := func(mode FusionMode, alpha float64) []string {
+		hs := NewHybridSearch(newTestSemanticSearch(), alpha)
+		if mode != "" {
+			hs.SetFusionMode(mode)
+		}
+		hs.IndexDocuments(context.Background(), []SearchResult{
+			{URI: "viking://resources/a", Abstract: "golang concurrency patterns"},
+			{URI: "viking://resources/c", Abstract: "golang testing guide"},
+		})
+		results, err := hs.Search(context.Background(), "testing", 10, nil)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		var uris []string
+		for _, r := range results {
+			uris = append(uris, r.URI)
+		}
+		return uris
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Semantic scores are a=1 and b=0.6; only c matches the keyword.
+	// RRF ranks a and c, each first in one list, ahead of b whatever the
+	// alpha; weighted fusion at alpha 0.9 compares 0.9*0.6 with 0.1*1.
+	rrf := search(FusionRRF, 0.9)
+	if want := []string{"viking://resources/a", "viking://resources/c", "viking://resources/b"}; !equal(rrf, want) {
+		t.Errorf("Expected RRF order %v, got %v", want, rrf)
+	}
+	weighted := search(FusionWeighted, 0.9)
+	if want := []string{"viking://resources/a", "viking://resources/b", "viking://resources/c"}; !equal(weighted, want) {
+		t.Errorf("Expected weighted order %v, got %v", want, weighted)
+	}
+
+	// In weighted mode alpha moves the keyword match ahead of the
+	// semantic one
+	if got := search(FusionWeighted, 0.9); got[0] != "viking://resources/a" {
+		t.Errorf("Expected semantic match first with alpha 0.9, got %v", got)
+	}
+	if got := search(FusionWeighted, 0.1); got[0] != "viking://resources/c" {
+		t.Errorf("Expected keyword match first with alpha 0.1, got %v", got)
+	}
+
+	// The default mode is RRF
+	if got := search("", 0.6); !equal(got, rrf) {
+		t.Errorf("Expected RRF by default, got %v", got)
+	}
+}
@@ -6,6 +6,8 @@ package retrieval
 import (
 	"math"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // HotnessConfig holds configuration for hotness scoring.
@@ -25,6 +27,7 @@ func DefaultHotnessConfig() HotnessConfig {
 // HotnessScorer calculates hotness scores for contexts.
 type HotnessScorer struct {
 	config HotnessConfig
+	clock  utils.Clock
 }
 
 // NewHotnessScorer creates a new hotness scorer.
@@ -32,14 +35,21 @@ func NewHotnessScorer(config HotnessConfig) *HotnessScorer {
 	if config.Alpha == 0 {
 		config.Alpha = 0.2
 	}
+	config.Alpha = clampUnit(config.Alpha)
 	if config.HalfLifeDays == 0 {
 		config.HalfLifeDays = 7
 	}
 	return &HotnessScorer{
 		config: config,
+		clock:  utils.RealClock{},
 	}
 }
 
+// SetClock sets the clock used to measure time since last access.
+func (h *HotnessScorer) SetClock(clock utils.Clock) {
+	h.clock = clock
+}
+
 // CalculateHotness calculates hotness score based on access count and last access time.
 // Returns value between 0 and 1.
 func (h *HotnessScorer) CalculateHotness(accessCount int, lastAccess time.Time) float64 {
@@ -65,7 +75,7 @@ func (h *HotnessScorer) sigmoid(x float64) float64 {
 // exponentialDecay calculates exponential decay based on time since last access.
 func (h *HotnessScorer) exponentialDecay(lastAccess time.Time) float64 {
 	// Calculate hours since last access
-	hoursSince := time.Since(lastAccess).Hours()
+	hoursSince := h.clock.Now().Sub(lastAccess).Hours()
 	if hoursSince < 0 {
 		hoursSince = 0
 	}
@@ -88,10 +98,35 @@ func (h *HotnessScorer) exponentialDecay(lastAccess time.Time) float64 {
 	return decay
 }
 
-// HybridScore combines semantic similarity with hotness score.
+// Alpha returns the weight given to hotness in HybridScore.
+func (h *HotnessScorer) Alpha() float64 {
+	return h.config.Alpha
+}
+
+// SetAlpha sets the weight given to hotness in HybridScore, clamped to
+// [0, 1]. Zero ranks by semantic similarity alone.
+func (h *HotnessScorer) SetAlpha(alpha float64) {
+	h.config.Alpha = clampUnit(alpha)
+}
+
+// HybridScore combines semantic similarity with hotness score as
+// (1-alpha)*semantic + alpha*hotness. Both inputs are clamped to [0, 1]
+// first, since similarity scores can stray slightly out of range, so the
+// result is always in [0, 1]. It is in (0, 1] whenever hotness counts
+// (alpha > 0) and is positive, as CalculateHotness always is.
 func (h *HotnessScorer) HybridScore(semanticScore, hotnessScore float64) float64 {
-	// final = (1 - alpha) * semantic + alpha * hotness
-	return (1-h.config.Alpha)*semanticScore + h.config.Alpha*hotnessScore
+	return (1-h.config.Alpha)*clampUnit(semanticScore) + h.config.Alpha*clampUnit(hotnessScore)
+}
+
+// clampUnit clamps x to [0, 1], mapping NaN to 0.
+func clampUnit(x float64) float64 {
+	if !(x > 0) {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
 }
 
 // CombineScores combines semantic and hotness scores with custom alpha.
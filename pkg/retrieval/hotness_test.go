@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+func TestHotnessDecayWithClock(t *testing.T) {
+	lastAccess := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := utils.NewFakeClock(lastAccess)
+
+	scorer := NewHotnessScorer(DefaultHotnessConfig())
+	scorer.SetClock(clock)
+
+	if got := scorer.exponentialDecay(lastAccess); got != 1 {
+		t.Errorf("Expected no decay at access time, got %f", got)
+	}
+
+	// One half-life later the recency score halves
+	clock.Advance(7 * 24 * time.Hour)
+	if got := scorer.exponentialDecay(lastAccess); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("Expected 0.5 after one half-life, got %f", got)
+	}
+
+	fresh := scorer.CalculateHotness(10, clock.Now())
+	stale := scorer.CalculateHotness(10, lastAccess)
+	if stale >= fresh {
+		t.Errorf("Expected stale access to score lower: fresh %f, stale %f", fresh, stale)
+	}
+}
+
+func TestHybridScoreClampsInputs(t *testing.T) {
+	scorer := NewHotnessScorer(DefaultHotnessConfig())
+	hotness := scorer.CalculateHotness(0, time.Now().Add(-365*24*time.Hour))
+
+	for _, semantic := range []float64{-0.05, -1e-9, 0, 0.5, 1, 1 + 1e-9, 1.05, math.NaN()} {
+		for _, hot := range []float64{hotness, 1, 1.02} {
+			got := scorer.HybridScore(semantic, hot)
+			if !(got > 0 && got <= 1) {
+				t.Errorf("HybridScore(%v, %v) = %v, expected a value in (0, 1]", semantic, hot, got)
+			}
+		}
+	}
+	if got := scorer.HybridScore(1.05, 1.02); got != 1 {
+		t.Errorf("Expected inputs above 1 to score 1, got %v", got)
+	}
+}
+
+func TestHotnessScorerAlpha(t *testing.T) {
+	scorer := NewHotnessScorer(DefaultHotnessConfig())
+	if scorer.Alpha() != 0.2 {
+		t.Errorf("Expected default alpha 0.2, got %v", scorer.Alpha())
+	}
+
+	scorer.SetAlpha(0.5)
+	if got := scorer.HybridScore(0.4, 0.8); math.Abs(got-0.6) > 1e-9 {
+		t.Errorf("Expected an even blend of 0.6, got %v", got)
+	}
+	scorer.SetAlpha(1.5)
+	if scorer.Alpha() != 1 {
+		t.Errorf("Expected alpha clamped to 1, got %v", scorer.Alpha())
+	}
+	scorer.SetAlpha(0)
+	if got := scorer.HybridScore(0.4, 0.8); got != 0.4 {
+		t.Errorf("Expected the semantic score alone, got %v", got)
+	}
+}
@@ -5,11 +5,26 @@ package retrieval
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"math"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// IDFVariant selects the inverse document frequency formula used by BM25.
+type IDFVariant string
+
+const (
+	// IDFLucene is log(1 + (N-df+0.5)/(df+0.5)), which stays positive for
+	// every term.
+	IDFLucene IDFVariant = "lucene"
+	// IDFClassic is the Robertson-Sparck Jones log((N-df+0.5)/(df+0.5)),
+	// which goes negative for terms in more than half the documents.
+	IDFClassic IDFVariant = "classic"
 )
 
 // KeywordSearch performs keyword-based search using BM25 or simple term matching.
@@ -17,6 +32,9 @@ type KeywordSearch struct {
 	// BM25 parameters
 	k1 float64 // term frequency saturation parameter
 	b  float64 // document length normalization parameter
+
+	idfVariant IDFVariant
+	idfFloor   bool
 }
 
 // NewKeywordSearch creates a new KeywordSearch with default BM25 parameters.
@@ -24,7 +42,36 @@ func NewKeywordSearch() *KeywordSearch {
 	return &KeywordSearch{
 		k1: 1.5, // BM25 standard
 		b:  0.75, // BM25 standard
+		idfVariant: IDFLucene,
+	}
+}
+
+// SetIDFVariant sets the IDF formula used when scoring.
+func (ks *KeywordSearch) SetIDFVariant(variant IDFVariant) {
+	ks.idfVariant = variant
+}
+
+// SetIDFFloor clamps negative IDF values to zero when enabled, so a term
+// common to most documents never lowers a document's score.
+func (ks *KeywordSearch) SetIDFFloor(enabled bool) {
+	ks.idfFloor = enabled
+}
+
+// idf returns the IDF of term in idx for the configured variant.
+func (ks *KeywordSearch) idf(idx *Index, term string) float64 {
+	var idf float64
+	switch ks.idfVariant {
+	case IDFClassic:
+		df := float64(idx.DocFreq[term])
+		N := float64(idx.TotalDocs)
+		idf = math.Log((N - df + 0.5) / (df + 0.5))
+	default:
+		idf = idx.IDF[term]
+	}
+	if ks.idfFloor && idf < 0 {
+		return 0
 	}
+	return idf
 }
 
 // BM25Result contains BM25 scoring information.
@@ -42,51 +89,92 @@ type Index struct {
 	DocLengths   map[string]int // URI -> length
 	AvgDocLength float64
 	IDF         map[string]float64 // term -> IDF score
+	DocFreq     map[string]int // term -> number of documents containing it
 	TotalDocs   int
+	// Metadata holds the metadata given for each document, if any
+	Metadata map[string]map[string]string // URI -> key -> value
+	// Positions holds where each term occurs, for phrase queries
+	Positions map[string]map[string][]int // URI -> term -> ascending positions
+
+	tokenizer *Tokenizer
 }
 
-// NewIndex creates a new Index.
+// NewIndex creates a new Index using the raw tokenizer.
 func NewIndex() *Index {
+	return NewIndexWithTokenizer(NewTokenizer(DefaultTokenizerConfig()))
+}
+
+// NewIndexWithTokenizer creates a new Index that tokenizes documents and
+// queries with the given tokenizer.
+func NewIndexWithTokenizer(tokenizer *Tokenizer) *Index {
 	return &Index{
 		Documents:  make(map[string]string),
 		TermFreq:   make(map[string]map[string]int),
 		DocLengths: make(map[string]int),
 		IDF:        make(map[string]float64),
+		DocFreq:    make(map[string]int),
+		Metadata:   make(map[string]map[string]string),
+		Positions:  make(map[string]map[string][]int),
+		tokenizer:  tokenizer,
 	}
 }
 
-// AddDocument adds a document to the index.
-func (idx *Index) AddDocument(uri, content string) {
+// Tokenize splits text into terms the same way documents were indexed.
+func (idx *Index) Tokenize(text string) []string {
+	if idx.tokenizer == nil {
+		return tokenize(text)
+	}
+	return idx.tokenizer.Tokenize(text)
+}
+
+// AddDocument adds a document to the index. metadata, which may be nil,
+// is what keyword search filters match against.
+func (idx *Index) AddDocument(uri, content string, metadata map[string]string) {
 	// Store document
 	idx.Documents[uri] = content
+	if metadata != nil {
+		idx.Metadata[uri] = metadata
+	} else {
+		delete(idx.Metadata, uri)
+	}
 
 	// Tokenize
-	terms := tokenize(content)
+	terms := idx.Tokenize(content)
 	idx.DocLengths[uri] = len(terms)
 
-	// Calculate term frequencies
+	// Calculate term frequencies and positions
 	freq := make(map[string]int)
-	for _, term := range terms {
+	positions := make(map[string][]int)
+	for i, term := range terms {
 		freq[term]++
+		positions[term] = append(positions[term], i)
 	}
 	idx.TermFreq[uri] = freq
+	idx.Positions[uri] = positions
 
 	idx.TotalDocs++
 }
 
-// tokenize splits text into terms.
-func tokenize(text string) []string {
-	// Convert to lowercase
-	text = strings.ToLower(text)
-
-	// Remove special characters and split
-	reg := regexp.MustCompile(`[a-z0-9]+`)
-	matches := reg.FindAllString(text, -1)
+// Matches reports whether the metadata of the document at uri holds every
+// key of filter with the same value. An empty filter matches every
+// document.
+func (idx *Index) Matches(uri string, filter map[string]string) bool {
+	metadata := idx.Metadata[uri]
+	for key, value := range filter {
+		if v, ok := metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
 
-	return matches
+// tokenize splits text into raw lowercase terms. CJK runs are kept whole.
+func tokenize(text string) []string {
+	return splitTerms(text, false)
 }
 
-// BuildIDF builds IDF scores for all terms.
+// BuildIDF builds document frequencies and Lucene-style IDF scores for
+// all terms.
 func (idx *Index) BuildIDF() {
 	// Count document frequency for each term
 	docFreq := make(map[string]int)
@@ -95,6 +183,7 @@ func (idx *Index) BuildIDF() {
 			docFreq[term]++
 		}
 	}
+	idx.DocFreq = docFreq
 
 	// Calculate IDF for each term
 	N := float64(idx.TotalDocs)
@@ -113,32 +202,49 @@ func (idx *Index) BuildIDF() {
 	}
 }
 
-// Score calculates BM25 score for a query against a document.
+// Score calculates BM25 score for a query against a document. Quoted
+// phrases in the query must occur in the document, which otherwise scores
+// 0; see parseKeywordQuery.
 func (ks *KeywordSearch) Score(query string, idx *Index, uri string) float64 {
-	terms := tokenize(query)
-	docFreq := idx.TermFreq[uri]
-	docLen := idx.DocLengths[uri]
+	q := idx.parseKeywordQuery(query)
 
 	var score float64
-	for _, term := range terms {
-		tf := float64(docFreq[term])
-		if tf == 0 {
-			continue
+	for _, term := range q.terms {
+		score += ks.termScore(idx, uri, term, idx.TermFreq[uri][term])
+	}
+	for _, phrase := range q.phrases {
+		// A phrase scores its terms as if each occurred once per phrase
+		// match
+		matches := idx.phraseMatches(uri, phrase)
+		if matches == 0 {
+			return 0
+		}
+		for _, term := range phrase.terms {
+			score += ks.termScore(idx, uri, term, matches)
 		}
+	}
 
-		idf := idx.IDF[term]
+	return score
+}
 
-		// BM25 scoring formula
-		numerator := tf * (ks.k1 + 1)
-		denominator := tf + ks.k1*(1 - ks.b + ks.b*float64(docLen)/idx.AvgDocLength)
-		score += idf * numerator / denominator
+// termScore returns the BM25 score of term occurring freq times in the
+// document at uri.
+func (ks *KeywordSearch) termScore(idx *Index, uri, term string, freq int) float64 {
+	if freq == 0 {
+		return 0
 	}
+	tf := float64(freq)
+	idf := ks.idf(idx, term)
 
-	return score
+	// BM25 scoring formula
+	numerator := tf * (ks.k1 + 1)
+	denominator := tf + ks.k1*(1-ks.b+ks.b*float64(idx.DocLengths[uri])/idx.AvgDocLength)
+	return idf * numerator / denominator
 }
 
-// Search performs keyword search.
-func (ks *KeywordSearch) Search(ctx context.Context, query string, idx *Index, limit int) []SearchResult {
+// Search performs keyword search over the documents whose metadata
+// matches filter; a nil filter searches every document.
+func (ks *KeywordSearch) Search(ctx context.Context, query string, idx *Index, limit int, filter map[string]string) []SearchResult {
 	if idx.TotalDocs == 0 {
 		return []SearchResult{}
 	}
@@ -146,11 +252,16 @@ func (ks *KeywordSearch) Search(ctx context.Context, query string, idx *Index, l
 	var results []SearchResult
 
 	for uri := range idx.Documents {
+		if !idx.Matches(uri, filter) {
+			continue
+		}
 		score := ks.Score(query, idx, uri)
 		if score > 0 {
 			results = append(results, SearchResult{
-				URI:    uri,
-				Score:  score,
+				URI:         uri,
+				Score:       score,
+				RawScore:    score,
+				HasRawScore: true,
 			})
 		}
 	}
@@ -167,12 +278,56 @@ func (ks *KeywordSearch) Search(ctx context.Context, query string, idx *Index, l
 	return results
 }
 
+// FusionMode selects how HybridSearch merges semantic and keyword results.
+type FusionMode string
+
+const (
+	// FusionRRF merges by Reciprocal Rank Fusion. Only ranks matter, and
+	// alpha is ignored.
+	FusionRRF FusionMode = "rrf"
+	// FusionWeighted scores each result as alpha times its semantic score
+	// plus 1-alpha times its keyword score, each scaled so the best match
+	// in its list scores 1.
+	FusionWeighted FusionMode = "weighted"
+)
+
+// IndexSource selects the text of a document indexed for keyword search.
+type IndexSource string
+
+const (
+	// IndexAbstract indexes only the abstract.
+	IndexAbstract IndexSource = "abstract"
+	// IndexContent indexes only the full content, falling back to the
+	// abstract for documents without content.
+	IndexContent IndexSource = "content"
+	// IndexBoth indexes the abstract and content together, counting each
+	// abstract term AbstractBoost times so abstract matches weigh more.
+	IndexBoth IndexSource = "both"
+)
+
+// DefaultAbstractBoost is how many times abstract terms count when
+// indexing IndexBoth.
+const DefaultAbstractBoost = 2
+
+// IndexSourceFor returns the named index source. Unknown names fall back
+// to IndexAbstract.
+func IndexSourceFor(name string) IndexSource {
+	switch IndexSource(name) {
+	case IndexContent, IndexBoth:
+		return IndexSource(name)
+	}
+	return IndexAbstract
+}
+
 // HybridSearch combines keyword and semantic search.
 type HybridSearch struct {
 	semanticSearch *SemanticSearch
 	keywordSearch *KeywordSearch
 	index         *Index
 	alpha         float64 // weight for semantic search (1-alpha for keyword)
+	fusionMode    FusionMode
+	indexSource   IndexSource
+	abstractBoost int
 }
 
 // NewHybridSearch creates a new HybridSearch.
@@ -182,38 +337,117 @@ func NewHybridSearch(semanticSearch *SemanticSearch, alpha float64) *HybridSearc
 		keywordSearch:  NewKeywordSearch(),
 		index:          NewIndex(),
 		alpha:          alpha,
+		fusionMode:     FusionRRF,
+		indexSource:    IndexAbstract,
+		abstractBoost:  DefaultAbstractBoost,
+	}
+}
+
+// SetFusionMode sets how semantic and keyword results are merged.
+// The default is FusionRRF.
+func (hs *HybridSearch) SetFusionMode(mode FusionMode) {
+	hs.fusionMode = mode
+}
+
+// SetTokenizer sets the tokenizer used for keyword indexing and querying.
+// It resets the keyword index, so call it before IndexDocuments.
+func (hs *HybridSearch) SetTokenizer(tokenizer *Tokenizer) {
+	hs.index = NewIndexWithTokenizer(tokenizer)
+}
+
+// SetIndexSource sets which text of a document IndexDocuments indexes,
+// and how many times abstract terms count for IndexBoth (at least 1). The
+// default is IndexAbstract. Call it before IndexDocuments.
+func (hs *HybridSearch) SetIndexSource(source IndexSource, abstractBoost int) {
+	if abstractBoost < 1 {
+		abstractBoost = 1
 	}
+	hs.indexSource = source
+	hs.abstractBoost = abstractBoost
 }
 
-// IndexDocuments indexes documents for keyword search.
+// IndexDocuments indexes documents for keyword search, along with their
+// metadata for filtering.
 func (hs *HybridSearch) IndexDocuments(ctx context.Context, documents []SearchResult) {
 	for _, doc := range documents {
-		hs.index.AddDocument(doc.URI, doc.Abstract)
+		hs.index.AddDocument(doc.URI, hs.indexText(doc), stringValues(doc.Metadata))
 	}
 	hs.index.BuildIDF()
 }
 
+// indexText returns the text of doc indexed under the index source.
+func (hs *HybridSearch) indexText(doc SearchResult) string {
+	return IndexText(hs.indexSource, hs.abstractBoost, doc.Abstract, doc.Content)
+}
+
+// IndexText returns the text of a document with the given abstract and
+// full content that is indexed for keyword search under source. For
+// IndexBoth, abstract terms count abstractBoost times (at least once).
+func IndexText(source IndexSource, abstractBoost int, abstract, content string) string {
+	switch source {
+	case IndexContent:
+		if content != "" {
+			return content
+		}
+	case IndexBoth:
+		if abstract == "" {
+			return content
+		}
+		if abstractBoost < 1 {
+			abstractBoost = 1
+		}
+		// Repeating the abstract multiplies its term frequencies
+		return strings.Repeat(abstract+"\n", abstractBoost) + content
+	}
+	return abstract
+}
+
+// stringValues returns m with each value formatted as a string, so that
+// metadata and filters compare alike whatever their value types. It
+// returns nil for an empty map.
+func stringValues(m map[string]interface{}) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(m))
+	for k, v := range m {
+		values[k] = fmt.Sprint(v)
+	}
+	return values
+}
+
 // Search performs hybrid search combining semantic and keyword search.
+// filter restricts both: the semantic leg passes it to the vector store,
+// and the keyword leg keeps only documents whose indexed metadata holds
+// each of its keys with the same value.
 func (hs *HybridSearch) Search(ctx context.Context, query string, limit int, filter map[string]interface{}) ([]SearchResult, error) {
 	var semanticResults []SearchResult
 	var keywordResults []SearchResult
 	var err error
 
-	// Run semantic search
+	// Run semantic search. If embedding fails, for instance because the
+	// embedding provider is down, degrade to keyword search alone.
 	if hs.semanticSearch != nil {
 		semanticResults, err = hs.semanticSearch.Search(ctx, query, limit*2, filter)
 		if err != nil {
-			return nil, err
+			if hs.index.TotalDocs == 0 || ctx.Err() != nil {
+				return nil, err
+			}
+			semanticResults = nil
 		}
 	}
 
 	// Run keyword search
 	if hs.index.TotalDocs > 0 {
-		keywordResults = hs.keywordSearch.Search(ctx, query, hs.index, limit*2)
+		keywordResults = hs.keywordSearch.Search(ctx, query, hs.index, limit*2, stringValues(filter))
 	}
 
-	// Merge results using RRF (Reciprocal Rank Fusion)
-	combined := hs.rrfMerge(semanticResults, keywordResults, limit)
+	var combined []SearchResult
+	if hs.fusionMode == FusionWeighted {
+		combined = hs.weightedMerge(semanticResults, keywordResults, limit)
+	} else {
+		combined = hs.rrfMerge(semanticResults, keywordResults, limit)
+	}
 
 	// Normalize scores
 	hs.normalizeScores(combined)
@@ -222,33 +456,94 @@ func (hs *HybridSearch) Search(ctx context.Context, query string, limit int, fil
 }
 
 // rrfMerge merges results using Reciprocal Rank Fusion.
+// Each merged result keeps the raw score of its first source, preferring
+// the semantic result when a URI appears in both lists.
 func (hs *HybridSearch) rrfMerge(semanticResults, keywordResults []SearchResult, limit int) []SearchResult {
 	scores := make(map[string]float64)
+	sources := make(map[string]SearchResult)
 	k := 60 // RRF parameter
 
 	// Add semantic scores
 	kFloat := float64(k)
 	for rank, result := range semanticResults {
 		scores[result.URI] += 1.0 / (float64(rank) + kFloat)
+		if _, ok := sources[result.URI]; !ok {
+			sources[result.URI] = result
+		}
 	}
 
 	// Add keyword scores
 	for rank, result := range keywordResults {
 		scores[result.URI] += 1.0 / (float64(rank) + kFloat)
+		if _, ok := sources[result.URI]; !ok {
+			sources[result.URI] = result
+		}
 	}
 
 	// Convert to results
 	var results []SearchResult
 	for uri, score := range scores {
-		results = append(results, SearchResult{
-			URI:   uri,
-			Score: score,
-		})
+		result := sources[uri]
+		keepRawScore(&result)
+		result.Score = score
+		results = append(results, result)
 	}
 
-	// Sort by combined score
+	// Sort by combined score, breaking ties by URI
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].URI < results[j].URI
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// weightedMerge merges results by a weighted sum of their semantic and
+// keyword scores, each divided by the best score in its list. A result
+// missing from one list scores 0 there. Like rrfMerge, each merged result
+// keeps the raw score of its first source.
+func (hs *HybridSearch) weightedMerge(semanticResults, keywordResults []SearchResult, limit int) []SearchResult {
+	scores := make(map[string]float64)
+	sources := make(map[string]SearchResult)
+
+	add := func(results []SearchResult, weight float64) {
+		maxScore := 0.0
+		for _, result := range results {
+			maxScore = math.Max(maxScore, result.Score)
+		}
+		for _, result := range results {
+			normalized := 0.0
+			if maxScore > 0 {
+				normalized = result.Score / maxScore
+			}
+			scores[result.URI] += weight * normalized
+			if _, ok := sources[result.URI]; !ok {
+				sources[result.URI] = result
+			}
+		}
+	}
+	add(semanticResults, hs.alpha)
+	add(keywordResults, 1-hs.alpha)
+
+	var results []SearchResult
+	for uri, score := range scores {
+		result := sources[uri]
+		keepRawScore(&result)
+		result.Score = score
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].URI < results[j].URI
 	})
 
 	if limit > 0 && len(results) > limit {
@@ -270,6 +565,7 @@ func (hs *HybridSearch) normalizeScores(results []SearchResult) {
 	}
 
 	for i := range results {
+		keepRawScore(&results[i])
 		results[i].Score = results[i].Score / maxScore
 	}
 }
@@ -329,6 +625,7 @@ func (hr *HybridRetriever) Retrieve(ctx context.Context, query string, sessionID
 
 	// Combine scores
 	for i := range results {
+		keepRawScore(&results[i])
 		results[i].Score = hr.CombineScores(results[i].Score, hotnessScore)
 	}
 
@@ -346,41 +643,135 @@ func (hr *HybridRetriever) Retrieve(ctx context.Context, query string, sessionID
 
 // Reranker re-ranks search results using cross-encoder.
 type Reranker struct {
-	client  interface{} // LLM provider for cross-encoder scoring
-	enabled bool
+	client    llm.Provider // LLM provider for cross-encoder scoring
+	enabled   bool
+	batchSize int
 }
 
-// NewReranker creates a new Reranker.
-func NewReranker(enabled bool) *Reranker {
+// defaultRerankBatchSize is how many candidates are scored per LLM call.
+const defaultRerankBatchSize = 10
+
+// NewReranker creates a new Reranker. With a nil client, results are
+// re-scored by query term overlap instead.
+func NewReranker(client llm.Provider, enabled bool) *Reranker {
 	return &Reranker{
-		enabled: enabled,
+		client:    client,
+		enabled:   enabled,
+		batchSize: defaultRerankBatchSize,
+	}
+}
+
+// SetBatchSize sets how many candidates are scored per LLM call.
+func (r *Reranker) SetBatchSize(n int) {
+	if n > 0 {
+		r.batchSize = n
 	}
 }
 
-// Rerank re-ranks search results.
+// Rerank re-ranks search results, blending each result's score equally
+// with its relevance to the query. Relevance comes from the LLM, scoring
+// candidates in batches; a batch the LLM fails to score falls back to
+// query term overlap.
 func (r *Reranker) Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
 	if !r.enabled || len(results) == 0 {
 		return results, nil
 	}
 
-	// Simple cross-encoder reranking: re-score each result with the query
-	// In a real implementation, this would use a cross-encoder model
 	var reranked []SearchResult
-	for _, result := range results {
-		// Calculate relevance score (simplified)
-		relevance := r.calculateRelevance(query, result)
-		result.Score = result.Score * 0.5 + relevance * 0.5
-		reranked = append(reranked, result)
+	for start := 0; start < len(results); start += r.batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + r.batchSize
+		if end > len(results) {
+			end = len(results)
+		}
+		batch := results[start:end]
+
+		relevance, err := r.crossEncode(ctx, query, batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			relevance = make([]float64, len(batch))
+			for i, result := range batch {
+				relevance[i] = r.calculateRelevance(query, result)
+			}
+		}
+
+		for i, result := range batch {
+			keepRawScore(&result)
+			result.Score = result.Score * 0.5 + relevance[i] * 0.5
+			reranked = append(reranked, result)
+		}
 	}
 
 	// Sort by new scores
-	sort.Slice(reranked, func(i, j int) bool {
+	sort.SliceStable(reranked, func(i, j int) bool {
 		return reranked[i].Score > reranked[j].Score
 	})
 
 	return reranked, nil
 }
 
+const rerankPrompt = `Rate how relevant each candidate is to the query, from 0 (unrelated) to 1 (answers it directly).
+Return only a JSON array of %d numbers, one per candidate, in order.
+
+Query: %s
+
+Candidates:
+%s`
+
+// crossEncode asks the LLM to score each (query, abstract) pair in batch.
+func (r *Reranker) crossEncode(ctx context.Context, query string, batch []SearchResult) ([]float64, error) {
+	if r.client == nil {
+		return nil, fmt.Errorf("no reranking model")
+	}
+
+	var candidates strings.Builder
+	for i, result := range batch {
+		fmt.Fprintf(&candidates, "[%d] %s\n", i+1, result.Abstract)
+	}
+
+	resp, err := r.client.Chat(ctx, &llm.ChatRequest{
+		Temperature: 0,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are a relevance judge. Reply with JSON only."},
+			{Role: llm.RoleUser, Content: fmt.Sprintf(rerankPrompt, len(batch), query, candidates.String())},
+		},
+		MaxTokens: 20 * len(batch),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty reranking response")
+	}
+	return parseRelevanceScores(resp.Choices[0].Message.Content, len(batch))
+}
+
+// parseRelevanceScores reads n scores from a JSON array in an LLM reply,
+// clamping each to [0, 1].
+func parseRelevanceScores(reply string, n int) ([]float64, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON array in reranking response")
+	}
+
+	var scores []float64
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse relevance scores: %w", err)
+	}
+	if len(scores) != n {
+		return nil, fmt.Errorf("expected %d relevance scores, got %d", n, len(scores))
+	}
+	for i, score := range scores {
+		scores[i] = math.Max(0, math.Min(1, score))
+	}
+	return scores, nil
+}
+
 // calculateRelevance calculates relevance between query and result.
 func (r *Reranker) calculateRelevance(query string, result SearchResult) float64 {
 	// Simple relevance: count query terms in result content
@@ -0,0 +1,453 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// fixedEmbedder embeds every text as the same dense vector.
+type fixedEmbedder struct {
+	vector []float64
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, text string) (*EmbedResult, error) {
+	return &EmbedResult{DenseVector: e.vector}, nil
+}
+
+func (e *fixedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*EmbedResult, error) {
+	results := make([]*EmbedResult, len(texts))
+	for i := range texts {
+		results[i], _ = e.Embed(ctx, texts[i])
+	}
+	return results, nil
+}
+
+func (e *fixedEmbedder) GetDimension() int { return len(e.vector) }
+func (e *fixedEmbedder) Close() error      { return nil }
+
+func newTestSemanticSearch() *SemanticSearch {
+	store := NewInMemoryVectorStore(2)
+	store.AddVector("viking://resources/a", []float64{1, 0}, nil)
+	store.AddVector("viking://resources/b", []float64{0.6, 0.8}, nil)
+	return NewSemanticSearch(&fixedEmbedder{vector: []float64{1, 0}}, store)
+}
+
+func TestHybridSearchKeepsRawScore(t *testing.T) {
+	hs := NewHybridSearch(newTestSemanticSearch(), 0.5)
+	hs.IndexDocuments(context.Background(), []SearchResult{
+		{URI: "viking://resources/a", Abstract: "golang concurrency patterns"},
+		{URI: "viking://resources/c", Abstract: "golang testing guide"},
+		{URI: "viking://resources/d", Abstract: "python packaging"},
+	})
+
+	results, err := hs.Search(context.Background(), "testing", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	raw := map[string]float64{
+		"viking://resources/a": 1.0,
+		"viking://resources/b": 0.6,
+		"viking://resources/c": hs.keywordSearch.Score("testing", hs.index, "viking://resources/c"),
+	}
+	if len(results) != len(raw) {
+		t.Fatalf("Expected %d results, got %d", len(raw), len(results))
+	}
+	if results[0].Score != 1.0 {
+		t.Errorf("Expected normalized top score 1.0, got %f", results[0].Score)
+	}
+	for _, r := range results {
+		want, ok := raw[r.URI]
+		if !ok {
+			t.Errorf("Unexpected result %s", r.URI)
+			continue
+		}
+		if diff := r.RawScore - want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected raw score %f for %s, got %f", want, r.URI, r.RawScore)
+		}
+	}
+}
+
+func TestHybridRetrieverKeepsRawScore(t *testing.T) {
+	hr := NewHybridRetriever(newTestSemanticSearch(), NewHotnessScorer(DefaultHotnessConfig()), 0.5)
+
+	results, err := hr.Retrieve(context.Background(), "query", "sess", 10, time.Now(), 10)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	for _, r := range results {
+		want := 1.0
+		if r.URI == "viking://resources/b" {
+			want = 0.6
+		}
+		if diff := r.RawScore - want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected raw score %f for %s, got %f", want, r.URI, r.RawScore)
+		}
+		if r.Score == r.RawScore {
+			t.Errorf("Expected combined score to differ from raw score for %s", r.URI)
+		}
+	}
+}
+
+func TestRerankerKeepsRawScore(t *testing.T) {
+	reranker := NewReranker(nil, true)
+	results := []SearchResult{
+		{URI: "viking://resources/a", Score: 0.4, Abstract: "unrelated text"},
+		{URI: "viking://resources/b", Score: 0.2, RawScore: 7.5, HasRawScore: true, Abstract: "rerank me"},
+		{URI: "viking://resources/c", Score: 0.1, RawScore: 0, HasRawScore: true, Abstract: "rerank"},
+	}
+
+	reranked, err := reranker.Rerank(context.Background(), "rerank me", results)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if reranked[0].URI != "viking://resources/b" {
+		t.Errorf("Expected viking://resources/b to rank first, got %s", reranked[0].URI)
+	}
+	// An earlier raw score survives; otherwise the pre-rerank score is kept.
+	if reranked[0].RawScore != 7.5 {
+		t.Errorf("Expected raw score 7.5, got %f", reranked[0].RawScore)
+	}
+	for _, r := range reranked {
+		if r.URI == "viking://resources/a" && r.RawScore != 0.4 {
+			t.Errorf("Expected raw score 0.4, got %f", r.RawScore)
+		}
+		// A raw score of 0 is a captured score, not a missing one
+		if r.URI == "viking://resources/c" && r.RawScore != 0 {
+			t.Errorf("Expected raw score 0 kept, got %f", r.RawScore)
+		}
+	}
+}
+
+// scoreProvider answers each reranking call with the next reply.
+type scoreProvider struct {
+	llm.Provider
+	replies []string
+	calls   int
+}
+
+func (p *scoreProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	if p.calls >= len(p.replies) {
+		return nil, errors.New("unexpected call")
+	}
+	reply := p.replies[p.calls]
+	p.calls++
+	if reply == "" {
+		return nil, errors.New("model unavailable")
+	}
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Content: reply}}}}, nil
+}
+
+func TestRerankerCrossEncoder(t *testing.T) {
+	provider := &scoreProvider{replies: []string{"```json\n[0.1, 0.9]\n```", "[1.5]"}}
+	reranker := NewReranker(provider, true)
+	reranker.SetBatchSize(2)
+	results := []SearchResult{
+		{URI: "viking://resources/a", Score: 0.8, Abstract: "goroutines"},
+		{URI: "viking://resources/b", Score: 0.6, Abstract: "channels"},
+		{URI: "viking://resources/c", Score: 0.1, Abstract: "mutexes"},
+	}
+
+	reranked, err := reranker.Rerank(context.Background(), "sync", results)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("Expected 2 batched calls, got %d", provider.calls)
+	}
+	// b: 0.3+0.45, c: 0.05+0.5 (clamped to 1), a: 0.4+0.05
+	want := []string{"viking://resources/b", "viking://resources/c", "viking://resources/a"}
+	for i, uri := range want {
+		if reranked[i].URI != uri {
+			t.Fatalf("Expected order %v, got %v", want, reranked)
+		}
+	}
+	if math.Abs(reranked[0].Score-0.75) > 1e-9 {
+		t.Errorf("Expected blended score 0.75, got %f", reranked[0].Score)
+	}
+}
+
+func TestRerankerFallsBackToOverlap(t *testing.T) {
+	// The first batch errors and the second returns too few scores
+	provider := &scoreProvider{replies: []string{"", "[0.5, 0.5]"}}
+	reranker := NewReranker(provider, true)
+	reranker.SetBatchSize(1)
+	results := []SearchResult{
+		{URI: "viking://resources/a", Score: 0.5, Abstract: "unrelated text"},
+		{URI: "viking://resources/b", Score: 0.5, Abstract: "rerank me"},
+	}
+
+	reranked, err := reranker.Rerank(context.Background(), "rerank me", results)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if reranked[0].URI != "viking://resources/b" || reranked[0].Score != 0.75 {
+		t.Errorf("Expected the overlapping result first, got %v", reranked)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := reranker.Rerank(ctx, "rerank me", results); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// downEmbedder fails every call, like an unreachable embedding provider.
+type downEmbedder struct{}
+
+func (downEmbedder) Embed(ctx context.Context, text string) (*EmbedResult, error) {
+	return nil, errors.New("circuit breaker open")
+}
+
+func (downEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*EmbedResult, error) {
+	return nil, errors.New("circuit breaker open")
+}
+
+func (downEmbedder) GetDimension() int { return 2 }
+func (downEmbedder) Close() error      { return nil }
+
+func TestHybridSearchDegradesToKeyword(t *testing.T) {
+	hs := NewHybridSearch(NewSemanticSearch(downEmbedder{}, NewInMemoryVectorStore(2)), 0.5)
+
+	// Without a keyword index there is nothing to fall back to
+	if _, err := hs.Search(context.Background(), "testing", 10, nil); err == nil {
+		t.Error("Expected the embedding error without a keyword index")
+	}
+
+	hs.IndexDocuments(context.Background(), []SearchResult{
+		{URI: "viking://resources/a", Abstract: "golang concurrency patterns"},
+		{URI: "viking://resources/c", Abstract: "golang testing guide"},
+	})
+	results, err := hs.Search(context.Background(), "testing", 10, nil)
+	if err != nil {
+		t.Fatalf("Expected keyword fallback, got %v", err)
+	}
+	if len(results) != 1 || results[0].URI != "viking://resources/c" {
+		t.Errorf("Expected the keyword match only, got %v", results)
+	}
+}
+
+func TestNoopEmbedder(t *testing.T) {
+	e := NewNoopEmbedder(0)
+	if e.GetDimension() != DefaultNoopDimension {
+		t.Errorf("Expected the default dimension %d, got %d", DefaultNoopDimension, e.GetDimension())
+	}
+
+	e = NewNoopEmbedder(4)
+	result, err := e.Embed(context.Background(), "anything")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(result.DenseVector) != 4 || CosineSimilarity(result.DenseVector, []float64{1, 0, 0, 0}) != 0 {
+		t.Errorf("Expected a zero vector of dimension 4, got %v", result.DenseVector)
+	}
+	batch, err := e.EmbedBatch(context.Background(), []string{"a", "b"})
+	if err != nil || len(batch) != 2 || len(batch[1].DenseVector) != 4 {
+		t.Errorf("Expected 2 zero vectors, got %v (%v)", batch, err)
+	}
+
+	if !IsNoopEmbedder(nil) || !IsNoopEmbedder(e) || IsNoopEmbedder(&fixedEmbedder{vector: []float64{1}}) {
+		t.Error("Expected only nil and NoopEmbedder to count as no-op")
+	}
+}
+
+func TestHybridSearchNoopEmbedderUsesKeywords(t *testing.T) {
+	store := NewInMemoryVectorStore(2)
+	store.AddVector("viking://resources/a", []float64{1, 0}, nil)
+	hs := NewHybridSearch(NewSemanticSearch(NewNoopEmbedder(2), store), 0.5)
+	hs.IndexDocuments(context.Background(), []SearchResult{
+		{URI: "viking://resources/b", Abstract: "golang concurrency patterns"},
+		{URI: "viking://resources/c", Abstract: "golang testing guide"},
+	})
+
+	results, err := hs.Search(context.Background(), "testing", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].URI != "viking://resources/c" {
+		t.Errorf("Expected the keyword match only, got %v", results)
+	}
+}
+
+// filterRecordingStore finds nothing and records the filter it was given.
+type filterRecordingStore struct {
+	VectorStore
+	filter map[string]interface{}
+}
+
+func (s *filterRecordingStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	s.filter = filter
+	return nil, nil
+}
+
+func TestKeywordSearchFilter(t *testing.T) {
+	idx := NewIndex()
+	idx.AddDocument("viking://resources/guide", "golang style guide", map[string]string{"type": "doc"})
+	idx.AddDocument("viking://resources/server", "golang http server", map[string]string{"type": "code", "lang": "go"})
+	idx.AddDocument("viking://resources/client", "golang http client", map[string]string{"type": "code", "lang": "go"})
+	idx.AddDocument("viking://resources/notes", "golang notes", nil)
+	idx.BuildIDF()
+	ks := NewKeywordSearch()
+
+	tests := []struct {
+		filter map[string]string
+		want   []string
+	}{
+		{nil, []string{"viking://resources/client", "viking://resources/guide", "viking://resources/notes", "viking://resources/server"}},
+		{map[string]string{"type": "code"}, []string{"viking://resources/client", "viking://resources/server"}},
+		{map[string]string{"type": "doc"}, []string{"viking://resources/guide"}},
+		{map[string]string{"type": "code", "lang": "rust"}, nil},
+		{map[string]string{"missing": ""}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range ks.Search(context.Background(), "golang", idx, 0, tt.filter) {
+			got = append(got, r.URI)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Filter %v: Expected %v, got %v", tt.filter, tt.want, got)
+		}
+	}
+}
+
+func TestHybridSearchFilter(t *testing.T) {
+	store := &filterRecordingStore{}
+	hs := NewHybridSearch(NewSemanticSearch(&fixedEmbedder{vector: []float64{1, 0}}, store), 0.5)
+	hs.IndexDocuments(context.Background(), []SearchResult{
+		{URI: "viking://resources/guide", Abstract: "testing guide", Metadata: map[string]interface{}{"type": "doc", "priority": 1}},
+		{URI: "viking://resources/suite", Abstract: "testing suite", Metadata: map[string]interface{}{"type": "code", "priority": 1}},
+		{URI: "viking://resources/bench", Abstract: "testing benchmarks", Metadata: map[string]interface{}{"type": "code", "priority": 2}},
+	})
+
+	filter := map[string]interface{}{"type": "code", "priority": 1}
+	results, err := hs.Search(context.Background(), "testing", 10, filter)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].URI != "viking://resources/suite" {
+		t.Errorf("Expected only the matching code document, got %v", results)
+	}
+	if !reflect.DeepEqual(store.filter, filter) {
+		t.Errorf("Expected the semantic leg to get the filter, got %v", store.filter)
+	}
+}
+
+// newIDFTestIndex indexes five documents that all mention golang, one of
+// which also mentions channels.
+func newIDFTestIndex() *Index {
+	idx := NewIndex()
+	idx.AddDocument("viking://resources/a", "golang channels", nil)
+	idx.AddDocument("viking://resources/b", "golang maps", nil)
+	idx.AddDocument("viking://resources/c", "golang slices", nil)
+	idx.AddDocument("viking://resources/d", "golang interfaces", nil)
+	idx.AddDocument("viking://resources/e", "golang generics", nil)
+	idx.BuildIDF()
+	return idx
+}
+
+func TestKeywordSearchIDFVariants(t *testing.T) {
+	idx := newIDFTestIndex()
+
+	tests := []struct {
+		variant IDFVariant
+		floor   bool
+		check   func(common float64) bool
+	}{
+		{IDFLucene, false, func(common float64) bool { return common > 0 && common < 0.1 }},
+		{IDFClassic, false, func(common float64) bool { return common < 0 }},
+		{IDFClassic, true, func(common float64) bool { return common == 0 }},
+	}
+	for _, tt := range tests {
+		ks := NewKeywordSearch()
+		ks.SetIDFVariant(tt.variant)
+		ks.SetIDFFloor(tt.floor)
+
+		common := ks.Score("golang", idx, "viking://resources/a")
+		if !tt.check(common) {
+			t.Errorf("%s (floor %v): unexpected score %f for a term in every document", tt.variant, tt.floor, common)
+		}
+		rare := ks.Score("channels", idx, "viking://resources/a")
+		if common >= 0 && (rare < 1 || rare < 10*common) {
+			t.Errorf("%s (floor %v): expected the rare term to dominate, got %f vs %f", tt.variant, tt.floor, rare, common)
+		}
+	}
+}
+
+func TestKeywordSearchIDFFloorRanking(t *testing.T) {
+	idx := newIDFTestIndex()
+	ks := NewKeywordSearch()
+	ks.SetIDFVariant(IDFClassic)
+
+	// Unclamped, the common term's negative IDF outweighs the rare match
+	if results := ks.Search(context.Background(), "golang channels", idx, 0, nil); len(results) != 0 {
+		t.Errorf("Expected the negative IDF to hide every match, got %v", results)
+	}
+
+	// Clamped, the common term neither adds to nor subtracts from a score
+	ks.SetIDFFloor(true)
+	results := ks.Search(context.Background(), "golang channels", idx, 0, nil)
+	if len(results) != 1 || results[0].URI != "viking://resources/a" {
+		t.Errorf("Expected only the rare match, got %v", results)
+	}
+	floored := ks.Score("golang channels", idx, "viking://resources/a")
+	if rare := ks.Score("channels", idx, "viking://resources/a"); floored != rare {
+		t.Errorf("Expected the common term to contribute nothing, got %f vs %f", floored, rare)
+	}
+}
+
+// contentDocs has a term, mutex, in one document's abstract and in another
+// document's content only.
+var contentDocs = []SearchResult{
+	{URI: "viking://resources/sync", Abstract: "golang sync primitives", Content: "Use a mutex to guard shared maps and a WaitGroup to join goroutines."},
+	{URI: "viking://resources/locks", Abstract: "mutex locking guide", Content: "Locks serialize access."},
+	{URI: "viking://resources/io", Abstract: "golang file io", Content: "Read files with os.ReadFile."},
+}
+
+func keywordURIs(t *testing.T, source IndexSource) []string {
+	t.Helper()
+	hs := NewHybridSearch(nil, 0)
+	hs.SetIndexSource(source, DefaultAbstractBoost)
+	hs.IndexDocuments(context.Background(), contentDocs)
+
+	results, err := hs.Search(context.Background(), "mutex", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	uris := make([]string, len(results))
+	for i, r := range results {
+		uris[i] = r.URI
+	}
+	return uris
+}
+
+func TestHybridSearchIndexSource(t *testing.T) {
+	if got := keywordURIs(t, IndexAbstract); len(got) != 1 || got[0] != "viking://resources/locks" {
+		t.Errorf("Expected abstract indexing to miss the content-only match, got %v", got)
+	}
+	if got := keywordURIs(t, IndexContent); len(got) != 1 || got[0] != "viking://resources/sync" {
+		t.Errorf("Expected content indexing to find only the content match, got %v", got)
+	}
+	// Both finds both, the boosted abstract match first
+	if got := keywordURIs(t, IndexBoth); len(got) != 2 || got[0] != "viking://resources/locks" || got[1] != "viking://resources/sync" {
+		t.Errorf("Expected the abstract match then the content match, got %v", got)
+	}
+}
+
+func TestIndexSourceFor(t *testing.T) {
+	for name, want := range map[string]IndexSource{"content": IndexContent, "both": IndexBoth, "abstract": IndexAbstract, "": IndexAbstract, "title": IndexAbstract} {
+		if got := IndexSourceFor(name); got != want {
+			t.Errorf("IndexSourceFor(%q) = %q, expected %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// KeywordExtractor extracts descriptive keywords from content.
+// Implementations may be statistical (TF-IDF) or backed by an LLM.
+type KeywordExtractor interface {
+	// Extract returns at most maxTags keywords ordered by relevance.
+	Extract(ctx context.Context, content string, maxTags int) ([]string, error)
+}
+
+// stopwords contains common English words that never make useful tags.
+var stopwords = map[string]bool{
+	"a": true, "about": true, "above": true, "after": true, "again": true, "all": true,
+	"also": true, "am": true, "an": true, "and": true, "any": true, "are": true,
+	"as": true, "at": true, "be": true, "because": true, "been": true, "before": true,
+	"being": true, "below": true, "between": true, "both": true, "but": true, "by": true,
+	"can": true, "could": true, "did": true, "do": true, "does": true, "doing": true,
+	"down": true, "during": true, "each": true, "few": true, "for": true, "from": true,
+	"further": true, "had": true, "has": true, "have": true, "having": true, "he": true,
+	"her": true, "here": true, "hers": true, "him": true, "his": true, "how": true,
+	"i": true, "if": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "itself": true, "just": true, "may": true, "me": true, "more": true,
+	"most": true, "must": true, "my": true, "no": true, "nor": true, "not": true,
+	"now": true, "of": true, "off": true, "on": true, "once": true, "only": true,
+	"or": true, "other": true, "our": true, "ours": true, "out": true, "over": true,
+	"own": true, "same": true, "she": true, "should": true, "so": true, "some": true,
+	"such": true, "than": true, "that": true, "the": true, "their": true, "theirs": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true, "this": true,
+	"those": true, "through": true, "to": true, "too": true, "under": true, "until": true,
+	"up": true, "use": true, "used": true, "uses": true, "using": true, "very": true,
+	"was": true, "we": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "while": true, "who": true, "whom": true, "why": true, "will": true,
+	"with": true, "would": true, "you": true, "your": true, "yours": true,
+}
+
+// TFIDFExtractor extracts keywords by weighting term frequency with
+// inverse document frequency taken from a keyword Index.
+type TFIDFExtractor struct {
+	index      *Index
+	minTermLen int
+}
+
+// NewTFIDFExtractor creates a new TFIDFExtractor. If idx is nil, or has no
+// documents, terms are ranked by frequency alone.
+func NewTFIDFExtractor(idx *Index) *TFIDFExtractor {
+	return &TFIDFExtractor{
+		index:      idx,
+		minTermLen: 3,
+	}
+}
+
+// Extract implements KeywordExtractor.
+func (e *TFIDFExtractor) Extract(ctx context.Context, content string, maxTags int) ([]string, error) {
+	if maxTags <= 0 {
+		return []string{}, nil
+	}
+
+	freq := make(map[string]int)
+	firstPos := make(map[string]int)
+	for pos, term := range tokenize(content) {
+		if !e.isCandidate(term) {
+			continue
+		}
+		if _, seen := firstPos[term]; !seen {
+			firstPos[term] = pos
+		}
+		freq[term]++
+	}
+
+	type scoredTerm struct {
+		term  string
+		score float64
+	}
+	scored := make([]scoredTerm, 0, len(freq))
+	for term, tf := range freq {
+		scored = append(scored, scoredTerm{term: term, score: float64(tf) * e.idf(term)})
+	}
+
+	// Sort by score descending, breaking ties by first occurrence
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return firstPos[scored[i].term] < firstPos[scored[j].term]
+	})
+
+	if len(scored) > maxTags {
+		scored = scored[:maxTags]
+	}
+
+	keywords := make([]string, len(scored))
+	for i, s := range scored {
+		keywords[i] = s.term
+	}
+	return keywords, nil
+}
+
+// isCandidate reports whether a token may become a keyword.
+func (e *TFIDFExtractor) isCandidate(term string) bool {
+	if len(term) < e.minTermLen || stopwords[term] {
+		return false
+	}
+	for _, r := range term {
+		if r < '0' || r > '9' {
+			return true
+		}
+	}
+	return false // purely numeric
+}
+
+// idf returns the IDF weight for a term. Terms unseen by the index get the
+// weight of a term appearing in no documents, since they are maximally rare.
+func (e *TFIDFExtractor) idf(term string) float64 {
+	if e.index == nil || e.index.TotalDocs == 0 {
+		return 1
+	}
+	// Look the term up in the form the index stored it
+	if terms := e.index.Tokenize(term); len(terms) == 1 {
+		term = terms[0]
+	}
+	if idf, ok := e.index.IDF[term]; ok {
+		return idf
+	}
+	n := float64(e.index.TotalDocs)
+	return math.Log((n+0.5)/0.5 + 1)
+}
+
+// ExtractKeywords extracts up to maxTags keywords from content using term
+// frequency with stopword filtering.
+func ExtractKeywords(content string, maxTags int) []string {
+	keywords, _ := NewTFIDFExtractor(nil).Extract(context.Background(), content, maxTags)
+	return keywords
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"testing"
+)
+
+const sampleDocument = `Kubernetes schedules containers across a cluster of nodes.
+Each Kubernetes pod groups one or more containers that share networking.
+The scheduler places pods on nodes with enough resources, and the cluster
+autoscaler adds nodes when pods cannot be scheduled.`
+
+func TestExtractKeywords(t *testing.T) {
+	keywords := ExtractKeywords(sampleDocument, 3)
+	if len(keywords) != 3 {
+		t.Fatalf("Expected 3 keywords, got %d: %v", len(keywords), keywords)
+	}
+
+	want := map[string]bool{"kubernetes": true, "nodes": true, "containers": true, "cluster": true, "pods": true}
+	for _, kw := range keywords {
+		if !want[kw] {
+			t.Errorf("Unexpected keyword %q in %v", kw, keywords)
+		}
+		if stopwords[kw] {
+			t.Errorf("Stopword %q returned as keyword", kw)
+		}
+	}
+}
+
+func TestExtractKeywordsEmpty(t *testing.T) {
+	if keywords := ExtractKeywords("", 5); len(keywords) != 0 {
+		t.Errorf("Expected no keywords for empty content, got %v", keywords)
+	}
+	if keywords := ExtractKeywords(sampleDocument, 0); len(keywords) != 0 {
+		t.Errorf("Expected no keywords for maxTags 0, got %v", keywords)
+	}
+}
+
+func TestTFIDFExtractorUsesIndex(t *testing.T) {
+	idx := NewIndex()
+	idx.AddDocument("doc1", "database storage engine", nil)
+	idx.AddDocument("doc2", "database query planner", nil)
+	idx.AddDocument("doc3", "database replication", nil)
+	idx.BuildIDF()
+
+	// "database" is more frequent but common across the index, so the
+	// rarer "vectors" should outrank it.
+	extractor := NewTFIDFExtractor(idx)
+	keywords, err := extractor.Extract(context.Background(), "database database vectors", 1)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(keywords) != 1 || keywords[0] != "vectors" {
+		t.Errorf("Expected [vectors], got %v", keywords)
+	}
+}
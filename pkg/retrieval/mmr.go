@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+// defaultDiversityLambda weighs relevance and novelty equally.
+const defaultDiversityLambda = 0.5
+
+// MaximalMarginalRelevance greedily selects up to limit results, each time
+// picking the one with the best lambda*score - (1-lambda)*similarity, where
+// similarity is the highest cosine similarity between the result's vector
+// and those already selected. A lambda of 1 keeps the score order; lower
+// values favor results unlike the ones already picked. Results without a
+// vector are treated as unlike every other result. A limit of zero or less
+// orders every result.
+func MaximalMarginalRelevance(results []SearchResult, lambda float64, limit int) []SearchResult {
+	if limit <= 0 || limit > len(results) {
+		limit = len(results)
+	}
+
+	remaining := make([]SearchResult, len(results))
+	copy(remaining, results)
+	// maxSim[i] is remaining[i]'s highest similarity to a selected result
+	maxSim := make([]float64, len(remaining))
+
+	selected := make([]SearchResult, 0, limit)
+	for len(selected) < limit {
+		best := -1
+		var bestMMR float64
+		for i, r := range remaining {
+			mmr := lambda*r.Score - (1-lambda)*maxSim[i]
+			// Break ties by URI so the selection does not depend on input order
+			if best < 0 || mmr > bestMMR || (mmr == bestMMR && r.URI < remaining[best].URI) {
+				best, bestMMR = i, mmr
+			}
+		}
+
+		pick := remaining[best]
+		selected = append(selected, pick)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		maxSim = append(maxSim[:best], maxSim[best+1:]...)
+		if len(pick.Vector) == 0 {
+			continue
+		}
+		for i, r := range remaining {
+			if len(r.Vector) == 0 {
+				continue
+			}
+			if sim := CosineSimilarity(pick.Vector, r.Vector); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+
+	return selected
+}
+
+// diversify re-ranks retrieval candidates with MaximalMarginalRelevance.
+func diversify(candidates []RetrievalResult, lambda float64, limit int) []RetrievalResult {
+	results := make([]SearchResult, len(candidates))
+	byURI := make(map[string]RetrievalResult, len(candidates))
+	for i, c := range candidates {
+		results[i] = SearchResult{URI: c.URI, Score: c.Score, Vector: c.Vector}
+		byURI[c.URI] = c
+	}
+
+	selected := MaximalMarginalRelevance(results, lambda, limit)
+	diversified := make([]RetrievalResult, len(selected))
+	for i, r := range selected {
+		diversified[i] = byURI[r.URI]
+	}
+	return diversified
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"testing"
+)
+
+// redundantResults holds a best match, a near duplicate of it and a
+// lower-scored distinct result.
+func redundantResults() []SearchResult {
+	return []SearchResult{
+		{URI: "viking://resources/a", Score: 0.9, Vector: []float64{1, 0}, IsLeaf: true},
+		{URI: "viking://resources/a-copy", Score: 0.88, Vector: []float64{1, 0.01}, IsLeaf: true},
+		{URI: "viking://resources/b", Score: 0.5, Vector: []float64{0, 1}, IsLeaf: true},
+	}
+}
+
+func resultURIs(results []SearchResult) []string {
+	uris := make([]string, len(results))
+	for i, r := range results {
+		uris[i] = r.URI
+	}
+	return uris
+}
+
+func TestMaximalMarginalRelevance(t *testing.T) {
+	tests := []struct {
+		lambda float64
+		want   []string
+	}{
+		// Pure relevance keeps the score order
+		{1, []string{"viking://resources/a", "viking://resources/a-copy"}},
+		{0.5, []string{"viking://resources/a", "viking://resources/b"}},
+	}
+	for _, tt := range tests {
+		got := resultURIs(MaximalMarginalRelevance(redundantResults(), tt.lambda, 2))
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("lambda %v: expected %v, got %v", tt.lambda, tt.want, got)
+		}
+	}
+
+	// Without a limit every result is ordered, the duplicate last
+	got := resultURIs(MaximalMarginalRelevance(redundantResults(), 0.5, 0))
+	if len(got) != 3 || got[2] != "viking://resources/a-copy" {
+		t.Errorf("Expected the duplicate last, got %v", got)
+	}
+}
+
+// vectorStore is a VectorStore whose root directory holds fixed children.
+type vectorStore struct {
+	children []SearchResult
+}
+
+func (s *vectorStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if filter["parent_uri"] != "viking://resources" {
+		return nil, nil
+	}
+	return s.children, nil
+}
+
+func (s *vectorStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *vectorStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *vectorStore) Close() error                                          { return nil }
+
+func TestRetrieverDiversify(t *testing.T) {
+	hr := NewHierarchicalRetriever(nil, &vectorStore{children: redundantResults()}, DefaultRetrieverConfig())
+	opts := DefaultSearchOptions()
+	opts.Limit = 2
+	opts.TargetDirectories = []string{"viking://resources"}
+
+	retrieve := func() []string {
+		result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "guide"}, opts)
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		var uris []string
+		for _, mc := range result.MatchedContexts {
+			uris = append(uris, mc.URI)
+		}
+		return uris
+	}
+
+	if got := retrieve(); len(got) != 2 || got[1] != "viking://resources/a-copy" {
+		t.Errorf("Expected the duplicate without diversity, got %v", got)
+	}
+	opts.Diversify = true
+	if got := retrieve(); len(got) != 2 || got[0] != "viking://resources/a" || got[1] != "viking://resources/b" {
+		t.Errorf("Expected the distinct result with diversity, got %v", got)
+	}
+
+	// An explicit lambda of 0 ignores scores, leaving ties broken by URI
+	hr = NewHierarchicalRetriever(nil, &vectorStore{children: []SearchResult{
+		{URI: "viking://resources/z", Score: 0.9, Vector: []float64{1, 0}, IsLeaf: true},
+		{URI: "viking://resources/a", Score: 0.1, Vector: []float64{0, 1}, IsLeaf: true},
+	}}, DefaultRetrieverConfig())
+	opts.Limit = 1
+	if got := retrieve(); len(got) != 1 || got[0] != "viking://resources/z" {
+		t.Errorf("Expected the best match with the default lambda, got %v", got)
+	}
+	zero := 0.0
+	opts.DiversityLambda = &zero
+	if got := retrieve(); len(got) != 1 || got[0] != "viking://resources/a" {
+		t.Errorf("Expected novelty alone with lambda 0, got %v", got)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keywordQuery is a parsed keyword query: free terms, each scored on its
+// own, and quoted phrases, which a document must contain.
+type keywordQuery struct {
+	terms   []string
+	phrases []phraseQuery
+}
+
+// phraseQuery is a quoted phrase. Its terms must occur in order with at
+// most slop other terms between them in total; a slop of 0 requires them
+// adjacent.
+type phraseQuery struct {
+	terms []string
+	slop  int
+}
+
+// parseKeywordQuery splits query into free terms and quoted phrases,
+// tokenizing both the way documents were indexed. A phrase followed by ~N,
+// as in "context window"~3, is a proximity query allowing up to N other
+// terms between its terms. An unclosed quote is read as free text, and a
+// phrase with no terms is ignored.
+func (idx *Index) parseKeywordQuery(query string) keywordQuery {
+	var q keywordQuery
+	var free strings.Builder
+	for {
+		start := strings.IndexByte(query, '"')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(query[start+1:], '"')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		free.WriteString(query[:start])
+		free.WriteByte(' ')
+
+		phrase := phraseQuery{terms: idx.Tokenize(query[start+1 : end])}
+		query = query[end+1:]
+		if strings.HasPrefix(query, "~") {
+			digits := len(query[1:]) - len(strings.TrimLeft(query[1:], "0123456789"))
+			if slop, err := strconv.Atoi(query[1 : 1+digits]); err == nil {
+				phrase.slop = slop
+				query = query[1+digits:]
+			}
+		}
+		if len(phrase.terms) > 0 {
+			q.phrases = append(q.phrases, phrase)
+		}
+	}
+	free.WriteString(query)
+	q.terms = idx.Tokenize(free.String())
+	return q
+}
+
+// phraseMatches counts the positions in the document at uri where phrase
+// starts a match: each following term occurs after the one before, with
+// the whole match spanning at most the phrase length plus its slop.
+func (idx *Index) phraseMatches(uri string, phrase phraseQuery) int {
+	positions := idx.Positions[uri]
+	maxSpan := len(phrase.terms) - 1 + phrase.slop
+
+	matches := 0
+	for _, start := range positions[phrase.terms[0]] {
+		last := start
+		for _, term := range phrase.terms[1:] {
+			// The earliest later occurrence keeps the span smallest
+			next := positions[term]
+			i := sort.SearchInts(next, last+1)
+			if i == len(next) {
+				last = -1
+				break
+			}
+			last = next[i]
+		}
+		if last >= 0 && last-start <= maxSpan {
+			matches++
+		}
+	}
+	return matches
+}
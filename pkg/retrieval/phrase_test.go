@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseKeywordQuery(t *testing.T) {
+	idx := NewIndexWithTokenizer(NewTokenizer(TokenizerConfig{}))
+
+	tests := []struct {
+		query   string
+		terms   []string
+		phrases []phraseQuery
+	}{
+		{"context window", []string{"context", "window"}, nil},
+		{`"context window"`, nil, []phraseQuery{{terms: []string{"context", "window"}}}},
+		{`llm "context window" limits`, []string{"llm", "limits"}, []phraseQuery{{terms: []string{"context", "window"}}}},
+		{`"context window"~3 tokens`, []string{"tokens"}, []phraseQuery{{terms: []string{"context", "window"}, slop: 3}}},
+		{`"a b" "c"`, nil, []phraseQuery{{terms: []string{"a", "b"}}, {terms: []string{"c"}}}},
+		{`"unclosed phrase`, []string{"unclosed", "phrase"}, nil},
+		{`"" empty~`, []string{"empty"}, nil},
+	}
+	for _, tt := range tests {
+		q := idx.parseKeywordQuery(tt.query)
+		if !reflect.DeepEqual(q.terms, tt.terms) || !reflect.DeepEqual(q.phrases, tt.phrases) {
+			t.Errorf("parseKeywordQuery(%q): Expected %v and %v, got %v and %v", tt.query, tt.terms, tt.phrases, q.terms, q.phrases)
+		}
+	}
+}
+
+// newPhraseTestIndex indexes, with the English tokenizer, a document with
+// "context window" adjacent and one mentioning both words more often but
+// far apart.
+func newPhraseTestIndex() *Index {
+	idx := NewIndexWithTokenizer(NewTokenizer(EnglishTokenizerConfig()))
+	idx.AddDocument("viking://resources/adjacent", "the context window limits how many tokens fit", nil)
+	idx.AddDocument("viking://resources/apart", "context switching stalls the window manager while context caching speeds up every open window", nil)
+	idx.AddDocument("viking://resources/other", "unrelated notes about tokens", nil)
+	idx.BuildIDF()
+	return idx
+}
+
+func TestKeywordSearchPhrase(t *testing.T) {
+	idx := newPhraseTestIndex()
+	ks := NewKeywordSearch()
+
+	// Unquoted, the words counted separately favour the repetitive document
+	results := ks.Search(context.Background(), "context window", idx, 0, nil)
+	if len(results) != 2 || results[0].URI != "viking://resources/apart" {
+		t.Fatalf("Expected the far-apart document first without quotes, got %v", results)
+	}
+
+	results = ks.Search(context.Background(), `"context window"`, idx, 0, nil)
+	if len(results) != 1 || results[0].URI != "viking://resources/adjacent" {
+		t.Errorf("Expected only the adjacent document for the phrase, got %v", results)
+	}
+
+	// Free terms still score alongside a phrase, but cannot stand in for it
+	results = ks.Search(context.Background(), `"context window" tokens`, idx, 0, nil)
+	if len(results) != 1 || results[0].URI != "viking://resources/adjacent" {
+		t.Errorf("Expected the phrase to be required, got %v", results)
+	}
+	if ks.Score(`"window context"`, idx, "viking://resources/adjacent") != 0 {
+		t.Error("Expected a phrase to require its terms in order")
+	}
+}
+
+func TestKeywordSearchProximity(t *testing.T) {
+	idx := newPhraseTestIndex()
+	ks := NewKeywordSearch()
+
+	// The closest far-apart pair, "context switching stalls the window",
+	// has two terms between once stopwords are dropped
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`"context window"~1`, []string{"viking://resources/adjacent"}},
+		{`"context window"~2`, []string{"viking://resources/adjacent", "viking://resources/apart"}},
+		{`"context tokens"~4`, []string{"viking://resources/adjacent"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range ks.Search(context.Background(), tt.query, idx, 0, nil) {
+			got = append(got, r.URI)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
 // RetrieverConfig contains configuration for the retriever.
@@ -18,6 +21,11 @@ type RetrieverConfig struct {
 	// Maximum convergence rounds (stop after multiple rounds with unchanged topk)
 	MaxConvergenceRounds int
 
+	// Stop after this many consecutive rounds yield no new candidates above
+	// threshold, even with fewer than limit results (0 = disabled). A round
+	// is the batch of up to SearchConcurrency directories searched together
+	MaxStaleRounds int
+
 	// Maximum relations per resource
 	MaxRelations int
 
@@ -32,17 +40,54 @@ type RetrieverConfig struct {
 
 	// Default score threshold
 	ScoreThreshold float64
+
+	// Maximum directory depth expanded below a starting point (0 = unlimited)
+	MaxDepth int
+
+	// Maximum directories visited per retrieval (0 = unlimited)
+	MaxDirectoriesVisited int
+
+	// Directories whose children are searched in parallel per round (0 or
+	// 1 = serially)
+	SearchConcurrency int
+
+	// Tokenizer used for keyword search
+	Tokenizer TokenizerConfig
+
+	// Weights balance keyword against semantic matches in hybrid search
+	Weights FusionWeights
+
+	// StrictEmbedding fails retrieval when the query cannot be embedded,
+	// instead of searching without a query vector
+	StrictEmbedding bool
+
+	// EmbeddingModel names the embedder's model, which keys cached query
+	// embeddings along with the query text
+	EmbeddingModel string
+
+	// Query embeddings cached, least recently used evicted first (0 = no
+	// cache), and how long each stays valid (0 = until evicted)
+	EmbeddingCacheSize int
+	EmbeddingCacheTTL  time.Duration
 }
 
 // DefaultRetrieverConfig returns default retriever configuration.
 func DefaultRetrieverConfig() RetrieverConfig {
 	return RetrieverConfig{
 		MaxConvergenceRounds:    3,
+		MaxStaleRounds:          3,
 		MaxRelations:           5,
 		ScorePropagationAlpha:  0.5,
 		DirectoryDominanceRatio: 1.2,
 		GlobalSearchTopK:       3,
 		ScoreThreshold:         0.0,
+		MaxDepth:               16,
+		MaxDirectoriesVisited:  1000,
+		SearchConcurrency:      4,
+		Tokenizer:              DefaultTokenizerConfig(),
+		Weights:                DefaultFusionWeights(),
+		EmbeddingCacheSize:     256,
+		EmbeddingCacheTTL:      10 * time.Minute,
 	}
 }
 
@@ -50,7 +95,13 @@ func DefaultRetrieverConfig() RetrieverConfig {
 type SearchResultHeap []SearchResult
 
 func (h SearchResultHeap) Len() int           { return len(h) }
-func (h SearchResultHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h SearchResultHeap) Less(i, j int) bool {
+	// Break score ties by URI so traversal order does not depend on push order
+	if h[i].Score == h[j].Score {
+		return h[i].URI < h[j].URI
+	}
+	return h[i].Score < h[j].Score
+}
 func (h SearchResultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 func (h *SearchResultHeap) Push(x interface{}) { *h = append(*h, x.(SearchResult)) }
 func (h *SearchResultHeap) Pop() interface{} {
@@ -65,9 +116,11 @@ func (h *SearchResultHeap) Pop() interface{} {
 type RetrievalResult struct {
 	URI       string
 	Score     float64
+	RawScore  float64
 	IsLeaf    bool
 	Abstract  string
 	ParentURI string
+	Vector    []float64
 }
 
 // HierarchicalRetriever implements hierarchical retrieval with directory traversal.
@@ -77,16 +130,28 @@ type HierarchicalRetriever struct {
 	vectorStore VectorStore
 	trajectory  *TrajectoryLogger
 	hybridSearch *HybridSearch
+	tracer       trace.Tracer
+	// tokenizer scores children by keyword when there is no query vector
+	tokenizer *Tokenizer
+	// embedCache is nil when query embeddings are not cached
+	embedCache *EmbeddingCache
 
 	mu sync.RWMutex
 }
 
-// NewHierarchicalRetriever creates a new HierarchicalRetriever.
+// NewHierarchicalRetriever creates a new HierarchicalRetriever. A nil
+// embedder or a NoopEmbedder searches without a query vector.
 func NewHierarchicalRetriever(embedder Embedder, vectorStore VectorStore, config RetrieverConfig) *HierarchicalRetriever {
 	var hs *HybridSearch
-	if embedder != nil && vectorStore != nil {
+	if !IsNoopEmbedder(embedder) && vectorStore != nil {
 		ss := NewSemanticSearch(embedder, vectorStore)
-		hs = NewHybridSearch(ss, 0.5)
+		hs = NewHybridSearch(ss, 1-config.Weights.Keyword)
+		hs.SetTokenizer(NewTokenizer(config.Tokenizer))
+	}
+
+	var cache *EmbeddingCache
+	if config.EmbeddingCacheSize > 0 {
+		cache = NewEmbeddingCache(config.EmbeddingCacheSize, config.EmbeddingCacheTTL)
 	}
 
 	return &HierarchicalRetriever{
@@ -95,11 +160,42 @@ func NewHierarchicalRetriever(embedder Embedder, vectorStore VectorStore, config
 		vectorStore:  vectorStore,
 		trajectory:   NewTrajectoryLogger(),
 		hybridSearch: hs,
+		tracer:       newTracer(nil),
+		tokenizer:    NewTokenizer(config.Tokenizer),
+		embedCache:   cache,
 	}
 }
 
+// SetTracerProvider sets the provider used to trace retrieval. Without one,
+// spans are not recorded.
+func (hr *HierarchicalRetriever) SetTracerProvider(tp trace.TracerProvider) {
+	hr.tracer = newTracer(tp)
+}
+
+// EmbeddingCache returns the cache of query embeddings, or nil when
+// RetrieverConfig.EmbeddingCacheSize is 0. Its Stats report cache hits.
+func (hr *HierarchicalRetriever) EmbeddingCache() *EmbeddingCache {
+	return hr.embedCache
+}
+
+// Config returns the retriever configuration.
+func (hr *HierarchicalRetriever) Config() RetrieverConfig {
+	return hr.config
+}
+
 // Retrieve performs hierarchical retrieval.
-func (hr *HierarchicalRetriever) Retrieve(ctx context.Context, query TypedQuery, opts SearchOptions) (*QueryResult, error) {
+func (hr *HierarchicalRetriever) Retrieve(ctx context.Context, query TypedQuery, opts SearchOptions) (result *QueryResult, err error) {
+	ctx, span := hr.tracer.Start(ctx, SpanRetrieve, trace.WithAttributes(
+		AttrQuery.String(query.Query),
+		AttrContextType.String(string(query.ContextType)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	// Fall back to the configured threshold when none is requested
+	if opts.ScoreThreshold == 0 {
+		opts.ScoreThreshold = hr.config.ScoreThreshold
+	}
+
 	// Create trajectory
 	trajectory := hr.trajectory.CreateTrajectory(query.Query)
 	thinkingTrace := &ThinkingTrace{StartTime: time.Now()}
@@ -117,13 +213,24 @@ func (hr *HierarchicalRetriever) Retrieve(ctx context.Context, query TypedQuery,
 			"context_type":       query.ContextType,
 		}, query.Query)
 
-	// Generate query vector
+	// Generate query vector; zero vectors would only flatten the scores
 	var queryVector *EmbedResult
-	if hr.embedder != nil {
-		var err error
-		queryVector, err = hr.embedder.Embed(ctx, query.Query)
-		if err != nil {
-			return nil, fmt.Errorf("failed to embed query: %w", err)
+	if !IsNoopEmbedder(hr.embedder) {
+		var embedErr error
+		queryVector, embedErr = hr.embed(ctx, query.Query)
+		if embedErr != nil {
+			if hr.config.StrictEmbedding {
+				return nil, fmt.Errorf("failed to embed query: %w", embedErr)
+			}
+			// Degrade to searching without a query vector: children are
+			// listed and scored by keyword
+			queryVector = nil
+			thinkingTrace.AddEvent(TraceEventEmbeddingFailed,
+				fmt.Sprintf("Embedding failed, searching without a query vector: %v", embedErr),
+				map[string]interface{}{
+					"error":    embedErr.Error(),
+					"degraded": true,
+				}, query.Query)
 		}
 	}
 
@@ -142,6 +249,12 @@ func (hr *HierarchicalRetriever) Retrieve(ctx context.Context, query TypedQuery,
 	// Convert to matched contexts
 	matched := hr.convertToMatchedContexts(candidates, query.ContextType)
 
+	uris := make([]string, len(matched))
+	for i, m := range matched {
+		uris[i] = m.URI
+	}
+	span.SetAttributes(resultAttributes(uris)...)
+
 	thinkingTrace.AddEvent(TraceEventSearchSummary,
 		fmt.Sprintf("Retrieval complete, found %d results", len(matched)),
 		map[string]interface{}{
@@ -155,6 +268,7 @@ func (hr *HierarchicalRetriever) Retrieve(ctx context.Context, query TypedQuery,
 		MatchedContexts:    matched,
 		SearchedDirectories: targetDirs,
 		ThinkingTrace:       thinkingTrace,
+		Trajectory:          trajectory,
 	}, nil
 }
 
@@ -191,6 +305,15 @@ func (hr *HierarchicalRetriever) mergeStartingPoints(query string, rootURIs []st
 		}
 	}
 
+	// Seed the queue in a fixed order so tied scores traverse the same way
+	// on every run
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].URI < items[j].URI
+	})
+
 	return items
 }
 
@@ -214,119 +337,228 @@ func (hr *HierarchicalRetriever) recursiveSearch(
 	heap.Init(dirQueue)
 
 	visited := make(map[string]bool)
+	depths := make(map[string]int)
+	// best ranks every candidate found; diversification also needs the
+	// ones it evicts
+	best := newTopK(opts.Limit)
 	var collected []RetrievalResult
+	totalFound := 0
 	prevTopKURIs := make(map[string]bool)
 	convergenceRounds := 0
-	depth := 0
+	staleRounds := 0
+	depthCapped := false
 
 	alpha := hr.config.ScorePropagationAlpha
 
 	// Initialize queue with starting points
 	for _, sp := range startingPoints {
 		heap.Push(dirQueue, SearchResult{URI: sp.URI, Score: sp.Score})
+		depths[sp.URI] = 0
+	}
+
+	parallelism := hr.config.SearchConcurrency
+	if parallelism < 1 {
+		parallelism = 1
 	}
 
-	for dirQueue.Len() > 0 {
+	limitReached := false
+search:
+	for dirQueue.Len() > 0 && !limitReached {
 		select {
 		case <-ctx.Done():
-			return collected, ctx.Err()
+			return nil, ctx.Err()
 		default:
 		}
 
-		item := heap.Pop(dirQueue).(SearchResult)
-		currentURI := item.URI
-		currentScore := item.Score
-
-		if visited[currentURI] {
-			continue
+		// Pop up to parallelism unvisited directories, best first
+		var batch []SearchResult
+		for len(batch) < parallelism && dirQueue.Len() > 0 {
+			item := heap.Pop(dirQueue).(SearchResult)
+			if visited[item.URI] {
+				continue
+			}
+			if hr.config.MaxDirectoriesVisited > 0 && len(visited) >= hr.config.MaxDirectoriesVisited {
+				thinkingTrace.AddEvent(TraceEventTraversalLimit,
+					fmt.Sprintf("Stopped after visiting %d directories", len(visited)),
+					map[string]interface{}{
+						"reason":  "max_directories_visited",
+						"limit":   hr.config.MaxDirectoriesVisited,
+						"pending": dirQueue.Len() + 1,
+					}, query)
+				limitReached = true
+				break
+			}
+			visited[item.URI] = true
+			batch = append(batch, item)
 		}
-		visited[currentURI] = true
-
-		// Add to trajectory
-		trajectory.AddNode(currentURI, depth, currentScore, nil)
-
-		thinkingTrace.AddEvent(TraceEventSearchDirectoryStart,
-			fmt.Sprintf("Searching directory: %s", currentURI),
-			map[string]interface{}{
-				"uri":   currentURI,
-				"score": currentScore,
-			}, query)
 
-		// Search children
-		children, err := hr.searchChildren(ctx, currentURI, queryVector, opts.Limit*2)
-		if err != nil {
-			continue
+		// Search the batch concurrently. Each directory's children land in
+		// its own slot and are merged below in pop order, so results and
+		// convergence do not depend on which search finishes first.
+		children := make([][]SearchResult, len(batch))
+		searched := make([]bool, len(batch))
+		g, gctx := errgroup.WithContext(ctx)
+		for i, item := range batch {
+			g.Go(func() error {
+				results, err := hr.searchChildren(gctx, query, item.URI, queryVector, opts.Limit*2, opts.MetadataFilter)
+				if err != nil {
+					// A failing directory is skipped unless retrieval was cancelled
+					return ctx.Err()
+				}
+				children[i] = results
+				searched[i] = true
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
 		}
 
-		for _, child := range children {
-			// Calculate final score with propagation
-			finalScore := alpha*child.Score + (1-alpha)*currentScore
+		// Merge the whole batch before checking for convergence, so every
+		// directory searched counts and a batch is one round
+		found, queued, merged := 0, 0, 0
+		for i, item := range batch {
+			currentURI := item.URI
+			currentScore := item.Score
+			depth := depths[currentURI]
 
-			// Check threshold
-			thresholdPassed := func() bool {
-				if opts.ScoreGTE {
-					return finalScore >= opts.ScoreThreshold
-				}
-				return finalScore > opts.ScoreThreshold
-			}()
+			// Add to trajectory
+			trajectory.AddNode(currentURI, depth, currentScore, nil)
 
-			if !thresholdPassed {
-				thinkingTrace.AddEvent(TraceEventCandidateExcluded,
-					fmt.Sprintf("Excluded %s (score %.4f below threshold %.4f)", child.URI, finalScore, opts.ScoreThreshold),
-					map[string]interface{}{
-						"uri":    child.URI,
-						"score":  finalScore,
-						"reason": "below_threshold",
-					}, query)
+			thinkingTrace.AddEvent(TraceEventSearchDirectoryStart,
+				fmt.Sprintf("Searching directory: %s", currentURI),
+				map[string]interface{}{
+					"uri":   currentURI,
+					"score": currentScore,
+				}, query)
+
+			if !searched[i] {
 				continue
 			}
+			merged++
+
+			for _, child := range children[i] {
+				// Calculate final score with propagation
+				finalScore := alpha*child.Score + (1-alpha)*currentScore
+
+				// Check threshold
+				thresholdPassed := func() bool {
+					if opts.ScoreGTE {
+						return finalScore >= opts.ScoreThreshold
+					}
+					return finalScore > opts.ScoreThreshold
+				}()
+
+				if !thresholdPassed {
+					thinkingTrace.AddEvent(TraceEventCandidateExcluded,
+						fmt.Sprintf("Excluded %s (score %.4f below threshold %.4f)", child.URI, finalScore, opts.ScoreThreshold),
+						map[string]interface{}{
+							"uri":     child.URI,
+							"parent":  currentURI,
+							"score":   finalScore,
+							"is_leaf": child.IsLeaf,
+							"reason":  "below_threshold",
+						}, query)
+					continue
+				}
 
-			// Check if already collected
-			alreadyCollected := false
-			for _, c := range collected {
-				if c.URI == child.URI {
-					alreadyCollected = true
-					break
+				// Directories only guide the traversal when leaves are wanted
+				excluded := opts.LeavesOnly && !child.IsLeaf
+				if excluded {
+					thinkingTrace.AddEvent(TraceEventCandidateExcluded,
+						fmt.Sprintf("Excluded directory %s from results", child.URI),
+						map[string]interface{}{
+							"uri":     child.URI,
+							"parent":  currentURI,
+							"score":   finalScore,
+							"is_leaf": false,
+							"reason":  "not_leaf",
+						}, query)
 				}
-			}
 
-			if !alreadyCollected {
-				collected = append(collected, RetrievalResult{
-					URI:       child.URI,
-					Score:     finalScore,
-					IsLeaf:    child.IsLeaf,
-					Abstract:  child.Abstract,
-				})
+				candidate := RetrievalResult{
+					URI:      child.URI,
+					Score:    finalScore,
+					RawScore: child.Score,
+					IsLeaf:   child.IsLeaf,
+					Abstract: child.Abstract,
+					Vector:   child.Vector,
+				}
+				// A child reached again through another parent keeps its
+				// first score
+				if !excluded && best.Offer(candidate) {
+					if opts.Diversify {
+						collected = append(collected, candidate)
+					}
+					totalFound++
+					found++
+
+					thinkingTrace.AddEvent(TraceEventCandidateSelected,
+						fmt.Sprintf("Added %s to candidates (score: %.4f)", child.URI, finalScore),
+						map[string]interface{}{
+							"uri":   child.URI,
+							"score": finalScore,
+						}, query)
+				}
 
-				thinkingTrace.AddEvent(TraceEventCandidateSelected,
-					fmt.Sprintf("Added %s to candidates (score: %.4f)", child.URI, finalScore),
-					map[string]interface{}{
-						"uri":   child.URI,
-						"score": finalScore,
-					}, query)
+				// Add non-leaf children to queue, unless that would exceed the depth cap
+				if !child.IsLeaf && hr.config.MaxDepth > 0 && depth+1 > hr.config.MaxDepth {
+					if !depthCapped {
+						depthCapped = true
+						thinkingTrace.AddEvent(TraceEventTraversalLimit,
+							fmt.Sprintf("Not expanding %s beyond max depth %d", child.URI, hr.config.MaxDepth),
+							map[string]interface{}{
+								"reason": "max_depth",
+								"limit":  hr.config.MaxDepth,
+								"uri":    child.URI,
+								"parent": currentURI,
+								"score":  finalScore,
+							}, query)
+					}
+				} else if !child.IsLeaf {
+					if _, seen := depths[child.URI]; !seen {
+						depths[child.URI] = depth + 1
+					}
+					heap.Push(dirQueue, SearchResult{URI: child.URI, Score: finalScore})
+					trajectory.AddEdge(currentURI, child.URI)
+					queued++
+
+					thinkingTrace.AddEvent(TraceEventDirectoryQueued,
+						fmt.Sprintf("Queued subdirectory: %s", child.URI),
+						map[string]interface{}{
+							"uri":   child.URI,
+							"score": finalScore,
+						}, query)
+				}
 			}
+		}
 
-			// Add non-leaf children to queue
-			if !child.IsLeaf {
-				heap.Push(dirQueue, SearchResult{URI: child.URI, Score: finalScore})
-				trajectory.AddEdge(currentURI, child.URI)
+		if merged == 0 {
+			continue
+		}
 
-				thinkingTrace.AddEvent(TraceEventDirectoryQueued,
-					fmt.Sprintf("Queued subdirectory: %s", child.URI),
+		// A sparse tree may never fill limit, so also stop once batches
+		// keep turning up nothing new. When only leaves are wanted, a
+		// directory leading to more directories is still progress, as the
+		// leaves may all sit further down
+		if found > 0 || (opts.LeavesOnly && queued > 0) {
+			staleRounds = 0
+		} else {
+			staleRounds++
+			if hr.config.MaxStaleRounds > 0 && staleRounds >= hr.config.MaxStaleRounds {
+				thinkingTrace.AddEvent(TraceEventSearchConverged,
+					"Search converged",
 					map[string]interface{}{
-						"uri":   child.URI,
-						"score": finalScore,
+						"reason":      "no_new_candidates",
+						"rounds":      staleRounds,
+						"total_found": totalFound,
 					}, query)
+				break search
 			}
 		}
 
 		// Convergence check
-		currentTopK := hr.getTopK(collected, opts.Limit)
-		currentTopKURIs := make(map[string]bool)
-		for _, c := range currentTopK {
-			currentTopKURIs[c.URI] = true
-		}
-
+		currentTopKURIs := best.URIs()
 		if hr.mapsEqual(currentTopKURIs, prevTopKURIs) && len(currentTopKURIs) >= opts.Limit {
 			convergenceRounds++
 			thinkingTrace.AddEvent(TraceEventConvergenceCheck,
@@ -341,54 +573,155 @@ func (hr *HierarchicalRetriever) recursiveSearch(
 				thinkingTrace.AddEvent(TraceEventSearchConverged,
 					"Search converged",
 					map[string]interface{}{
+						"reason":       "topk_stable",
 						"rounds":       convergenceRounds,
-						"total_found":  len(collected),
+						"total_found":  totalFound,
 					}, query)
-				break
+				break search
 			}
 		} else {
 			convergenceRounds = 0
 		}
 		prevTopKURIs = currentTopKURIs
-		depth++
+
 	}
 
-	// Sort by score
-	sort.Slice(collected, func(i, j int) bool {
-		return collected[i].Score > collected[j].Score
-	})
+	// Diversification picks from every candidate, not just the top scores
+	if opts.Diversify {
+		sort.Slice(collected, func(i, j int) bool {
+			return ranksBefore(collected[i], collected[j])
+		})
+		lambda := defaultDiversityLambda
+		if opts.DiversityLambda != nil {
+			lambda = *opts.DiversityLambda
+		}
+		return diversify(collected, lambda, opts.Limit), nil
+	}
+
+	return best.Sorted(), nil
+}
 
-	if len(collected) > opts.Limit {
-		collected = collected[:opts.Limit]
+// embed embeds the query text, reusing a cached embedding of the same
+// text by the same model.
+func (hr *HierarchicalRetriever) embed(ctx context.Context, text string) (result *EmbedResult, err error) {
+	ctx, span := hr.tracer.Start(ctx, SpanEmbed, trace.WithAttributes(AttrQuery.String(text)))
+	defer func() { endSpan(span, err) }()
+
+	if hr.embedCache != nil {
+		cached, ok := hr.embedCache.Get(hr.config.EmbeddingModel, text)
+		span.SetAttributes(AttrEmbeddingCacheHit.Bool(ok))
+		if ok {
+			return cached, nil
+		}
 	}
 
-	return collected, nil
+	result, err = hr.embedder.Embed(ctx, text)
+	if err == nil && result != nil {
+		hr.embedCache.Put(hr.config.EmbeddingModel, text, result)
+	}
+	return result, err
 }
 
-// searchChildren searches for children of a directory.
-func (hr *HierarchicalRetriever) searchChildren(ctx context.Context, parentURI string, queryVector *EmbedResult, limit int) ([]SearchResult, error) {
+// searchChildren searches for the children of a directory. Leaves must
+// match metadataFilter; subdirectories need not, as a matching leaf may sit
+// below an untagged directory. The directory's own parent_uri condition
+// overrides a parent_uri key in metadataFilter, which would otherwise stop
+// traversal. Without a query vector, children are scored by how well their
+// abstracts match the query's keywords, unless the store scored them higher
+// itself.
+func (hr *HierarchicalRetriever) searchChildren(ctx context.Context, query, parentURI string, queryVector *EmbedResult, limit int, metadataFilter map[string]interface{}) (results []SearchResult, err error) {
+	ctx, span := hr.tracer.Start(ctx, SpanSearchChildren, trace.WithAttributes(AttrParentURI.String(parentURI)))
+	defer func() { endSpan(span, err) }()
+
 	if hr.vectorStore == nil {
 		return []SearchResult{}, nil
 	}
 
-	filter := map[string]interface{}{
-		"parent_uri": parentURI,
-	}
-
-	results, err := hr.vectorStore.Search(ctx, queryVector, limit, filter)
+	results, err = hr.searchChildrenMatching(ctx, query, queryVector, limit, map[string]interface{}{"parent_uri": parentURI})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(metadataFilter) > 0 {
+		filter := make(map[string]interface{}, len(metadataFilter)+1)
+		for key, value := range metadataFilter {
+			filter[key] = value
+		}
+		filter["parent_uri"] = parentURI
+
+		leaves, err := hr.searchChildrenMatching(ctx, query, queryVector, limit, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the subdirectories of the unfiltered search and the leaves
+		// of the filtered one
+		merged := make([]SearchResult, 0, len(results)+len(leaves))
+		for _, r := range results {
+			if !r.IsLeaf {
+				merged = append(merged, r)
+			}
+		}
+		for _, r := range leaves {
+			if r.IsLeaf {
+				merged = append(merged, r)
+			}
+		}
+		sort.SliceStable(merged, func(i, j int) bool {
+			return merged[i].Score > merged[j].Score
+		})
+		results = merged
+	}
+
+	uris := make([]string, len(results))
+	for i, r := range results {
+		uris[i] = r.URI
+	}
+	span.SetAttributes(resultAttributes(uris)...)
+
 	return results, nil
 }
 
-// getTopK returns top k results by score.
-func (hr *HierarchicalRetriever) getTopK(results []RetrievalResult, k int) []RetrievalResult {
-	if k >= len(results) {
-		return results
+// searchChildrenMatching searches for the children matching filter, by
+// vector or, without a query vector, by keyword.
+func (hr *HierarchicalRetriever) searchChildrenMatching(ctx context.Context, query string, queryVector *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if queryVector == nil {
+		return hr.searchByKeyword(ctx, query, limit, filter)
 	}
-	return results[:k]
+	return hr.vectorStore.Search(ctx, queryVector, limit, filter)
+}
+
+// searchByKeyword lists every child matching filter and keeps the limit
+// best by keyword match against query. A store matching children itself
+// keeps its own score where that is higher.
+func (hr *HierarchicalRetriever) searchByKeyword(ctx context.Context, query string, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	results, err := hr.vectorStore.Search(ctx, nil, 0, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(results))
+	for i, r := range results {
+		texts[i] = r.Abstract
+	}
+	for i, score := range KeywordScores(hr.tokenizer, query, texts) {
+		if score > results[i].Score {
+			results[i].Score = score
+			results[i].RawScore = score
+			results[i].HasRawScore = true
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].URI < results[j].URI
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
 }
 
 // mapsEqual compares two string maps.
@@ -429,6 +762,7 @@ func (hr *HierarchicalRetriever) convertToMatchedContexts(candidates []Retrieval
 			IsLeaf:      c.IsLeaf,
 			Abstract:    c.Abstract,
 			Score:       c.Score,
+			RawScore:    c.RawScore,
 		})
 	}
 
@@ -477,6 +811,7 @@ func (hr *HierarchicalRetriever) PropagateScores(results []SearchResult) []Searc
 	for i := range results {
 		dirPath := extractDirectory(results[i].URI)
 		if parentScore, ok := scoreMap[dirPath]; ok {
+			keepRawScore(&results[i])
 			results[i].Score = results[i].Score + parentScore*0.3
 		}
 	}
@@ -532,6 +867,7 @@ func (hr *HierarchicalRetriever) HierarchicalRank(results []SearchResult, rootPa
 	// Apply boosts
 	for i := range results {
 		if boost, ok := scoreBoost[results[i].URI]; ok {
+			keepRawScore(&results[i])
 			results[i].Score += boost
 		}
 	}
@@ -0,0 +1,716 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainStore is a VectorStore exposing a synthetic tree where every
+// directory holds one leaf and width subdirectories, down to maxLevel.
+// Directories above leafLevel hold no leaf.
+type chainStore struct {
+	width     int
+	maxLevel  int
+	leafLevel int
+
+	mu       sync.Mutex
+	searches int
+}
+
+func (s *chainStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	parent, _ := filter["parent_uri"].(string)
+	if parent == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	s.searches++
+	s.mu.Unlock()
+
+	var level int
+	fmt.Sscanf(parent[len(parent)-4:], "%04d", &level)
+	if level >= s.maxLevel {
+		return nil, nil
+	}
+
+	var results []SearchResult
+	if level >= s.leafLevel {
+		results = append(results, SearchResult{URI: fmt.Sprintf("%s/leaf", parent), Score: 0.9, IsLeaf: true})
+	}
+	for i := 0; i < s.width; i++ {
+		results = append(results, SearchResult{
+			URI:   fmt.Sprintf("%s/d%d-%04d", parent, i, level+1),
+			Score: 0.8,
+		})
+	}
+	return results, nil
+}
+
+func (s *chainStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *chainStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *chainStore) Close() error                                          { return nil }
+
+func traversalLimitReasons(trace *ThinkingTrace) []string {
+	var reasons []string
+	for _, e := range trace.Events {
+		if e.EventType == TraceEventTraversalLimit {
+			reasons = append(reasons, e.Data["reason"].(string))
+		}
+	}
+	return reasons
+}
+
+func TestRetrieverMaxDepth(t *testing.T) {
+	store := &chainStore{width: 1, maxLevel: 500}
+	config := DefaultRetrieverConfig()
+	config.MaxDepth = 5
+	config.MaxDirectoriesVisited = 0
+
+	hr := NewHierarchicalRetriever(nil, store, config)
+	opts := DefaultSearchOptions()
+	opts.Limit = 1000
+	opts.TargetDirectories = []string{"viking://root-0000"}
+
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "deep"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// Root plus MaxDepth levels of subdirectories are searched
+	if store.searches != 6 {
+		t.Errorf("Expected 6 directories searched, got %d", store.searches)
+	}
+	if len(result.MatchedContexts) == 0 {
+		t.Error("Expected collected results to be returned")
+	}
+	reasons := traversalLimitReasons(result.ThinkingTrace)
+	if len(reasons) != 1 || reasons[0] != "max_depth" {
+		t.Errorf("Expected a single max_depth trace event, got %v", reasons)
+	}
+}
+
+func TestRetrieverMaxDirectoriesVisited(t *testing.T) {
+	store := &chainStore{width: 3, maxLevel: 50}
+	config := DefaultRetrieverConfig()
+	config.MaxDepth = 0
+	config.MaxDirectoriesVisited = 20
+
+	hr := NewHierarchicalRetriever(nil, store, config)
+	opts := DefaultSearchOptions()
+	opts.Limit = 1000
+	opts.TargetDirectories = []string{"viking://root-0000"}
+
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "wide"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if store.searches != 20 {
+		t.Errorf("Expected 20 directories searched, got %d", store.searches)
+	}
+	if len(result.MatchedContexts) == 0 {
+		t.Error("Expected collected results to be returned")
+	}
+	reasons := traversalLimitReasons(result.ThinkingTrace)
+	if len(reasons) != 1 || reasons[0] != "max_directories_visited" {
+		t.Errorf("Expected a single max_directories_visited trace event, got %v", reasons)
+	}
+}
+
+func TestRetrieverLeavesOnly(t *testing.T) {
+	config := DefaultRetrieverConfig()
+	config.MaxDepth = 0
+	config.MaxDirectoriesVisited = 0
+	opts := DefaultSearchOptions()
+	opts.Limit = 100
+	opts.TargetDirectories = []string{"viking://root-0000"}
+
+	// Without the option directories are results too
+	hr := NewHierarchicalRetriever(nil, &chainStore{width: 1, maxLevel: 3}, config)
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "all"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	dirs := 0
+	for _, m := range result.MatchedContexts {
+		if !m.IsLeaf {
+			dirs++
+		}
+	}
+	if dirs == 0 {
+		t.Errorf("Expected directories in the results, got %+v", result.MatchedContexts)
+	}
+
+	store := &chainStore{width: 1, maxLevel: 3}
+	hr = NewHierarchicalRetriever(nil, store, config)
+	opts.LeavesOnly = true
+	result, err = hr.Retrieve(context.Background(), TypedQuery{Query: "leaves"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// The search still descends through every directory
+	if store.searches != 4 {
+		t.Errorf("Expected 4 directories searched, got %d", store.searches)
+	}
+	want := map[string]bool{
+		"viking://root-0000/leaf":                 true,
+		"viking://root-0000/d0-0001/leaf":         true,
+		"viking://root-0000/d0-0001/d0-0002/leaf": true,
+	}
+	if len(result.MatchedContexts) != len(want) {
+		t.Errorf("Expected %d leaves, got %+v", len(want), result.MatchedContexts)
+	}
+	for _, m := range result.MatchedContexts {
+		if !m.IsLeaf || !want[m.URI] {
+			t.Errorf("Expected only leaf results, got %s", m.URI)
+		}
+	}
+}
+
+func TestRetrieverLeavesOnlyDeepLeaves(t *testing.T) {
+	config := DefaultRetrieverConfig()
+	config.MaxStaleRounds = 2
+	opts := DefaultSearchOptions()
+	opts.Limit = 100
+	opts.LeavesOnly = true
+	opts.TargetDirectories = []string{"viking://root-0000"}
+
+	store := &chainStore{width: 1, maxLevel: 6, leafLevel: 4}
+	hr := NewHierarchicalRetriever(nil, store, config)
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "deep"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// Directories leading only to directories are not stale
+	want := map[string]bool{
+		"viking://root-0000/d0-0001/d0-0002/d0-0003/d0-0004/leaf":         true,
+		"viking://root-0000/d0-0001/d0-0002/d0-0003/d0-0004/d0-0005/leaf": true,
+	}
+	if len(result.MatchedContexts) != len(want) {
+		t.Errorf("Expected %d leaves, got %+v", len(want), result.MatchedContexts)
+	}
+	for _, m := range result.MatchedContexts {
+		if !want[m.URI] {
+			t.Errorf("Expected only the deep leaves, got %s", m.URI)
+		}
+	}
+}
+
+// sparseStore is a VectorStore whose root holds one leaf and width empty
+// subdirectories, so a search finds far fewer results than its limit.
+type sparseStore struct {
+	width int
+
+	mu       sync.Mutex
+	searches int
+}
+
+func (s *sparseStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	parent, _ := filter["parent_uri"].(string)
+	if parent == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	s.searches++
+	s.mu.Unlock()
+	if parent != "viking://root" {
+		return nil, nil
+	}
+
+	results := []SearchResult{{URI: "viking://root/leaf", Score: 0.9, IsLeaf: true}}
+	for i := 0; i < s.width; i++ {
+		results = append(results, SearchResult{URI: fmt.Sprintf("viking://root/d%02d", i), Score: 0.5})
+	}
+	return results, nil
+}
+
+func (s *sparseStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *sparseStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *sparseStore) Close() error                                          { return nil }
+
+func TestRetrieverConvergesOnSparseTree(t *testing.T) {
+	store := &sparseStore{width: 40}
+	config := DefaultRetrieverConfig()
+	config.MaxStaleRounds = 2
+
+	hr := NewHierarchicalRetriever(nil, store, config)
+	opts := DefaultSearchOptions()
+	opts.Limit = 100
+	opts.TargetDirectories = []string{"viking://root"}
+
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "sparse"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// The root, then MaxStaleRounds batches of SearchConcurrency empty
+	// subdirectories
+	if store.searches != 9 {
+		t.Errorf("Expected 9 directories searched, got %d", store.searches)
+	}
+	// Every directory searched is merged before the search stops
+	merged := 0
+	for _, e := range result.ThinkingTrace.Events {
+		if _, ok := e.Data["uri"]; ok && e.EventType == TraceEventSearchDirectoryStart {
+			merged++
+		}
+	}
+	if merged != store.searches {
+		t.Errorf("Expected all %d directories searched merged, got %d", store.searches, merged)
+	}
+	if len(result.MatchedContexts) != 41 {
+		t.Errorf("Expected the 41 candidates found, got %d", len(result.MatchedContexts))
+	}
+	var reason interface{}
+	for _, e := range result.ThinkingTrace.Events {
+		if e.EventType == TraceEventSearchConverged {
+			reason = e.Data["reason"]
+		}
+	}
+	if reason != "no_new_candidates" {
+		t.Errorf("Expected convergence on no new candidates, got %v", reason)
+	}
+}
+
+func TestRetrieverStaleRoundsDisabled(t *testing.T) {
+	store := &sparseStore{width: 40}
+	config := DefaultRetrieverConfig()
+	config.MaxStaleRounds = 0
+
+	hr := NewHierarchicalRetriever(nil, store, config)
+	opts := DefaultSearchOptions()
+	opts.Limit = 100
+	opts.TargetDirectories = []string{"viking://root"}
+
+	if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "sparse"}, opts); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// Without stale rounds the whole frontier is searched
+	if store.searches != 41 {
+		t.Errorf("Expected 41 directories searched, got %d", store.searches)
+	}
+}
+
+func TestRetrieverKeepsRawScore(t *testing.T) {
+	store := &chainStore{width: 0, maxLevel: 1}
+	hr := NewHierarchicalRetriever(nil, store, DefaultRetrieverConfig())
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://root-0000"}
+
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "leaf"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(result.MatchedContexts) != 1 {
+		t.Fatalf("Expected 1 matched context, got %d", len(result.MatchedContexts))
+	}
+	mc := result.MatchedContexts[0]
+	if mc.RawScore != 0.9 {
+		t.Errorf("Expected raw score 0.9, got %f", mc.RawScore)
+	}
+	// The root starts at score 0, so propagation halves the leaf's score
+	if mc.Score != 0.45 {
+		t.Errorf("Expected propagated score 0.45, got %f", mc.Score)
+	}
+}
+
+// slowStore is a VectorStore whose root holds width subdirectories of two
+// leaves each, taking delay per search and tracking how many searches run
+// at once.
+type slowStore struct {
+	width int
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *slowStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	parent, _ := filter["parent_uri"].(string)
+	if parent == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(s.delay)
+
+	var results []SearchResult
+	if parent == "viking://wide" {
+		for i := 0; i < s.width; i++ {
+			results = append(results, SearchResult{URI: fmt.Sprintf("%s/d%d", parent, i), Score: 0.5})
+		}
+		return results, nil
+	}
+	var dir int
+	fmt.Sscanf(parent, "viking://wide/d%d", &dir)
+	for j := 0; j < 2; j++ {
+		results = append(results, SearchResult{
+			URI:    fmt.Sprintf("%s/leaf%d", parent, j),
+			Score:  0.3 + 0.05*float64(dir) + 0.01*float64(j),
+			IsLeaf: true,
+		})
+	}
+	return results, nil
+}
+
+func (s *slowStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *slowStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *slowStore) Close() error                                          { return nil }
+
+func TestRetrieverSearchConcurrency(t *testing.T) {
+	retrieve := func(concurrency int) ([]MatchedContext, int) {
+		store := &slowStore{width: 8, delay: 20 * time.Millisecond}
+		config := DefaultRetrieverConfig()
+		config.MaxStaleRounds = 0
+		config.SearchConcurrency = concurrency
+
+		hr := NewHierarchicalRetriever(nil, store, config)
+		opts := DefaultSearchOptions()
+		opts.Limit = 100
+		opts.TargetDirectories = []string{"viking://wide"}
+
+		result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "wide"}, opts)
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		return result.MatchedContexts, store.maxInFlight
+	}
+
+	serial, serialMax := retrieve(1)
+	parallel, parallelMax := retrieve(4)
+
+	if serialMax != 1 || parallelMax != 4 {
+		t.Errorf("Expected at most 1 and 4 searches at once, got %d and %d", serialMax, parallelMax)
+	}
+
+	if len(serial) != 24 || len(parallel) != len(serial) {
+		t.Fatalf("Expected 24 results either way, got %d and %d", len(serial), len(parallel))
+	}
+	for i := range serial {
+		if serial[i].URI != parallel[i].URI || serial[i].Score != parallel[i].Score {
+			t.Errorf("Expected result %d unchanged, got %+v and %+v", i, serial[i], parallel[i])
+		}
+	}
+}
+
+// filterStore records the filters of child searches and returns no
+// children.
+type filterStore struct {
+	mu      sync.Mutex
+	filters []map[string]interface{}
+}
+
+func (s *filterStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if filter != nil {
+		s.mu.Lock()
+		s.filters = append(s.filters, filter)
+		s.mu.Unlock()
+	}
+	return nil, nil
+}
+
+func (s *filterStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *filterStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *filterStore) Close() error                                          { return nil }
+
+func TestRetrieverPassesMetadataFilter(t *testing.T) {
+	store := &filterStore{}
+	hr := NewHierarchicalRetriever(nil, store, DefaultRetrieverConfig())
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://user/memories"}
+	opts.MetadataFilter = map[string]interface{}{
+		"category":   "preference",
+		"user_id":    "alice",
+		"parent_uri": "viking://elsewhere",
+	}
+
+	if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "tea"}, opts); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	// Subdirectories are listed unfiltered, leaves with the filter
+	want := []map[string]interface{}{
+		{"parent_uri": "viking://user/memories"},
+		{
+			"category":   "preference",
+			"user_id":    "alice",
+			"parent_uri": "viking://user/memories",
+		},
+	}
+	if !reflect.DeepEqual(store.filters, want) {
+		t.Errorf("Expected filters %v, got %v", want, store.filters)
+	}
+	// The caller's map is left as it was
+	if opts.MetadataFilter["parent_uri"] != "viking://elsewhere" {
+		t.Errorf("Expected the metadata filter unchanged, got %v", opts.MetadataFilter)
+	}
+}
+
+func TestRetrieverMetadataFilterBelowUntaggedDirectory(t *testing.T) {
+	store := NewInMemoryVectorStore(2)
+	entry := func(uri, parent, abstract string, leaf bool, team string) SearchResult {
+		metadata := map[string]interface{}{
+			"vector":     []float64{1, 0},
+			"parent_uri": parent,
+		}
+		if team != "" {
+			metadata["team"] = team
+		}
+		return SearchResult{URI: uri, Abstract: abstract, IsLeaf: leaf, ParentURI: parent, Metadata: metadata}
+	}
+	err := store.Add(context.Background(), []SearchResult{
+		entry("viking://resources/docs", "viking://resources", "Deployment guide and reference", false, ""),
+		entry("viking://resources/notes.md", "viking://resources", "Deployment notes", true, ""),
+		entry("viking://resources/docs/guide.md", "viking://resources/docs", "Deployment guide for the cluster", true, "ops"),
+		entry("viking://resources/docs/billing.md", "viking://resources/docs", "Deployment guide for billing", true, "billing"),
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://resources"}
+	opts.MetadataFilter = map[string]interface{}{"team": "ops"}
+
+	hr := NewHierarchicalRetriever(downEmbedder{}, store, DefaultRetrieverConfig())
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "deployment guide"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	var leaves []string
+	for _, m := range result.MatchedContexts {
+		if m.IsLeaf {
+			leaves = append(leaves, m.URI)
+		}
+	}
+	if !reflect.DeepEqual(leaves, []string{"viking://resources/docs/guide.md"}) {
+		t.Errorf("Expected only the tagged leaf below the untagged directory, got %v", leaves)
+	}
+}
+
+// orderStore records the order in which directories are searched and
+// returns no children.
+type orderStore struct {
+	searched []string
+}
+
+func (s *orderStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if parent, _ := filter["parent_uri"].(string); parent != "" {
+		s.searched = append(s.searched, parent)
+	}
+	return nil, nil
+}
+
+func (s *orderStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *orderStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *orderStore) Close() error                                          { return nil }
+
+func TestMergeStartingPointsOrder(t *testing.T) {
+	hr := NewHierarchicalRetriever(nil, nil, DefaultRetrieverConfig())
+	global := []SearchResult{
+		{URI: "viking://resources/b", Score: 0.5},
+		{URI: "viking://resources/c", Score: 0.9},
+		{URI: "viking://resources/a", Score: 0.5},
+	}
+	roots := []string{"viking://user/z", "viking://resources/a", "viking://agent/y"}
+
+	items := hr.mergeStartingPoints("q", roots, global)
+	want := []string{
+		"viking://resources/c",
+		"viking://resources/a",
+		"viking://resources/b",
+		"viking://agent/y",
+		"viking://user/z",
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d starting points, got %d", len(want), len(items))
+	}
+	for i, uri := range want {
+		if items[i].URI != uri {
+			t.Errorf("Expected starting point %d to be %s, got %s", i, uri, items[i].URI)
+		}
+	}
+}
+
+func TestRetrieverTiedStartingPointsStableOrder(t *testing.T) {
+	dirs := []string{"viking://user/memories", "viking://agent/skills", "viking://resources/docs"}
+	permutations := [][]string{
+		{dirs[0], dirs[1], dirs[2]},
+		{dirs[2], dirs[0], dirs[1]},
+		{dirs[1], dirs[2], dirs[0]},
+	}
+
+	var first []string
+	for run, targets := range permutations {
+		store := &orderStore{}
+		config := DefaultRetrieverConfig()
+		// Searched one at a time, the store sees the order they are popped
+		config.SearchConcurrency = 1
+		hr := NewHierarchicalRetriever(nil, store, config)
+		opts := DefaultSearchOptions()
+		opts.TargetDirectories = targets
+
+		if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "tie"}, opts); err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		if run == 0 {
+			first = store.searched
+			if len(first) != len(dirs) {
+				t.Fatalf("Expected %d directories searched, got %v", len(dirs), first)
+			}
+			continue
+		}
+		if fmt.Sprint(store.searched) != fmt.Sprint(first) {
+			t.Errorf("Expected traversal order %v, got %v for targets %v", first, store.searched, targets)
+		}
+	}
+}
+
+// keywordStore is a VectorStore matching children by their parent alone,
+// like a store falling back to keyword matching without a query vector.
+type keywordStore struct {
+	children      map[string][]SearchResult
+	vectorQueries int
+}
+
+func (s *keywordStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if query != nil {
+		s.vectorQueries++
+	}
+	parent, _ := filter["parent_uri"].(string)
+	return s.children[parent], nil
+}
+
+func (s *keywordStore) Add(ctx context.Context, vectors []SearchResult) error { return nil }
+func (s *keywordStore) Delete(ctx context.Context, uris []string) error       { return nil }
+func (s *keywordStore) Close() error                                          { return nil }
+
+func TestRetrieverNoopEmbedder(t *testing.T) {
+	store := &keywordStore{children: map[string][]SearchResult{
+		"viking://resources": {
+			{URI: "viking://resources/intro.md", Score: 0.8, IsLeaf: true},
+		},
+	}}
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://resources"}
+
+	hr := NewHierarchicalRetriever(NewNoopEmbedder(8), store, DefaultRetrieverConfig())
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "intro"}, opts)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(result.MatchedContexts) != 1 || result.MatchedContexts[0].URI != "viking://resources/intro.md" {
+		t.Errorf("Expected the leaf found by the store's own matching, got %+v", result.MatchedContexts)
+	}
+	// Zero vectors are never sent, and nothing is reported as failing
+	if store.vectorQueries != 0 {
+		t.Errorf("Expected no searches with a query vector, got %d", store.vectorQueries)
+	}
+	for _, e := range result.ThinkingTrace.Events {
+		if e.EventType == TraceEventEmbeddingFailed {
+			t.Errorf("Expected no embedding_failed event, got %+v", e)
+		}
+	}
+}
+
+func TestRetrieverDegradesWhenEmbeddingFails(t *testing.T) {
+	store := &keywordStore{children: map[string][]SearchResult{
+		"viking://resources": {
+			{URI: "viking://resources/docs", Score: 0.6},
+			{URI: "viking://resources/intro.md", Score: 0.8, IsLeaf: true},
+		},
+		"viking://resources/docs": {
+			{URI: "viking://resources/docs/guide.md", Score: 0.7, IsLeaf: true},
+		},
+	}}
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://resources"}
+
+	hr := NewHierarchicalRetriever(downEmbedder{}, store, DefaultRetrieverConfig())
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "guide"}, opts)
+	if err != nil {
+		t.Fatalf("Expected retrieval to degrade, got %v", err)
+	}
+	uris := make(map[string]bool)
+	for _, m := range result.MatchedContexts {
+		uris[m.URI] = true
+	}
+	if !uris["viking://resources/intro.md"] || !uris["viking://resources/docs/guide.md"] {
+		t.Errorf("Expected both leaves found without a query vector, got %v", uris)
+	}
+	if store.vectorQueries != 0 {
+		t.Errorf("Expected no searches with a query vector, got %d", store.vectorQueries)
+	}
+
+	var degraded []TraceEvent
+	for _, e := range result.ThinkingTrace.Events {
+		if e.EventType == TraceEventEmbeddingFailed {
+			degraded = append(degraded, e)
+		}
+	}
+	if len(degraded) != 1 || degraded[0].Data["error"] != "circuit breaker open" {
+		t.Errorf("Expected one embedding_failed trace event, got %+v", degraded)
+	}
+
+	config := DefaultRetrieverConfig()
+	config.StrictEmbedding = true
+	hr = NewHierarchicalRetriever(downEmbedder{}, store, config)
+	if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "guide"}, opts); err == nil {
+		t.Error("Expected strict retrieval to fail when embedding fails")
+	}
+}
+
+func TestRetrieverKeywordFallbackInMemory(t *testing.T) {
+	store := NewInMemoryVectorStore(2)
+	entry := func(uri, parent, abstract string, leaf bool) SearchResult {
+		return SearchResult{
+			URI:       uri,
+			Abstract:  abstract,
+			IsLeaf:    leaf,
+			ParentURI: parent,
+			Metadata: map[string]interface{}{
+				"vector":     []float64{1, 0},
+				"parent_uri": parent,
+			},
+		}
+	}
+	err := store.Add(context.Background(), []SearchResult{
+		entry("viking://resources/docs", "viking://resources", "Deployment guide and reference", false),
+		entry("viking://resources/notes.md", "viking://resources", "Meeting notes", true),
+		entry("viking://resources/docs/guide.md", "viking://resources/docs", "Deployment guide for the cluster", true),
+		entry("viking://resources/docs/faq.md", "viking://resources/docs", "Billing questions", true),
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://resources"}
+
+	hr := NewHierarchicalRetriever(downEmbedder{}, store, DefaultRetrieverConfig())
+	result, err := hr.Retrieve(context.Background(), TypedQuery{Query: "deployment guide"}, opts)
+	if err != nil {
+		t.Fatalf("Expected retrieval to degrade, got %v", err)
+	}
+	if len(result.MatchedContexts) == 0 {
+		t.Fatal("Expected keyword matches without a query vector, got none")
+	}
+	if got := result.MatchedContexts[0].URI; got != "viking://resources/docs/guide.md" {
+		t.Errorf("Expected the best keyword match first, got %s", got)
+	}
+	for _, m := range result.MatchedContexts {
+		if m.URI == "viking://resources/notes.md" {
+			t.Errorf("Expected a leaf matching no keyword in an unscored directory left out, got %+v", m)
+		}
+	}
+}
@@ -6,18 +6,40 @@ package retrieval
 import (
 	"context"
 	"math"
+	"reflect"
 	"sort"
 	"sync"
+
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // SearchResult represents a search result with score.
+// RawScore holds the score produced by the underlying search before any
+// fusion, normalization or reranking rewrote Score.
 type SearchResult struct {
 	URI       string                 `json:"uri"`
 	Score     float64                `json:"score"`
+	RawScore  float64                `json:"raw_score"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Abstract  string                 `json:"abstract,omitempty"`
+	// Content is the full text, when the caller has it to index
+	Content   string                 `json:"content,omitempty"`
 	IsLeaf    bool                   `json:"is_leaf"`
 	ParentURI string                 `json:"parent_uri,omitempty"`
+	// Vector is the result's embedding, when the vector store returns it
+	Vector []float64 `json:"-"`
+	// HasRawScore reports whether RawScore was captured, as a raw score
+	// of 0 is valid
+	HasRawScore bool `json:"-"`
+}
+
+// keepRawScore records a result's current score as its raw score unless an
+// earlier stage already captured one.
+func keepRawScore(r *SearchResult) {
+	if !r.HasRawScore {
+		r.RawScore = r.Score
+		r.HasRawScore = true
+	}
 }
 
 // VectorStore defines interface for vector storage and search.
@@ -49,8 +71,13 @@ func NewSemanticSearch(embedder Embedder, vectorStore VectorStore) *SemanticSear
 	}
 }
 
-// Search performs semantic search.
+// Search performs semantic search. Without a usable embedder it finds
+// nothing, leaving ranking to keyword search.
 func (ss *SemanticSearch) Search(ctx context.Context, query string, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	if IsNoopEmbedder(ss.embedder) {
+		return nil, nil
+	}
+
 	// Embed the query
 	embedResult, err := ss.embedder.Embed(ctx, query)
 	if err != nil {
@@ -68,6 +95,10 @@ func (ss *SemanticSearch) Search(ctx context.Context, query string, limit int, f
 
 // SearchBatch performs batch semantic search.
 func (ss *SemanticSearch) SearchBatch(ctx context.Context, queries []string, limit int) ([][]SearchResult, error) {
+	if IsNoopEmbedder(ss.embedder) {
+		return make([][]SearchResult, len(queries)), nil
+	}
+
 	// Embed all queries
 	embedResults, err := ss.embedder.EmbedBatch(ctx, queries)
 	if err != nil {
@@ -89,25 +120,7 @@ func (ss *SemanticSearch) SearchBatch(ctx context.Context, queries []string, lim
 
 // CosineSimilarity calculates cosine similarity between two vectors.
 func CosineSimilarity(a, b []float64) float64 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
-	}
-
-	var dotProduct, normA, normB float64
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
-	}
-
-	normA = math.Sqrt(normA)
-	normB = math.Sqrt(normB)
-
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-
-	return dotProduct / (normA * normB)
+	return utils.CosineSimilarity(a, b)
 }
 
 // EuclideanDistance calculates Euclidean distance between two vectors.
@@ -141,6 +154,8 @@ func DotProduct(a, b []float64) float64 {
 type InMemoryVectorStore struct {
 	vectors map[string][]float64
 	metadata map[string]map[string]interface{}
+	// entries keeps the abstract and shape of each added result
+	entries  map[string]SearchResult
 	dimension int
 	mu       sync.RWMutex
 }
@@ -150,28 +165,42 @@ func NewInMemoryVectorStore(dimension int) *InMemoryVectorStore {
 	return &InMemoryVectorStore{
 		vectors:  make(map[string][]float64),
 		metadata: make(map[string]map[string]interface{}),
+		entries:  make(map[string]SearchResult),
 		dimension: dimension,
 	}
 }
 
-// Search implements VectorStore interface.
+// Search implements VectorStore interface. Only entries whose metadata
+// holds every filter value are returned. Without a query vector, every
+// matching entry is listed unscored, in URI order.
 func (vs *InMemoryVectorStore) Search(ctx context.Context, query *EmbedResult, limit int, filter map[string]interface{}) ([]SearchResult, error) {
 	vs.mu.RLock()
 	defer vs.mu.RUnlock()
 
-	if query.DenseVector == nil || len(query.DenseVector) == 0 {
-		return []SearchResult{}, nil
+	if query == nil || len(query.DenseVector) == 0 {
+		results := []SearchResult{}
+		for uri, metadata := range vs.metadata {
+			if matchesFilter(metadata, filter) {
+				results = append(results, vs.result(uri, 0))
+			}
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].URI < results[j].URI
+		})
+		if limit > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+		return results, nil
 	}
 
 	var results []SearchResult
 
 	for uri, vector := range vs.vectors {
+		if !matchesFilter(vs.metadata[uri], filter) {
+			continue
+		}
 		score := CosineSimilarity(query.DenseVector, vector)
-		results = append(results, SearchResult{
-			URI:      uri,
-			Score:    score,
-			Metadata: vs.metadata[uri],
-		})
+		results = append(results, vs.result(uri, score))
 	}
 
 	// Sort by score descending
@@ -186,6 +215,32 @@ func (vs *InMemoryVectorStore) Search(ctx context.Context, query *EmbedResult, l
 	return results, nil
 }
 
+// result builds the search result for the entry at uri.
+func (vs *InMemoryVectorStore) result(uri string, score float64) SearchResult {
+	entry := vs.entries[uri]
+	return SearchResult{
+		URI:         uri,
+		Score:       score,
+		RawScore:    score,
+		HasRawScore: true,
+		Abstract:    entry.Abstract,
+		IsLeaf:      entry.IsLeaf,
+		ParentURI:   entry.ParentURI,
+		Metadata:    vs.metadata[uri],
+		Vector:      vs.vectors[uri],
+	}
+}
+
+// matchesFilter reports whether metadata holds every value in filter.
+func matchesFilter(metadata, filter map[string]interface{}) bool {
+	for key, value := range filter {
+		if !reflect.DeepEqual(metadata[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
 // Add implements VectorStore interface.
 func (vs *InMemoryVectorStore) Add(ctx context.Context, vectors []SearchResult) error {
 	vs.mu.Lock()
@@ -197,6 +252,7 @@ func (vs *InMemoryVectorStore) Add(ctx context.Context, vectors []SearchResult)
 			vs.vectors[v.URI] = vec
 		}
 		vs.metadata[v.URI] = v.Metadata
+		vs.entries[v.URI] = SearchResult{URI: v.URI, Abstract: v.Abstract, IsLeaf: v.IsLeaf, ParentURI: v.ParentURI}
 	}
 	return nil
 }
@@ -209,6 +265,7 @@ func (vs *InMemoryVectorStore) Delete(ctx context.Context, uris []string) error
 	for _, uri := range uris {
 		delete(vs.vectors, uri)
 		delete(vs.metadata, uri)
+		delete(vs.entries, uri)
 	}
 	return nil
 }
@@ -0,0 +1,356 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenizer names accepted by TokenizerConfigFor.
+const (
+	TokenizerEnglish = "english"
+	TokenizerRaw     = "raw"
+)
+
+// TokenizerConfig configures how text is split into keyword terms.
+type TokenizerConfig struct {
+	// Stem reduces terms to their Porter stem ("running" -> "run")
+	Stem bool
+
+	// RemoveStopwords drops common English words such as "the"
+	RemoveStopwords bool
+
+	// CJKBigrams splits runs of Chinese, Japanese and Korean characters,
+	// which have no spaces between words, into overlapping bigrams
+	CJKBigrams bool
+}
+
+// DefaultTokenizerConfig returns the raw tokenizer configuration, which
+// splits text into lowercase terms without stemming or stopwords.
+func DefaultTokenizerConfig() TokenizerConfig {
+	return TokenizerConfig{}
+}
+
+// EnglishTokenizerConfig returns the English tokenizer configuration.
+func EnglishTokenizerConfig() TokenizerConfig {
+	return TokenizerConfig{
+		Stem:            true,
+		RemoveStopwords: true,
+		CJKBigrams:      true,
+	}
+}
+
+// TokenizerConfigFor returns the configuration for a named tokenizer.
+// Unknown names fall back to the raw tokenizer.
+func TokenizerConfigFor(name string) TokenizerConfig {
+	if name == TokenizerEnglish {
+		return EnglishTokenizerConfig()
+	}
+	return DefaultTokenizerConfig()
+}
+
+// Tokenizer splits text into normalized keyword terms. The same tokenizer
+// must be used for indexing and querying so that terms line up.
+type Tokenizer struct {
+	config TokenizerConfig
+}
+
+// NewTokenizer creates a new Tokenizer.
+func NewTokenizer(config TokenizerConfig) *Tokenizer {
+	return &Tokenizer{config: config}
+}
+
+// Tokenize splits text into lowercase letter and number terms, then applies
+// stopword removal and stemming as configured.
+func (t *Tokenizer) Tokenize(text string) []string {
+	raw := splitTerms(text, t.config.CJKBigrams)
+	if !t.config.Stem && !t.config.RemoveStopwords {
+		return raw
+	}
+
+	terms := make([]string, 0, len(raw))
+	for _, term := range raw {
+		if t.config.RemoveStopwords && stopwords[term] {
+			continue
+		}
+		if t.config.Stem {
+			term = Stem(term)
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// isCJK reports whether r belongs to a script written without spaces
+// between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r == 'ー' // katakana prolonged sound mark
+}
+
+// splitTerms splits text into lowercase runs of Unicode letters and numbers.
+// CJK characters form separate runs, which are emitted whole or, with
+// bigrams set, as overlapping character pairs.
+func splitTerms(text string, bigrams bool) []string {
+	var terms []string
+	var run []rune
+	runCJK := false
+
+	flush := func() {
+		switch {
+		case len(run) == 0:
+		case runCJK && bigrams && len(run) > 1:
+			for i := 0; i+1 < len(run); i++ {
+				terms = append(terms, string(run[i:i+2]))
+			}
+		default:
+			terms = append(terms, string(run))
+		}
+		run = run[:0]
+	}
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.Is(unicode.Mn, r) {
+			flush()
+			continue
+		}
+		if cjk := isCJK(r); cjk != runCJK {
+			flush()
+			runCJK = cjk
+		}
+		run = append(run, unicode.ToLower(r))
+	}
+	flush()
+
+	return terms
+}
+
+// Stem reduces a lowercase English word to its stem using the Porter
+// stemming algorithm. Words containing non-letters are returned unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	word = stemStep1a(word)
+	word = stemStep1b(word)
+	word = stemStep1c(word)
+	word = stemStep2(word)
+	word = stemStep3(word)
+	word = stemStep4(word)
+	word = stemStep5(word)
+	return word
+}
+
+// isConsonant reports whether w[i] is a consonant in the Porter sense,
+// where "y" is a consonant only when it follows a vowel.
+func isConsonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure returns the number of vowel-consonant sequences in w.
+func measure(w string) int {
+	m := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel reports whether w contains a vowel.
+func hasVowel(w string) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant reports whether w ends with a doubled consonant.
+func endsDoubleConsonant(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant where the final
+// consonant is not w, x or y.
+func endsCVC(w string) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// stemRule replaces suffix with replacement when the stem measure exceeds
+// minMeasure.
+type stemRule struct {
+	suffix      string
+	replacement string
+}
+
+// applyStemRules applies the first rule whose suffix matches w, provided
+// the remaining stem has a measure greater than minMeasure. Rules are
+// ordered so that longer suffixes are tried first.
+func applyStemRules(w string, rules []stemRule, minMeasure int) string {
+	for _, r := range rules {
+		if !strings.HasSuffix(w, r.suffix) {
+			continue
+		}
+		stem := w[:len(w)-len(r.suffix)]
+		if measure(stem) > minMeasure {
+			return stem + r.replacement
+		}
+		return w
+	}
+	return w
+}
+
+func stemStep1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func stemStep1b(w string) string {
+	if strings.HasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem string
+	switch {
+	case strings.HasSuffix(w, "ed"):
+		stem = w[:len(w)-2]
+	case strings.HasSuffix(w, "ing"):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+	if !hasVowel(stem) {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case endsDoubleConsonant(stem):
+		switch stem[len(stem)-1] {
+		case 'l', 's', 'z':
+			return stem
+		}
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && endsCVC(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func stemStep1c(w string) string {
+	if strings.HasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		return w[:len(w)-1] + "i"
+	}
+	return w
+}
+
+var step2Rules = []stemRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func stemStep2(w string) string {
+	return applyStemRules(w, step2Rules, 0)
+}
+
+var step3Rules = []stemRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func stemStep3(w string) string {
+	return applyStemRules(w, step3Rules, 0)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func stemStep4(w string) string {
+	// Pick the longest matching suffix
+	match := ""
+	for _, suffix := range step4Suffixes {
+		if strings.HasSuffix(w, suffix) && len(suffix) > len(match) {
+			match = suffix
+		}
+	}
+	if match == "" {
+		return w
+	}
+
+	stem := w[:len(w)-len(match)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if match == "ion" && !strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "t") {
+		return w
+	}
+	return stem
+}
+
+func stemStep5(w string) string {
+	if strings.HasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if strings.HasSuffix(w, "ll") && measure(w) > 1 {
+		w = w[:len(w)-1]
+	}
+	return w
+}
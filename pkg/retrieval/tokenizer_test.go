@@ -0,0 +1,171 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"running", "run"},
+		{"runs", "run"},
+		{"hopping", "hop"},
+		{"agreed", "agre"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"generalizations", "gener"},
+		{"electrical", "electr"},
+		{"adjustment", "adjust"},
+		{"controlling", "control"},
+		{"go", "go"},
+		{"v2", "v2"},
+	}
+
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q): expected %q, got %q", tt.word, tt.want, got)
+		}
+	}
+}
+
+func TestTokenizerConfigs(t *testing.T) {
+	text := "The runner is Running the tests"
+
+	raw := NewTokenizer(TokenizerConfigFor(TokenizerRaw)).Tokenize(text)
+	wantRaw := []string{"the", "runner", "is", "running", "the", "tests"}
+	if !reflect.DeepEqual(raw, wantRaw) {
+		t.Errorf("Expected raw tokens %v, got %v", wantRaw, raw)
+	}
+
+	english := NewTokenizer(TokenizerConfigFor(TokenizerEnglish)).Tokenize(text)
+	wantEnglish := []string{"runner", "run", "test"}
+	if !reflect.DeepEqual(english, wantEnglish) {
+		t.Errorf("Expected english tokens %v, got %v", wantEnglish, english)
+	}
+}
+
+func TestKeywordSearchStemmedMatch(t *testing.T) {
+	idx := NewIndexWithTokenizer(NewTokenizer(EnglishTokenizerConfig()))
+	idx.AddDocument("viking://resources/a", "running services in production", nil)
+	idx.AddDocument("viking://resources/b", "writing documentation", nil)
+	idx.BuildIDF()
+
+	ks := NewKeywordSearch()
+	if score := ks.Score("run service", idx, "viking://resources/a"); score <= 0 {
+		t.Errorf("Expected stemmed query to match, got score %f", score)
+	}
+
+	rawIdx := NewIndex()
+	rawIdx.AddDocument("viking://resources/a", "running services in production", nil)
+	rawIdx.AddDocument("viking://resources/b", "writing documentation", nil)
+	rawIdx.BuildIDF()
+	if score := ks.Score("run service", rawIdx, "viking://resources/a"); score != 0 {
+		t.Errorf("Expected raw tokenizer not to match, got score %f", score)
+	}
+}
+
+func TestKeywordSearchIgnoresStopwords(t *testing.T) {
+	idx := NewIndexWithTokenizer(NewTokenizer(EnglishTokenizerConfig()))
+	idx.AddDocument("viking://resources/a", "the cache layer", nil)
+	idx.AddDocument("viking://resources/b", "cache eviction policy for the cache", nil)
+	idx.BuildIDF()
+
+	ks := NewKeywordSearch()
+	for _, uri := range []string{"viking://resources/a", "viking://resources/b"} {
+		plain := ks.Score("cache", idx, uri)
+		padded := ks.Score("the cache of the", idx, uri)
+		if plain != padded {
+			t.Errorf("Expected stopwords not to change score for %s: %f vs %f", uri, plain, padded)
+		}
+	}
+	// Stopwords don't count toward document length either
+	if idx.DocLengths["viking://resources/a"] != 2 {
+		t.Errorf("Expected document length 2, got %d", idx.DocLengths["viking://resources/a"])
+	}
+}
+
+func TestHybridSearchSetTokenizer(t *testing.T) {
+	docs := []SearchResult{{URI: "viking://resources/a", Abstract: "running services"}}
+
+	hs := NewHybridSearch(nil, 0.5)
+	hs.IndexDocuments(context.Background(), docs)
+	results, err := hs.Search(context.Background(), "run", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no matches with the default raw tokenizer, got %v", results)
+	}
+
+	hs.SetTokenizer(NewTokenizer(EnglishTokenizerConfig()))
+	hs.IndexDocuments(context.Background(), docs)
+	results, err = hs.Search(context.Background(), "run", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected a stemmed match with the English tokenizer, got %v", results)
+	}
+}
+
+func TestTokenizeUnicode(t *testing.T) {
+	english := NewTokenizer(EnglishTokenizerConfig())
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"chinese", "向量数据库", []string{"向量", "量数", "数据", "据库"}},
+		{"japanese", "東京タワー", []string{"東京", "京タ", "タワ", "ワー"}},
+		{"accented", "Café Über naïve", []string{"café", "über", "naïve"}},
+		{"mixed", "Go语言 v2", []string{"go", "语言", "v2"}},
+		{"single cjk", "猫", []string{"猫"}},
+	}
+
+	for _, tt := range tests {
+		if got := english.Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// Without bigrams CJK runs stay whole
+	if got := tokenize("向量数据库 search"); !reflect.DeepEqual(got, []string{"向量数据库", "search"}) {
+		t.Errorf("Expected whole CJK run, got %v", got)
+	}
+}
+
+func TestKeywordSearchUnicode(t *testing.T) {
+	idx := NewIndexWithTokenizer(NewTokenizer(EnglishTokenizerConfig()))
+	idx.AddDocument("viking://resources/zh", "分布式向量数据库的检索方法", nil)
+	idx.AddDocument("viking://resources/ja", "東京の天気予報を確認する", nil)
+	idx.AddDocument("viking://resources/fr", "Résumé du café à Zürich", nil)
+	idx.AddDocument("viking://resources/en", "plain english notes", nil)
+	idx.BuildIDF()
+
+	ks := NewKeywordSearch()
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"向量数据库", "viking://resources/zh"},
+		{"天気予報", "viking://resources/ja"},
+		{"zürich café", "viking://resources/fr"},
+	}
+
+	for _, tt := range tests {
+		results := ks.Search(context.Background(), tt.query, idx, 10, nil)
+		if len(results) != 1 || results[0].URI != tt.want {
+			t.Errorf("Query %q: expected only %s, got %v", tt.query, tt.want, results)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import "container/heap"
+
+// ranksBefore reports whether a ranks ahead of b: higher scores first,
+// with ties broken by URI so the order does not depend on discovery order.
+func ranksBefore(a, b RetrievalResult) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.URI < b.URI
+}
+
+// retrievalResultHeap implements heap.Interface with the lowest ranked
+// result at the root.
+type retrievalResultHeap []RetrievalResult
+
+func (h retrievalResultHeap) Len() int            { return len(h) }
+func (h retrievalResultHeap) Less(i, j int) bool  { return ranksBefore(h[j], h[i]) }
+func (h retrievalResultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *retrievalResultHeap) Push(x interface{}) { *h = append(*h, x.(RetrievalResult)) }
+func (h *retrievalResultHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// topK keeps the limit best results offered to it, in O(log limit) per
+// offer, so selecting them never sorts every candidate. It remembers every
+// URI offered, so a result found again is not offered twice.
+type topK struct {
+	limit   int
+	results retrievalResultHeap
+	offered map[string]bool
+}
+
+// newTopK creates a topK keeping up to limit results.
+func newTopK(limit int) *topK {
+	return &topK{limit: limit, offered: make(map[string]bool)}
+}
+
+// Offer adds r if it ranks among the best results so far, evicting the
+// lowest ranked one when full. It reports whether r's URI was new; a URI
+// offered before is ignored, even if it has since been evicted.
+func (t *topK) Offer(r RetrievalResult) bool {
+	if t.offered[r.URI] {
+		return false
+	}
+	t.offered[r.URI] = true
+	if t.limit <= 0 {
+		return true
+	}
+	if t.results.Len() < t.limit {
+		heap.Push(&t.results, r)
+		return true
+	}
+	if ranksBefore(r, t.results[0]) {
+		t.results[0] = r
+		heap.Fix(&t.results, 0)
+	}
+	return true
+}
+
+// URIs returns the set of URIs currently kept.
+func (t *topK) URIs() map[string]bool {
+	uris := make(map[string]bool, t.results.Len())
+	for _, r := range t.results {
+		uris[r.URI] = true
+	}
+	return uris
+}
+
+// Sorted returns the kept results best first, or nil if none were kept.
+// It empties t.
+func (t *topK) Sorted() []RetrievalResult {
+	if t.results.Len() == 0 {
+		return nil
+	}
+	sorted := make([]RetrievalResult, t.results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(&t.results).(RetrievalResult)
+	}
+	return sorted
+}
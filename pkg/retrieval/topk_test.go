@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomCandidates returns n candidates with distinct URIs in random order
+// whose scores, drawn from a few values, often tie.
+func randomCandidates(rng *rand.Rand, n int) []RetrievalResult {
+	candidates := make([]RetrievalResult, n)
+	ids := rng.Perm(n * 4)
+	for i := range candidates {
+		candidates[i] = RetrievalResult{
+			URI:   fmt.Sprintf("viking://resources/doc-%d", ids[i]),
+			Score: float64(rng.Intn(10)) / 10,
+		}
+	}
+	return candidates
+}
+
+// fullSortTopK selects the best limit candidates by sorting all of them.
+func fullSortTopK(candidates []RetrievalResult, limit int) []RetrievalResult {
+	sorted := append([]RetrievalResult(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return ranksBefore(sorted[i], sorted[j]) })
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func TestTopKMatchesFullSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 5, 50, 500} {
+		for _, limit := range []int{1, 3, 10, 100} {
+			t.Run(fmt.Sprintf("n=%d/limit=%d", n, limit), func(t *testing.T) {
+				candidates := randomCandidates(rng, n)
+				best := newTopK(limit)
+				for _, c := range candidates {
+					best.Offer(c)
+				}
+
+				got := best.Sorted()
+				want := fullSortTopK(candidates, limit)
+				if len(got) != len(want) {
+					t.Fatalf("Expected %d results, got %d", len(want), len(got))
+				}
+				for i := range want {
+					if got[i].URI != want[i].URI || got[i].Score != want[i].Score {
+						t.Fatalf("Expected %s (%.1f) at %d, got %s (%.1f)",
+							want[i].URI, want[i].Score, i, got[i].URI, got[i].Score)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTopKBreaksTiesByURI(t *testing.T) {
+	best := newTopK(2)
+	for _, uri := range []string{"viking://resources/c", "viking://resources/a", "viking://resources/b"} {
+		best.Offer(RetrievalResult{URI: uri, Score: 0.5})
+	}
+
+	got := best.Sorted()
+	if len(got) != 2 || got[0].URI != "viking://resources/a" || got[1].URI != "viking://resources/b" {
+		t.Errorf("Expected a then b, got %v", got)
+	}
+}
+
+func TestTopKIgnoresRepeatedURIs(t *testing.T) {
+	best := newTopK(1)
+	if !best.Offer(RetrievalResult{URI: "viking://resources/a", Score: 0.2}) {
+		t.Error("Expected a new URI accepted")
+	}
+	if !best.Offer(RetrievalResult{URI: "viking://resources/b", Score: 0.5}) {
+		t.Error("Expected a new URI accepted")
+	}
+	// a was evicted by b, but is still known
+	if best.Offer(RetrievalResult{URI: "viking://resources/a", Score: 0.9}) {
+		t.Error("Expected a repeated URI ignored")
+	}
+	if got := best.Sorted(); len(got) != 1 || got[0].URI != "viking://resources/b" {
+		t.Errorf("Expected only b kept, got %v", got)
+	}
+}
+
+func TestTopKZeroLimit(t *testing.T) {
+	best := newTopK(0)
+	best.Offer(RetrievalResult{URI: "viking://resources/a", Score: 1})
+	if got := best.Sorted(); got != nil {
+		t.Errorf("Expected no results, got %v", got)
+	}
+}
+
+func BenchmarkTopK(b *testing.B) {
+	candidates := randomCandidates(rand.New(rand.NewSource(1)), 10000)
+	const limit = 10
+
+	b.Run("heap", func(b *testing.B) {
+		for b.Loop() {
+			best := newTopK(limit)
+			for _, c := range candidates {
+				best.Offer(c)
+			}
+			best.Sorted()
+		}
+	})
+	b.Run("full_sort", func(b *testing.B) {
+		for b.Loop() {
+			fullSortTopK(candidates, limit)
+		}
+	})
+}
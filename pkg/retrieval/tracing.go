@@ -0,0 +1,57 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifies spans emitted by this package.
+const tracerName = "github.com/jqnote/goviking/pkg/retrieval"
+
+// Span names emitted during retrieval.
+const (
+	SpanRetrieve       = "retrieval.Retrieve"
+	SpanEmbed          = "retrieval.Embed"
+	SpanSearchChildren = "retrieval.searchChildren"
+)
+
+// Span attribute keys.
+const (
+	AttrQuery       = attribute.Key("retrieval.query")
+	AttrContextType = attribute.Key("retrieval.context_type")
+	AttrParentURI   = attribute.Key("retrieval.parent_uri")
+	AttrResultCount = attribute.Key("retrieval.result_count")
+	AttrResultURIs  = attribute.Key("retrieval.result_uris")
+	// AttrEmbeddingCacheHit is set on embed spans when embeddings are cached
+	AttrEmbeddingCacheHit = attribute.Key("retrieval.embedding_cache_hit")
+)
+
+// newTracer returns a tracer from tp, or a no-op tracer when tp is nil.
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// resultAttributes describes a list of result URIs.
+func resultAttributes(uris []string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttrResultCount.Int(len(uris)),
+		AttrResultURIs.StringSlice(uris),
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttr returns the value of key on span, or an invalid value.
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestRetrieveEmitsSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	store := &chainStore{width: 0, maxLevel: 1}
+	hr := NewHierarchicalRetriever(&fixedEmbedder{vector: []float64{1}}, store, DefaultRetrieverConfig())
+	hr.SetTracerProvider(tp)
+
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://root-0000"}
+	if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "leaf", ContextType: ContextTypeResource}, opts); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	for _, name := range []string{SpanRetrieve, SpanEmbed, SpanSearchChildren} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("Expected span %s, got %d spans", name, len(recorder.Ended()))
+		}
+	}
+
+	root := spans[SpanRetrieve]
+	if got := spanAttr(root, AttrQuery).AsString(); got != "leaf" {
+		t.Errorf("Expected query attribute leaf, got %q", got)
+	}
+	if got := spanAttr(root, AttrContextType).AsString(); got != "resource" {
+		t.Errorf("Expected context type attribute resource, got %q", got)
+	}
+	if got := spanAttr(root, AttrResultCount).AsInt64(); got != 1 {
+		t.Errorf("Expected result count 1, got %d", got)
+	}
+	wantURIs := []string{"viking://root-0000/leaf"}
+	if got := spanAttr(root, AttrResultURIs).AsStringSlice(); !reflect.DeepEqual(got, wantURIs) {
+		t.Errorf("Expected result URIs %v, got %v", wantURIs, got)
+	}
+
+	children := spans[SpanSearchChildren]
+	if got := spanAttr(children, AttrParentURI).AsString(); got != "viking://root-0000" {
+		t.Errorf("Expected parent URI attribute, got %q", got)
+	}
+	if got := spanAttr(children, AttrResultURIs).AsStringSlice(); !reflect.DeepEqual(got, wantURIs) {
+		t.Errorf("Expected child URIs %v, got %v", wantURIs, got)
+	}
+
+	// Child spans share the trace and hang off the retrieve span
+	for _, name := range []string{SpanEmbed, SpanSearchChildren} {
+		if spans[name].Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of %s", name, SpanRetrieve)
+		}
+	}
+}
+
+func TestRetrieveWithoutTracer(t *testing.T) {
+	hr := NewHierarchicalRetriever(nil, &chainStore{maxLevel: 1}, DefaultRetrieverConfig())
+	opts := DefaultSearchOptions()
+	opts.TargetDirectories = []string{"viking://root-0000"}
+	if _, err := hr.Retrieve(context.Background(), TypedQuery{Query: "leaf"}, opts); err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+}
@@ -6,17 +6,45 @@ package retrieval
 
 import (
 	"time"
+
+	"github.com/jqnote/goviking/pkg/core"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // ContextType represents the type of context.
 type ContextType string
 
 const (
-	ContextTypeMemory   ContextType = "memory"
-	ContextTypeResource ContextType = "resource"
-	ContextTypeSkill    ContextType = "skill"
+	ContextTypeMemory   ContextType = utils.ContextTypeMemory
+	ContextTypeResource ContextType = utils.ContextTypeResource
+	ContextTypeSkill    ContextType = utils.ContextTypeSkill
 )
 
+// Valid reports whether t is a known context type.
+func (t ContextType) Valid() bool {
+	return utils.IsValidContextType(string(t))
+}
+
+// ParseContextType converts a context type name to a ContextType. Case is
+// ignored; unknown names return utils.ErrInvalidContextType.
+func ParseContextType(s string) (ContextType, error) {
+	name, err := utils.ParseContextType(s)
+	if err != nil {
+		return "", err
+	}
+	return ContextType(name), nil
+}
+
+// FromCoreContextType converts a core context type to a retrieval one.
+func FromCoreContextType(t core.ContextType) (ContextType, error) {
+	return ParseContextType(string(t))
+}
+
+// Core converts t to the equivalent core context type.
+func (t ContextType) Core() (core.ContextType, error) {
+	return core.ParseContextType(string(t))
+}
+
 // RetrieverMode defines the retrieval mode.
 type RetrieverMode string
 
@@ -56,6 +84,7 @@ type MatchedContext struct {
 	Overview    string           `json:"overview,omitempty"`
 	Category    string           `json:"category"`
 	Score       float64          `json:"score"`
+	RawScore    float64          `json:"raw_score"`
 	MatchReason string           `json:"match_reason,omitempty"`
 	Relations   []RelatedContext `json:"relations,omitempty"`
 }
@@ -66,6 +95,7 @@ type QueryResult struct {
 	MatchedContexts    []MatchedContext  `json:"matched_contexts"`
 	SearchedDirectories []string         `json:"searched_directories"`
 	ThinkingTrace     *ThinkingTrace    `json:"thinking_trace,omitempty"`
+	Trajectory        *Trajectory       `json:"trajectory,omitempty"`
 }
 
 // FindResult represents final result from search.
@@ -92,6 +122,8 @@ const (
 	TraceEventConvergenceCheck      TraceEventType = "convergence_check"
 	TraceEventSearchConverged       TraceEventType = "search_converged"
 	TraceEventSearchSummary         TraceEventType = "search_summary"
+	TraceEventTraversalLimit        TraceEventType = "traversal_limit"
+	TraceEventEmbeddingFailed       TraceEventType = "embedding_failed"
 )
 
 // TraceEvent represents a single trace event.
@@ -162,7 +194,18 @@ type SearchOptions struct {
 	ScoreThreshold    float64
 	ScoreGTE          bool
 	TargetDirectories []string
+	// MetadataFilter is what leaf results must match. It is passed to the
+	// vector store alongside the parent_uri of the directory searched;
+	// subdirectories are traversed whether they match or not
 	MetadataFilter    map[string]interface{}
+	// Diversify re-ranks results with maximal marginal relevance so near
+	// duplicates give way to distinct results
+	Diversify bool
+	// DiversityLambda trades relevance (1) against novelty (0); nil uses 0.5
+	DiversityLambda *float64
+	// LeavesOnly keeps directories out of the results; they are still
+	// searched for the leaves below them
+	LeavesOnly bool
 }
 
 // DefaultSearchOptions returns default search options.
@@ -0,0 +1,46 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package retrieval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/core"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+func TestContextTypeRoundTrip(t *testing.T) {
+	for _, ct := range []core.ContextType{core.ContextTypeMemory, core.ContextTypeResource, core.ContextTypeSkill} {
+		rt, err := FromCoreContextType(ct)
+		if err != nil {
+			t.Fatalf("FromCoreContextType(%q) failed: %v", ct, err)
+		}
+		if !rt.Valid() {
+			t.Errorf("Expected %q to be valid", rt)
+		}
+		back, err := rt.Core()
+		if err != nil {
+			t.Fatalf("Core() of %q failed: %v", rt, err)
+		}
+		if back != ct {
+			t.Errorf("Expected round trip to %q, got %q", ct, back)
+		}
+	}
+}
+
+func TestContextTypeRejectsUnknown(t *testing.T) {
+	if _, err := FromCoreContextType("document"); !errors.Is(err, utils.ErrInvalidContextType) {
+		t.Errorf("Expected ErrInvalidContextType, got %v", err)
+	}
+	if _, err := ContextType("Memories").Core(); !errors.Is(err, utils.ErrInvalidContextType) {
+		t.Errorf("Expected ErrInvalidContextType, got %v", err)
+	}
+	if ContextType("Memory").Valid() {
+		t.Error("Expected non-canonical spelling to be invalid")
+	}
+	if ct, err := ParseContextType("Memory"); err != nil || ct != ContextTypeMemory {
+		t.Errorf("Expected memory, got %q, %v", ct, err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListContextsPaged(t *testing.T) {
+	c := newRelationTestClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		limit, offset, items int
+	}{
+		{0, 0, 3},
+		{2, 0, 2},
+		{2, 2, 1},
+		{2, 5, 0},
+		{0, 1, 2},
+	}
+	for _, tt := range tests {
+		page, err := c.Contexts.ListPaged(ctx, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("ListPaged(%d, %d) failed: %v", tt.limit, tt.offset, err)
+		}
+		if len(page.Items) != tt.items || page.Total != 3 {
+			t.Errorf("ListPaged(%d, %d): Expected %d items of 3, got %d of %d", tt.limit, tt.offset, tt.items, len(page.Items), page.Total)
+		}
+	}
+}
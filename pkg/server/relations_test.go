@@ -0,0 +1,136 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/client"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// newRelationTestClient serves a SQLite-backed server holding three
+// contexts and returns a client for it.
+func newRelationTestClient(t *testing.T) *client.Client {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(storage.Config{
+		DBPath:       filepath.Join(t.TempDir(), "relations.db"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ts := httptest.NewServer(New(store, nil).router)
+	t.Cleanup(ts.Close)
+	c, err := client.NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := c.Contexts.Create(context.Background(), &client.Context{URI: "viking://resources/" + name}); err != nil {
+			t.Fatalf("Failed to create context %s: %v", name, err)
+		}
+	}
+	return c
+}
+
+// relationIDs returns the sorted IDs of relations.
+func relationIDs(relations []client.Relation) []string {
+	ids := make([]string, len(relations))
+	for i, rel := range relations {
+		ids[i] = rel.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestRelationRoutes(t *testing.T) {
+	c := newRelationTestClient(t)
+	ctx := context.Background()
+	a, b, cURI := "viking://resources/a", "viking://resources/b", "viking://resources/c"
+
+	ab, err := c.Relations.Create(ctx, &client.Relation{Source: a, Target: b, Reason: "a cites b"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if ab.ID == "" || ab.Source != a || ab.Target != b || ab.Reason != "a cites b" {
+		t.Errorf("Expected the created relation back with an ID, got %+v", ab)
+	}
+	cb, err := c.Relations.Create(ctx, &client.Relation{Source: cURI, Target: b, Bidirectional: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	tests := []struct {
+		uri, direction string
+		want           []string
+	}{
+		{b, "", []string{ab.ID, cb.ID}},
+		{b, "incoming", []string{ab.ID, cb.ID}},
+		{b, "outgoing", []string{cb.ID}},
+		{a, "outgoing", []string{ab.ID}},
+		{a, "incoming", []string{}},
+		{cURI, "incoming", []string{cb.ID}},
+	}
+	for _, tt := range tests {
+		relations, err := c.Relations.List(ctx, tt.uri, tt.direction)
+		if err != nil {
+			t.Fatalf("List(%s, %q) failed: %v", tt.uri, tt.direction, err)
+		}
+		sort.Strings(tt.want)
+		if got := relationIDs(relations); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("List(%s, %q): Expected %v, got %v", tt.uri, tt.direction, tt.want, got)
+		}
+	}
+
+	// Bidirectional relations can be deleted from either end
+	if err := c.Relations.DeleteBetween(ctx, b, cURI); err != nil {
+		t.Fatalf("DeleteBetween failed: %v", err)
+	}
+	if err := c.Relations.Delete(ctx, ab.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if relations, err := c.Relations.List(ctx, b, ""); err != nil || len(relations) != 0 {
+		t.Errorf("Expected no relations left, got %v (%v)", relations, err)
+	}
+	if err := c.Relations.Delete(ctx, ab.ID); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected deleting a missing relation to fail with 404, got %v", err)
+	}
+}
+
+func TestRelationRoutesValidate(t *testing.T) {
+	c := newRelationTestClient(t)
+	ctx := context.Background()
+	a := "viking://resources/a"
+
+	tests := []struct {
+		name string
+		req  *client.Relation
+		code string
+	}{
+		{"missing target", &client.Relation{Source: a}, "400"},
+		{"self relation", &client.Relation{Source: a, Target: a}, "400"},
+		{"unknown target", &client.Relation{Source: a, Target: "viking://resources/missing"}, "404"},
+		{"unknown source", &client.Relation{Source: "viking://resources/missing", Target: a}, "404"},
+	}
+	for _, tt := range tests {
+		if _, err := c.Relations.Create(ctx, tt.req); err == nil || !strings.Contains(err.Error(), tt.code) {
+			t.Errorf("%s: Expected a %s error, got %v", tt.name, tt.code, err)
+		}
+	}
+	if _, err := c.Relations.List(ctx, a, "sideways"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected an invalid direction to fail with 400, got %v", err)
+	}
+}
@@ -5,31 +5,64 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"mime"
+	"net"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+
+	"github.com/jqnote/goviking/pkg/agfs"
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/service"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
+// HeaderEffectiveLimit carries the number of results a search route
+// applied, after defaulting and clamping the requested limit to the
+// search service's results cap.
+const HeaderEffectiveLimit = "X-Effective-Limit"
+
 // Server is the GoViking HTTP server.
 type Server struct {
 	router   *mux.Router
 	server   *http.Server
+	fs       *agfs.Client
+	store    storage.StorageInterface
+	search   *service.SearchService
+	conns    atomic.Int64
 }
 
-// New creates a new server.
-func New() *Server {
+// New creates a new server backed by store for contexts, export and
+// import, and by fs for the FS routes. Either may be nil, in which case
+// its routes answer 503.
+func New(store storage.StorageInterface, fs *agfs.Client) *Server {
 	r := mux.NewRouter()
 	s := &Server{
 		router: r,
+		store:  auditedStore(store),
+		fs:     fs,
 		server: &http.Server{
 			Handler: r,
 			Addr:    ":8080",
 		},
 	}
+	s.server.ConnState = s.trackConn
 	s.setupRoutes()
 	return s
 }
@@ -39,21 +72,45 @@ func (s *Server) SetAddr(addr string) {
 	s.server.Addr = addr
 }
 
+// SetStorage sets the storage backing the context, export and import routes.
+func (s *Server) SetStorage(store storage.StorageInterface) {
+	s.store = auditedStore(store)
+}
+
+// auditedStore wraps store so that every context, memory and session
+// mutation made through it is recorded in its audit log.
+func auditedStore(store storage.StorageInterface) storage.StorageInterface {
+	if store == nil {
+		return nil
+	}
+	return service.NewAuditedStore(store, service.NewAuditLogger(store))
+}
+
+// SetSearchService sets the search service backing the search route.
+func (s *Server) SetSearchService(search *service.SearchService) {
+	s.search = search
+}
+
 // setupRoutes sets up the HTTP routes.
 func (s *Server) setupRoutes() {
+	s.router.Use(withActor)
+
 	// Health check
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	s.router.HandleFunc("/health/ready", s.handleReady).Methods("GET")
 
 	// Context routes
 	s.router.HandleFunc("/api/v1/contexts", s.handleListContexts).Methods("GET")
 	s.router.HandleFunc("/api/v1/contexts", s.handleCreateContext).Methods("POST")
 	s.router.HandleFunc("/api/v1/contexts/{id}", s.handleGetContext).Methods("GET")
+	s.router.HandleFunc("/api/v1/contexts/{id}", s.handleHeadContext).Methods("HEAD")
 	s.router.HandleFunc("/api/v1/contexts/{id}", s.handleDeleteContext).Methods("DELETE")
 
 	// Session routes
 	s.router.HandleFunc("/api/v1/sessions", s.handleListSessions).Methods("GET")
 	s.router.HandleFunc("/api/v1/sessions", s.handleCreateSession).Methods("POST")
 	s.router.HandleFunc("/api/v1/sessions/{id}", s.handleGetSession).Methods("GET")
+	s.router.HandleFunc("/api/v1/sessions/{id}/fork", s.handleForkSession).Methods("POST")
 
 	// FS routes
 	s.router.HandleFunc("/api/v1/fs/list", s.handleFSList).Methods("GET")
@@ -63,6 +120,23 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("/api/v1/fs/delete", s.handleFSDelete).Methods("DELETE")
 	s.router.HandleFunc("/api/v1/fs/move", s.handleFSMove).Methods("POST")
 	s.router.HandleFunc("/api/v1/fs/tree", s.handleFSTree).Methods("GET")
+
+	// Search routes
+	s.router.HandleFunc("/api/v1/search", s.handleSearch).Methods("GET")
+	s.router.HandleFunc("/api/v1/search/explain", s.handleSearchExplain).Methods("GET")
+	s.router.HandleFunc("/api/v1/find", s.handleFind).Methods("POST")
+
+	// Relation routes
+	s.router.HandleFunc("/api/v1/relations", s.handleListRelations).Methods("GET")
+	s.router.HandleFunc("/api/v1/relations", s.handleCreateRelation).Methods("POST")
+	s.router.HandleFunc("/api/v1/relations", s.handleDeleteRelation).Methods("DELETE")
+
+	// Audit routes
+	s.router.HandleFunc("/api/v1/audit", s.handleListAudit).Methods("GET")
+
+	// Backup routes
+	s.router.HandleFunc("/api/v1/export", s.handleExport).Methods("GET")
+	s.router.HandleFunc("/api/v1/import", s.handleImport).Methods("POST")
 }
 
 // Start starts the server.
@@ -81,11 +155,37 @@ func (s *Server) StartTLS(addr, certFile, keyFile string) error {
 	return s.server.ListenAndServeTLS(certFile, keyFile)
 }
 
+// Serve accepts connections on l.
+func (s *Server) Serve(l net.Listener) error {
+	return s.server.Serve(l)
+}
+
 // Shutdown shuts down the server gracefully.
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+// Close closes the server and all its connections immediately.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// ActiveConnections returns the number of open client connections,
+// including idle keep-alive ones.
+func (s *Server) ActiveConnections() int {
+	return int(s.conns.Load())
+}
+
+// trackConn counts open connections.
+func (s *Server) trackConn(c net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.conns.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		s.conns.Add(-1)
+	}
+}
+
 // handleHealth handles health check requests.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -95,37 +195,221 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleReady reports whether the server can serve requests, which means
+// its storage, if any, answers a ping. It returns 503 when it cannot.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	status := map[string]string{
+		"status": "ready",
+		"time":   time.Now().Format(time.RFC3339),
+	}
+	code := http.StatusOK
+	if s.store != nil {
+		if err := s.store.Ping(r.Context()); err != nil {
+			status["status"] = "unavailable"
+			status["error"] = err.Error()
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// withActor passes the user a client identifies itself as, in its
+// utils.HeaderUser header, on to the request's context as the actor of
+// the mutations it makes. Requests without one are audited as
+// service.AnonymousActor. The header is self-asserted: any caller can set
+// it, so the actor records who a client claims to be, not an
+// authenticated identity.
+func withActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := r.Header.Get(utils.HeaderUser); actor != "" {
+			r = r.WithContext(service.WithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionID returns the session a request names explicitly, or else the
+// one its client identifies itself with in the utils.HeaderSession header.
+func sessionID(r *http.Request, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return r.Header.Get(utils.HeaderSession)
+}
+
+// auditLogger returns the logger reading the audit log of the storage.
+func (s *Server) auditLogger() *service.AuditLogger {
+	return service.NewAuditLogger(s.store)
+}
+
 // Context handlers
+
+// handleListContexts lists stored contexts. Query parameters: limit,
+// offset and context_type. The X-Total-Count header carries the number of
+// contexts matching context_type regardless of limit and offset.
 func (s *Server) handleListContexts(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := storage.QueryOptions{OrderBy: "created_at"}
+	var err error
+	if opts.Limit, err = intParam(q.Get("limit")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+		return
+	}
+	if opts.Offset, err = intParam(q.Get("offset")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid offset: %v", err), http.StatusBadRequest)
+		return
+	}
+	if name := q.Get("context_type"); name != "" {
+		contextType, err := utils.ParseContextType(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Filter = &storage.Filter{Conds: []storage.FilterCondition{
+			{Op: "must", Field: "context_type", Value: contextType},
+		}}
+	}
+
+	contexts, err := s.store.QueryContexts(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if contexts == nil {
+		contexts = []storage.Context{}
+	}
+	total, err := s.store.CountContexts(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(utils.HeaderTotalCount, strconv.Itoa(total))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]interface{}{})
+	json.NewEncoder(w).Encode(contexts)
 }
 
+// handleCreateContext stores a context. A uri is required; the id and
+// timestamps are filled in when missing.
 func (s *Server) handleCreateContext(w http.ResponseWriter, r *http.Request) {
-	var req map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var c storage.Context
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if c.URI == "" {
+		http.Error(w, "uri is required", http.StatusBadRequest)
+		return
+	}
+	if c.ContextType != "" {
+		contextType, err := utils.ParseContextType(c.ContextType)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.ContextType = contextType
+	}
+
+	if c.ID == "" {
+		c.ID = utils.GenerateID()
+	} else if exists, err := s.store.ContextExists(r.Context(), c.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if exists {
+		http.Error(w, fmt.Sprintf("context %s already exists", c.ID), http.StatusConflict)
+		return
+	}
+	now := utils.Now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	if c.UpdatedAt.IsZero() {
+		c.UpdatedAt = now
+	}
+
+	if err := s.store.CreateContext(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(req)
+	json.NewEncoder(w).Encode(c)
 }
 
 func (s *Server) handleGetContext(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":   id,
-		"uri":  fmt.Sprintf("viking://context/%s", id),
-		"name": "sample",
-	})
+	id := mux.Vars(r)["id"]
+	c, err := s.store.GetContext(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.Error(w, fmt.Sprintf("context %s not found", id), http.StatusNotFound)
+		return
+	}
+
+	writeJSONWithETag(w, r, c)
+}
+
+// handleHeadContext reports whether a context exists with 200 or 404. It
+// checks existence only and never loads the context itself.
+func (s *Server) handleHeadContext(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	exists, err := s.store.ContextExists(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) handleDeleteContext(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	exists, err := s.store.ContextExists(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, fmt.Sprintf("context %s not found", id), http.StatusNotFound)
+		return
+	}
+	if err := s.store.DeleteContext(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -159,19 +443,101 @@ func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// FS handlers
+// handleForkSession copies the messages of a session up to and including
+// upto_message_index into a new session that references it in its
+// metadata.
+func (s *Server) handleForkSession(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		UptoMessageIndex *int `json:"upto_message_index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.UptoMessageIndex == nil {
+		http.Error(w, "upto_message_index is required", http.StatusBadRequest)
+		return
+	}
+
+	sessions := service.NewSessionService()
+	sessions.SetStorage(s.store)
+	fork, err := sessions.ForkSession(r.Context(), mux.Vars(r)["id"], *req.UptoMessageIndex)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrInvalidSession):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fork)
+}
+
+// FS handlers take AGFS URIs such as viking://resources/docs as paths; a
+// path starting with / is read as a URI below viking://.
+
+// writeFSError replies with the status matching an AGFS error.
+func writeFSError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, agfs.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, agfs.ErrAlreadyExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, agfs.ErrInvalidURI), errors.Is(err, agfs.ErrIsDirectory), errors.Is(err, agfs.ErrNotADirectory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) handleFSList(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	path := r.URL.Query().Get("path")
 	if path == "" {
 		path = "/"
 	}
 
-	// Use service.FSService if available, otherwise return empty
+	// The listing shows the directory's children and their sizes
+	modTime, err := s.fs.TreeModTime(path, 1)
+	if err != nil {
+		writeFSError(w, err)
+		return
+	}
+	if notModified(w, r, modTime) {
+		return
+	}
+
+	entries, err := s.fs.ListDir(path)
+	if err != nil {
+		writeFSError(w, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]interface{}{})
+	json.NewEncoder(w).Encode(entries)
 }
 
 func (s *Server) handleFSMkdir(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	var req struct {
 		Path string `json:"path"`
 	}
@@ -179,6 +545,15 @@ func (s *Server) handleFSMkdir(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.Path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.fs.CreateDirAll(req.Path); err != nil {
+		writeFSError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -187,20 +562,146 @@ func (s *Server) handleFSMkdir(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleFSRead(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	path := r.URL.Query().Get("path")
 	if path == "" {
 		http.Error(w, "path is required", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	data, err := s.fs.AGFS().Read(path, 0, -1)
+	if err != nil {
+		writeFSError(w, err)
+		return
+	}
+
+	// Stream raw bytes when the client asks for them
+	if wantsRaw(r) {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": filepath.Base(path),
+		}))
+		writeWithETag(w, r, detectContentType(path, data), data)
+		return
+	}
+
+	resp := map[string]string{
 		"path":    path,
-		"content": "",
-	})
+		"content": string(data),
+	}
+	if isBinary(data) {
+		resp["content"] = base64.StdEncoding.EncodeToString(data)
+		resp["encoding"] = "base64"
+	}
+
+	writeJSONWithETag(w, r, resp)
+}
+
+// writeJSONWithETag encodes v as JSON and writes it with an ETag.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeWithETag(w, r, "application/json", buf.Bytes())
+}
+
+// writeWithETag writes body tagged with a checksum ETag, replying 304 Not
+// Modified when the request's If-None-Match already names that ETag.
+func writeWithETag(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	etag := computeETag(body)
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// computeETag returns a strong ETag derived from the SHA-256 of data.
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Weak comparison is used, as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets Cache-Control and a Last-Modified of modTime, and
+// replies 304 Not Modified, reporting true, when the request's
+// If-Modified-Since is no older than modTime.
+func notModified(w http.ResponseWriter, r *http.Request, modTime time.Time) bool {
+	w.Header().Set("Cache-Control", "no-cache")
+	if modTime.IsZero() {
+		return false
+	}
+	// HTTP dates have whole seconds
+	modTime = modTime.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modTime.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// wantsRaw reports whether a read request asks for raw file bytes.
+func wantsRaw(r *http.Request) bool {
+	if r.URL.Query().Get("raw") == "true" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/octet-stream")
+}
+
+// detectContentType returns the MIME type of a file, preferring its
+// extension and falling back to content sniffing.
+func detectContentType(path string, data []byte) string {
+	if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(data)
+}
+
+// isBinary reports whether data cannot be safely returned as a JSON string.
+func isBinary(data []byte) bool {
+	if !utf8.Valid(data) {
+		return true
+	}
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Server) handleFSWrite(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	var req struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
@@ -209,6 +710,15 @@ func (s *Server) handleFSWrite(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.Path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.fs.WriteText(req.Path, req.Content); err != nil {
+		writeFSError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -216,13 +726,25 @@ func (s *Server) handleFSWrite(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleFSDelete deletes a file, or a directory with recursive=true when
+// it is not empty.
 func (s *Server) handleFSDelete(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	path := r.URL.Query().Get("path")
 	if path == "" {
 		http.Error(w, "path is required", http.StatusBadRequest)
 		return
 	}
 
+	if err := s.fs.AGFS().Delete(path, r.URL.Query().Get("recursive") == "true"); err != nil {
+		writeFSError(w, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"path": path,
@@ -230,6 +752,11 @@ func (s *Server) handleFSDelete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleFSMove(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	var req struct {
 		From string `json:"from"`
 		To   string `json:"to"`
@@ -238,6 +765,15 @@ func (s *Server) handleFSMove(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.From == "" || req.To == "" {
+		http.Error(w, "from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.fs.Rename(req.From, req.To); err != nil {
+		writeFSError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -246,15 +782,446 @@ func (s *Server) handleFSMove(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleFSTree returns the directory tree below path, limited to depth
+// levels when depth is given.
 func (s *Server) handleFSTree(w http.ResponseWriter, r *http.Request) {
+	if s.fs == nil {
+		http.Error(w, "filesystem not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	path := r.URL.Query().Get("path")
 	if path == "" {
 		path = "/"
 	}
+	depth, err := intParam(r.URL.Query().Get("depth"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid depth: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	modTime, err := s.fs.TreeModTime(path, depth)
+	if err != nil {
+		writeFSError(w, err)
+		return
+	}
+	if notModified(w, r, modTime) {
+		return
+	}
+
+	tree, err := s.fs.GetTree(path, depth)
+	if err != nil {
+		writeFSError(w, err)
+		return
+	}
+	if tree == nil {
+		tree = []agfs.TreeEntry{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"path": path,
-		"tree": "",
+		"tree": tree,
+	})
+}
+
+// handleSearch searches contexts. Query parameters: q (required), limit,
+// offset, session_id, personalize, type, and keyword_weight and
+// hotness_weight to override the configured score blend. The session
+// defaults to the utils.HeaderSession header.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if s.search == nil {
+		http.Error(w, "search not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := s.searchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.search.Search(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderEffectiveLimit, strconv.Itoa(req.Limit))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleSearchExplain searches contexts like handleSearch and also returns
+// the directories the retriever visited and where it converged or pruned.
+func (s *Server) handleSearchExplain(w http.ResponseWriter, r *http.Request) {
+	if s.search == nil {
+		http.Error(w, "search not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := s.searchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := s.search.Explain(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderEffectiveLimit, strconv.Itoa(req.Limit))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
+
+// searchRequest builds a search request from the search query parameters
+// of r.
+func (s *Server) searchRequest(r *http.Request) (*service.SearchRequest, error) {
+	q := r.URL.Query()
+	req := &service.SearchRequest{
+		Query:       q.Get("q"),
+		SessionID:   sessionID(r, q.Get("session_id")),
+		Personalize: q.Get("personalize") == "true",
+	}
+	if req.Query == "" {
+		return nil, fmt.Errorf("missing query parameter q")
+	}
+	if t := q.Get("type"); t != "" {
+		req.Filters = map[string]string{"type": t}
+	}
+
+	var err error
+	if req.Limit, err = intParam(q.Get("limit")); err != nil {
+		return nil, fmt.Errorf("invalid limit: %v", err)
+	}
+	if req.Offset, err = intParam(q.Get("offset")); err != nil {
+		return nil, fmt.Errorf("invalid offset: %v", err)
+	}
+
+	if q.Has("keyword_weight") || q.Has("hotness_weight") {
+		weights := s.search.FusionWeights()
+		if v := q.Get("keyword_weight"); v != "" {
+			if weights.Keyword, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("invalid keyword_weight: %v", err)
+			}
+		}
+		if v := q.Get("hotness_weight"); v != "" {
+			if weights.Hotness, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("invalid hotness_weight: %v", err)
+			}
+		}
+		if err := weights.Validate(); err != nil {
+			return nil, err
+		}
+		req.Weights = &weights
+	}
+
+	return req, nil
+}
+
+// handleFind searches memories, resources and skills in one call and
+// returns the matches grouped by type. The JSON body holds query
+// (required), session_id, context_types, limit, and trace to include the
+// query plan and per-query results. The session defaults to the
+// utils.HeaderSession header.
+func (s *Server) handleFind(w http.ResponseWriter, r *http.Request) {
+	if s.search == nil {
+		http.Error(w, "search not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		Query        string   `json:"query"`
+		SessionID    string   `json:"session_id"`
+		ContextTypes []string `json:"context_types"`
+		Limit        int      `json:"limit"`
+		Trace        bool     `json:"trace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	if body.Limit < 0 {
+		http.Error(w, "invalid limit: must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	req := &service.FindRequest{
+		Query:     body.Query,
+		SessionID: sessionID(r, body.SessionID),
+		Limit:     body.Limit,
+		Trace:     body.Trace,
+	}
+	for _, name := range body.ContextTypes {
+		contextType, err := retrieval.ParseContextType(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ContextTypes = append(req.ContextTypes, contextType)
+	}
+
+	result, err := s.search.Find(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderEffectiveLimit, strconv.Itoa(req.Limit))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// intParam parses an optional non-negative integer query parameter.
+func intParam(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return n, nil
+}
+
+// Relation handlers
+
+// relations returns the service managing relations in the storage.
+func (s *Server) relations() *service.RelationService {
+	return service.NewRelationServiceWithStore(s.store)
+}
+
+// uriExists reports whether uri names a stored context or, when a
+// filesystem is configured, a file or directory.
+func (s *Server) uriExists(ctx context.Context, uri string) (bool, error) {
+	contexts, err := s.store.QueryContexts(ctx, storage.QueryOptions{
+		Filter: &storage.Filter{Conds: []storage.FilterCondition{
+			{Op: "must", Field: "uri", Value: uri},
+		}},
+		Limit: 1,
 	})
+	if err != nil {
+		return false, err
+	}
+	if len(contexts) > 0 {
+		return true, nil
+	}
+	return s.fs != nil && s.fs.FileExists(uri), nil
+}
+
+// handleListRelations lists the relations of a URI. Query parameters: uri
+// (required) and direction, one of outgoing, incoming or both (default).
+func (s *Server) handleListRelations(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	uri := q.Get("uri")
+	if uri == "" {
+		http.Error(w, "uri is required", http.StatusBadRequest)
+		return
+	}
+	direction := q.Get("direction")
+	switch direction {
+	case "":
+		direction = service.DirectionBoth
+	case service.DirectionOutgoing, service.DirectionIncoming, service.DirectionBoth:
+	default:
+		http.Error(w, fmt.Sprintf("invalid direction: %s", direction), http.StatusBadRequest)
+		return
+	}
+
+	relations, err := s.relations().ListRelations(r.Context(), uri, direction)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relations)
+}
+
+// handleCreateRelation links a source to a target. Both URIs are required
+// and must exist.
+func (s *Server) handleCreateRelation(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req service.Relation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" || req.Target == "" {
+		http.Error(w, "source and target are required", http.StatusBadRequest)
+		return
+	}
+	if req.Source == req.Target {
+		http.Error(w, "source and target must differ", http.StatusBadRequest)
+		return
+	}
+	for _, uri := range []string{req.Source, req.Target} {
+		exists, err := s.uriExists(r.Context(), uri)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, fmt.Sprintf("uri %s not found", uri), http.StatusNotFound)
+			return
+		}
+	}
+
+	rel, err := s.relations().AddRelation(r.Context(), req)
+	if errors.Is(err, service.ErrRelationExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rel)
+}
+
+// handleDeleteRelation deletes relations. Query parameters: id, or source
+// and target to delete every relation from source to target, including
+// bidirectional ones stored the other way round.
+func (s *Server) handleDeleteRelation(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	id, source, target := q.Get("id"), q.Get("source"), q.Get("target")
+	var err error
+	switch {
+	case id != "":
+		err = s.relations().DeleteRelationByID(r.Context(), id)
+	case source != "" && target != "":
+		err = s.relations().DeleteRelation(r.Context(), source, target)
+	default:
+		http.Error(w, "id, or source and target, are required", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrRelationNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Audit handlers
+
+// handleListAudit lists audit entries newest first. Query parameters:
+// actor, action, entity_type, entity_id, limit and offset.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := service.AuditFilter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+	}
+	var err error
+	if filter.Limit, err = intParam(q.Get("limit")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = intParam(q.Get("offset")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid offset: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := s.auditLogger().QueryAudit(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []storage.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// Backup handlers
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" && format != "ndjson" {
+		http.Error(w, fmt.Sprintf("unsupported format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	opts := storage.ExportOptions{}
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "memories":
+			opts.IncludeMemories = true
+		case "relations":
+			opts.IncludeRelations = true
+		case "all":
+			opts.IncludeMemories = true
+			opts.IncludeRelations = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	// Headers are already sent once streaming starts, so an error aborts
+	// the response and the client sees a broken transfer rather than a
+	// clean end of a truncated stream.
+	if _, err := storage.Export(r.Context(), s.store, w, opts); err != nil {
+		log.Printf("export: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	opts := storage.ImportOptions{
+		OnConflict: storage.ConflictPolicy(r.URL.Query().Get("on_conflict")),
+	}
+	result, err := storage.ImportStore(r.Context(), s.store, r.Body, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
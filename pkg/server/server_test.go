@@ -0,0 +1,885 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/agfs"
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/service"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+var binaryData = []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff, 0xfe}
+
+// newFSClient returns an AGFS client rooted in a temporary directory.
+func newFSClient(t *testing.T) (*agfs.Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	fs, err := agfs.NewClient(agfs.Config{RootPath: dir})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS client: %v", err)
+	}
+	return fs, dir
+}
+
+func newFSTestServer(t *testing.T) *Server {
+	t.Helper()
+	fs, dir := newFSClient(t)
+	if err := os.WriteFile(filepath.Join(dir, "resources", "image.png"), binaryData, 0644); err != nil {
+		t.Fatalf("Failed to write binary file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "resources", "notes.txt"), []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Failed to write text file: %v", err)
+	}
+
+	return New(nil, fs)
+}
+
+func TestFSReadRaw(t *testing.T) {
+	s := newFSTestServer(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+	}{
+		{name: "accept header", url: "/api/v1/fs/read?path=viking://resources/image.png", accept: "application/octet-stream"},
+		{name: "raw param", url: "/api/v1/fs/read?path=viking://resources/image.png&raw=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), binaryData) {
+				t.Errorf("Expected raw bytes %v, got %v", binaryData, rec.Body.Bytes())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+				t.Errorf("Expected Content-Type 'image/png', got '%s'", ct)
+			}
+			if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=image.png" {
+				t.Errorf("Unexpected Content-Disposition '%s'", cd)
+			}
+		})
+	}
+}
+
+func TestFSReadBinaryJSON(t *testing.T) {
+	s := newFSTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/fs/read?path=viking://resources/image.png", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["encoding"] != "base64" {
+		t.Errorf("Expected base64 encoding, got '%s'", resp["encoding"])
+	}
+	decoded, err := base64.StdEncoding.DecodeString(resp["content"])
+	if err != nil {
+		t.Fatalf("Failed to decode base64 content: %v", err)
+	}
+	if !bytes.Equal(decoded, binaryData) {
+		t.Errorf("Expected decoded bytes %v, got %v", binaryData, decoded)
+	}
+}
+
+func TestFSReadTextJSON(t *testing.T) {
+	s := newFSTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/fs/read?path=viking://resources/notes.txt", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["content"] != "hello world" {
+		t.Errorf("Expected content 'hello world', got '%s'", resp["content"])
+	}
+	if _, ok := resp["encoding"]; ok {
+		t.Errorf("Expected no encoding for text, got '%s'", resp["encoding"])
+	}
+}
+
+func TestFSReadNotFound(t *testing.T) {
+	s := newFSTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/fs/read?path=viking://resources/missing.txt", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestFSReadETag(t *testing.T) {
+	fs, dir := newFSClient(t)
+	file := filepath.Join(dir, "resources", "notes.txt")
+	if err := os.WriteFile(file, []byte("version one"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	s := New(nil, fs)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/fs/read?path=viking://resources/notes.txt", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header")
+	}
+
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rec.Code)
+	}
+
+	if err := os.WriteFile(file, []byte("version two"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	rec := get(etag)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after modification, got %d", rec.Code)
+	}
+	if newETag := rec.Header().Get("ETag"); newETag == etag {
+		t.Error("Expected ETag to change after modification")
+	}
+}
+
+func TestFSListTreeConditionalGet(t *testing.T) {
+	fs, dir := newFSClient(t)
+	docs := filepath.Join(dir, "resources", "docs")
+	if err := os.MkdirAll(docs, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(docs, "a.md"), []byte("alpha"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Backdate the directory so a later write is visibly newer
+	old := time.Now().Add(-time.Hour)
+	for _, p := range []string{filepath.Join(docs, "a.md"), docs} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatalf("Failed to backdate %s: %v", p, err)
+		}
+	}
+	s := New(nil, fs)
+
+	get := func(url, since string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if since != "" {
+			req.Header.Set("If-Modified-Since", since)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	urls := []string{
+		"/api/v1/fs/list?path=viking://resources/docs",
+		"/api/v1/fs/tree?path=viking://resources/docs",
+	}
+	lastModified := make(map[string]string)
+	for _, url := range urls {
+		first := get(url, "")
+		if first.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", url, first.Code)
+		}
+		if cc := first.Header().Get("Cache-Control"); cc != "no-cache" {
+			t.Errorf("Expected Cache-Control no-cache for %s, got %q", url, cc)
+		}
+		lastModified[url] = first.Header().Get("Last-Modified")
+		if want := old.UTC().Format(http.TimeFormat); lastModified[url] != want {
+			t.Errorf("Expected Last-Modified %s for %s, got %q", want, url, lastModified[url])
+		}
+
+		rec := get(url, lastModified[url])
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected status 304 for unchanged %s, got %d", url, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("Expected empty body on 304, got %q", rec.Body.String())
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(docs, "b.md"), []byte("beta"), 0644); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	for _, url := range urls {
+		rec := get(url, lastModified[url])
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for %s after adding a file, got %d", url, rec.Code)
+		}
+		if !bytes.Contains(rec.Body.Bytes(), []byte("b.md")) {
+			t.Errorf("Expected %s to list b.md, got %s", url, rec.Body.String())
+		}
+	}
+}
+
+func TestGetContextETag(t *testing.T) {
+	s := New(newContextStore(
+		storage.Context{ID: "abc", URI: "viking://resources/abc"},
+		storage.Context{ID: "other", URI: "viking://resources/other"},
+	), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/contexts/abc", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/contexts/abc", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected empty body on 304, got %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/contexts/other", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a different context, got %d", rec.Code)
+	}
+}
+
+// existsStore answers ContextExists from a fixed set of IDs. Every other
+// method panics through the nil embedded interface, so tests also catch
+// handlers loading more than they need.
+type existsStore struct {
+	storage.StorageInterface
+	ids map[string]bool
+}
+
+func (s *existsStore) ContextExists(ctx context.Context, id string) (bool, error) {
+	return s.ids[id], nil
+}
+
+func TestHeadContext(t *testing.T) {
+	s := New(&existsStore{ids: map[string]bool{"abc": true}}, nil)
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"abc", http.StatusOK},
+		{"missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodHead, "/api/v1/contexts/"+tt.id, nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d", tt.want, tt.id, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("Expected empty body for %s, got %q", tt.id, rec.Body.String())
+		}
+	}
+}
+
+func TestCreateContextValidatesContextType(t *testing.T) {
+	s := New(newContextStore(), nil)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"uri": "viking://resources/a", "context_type": "Resource"}`, http.StatusCreated},
+		{`{"uri": "viking://resources/a"}`, http.StatusCreated},
+		{`{"uri": "viking://resources/a", "context_type": "document"}`, http.StatusBadRequest},
+		{`{"uri": "viking://resources/a", "context_type": 3}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/contexts", bytes.NewBufferString(tt.body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d", tt.want, tt.body, rec.Code)
+		}
+	}
+}
+
+// contextStore keeps contexts in memory. Methods the tests do not use
+// panic through the nil embedded interface.
+type contextStore struct {
+	storage.StorageInterface
+	contexts map[string]storage.Context
+	audit    []storage.AuditEntry
+}
+
+func newContextStore(contexts ...storage.Context) *contextStore {
+	s := &contextStore{contexts: map[string]storage.Context{}}
+	for _, c := range contexts {
+		s.contexts[c.ID] = c
+	}
+	return s
+}
+
+func (s *contextStore) CreateContext(ctx context.Context, c *storage.Context) error {
+	s.contexts[c.ID] = *c
+	return nil
+}
+
+func (s *contextStore) GetContext(ctx context.Context, id string) (*storage.Context, error) {
+	c, ok := s.contexts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (s *contextStore) ContextExists(ctx context.Context, id string) (bool, error) {
+	_, ok := s.contexts[id]
+	return ok, nil
+}
+
+func (s *contextStore) DeleteContext(ctx context.Context, id string) error {
+	delete(s.contexts, id)
+	return nil
+}
+
+func (s *contextStore) QueryContexts(ctx context.Context, opts storage.QueryOptions) ([]storage.Context, error) {
+	var contexts []storage.Context
+	for _, c := range s.contexts {
+		contexts = append(contexts, c)
+	}
+	return contexts, nil
+}
+
+func (s *contextStore) CountContexts(ctx context.Context, opts storage.QueryOptions) (int, error) {
+	return len(s.contexts), nil
+}
+
+func (s *contextStore) CreateAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+// QueryAudit returns all entries newest first, ignoring the filter.
+func (s *contextStore) QueryAudit(ctx context.Context, opts storage.QueryOptions) ([]storage.AuditEntry, error) {
+	var entries []storage.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		entries = append(entries, s.audit[i])
+	}
+	return entries, nil
+}
+
+func TestContextHandlers(t *testing.T) {
+	s := New(newContextStore(), nil)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/contexts", `{"uri": "viking://resources/guide", "name": "Guide", "context_type": "resource"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Context
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if created.ID == "" || created.CreatedAt.IsZero() {
+		t.Errorf("Expected generated id and timestamps, got %+v", created)
+	}
+
+	rec = do(http.MethodGet, "/api/v1/contexts/"+created.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var got storage.Context
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if got.URI != "viking://resources/guide" || got.Name != "Guide" || got.ContextType != "resource" {
+		t.Errorf("Expected the created context, got %+v", got)
+	}
+
+	rec = do(http.MethodGet, "/api/v1/contexts", "")
+	var listed []storage.Context
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed) != 1 {
+		t.Errorf("Expected 1 listed context, got %s", rec.Body.String())
+	}
+	if total := rec.Header().Get(utils.HeaderTotalCount); total != "1" {
+		t.Errorf("Expected total count 1, got %q", total)
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/contexts", `{"id": "`+created.ID+`", "uri": "viking://resources/other"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate id, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/api/v1/contexts/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/contexts/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/v1/contexts/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting twice, got %d", rec.Code)
+	}
+
+	for _, body := range []string{`{"uri": `, `{"name": "no uri"}`} {
+		if rec := do(http.MethodPost, "/api/v1/contexts", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	for _, url := range []string{"/api/v1/contexts?limit=-1", "/api/v1/contexts?context_type=document"} {
+		if rec := do(http.MethodGet, url, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, rec.Code)
+		}
+	}
+}
+
+func TestAuditContextMutations(t *testing.T) {
+	store := newContextStore()
+	s := New(store, nil)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set(utils.HeaderUser, "alice")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/contexts", `{"id": "ctx-1", "uri": "viking://resources/guide", "name": "Guide"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodDelete, "/api/v1/contexts/ctx-1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	// Imports are audited too, and anonymous without a user header
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import", strings.NewReader(`{"kind": "context", "context": {"id": "ctx-2", "uri": "viking://resources/imported"}}`+"\n"))
+	imported := httptest.NewRecorder()
+	s.router.ServeHTTP(imported, req)
+	if imported.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 importing, got %d: %s", imported.Code, imported.Body.String())
+	}
+
+	rec := do(http.MethodGet, "/api/v1/audit", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var entries []storage.AuditEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Action != service.AuditCreate || e.Actor != service.AnonymousActor || e.EntityID != "ctx-2" {
+		t.Errorf("Expected an anonymous create of ctx-2, got %+v", e)
+	}
+	for i, action := range []string{service.AuditDelete, service.AuditCreate} {
+		e := entries[i+1]
+		if e.Action != action || e.Actor != "alice" || e.EntityType != service.AuditEntityContext || e.EntityID != "ctx-1" || e.Timestamp.IsZero() {
+			t.Errorf("Expected alice's %s of ctx-1, got %+v", action, e)
+		}
+		if !strings.Contains(e.Diff, `"uri"`) {
+			t.Errorf("Expected the %s diff to hold the uri, got %s", action, e.Diff)
+		}
+	}
+
+	if rec := do(http.MethodGet, "/api/v1/audit?limit=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid limit, got %d", rec.Code)
+	}
+}
+
+func TestContextHandlersWithoutStorage(t *testing.T) {
+	s := New(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/contexts/abc", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestFSHandlers(t *testing.T) {
+	fs, _ := newFSClient(t)
+	s := New(nil, fs)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/fs/mkdir", `{"path": "viking://resources/docs"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for mkdir, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/v1/fs/write", `{"path": "viking://resources/docs/a.md", "content": "hello"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for write, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if content, _ := fs.ReadText("viking://resources/docs/a.md"); content != "hello" {
+		t.Errorf("Expected written content 'hello', got %q", content)
+	}
+
+	rec := do(http.MethodGet, "/api/v1/fs/list?path=viking://resources/docs", "")
+	var entries []agfs.Entry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || len(entries) != 1 || entries[0].Name != "a.md" {
+		t.Errorf("Expected a.md listed, got %s", rec.Body.String())
+	}
+
+	rec = do(http.MethodGet, "/api/v1/fs/tree?path=viking://resources", "")
+	var tree struct {
+		Tree []agfs.TreeEntry `json:"tree"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil || len(tree.Tree) == 0 {
+		t.Errorf("Expected a tree below resources, got %s", rec.Body.String())
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/fs/move", `{"from": "viking://resources/docs/a.md", "to": "viking://resources/docs/b.md"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for move, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !fs.FileExists("viking://resources/docs/b.md") || fs.FileExists("viking://resources/docs/a.md") {
+		t.Error("Expected a.md moved to b.md")
+	}
+
+	if rec := do(http.MethodDelete, "/api/v1/fs/delete?path=viking://resources/docs/b.md", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for delete, got %d", rec.Code)
+	}
+
+	tests := []struct {
+		method, url, body string
+		want              int
+	}{
+		{http.MethodGet, "/api/v1/fs/read?path=viking://resources/docs/b.md", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/fs/delete?path=viking://resources/docs/b.md", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/fs/list?path=viking://resources/missing", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/fs/move", `{"from": "viking://resources/missing", "to": "viking://resources/x"}`, http.StatusNotFound},
+		{http.MethodGet, "/api/v1/fs/read?path=relative.md", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/fs/read?path=viking://resources/docs", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/fs/write", `{"path": `, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/fs/write", `{"content": "no path"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/fs/move", `{"from": "viking://resources/docs"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/fs/tree?depth=x", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.url, tt.body); rec.Code != tt.want {
+			t.Errorf("Expected status %d for %s %s %s, got %d", tt.want, tt.method, tt.url, tt.body, rec.Code)
+		}
+	}
+}
+
+// pingStore fails Ping with err.
+type pingStore struct {
+	storage.StorageInterface
+	err error
+}
+
+func (s *pingStore) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name   string
+		store  storage.StorageInterface
+		want   int
+		status string
+	}{
+		{"no storage", nil, http.StatusOK, "ready"},
+		{"storage up", &pingStore{}, http.StatusOK, "ready"},
+		{"storage down", &pingStore{err: errors.New("database is locked")}, http.StatusServiceUnavailable, "unavailable"},
+	}
+	for _, tt := range tests {
+		s := New(tt.store, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", tt.name, err)
+		}
+		if body["status"] != tt.status {
+			t.Errorf("%s: expected status %q, got %q", tt.name, tt.status, body["status"])
+		}
+	}
+}
+
+// iterateStore streams one context, then fails.
+type iterateStore struct {
+	storage.StorageInterface
+}
+
+func (iterateStore) IterateContexts(ctx context.Context, fn func(*storage.Context) error) error {
+	if err := fn(&storage.Context{ID: "ctx-1", URI: "viking://resources/a"}); err != nil {
+		return err
+	}
+	return errors.New("database is locked")
+}
+
+func TestExportAbortsOnError(t *testing.T) {
+	ts := httptest.NewServer(New(iterateStore{}, nil).router)
+	defer ts.Close()
+
+	// Depending on buffering, the abort surfaces before or after headers
+	resp, err := http.Get(ts.URL + "/api/v1/export")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("Expected a failed export to break the transfer")
+	}
+}
+
+// searchRetriever returns the same resources for every query.
+type searchRetriever struct{}
+
+func (searchRetriever) Retrieve(ctx context.Context, query retrieval.TypedQuery, opts retrieval.SearchOptions) (*retrieval.QueryResult, error) {
+	result := &retrieval.QueryResult{Query: query}
+	if query.ContextType == retrieval.ContextTypeResource {
+		result.MatchedContexts = []retrieval.MatchedContext{
+			{URI: "viking://resources/go", ContextType: query.ContextType, Abstract: "golang guide", Score: 0.9},
+			{URI: "viking://resources/python", ContextType: query.ContextType, Abstract: "python guide", Score: 0.8},
+		}
+	}
+	return result, nil
+}
+
+func TestSearchWeights(t *testing.T) {
+	search := service.NewSearchService()
+	search.SetRetriever(searchRetriever{})
+	s := New(nil, nil)
+	s.SetSearchService(search)
+
+	topResult := func(query string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var results []service.SearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if len(results) == 0 {
+			t.Fatalf("Expected results for %s", query)
+		}
+		return results[0].URI
+	}
+
+	if got := topResult("q=python"); got != "viking://resources/go" {
+		t.Errorf("Expected semantic order by default, got %s first", got)
+	}
+	if got := topResult("q=python&keyword_weight=0.5"); got != "viking://resources/python" {
+		t.Errorf("Expected keyword_weight to promote the keyword match, got %s first", got)
+	}
+
+	for _, query := range []string{"", "q=python&keyword_weight=2", "q=python&hotness_weight=x", "q=python&limit=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestSearchSessionHeader(t *testing.T) {
+	search := service.NewSearchService()
+	search.SetRetriever(searchRetriever{})
+	s := New(nil, nil)
+	s.SetSearchService(search)
+
+	tests := []struct {
+		query, header, want string
+	}{
+		{"q=go", "sess-1", "sess-1"},
+		{"q=go&session_id=sess-2", "sess-1", "sess-2"},
+		{"q=go", "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+tt.query, nil)
+		if tt.header != "" {
+			req.Header.Set(utils.HeaderSession, tt.header)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		var results []service.SearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil || len(results) == 0 {
+			t.Fatalf("Expected results, got %s (%v)", rec.Body.String(), err)
+		}
+		if results[0].SessionID != tt.want {
+			t.Errorf("%s with header %q: Expected session %q, got %q", tt.query, tt.header, tt.want, results[0].SessionID)
+		}
+	}
+}
+
+func TestSearchLimitCap(t *testing.T) {
+	search := service.NewSearchService()
+	search.SetRetriever(searchRetriever{})
+	s := New(nil, nil)
+	s.SetSearchService(search)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", url, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/v1/search?q=guide&limit=1000000", "")
+	if got := rec.Header().Get(HeaderEffectiveLimit); got != strconv.Itoa(service.DefaultMaxResultsCap) {
+		t.Errorf("Expected the limit clamped to %d, got %q", service.DefaultMaxResultsCap, got)
+	}
+	if got := do(http.MethodGet, "/api/v1/search?q=guide", "").Header().Get(HeaderEffectiveLimit); got != "10" {
+		t.Errorf("Expected the default limit 10, got %q", got)
+	}
+
+	search.SetMaxResultsCap(1)
+	rec = do(http.MethodGet, "/api/v1/search?q=guide&limit=1000000", "")
+	var results []service.SearchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if got := rec.Header().Get(HeaderEffectiveLimit); got != "1" || len(results) != 1 {
+		t.Errorf("Expected 1 result at limit 1, got %d at %q", len(results), got)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{
+		do(http.MethodGet, "/api/v1/search/explain?q=guide&limit=50", ""),
+		do(http.MethodPost, "/api/v1/find", `{"query": "guide", "limit": 50}`),
+	} {
+		if got := rec.Header().Get(HeaderEffectiveLimit); got != "1" {
+			t.Errorf("Expected the limit clamped to 1, got %q", got)
+		}
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	search := service.NewSearchService()
+	search.SetRetriever(searchRetriever{})
+	s := New(nil, nil)
+	s.SetSearchService(search)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search/explain?q=python&type=resource", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var explanation service.SearchExplanation
+	if err := json.Unmarshal(rec.Body.Bytes(), &explanation); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if explanation.Query != "python" || len(explanation.Results) != 2 {
+		t.Errorf("Expected 2 results for python, got %+v", explanation)
+	}
+	if len(explanation.Traversals) != 1 || explanation.Traversals[0].ContextType != "resource" {
+		t.Errorf("Expected one resource traversal, got %+v", explanation.Traversals)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/search/explain", nil)
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without q, got %d", rec.Code)
+	}
+}
+
+func TestFind(t *testing.T) {
+	search := service.NewSearchService()
+	search.SetRetriever(searchRetriever{})
+	s := New(nil, nil)
+	s.SetSearchService(search)
+
+	find := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/find", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := find(`{"query": "guide", "trace": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result retrieval.FindResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(result.Resources) != 2 || len(result.Memories) != 0 || len(result.Skills) != 0 || result.Total != 2 {
+		t.Errorf("Expected 2 resources, got %+v", result)
+	}
+	if result.QueryPlan == nil || len(result.QueryPlan.Queries) != 3 {
+		t.Errorf("Expected a plan with a query per type, got %+v", result.QueryPlan)
+	}
+
+	rec = find(`{"query": "guide", "context_types": ["memory"]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Total != 0 {
+		t.Errorf("Expected no memories, got %s", rec.Body.String())
+	}
+
+	for _, body := range []string{`{"query": `, `{}`, `{"query": "x", "context_types": ["document"]}`, `{"query": "x", "limit": -1}`} {
+		if rec := find(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
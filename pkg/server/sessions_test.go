@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/client"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+func TestForkSessionRoute(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(storage.Config{
+		DBPath:       filepath.Join(t.TempDir(), "sessions.db"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := store.CreateSession(ctx, &storage.Session{ID: "p", SessionID: "parent", UserID: "alice", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		msg := &storage.SessionMessage{ID: fmt.Sprintf("m%d", i), SessionID: "parent", Role: "user",
+			Content: fmt.Sprintf("message %d", i), OrderIndex: int64(i), CreatedAt: now}
+		if err := store.CreateSessionMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	ts := httptest.NewServer(New(store, nil).router)
+	t.Cleanup(ts.Close)
+	c, err := client.NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	fork, err := c.Sessions.Fork(ctx, "parent", 1)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if fork.SessionID == "" || fork.SessionID == "parent" || fork.Metadata["parent_session_id"] != "parent" {
+		t.Errorf("Expected a new session referencing the parent, got %+v", fork)
+	}
+
+	messages, err := store.GetSessionMessages(ctx, fork.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "message 0" || messages[1].Content != "message 1" {
+		t.Errorf("Expected the first 2 messages in the fork, got %+v", messages)
+	}
+	if parent, _ := store.GetSessionMessages(ctx, "parent"); len(parent) != 3 {
+		t.Errorf("Expected the parent left with 3 messages, got %d", len(parent))
+	}
+
+	if _, err := c.Sessions.Fork(ctx, "parent", 3); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected an out-of-range index to fail with 400, got %v", err)
+	}
+	if _, err := c.Sessions.Fork(ctx, "missing", 0); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a missing session to fail with 404, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// Audited actions.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// Audited entity types.
+const (
+	AuditEntityContext = "context"
+	AuditEntityMemory  = "memory"
+	AuditEntitySession = "session"
+)
+
+// AnonymousActor is recorded for mutations whose context names no actor.
+const AnonymousActor = "anonymous"
+
+// auditValueLimit is the number of characters of a string field kept in a
+// diff summary; longer values, such as context content, are cut short.
+const auditValueLimit = 200
+
+// actorKey is the context key of the acting user.
+type actorKey struct{}
+
+// WithActor returns a context naming actor as the user behind the
+// mutations made with it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or AnonymousActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+// AuditFilter selects audit entries. Empty fields match any entry.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	Limit      int
+	Offset     int
+}
+
+// AuditLogger records who created, updated or deleted contexts, memories
+// and sessions. A nil *AuditLogger records nothing. Storage mutations are
+// recorded by an AuditedStore around the storage.
+type AuditLogger struct {
+	store storage.StorageInterface
+	clock utils.Clock
+}
+
+// NewAuditLogger creates an AuditLogger writing to store.
+func NewAuditLogger(store storage.StorageInterface) *AuditLogger {
+	return &AuditLogger{
+		store: store,
+		clock: utils.RealClock{},
+	}
+}
+
+// SetClock sets the clock used to timestamp audit entries.
+func (a *AuditLogger) SetClock(clock utils.Clock) {
+	a.clock = clock
+}
+
+// Log records action on the entity of entityType and entityID by the actor
+// of ctx. before and after are the entity as it was and as it became, nil
+// for a create or a delete respectively; the entry summarizes the fields
+// that differ.
+func (a *AuditLogger) Log(ctx context.Context, action, entityType, entityID string, before, after any) error {
+	if a == nil {
+		return nil
+	}
+
+	diff, err := diffSummary(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to summarize %s %s: %w", entityType, entityID, err)
+	}
+	entry := &storage.AuditEntry{
+		ID:         uuid.New().String(),
+		Actor:      ActorFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       diff,
+		Timestamp:  a.clock.Now().UTC(),
+	}
+	if err := a.store.CreateAudit(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record %s of %s %s: %w", action, entityType, entityID, err)
+	}
+	return nil
+}
+
+// QueryAudit returns the audit entries matching filter, newest first.
+func (a *AuditLogger) QueryAudit(ctx context.Context, filter AuditFilter) ([]storage.AuditEntry, error) {
+	var conds []storage.FilterCondition
+	for _, f := range []struct{ field, value string }{
+		{"actor", filter.Actor},
+		{"action", filter.Action},
+		{"entity_type", filter.EntityType},
+		{"entity_id", filter.EntityID},
+	} {
+		if f.value != "" {
+			conds = append(conds, storage.FilterCondition{Op: "must", Field: f.field, Value: f.value})
+		}
+	}
+
+	opts := storage.QueryOptions{
+		OrderBy:   "timestamp",
+		OrderDesc: true,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	}
+	if len(conds) > 0 {
+		opts.Filter = &storage.Filter{Conds: conds}
+	}
+	return a.store.QueryAudit(ctx, opts)
+}
+
+// AuditedStore wraps a StorageInterface and records each create, update
+// and delete of a context, memory or session in an audit log, so every
+// caller mutating through it is audited. Other methods pass straight
+// through. An audit entry is written once its mutation has succeeded, and
+// a failure to write it does not fail the mutation; it is passed to the
+// error handler instead.
+type AuditedStore struct {
+	storage.StorageInterface
+	audit   *AuditLogger
+	onError func(error)
+}
+
+// NewAuditedStore creates an AuditedStore mutating store and recording
+// the mutations with audit.
+func NewAuditedStore(store storage.StorageInterface, audit *AuditLogger) *AuditedStore {
+	return &AuditedStore{
+		StorageInterface: store,
+		audit:            audit,
+		onError: func(err error) {
+			log.Printf("audit: %v", err)
+		},
+	}
+}
+
+// SetErrorHandler sets the function receiving failed audit writes. The
+// default logs them.
+func (s *AuditedStore) SetErrorHandler(fn func(error)) {
+	s.onError = fn
+}
+
+// record writes an audit entry, handing any failure to the error handler.
+func (s *AuditedStore) record(ctx context.Context, action, entityType, entityID string, before, after any) {
+	if err := s.audit.Log(ctx, action, entityType, entityID, before, after); err != nil {
+		s.onError(err)
+	}
+}
+
+// CreateContext implements storage.StorageInterface.
+func (s *AuditedStore) CreateContext(ctx context.Context, c *storage.Context) error {
+	if err := s.StorageInterface.CreateContext(ctx, c); err != nil {
+		return err
+	}
+	s.record(ctx, AuditCreate, AuditEntityContext, c.ID, nil, c)
+	return nil
+}
+
+// UpdateContext implements storage.StorageInterface.
+func (s *AuditedStore) UpdateContext(ctx context.Context, c *storage.Context) error {
+	before, err := s.StorageInterface.GetContext(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.UpdateContext(ctx, c); err != nil {
+		return err
+	}
+	s.record(ctx, AuditUpdate, AuditEntityContext, c.ID, before, c)
+	return nil
+}
+
+// DeleteContext implements storage.StorageInterface.
+func (s *AuditedStore) DeleteContext(ctx context.Context, id string) error {
+	before, err := s.StorageInterface.GetContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.DeleteContext(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, AuditDelete, AuditEntityContext, id, before, nil)
+	return nil
+}
+
+// CreateMemory implements storage.StorageInterface.
+func (s *AuditedStore) CreateMemory(ctx context.Context, m *storage.Memory) error {
+	if err := s.StorageInterface.CreateMemory(ctx, m); err != nil {
+		return err
+	}
+	s.record(ctx, AuditCreate, AuditEntityMemory, m.ID, nil, m)
+	return nil
+}
+
+// UpdateMemory implements storage.StorageInterface.
+func (s *AuditedStore) UpdateMemory(ctx context.Context, m *storage.Memory) error {
+	before, err := s.StorageInterface.GetMemory(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.UpdateMemory(ctx, m); err != nil {
+		return err
+	}
+	s.record(ctx, AuditUpdate, AuditEntityMemory, m.ID, before, m)
+	return nil
+}
+
+// DeleteMemory implements storage.StorageInterface.
+func (s *AuditedStore) DeleteMemory(ctx context.Context, id string) error {
+	before, err := s.StorageInterface.GetMemory(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.DeleteMemory(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, AuditDelete, AuditEntityMemory, id, before, nil)
+	return nil
+}
+
+// CreateSession implements storage.StorageInterface.
+func (s *AuditedStore) CreateSession(ctx context.Context, session *storage.Session) error {
+	if err := s.StorageInterface.CreateSession(ctx, session); err != nil {
+		return err
+	}
+	s.record(ctx, AuditCreate, AuditEntitySession, session.ID, nil, session)
+	return nil
+}
+
+// UpdateSession implements storage.StorageInterface.
+func (s *AuditedStore) UpdateSession(ctx context.Context, session *storage.Session) error {
+	before, err := s.StorageInterface.GetSession(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.UpdateSession(ctx, session); err != nil {
+		return err
+	}
+	s.record(ctx, AuditUpdate, AuditEntitySession, session.ID, before, session)
+	return nil
+}
+
+// DeleteSession implements storage.StorageInterface.
+func (s *AuditedStore) DeleteSession(ctx context.Context, id string) error {
+	before, err := s.StorageInterface.GetSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageInterface.DeleteSession(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, AuditDelete, AuditEntitySession, id, before, nil)
+	return nil
+}
+
+// auditChange is the before and after value of a changed field. A side is
+// omitted when the field is unset there.
+type auditChange struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+// diffSummary returns a JSON object mapping each field that differs
+// between before and after to its two values, or "" when none differs.
+// Fields unset on one side and zero on the other are left out.
+func diffSummary(before, after any) (string, error) {
+	old, err := auditFields(before)
+	if err != nil {
+		return "", err
+	}
+	cur, err := auditFields(after)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make(map[string]bool)
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range cur {
+		keys[k] = true
+	}
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	changes := make(map[string]auditChange)
+	for _, k := range names {
+		b, a := old[k], cur[k]
+		if reflect.DeepEqual(b, a) || (isZeroValue(b) && isZeroValue(a)) {
+			continue
+		}
+		changes[k] = auditChange{Before: truncateValue(b), After: truncateValue(a)}
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// auditFields returns the JSON fields of v, or none for nil.
+func auditFields(v any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// isZeroValue reports whether a decoded JSON value is absent or zero.
+func isZeroValue(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// truncateValue cuts strings longer than auditValueLimit characters.
+func truncateValue(v any) any {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if runes := []rune(s); len(runes) > auditValueLimit {
+		return string(runes[:auditValueLimit]) + "..."
+	}
+	return s
+}
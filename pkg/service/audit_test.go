@@ -0,0 +1,251 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+func (m *memStore) CreateAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	m.audit = append(m.audit, *entry)
+	return nil
+}
+
+func (m *memStore) CreateContext(ctx context.Context, c *storage.Context) error {
+	m.contexts[c.ID] = *c
+	return nil
+}
+
+func (m *memStore) GetContext(ctx context.Context, id string) (*storage.Context, error) {
+	c, ok := m.contexts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (m *memStore) UpdateContext(ctx context.Context, c *storage.Context) error {
+	m.contexts[c.ID] = *c
+	return nil
+}
+
+func (m *memStore) GetMemory(ctx context.Context, id string) (*storage.Memory, error) {
+	mem, ok := m.memories[id]
+	if !ok {
+		return nil, nil
+	}
+	return &mem, nil
+}
+
+// QueryAudit returns the entries matching every "must" condition, newest
+// first.
+func (m *memStore) QueryAudit(ctx context.Context, opts storage.QueryOptions) ([]storage.AuditEntry, error) {
+	var entries []storage.AuditEntry
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		e := m.audit[i]
+		fields := map[string]string{"actor": e.Actor, "action": e.Action, "entity_type": e.EntityType, "entity_id": e.EntityID}
+		match := true
+		if opts.Filter != nil {
+			for _, c := range opts.Filter.Conds {
+				match = match && fields[c.Field] == c.Value
+			}
+		}
+		if match {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// auditDiff decodes the diff of an audit entry.
+func auditDiff(t *testing.T, e storage.AuditEntry) map[string]map[string]any {
+	t.Helper()
+	var diff map[string]map[string]any
+	if err := json.Unmarshal([]byte(e.Diff), &diff); err != nil {
+		t.Fatalf("Invalid diff %q: %v", e.Diff, err)
+	}
+	return diff
+}
+
+func TestAuditLogger(t *testing.T) {
+	store := newMemStore()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	audit := NewAuditLogger(store)
+	audit.SetClock(utils.NewFakeClock(now))
+	ctx := WithActor(context.Background(), "alice")
+
+	created := &storage.Memory{ID: "m1", Content: "likes tea", Importance: 0.5}
+	updated := &storage.Memory{ID: "m1", Content: "likes coffee", Importance: 0.5}
+	steps := []struct {
+		action        string
+		before, after any
+	}{
+		{AuditCreate, nil, created},
+		{AuditUpdate, created, updated},
+		{AuditDelete, updated, nil},
+	}
+	for _, step := range steps {
+		if err := audit.Log(ctx, step.action, AuditEntityMemory, "m1", step.before, step.after); err != nil {
+			t.Fatalf("Log %s failed: %v", step.action, err)
+		}
+	}
+
+	if len(store.audit) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(store.audit))
+	}
+	for i, e := range store.audit {
+		if e.ID == "" || e.Actor != "alice" || e.Action != steps[i].action || e.EntityType != AuditEntityMemory || e.EntityID != "m1" || !e.Timestamp.Equal(now) {
+			t.Errorf("Expected alice's %s of m1 at %v, got %+v", steps[i].action, now, e)
+		}
+	}
+
+	// A create holds the set fields, leaving out zero ones
+	diff := auditDiff(t, store.audit[0])
+	if diff["content"]["after"] != "likes tea" || diff["content"]["before"] != nil {
+		t.Errorf("Expected the created content, got %v", diff)
+	}
+	if _, ok := diff["tags"]; ok {
+		t.Errorf("Expected unset tags left out, got %v", diff)
+	}
+
+	// An update holds the changed fields only
+	diff = auditDiff(t, store.audit[1])
+	if len(diff) != 1 || diff["content"]["before"] != "likes tea" || diff["content"]["after"] != "likes coffee" {
+		t.Errorf("Expected only the content change, got %v", diff)
+	}
+
+	diff = auditDiff(t, store.audit[2])
+	if diff["content"]["before"] != "likes coffee" || diff["importance"]["before"] != 0.5 || diff["content"]["after"] != nil {
+		t.Errorf("Expected the deleted memory's fields, got %v", diff)
+	}
+}
+
+func TestAuditLoggerActorAndTruncation(t *testing.T) {
+	store := newMemStore()
+	audit := NewAuditLogger(store)
+
+	long := strings.Repeat("x", auditValueLimit+50)
+	if err := audit.Log(context.Background(), AuditCreate, AuditEntityContext, "c1", nil, &storage.Context{ID: "c1", Content: long}); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	e := store.audit[0]
+	if e.Actor != AnonymousActor {
+		t.Errorf("Expected the anonymous actor, got %q", e.Actor)
+	}
+	content, _ := auditDiff(t, e)["content"]["after"].(string)
+	if len(content) != auditValueLimit+len("...") {
+		t.Errorf("Expected content cut to %d characters, got %d", auditValueLimit, len(content))
+	}
+
+	var disabled *AuditLogger
+	if err := disabled.Log(context.Background(), AuditDelete, AuditEntityContext, "c1", nil, nil); err != nil {
+		t.Errorf("Expected a nil logger to record nothing, got %v", err)
+	}
+}
+
+func TestQueryAudit(t *testing.T) {
+	store := newMemStore()
+	audit := NewAuditLogger(store)
+	for _, actor := range []string{"alice", "bob", "alice"} {
+		if err := audit.Log(WithActor(context.Background(), actor), AuditCreate, AuditEntitySession, actor, nil, nil); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+
+	entries, err := audit.QueryAudit(context.Background(), AuditFilter{Actor: "alice", Action: AuditCreate})
+	if err != nil {
+		t.Fatalf("QueryAudit failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != store.audit[2].ID {
+		t.Errorf("Expected alice's 2 entries newest first, got %+v", entries)
+	}
+}
+
+func TestAuditedStore(t *testing.T) {
+	store := newMemStore()
+	audited := NewAuditedStore(store, NewAuditLogger(store))
+	ctx := WithActor(context.Background(), "bob")
+
+	if err := audited.CreateContext(ctx, &storage.Context{ID: "c1", URI: "viking://resources/guide", Name: "Guide"}); err != nil {
+		t.Fatalf("CreateContext failed: %v", err)
+	}
+	if err := audited.UpdateContext(ctx, &storage.Context{ID: "c1", URI: "viking://resources/guide", Name: "Handbook"}); err != nil {
+		t.Fatalf("UpdateContext failed: %v", err)
+	}
+	if err := audited.DeleteContext(ctx, "c1"); err != nil {
+		t.Fatalf("DeleteContext failed: %v", err)
+	}
+	if _, ok := store.contexts["c1"]; ok {
+		t.Error("Expected c1 to be deleted")
+	}
+
+	if len(store.audit) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %+v", store.audit)
+	}
+	for i, action := range []string{AuditCreate, AuditUpdate, AuditDelete} {
+		if e := store.audit[i]; e.Action != action || e.Actor != "bob" || e.EntityType != AuditEntityContext || e.EntityID != "c1" {
+			t.Errorf("Expected bob's %s of c1, got %+v", action, e)
+		}
+	}
+	diff := auditDiff(t, store.audit[1])
+	if len(diff) != 1 || diff["name"]["before"] != "Guide" || diff["name"]["after"] != "Handbook" {
+		t.Errorf("Expected only the name change, got %v", diff)
+	}
+	if diff := auditDiff(t, store.audit[2]); diff["name"]["before"] != "Handbook" {
+		t.Errorf("Expected the deleted context's fields, got %v", diff)
+	}
+}
+
+// failingAuditStore fails every audit write.
+type failingAuditStore struct {
+	*memStore
+}
+
+func (failingAuditStore) CreateAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	return errors.New("audit table is full")
+}
+
+func TestAuditedStoreAuditFailure(t *testing.T) {
+	store := failingAuditStore{newMemStore()}
+	audited := NewAuditedStore(store, NewAuditLogger(store))
+	var failures []error
+	audited.SetErrorHandler(func(err error) { failures = append(failures, err) })
+
+	// The context is created, so its creation must not be reported failed
+	if err := audited.CreateContext(context.Background(), &storage.Context{ID: "c1"}); err != nil {
+		t.Fatalf("Expected the mutation to succeed despite the audit failure, got %v", err)
+	}
+	if _, ok := store.contexts["c1"]; !ok {
+		t.Error("Expected c1 to be created")
+	}
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "audit table is full") {
+		t.Errorf("Expected the audit failure to be reported, got %v", failures)
+	}
+}
+
+func TestPruneAudited(t *testing.T) {
+	store := newPruneFixture()
+	p := newTestPruner(NewAuditedStore(store, NewAuditLogger(store)), PruneOptions{MinImportance: 0.3, UnusedSince: 90 * 24 * time.Hour})
+
+	if _, err := p.Prune(context.Background()); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(store.audit) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v", store.audit)
+	}
+	if e := store.audit[0]; e.Action != AuditDelete || e.EntityType != AuditEntityMemory || e.EntityID != "m1" {
+		t.Errorf("Expected the deletion of memory m1, got %+v", e)
+	}
+	if e := store.audit[1]; e.Action != AuditDelete || e.EntityType != AuditEntityContext || e.EntityID != "c1" {
+		t.Errorf("Expected the deletion of context c1, got %+v", e)
+	}
+}
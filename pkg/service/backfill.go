@@ -0,0 +1,213 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// BackfillOptions selects the messages BackfillMemories replays. A zero
+// bound leaves that side of the range open.
+type BackfillOptions struct {
+	// Since skips messages created before it.
+	Since time.Time
+
+	// Until skips messages created at or after it.
+	Until time.Time
+
+	// Conflicts, when set, checks new memories against the stored
+	// memories of the same user before they are stored.
+	Conflicts ConflictDetector
+
+	// ConflictPolicy decides what happens to conflicting memories.
+	ConflictPolicy ConflictPolicy
+}
+
+// ConflictPolicy is how BackfillMemories handles a new memory that
+// contradicts a stored one. Every conflict is reported either way.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyReport stores the new memory and keeps the existing
+	// one. It is the default.
+	ConflictPolicyReport ConflictPolicy = "report"
+	// ConflictPolicySkip does not store the new memory.
+	ConflictPolicySkip ConflictPolicy = "skip"
+	// ConflictPolicyResolve follows the suggested resolution, deleting the
+	// existing memory once the new one that supersedes it is stored.
+	ConflictPolicyResolve ConflictPolicy = "resolve"
+)
+
+// SessionBackfill reports the outcome of backfilling one session.
+type SessionBackfill struct {
+	SessionID  string `json:"session_id"`
+	Messages   int    `json:"messages"`
+	Extracted  int    `json:"extracted"`
+	Duplicates int    `json:"duplicates"`
+	Conflicts  int    `json:"conflicts"`
+	Added      int    `json:"added"`
+}
+
+// BackfillReport lists the per-session outcome of a backfill.
+type BackfillReport struct {
+	Sessions  []SessionBackfill `json:"sessions"`
+	Added     int               `json:"added"`
+	Conflicts []MemoryConflict  `json:"conflicts,omitempty"`
+}
+
+// BackfillMemories replays the stored messages of each session through
+// extractor and stores the memories that are not already known. Extracted
+// memories are deduplicated against each other and against every existing
+// memory, including those added earlier in the same run, so backfilling a
+// session twice adds nothing the second time. With no session IDs every
+// stored session is backfilled.
+func BackfillMemories(ctx context.Context, sessionIDs []string, store storage.StorageInterface, extractor session.MemoryExtractor, deduper *session.Deduper, opts BackfillOptions) (report BackfillReport, err error) {
+	sessions, err := store.QuerySessions(ctx, storage.QueryOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	users := make(map[string]string, len(sessions))
+	for _, s := range sessions {
+		users[s.SessionID] = s.UserID
+	}
+	if len(sessionIDs) == 0 {
+		for _, s := range sessions {
+			sessionIDs = append(sessionIDs, s.SessionID)
+		}
+	}
+
+	memories, err := store.QueryMemories(ctx, storage.QueryOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list memories: %w", err)
+	}
+	existing := make([]string, 0, len(memories))
+	known := make([]*storage.Memory, 0, len(memories))
+	for i, m := range memories {
+		existing = append(existing, m.Content)
+		known = append(known, &memories[i])
+	}
+
+	for _, id := range sessionIDs {
+		result := SessionBackfill{SessionID: id}
+
+		stored, err := store.GetSessionMessages(ctx, id)
+		if err != nil {
+			return report, fmt.Errorf("failed to load messages of session %s: %w", id, err)
+		}
+		var messages []*session.Message
+		for _, m := range stored {
+			if !opts.Since.IsZero() && m.CreatedAt.Before(opts.Since) {
+				continue
+			}
+			if !opts.Until.IsZero() && !m.CreatedAt.Before(opts.Until) {
+				continue
+			}
+			messages = append(messages, &session.Message{
+				ID:        m.ID,
+				SessionID: m.SessionID,
+				Role:      session.Role(m.Role),
+				Content:   m.Content,
+				CreatedAt: m.CreatedAt,
+			})
+		}
+		result.Messages = len(messages)
+		if len(messages) == 0 {
+			report.Sessions = append(report.Sessions, result)
+			continue
+		}
+
+		extracted, err := extractor.Extract(ctx, messages)
+		if err != nil {
+			return report, fmt.Errorf("failed to extract memories from session %s: %w", id, err)
+		}
+		fresh := deduper.DedupAgainst(existing, extracted)
+		result.Extracted = len(extracted)
+		result.Duplicates = len(extracted) - len(fresh)
+
+		skip := make(map[*session.ExtractedMemory]bool)
+		superseded := make(map[*session.ExtractedMemory][]*storage.Memory)
+		if opts.Conflicts != nil && len(fresh) > 0 {
+			var userMemories []*storage.Memory
+			for _, m := range known {
+				if m.UserID == users[id] {
+					userMemories = append(userMemories, m)
+				}
+			}
+			conflicts, err := opts.Conflicts.DetectConflicts(ctx, fresh, userMemories)
+			if err != nil {
+				return report, fmt.Errorf("failed to detect memory conflicts in session %s: %w", id, err)
+			}
+			for _, c := range conflicts {
+				switch opts.ConflictPolicy {
+				case ConflictPolicySkip:
+					skip[c.New] = true
+				case ConflictPolicyResolve:
+					if c.Resolution == ResolutionSupersede {
+						superseded[c.New] = append(superseded[c.New], c.Existing)
+					}
+				}
+			}
+			result.Conflicts = len(conflicts)
+			report.Conflicts = append(report.Conflicts, conflicts...)
+		}
+
+		for _, m := range fresh {
+			if skip[m] {
+				continue
+			}
+			now := time.Now()
+			memory := &storage.Memory{
+				ID:               utils.GenerateIDWithPrefix("mem"),
+				SessionID:        id,
+				UserID:           users[id],
+				Content:          m.Content,
+				Importance:       m.Importance,
+				Tags:             m.Category,
+				SourceMessageIDs: strings.Join(m.SourceMessageIDs, ","),
+				Evidence:         m.Evidence,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			if err := store.CreateMemory(ctx, memory); err != nil {
+				return report, fmt.Errorf("failed to store memory for session %s: %w", id, err)
+			}
+			existing = append(existing, m.Content)
+			known = append(known, memory)
+			result.Added++
+
+			// Only a stored memory may replace another
+			for _, old := range superseded[m] {
+				if known, err = supersede(ctx, store, known, old); err != nil {
+					return report, err
+				}
+			}
+		}
+
+		report.Sessions = append(report.Sessions, result)
+		report.Added += result.Added
+	}
+	return report, nil
+}
+
+// supersede deletes a memory replaced by a newer one and drops it from
+// known. A memory superseded twice is deleted once.
+func supersede(ctx context.Context, store storage.StorageInterface, known []*storage.Memory, old *storage.Memory) ([]*storage.Memory, error) {
+	for i, m := range known {
+		if m.ID != old.ID {
+			continue
+		}
+		if err := store.DeleteMemory(ctx, old.ID); err != nil {
+			return known, fmt.Errorf("failed to delete superseded memory %s: %w", old.ID, err)
+		}
+		return append(known[:i], known[i+1:]...), nil
+	}
+	return known, nil
+}
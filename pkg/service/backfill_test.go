@@ -0,0 +1,289 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// backfillStore adds sessions and their messages to memStore.
+type backfillStore struct {
+	*memStore
+	sessions []storage.Session
+	messages map[string][]storage.SessionMessage
+}
+
+func (b *backfillStore) QuerySessions(ctx context.Context, opts storage.QueryOptions) ([]storage.Session, error) {
+	return b.sessions, nil
+}
+
+func (b *backfillStore) GetSessionMessages(ctx context.Context, sessionID string) ([]storage.SessionMessage, error) {
+	return b.messages[sessionID], nil
+}
+
+func (b *backfillStore) CreateMemory(ctx context.Context, memory *storage.Memory) error {
+	b.memories[memory.ID] = *memory
+	return nil
+}
+
+// lineExtractor turns every user message into a memory.
+type lineExtractor struct {
+	session.MemoryExtractor
+}
+
+func (lineExtractor) Extract(ctx context.Context, messages []*session.Message) ([]*session.ExtractedMemory, error) {
+	var memories []*session.ExtractedMemory
+	for _, m := range messages {
+		if m.Role == session.RoleUser {
+			memories = append(memories, &session.ExtractedMemory{Content: m.Content, Importance: 0.5, Category: "preference",
+				SourceMessageIDs: []string{m.ID}, Evidence: m.Content})
+		}
+	}
+	return memories, nil
+}
+
+var backfillStart = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func newBackfillFixture() *backfillStore {
+	store := &backfillStore{
+		memStore: newMemStore(),
+		sessions: []storage.Session{
+			{ID: "1", SessionID: "s1", UserID: "alice"},
+			{ID: "2", SessionID: "s2", UserID: "bob"},
+		},
+		messages: map[string][]storage.SessionMessage{},
+	}
+	add := func(sessionID, role, content string, day int) {
+		store.messages[sessionID] = append(store.messages[sessionID], storage.SessionMessage{
+			ID:         sessionID + "-" + content,
+			SessionID:  sessionID,
+			Role:       role,
+			Content:    content,
+			OrderIndex: int64(len(store.messages[sessionID])),
+			CreatedAt:  backfillStart.AddDate(0, 0, day),
+		})
+	}
+	add("s1", "user", "prefers dark mode", 0)
+	add("s1", "assistant", "noted", 0)
+	add("s1", "user", "lives in Berlin", 1)
+	add("s2", "user", "prefers dark mode", 10)
+	add("s2", "user", "uses vim", 11)
+
+	store.memories["m1"] = storage.Memory{ID: "m1", SessionID: "s1", UserID: "alice", Content: "lives in Berlin"}
+	return store
+}
+
+func memoryContents(store *memStore) []string {
+	var contents []string
+	for _, m := range store.memories {
+		contents = append(contents, m.Content)
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+func TestBackfillMemories(t *testing.T) {
+	store := newBackfillFixture()
+	ctx := context.Background()
+
+	report, err := BackfillMemories(ctx, []string{"s1", "s2"}, store, lineExtractor{}, session.NewDeduper(0), BackfillOptions{})
+	if err != nil {
+		t.Fatalf("BackfillMemories failed: %v", err)
+	}
+
+	want := []SessionBackfill{
+		{SessionID: "s1", Messages: 3, Extracted: 2, Duplicates: 1, Added: 1},
+		{SessionID: "s2", Messages: 2, Extracted: 2, Duplicates: 1, Added: 1},
+	}
+	if len(report.Sessions) != len(want) {
+		t.Fatalf("Expected %d sessions, got %d", len(want), len(report.Sessions))
+	}
+	for i, w := range want {
+		if report.Sessions[i] != w {
+			t.Errorf("Expected %+v, got %+v", w, report.Sessions[i])
+		}
+	}
+	if report.Added != 2 {
+		t.Errorf("Expected 2 added, got %d", report.Added)
+	}
+
+	contents := memoryContents(store.memStore)
+	wantContents := []string{"lives in Berlin", "prefers dark mode", "uses vim"}
+	if len(contents) != len(wantContents) {
+		t.Fatalf("Expected memories %v, got %v", wantContents, contents)
+	}
+	for i := range wantContents {
+		if contents[i] != wantContents[i] {
+			t.Errorf("Expected memories %v, got %v", wantContents, contents)
+			break
+		}
+	}
+	for _, m := range store.memories {
+		if m.Content == "uses vim" && (m.SessionID != "s2" || m.UserID != "bob" || m.Tags != "preference") {
+			t.Errorf("Expected memory from s2 for bob tagged preference, got %+v", m)
+		}
+		if m.Content == "uses vim" && (m.SourceMessageIDs != "s2-uses vim" || m.Evidence != "uses vim") {
+			t.Errorf("Expected the memory's source message and evidence, got %+v", m)
+		}
+	}
+
+	// A second run finds nothing new
+	report, err = BackfillMemories(ctx, nil, store, lineExtractor{}, session.NewDeduper(0), BackfillOptions{})
+	if err != nil {
+		t.Fatalf("BackfillMemories failed: %v", err)
+	}
+	if report.Added != 0 || len(store.memories) != 3 {
+		t.Errorf("Expected rerun to add nothing, added %d (%d memories)", report.Added, len(store.memories))
+	}
+	if len(report.Sessions) != 2 {
+		t.Errorf("Expected all sessions to be backfilled, got %d", len(report.Sessions))
+	}
+}
+
+func TestBackfillMemoriesTimeRange(t *testing.T) {
+	store := newBackfillFixture()
+
+	opts := BackfillOptions{Since: backfillStart.AddDate(0, 0, 1), Until: backfillStart.AddDate(0, 0, 11)}
+	report, err := BackfillMemories(context.Background(), nil, store, lineExtractor{}, session.NewDeduper(0), opts)
+	if err != nil {
+		t.Fatalf("BackfillMemories failed: %v", err)
+	}
+
+	// s1 keeps only the Berlin message, which is already known; s2 keeps
+	// only dark mode, which is new. "uses vim" falls on the Until bound.
+	if report.Sessions[0].Messages != 1 || report.Sessions[0].Added != 0 {
+		t.Errorf("Expected s1 to replay 1 message and add nothing, got %+v", report.Sessions[0])
+	}
+	if report.Sessions[1].Messages != 1 || report.Sessions[1].Added != 1 {
+		t.Errorf("Expected s2 to replay 1 message and add 1, got %+v", report.Sessions[1])
+	}
+	contents := memoryContents(store.memStore)
+	if len(contents) != 2 || contents[1] != "prefers dark mode" {
+		t.Errorf("Expected Berlin and dark mode memories, got %v", contents)
+	}
+}
+
+// contradictionProvider reports a stored "prefers light mode" memory as
+// superseded by a new "prefers dark mode" one, finding both in the prompt.
+type contradictionProvider struct {
+	llm.Provider
+}
+
+func (contradictionProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	prompt := req.Messages[len(req.Messages)-1].Content
+	newList, storedList, _ := strings.Cut(prompt[strings.Index(prompt, "New memories:"):], "Stored memories:")
+	index := func(list, content string) string {
+		m := regexp.MustCompile(`\[(\d+)\] ` + content).FindStringSubmatch(list)
+		if m == nil {
+			return "0"
+		}
+		return m[1]
+	}
+	reply := "```json\n" + `{"conflicts": [{"new": ` + index(newList, "prefers dark mode") +
+		`, "existing": ` + index(storedList, "prefers light mode") +
+		`, "reason": "the display preference changed", "resolution": "supersede"}]}` + "\n```"
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Role: llm.RoleAssistant, Content: reply}}}}, nil
+}
+
+// modelRecorder records the model of each chat request.
+type modelRecorder struct {
+	llm.Provider
+	models []string
+}
+
+func (r *modelRecorder) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	r.models = append(r.models, req.Model)
+	return r.Provider.Chat(ctx, req)
+}
+
+func TestLLMConflictDetector(t *testing.T) {
+	recorder := &modelRecorder{Provider: contradictionProvider{}}
+	detector := NewLLMConflictDetector(recorder, "checker-model")
+	fresh := []*session.ExtractedMemory{{Content: "uses vim"}, {Content: "prefers dark mode"}}
+	existing := []*storage.Memory{{ID: "m1", Content: "lives in Berlin"}, {ID: "m2", Content: "prefers light mode"}}
+
+	conflicts, err := detector.DetectConflicts(context.Background(), fresh, existing)
+	if err != nil {
+		t.Fatalf("DetectConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(conflicts))
+	}
+	c := conflicts[0]
+	if c.New != fresh[1] || c.Existing != existing[1] || c.Resolution != ResolutionSupersede || c.Reason == "" {
+		t.Errorf("Expected dark mode to supersede light mode, got %+v", c)
+	}
+	if len(recorder.models) != 1 || recorder.models[0] != "checker-model" {
+		t.Errorf("Expected the configured model asked, got %v", recorder.models)
+	}
+
+	// Pairs naming unknown memories are dropped
+	conflicts, err = detector.DetectConflicts(context.Background(), fresh[:1], existing)
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v (%v)", conflicts, err)
+	}
+
+	if _, err := NewLLMConflictDetector(&planProvider{reply: "none"}, "").DetectConflicts(context.Background(), fresh, existing); err == nil {
+		t.Error("Expected an error for a reply without JSON")
+	}
+}
+
+func TestBackfillMemoriesConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy ConflictPolicy
+		want   []string
+	}{
+		{ConflictPolicyReport, []string{"lives in Berlin", "prefers dark mode", "prefers light mode"}},
+		{ConflictPolicySkip, []string{"lives in Berlin", "prefers light mode"}},
+		{ConflictPolicyResolve, []string{"lives in Berlin", "prefers dark mode"}},
+	}
+	for _, tt := range tests {
+		store := newBackfillFixture()
+		store.memories["m2"] = storage.Memory{ID: "m2", SessionID: "s0", UserID: "alice", Content: "prefers light mode"}
+
+		opts := BackfillOptions{Conflicts: NewLLMConflictDetector(contradictionProvider{}, ""), ConflictPolicy: tt.policy}
+		report, err := BackfillMemories(context.Background(), []string{"s1"}, store, lineExtractor{}, session.NewDeduper(0), opts)
+		if err != nil {
+			t.Fatalf("%s: BackfillMemories failed: %v", tt.policy, err)
+		}
+		if len(report.Conflicts) != 1 || report.Sessions[0].Conflicts != 1 || report.Conflicts[0].Existing.ID != "m2" {
+			t.Errorf("%s: Expected the conflict with m2 reported, got %+v", tt.policy, report)
+		}
+		if got := memoryContents(store.memStore); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: Expected memories %v, got %v", tt.policy, tt.want, got)
+		}
+	}
+}
+
+// failingMemoryStore fails to store new memories.
+type failingMemoryStore struct {
+	*backfillStore
+}
+
+func (failingMemoryStore) CreateMemory(ctx context.Context, memory *storage.Memory) error {
+	return errors.New("disk full")
+}
+
+func TestBackfillMemoriesResolveKeepsOldOnFailure(t *testing.T) {
+	store := newBackfillFixture()
+	store.memories["m2"] = storage.Memory{ID: "m2", SessionID: "s0", UserID: "alice", Content: "prefers light mode"}
+
+	opts := BackfillOptions{Conflicts: NewLLMConflictDetector(contradictionProvider{}, ""), ConflictPolicy: ConflictPolicyResolve}
+	if _, err := BackfillMemories(context.Background(), []string{"s1"}, failingMemoryStore{store}, lineExtractor{}, session.NewDeduper(0), opts); err == nil {
+		t.Fatal("Expected the failed store reported")
+	}
+	if _, ok := store.memories["m2"]; !ok {
+		t.Error("Expected the superseded memory kept when its replacement was not stored")
+	}
+}
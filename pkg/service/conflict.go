@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/session"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// ConflictResolution is the suggested handling of a memory conflict.
+type ConflictResolution string
+
+const (
+	// ResolutionSupersede replaces the existing memory with the new one.
+	ResolutionSupersede ConflictResolution = "supersede"
+	// ResolutionKeepBoth keeps both memories, e.g. when each holds in a
+	// different setting.
+	ResolutionKeepBoth ConflictResolution = "keep_both"
+)
+
+// MemoryConflict pairs a new memory with an existing one it contradicts.
+type MemoryConflict struct {
+	New        *session.ExtractedMemory `json:"new"`
+	Existing   *storage.Memory          `json:"existing"`
+	Reason     string                   `json:"reason,omitempty"`
+	Resolution ConflictResolution       `json:"resolution"`
+}
+
+// ConflictDetector finds new memories that contradict existing ones.
+// *LLMConflictDetector implements this interface.
+type ConflictDetector interface {
+	DetectConflicts(ctx context.Context, fresh []*session.ExtractedMemory, existing []*storage.Memory) ([]MemoryConflict, error)
+}
+
+// LLMConflictDetector asks an LLM which new memories contradict existing
+// ones.
+type LLMConflictDetector struct {
+	client llm.Provider
+	model  string
+}
+
+// NewLLMConflictDetector creates a conflict detector asking model through
+// client.
+func NewLLMConflictDetector(client llm.Provider, model string) *LLMConflictDetector {
+	return &LLMConflictDetector{client: client, model: model}
+}
+
+const conflictPrompt = `Compare new memories about a user with the memories already stored.
+Report each new memory that contradicts a stored one, such as a changed
+preference or a corrected fact. Memories that merely differ or add detail
+are not conflicts.
+
+For each conflict suggest "supersede" when the new memory replaces the
+stored one, or "keep_both" when both can hold, e.g. in different settings.
+Return only a JSON object, with an empty list when nothing conflicts:
+{"conflicts": [{"new": 1, "existing": 2, "reason": "...", "resolution": "supersede|keep_both"}]}
+
+New memories:
+%s
+Stored memories:
+%s`
+
+// DetectConflicts asks the LLM for contradictions between fresh and
+// existing memories. Pairs naming unknown memories are dropped, and an unknown
+// resolution is read as keep_both.
+func (d *LLMConflictDetector) DetectConflicts(ctx context.Context, fresh []*session.ExtractedMemory, existing []*storage.Memory) ([]MemoryConflict, error) {
+	if len(fresh) == 0 || len(existing) == 0 {
+		return nil, nil
+	}
+
+	var newList, existingList strings.Builder
+	for i, m := range fresh {
+		fmt.Fprintf(&newList, "[%d] %s\n", i+1, m.Content)
+	}
+	for i, m := range existing {
+		fmt.Fprintf(&existingList, "[%d] %s\n", i+1, m.Content)
+	}
+
+	resp, err := d.client.Chat(ctx, &llm.ChatRequest{
+		Model:       d.model,
+		Temperature: 0.2,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are a memory consistency checker. Reply with JSON only."},
+			{Role: llm.RoleUser, Content: fmt.Sprintf(conflictPrompt, newList.String(), existingList.String())},
+		},
+		MaxTokens: 1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty conflict detector response")
+	}
+
+	pairs, err := parseConflicts(resp.Choices[0].Message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []MemoryConflict
+	for _, p := range pairs {
+		if p.New < 1 || p.New > len(fresh) || p.Existing < 1 || p.Existing > len(existing) {
+			continue
+		}
+		resolution := p.Resolution
+		if resolution != ResolutionSupersede {
+			resolution = ResolutionKeepBoth
+		}
+		conflicts = append(conflicts, MemoryConflict{
+			New:        fresh[p.New-1],
+			Existing:   existing[p.Existing-1],
+			Reason:     p.Reason,
+			Resolution: resolution,
+		})
+	}
+	return conflicts, nil
+}
+
+// conflictPair is a conflict as reported by the LLM, by 1-based index.
+type conflictPair struct {
+	New        int                `json:"new"`
+	Existing   int                `json:"existing"`
+	Reason     string             `json:"reason"`
+	Resolution ConflictResolution `json:"resolution"`
+}
+
+// parseConflicts decodes conflicts from an LLM reply, which may wrap the
+// JSON object in a markdown code block or surrounding text.
+func parseConflicts(reply string) ([]conflictPair, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in conflict detector response")
+	}
+
+	var result struct {
+		Conflicts []conflictPair `json:"conflicts"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse conflicts: %w", err)
+	}
+	return result.Conflicts, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// StoreContent is a ContentSource reading context content from storage,
+// including content kept outside the contexts table.
+type StoreContent struct {
+	store storage.StorageInterface
+}
+
+// NewStoreContent creates a StoreContent reading contexts from store.
+func NewStoreContent(store storage.StorageInterface) *StoreContent {
+	return &StoreContent{store: store}
+}
+
+// Content returns the content of the context at uri, or "" when no
+// context is stored there.
+func (c *StoreContent) Content(ctx context.Context, uri string) (string, error) {
+	contexts, err := c.store.QueryContexts(ctx, storage.QueryOptions{
+		Filter: &storage.Filter{Op: "and", Conds: []storage.FilterCondition{{Op: "must", Field: "uri", Value: uri}}},
+		Limit:  1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", uri, err)
+	}
+	if len(contexts) == 0 {
+		return "", nil
+	}
+	if contexts[0].ContentRef == "" {
+		return contexts[0].Content, nil
+	}
+
+	// Queries leave externalized content unloaded
+	full, err := c.store.GetContext(ctx, contexts[0].ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load content of %s: %w", uri, err)
+	}
+	if full == nil {
+		return "", nil
+	}
+	return full.Content, nil
+}
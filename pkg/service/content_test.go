@@ -0,0 +1,53 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// externalStore is a uriStore whose GetContext loads externalized content.
+type externalStore struct {
+	uriStore
+	external map[string]string // id -> content
+}
+
+func (s externalStore) GetContext(ctx context.Context, id string) (*storage.Context, error) {
+	for _, c := range s.contexts {
+		if c.ID == id {
+			c.Content = s.external[id]
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestStoreContent(t *testing.T) {
+	store := externalStore{
+		uriStore: uriStore{contexts: map[string]storage.Context{
+			"viking://resources/inline":   {ID: "1", URI: "viking://resources/inline", Content: "inline text"},
+			"viking://resources/external": {ID: "2", URI: "viking://resources/external", ContentRef: "ab/cdef"},
+		}},
+		external: map[string]string{"2": "external text"},
+	}
+	source := NewStoreContent(store)
+
+	ctx := context.Background()
+	for uri, want := range map[string]string{
+		"viking://resources/inline":   "inline text",
+		"viking://resources/external": "external text",
+		"viking://resources/missing":  "",
+	} {
+		got, err := source.Content(ctx, uri)
+		if err != nil {
+			t.Fatalf("Content(%s) failed: %v", uri, err)
+		}
+		if got != want {
+			t.Errorf("Expected content %q for %s, got %q", want, uri, got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// DefaultExpansionWeight is the share of its score an expanded-only match
+// keeps, so matches for the original wording rank first.
+const DefaultExpansionWeight = 0.8
+
+// maxQueryExpansions caps the alternative queries taken from an LLM.
+const maxQueryExpansions = 3
+
+// QueryExpander rewrites a query into alternative queries that may match
+// contexts using different wording. *SynonymExpander and
+// *LLMQueryExpander implement this interface.
+type QueryExpander interface {
+	// Expand returns the alternative queries, without the original. None
+	// means the query has no useful expansion.
+	Expand(ctx context.Context, query string) ([]string, error)
+}
+
+// SetQueryExpander sets the expander whose queries Search runs alongside
+// the original. Without one, only the original query is searched.
+func (s *SearchService) SetQueryExpander(e QueryExpander) {
+	s.expander = e
+}
+
+// SetExpansionWeight sets the share of its score a match found only by an
+// expanded query keeps, between 0 and 1.
+func (s *SearchService) SetExpansionWeight(weight float64) {
+	s.expansionWeight = weight
+}
+
+// expandedQueries returns the expander's alternatives to query, minus any
+// repeat of the query itself. Expansion is best effort: an expander error
+// other than cancellation leaves only the original query.
+func (s *SearchService) expandedQueries(ctx context.Context, query string) ([]string, error) {
+	if s.expander == nil {
+		return nil, nil
+	}
+	expansions, err := s.expander.Expand(ctx, query)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}
+
+	var queries []string
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	for _, q := range expansions {
+		key := strings.ToLower(strings.TrimSpace(q))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, strings.TrimSpace(q))
+	}
+	return queries, nil
+}
+
+// blendResults adds the results of an expanded query to results, scaling
+// their scores by weight. A context matched by several queries keeps its
+// best score.
+func blendResults(results, expanded []SearchResult, weight float64) []SearchResult {
+	index := make(map[string]int, len(results))
+	for i, r := range results {
+		index[r.URI] = i
+	}
+	for _, r := range expanded {
+		r.Score *= weight
+		if i, ok := index[r.URI]; ok {
+			if r.Score > results[i].Score {
+				results[i].Score = r.Score
+			}
+			continue
+		}
+		index[r.URI] = len(results)
+		results = append(results, r)
+	}
+	sortByScore(results)
+	return results
+}
+
+// SynonymExpander expands a query by adding the synonyms of its words.
+type SynonymExpander struct {
+	synonyms map[string][]string
+}
+
+// NewSynonymExpander creates an expander from a map of lowercase words to
+// their synonyms.
+func NewSynonymExpander(synonyms map[string][]string) *SynonymExpander {
+	normalized := make(map[string][]string, len(synonyms))
+	for word, syns := range synonyms {
+		key := strings.ToLower(word)
+		normalized[key] = append(normalized[key], syns...)
+	}
+	return &SynonymExpander{synonyms: normalized}
+}
+
+// Expand returns the query with the synonyms of each word after it, or
+// nothing when no word has synonyms.
+func (e *SynonymExpander) Expand(ctx context.Context, query string) ([]string, error) {
+	var terms []string
+	expanded := false
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, word)
+		key := strings.ToLower(strings.Trim(word, ".,;:!?\"'()"))
+		for _, syn := range e.synonyms[key] {
+			terms = append(terms, syn)
+			expanded = true
+		}
+	}
+	if !expanded {
+		return nil, nil
+	}
+	return []string{strings.Join(terms, " ")}, nil
+}
+
+// LLMQueryExpander asks an LLM for alternative phrasings of a query.
+type LLMQueryExpander struct {
+	client llm.Provider
+}
+
+// NewLLMQueryExpander creates a query expander backed by client.
+func NewLLMQueryExpander(client llm.Provider) *LLMQueryExpander {
+	return &LLMQueryExpander{client: client}
+}
+
+const queryExpansionPrompt = `Rewrite the search query below into up to %d alternative queries
+that use different wording, such as synonyms or related terms, for the same
+information need. Return one query per line with no numbering or commentary.
+
+Query: %s`
+
+// listMarker matches a bullet or number starting a list item.
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// Expand asks the LLM for up to three alternative queries.
+func (e *LLMQueryExpander) Expand(ctx context.Context, query string) ([]string, error) {
+	resp, err := e.client.Chat(ctx, &llm.ChatRequest{
+		Temperature: 0.3,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are a search query rewriter. Reply with queries only."},
+			{Role: llm.RoleUser, Content: fmt.Sprintf(queryExpansionPrompt, maxQueryExpansions, query)},
+		},
+		MaxTokens: 200,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty expander response")
+	}
+
+	var queries []string
+	for _, line := range strings.Split(resp.Choices[0].Message.Content, "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		queries = append(queries, line)
+		if len(queries) == maxQueryExpansions {
+			break
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/retrieval"
+)
+
+// termRetriever matches resources whose abstract contains a word of the
+// query, scoring each match 0.9.
+type termRetriever struct {
+	abstracts map[string]string // URI -> abstract
+}
+
+func (r *termRetriever) Retrieve(ctx context.Context, query retrieval.TypedQuery, opts retrieval.SearchOptions) (*retrieval.QueryResult, error) {
+	qr := &retrieval.QueryResult{Query: query}
+	if query.ContextType != retrieval.ContextTypeResource {
+		return qr, nil
+	}
+	words := strings.FieldsFunc(strings.ToLower(query.Query), func(r rune) bool { return r == ' ' || r == ',' })
+	for uri, abstract := range r.abstracts {
+		for _, word := range words {
+			if strings.Contains(abstract, word) {
+				qr.MatchedContexts = append(qr.MatchedContexts, retrieval.MatchedContext{
+					URI: uri, ContextType: retrieval.ContextTypeResource, Abstract: abstract, Score: 0.9,
+				})
+				break
+			}
+		}
+	}
+	return qr, nil
+}
+
+// stubExpander returns canned expansions, or err.
+type stubExpander struct {
+	expansions map[string][]string
+	err        error
+}
+
+func (e *stubExpander) Expand(ctx context.Context, query string) ([]string, error) {
+	return e.expansions[query], e.err
+}
+
+func newExpansionService() *SearchService {
+	svc := NewSearchService()
+	svc.SetRetriever(&termRetriever{abstracts: map[string]string{
+		"viking://resources/parser": "fast json parser",
+		"viking://resources/sort":   "quick sort implementation",
+		"viking://resources/server": "performant http server",
+		"viking://resources/db":     "slow database driver",
+	}})
+	return svc
+}
+
+func TestSearchQueryExpansion(t *testing.T) {
+	svc := newExpansionService()
+
+	results, err := svc.Search(context.Background(), &SearchRequest{Query: "fast"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := resultURIs(results); !reflect.DeepEqual(got, []string{"viking://resources/parser"}) {
+		t.Fatalf("Expected only the literal match without expansion, got %v", got)
+	}
+
+	svc.SetQueryExpander(&stubExpander{expansions: map[string][]string{"fast": {"fast, quick, performant"}}})
+	results, err = svc.Search(context.Background(), &SearchRequest{Query: "fast"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	got := resultURIs(results)
+	if len(got) != 3 || got[0] != "viking://resources/parser" {
+		t.Fatalf("Expected the literal match first, then the expanded matches, got %v", got)
+	}
+	for _, r := range results[1:] {
+		if r.URI != "viking://resources/sort" && r.URI != "viking://resources/server" {
+			t.Errorf("Expected only expanded matches after the literal one, got %s", r.URI)
+		}
+		if want := 0.9 * DefaultExpansionWeight; math.Abs(r.Score-want) > 1e-9 {
+			t.Errorf("Expected expanded match %s scored %v, got %v", r.URI, want, r.Score)
+		}
+	}
+	if results[0].Score != 0.9 {
+		t.Errorf("Expected the literal match to keep its score, got %v", results[0].Score)
+	}
+}
+
+func TestSearchQueryExpansionFailureKeepsOriginal(t *testing.T) {
+	svc := newExpansionService()
+	svc.SetQueryExpander(&stubExpander{err: errors.New("connection refused")})
+
+	results, err := svc.Search(context.Background(), &SearchRequest{Query: "fast"})
+	if err != nil {
+		t.Fatalf("Expected search to succeed without expansion, got %v", err)
+	}
+	if got := resultURIs(results); !reflect.DeepEqual(got, []string{"viking://resources/parser"}) {
+		t.Errorf("Expected the original query's results, got %v", got)
+	}
+}
+
+func TestSynonymExpander(t *testing.T) {
+	e := NewSynonymExpander(map[string][]string{"Fast": {"quick", "performant"}})
+
+	got, err := e.Expand(context.Background(), "fast parser")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"fast quick performant parser"}) {
+		t.Errorf("Expected synonyms after their word, got %v", got)
+	}
+	if got, _ := e.Expand(context.Background(), "slow parser"); got != nil {
+		t.Errorf("Expected no expansion without synonyms, got %v", got)
+	}
+}
+
+// replyProvider answers every chat with reply.
+type replyProvider struct {
+	llm.Provider
+	reply string
+}
+
+func (p *replyProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Content: p.reply}}}}, nil
+}
+
+func TestLLMQueryExpander(t *testing.T) {
+	e := NewLLMQueryExpander(&replyProvider{reply: "1. quick parser\n- 3D printing speed\n\n* performant parser\nrapid parser"})
+
+	got, err := e.Expand(context.Background(), "fast parser")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	want := []string{"quick parser", "3D printing speed", "performant parser"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+)
+
+// Traversal event types.
+const (
+	// TraversalConverged marks the directory after which the top results
+	// stopped changing and the search ended.
+	TraversalConverged = "converged"
+	// TraversalBelowThreshold marks a subdirectory that was not explored
+	// because its score fell below the similarity threshold.
+	TraversalBelowThreshold = "below_threshold"
+	// TraversalMaxDepth marks a subdirectory that was not explored because
+	// it lies beyond the maximum depth.
+	TraversalMaxDepth = "max_depth"
+	// TraversalMaxDirectories marks the directory after which the search
+	// stopped because it had visited the maximum number of directories.
+	TraversalMaxDirectories = "max_directories_visited"
+	// TraversalEmbeddingFailed marks a search that went on without a query
+	// vector because the query could not be embedded.
+	TraversalEmbeddingFailed = "embedding_failed"
+)
+
+// TraversalStep is a directory visited by the retriever.
+type TraversalStep struct {
+	URI string `json:"uri"`
+	// Parent is the directory the step was reached from, empty for
+	// starting points.
+	Parent string  `json:"parent,omitempty"`
+	Depth  int     `json:"depth"`
+	Score  float64 `json:"score"`
+}
+
+// TraversalEvent records where the retriever converged or pruned the tree.
+type TraversalEvent struct {
+	Type    string  `json:"type"`
+	URI     string  `json:"uri,omitempty"`
+	Parent  string  `json:"parent,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+	Message string  `json:"message"`
+}
+
+// Traversal is the path the retriever took through the context tree for
+// one context type. Steps are in visiting order.
+type Traversal struct {
+	ContextType string           `json:"context_type"`
+	Steps       []TraversalStep  `json:"steps"`
+	Events      []TraversalEvent `json:"events,omitempty"`
+}
+
+// SearchExplanation is a search's results together with the traversals
+// that produced them.
+type SearchExplanation struct {
+	Query      string         `json:"query"`
+	Results    []SearchResult `json:"results"`
+	Traversals []Traversal    `json:"traversals"`
+}
+
+// Explain performs a search like Search and reports how the retriever
+// traversed the context tree to find the results.
+func (s *SearchService) Explain(ctx context.Context, req *SearchRequest) (*SearchExplanation, error) {
+	results, traversals, err := s.search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if traversals == nil {
+		traversals = []Traversal{}
+	}
+	return &SearchExplanation{Query: req.Query, Results: results, Traversals: traversals}, nil
+}
+
+// toTraversal extracts the visited directories from a query result's
+// trajectory, and the convergence and pruning decisions from its trace.
+func toTraversal(contextType retrieval.ContextType, qr *retrieval.QueryResult) Traversal {
+	t := Traversal{ContextType: string(contextType), Steps: []TraversalStep{}}
+
+	last := ""
+	if tr := qr.Trajectory; tr != nil {
+		for _, uri := range tr.Path {
+			node := tr.Nodes[uri]
+			t.Steps = append(t.Steps, TraversalStep{
+				URI:    uri,
+				Parent: tr.Parents[uri],
+				Depth:  node.Depth,
+				Score:  node.Score,
+			})
+			last = uri
+		}
+	}
+
+	if qr.ThinkingTrace == nil {
+		return t
+	}
+	for _, e := range qr.ThinkingTrace.Events {
+		event := TraversalEvent{Message: e.Message}
+		event.URI, _ = e.Data["uri"].(string)
+		event.Parent, _ = e.Data["parent"].(string)
+		event.Score, _ = e.Data["score"].(float64)
+
+		switch e.EventType {
+		case retrieval.TraceEventSearchConverged:
+			event.Type = TraversalConverged
+			event.URI = last
+		case retrieval.TraceEventTraversalLimit:
+			event.Type, _ = e.Data["reason"].(string)
+			if event.Type == TraversalMaxDirectories {
+				event.URI = last
+			}
+		case retrieval.TraceEventEmbeddingFailed:
+			event.Type = TraversalEmbeddingFailed
+		case retrieval.TraceEventCandidateExcluded:
+			// Only directories below the threshold prune the traversal;
+			// leaves below it are rejected results, and directories left
+			// out of leaf-only results are still traversed
+			if reason, _ := e.Data["reason"].(string); reason != TraversalBelowThreshold {
+				continue
+			}
+			if isLeaf, _ := e.Data["is_leaf"].(bool); isLeaf {
+				continue
+			}
+			event.Type = TraversalBelowThreshold
+		default:
+			continue
+		}
+		t.Events = append(t.Events, event)
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+)
+
+func TestToTraversal(t *testing.T) {
+	trajectory := retrieval.NewTrajectory("docs")
+	trajectory.AddNode("viking://resources", 0, 0, nil)
+	trajectory.AddEdge("viking://resources", "viking://resources/docs")
+	trajectory.AddNode("viking://resources/docs", 1, 0.8, nil)
+
+	trace := &retrieval.ThinkingTrace{}
+	trace.AddEvent(retrieval.TraceEventCandidateExcluded, "excluded leaf",
+		map[string]interface{}{"uri": "viking://resources/a.md", "parent": "viking://resources", "score": 0.1, "is_leaf": true, "reason": "below_threshold"}, "docs")
+	trace.AddEvent(retrieval.TraceEventCandidateExcluded, "excluded dir",
+		map[string]interface{}{"uri": "viking://resources/old", "parent": "viking://resources", "score": 0.2, "is_leaf": false, "reason": "below_threshold"}, "docs")
+	trace.AddEvent(retrieval.TraceEventCandidateExcluded, "Excluded directory viking://resources/docs from results",
+		map[string]interface{}{"uri": "viking://resources/docs", "parent": "viking://resources", "score": 0.8, "is_leaf": false, "reason": "not_leaf"}, "docs")
+	trace.AddEvent(retrieval.TraceEventSearchConverged, "Search converged", map[string]interface{}{"rounds": 3}, "docs")
+
+	got := toTraversal(retrieval.ContextTypeResource, &retrieval.QueryResult{Trajectory: trajectory, ThinkingTrace: trace})
+
+	if got.ContextType != "resource" || len(got.Steps) != 2 {
+		t.Fatalf("Expected 2 resource steps, got %+v", got)
+	}
+	if step := got.Steps[1]; step.URI != "viking://resources/docs" || step.Parent != "viking://resources" || step.Depth != 1 || step.Score != 0.8 {
+		t.Errorf("Expected docs step under resources, got %+v", step)
+	}
+	want := []TraversalEvent{
+		{Type: TraversalBelowThreshold, URI: "viking://resources/old", Parent: "viking://resources", Score: 0.2, Message: "excluded dir"},
+		{Type: TraversalConverged, URI: "viking://resources/docs", Message: "Search converged"},
+	}
+	if len(got.Events) != len(want) {
+		t.Fatalf("Expected events %+v, got %+v", want, got.Events)
+	}
+	for i := range want {
+		if got.Events[i] != want[i] {
+			t.Errorf("Expected event %+v, got %+v", want[i], got.Events[i])
+		}
+	}
+}
+
+func TestSearchServiceExplain(t *testing.T) {
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+
+	explanation, err := svc.Explain(context.Background(), &SearchRequest{Query: "go", Filters: map[string]string{"type": "resource"}})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if explanation.Query != "go" || len(explanation.Results) == 0 {
+		t.Errorf("Expected results for go, got %+v", explanation)
+	}
+	if len(explanation.Traversals) != 1 || explanation.Traversals[0].ContextType != "resource" {
+		t.Errorf("Expected one resource traversal, got %+v", explanation.Traversals)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/retrieval"
+)
+
+// QueryPlanner turns a query into typed queries for the context types to
+// search. *LLMQueryPlanner implements this interface.
+type QueryPlanner interface {
+	Plan(ctx context.Context, query string, contextTypes []retrieval.ContextType) (*retrieval.QueryPlan, error)
+}
+
+// FindRequest is a search whose results are grouped by context type.
+type FindRequest struct {
+	Query     string
+	SessionID string
+	// ContextTypes limits the search to these types; empty searches
+	// memories, resources and skills.
+	ContextTypes []retrieval.ContextType
+	// Limit caps the results in each bucket, the service's default when
+	// zero. Find replaces it with the limit applied, clamped to the
+	// results cap.
+	Limit int
+	// Trace includes the query plan and per-query results in the result.
+	Trace bool
+}
+
+// SetQueryPlanner sets the planner used by Find. Without one, Find sends
+// the query unchanged to every requested context type.
+func (s *SearchService) SetQueryPlanner(p QueryPlanner) {
+	s.planner = p
+}
+
+// Find plans the query, runs each planned query against the retriever and
+// returns the matches grouped into memories, resources and skills. A
+// context matched by several queries appears once, with its best score.
+func (s *SearchService) Find(ctx context.Context, req *FindRequest) (*retrieval.FindResult, error) {
+	req.Limit = s.effectiveLimit(req.Limit)
+	contextTypes := req.ContextTypes
+	if len(contextTypes) == 0 {
+		contextTypes = searchableTypes
+	}
+	for _, t := range contextTypes {
+		if !t.Valid() {
+			return nil, fmt.Errorf("invalid context type: %q", t)
+		}
+	}
+
+	plan := defaultQueryPlan(req.Query, contextTypes)
+	if s.planner != nil {
+		var err error
+		plan, err = s.planner.Plan(ctx, req.Query, contextTypes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan query: %w", err)
+		}
+	}
+
+	result := &retrieval.FindResult{
+		Memories:  []retrieval.MatchedContext{},
+		Resources: []retrieval.MatchedContext{},
+		Skills:    []retrieval.MatchedContext{},
+	}
+	if req.Trace {
+		result.QueryPlan = plan
+	}
+	if s.retriever == nil {
+		return result, nil
+	}
+
+	opts := s.searchOptions
+	opts.Limit = req.Limit
+	best := make(map[string]retrieval.MatchedContext)
+	for _, query := range plan.Queries {
+		qr, err := s.retriever.Retrieve(ctx, query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve %s contexts: %w", query.ContextType, err)
+		}
+		if req.Trace {
+			result.QueryResults = append(result.QueryResults, *qr)
+		}
+		for _, mc := range qr.MatchedContexts {
+			if mc.ContextType == "" {
+				mc.ContextType = query.ContextType
+			}
+			if prev, ok := best[mc.URI]; !ok || mc.Score > prev.Score {
+				best[mc.URI] = mc
+			}
+		}
+	}
+
+	for _, mc := range best {
+		switch mc.ContextType {
+		case retrieval.ContextTypeMemory:
+			result.Memories = append(result.Memories, mc)
+		case retrieval.ContextTypeResource:
+			result.Resources = append(result.Resources, mc)
+		case retrieval.ContextTypeSkill:
+			result.Skills = append(result.Skills, mc)
+		}
+	}
+	result.Memories = topMatches(result.Memories, req.Limit)
+	result.Resources = topMatches(result.Resources, req.Limit)
+	result.Skills = topMatches(result.Skills, req.Limit)
+	result.Total = len(result.Memories) + len(result.Resources) + len(result.Skills)
+
+	return result, nil
+}
+
+// defaultQueryPlan sends query unchanged to each context type.
+func defaultQueryPlan(query string, contextTypes []retrieval.ContextType) *retrieval.QueryPlan {
+	plan := &retrieval.QueryPlan{}
+	for _, t := range contextTypes {
+		plan.Queries = append(plan.Queries, retrieval.TypedQuery{Query: query, ContextType: t})
+	}
+	return plan
+}
+
+// topMatches sorts matches by score descending, breaking ties by URI, and
+// keeps the first limit.
+func topMatches(matches []retrieval.MatchedContext, limit int) []retrieval.MatchedContext {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].URI < matches[j].URI
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// LLMQueryPlanner asks an LLM to rewrite a query into one or more queries
+// per context type.
+type LLMQueryPlanner struct {
+	client llm.Provider
+}
+
+// NewLLMQueryPlanner creates a query planner backed by client.
+func NewLLMQueryPlanner(client llm.Provider) *LLMQueryPlanner {
+	return &LLMQueryPlanner{client: client}
+}
+
+const queryPlanPrompt = `Plan a search over an agent's context store for the query below.
+Memories hold facts about the user and past work, resources hold documents
+and code, and skills hold tools the agent can use.
+
+Write one or more focused queries for each relevant type among: %s.
+Return only a JSON object:
+{"queries": [{"query": "...", "context_type": "memory|resource|skill", "intent": "...", "priority": 1}], "reasoning": "..."}
+
+Query: %s`
+
+// Plan asks the LLM for a query plan. Queries for types outside
+// contextTypes are dropped, and a plan left with none is an error.
+func (p *LLMQueryPlanner) Plan(ctx context.Context, query string, contextTypes []retrieval.ContextType) (*retrieval.QueryPlan, error) {
+	names := make([]string, len(contextTypes))
+	allowed := make(map[retrieval.ContextType]bool)
+	for i, t := range contextTypes {
+		names[i] = string(t)
+		allowed[t] = true
+	}
+
+	resp, err := p.client.Chat(ctx, &llm.ChatRequest{
+		Temperature: 0.2,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are a retrieval planner. Reply with JSON only."},
+			{Role: llm.RoleUser, Content: fmt.Sprintf(queryPlanPrompt, strings.Join(names, ", "), query)},
+		},
+		MaxTokens: 1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty planner response")
+	}
+
+	plan, err := parseQueryPlan(resp.Choices[0].Message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := plan.Queries[:0]
+	for _, q := range plan.Queries {
+		t, err := retrieval.ParseContextType(string(q.ContextType))
+		if err != nil || !allowed[t] || strings.TrimSpace(q.Query) == "" {
+			continue
+		}
+		q.ContextType = t
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("planner returned no usable queries")
+	}
+	plan.Queries = queries
+	return plan, nil
+}
+
+// parseQueryPlan decodes a plan from an LLM reply, which may wrap the JSON
+// object in a markdown code block or surrounding text.
+func parseQueryPlan(reply string) (*retrieval.QueryPlan, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in planner response")
+	}
+
+	var plan retrieval.QueryPlan
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	return &plan, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/retrieval"
+)
+
+// stubPlanner returns a fixed plan.
+type stubPlanner struct {
+	plan *retrieval.QueryPlan
+}
+
+func (p stubPlanner) Plan(ctx context.Context, query string, contextTypes []retrieval.ContextType) (*retrieval.QueryPlan, error) {
+	return p.plan, nil
+}
+
+func TestSearchServiceFind(t *testing.T) {
+	fake := newFakeRetriever()
+	fake.matches[retrieval.ContextTypeSkill] = []retrieval.MatchedContext{
+		{URI: "viking://agent/skills/bash", ContextType: retrieval.ContextTypeSkill, Score: 0.7},
+	}
+	svc := NewSearchService()
+	svc.SetRetriever(fake)
+
+	result, err := svc.Find(context.Background(), &FindRequest{Query: "guide"})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(result.Memories) != 1 || len(result.Resources) != 2 || len(result.Skills) != 1 {
+		t.Fatalf("Expected 1 memory, 2 resources and 1 skill, got %+v", result)
+	}
+	for _, mc := range result.Resources {
+		if mc.ContextType != retrieval.ContextTypeResource {
+			t.Errorf("Expected only resources in the resource bucket, got %+v", mc)
+		}
+	}
+	if result.Resources[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected resources by score, got %+v", result.Resources)
+	}
+	if result.Total != 4 {
+		t.Errorf("Expected total 4, got %d", result.Total)
+	}
+	if result.QueryPlan != nil || result.QueryResults != nil {
+		t.Errorf("Expected no trace without Trace, got %+v", result)
+	}
+}
+
+func TestSearchServiceFindDedupsPlannedQueries(t *testing.T) {
+	fake := newFakeRetriever()
+	svc := NewSearchService()
+	svc.SetRetriever(fake)
+	// Both resource queries match the same two resources
+	svc.SetQueryPlanner(stubPlanner{plan: &retrieval.QueryPlan{Queries: []retrieval.TypedQuery{
+		{Query: "go guide", ContextType: retrieval.ContextTypeResource},
+		{Query: "python guide", ContextType: retrieval.ContextTypeResource},
+		{Query: "editor", ContextType: retrieval.ContextTypeMemory},
+	}}})
+
+	result, err := svc.Find(context.Background(), &FindRequest{Query: "guides", Trace: true})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(fake.queries) != 3 {
+		t.Errorf("Expected the 3 planned queries, got %+v", fake.queries)
+	}
+	if len(result.Resources) != 2 || len(result.Memories) != 1 || len(result.Skills) != 0 {
+		t.Errorf("Expected deduplicated buckets, got %+v", result)
+	}
+	if sum := len(result.Memories) + len(result.Resources) + len(result.Skills); result.Total != sum {
+		t.Errorf("Expected total %d, got %d", sum, result.Total)
+	}
+	if result.QueryPlan == nil || len(result.QueryResults) != 3 {
+		t.Errorf("Expected the plan and 3 query results with Trace, got %+v", result)
+	}
+}
+
+func TestSearchServiceFindInvalidType(t *testing.T) {
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+
+	if _, err := svc.Find(context.Background(), &FindRequest{Query: "x", ContextTypes: []retrieval.ContextType{"document"}}); err == nil {
+		t.Error("Expected an error for an invalid context type")
+	}
+}
+
+// planProvider answers every chat with reply.
+type planProvider struct {
+	llm.Provider
+	reply string
+}
+
+func (p *planProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Role: llm.RoleAssistant, Content: p.reply}}}}, nil
+}
+
+func TestLLMQueryPlanner(t *testing.T) {
+	planner := NewLLMQueryPlanner(&planProvider{reply: "```json\n" + `{"queries": [
+		{"query": "goroutine docs", "context_type": "Resource", "priority": 1},
+		{"query": "user editor", "context_type": "memory", "priority": 2},
+		{"query": "shell", "context_type": "skill"},
+		{"query": "bad", "context_type": "document"}
+	], "reasoning": "docs and preferences"}` + "\n```"})
+
+	plan, err := planner.Plan(context.Background(), "goroutines",
+		[]retrieval.ContextType{retrieval.ContextTypeResource, retrieval.ContextTypeMemory})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Queries) != 2 {
+		t.Fatalf("Expected the resource and memory queries, got %+v", plan.Queries)
+	}
+	if plan.Queries[0].ContextType != retrieval.ContextTypeResource || plan.Queries[0].Query != "goroutine docs" {
+		t.Errorf("Expected a canonical resource query, got %+v", plan.Queries[0])
+	}
+	if plan.Reasoning != "docs and preferences" {
+		t.Errorf("Expected reasoning, got %q", plan.Reasoning)
+	}
+
+	planner = NewLLMQueryPlanner(&planProvider{reply: "no plan"})
+	if _, err := planner.Plan(context.Background(), "x", searchableTypes); err == nil {
+		t.Error("Expected an error for a reply without JSON")
+	}
+}
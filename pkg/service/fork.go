@@ -0,0 +1,110 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// Metadata keys linking a forked session to the session it was forked
+// from.
+const (
+	// MetadataParentSession holds the session ID of the parent.
+	MetadataParentSession = "parent_session_id"
+	// MetadataForkIndex holds the index of the last parent message copied.
+	MetadataForkIndex = "fork_message_index"
+)
+
+// SetStorage sets the storage holding sessions and their messages.
+func (s *SessionService) SetStorage(store storage.StorageInterface) {
+	s.store = store
+}
+
+// ForkSession creates a new session holding copies of the messages of
+// sessionID up to and including uptoMessageIndex, leaving the original
+// untouched. The fork belongs to the same user, inherits the parent's
+// metadata and records the parent under MetadataParentSession and
+// MetadataForkIndex.
+func (s *SessionService) ForkSession(ctx context.Context, sessionID string, uptoMessageIndex int) (*Session, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("session storage not configured")
+	}
+
+	parents, err := s.store.QuerySessions(ctx, storage.QueryOptions{
+		Filter: &storage.Filter{Conds: []storage.FilterCondition{
+			{Op: "must", Field: "session_id", Value: sessionID},
+		}},
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session %s: %w", sessionID, err)
+	}
+	if len(parents) == 0 {
+		return nil, fmt.Errorf("%w: session %s", ErrNotFound, sessionID)
+	}
+	parent := parents[0]
+
+	messages, err := s.store.GetSessionMessages(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages of session %s: %w", sessionID, err)
+	}
+	if uptoMessageIndex < 0 || uptoMessageIndex >= len(messages) {
+		return nil, fmt.Errorf("%w: message index %d out of range for %d messages", ErrInvalidSession, uptoMessageIndex, len(messages))
+	}
+
+	metadata := map[string]any{}
+	if parent.Metadata != "" {
+		if err := json.Unmarshal([]byte(parent.Metadata), &metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata on session %s: %w", sessionID, err)
+		}
+	}
+	metadata[MetadataParentSession] = sessionID
+	metadata[MetadataForkIndex] = uptoMessageIndex
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	fork := &storage.Session{
+		ID:         uuid.New().String(),
+		SessionID:  uuid.New().String(),
+		UserID:     parent.UserID,
+		TotalTurns: int64(uptoMessageIndex + 1),
+		Metadata:   string(encoded),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.store.CreateSession(ctx, fork); err != nil {
+		return nil, fmt.Errorf("failed to create fork of session %s: %w", sessionID, err)
+	}
+
+	for _, m := range messages[:uptoMessageIndex+1] {
+		m.ID = uuid.New().String()
+		m.SessionID = fork.SessionID
+		if err := s.store.CreateSessionMessage(ctx, &m); err != nil {
+			// Leave no half-copied fork behind
+			s.store.DeleteSessionMessages(ctx, fork.SessionID)
+			s.store.DeleteSession(ctx, fork.ID)
+			return nil, fmt.Errorf("failed to copy messages of session %s: %w", sessionID, err)
+		}
+	}
+
+	return &Session{
+		ID:        fork.ID,
+		SessionID: fork.SessionID,
+		UserID:    fork.UserID,
+		State:     "active",
+		Metadata:  metadata,
+		CreatedAt: fork.CreatedAt,
+		UpdatedAt: fork.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// sessionStore keeps sessions and their messages in memory.
+type sessionStore struct {
+	*memStore
+	sessions []storage.Session
+	messages map[string][]storage.SessionMessage
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{memStore: newMemStore(), messages: map[string][]storage.SessionMessage{}}
+}
+
+func (s *sessionStore) CreateSession(ctx context.Context, session *storage.Session) error {
+	s.sessions = append(s.sessions, *session)
+	return nil
+}
+
+func (s *sessionStore) QuerySessions(ctx context.Context, opts storage.QueryOptions) ([]storage.Session, error) {
+	var sessions []storage.Session
+	for _, session := range s.sessions {
+		if opts.Filter != nil && session.SessionID != opts.Filter.Conds[0].Value {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *sessionStore) CreateSessionMessage(ctx context.Context, msg *storage.SessionMessage) error {
+	s.messages[msg.SessionID] = append(s.messages[msg.SessionID], *msg)
+	return nil
+}
+
+func (s *sessionStore) GetSessionMessages(ctx context.Context, sessionID string) ([]storage.SessionMessage, error) {
+	return s.messages[sessionID], nil
+}
+
+func TestForkSession(t *testing.T) {
+	ctx := context.Background()
+	store := newSessionStore()
+	store.CreateSession(ctx, &storage.Session{ID: "p", SessionID: "parent", UserID: "alice", Metadata: `{"topic":"go"}`})
+	for i := 0; i < 4; i++ {
+		store.CreateSessionMessage(ctx, &storage.SessionMessage{
+			ID: fmt.Sprintf("m%d", i), SessionID: "parent", Role: "user", Content: fmt.Sprintf("message %d", i), OrderIndex: int64(i),
+		})
+	}
+
+	svc := NewSessionService()
+	svc.SetStorage(store)
+	fork, err := svc.ForkSession(ctx, "parent", 1)
+	if err != nil {
+		t.Fatalf("ForkSession failed: %v", err)
+	}
+	if fork.SessionID == "parent" || fork.UserID != "alice" {
+		t.Errorf("Expected a new session of the same user, got %+v", fork)
+	}
+	if fork.Metadata[MetadataParentSession] != "parent" || fork.Metadata[MetadataForkIndex] != 1 || fork.Metadata["topic"] != "go" {
+		t.Errorf("Expected the parent recorded in the metadata, got %v", fork.Metadata)
+	}
+
+	messages := store.messages[fork.SessionID]
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages in the fork, got %d", len(messages))
+	}
+	for i, m := range messages {
+		if m.Content != fmt.Sprintf("message %d", i) || m.OrderIndex != int64(i) || m.ID == fmt.Sprintf("m%d", i) {
+			t.Errorf("Expected a copy of message %d, got %+v", i, m)
+		}
+	}
+	if len(store.messages["parent"]) != 4 {
+		t.Errorf("Expected the parent left with 4 messages, got %d", len(store.messages["parent"]))
+	}
+
+	stored, _ := store.QuerySessions(ctx, storage.QueryOptions{Filter: &storage.Filter{Conds: []storage.FilterCondition{
+		{Op: "must", Field: "session_id", Value: fork.SessionID},
+	}}})
+	var metadata map[string]any
+	if len(stored) != 1 || json.Unmarshal([]byte(stored[0].Metadata), &metadata) != nil || metadata[MetadataParentSession] != "parent" {
+		t.Errorf("Expected the stored fork to reference the parent, got %+v", stored)
+	}
+
+	if _, err := svc.ForkSession(ctx, "parent", 4); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession for an index past the end, got %v", err)
+	}
+	if _, err := svc.ForkSession(ctx, "missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing session, got %v", err)
+	}
+}
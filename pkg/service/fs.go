@@ -84,17 +84,26 @@ func (s *FSService) Mkdir(ctx context.Context, path string) error {
 
 // Read reads a file.
 func (s *FSService) Read(ctx context.Context, path string) (string, error) {
+	data, err := s.ReadBytes(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ReadBytes reads a file as raw bytes.
+func (s *FSService) ReadBytes(ctx context.Context, path string) ([]byte, error) {
 	fullPath := s.resolvePath(path)
 
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", ErrFileNotFound
+			return nil, ErrFileNotFound
 		}
-		return "", err
+		return nil, err
 	}
 
-	return string(data), nil
+	return data, nil
 }
 
 // Write writes content to a file.
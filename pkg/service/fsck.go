@@ -0,0 +1,240 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jqnote/goviking/pkg/agfs"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// ReconcileOptions controls which inconsistencies Reconcile repairs. With
+// no options set, Reconcile only reports.
+type ReconcileOptions struct {
+	// RecreateRows adds a files row for each orphan file and updates the
+	// stored checksum and size of mismatched rows.
+	RecreateRows bool
+
+	// DeleteOrphans deletes orphan files from AGFS. It cannot be combined
+	// with RecreateRows.
+	DeleteOrphans bool
+
+	// DeleteDangling deletes files and contexts rows whose URI no longer
+	// exists in AGFS.
+	DeleteDangling bool
+}
+
+// ChecksumMismatch describes a file whose content no longer matches the
+// checksum stored in its files row.
+type ChecksumMismatch struct {
+	URI    string `json:"uri"`
+	Stored string `json:"stored"`
+	Actual string `json:"actual"`
+}
+
+// ReconcileReport lists the inconsistencies found between AGFS and storage.
+type ReconcileReport struct {
+	// OrphanFiles are AGFS files with no files row and no context row for
+	// them or their directory.
+	OrphanFiles []string `json:"orphan_files,omitempty"`
+
+	// DanglingFiles and DanglingContexts are rows whose URI is missing
+	// from AGFS.
+	DanglingFiles    []string `json:"dangling_files,omitempty"`
+	DanglingContexts []string `json:"dangling_contexts,omitempty"`
+
+	ChecksumMismatches []ChecksumMismatch `json:"checksum_mismatches,omitempty"`
+
+	// Repaired counts the inconsistencies fixed according to the options.
+	Repaired int `json:"repaired"`
+}
+
+// Clean reports whether no inconsistencies were found.
+func (r ReconcileReport) Clean() bool {
+	return len(r.OrphanFiles) == 0 && len(r.DanglingFiles) == 0 &&
+		len(r.DanglingContexts) == 0 && len(r.ChecksumMismatches) == 0
+}
+
+// Unrepaired counts the inconsistencies found but left in place.
+func (r ReconcileReport) Unrepaired() int {
+	found := len(r.OrphanFiles) + len(r.DanglingFiles) + len(r.DanglingContexts) + len(r.ChecksumMismatches)
+	return found - r.Repaired
+}
+
+// Reconciler checks that AGFS and the storage files and contexts tables
+// describe the same data.
+type Reconciler struct {
+	fs    *agfs.AGFS
+	store storage.StorageInterface
+	opts  ReconcileOptions
+}
+
+// NewReconciler creates a new Reconciler.
+func NewReconciler(fs *agfs.AGFS, store storage.StorageInterface, opts ReconcileOptions) *Reconciler {
+	return &Reconciler{
+		fs:    fs,
+		store: store,
+		opts:  opts,
+	}
+}
+
+// Reconcile lists both sides, reports orphan files, dangling rows and
+// checksum mismatches, and repairs them as the options request.
+func (r *Reconciler) Reconcile(ctx context.Context) (report ReconcileReport, err error) {
+	if r.opts.RecreateRows && r.opts.DeleteOrphans {
+		return report, errors.New("RecreateRows and DeleteOrphans cannot both be set")
+	}
+
+	files, err := r.fs.Files("viking://")
+	if err != nil {
+		return report, fmt.Errorf("failed to list AGFS files: %w", err)
+	}
+	rows, err := r.store.QueryFiles(ctx, storage.QueryOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list file rows: %w", err)
+	}
+	contexts, err := r.store.QueryContexts(ctx, storage.QueryOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list context rows: %w", err)
+	}
+
+	onDisk := make(map[string]agfs.Entry, len(files))
+	for _, f := range files {
+		onDisk[f.URI] = f
+	}
+	rowsByURI := make(map[string]storage.File, len(rows))
+	for _, row := range rows {
+		rowsByURI[row.URI] = row
+	}
+	contextURIs := make(map[string]bool, len(contexts))
+	for _, c := range contexts {
+		contextURIs[c.URI] = true
+	}
+
+	for _, f := range files {
+		row, tracked := rowsByURI[f.URI]
+		if !tracked {
+			if contextURIs[f.URI] || contextURIs[parentURI(f.URI)] {
+				continue
+			}
+			report.OrphanFiles = append(report.OrphanFiles, f.URI)
+			if err := r.repairOrphan(ctx, f); err != nil {
+				return report, err
+			}
+			if r.opts.RecreateRows || r.opts.DeleteOrphans {
+				report.Repaired++
+			}
+			continue
+		}
+
+		if row.Checksum == "" {
+			continue
+		}
+		data, err := r.fs.Read(f.URI, 0, 0)
+		if err != nil {
+			return report, fmt.Errorf("failed to read %s: %w", f.URI, err)
+		}
+		if actual := fileChecksum(data); actual != row.Checksum {
+			report.ChecksumMismatches = append(report.ChecksumMismatches, ChecksumMismatch{
+				URI:    f.URI,
+				Stored: row.Checksum,
+				Actual: actual,
+			})
+			if r.opts.RecreateRows {
+				row.Checksum = actual
+				row.Size = int64(len(data))
+				row.UpdatedAt = time.Now()
+				if err := r.store.UpdateFile(ctx, &row); err != nil {
+					return report, fmt.Errorf("failed to update row for %s: %w", f.URI, err)
+				}
+				report.Repaired++
+			}
+		}
+	}
+
+	for _, row := range rows {
+		if _, ok := onDisk[row.URI]; ok {
+			continue
+		}
+		report.DanglingFiles = append(report.DanglingFiles, row.URI)
+		if r.opts.DeleteDangling {
+			if err := r.store.DeleteFile(ctx, row.ID); err != nil {
+				return report, fmt.Errorf("failed to delete row for %s: %w", row.URI, err)
+			}
+			report.Repaired++
+		}
+	}
+
+	for _, c := range contexts {
+		if r.fs.Exists(c.URI) {
+			continue
+		}
+		report.DanglingContexts = append(report.DanglingContexts, c.URI)
+		if r.opts.DeleteDangling {
+			if err := r.store.DeleteContext(ctx, c.ID); err != nil {
+				return report, fmt.Errorf("failed to delete context %s: %w", c.URI, err)
+			}
+			report.Repaired++
+		}
+	}
+
+	sort.Strings(report.OrphanFiles)
+	sort.Strings(report.DanglingFiles)
+	sort.Strings(report.DanglingContexts)
+	return report, nil
+}
+
+// repairOrphan records or deletes an orphan file as the options request.
+func (r *Reconciler) repairOrphan(ctx context.Context, f agfs.Entry) error {
+	switch {
+	case r.opts.DeleteOrphans:
+		if err := r.fs.Delete(f.URI, false); err != nil {
+			return fmt.Errorf("failed to delete orphan %s: %w", f.URI, err)
+		}
+	case r.opts.RecreateRows:
+		data, err := r.fs.Read(f.URI, 0, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.URI, err)
+		}
+		now := time.Now()
+		row := &storage.File{
+			ID:        uuid.New().String(),
+			URI:       f.URI,
+			Name:      f.Name,
+			Size:      int64(len(data)),
+			Checksum:  fileChecksum(data),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := r.store.CreateFile(ctx, row); err != nil {
+			return fmt.Errorf("failed to create row for %s: %w", f.URI, err)
+		}
+	}
+	return nil
+}
+
+// fileChecksum returns the hex SHA-256 of data, the format stored in the
+// files table.
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// parentURI returns the URI of the directory holding uri.
+func parentURI(uri string) string {
+	if i := strings.LastIndex(uri, "/"); i >= 0 {
+		return uri[:i]
+	}
+	return uri
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/agfs"
+	"github.com/jqnote/goviking/pkg/storage"
+)
+
+// memStore keeps files, contexts and memories rows in memory. Methods the
+// tests do not use panic through the nil embedded interface.
+type memStore struct {
+	storage.StorageInterface
+	files    map[string]storage.File
+	contexts map[string]storage.Context
+	memories map[string]storage.Memory
+	audit    []storage.AuditEntry
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		files:    map[string]storage.File{},
+		contexts: map[string]storage.Context{},
+		memories: map[string]storage.Memory{},
+	}
+}
+
+func (m *memStore) CreateFile(ctx context.Context, f *storage.File) error {
+	m.files[f.ID] = *f
+	return nil
+}
+
+func (m *memStore) UpdateFile(ctx context.Context, f *storage.File) error {
+	m.files[f.ID] = *f
+	return nil
+}
+
+func (m *memStore) DeleteFile(ctx context.Context, id string) error {
+	delete(m.files, id)
+	return nil
+}
+
+func (m *memStore) QueryFiles(ctx context.Context, opts storage.QueryOptions) ([]storage.File, error) {
+	var files []storage.File
+	for _, f := range m.files {
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func (m *memStore) DeleteContext(ctx context.Context, id string) error {
+	delete(m.contexts, id)
+	return nil
+}
+
+func (m *memStore) QueryContexts(ctx context.Context, opts storage.QueryOptions) ([]storage.Context, error) {
+	var contexts []storage.Context
+	for _, c := range m.contexts {
+		contexts = append(contexts, c)
+	}
+	return contexts, nil
+}
+
+// newReconcileFixture sets up a tracked file, a context directory, an
+// orphan file, a changed file and dangling file and context rows.
+func newReconcileFixture(t *testing.T) (*agfs.AGFS, *memStore) {
+	t.Helper()
+	fs, err := agfs.New(agfs.Config{RootPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create AGFS: %v", err)
+	}
+	store := newMemStore()
+
+	write := func(uri, content string) {
+		if err := fs.Write(uri, []byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", uri, err)
+		}
+	}
+	write("viking://resources/tracked.md", "tracked")
+	write("viking://resources/orphan.md", "orphan")
+	write("viking://resources/changed.md", "changed")
+	if err := fs.WriteContext("viking://resources/doc", "abstract", "", "content", true); err != nil {
+		t.Fatalf("Failed to write context: %v", err)
+	}
+
+	store.files["f1"] = storage.File{ID: "f1", URI: "viking://resources/tracked.md", Checksum: fileChecksum([]byte("tracked"))}
+	store.files["f2"] = storage.File{ID: "f2", URI: "viking://resources/changed.md", Checksum: fileChecksum([]byte("original"))}
+	store.files["f3"] = storage.File{ID: "f3", URI: "viking://resources/gone.md"}
+	store.contexts["c1"] = storage.Context{ID: "c1", URI: "viking://resources/doc"}
+	store.contexts["c2"] = storage.Context{ID: "c2", URI: "viking://resources/removed"}
+
+	return fs, store
+}
+
+func TestReconcileReport(t *testing.T) {
+	fs, store := newReconcileFixture(t)
+
+	report, err := NewReconciler(fs, store, ReconcileOptions{}).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if want := []string{"viking://resources/orphan.md"}; !reflect.DeepEqual(report.OrphanFiles, want) {
+		t.Errorf("Expected orphans %v, got %v", want, report.OrphanFiles)
+	}
+	if want := []string{"viking://resources/gone.md"}; !reflect.DeepEqual(report.DanglingFiles, want) {
+		t.Errorf("Expected dangling files %v, got %v", want, report.DanglingFiles)
+	}
+	if want := []string{"viking://resources/removed"}; !reflect.DeepEqual(report.DanglingContexts, want) {
+		t.Errorf("Expected dangling contexts %v, got %v", want, report.DanglingContexts)
+	}
+	if len(report.ChecksumMismatches) != 1 || report.ChecksumMismatches[0].URI != "viking://resources/changed.md" {
+		t.Errorf("Expected one checksum mismatch on changed.md, got %v", report.ChecksumMismatches)
+	}
+	if report.Repaired != 0 || report.Clean() {
+		t.Errorf("Expected an unrepaired report, got %+v", report)
+	}
+	if len(store.files) != 3 || len(store.contexts) != 2 {
+		t.Error("Expected report-only run to leave rows untouched")
+	}
+}
+
+func TestReconcileRecreateRows(t *testing.T) {
+	fs, store := newReconcileFixture(t)
+	ctx := context.Background()
+
+	report, err := NewReconciler(fs, store, ReconcileOptions{RecreateRows: true, DeleteDangling: true}).Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Repaired != 4 || report.Unrepaired() != 0 {
+		t.Errorf("Expected 4 repairs and none left, got %+v", report)
+	}
+	if !fs.Exists("viking://resources/orphan.md") {
+		t.Error("Expected orphan file to be kept")
+	}
+
+	report, err = NewReconciler(fs, store, ReconcileOptions{}).Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !report.Clean() {
+		t.Errorf("Expected clean report after repair, got %+v", report)
+	}
+}
+
+func TestReconcileDeleteOrphans(t *testing.T) {
+	fs, store := newReconcileFixture(t)
+
+	report, err := NewReconciler(fs, store, ReconcileOptions{DeleteOrphans: true}).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Repaired != 1 {
+		t.Errorf("Expected 1 repair, got %d", report.Repaired)
+	}
+	if report.Unrepaired() != 3 {
+		t.Errorf("Expected 3 unrepaired inconsistencies, got %d", report.Unrepaired())
+	}
+	if fs.Exists("viking://resources/orphan.md") {
+		t.Error("Expected orphan file to be deleted")
+	}
+	if len(store.files) != 3 {
+		t.Errorf("Expected rows to be kept, got %d", len(store.files))
+	}
+}
+
+func TestReconcileConflictingOptions(t *testing.T) {
+	fs, store := newReconcileFixture(t)
+
+	_, err := NewReconciler(fs, store, ReconcileOptions{RecreateRows: true, DeleteOrphans: true}).Reconcile(context.Background())
+	if err == nil {
+		t.Error("Expected error for conflicting options")
+	}
+}
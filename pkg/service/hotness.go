@@ -0,0 +1,51 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// StoreHotness is a HotnessSource scoring contexts by the access count and
+// last update recorded for them in storage.
+type StoreHotness struct {
+	store  storage.StorageInterface
+	scorer *retrieval.HotnessScorer
+}
+
+// NewStoreHotness creates a StoreHotness reading contexts from store.
+func NewStoreHotness(store storage.StorageInterface) *StoreHotness {
+	return &StoreHotness{
+		store:  store,
+		scorer: retrieval.NewHotnessScorer(retrieval.DefaultHotnessConfig()),
+	}
+}
+
+// SetClock sets the clock used to measure time since a context's last
+// update.
+func (h *StoreHotness) SetClock(clock utils.Clock) {
+	h.scorer.SetClock(clock)
+}
+
+// Hotness returns the hotness of the context at uri, or 0 when no context
+// is stored there.
+func (h *StoreHotness) Hotness(ctx context.Context, uri string) (float64, error) {
+	contexts, err := h.store.QueryContexts(ctx, storage.QueryOptions{
+		Filter: &storage.Filter{Op: "and", Conds: []storage.FilterCondition{{Op: "must", Field: "uri", Value: uri}}},
+		Limit:  1,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up %s: %w", uri, err)
+	}
+	if len(contexts) == 0 {
+		return 0, nil
+	}
+	c := contexts[0]
+	return h.scorer.CalculateHotness(int(c.ActiveCount), c.UpdatedAt), nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// uriStore returns the stored context matching a query's uri condition.
+type uriStore struct {
+	storage.StorageInterface
+	contexts map[string]storage.Context
+}
+
+func (s uriStore) QueryContexts(ctx context.Context, opts storage.QueryOptions) ([]storage.Context, error) {
+	for _, cond := range opts.Filter.Conds {
+		if c, ok := s.contexts[cond.Value.(string)]; ok && cond.Field == "uri" {
+			return []storage.Context{c}, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestStoreHotness(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := uriStore{contexts: map[string]storage.Context{
+		"viking://resources/hot":  {URI: "viking://resources/hot", ActiveCount: 50, UpdatedAt: now},
+		"viking://resources/cold": {URI: "viking://resources/cold", ActiveCount: 0, UpdatedAt: now.AddDate(0, -3, 0)},
+	}}
+	hotness := NewStoreHotness(store)
+	hotness.SetClock(utils.NewFakeClock(now))
+
+	ctx := context.Background()
+	hot, err := hotness.Hotness(ctx, "viking://resources/hot")
+	if err != nil {
+		t.Fatalf("Hotness failed: %v", err)
+	}
+	cold, err := hotness.Hotness(ctx, "viking://resources/cold")
+	if err != nil {
+		t.Fatalf("Hotness failed: %v", err)
+	}
+	if hot <= cold {
+		t.Errorf("Expected a busy recent context hotter than an idle old one, got %f and %f", hot, cold)
+	}
+
+	missing, err := hotness.Hotness(ctx, "viking://resources/missing")
+	if err != nil {
+		t.Fatalf("Hotness failed: %v", err)
+	}
+	if missing != 0 {
+		t.Errorf("Expected no hotness for a missing context, got %f", missing)
+	}
+}
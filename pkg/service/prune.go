@@ -0,0 +1,118 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// PruneOptions selects the memories and contexts Prune removes. A zero
+// criterion disables that part of the prune.
+type PruneOptions struct {
+	// MinImportance removes memories with importance below it.
+	MinImportance float64
+
+	// UnusedSince removes contexts that were never used (active_count of
+	// zero) and have not been modified for at least this long.
+	UnusedSince time.Duration
+
+	// DryRun reports what would be removed without deleting anything.
+	DryRun bool
+}
+
+// PruneReport lists the memories and contexts removed, or that would be
+// removed in a dry run.
+type PruneReport struct {
+	Memories []storage.Memory  `json:"memories,omitempty"`
+	Contexts []storage.Context `json:"contexts,omitempty"`
+	DryRun   bool              `json:"dry_run"`
+}
+
+// Pruner deletes low-importance memories and stale unused contexts.
+type Pruner struct {
+	store storage.StorageInterface
+	opts  PruneOptions
+	clock utils.Clock
+}
+
+// NewPruner creates a new Pruner.
+func NewPruner(store storage.StorageInterface, opts PruneOptions) *Pruner {
+	return &Pruner{
+		store: store,
+		opts:  opts,
+		clock: utils.RealClock{},
+	}
+}
+
+// SetClock sets the clock used to compute the unused-since cutoff.
+func (p *Pruner) SetClock(clock utils.Clock) {
+	p.clock = clock
+}
+
+// Prune finds the memories and contexts matching the options and, unless
+// DryRun is set, deletes them.
+func (p *Pruner) Prune(ctx context.Context) (report PruneReport, err error) {
+	report.DryRun = p.opts.DryRun
+
+	if p.opts.MinImportance > 0 {
+		memories, err := p.store.QueryMemories(ctx, storage.QueryOptions{})
+		if err != nil {
+			return report, fmt.Errorf("failed to list memories: %w", err)
+		}
+		for _, m := range memories {
+			if m.Importance < p.opts.MinImportance {
+				report.Memories = append(report.Memories, m)
+			}
+		}
+		sort.Slice(report.Memories, func(i, j int) bool {
+			return report.Memories[i].ID < report.Memories[j].ID
+		})
+	}
+
+	if p.opts.UnusedSince > 0 {
+		contexts, err := p.store.QueryContexts(ctx, storage.QueryOptions{})
+		if err != nil {
+			return report, fmt.Errorf("failed to list contexts: %w", err)
+		}
+		cutoff := p.clock.Now().Add(-p.opts.UnusedSince)
+		for _, c := range contexts {
+			if c.ActiveCount == 0 && lastModified(c).Before(cutoff) {
+				report.Contexts = append(report.Contexts, c)
+			}
+		}
+		sort.Slice(report.Contexts, func(i, j int) bool {
+			return report.Contexts[i].URI < report.Contexts[j].URI
+		})
+	}
+
+	if p.opts.DryRun {
+		return report, nil
+	}
+
+	for _, m := range report.Memories {
+		if err := p.store.DeleteMemory(ctx, m.ID); err != nil {
+			return report, fmt.Errorf("failed to delete memory %s: %w", m.ID, err)
+		}
+	}
+	for _, c := range report.Contexts {
+		if err := p.store.DeleteContext(ctx, c.ID); err != nil {
+			return report, fmt.Errorf("failed to delete context %s: %w", c.URI, err)
+		}
+	}
+	return report, nil
+}
+
+// lastModified returns the later of a context's creation and update times.
+func lastModified(c storage.Context) time.Time {
+	if c.UpdatedAt.After(c.CreatedAt) {
+		return c.UpdatedAt
+	}
+	return c.CreatedAt
+}
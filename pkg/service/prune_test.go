@@ -0,0 +1,110 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+func (m *memStore) QueryMemories(ctx context.Context, opts storage.QueryOptions) ([]storage.Memory, error) {
+	var memories []storage.Memory
+	for _, mem := range m.memories {
+		memories = append(memories, mem)
+	}
+	return memories, nil
+}
+
+func (m *memStore) DeleteMemory(ctx context.Context, id string) error {
+	delete(m.memories, id)
+	return nil
+}
+
+var pruneNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// newPruneFixture seeds memories on both sides of a 0.3 importance
+// threshold and contexts that are old, recent, used or recently updated.
+func newPruneFixture() *memStore {
+	store := newMemStore()
+	store.memories["m1"] = storage.Memory{ID: "m1", Content: "trivia", Importance: 0.1}
+	store.memories["m2"] = storage.Memory{ID: "m2", Content: "borderline", Importance: 0.3}
+	store.memories["m3"] = storage.Memory{ID: "m3", Content: "profile", Importance: 0.9}
+
+	old := pruneNow.AddDate(0, 0, -120)
+	recent := pruneNow.AddDate(0, 0, -10)
+	store.contexts["c1"] = storage.Context{ID: "c1", URI: "viking://resources/stale", CreatedAt: old, UpdatedAt: old}
+	store.contexts["c2"] = storage.Context{ID: "c2", URI: "viking://resources/used", ActiveCount: 3, CreatedAt: old, UpdatedAt: old}
+	store.contexts["c3"] = storage.Context{ID: "c3", URI: "viking://resources/new", CreatedAt: recent, UpdatedAt: recent}
+	store.contexts["c4"] = storage.Context{ID: "c4", URI: "viking://resources/edited", CreatedAt: old, UpdatedAt: recent}
+	return store
+}
+
+func newTestPruner(store storage.StorageInterface, opts PruneOptions) *Pruner {
+	p := NewPruner(store, opts)
+	p.SetClock(utils.NewFakeClock(pruneNow))
+	return p
+}
+
+func TestPrune(t *testing.T) {
+	store := newPruneFixture()
+
+	report, err := newTestPruner(store, PruneOptions{MinImportance: 0.3, UnusedSince: 90 * 24 * time.Hour}).Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	if len(report.Memories) != 1 || report.Memories[0].ID != "m1" {
+		t.Errorf("Expected only m1 to be pruned, got %v", report.Memories)
+	}
+	if len(report.Contexts) != 1 || report.Contexts[0].ID != "c1" {
+		t.Errorf("Expected only c1 to be pruned, got %v", report.Contexts)
+	}
+	if _, ok := store.memories["m1"]; ok {
+		t.Error("Expected m1 to be deleted")
+	}
+	if _, ok := store.contexts["c1"]; ok {
+		t.Error("Expected c1 to be deleted")
+	}
+	if len(store.memories) != 2 || len(store.contexts) != 3 {
+		t.Errorf("Expected 2 memories and 3 contexts left, got %d and %d", len(store.memories), len(store.contexts))
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	store := newPruneFixture()
+
+	report, err := newTestPruner(store, PruneOptions{MinImportance: 0.5, UnusedSince: 90 * 24 * time.Hour, DryRun: true}).Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	if !report.DryRun {
+		t.Error("Expected report to be marked as dry run")
+	}
+	if len(report.Memories) != 2 || len(report.Contexts) != 1 {
+		t.Errorf("Expected 2 memories and 1 context targeted, got %d and %d", len(report.Memories), len(report.Contexts))
+	}
+	if len(store.memories) != 3 || len(store.contexts) != 4 {
+		t.Error("Expected dry run to leave rows untouched")
+	}
+}
+
+func TestPruneDisabledCriteria(t *testing.T) {
+	store := newPruneFixture()
+
+	report, err := newTestPruner(store, PruneOptions{UnusedSince: 5 * 24 * time.Hour}).Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(report.Memories) != 0 {
+		t.Errorf("Expected no memories pruned without a threshold, got %v", report.Memories)
+	}
+	if len(report.Contexts) != 3 {
+		t.Errorf("Expected 3 unused contexts older than 5 days, got %d", len(report.Contexts))
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// ReindexOptions controls how Reindex batches, paces and checkpoints its
+// embedding requests.
+type ReindexOptions struct {
+	// BatchSize is the number of contexts embedded per request, 32 when
+	// zero.
+	BatchSize int
+
+	// RequestsPerMinute spaces embedding requests, retries included, so
+	// no more than this many are made in any minute. Zero does not pace.
+	RequestsPerMinute int
+
+	// MaxRetries is how many times a failed batch is retried, 3 when
+	// zero; negative disables retries. Every error other than context
+	// cancellation is treated as transient.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubling for each
+	// retry after it. One second when zero.
+	RetryBackoff time.Duration
+
+	// CheckpointPath names a file recording the ID of the last context
+	// indexed, so an interrupted reindex resumes after it. The file is
+	// removed once the reindex completes. Empty disables checkpoints.
+	CheckpointPath string
+}
+
+// DefaultReindexOptions returns the default reindex options.
+func DefaultReindexOptions() ReindexOptions {
+	return ReindexOptions{
+		BatchSize:    32,
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+	}
+}
+
+// ReindexReport summarizes a reindex.
+type ReindexReport struct {
+	// ResumedAfter is the checkpointed context ID the run resumed after,
+	// empty for a fresh run.
+	ResumedAfter string `json:"resumed_after,omitempty"`
+	Indexed      int    `json:"indexed"`
+	Skipped      int    `json:"skipped"`
+	Requests     int    `json:"requests"`
+	Retries      int    `json:"retries"`
+}
+
+// reindexCheckpoint is the content of a checkpoint file.
+type reindexCheckpoint struct {
+	LastID string `json:"last_id"`
+}
+
+// Reindexer embeds stored contexts in batches and writes the vectors to a
+// vector store.
+type Reindexer struct {
+	store    storage.StorageInterface
+	embedder retrieval.Embedder
+	vectors  retrieval.VectorStore
+	opts     ReindexOptions
+	clock    utils.Clock
+	// sleep waits for d unless ctx is done first
+	sleep func(ctx context.Context, d time.Duration) error
+
+	lastRequest time.Time
+}
+
+// NewReindexer creates a new Reindexer. Zero options take their defaults.
+func NewReindexer(store storage.StorageInterface, embedder retrieval.Embedder, vectors retrieval.VectorStore, opts ReindexOptions) *Reindexer {
+	defaults := DefaultReindexOptions()
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaults.MaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaults.RetryBackoff
+	}
+	return &Reindexer{
+		store:    store,
+		embedder: embedder,
+		vectors:  vectors,
+		opts:     opts,
+		clock:    utils.RealClock{},
+		sleep:    sleepContext,
+	}
+}
+
+// SetClock sets the clock used to pace requests.
+func (r *Reindexer) SetClock(clock utils.Clock) {
+	r.clock = clock
+}
+
+// Reindex embeds the abstract, or the name when there is no abstract, of
+// every stored context in ID order. Contexts with neither are skipped.
+// After each batch is stored the checkpoint advances, so a reindex that
+// fails or is interrupted resumes with the next batch. Reindexing needs a
+// real embedder; zero vectors from a NoopEmbedder are not worth storing.
+func (r *Reindexer) Reindex(ctx context.Context) (report ReindexReport, err error) {
+	if retrieval.IsNoopEmbedder(r.embedder) {
+		return report, errors.New("reindex requires an embedder")
+	}
+
+	lastID, err := r.readCheckpoint()
+	if err != nil {
+		return report, err
+	}
+	report.ResumedAfter = lastID
+
+	contexts, err := r.store.QueryContexts(ctx, storage.QueryOptions{OrderBy: "id"})
+	if err != nil {
+		return report, fmt.Errorf("failed to list contexts: %w", err)
+	}
+	// The checkpoint relies on a stable order, whatever the store returns
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].ID < contexts[j].ID })
+
+	var batch []storage.Context
+	for _, c := range contexts {
+		if c.ID <= lastID {
+			continue
+		}
+		if c.Abstract == "" && c.Name == "" {
+			report.Skipped++
+			continue
+		}
+		batch = append(batch, c)
+		if len(batch) == r.opts.BatchSize {
+			if err := r.indexBatch(ctx, batch, &report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := r.indexBatch(ctx, batch, &report); err != nil {
+			return report, err
+		}
+	}
+
+	if r.opts.CheckpointPath != "" {
+		if err := os.Remove(r.opts.CheckpointPath); err != nil && !os.IsNotExist(err) {
+			return report, fmt.Errorf("failed to remove checkpoint: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// indexBatch embeds a batch, retrying transient failures, stores the
+// vectors and checkpoints the batch's last ID.
+func (r *Reindexer) indexBatch(ctx context.Context, batch []storage.Context, report *ReindexReport) error {
+	texts := make([]string, len(batch))
+	for i, c := range batch {
+		texts[i] = c.Abstract
+		if texts[i] == "" {
+			texts[i] = c.Name
+		}
+	}
+
+	var results []*retrieval.EmbedResult
+	backoff := r.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := r.pace(ctx); err != nil {
+			return err
+		}
+		report.Requests++
+
+		var err error
+		results, err = r.embedder.EmbedBatch(ctx, texts)
+		if err == nil && len(results) != len(texts) {
+			err = fmt.Errorf("embedder returned %d results for %d texts", len(results), len(texts))
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || attempt >= r.opts.MaxRetries {
+			return fmt.Errorf("failed to embed contexts %s to %s: %w", batch[0].ID, batch[len(batch)-1].ID, err)
+		}
+		report.Retries++
+		if err := r.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+
+	vectors := make([]retrieval.SearchResult, 0, len(batch))
+	for i, c := range batch {
+		if results[i] == nil || results[i].DenseVector == nil {
+			report.Skipped++
+			continue
+		}
+		vectors = append(vectors, retrieval.SearchResult{
+			URI:       c.URI,
+			Abstract:  c.Abstract,
+			IsLeaf:    c.IsLeaf,
+			ParentURI: c.ParentURI,
+			Metadata: map[string]interface{}{
+				"vector":       results[i].DenseVector,
+				"parent_uri":   c.ParentURI,
+				"context_type": c.ContextType,
+			},
+		})
+	}
+	if err := r.vectors.Add(ctx, vectors); err != nil {
+		return fmt.Errorf("failed to store vectors: %w", err)
+	}
+	report.Indexed += len(vectors)
+
+	return r.writeCheckpoint(batch[len(batch)-1].ID)
+}
+
+// pace waits until the next request is allowed under RequestsPerMinute.
+func (r *Reindexer) pace(ctx context.Context) error {
+	if r.opts.RequestsPerMinute > 0 && !r.lastRequest.IsZero() {
+		interval := time.Minute / time.Duration(r.opts.RequestsPerMinute)
+		if wait := r.lastRequest.Add(interval).Sub(r.clock.Now()); wait > 0 {
+			if err := r.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+	}
+	r.lastRequest = r.clock.Now()
+	return nil
+}
+
+// readCheckpoint returns the last indexed ID, or "" without a checkpoint.
+func (r *Reindexer) readCheckpoint() (string, error) {
+	if r.opts.CheckpointPath == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(r.opts.CheckpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp reindexCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return "", fmt.Errorf("failed to parse checkpoint %s: %w", r.opts.CheckpointPath, err)
+	}
+	return cp.LastID, nil
+}
+
+// writeCheckpoint records lastID, replacing the checkpoint file atomically
+// so a crash mid-write leaves the previous checkpoint intact.
+func (r *Reindexer) writeCheckpoint(lastID string) error {
+	if r.opts.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(reindexCheckpoint{LastID: lastID})
+	if err != nil {
+		return err
+	}
+	tmp := r.opts.CheckpointPath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tmp), 0755); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, r.opts.CheckpointPath); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// sleepContext waits for d, returning early with ctx's error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// budgetEmbedder embeds each text as its length. It fails every call past
+// budget, like a provider that has gone away, and the calls listed in
+// transient once each.
+type budgetEmbedder struct {
+	retrieval.Embedder
+	clock     *utils.FakeClock
+	budget    int
+	transient map[int]bool
+	calls     []time.Time
+	texts     [][]string
+}
+
+func (e *budgetEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*retrieval.EmbedResult, error) {
+	e.calls = append(e.calls, e.clock.Now())
+	call := len(e.calls)
+	if e.budget > 0 && call > e.budget {
+		return nil, fmt.Errorf("call budget of %d exceeded", e.budget)
+	}
+	if e.transient[call] {
+		return nil, errors.New("API error: 429 too many requests")
+	}
+	e.texts = append(e.texts, texts)
+	results := make([]*retrieval.EmbedResult, len(texts))
+	for i, text := range texts {
+		results[i] = &retrieval.EmbedResult{DenseVector: []float64{float64(len(text))}}
+	}
+	return results, nil
+}
+
+// newReindexFixture seeds five contexts with abstracts and one with
+// neither abstract nor name.
+func newReindexFixture() *memStore {
+	store := newMemStore()
+	for i := 1; i <= 5; i++ {
+		id := fmt.Sprintf("c%d", i)
+		store.contexts[id] = storage.Context{ID: id, URI: "viking://resources/" + id, Abstract: "abstract " + id}
+	}
+	store.contexts["c6"] = storage.Context{ID: "c6", URI: "viking://resources/c6"}
+	return store
+}
+
+// newTestReindexer returns a reindexer whose sleeps advance clock instead
+// of waiting.
+func newTestReindexer(store storage.StorageInterface, embedder retrieval.Embedder, vectors retrieval.VectorStore, clock *utils.FakeClock, opts ReindexOptions) *Reindexer {
+	r := NewReindexer(store, embedder, vectors, opts)
+	r.SetClock(clock)
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		clock.Advance(d)
+		return nil
+	}
+	return r
+}
+
+func TestReindexPacesRequests(t *testing.T) {
+	clock := utils.NewFakeClock(pruneNow)
+	embedder := &budgetEmbedder{clock: clock}
+	vectors := retrieval.NewInMemoryVectorStore(1)
+
+	report, err := newTestReindexer(newReindexFixture(), embedder, vectors, clock, ReindexOptions{BatchSize: 2, RequestsPerMinute: 30}).Reindex(context.Background())
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if report.Indexed != 5 || report.Skipped != 1 || report.Requests != 3 {
+		t.Errorf("Expected 5 indexed, 1 skipped in 3 requests, got %+v", report)
+	}
+	for i := 1; i < len(embedder.calls); i++ {
+		if gap := embedder.calls[i].Sub(embedder.calls[i-1]); gap < 2*time.Second {
+			t.Errorf("Expected requests at least 2s apart at 30/min, call %d came after %v", i+1, gap)
+		}
+	}
+	if vec, ok := vectors.GetVector("viking://resources/c3"); !ok || vec[0] != float64(len("abstract c3")) {
+		t.Errorf("Expected c3 to be indexed, got %v", vec)
+	}
+}
+
+func TestReindexNeedsEmbedder(t *testing.T) {
+	clock := utils.NewFakeClock(pruneNow)
+	vectors := retrieval.NewInMemoryVectorStore(4)
+	for _, embedder := range []retrieval.Embedder{nil, retrieval.NewNoopEmbedder(4)} {
+		_, err := newTestReindexer(newReindexFixture(), embedder, vectors, clock, ReindexOptions{}).Reindex(context.Background())
+		if err == nil {
+			t.Errorf("Expected reindexing with %T to fail", embedder)
+		}
+	}
+	if _, ok := vectors.GetVector("viking://resources/c1"); ok {
+		t.Error("Expected no zero vectors stored")
+	}
+}
+
+func TestReindexRetriesTransientErrors(t *testing.T) {
+	clock := utils.NewFakeClock(pruneNow)
+	embedder := &budgetEmbedder{clock: clock, transient: map[int]bool{1: true, 2: true}}
+
+	report, err := newTestReindexer(newReindexFixture(), embedder, retrieval.NewInMemoryVectorStore(1), clock, ReindexOptions{BatchSize: 10, RetryBackoff: time.Second}).Reindex(context.Background())
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if report.Retries != 2 || report.Indexed != 5 {
+		t.Errorf("Expected 2 retries then 5 indexed, got %+v", report)
+	}
+	// Backoff doubles: 1s then 2s
+	if waited := embedder.calls[2].Sub(embedder.calls[0]); waited != 3*time.Second {
+		t.Errorf("Expected 3s of backoff, got %v", waited)
+	}
+
+	// Retries are bounded
+	embedder = &budgetEmbedder{clock: clock, transient: map[int]bool{1: true, 2: true, 3: true}}
+	_, err = newTestReindexer(newReindexFixture(), embedder, retrieval.NewInMemoryVectorStore(1), clock, ReindexOptions{MaxRetries: 2}).Reindex(context.Background())
+	if err == nil || len(embedder.calls) != 3 {
+		t.Errorf("Expected failure after 3 attempts, got %v after %d", err, len(embedder.calls))
+	}
+}
+
+func TestReindexResumesFromCheckpoint(t *testing.T) {
+	clock := utils.NewFakeClock(pruneNow)
+	checkpoint := filepath.Join(t.TempDir(), "reindex.json")
+	store := newReindexFixture()
+	opts := ReindexOptions{BatchSize: 2, MaxRetries: -1, CheckpointPath: checkpoint}
+
+	// The embedder gives out after two batches, as if the process crashed
+	crashed := &budgetEmbedder{clock: clock, budget: 2}
+	report, err := newTestReindexer(store, crashed, retrieval.NewInMemoryVectorStore(1), clock, opts).Reindex(context.Background())
+	if err == nil {
+		t.Fatal("Expected the reindex to fail once the budget ran out")
+	}
+	if report.Indexed != 4 {
+		t.Errorf("Expected 4 contexts indexed before the crash, got %d", report.Indexed)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("Expected a checkpoint after the crash: %v", err)
+	}
+
+	resumed := &budgetEmbedder{clock: clock}
+	report, err = newTestReindexer(store, resumed, retrieval.NewInMemoryVectorStore(1), clock, opts).Reindex(context.Background())
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if report.ResumedAfter != "c4" || report.Indexed != 1 {
+		t.Errorf("Expected to resume after c4 and index c5, got %+v", report)
+	}
+	if len(resumed.texts) != 1 || len(resumed.texts[0]) != 1 || resumed.texts[0][0] != "abstract c5" {
+		t.Errorf("Expected only c5 to be embedded, got %v", resumed.texts)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint removed on completion, got %v", err)
+	}
+}
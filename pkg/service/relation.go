@@ -5,11 +5,15 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/jqnote/goviking/pkg/storage"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 var (
@@ -21,160 +25,280 @@ var (
 
 // Relation represents a relation between resources.
 type Relation struct {
-	ID        string    `json:"id"`
-	Source    string    `json:"source"`
-	Target    string    `json:"target"`
-	Type      string    `json:"type"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	Source        string    `json:"source"`
+	Target        string    `json:"target"`
+	Type          string    `json:"type"`
+	Reason        string    `json:"reason,omitempty"`
+	Bidirectional bool      `json:"bidirectional"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
-// RelationService provides relation management functionality.
-type RelationService struct {
-	relations map[string]map[string]*Relation // source -> target -> Relation
-	mu        sync.RWMutex
+// Relation directions, relative to the resource whose relations are listed.
+const (
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+	DirectionBoth     = "both"
+)
+
+// matches reports whether the relation leads from or to resource in
+// direction. A bidirectional relation leads both ways.
+func (rel Relation) matches(resource, direction string) bool {
+	outgoing := rel.Source == resource || (rel.Bidirectional && rel.Target == resource)
+	incoming := rel.Target == resource || (rel.Bidirectional && rel.Source == resource)
+	switch direction {
+	case DirectionOutgoing:
+		return outgoing
+	case DirectionIncoming:
+		return incoming
+	default:
+		return outgoing || incoming
+	}
 }
 
-// NewRelationService creates a new relation service.
-func NewRelationService() *RelationService {
-	return &RelationService{
-		relations: make(map[string]map[string]*Relation),
+// relationFromEntry converts a stored relation. URIs are stored as a JSON
+// array, or comma-separated by older writers; relations with fewer than
+// two URIs are reported as not ok.
+func relationFromEntry(entry storage.RelationEntry) (*Relation, bool) {
+	var uris []string
+	if err := json.Unmarshal([]byte(entry.URIs), &uris); err != nil {
+		uris = strings.Split(entry.URIs, ",")
+	}
+	if len(uris) < 2 {
+		return nil, false
 	}
+	return &Relation{
+		ID:            entry.ID,
+		Source:        strings.TrimSpace(uris[0]),
+		Target:        strings.TrimSpace(uris[1]),
+		Type:          entry.Type,
+		Reason:        entry.Reason,
+		Bidirectional: entry.Bidirectional,
+		CreatedAt:     entry.CreatedAt,
+	}, true
 }
 
-// CreateRelation creates a new relation.
-func (s *RelationService) CreateRelation(ctx context.Context, source string, target string, relType string) (*Relation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RelationStore keeps relations. storage.StorageInterface implements it.
+type RelationStore interface {
+	CreateRelation(ctx context.Context, relation *storage.RelationEntry) error
+	QueryRelations(ctx context.Context, uri string) ([]storage.RelationEntry, error)
+	DeleteRelation(ctx context.Context, id string) error
+	IterateRelations(ctx context.Context, fn func(*storage.RelationEntry) error) error
+}
 
-	// Check if relation already exists
-	if s.relations[source] != nil {
-		if _, exists := s.relations[source][target]; exists {
-			return nil, ErrRelationExists
+// memoryRelationStore is a RelationStore kept in memory.
+type memoryRelationStore struct {
+	mu        sync.RWMutex
+	relations []storage.RelationEntry
+}
+
+func (m *memoryRelationStore) CreateRelation(ctx context.Context, relation *storage.RelationEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.relations = append(m.relations, *relation)
+	return nil
+}
+
+// QueryRelations returns the relations whose URIs contain uri, matching
+// like the SQL stores do.
+func (m *memoryRelationStore) QueryRelations(ctx context.Context, uri string) ([]storage.RelationEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []storage.RelationEntry
+	for _, r := range m.relations {
+		if strings.Contains(r.URIs, uri) {
+			matched = append(matched, r)
 		}
 	}
+	return matched, nil
+}
 
-	relation := &Relation{
-		ID:        uuid.New().String(),
-		Source:    source,
-		Target:    target,
-		Type:      relType,
-		CreatedAt: time.Now().UTC(),
+func (m *memoryRelationStore) DeleteRelation(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.relations {
+		if r.ID == id {
+			m.relations = append(m.relations[:i], m.relations[i+1:]...)
+			break
+		}
 	}
+	return nil
+}
 
-	if s.relations[source] == nil {
-		s.relations[source] = make(map[string]*Relation)
+func (m *memoryRelationStore) IterateRelations(ctx context.Context, fn func(*storage.RelationEntry) error) error {
+	m.mu.RLock()
+	relations := append([]storage.RelationEntry(nil), m.relations...)
+	m.mu.RUnlock()
+	for i := range relations {
+		if err := fn(&relations[i]); err != nil {
+			return err
+		}
 	}
-	s.relations[source][target] = relation
+	return nil
+}
 
-	return relation, nil
+// RelationService provides relation management functionality.
+type RelationService struct {
+	store RelationStore
 }
 
-// GetRelated gets all related resources.
-func (s *RelationService) GetRelated(ctx context.Context, resource string) ([]string, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// NewRelationService creates a new relation service keeping relations in
+// memory.
+func NewRelationService() *RelationService {
+	return NewRelationServiceWithStore(&memoryRelationStore{})
+}
 
-	var results []string
+// NewRelationServiceWithStore creates a relation service storing relations
+// in store.
+func NewRelationServiceWithStore(store RelationStore) *RelationService {
+	return &RelationService{store: store}
+}
 
-	// Get resources that this resource relates to
-	if relations, ok := s.relations[resource]; ok {
-		for target := range relations {
-			results = append(results, target)
-		}
-	}
+// CreateRelation creates a new relation of relType from source to target.
+func (s *RelationService) CreateRelation(ctx context.Context, source string, target string, relType string) (*Relation, error) {
+	return s.AddRelation(ctx, Relation{Source: source, Target: target, Type: relType})
+}
 
-	// Get resources that relate to this resource
-	for source, relations := range s.relations {
-		if source == resource {
-			continue
-		}
-		for target := range relations {
-			if target == resource {
-				results = append(results, source)
-			}
+// AddRelation stores a relation from rel.Source to rel.Target, filling in
+// its ID and creation time. It returns ErrRelationExists when one already
+// leads from the source to the target.
+func (s *RelationService) AddRelation(ctx context.Context, rel Relation) (*Relation, error) {
+	existing, err := s.ListRelations(ctx, rel.Source, DirectionOutgoing)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		if e.Source == rel.Source && e.Target == rel.Target {
+			return nil, ErrRelationExists
 		}
 	}
 
-	return results, nil
+	uris, err := json.Marshal([]string{rel.Source, rel.Target})
+	if err != nil {
+		return nil, err
+	}
+	rel.ID = utils.GenerateID()
+	rel.CreatedAt = utils.Now()
+	entry := storage.RelationEntry{
+		ID:            rel.ID,
+		URIs:          string(uris),
+		Reason:        rel.Reason,
+		Bidirectional: rel.Bidirectional,
+		Type:          rel.Type,
+		CreatedAt:     rel.CreatedAt,
+	}
+	if err := s.store.CreateRelation(ctx, &entry); err != nil {
+		return nil, fmt.Errorf("failed to store relation: %w", err)
+	}
+	return &rel, nil
 }
 
 // GetRelations gets all relations for a resource.
 func (s *RelationService) GetRelations(ctx context.Context, resource string) ([]*Relation, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	var results []*Relation
+	return s.ListRelations(ctx, resource, DirectionBoth)
+}
 
-	// Get outgoing relations
-	if relations, ok := s.relations[resource]; ok {
-		for _, rel := range relations {
-			results = append(results, rel)
+// ListRelations gets the relations of a resource in direction, one of
+// DirectionOutgoing, DirectionIncoming or DirectionBoth.
+func (s *RelationService) ListRelations(ctx context.Context, resource, direction string) ([]*Relation, error) {
+	entries, err := s.store.QueryRelations(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relations: %w", err)
+	}
+	relations := []*Relation{}
+	for _, entry := range entries {
+		// QueryRelations matches substrings, so check the endpoints
+		if rel, ok := relationFromEntry(entry); ok && rel.matches(resource, direction) {
+			relations = append(relations, rel)
 		}
 	}
+	return relations, nil
+}
 
-	// Get incoming relations
-	for source, relations := range s.relations {
-		if source == resource {
-			continue
-		}
-		for _, rel := range relations {
-			if rel.Target == resource {
-				results = append(results, rel)
-			}
+// GetRelated gets all related resources.
+func (s *RelationService) GetRelated(ctx context.Context, resource string) ([]string, error) {
+	relations, err := s.ListRelations(ctx, resource, DirectionBoth)
+	if err != nil {
+		return nil, err
+	}
+	var results []string
+	for _, rel := range relations {
+		if rel.Source == resource {
+			results = append(results, rel.Target)
+		} else {
+			results = append(results, rel.Source)
 		}
 	}
-
 	return results, nil
 }
 
-// DeleteRelation deletes a relation.
+// DeleteRelation deletes every relation from source to target, including
+// bidirectional ones stored the other way round.
 func (s *RelationService) DeleteRelation(ctx context.Context, source string, target string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.relations[source] == nil {
+	relations, err := s.ListRelations(ctx, source, DirectionOutgoing)
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for _, rel := range relations {
+		if rel.Target != target && rel.Source != target {
+			continue
+		}
+		if err := s.store.DeleteRelation(ctx, rel.ID); err != nil {
+			return fmt.Errorf("failed to delete relation: %w", err)
+		}
+		deleted++
+	}
+	if deleted == 0 {
 		return ErrRelationNotFound
 	}
+	return nil
+}
 
-	if _, exists := s.relations[source][target]; !exists {
+// DeleteRelationByID deletes the relation with the given ID.
+func (s *RelationService) DeleteRelationByID(ctx context.Context, id string) error {
+	// Relations cannot be fetched by ID, so scan for it
+	found := false
+	err := s.store.IterateRelations(ctx, func(entry *storage.RelationEntry) error {
+		if entry.ID == id {
+			found = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to look up relation: %w", err)
+	}
+	if !found {
 		return ErrRelationNotFound
 	}
-
-	delete(s.relations[source], target)
-	return nil
+	return s.store.DeleteRelation(ctx, id)
 }
 
 // DeleteAllRelations deletes all relations for a resource.
 func (s *RelationService) DeleteAllRelations(ctx context.Context, resource string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Delete outgoing relations
-	delete(s.relations, resource)
-
-	// Delete incoming relations
-	for source := range s.relations {
-		for target := range s.relations[source] {
-			if target == resource {
-				delete(s.relations[source], target)
-			}
+	relations, err := s.ListRelations(ctx, resource, DirectionBoth)
+	if err != nil {
+		return err
+	}
+	for _, rel := range relations {
+		if err := s.store.DeleteRelation(ctx, rel.ID); err != nil {
+			return fmt.Errorf("failed to delete relation: %w", err)
 		}
 	}
-
 	return nil
 }
 
 // GetAllRelations gets all relations in the system.
 func (s *RelationService) GetAllRelations(ctx context.Context) ([]*Relation, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	var results []*Relation
-	for _, relations := range s.relations {
-		for _, rel := range relations {
+	err := s.store.IterateRelations(ctx, func(entry *storage.RelationEntry) error {
+		if rel, ok := relationFromEntry(*entry); ok {
 			results = append(results, rel)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations: %w", err)
 	}
-
 	return results, nil
 }
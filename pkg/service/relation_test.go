@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRelationService(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryRelationStore{}
+	svc := NewRelationServiceWithStore(store)
+	a, b, c := "viking://resources/a", "viking://resources/b", "viking://resources/c"
+
+	ab, err := svc.AddRelation(ctx, Relation{Source: a, Target: b, Type: "cites", Reason: "a cites b"})
+	if err != nil {
+		t.Fatalf("AddRelation failed: %v", err)
+	}
+	if ab.ID == "" || ab.CreatedAt.IsZero() {
+		t.Errorf("Expected an ID and creation time, got %+v", ab)
+	}
+	if len(store.relations) != 1 || store.relations[0].URIs != `["viking://resources/a","viking://resources/b"]` {
+		t.Errorf("Expected the relation stored, got %+v", store.relations)
+	}
+	if _, err := svc.CreateRelation(ctx, a, b, "cites"); !errors.Is(err, ErrRelationExists) {
+		t.Errorf("Expected ErrRelationExists for a duplicate, got %v", err)
+	}
+	if _, err := svc.AddRelation(ctx, Relation{Source: c, Target: b, Bidirectional: true}); err != nil {
+		t.Fatalf("AddRelation failed: %v", err)
+	}
+
+	tests := []struct {
+		resource, direction string
+		want                int
+	}{
+		{b, DirectionIncoming, 2},
+		{b, DirectionOutgoing, 1},
+		{a, DirectionIncoming, 0},
+		{c, DirectionBoth, 1},
+	}
+	for _, tt := range tests {
+		relations, err := svc.ListRelations(ctx, tt.resource, tt.direction)
+		if err != nil {
+			t.Fatalf("ListRelations failed: %v", err)
+		}
+		if len(relations) != tt.want {
+			t.Errorf("ListRelations(%s, %s): Expected %d relations, got %+v", tt.resource, tt.direction, tt.want, relations)
+		}
+	}
+	outgoing, err := svc.ListRelations(ctx, a, DirectionOutgoing)
+	if err != nil || len(outgoing) != 1 || outgoing[0].Type != "cites" || outgoing[0].Reason != "a cites b" {
+		t.Errorf("Expected the type and reason to round-trip, got %+v (%v)", outgoing, err)
+	}
+	related, err := svc.GetRelated(ctx, b)
+	if err != nil || len(related) != 2 {
+		t.Errorf("Expected a and c related to b, got %v (%v)", related, err)
+	}
+
+	// Bidirectional relations can be deleted from either end
+	if err := svc.DeleteRelation(ctx, b, c); err != nil {
+		t.Errorf("DeleteRelation failed: %v", err)
+	}
+	if err := svc.DeleteRelation(ctx, b, c); !errors.Is(err, ErrRelationNotFound) {
+		t.Errorf("Expected ErrRelationNotFound once deleted, got %v", err)
+	}
+	if err := svc.DeleteRelationByID(ctx, ab.ID); err != nil {
+		t.Errorf("DeleteRelationByID failed: %v", err)
+	}
+	if err := svc.DeleteRelationByID(ctx, ab.ID); !errors.Is(err, ErrRelationNotFound) {
+		t.Errorf("Expected ErrRelationNotFound for a missing ID, got %v", err)
+	}
+	if len(store.relations) != 0 {
+		t.Errorf("Expected no relations left, got %+v", store.relations)
+	}
+}
@@ -5,9 +5,15 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 
-	"github.com/google/uuid"
+	"github.com/jqnote/goviking/pkg/config"
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/session"
 )
 
 // SearchResult represents a search result.
@@ -17,17 +23,90 @@ type SearchResult struct {
 	Title     string         `json:"title"`
 	Content   string         `json:"content"`
 	Score     float64        `json:"score"`
+	RawScore  float64        `json:"raw_score"`
 	Type      string         `json:"type"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
 	SessionID string         `json:"session_id,omitempty"`
 }
 
+// HotnessSource reports how hot a context is, from 0 for never used to 1
+// for heavily and recently used.
+type HotnessSource interface {
+	Hotness(ctx context.Context, uri string) (float64, error)
+}
+
+// ContentSource returns the full content of a context, for keyword scoring
+// against more than its abstract.
+type ContentSource interface {
+	Content(ctx context.Context, uri string) (string, error)
+}
+
+// MemoryStore loads the memories recorded for a session.
+type MemoryStore interface {
+	GetMemories(ctx context.Context, sessionID string) ([]*session.ExtractedMemory, error)
+}
+
+// personalCategories are the memory categories that describe the user and
+// therefore drive personalization.
+var personalCategories = map[string]bool{
+	string(session.CategoryProfile):    true,
+	string(session.CategoryPreference): true,
+}
+
+const (
+	// termsPerMemory is the number of keywords taken from each memory.
+	termsPerMemory = 3
+	// maxExpansionTerms caps how many memory terms are appended to a query.
+	maxExpansionTerms = 5
+)
+
+const (
+	// DefaultSearchLimit is the number of results returned when neither
+	// the request nor the configuration sets a limit.
+	DefaultSearchLimit = 10
+	// DefaultMaxResultsCap is the most results one search may ask for.
+	DefaultMaxResultsCap = 100
+)
+
+// searchableTypes are the context types queried when no type filter is given.
+var searchableTypes = []retrieval.ContextType{
+	retrieval.ContextTypeMemory,
+	retrieval.ContextTypeResource,
+	retrieval.ContextTypeSkill,
+}
+
 // SearchService provides search functionality.
 type SearchService struct {
 	// Embed retrieval components (would be injected)
 	hybridSearch interface{ /* HybridRetriever interface */ }
 
+	// Retriever and the default options it is queried with
+	retriever     retrieval.Retriever
+	searchOptions retrieval.SearchOptions
+	planner       QueryPlanner
+
+	// Results returned when a request sets no limit, and the most a
+	// request may ask for (0 = no cap)
+	defaultLimit  int
+	maxResultsCap int
+
+	// Optional query expansion, blended in at expansionWeight
+	expander        QueryExpander
+	expansionWeight float64
+
+	// Blending of keyword relevance and hotness into retriever scores,
+	// off unless configured
+	weights   retrieval.FusionWeights
+	tokenizer *retrieval.Tokenizer
+	hotness   HotnessSource
+
+	// Text scored for keyword relevance, and where full content comes from
+	indexSource   retrieval.IndexSource
+	abstractBoost int
+	content       ContentSource
+
 	// Personalization data
+	memoryStore     MemoryStore
 	personalization map[string]map[string]float64 // sessionID -> term -> boost
 	mu              sync.RWMutex
 
@@ -38,11 +117,135 @@ type SearchService struct {
 // NewSearchService creates a new search service.
 func NewSearchService() *SearchService {
 	return &SearchService{
+		searchOptions:   retrieval.DefaultSearchOptions(),
+		defaultLimit:    DefaultSearchLimit,
+		maxResultsCap:   DefaultMaxResultsCap,
+		expansionWeight: DefaultExpansionWeight,
+		tokenizer:       retrieval.NewTokenizer(retrieval.DefaultTokenizerConfig()),
+		indexSource:     retrieval.IndexAbstract,
+		abstractBoost:   retrieval.DefaultAbstractBoost,
 		personalization: make(map[string]map[string]float64),
 		typeIndex:       make(map[string][]string),
 	}
 }
 
+// NewSearchServiceFromConfig creates a search service whose retriever is
+// built from the retrieval configuration. The similarity threshold and
+// maximum result count become the retriever's search defaults, the results
+// cap bounds every requested limit, and the keyword and hotness weights
+// become the default score blend. Without an embedder, a NoopEmbedder of
+// the configured dimension stands in and results are ranked by keyword
+// alone.
+func NewSearchServiceFromConfig(cfg config.RetrievalConfig, embedder retrieval.Embedder, vectorStore retrieval.VectorStore) *SearchService {
+	if embedder == nil {
+		embedder = retrieval.NewNoopEmbedder(cfg.EmbeddingDimension)
+	}
+
+	s := NewSearchService()
+	s.weights = retrieval.FusionWeights{Keyword: cfg.KeywordWeight, Hotness: cfg.HotnessWeight}
+	s.tokenizer = retrieval.NewTokenizer(retrieval.TokenizerConfigFor(cfg.Tokenizer))
+
+	retrieverConfig := retrieval.DefaultRetrieverConfig()
+	retrieverConfig.ScoreThreshold = cfg.SimilarityThreshold
+	retrieverConfig.Tokenizer = retrieval.TokenizerConfigFor(cfg.Tokenizer)
+	retrieverConfig.Weights = s.weights
+	retrieverConfig.EmbeddingModel = cfg.EmbeddingModel
+	retrieverConfig.StrictEmbedding = cfg.StrictEmbedding
+	s.retriever = retrieval.NewHierarchicalRetriever(embedder, vectorStore, retrieverConfig)
+
+	s.indexSource = retrieval.IndexSourceFor(cfg.IndexSource)
+	if cfg.AbstractBoost > 0 {
+		s.abstractBoost = cfg.AbstractBoost
+	}
+
+	s.searchOptions.ScoreThreshold = cfg.SimilarityThreshold
+	if cfg.MaxResults > 0 {
+		s.searchOptions.Limit = cfg.MaxResults
+		s.defaultLimit = cfg.MaxResults
+	}
+	s.maxResultsCap = cfg.MaxResultsCap
+
+	if cfg.QueryExpansion.Enabled && len(cfg.QueryExpansion.Synonyms) > 0 {
+		s.expander = NewSynonymExpander(cfg.QueryExpansion.Synonyms)
+	}
+	if cfg.QueryExpansion.Weight > 0 {
+		s.expansionWeight = cfg.QueryExpansion.Weight
+	}
+
+	return s
+}
+
+// SetMaxResultsCap sets the most results one search or find may ask for;
+// larger requested limits are clamped to it. 0 removes the cap.
+func (s *SearchService) SetMaxResultsCap(n int) {
+	s.maxResultsCap = n
+}
+
+// MaxResultsCap returns the most results one search or find may ask for,
+// or 0 when uncapped.
+func (s *SearchService) MaxResultsCap() int {
+	return s.maxResultsCap
+}
+
+// effectiveLimit returns the limit applied to a request asking for limit:
+// the default limit when it asks for none, clamped to the results cap.
+func (s *SearchService) effectiveLimit(limit int) int {
+	if limit == 0 {
+		limit = s.defaultLimit
+	}
+	if s.maxResultsCap > 0 && limit > s.maxResultsCap {
+		limit = s.maxResultsCap
+	}
+	return limit
+}
+
+// SetRetriever sets the retriever used to answer searches.
+func (s *SearchService) SetRetriever(r retrieval.Retriever) {
+	s.retriever = r
+}
+
+// SetMemoryStore sets the store used to load session memories for
+// personalization.
+func (s *SearchService) SetMemoryStore(ms MemoryStore) {
+	s.memoryStore = ms
+}
+
+// SetHotnessSource sets the source of hotness scores. Without one, hotness
+// does not affect ranking.
+func (s *SearchService) SetHotnessSource(hs HotnessSource) {
+	s.hotness = hs
+}
+
+// SetContentSource sets the source of full context content, read when the
+// index source scores keyword relevance against content.
+func (s *SearchService) SetContentSource(cs ContentSource) {
+	s.content = cs
+}
+
+// SetIndexSource sets the text of each result scored for keyword
+// relevance, and how many times abstract terms count for
+// retrieval.IndexBoth. Content is only read with a content source;
+// without one, results are scored by their abstract.
+func (s *SearchService) SetIndexSource(source retrieval.IndexSource, abstractBoost int) {
+	s.indexSource = source
+	s.abstractBoost = abstractBoost
+}
+
+// SetFusionWeights sets the default keyword and hotness weights.
+func (s *SearchService) SetFusionWeights(w retrieval.FusionWeights) {
+	s.weights = w
+}
+
+// FusionWeights returns the default keyword and hotness weights.
+func (s *SearchService) FusionWeights() retrieval.FusionWeights {
+	return s.weights
+}
+
+// SearchOptions returns the default options used when querying the retriever.
+func (s *SearchService) SearchOptions() retrieval.SearchOptions {
+	return s.searchOptions
+}
+
 // SetHybridSearch sets the hybrid search implementation.
 func (s *SearchService) SetHybridSearch(hs interface{}) {
 	s.hybridSearch = hs
@@ -53,22 +256,80 @@ type SearchRequest struct {
 	Query      string
 	SessionID  string
 	Filters    map[string]string
+	// Limit is the number of results wanted. Search replaces it with the
+	// limit applied: the default when zero, clamped so that Offset+Limit
+	// stays within the results cap.
 	Limit      int
 	Offset     int
 	Personalize bool
+
+	// Weights overrides the service's default score blend when set
+	Weights *retrieval.FusionWeights
 }
 
 // Search performs a search.
 func (s *SearchService) Search(ctx context.Context, req *SearchRequest) ([]SearchResult, error) {
+	results, _, err := s.search(ctx, req)
+	return results, err
+}
+
+// search performs a search, also returning how the retriever traversed the
+// context tree for each queried context type.
+func (s *SearchService) search(ctx context.Context, req *SearchRequest) ([]SearchResult, []Traversal, error) {
+	req.Limit = s.effectiveLimit(req.Limit)
+
+	// The cap bounds how deep a search retrieves, so an offset cannot
+	// page past it
+	if s.maxResultsCap > 0 && req.Offset+req.Limit > s.maxResultsCap {
+		req.Limit = max(s.maxResultsCap-req.Offset, 0)
+	}
 	if req.Limit == 0 {
-		req.Limit = 10
+		return []SearchResult{}, nil, nil
 	}
 
-	results := s.basicSearch(ctx, req.Query, req.Limit)
+	weights := s.weights
+	if req.Weights != nil {
+		if err := req.Weights.Validate(); err != nil {
+			return nil, nil, err
+		}
+		weights = *req.Weights
+	}
 
-	// Apply personalization if enabled
+	var boosts map[string]float64
 	if req.Personalize && req.SessionID != "" {
-		results = s.applyPersonalization(ctx, results, req.SessionID)
+		var err error
+		boosts, err = s.personalBoosts(ctx, req.SessionID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	results, traversals, err := s.retrieve(ctx, req, expandQuery(req.Query, boosts))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expansions, err := s.expandedQueries(ctx, req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, q := range expansions {
+		expanded, more, err := s.retrieve(ctx, req, expandQuery(q, boosts))
+		if err != nil {
+			return nil, nil, err
+		}
+		results = blendResults(results, expanded, s.expansionWeight)
+		traversals = append(traversals, more...)
+	}
+
+	results, err = s.fuse(ctx, req.Query, results, weights)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Apply personalization if enabled
+	if len(boosts) > 0 {
+		results = applyBoosts(results, boosts)
 	}
 
 	// Apply filters
@@ -78,7 +339,7 @@ func (s *SearchService) Search(ctx context.Context, req *SearchRequest) ([]Searc
 
 	// Apply pagination
 	if req.Offset > len(results) {
-		return []SearchResult{}, nil
+		return []SearchResult{}, traversals, nil
 	}
 
 	end := req.Offset + req.Limit
@@ -86,50 +347,211 @@ func (s *SearchService) Search(ctx context.Context, req *SearchRequest) ([]Searc
 		end = len(results)
 	}
 
-	return results[req.Offset:end], nil
+	return results[req.Offset:end], traversals, nil
+}
+
+// retrieve queries the retriever for each requested context type and merges
+// the matches by score.
+func (s *SearchService) retrieve(ctx context.Context, req *SearchRequest, queryText string) ([]SearchResult, []Traversal, error) {
+	results := []SearchResult{}
+	if s.retriever == nil {
+		return results, nil, nil
+	}
+
+	contextTypes := searchableTypes
+	if t, ok := req.Filters["type"]; ok {
+		contextTypes = []retrieval.ContextType{retrieval.ContextType(t)}
+	}
+
+	opts := s.searchOptions
+	opts.Limit = req.Offset + req.Limit
+	opts.MetadataFilter = nil
+	for key, value := range req.Filters {
+		if key == "type" || key == "session_id" {
+			continue
+		}
+		if opts.MetadataFilter == nil {
+			opts.MetadataFilter = make(map[string]interface{})
+		}
+		opts.MetadataFilter[key] = value
+	}
+
+	var traversals []Traversal
+	for _, contextType := range contextTypes {
+		query := retrieval.TypedQuery{Query: queryText, ContextType: contextType}
+		qr, err := s.retriever.Retrieve(ctx, query, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve %s contexts: %w", contextType, err)
+		}
+		for _, mc := range qr.MatchedContexts {
+			results = append(results, toSearchResult(mc, req.SessionID))
+		}
+		traversals = append(traversals, toTraversal(contextType, qr))
+	}
+
+	sortByScore(results)
+	return results, traversals, nil
+}
+
+// toSearchResult maps a matched context to a service search result.
+func toSearchResult(mc retrieval.MatchedContext, sessionID string) SearchResult {
+	r := SearchResult{
+		ID:        mc.URI,
+		URI:       mc.URI,
+		Title:     path.Base(mc.URI),
+		Content:   mc.Abstract,
+		Score:     mc.Score,
+		RawScore:  mc.RawScore,
+		Type:      string(mc.ContextType),
+		SessionID: sessionID,
+	}
+	if mc.Category != "" {
+		r.Metadata = map[string]any{"category": mc.Category}
+	}
+	return r
+}
+
+// fuse blends keyword relevance and hotness into the retriever scores
+// according to weights and re-sorts the results. Keyword relevance is
+// scored against the text picked by the index source and skipped when no
+// result matches the query terms; hotness is skipped when the service has
+// no hotness source.
+func (s *SearchService) fuse(ctx context.Context, query string, results []SearchResult, weights retrieval.FusionWeights) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	if weights.Keyword > 0 {
+		texts := make([]string, len(results))
+		for i, r := range results {
+			text, err := s.keywordText(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			texts[i] = r.Title + " " + text
+		}
+		keywordScores := retrieval.KeywordScores(s.tokenizer, query, texts)
+		matched := false
+		for _, score := range keywordScores {
+			if score > 0 {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			for i := range results {
+				results[i].Score = retrieval.CombineScores(results[i].Score, keywordScores[i], weights.Keyword)
+			}
+		}
+	}
+
+	if weights.Hotness > 0 && s.hotness != nil {
+		for i := range results {
+			hotness, err := s.hotness.Hotness(ctx, results[i].URI)
+			if err != nil {
+				return nil, fmt.Errorf("failed to score hotness of %s: %w", results[i].URI, err)
+			}
+			results[i].Score = retrieval.CombineScores(results[i].Score, hotness, weights.Hotness)
+		}
+	}
+
+	sortByScore(results)
+	return results, nil
 }
 
-// basicSearch performs a basic search (placeholder).
-func (s *SearchService) basicSearch(ctx context.Context, query string, limit int) []SearchResult {
-	// This would integrate with the actual retrieval system
-	// For now, return placeholder results
-	return []SearchResult{
-		{
-			ID:      uuid.New().String(),
-			URI:     "/docs/example",
-			Title:   "Example Document",
-			Content: "Content matching: " + query,
-			Score:   0.9,
-			Type:    "document",
-		},
+// keywordText returns the text of r scored for keyword relevance: its
+// abstract, its full content, or both, as the index source says.
+func (s *SearchService) keywordText(ctx context.Context, r SearchResult) (string, error) {
+	if s.indexSource == retrieval.IndexAbstract || s.content == nil {
+		return r.Content, nil
+	}
+	content, err := s.content.Content(ctx, r.URI)
+	if err != nil {
+		return "", fmt.Errorf("failed to load content of %s: %w", r.URI, err)
 	}
+	return retrieval.IndexText(s.indexSource, s.abstractBoost, r.Content, content), nil
 }
 
-// applyPersonalization applies session-based personalization.
-func (s *SearchService) applyPersonalization(ctx context.Context, results []SearchResult, sessionID string) []SearchResult {
+// sortByScore sorts results by score descending.
+func sortByScore(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// personalBoosts returns per-term boosts for a session, combining terms from
+// the session's profile and preference memories with its recorded searches.
+func (s *SearchService) personalBoosts(ctx context.Context, sessionID string) (map[string]float64, error) {
+	boosts := make(map[string]float64)
+
+	if s.memoryStore != nil {
+		memories, err := s.memoryStore.GetMemories(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session memories: %w", err)
+		}
+		for _, m := range memories {
+			if !personalCategories[m.Category] {
+				continue
+			}
+			for _, term := range retrieval.ExtractKeywords(m.Content, termsPerMemory) {
+				boosts[term] += m.Importance
+			}
+		}
+	}
+
 	s.mu.RLock()
-	boosts := s.personalization[sessionID]
+	for term, boost := range s.personalization[sessionID] {
+		boosts[strings.ToLower(term)] += boost
+	}
 	s.mu.RUnlock()
 
+	return boosts, nil
+}
+
+// expandQuery appends the strongest personalization terms to the query so
+// the retriever's embedding leans toward the user's interests.
+func expandQuery(query string, boosts map[string]float64) string {
 	if len(boosts) == 0 {
-		return results
+		return query
+	}
+
+	terms := make([]string, 0, len(boosts))
+	for term := range boosts {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if boosts[terms[i]] != boosts[terms[j]] {
+			return boosts[terms[i]] > boosts[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > maxExpansionTerms {
+		terms = terms[:maxExpansionTerms]
 	}
 
-	// Apply boosts based on session history
+	return query + " " + strings.Join(terms, " ")
+}
+
+// applyBoosts raises the score of results mentioning boosted terms and
+// re-sorts them.
+func applyBoosts(results []SearchResult, boosts map[string]float64) []SearchResult {
 	for i := range results {
+		content := strings.ToLower(results[i].Content)
+		title := strings.ToLower(results[i].Title)
 		for term, boost := range boosts {
-			if contains(results[i].Content, term) || contains(results[i].Title, term) {
+			if strings.Contains(content, term) || strings.Contains(title, term) {
 				results[i].Score *= (1 + boost)
 			}
 		}
 	}
 
+	sortByScore(results)
 	return results
 }
 
 // applyFilters applies filters to search results.
 func (s *SearchService) applyFilters(ctx context.Context, results []SearchResult, filters map[string]string) []SearchResult {
-	var filtered []SearchResult
+	filtered := make([]SearchResult, 0, len(results))
 
 	for _, r := range results {
 		match := true
@@ -200,20 +622,6 @@ func tokenizeQuery(query string) []string {
 	return tokens
 }
 
-// contains is a simple string contains check.
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
-}
-
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
-
 // IndexResult indexes a result for faster filtering.
 func (s *SearchService) IndexResult(result SearchResult) {
 	s.mu.Lock()
@@ -0,0 +1,462 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/config"
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/session"
+)
+
+func TestNewSearchServiceFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "retrieval:\n  similarity_threshold: 0.42\n  max_results: 7\n  strict_embedding: true\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	svc := NewSearchServiceFromConfig(cfg.Retrieval, nil, retrieval.NewInMemoryVectorStore(3))
+	hr, ok := svc.retriever.(*retrieval.HierarchicalRetriever)
+	if !ok {
+		t.Fatalf("Expected a HierarchicalRetriever, got %T", svc.retriever)
+	}
+	if got := hr.Config().ScoreThreshold; got != 0.42 {
+		t.Errorf("Expected retriever threshold 0.42, got %v", got)
+	}
+	if !hr.Config().StrictEmbedding {
+		t.Error("Expected strict embedding from config")
+	}
+
+	opts := svc.SearchOptions()
+	if opts.ScoreThreshold != 0.42 {
+		t.Errorf("Expected search threshold 0.42, got %v", opts.ScoreThreshold)
+	}
+	if opts.Limit != 7 {
+		t.Errorf("Expected search limit 7, got %d", opts.Limit)
+	}
+}
+
+func TestNewSearchServiceFromConfigDefaults(t *testing.T) {
+	svc := NewSearchServiceFromConfig(config.RetrievalConfig{}, nil, nil)
+
+	defaults := retrieval.DefaultSearchOptions()
+	if svc.SearchOptions().Limit != defaults.Limit {
+		t.Errorf("Expected default limit %d, got %d", defaults.Limit, svc.SearchOptions().Limit)
+	}
+}
+
+// fakeRetriever returns canned matches per context type.
+type fakeRetriever struct {
+	matches map[retrieval.ContextType][]retrieval.MatchedContext
+	queries []retrieval.TypedQuery
+	limits  []int
+}
+
+func (f *fakeRetriever) Retrieve(ctx context.Context, query retrieval.TypedQuery, opts retrieval.SearchOptions) (*retrieval.QueryResult, error) {
+	f.queries = append(f.queries, query)
+	f.limits = append(f.limits, opts.Limit)
+	return &retrieval.QueryResult{
+		Query:           query,
+		MatchedContexts: f.matches[query.ContextType],
+	}, nil
+}
+
+func newFakeRetriever() *fakeRetriever {
+	return &fakeRetriever{
+		matches: map[retrieval.ContextType][]retrieval.MatchedContext{
+			retrieval.ContextTypeResource: {
+				{URI: "viking://resources/go-guide", ContextType: retrieval.ContextTypeResource, Abstract: "golang concurrency guide", Score: 0.9},
+				{URI: "viking://resources/python-guide", ContextType: retrieval.ContextTypeResource, Abstract: "python packaging guide", Score: 0.8, RawScore: 0.6},
+			},
+			retrieval.ContextTypeMemory: {
+				{URI: "viking://user/memories/editor", ContextType: retrieval.ContextTypeMemory, Abstract: "prefers vim", Score: 0.85},
+			},
+		},
+	}
+}
+
+func TestSearchServiceUsesRetriever(t *testing.T) {
+	svc := NewSearchService()
+	fake := newFakeRetriever()
+	svc.SetRetriever(fake)
+
+	results, err := svc.Search(context.Background(), &SearchRequest{Query: "guide", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(fake.queries) != len(searchableTypes) {
+		t.Errorf("Expected %d retriever queries, got %d", len(searchableTypes), len(fake.queries))
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	want := []string{"viking://resources/go-guide", "viking://user/memories/editor", "viking://resources/python-guide"}
+	for i, uri := range want {
+		if results[i].URI != uri {
+			t.Errorf("Result %d: expected %s, got %s", i, uri, results[i].URI)
+		}
+	}
+	if results[0].Content != "golang concurrency guide" {
+		t.Errorf("Expected abstract as content, got %q", results[0].Content)
+	}
+}
+
+func TestSearchServiceFilters(t *testing.T) {
+	svc := NewSearchService()
+	fake := newFakeRetriever()
+	svc.SetRetriever(fake)
+
+	results, err := svc.Search(context.Background(), &SearchRequest{
+		Query:   "guide",
+		Filters: map[string]string{"type": "memory"},
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(fake.queries) != 1 || fake.queries[0].ContextType != retrieval.ContextTypeMemory {
+		t.Errorf("Expected a single memory query, got %v", fake.queries)
+	}
+	if len(results) != 1 || results[0].Type != "memory" {
+		t.Errorf("Expected only memory results, got %v", results)
+	}
+}
+
+func TestSearchServicePersonalize(t *testing.T) {
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+	svc.RecordSearch(context.Background(), "session-1", "python")
+	svc.RecordSearch(context.Background(), "session-1", "python")
+
+	req := &SearchRequest{Query: "guide", SessionID: "session-1", Limit: 10}
+	plain, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if plain[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected go-guide first without personalization, got %s", plain[0].URI)
+	}
+
+	req.Personalize = true
+	personalized, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if personalized[0].URI != "viking://resources/python-guide" {
+		t.Errorf("Expected python-guide first with personalization, got %s", personalized[0].URI)
+	}
+	if personalized[0].RawScore != 0.6 {
+		t.Errorf("Expected raw score 0.6 to survive boosting, got %f", personalized[0].RawScore)
+	}
+}
+
+func TestSearchServiceNoRetriever(t *testing.T) {
+	results, err := NewSearchService().Search(context.Background(), &SearchRequest{Query: "anything"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results == nil || len(results) != 0 {
+		t.Errorf("Expected empty results, got %v", results)
+	}
+}
+
+// fakeMemoryStore returns canned memories per session.
+type fakeMemoryStore map[string][]*session.ExtractedMemory
+
+func (f fakeMemoryStore) GetMemories(ctx context.Context, sessionID string) ([]*session.ExtractedMemory, error) {
+	return f[sessionID], nil
+}
+
+func TestSearchServicePersonalizeWithMemories(t *testing.T) {
+	svc := NewSearchService()
+	fake := newFakeRetriever()
+	svc.SetRetriever(fake)
+	svc.SetMemoryStore(fakeMemoryStore{
+		"session-py": {
+			{Content: "User writes Python every day", Importance: 0.8, Category: "preference"},
+			{Content: "User visited Golang conference", Importance: 0.9, Category: "event"},
+		},
+	})
+
+	req := &SearchRequest{Query: "guide", SessionID: "session-py", Limit: 10}
+	plain, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if plain[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected go-guide first without personalization, got %s", plain[0].URI)
+	}
+
+	fake.queries = nil
+	req.Personalize = true
+	personalized, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if personalized[0].URI != "viking://resources/python-guide" {
+		t.Errorf("Expected python-guide first with personalization, got %s", personalized[0].URI)
+	}
+	if fake.queries[0].Query == "guide" {
+		t.Error("Expected query to be expanded with memory terms")
+	}
+	for _, q := range fake.queries {
+		if strings.Contains(q.Query, "golang") {
+			t.Errorf("Event memories should not drive personalization, got query %q", q.Query)
+		}
+	}
+}
+
+func TestSearchServicePersonalizeWithoutMemories(t *testing.T) {
+	svc := NewSearchService()
+	fake := newFakeRetriever()
+	svc.SetRetriever(fake)
+	svc.SetMemoryStore(fakeMemoryStore{})
+
+	results, err := svc.Search(context.Background(), &SearchRequest{
+		Query:       "guide",
+		SessionID:   "unknown",
+		Personalize: true,
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if fake.queries[0].Query != "guide" {
+		t.Errorf("Expected unexpanded query, got %q", fake.queries[0].Query)
+	}
+	if results[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected unpersonalized ordering, got %s first", results[0].URI)
+	}
+}
+
+// fixedHotness returns canned hotness scores by URI.
+type fixedHotness map[string]float64
+
+func (h fixedHotness) Hotness(ctx context.Context, uri string) (float64, error) {
+	return h[uri], nil
+}
+
+func resultURIs(results []SearchResult) []string {
+	uris := make([]string, len(results))
+	for i, r := range results {
+		uris[i] = r.URI
+	}
+	return uris
+}
+
+func TestNewSearchServiceFromConfigWeights(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "retrieval:\n  keyword_weight: 0.8\n  hotness_weight: 0.1\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	svc := NewSearchServiceFromConfig(cfg.Retrieval, nil, nil)
+	want := retrieval.FusionWeights{Keyword: 0.8, Hotness: 0.1}
+	if got := svc.FusionWeights(); got != want {
+		t.Errorf("Expected weights %+v, got %+v", want, got)
+	}
+	hr := svc.retriever.(*retrieval.HierarchicalRetriever)
+	if got := hr.Config().Weights; got != want {
+		t.Errorf("Expected retriever weights %+v, got %+v", want, got)
+	}
+
+	svc.SetRetriever(newFakeRetriever())
+	results, err := svc.Search(context.Background(), &SearchRequest{Query: "python", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results[0].URI != "viking://resources/python-guide" {
+		t.Errorf("Expected keyword match to rank first, got %v", resultURIs(results))
+	}
+}
+
+func TestSearchServiceLimitCap(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSearchService()
+	fake := newFakeRetriever()
+	svc.SetRetriever(fake)
+	svc.SetMaxResultsCap(2)
+
+	req := &SearchRequest{Query: "guide", Limit: 1000000}
+	results, err := svc.Search(ctx, req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if req.Limit != 2 || len(results) != 2 {
+		t.Errorf("Expected the limit clamped to 2, got limit %d and %d results", req.Limit, len(results))
+	}
+	for _, limit := range fake.limits {
+		if limit != 2 {
+			t.Errorf("Expected the retriever asked for 2 results, got %d", limit)
+		}
+	}
+
+	fake.limits = nil
+	find := &FindRequest{Query: "guide", Limit: 500}
+	if _, err := svc.Find(ctx, find); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if find.Limit != 2 || fake.limits[0] != 2 {
+		t.Errorf("Expected find clamped to 2, got %d (retriever %v)", find.Limit, fake.limits)
+	}
+
+	// An offset cannot page past the cap
+	fake.limits = nil
+	req = &SearchRequest{Query: "guide", Limit: 2, Offset: 1000000}
+	results, err = svc.Search(ctx, req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if req.Limit != 0 || len(results) != 0 || len(fake.limits) != 0 {
+		t.Errorf("Expected nothing retrieved past the cap, got limit %d, %d results (retriever %v)", req.Limit, len(results), fake.limits)
+	}
+	req = &SearchRequest{Query: "guide", Limit: 2, Offset: 1}
+	if _, err := svc.Search(ctx, req); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if req.Limit != 1 {
+		t.Errorf("Expected the limit cut to 1 at offset 1, got %d", req.Limit)
+	}
+	for _, limit := range fake.limits {
+		if limit != 2 {
+			t.Errorf("Expected the retriever asked for 2 results, got %d", limit)
+		}
+	}
+
+	// Without a cap the request limit stands
+	svc.SetMaxResultsCap(0)
+	req = &SearchRequest{Query: "guide", Limit: 1000}
+	if _, err := svc.Search(ctx, req); err != nil || req.Limit != 1000 {
+		t.Errorf("Expected the uncapped limit 1000, got %d (%v)", req.Limit, err)
+	}
+}
+
+func TestSearchServiceConfigLimits(t *testing.T) {
+	svc := NewSearchServiceFromConfig(config.RetrievalConfig{MaxResults: 20, MaxResultsCap: 5}, nil, nil)
+	svc.SetRetriever(newFakeRetriever())
+	if svc.MaxResultsCap() != 5 {
+		t.Errorf("Expected the configured cap 5, got %d", svc.MaxResultsCap())
+	}
+
+	// The configured default limit is clamped like a requested one
+	req := &SearchRequest{Query: "guide"}
+	if _, err := svc.Search(context.Background(), req); err != nil || req.Limit != 5 {
+		t.Errorf("Expected the default of 20 clamped to 5, got %d (%v)", req.Limit, err)
+	}
+
+	svc.SetMaxResultsCap(50)
+	req = &SearchRequest{Query: "guide"}
+	if _, err := svc.Search(context.Background(), req); err != nil || req.Limit != 20 {
+		t.Errorf("Expected the configured default 20, got %d (%v)", req.Limit, err)
+	}
+}
+
+func TestSearchServiceKeywordWeight(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+
+	results, err := svc.Search(ctx, &SearchRequest{Query: "python", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected semantic order without weights, got %v", resultURIs(results))
+	}
+
+	svc.SetFusionWeights(retrieval.FusionWeights{Keyword: 0.5})
+	results, err = svc.Search(ctx, &SearchRequest{Query: "python", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	want := []string{"viking://resources/python-guide", "viking://resources/go-guide", "viking://user/memories/editor"}
+	if !reflect.DeepEqual(resultURIs(results), want) {
+		t.Errorf("Expected %v, got %v", want, resultURIs(results))
+	}
+
+	// A per-request override turns keyword blending back off
+	results, err = svc.Search(ctx, &SearchRequest{Query: "python", Limit: 10, Weights: &retrieval.FusionWeights{}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected override to restore semantic order, got %v", resultURIs(results))
+	}
+}
+
+// fixedContent returns canned full content by URI.
+type fixedContent map[string]string
+
+func (c fixedContent) Content(ctx context.Context, uri string) (string, error) {
+	return c[uri], nil
+}
+
+func TestSearchServiceIndexSource(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+	svc.SetContentSource(fixedContent{"viking://resources/python-guide": "building rust extensions for python"})
+	req := &SearchRequest{Query: "rust", Limit: 10, Weights: &retrieval.FusionWeights{Keyword: 0.5}}
+
+	// Only the content mentions rust, so abstracts leave the order alone
+	results, err := svc.Search(ctx, req)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results[0].URI != "viking://resources/go-guide" {
+		t.Errorf("Expected semantic order when scoring abstracts, got %v", resultURIs(results))
+	}
+
+	for _, source := range []retrieval.IndexSource{retrieval.IndexContent, retrieval.IndexBoth} {
+		svc.SetIndexSource(source, retrieval.DefaultAbstractBoost)
+		results, err := svc.Search(ctx, req)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if results[0].URI != "viking://resources/python-guide" {
+			t.Errorf("Expected content match first with index source %s, got %v", source, resultURIs(results))
+		}
+		if results[0].Content != "python packaging guide" {
+			t.Errorf("Expected results to keep their abstract, got %q", results[0].Content)
+		}
+	}
+}
+
+func TestSearchServiceHotnessWeight(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSearchService()
+	svc.SetRetriever(newFakeRetriever())
+	svc.SetHotnessSource(fixedHotness{"viking://user/memories/editor": 1})
+
+	results, err := svc.Search(ctx, &SearchRequest{Query: "guide", Limit: 10, Weights: &retrieval.FusionWeights{Hotness: 0.5}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results[0].URI != "viking://user/memories/editor" {
+		t.Errorf("Expected hot memory to rank first, got %v", resultURIs(results))
+	}
+
+	_, err = svc.Search(ctx, &SearchRequest{Query: "guide", Weights: &retrieval.FusionWeights{Hotness: 1.5}})
+	if err == nil {
+		t.Error("Expected error for out of range weight")
+	}
+}
@@ -7,9 +7,13 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/jqnote/goviking/pkg/retrieval"
+	"github.com/jqnote/goviking/pkg/storage"
 )
 
 var (
@@ -21,14 +25,29 @@ var (
 	ErrNotFound = errors.New("not found")
 )
 
+// defaultMaxTags is the number of tags generated for untagged contexts.
+const defaultMaxTags = 5
+
 // ContextService provides context business logic.
 type ContextService struct {
 	// Storage would be injected here
+
+	keywordExtractor retrieval.KeywordExtractor
+	maxTags          int
 }
 
 // NewContextService creates a new context service.
 func NewContextService() *ContextService {
-	return &ContextService{}
+	return &ContextService{
+		keywordExtractor: retrieval.NewTFIDFExtractor(nil),
+		maxTags:          defaultMaxTags,
+	}
+}
+
+// SetKeywordExtractor sets the extractor used to tag untagged contexts.
+// Passing nil disables automatic tagging.
+func (s *ContextService) SetKeywordExtractor(ke retrieval.KeywordExtractor) {
+	s.keywordExtractor = ke
 }
 
 // CreateContextRequest represents a create context request.
@@ -37,6 +56,7 @@ type CreateContextRequest struct {
 	Type     string
 	Name     string
 	Content  string
+	Tags     []string
 	Metadata map[string]any
 }
 
@@ -48,6 +68,9 @@ func (r *CreateContextRequest) Validate() error {
 	if r.Type == "" {
 		return errors.New("type is required")
 	}
+	if _, err := retrieval.ParseContextType(r.Type); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -58,6 +81,7 @@ type Context struct {
 	Type      string         `json:"type"`
 	Name      string         `json:"name"`
 	Content   string         `json:"content"`
+	Tags      []string       `json:"tags,omitempty"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
@@ -68,14 +92,25 @@ func (s *ContextService) Create(ctx context.Context, req *CreateContextRequest)
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
+	contextType, _ := retrieval.ParseContextType(req.Type)
+
+	tags := req.Tags
+	if len(tags) == 0 && req.Content != "" && s.keywordExtractor != nil {
+		extracted, err := s.keywordExtractor.Extract(ctx, req.Content, s.maxTags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract tags: %w", err)
+		}
+		tags = extracted
+	}
 
 	now := time.Now().UTC()
 	return &Context{
 		ID:        uuid.New().String(),
 		URI:       req.URI,
-		Type:      req.Type,
+		Type:      string(contextType),
 		Name:      req.Name,
 		Content:   req.Content,
+		Tags:      tags,
 		Metadata:  req.Metadata,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -84,7 +119,7 @@ func (s *ContextService) Create(ctx context.Context, req *CreateContextRequest)
 
 // SessionService provides session business logic.
 type SessionService struct {
-	// Storage would be injected here
+	store storage.StorageInterface
 }
 
 // NewSessionService creates a new session service.
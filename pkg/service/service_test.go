@@ -55,6 +55,16 @@ func TestContextServiceValidate(t *testing.T) {
 			req:     &CreateContextRequest{URI: "test"},
 			wantErr: true,
 		},
+		{
+			name:    "mixed case Type",
+			req:     &CreateContextRequest{URI: "test", Type: "Memory"},
+			wantErr: false,
+		},
+		{
+			name:    "unknown Type",
+			req:     &CreateContextRequest{URI: "test", Type: "document"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -136,3 +146,77 @@ func TestSessionServiceResume(t *testing.T) {
 		t.Errorf("Expected ID 'session123', got '%s'", result.ID)
 	}
 }
+
+func TestContextServiceCreateExtractsTags(t *testing.T) {
+	svc := NewContextService()
+	req := &CreateContextRequest{
+		URI:     "viking://resources/golang",
+		Type:    "resource",
+		Content: "Goroutines and channels make concurrency in Golang simple. Goroutines are cheap.",
+	}
+
+	result, err := svc.Create(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(result.Tags) == 0 {
+		t.Fatal("Expected tags to be extracted")
+	}
+	if result.Tags[0] != "goroutines" {
+		t.Errorf("Expected first tag 'goroutines', got %v", result.Tags)
+	}
+}
+
+func TestContextServiceCreateKeepsExplicitTags(t *testing.T) {
+	svc := NewContextService()
+	req := &CreateContextRequest{
+		URI:     "viking://resources/golang",
+		Type:    "resource",
+		Content: "Goroutines and channels make concurrency in Golang simple.",
+		Tags:    []string{"go", "tutorial"},
+	}
+
+	result, err := svc.Create(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(result.Tags) != 2 || result.Tags[0] != "go" || result.Tags[1] != "tutorial" {
+		t.Errorf("Expected explicit tags to be kept, got %v", result.Tags)
+	}
+}
+
+type stubExtractor struct {
+	keywords []string
+}
+
+func (e *stubExtractor) Extract(ctx context.Context, content string, maxTags int) ([]string, error) {
+	return e.keywords, nil
+}
+
+func TestContextServiceCustomKeywordExtractor(t *testing.T) {
+	svc := NewContextService()
+	svc.SetKeywordExtractor(&stubExtractor{keywords: []string{"llm-tag"}})
+
+	result, err := svc.Create(context.Background(), &CreateContextRequest{
+		URI:     "viking://memory/test",
+		Type:    "memory",
+		Content: "anything",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(result.Tags) != 1 || result.Tags[0] != "llm-tag" {
+		t.Errorf("Expected tags from custom extractor, got %v", result.Tags)
+	}
+}
+
+func TestContextServiceCreateNormalizesType(t *testing.T) {
+	svc := NewContextService()
+	ctx, err := svc.Create(context.Background(), &CreateContextRequest{URI: "test", Type: " Skill "})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if ctx.Type != "skill" {
+		t.Errorf("Expected type skill, got %q", ctx.Type)
+	}
+}
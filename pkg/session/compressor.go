@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"unicode/utf8"
 )
 
 // SessionCompressor handles session compression with extraction and deduplication.
@@ -69,6 +70,7 @@ type SessionCompressionResult struct {
 	TokensSaved       int64                  // Estimated tokens saved
 	Summary           string                  // Summary if summarization was used
 	ExtractedMemories []*ExtractedMemory     // Extracted memories
+	Degraded          bool                   // An LLM step failed and was skipped or replaced by a fallback
 }
 
 // Compress compresses session messages.
@@ -91,9 +93,15 @@ func (c *SessionCompressor) Compress(ctx context.Context, messages []*Message) (
 
 	// Option 1: Extract important memories
 	if c.config.AutoExtract && c.extractor != nil {
+		// Extraction has no non-LLM fallback, so a failure skips it rather
+		// than failing the compression
 		memories, err := c.extractor.Extract(ctx, olderMsgs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract memories: %w", err)
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to extract memories: %w", err)
+			}
+			result.Degraded = true
+			memories = nil
 		}
 
 		result.MemoriesExtracted = len(memories)
@@ -117,7 +125,11 @@ func (c *SessionCompressor) Compress(ctx context.Context, messages []*Message) (
 		if int64(estimatedTokens) > int64(c.config.MaxTokens) {
 			summary, tokensSaved, err := c.summarizer.Compress(ctx, olderMsgs, c.config.MaxTokens)
 			if err != nil {
-				return nil, fmt.Errorf("failed to summarize: %w", err)
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("failed to summarize: %w", err)
+				}
+				result.Degraded = true
+				summary, tokensSaved = truncateMessages(olderMsgs, c.config.MaxTokens)
 			}
 			result.Summary = summary
 			result.TokensSaved = tokensSaved
@@ -162,6 +174,25 @@ func estimateTokens(messages []*Message) int {
 	return total
 }
 
+// truncateMessages compresses messages without an LLM by keeping only the
+// most recent text that fits in maxTokens. It returns the kept text and the
+// estimated tokens saved.
+func truncateMessages(messages []*Message, maxTokens int) (string, int64) {
+	text := formatMessagesForSummary(messages)
+	// Rough estimate: 1 token ≈ 4 characters
+	maxChars := maxTokens * 4
+	if len(text) <= maxChars {
+		return text, 0
+	}
+
+	start := len(text) - maxChars
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	truncated := "..." + text[start:]
+	return truncated, int64((len(text) - len(truncated)) / 4)
+}
+
 // FilterMemoriesByImportance filters memories by minimum importance.
 func (c *SessionCompressor) FilterMemoriesByImportance(memories []*ExtractedMemory) []*ExtractedMemory {
 	var filtered []*ExtractedMemory
@@ -0,0 +1,122 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// downProvider fails every call, like an unreachable LLM endpoint.
+type downProvider struct {
+	calls int
+}
+
+func (p *downProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	p.calls++
+	return nil, errors.New("connection refused")
+}
+
+func (p *downProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (llm.StreamReader, error) {
+	p.calls++
+	return nil, errors.New("connection refused")
+}
+
+func (p *downProvider) Embed(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	p.calls++
+	return nil, errors.New("connection refused")
+}
+
+func (p *downProvider) Close() error { return nil }
+
+func longConversation(n int) []*Message {
+	messages := make([]*Message, n)
+	for i := range messages {
+		messages[i] = &Message{
+			Role:    RoleUser,
+			Content: strings.Repeat("we discussed the deployment plan in detail ", 10),
+		}
+	}
+	return messages
+}
+
+func TestSessionCompressorDegradesWhenLLMDown(t *testing.T) {
+	provider := &downProvider{}
+	breaker := llm.NewCircuitBreaker(provider, llm.BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+
+	compressor := NewSessionCompressor(
+		NewLLMExtractor(breaker, DefaultExtractorConfig("sess")),
+		NewMemoryDeduper(breaker, 0),
+		NewLLMSummarizer(breaker, SummarizerConfig{}),
+		CompressionConfig{KeepRecent: 2, MaxTokens: 100, AutoExtract: true, AutoDedup: true},
+	)
+
+	for i := 0; i < 3; i++ {
+		result, err := compressor.Compress(context.Background(), longConversation(20))
+		if err != nil {
+			t.Fatalf("Compress %d: expected degraded result, got error %v", i, err)
+		}
+		if !result.Degraded {
+			t.Errorf("Compress %d: expected result to be marked degraded", i)
+		}
+		if result.MemoriesExtracted != 0 {
+			t.Errorf("Compress %d: expected extraction to be skipped, got %d memories", i, result.MemoriesExtracted)
+		}
+		if result.Summary == "" || len(result.Summary) > 100*4+3 {
+			t.Errorf("Compress %d: expected a truncated summary within budget, got %d chars", i, len(result.Summary))
+		}
+		if result.TokensSaved <= 0 {
+			t.Errorf("Compress %d: expected tokens saved, got %d", i, result.TokensSaved)
+		}
+	}
+
+	if breaker.State() != llm.BreakerOpen {
+		t.Errorf("Expected the breaker to open, got %s", breaker.State())
+	}
+	if provider.calls != 2 {
+		t.Errorf("Expected the open breaker to stop provider calls after 2, got %d", provider.calls)
+	}
+}
+
+func TestSessionCompressorPropagatesCancellation(t *testing.T) {
+	compressor := NewSessionCompressor(
+		NewLLMExtractor(&downProvider{}, DefaultExtractorConfig("sess")),
+		nil, nil,
+		CompressionConfig{KeepRecent: 2, AutoExtract: true},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := compressor.Compress(ctx, longConversation(5)); err == nil {
+		t.Error("Expected cancellation to fail compression")
+	}
+}
+
+func TestMemoryDeduperFallsBackWhenLLMDown(t *testing.T) {
+	breaker := llm.NewCircuitBreaker(&downProvider{}, llm.BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	deduper := NewMemoryDeduper(breaker, 0.5)
+
+	memories := []*ExtractedMemory{
+		{Content: "User prefers dark mode in the editor", Importance: 0.6},
+		{Content: "User prefers dark mode in editor", Importance: 0.9},
+		{Content: "User works on payments", Importance: 0.7},
+	}
+	for i := 0; i < 2; i++ {
+		deduped, err := deduper.Dedup(context.Background(), memories)
+		if err != nil {
+			t.Fatalf("Dedup %d failed: %v", i, err)
+		}
+		if len(deduped) != 2 {
+			t.Fatalf("Dedup %d: expected word-overlap fallback to leave 2 memories, got %d", i, len(deduped))
+		}
+		if deduped[0].Importance != 0.9 {
+			t.Errorf("Dedup %d: expected the more important duplicate to be kept, got %v", i, deduped[0].Importance)
+		}
+	}
+}
@@ -10,13 +10,19 @@ import (
 	"strings"
 
 	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // MemoryDeduper handles memory deduplication with LLM-based decision making.
 type MemoryDeduper struct {
 	client           llm.Provider
 	threshold       float64
+	useLLM          bool
+	maxGroupSize    int
 	mergePromptTmpl string
+	useEmbeddings   bool
+	embeddingModel  string
+	model           string
 }
 
 // DedupDecision represents the decision for handling duplicate memories.
@@ -31,13 +37,26 @@ const (
 
 // NewMemoryDeduper creates a new memory deduper.
 func NewMemoryDeduper(client llm.Provider, threshold float64) *MemoryDeduper {
-	if threshold == 0 {
-		threshold = 0.8 // 80% similarity threshold
+	config := DefaultDedupConfig()
+	config.Threshold = threshold
+	return NewMemoryDeduperWithConfig(client, config)
+}
+
+// NewMemoryDeduperWithConfig creates a new memory deduper from config.
+// Groups larger than config.MaxGroupSize are sent to the LLM in batches.
+func NewMemoryDeduperWithConfig(client llm.Provider, config DedupConfig) *MemoryDeduper {
+	if config.Threshold == 0 {
+		config.Threshold = 0.8 // 80% similarity threshold
 	}
 	return &MemoryDeduper{
 		client:           client,
-		threshold:       threshold,
+		threshold:       config.Threshold,
+		useLLM:          config.UseLLM,
+		maxGroupSize:    config.MaxGroupSize,
 		mergePromptTmpl: defaultMergePrompt,
+		useEmbeddings:   config.UseEmbeddings,
+		embeddingModel:  config.EmbeddingModel,
+		model:           config.Model,
 	}
 }
 
@@ -47,52 +66,85 @@ func (d *MemoryDeduper) Dedup(ctx context.Context, memories []*ExtractedMemory)
 		return memories, nil
 	}
 
-	// First pass: simple similarity-based deduplication
-	groups := d.groupSimilar(memories)
+	// First pass: similarity-based grouping, by embedding when the
+	// provider can embed and by word overlap otherwise
+	groups := d.groupSimilar(memories, d.embedContents(ctx, memories))
 
 	// Second pass: LLM-based decision for each group
 	var result []*ExtractedMemory
 	for _, group := range groups {
-		if len(group) <= 1 {
-			result = append(result, group[0])
-			continue
-		}
+		result = append(result, d.dedupGroup(ctx, group)...)
+	}
+
+	return result, nil
+}
 
-		// Use LLM to decide how to handle the group
-		decisions, err := d.decideMergeOrDelete(ctx, group)
-		if err != nil {
-			// Fall back to simple merge
-			merged := d.simpleMerge(group)
-			result = append(result, merged)
-			continue
+// dedupGroup dedups a group of similar memories. A group larger than
+// maxGroupSize is split into batches of at most that size; the survivors
+// of every batch are then dedup'd together in the same way, until they fit
+// in one batch or a pass removes nothing.
+func (d *MemoryDeduper) dedupGroup(ctx context.Context, group []*ExtractedMemory) []*ExtractedMemory {
+	if d.maxGroupSize <= 0 || len(group) <= d.maxGroupSize {
+		return d.resolveGroup(ctx, group)
+	}
+
+	var survivors []*ExtractedMemory
+	for start := 0; start < len(group); start += d.maxGroupSize {
+		end := start + d.maxGroupSize
+		if end > len(group) {
+			end = len(group)
 		}
+		survivors = append(survivors, d.resolveGroup(ctx, group[start:end])...)
+	}
+	if len(survivors) == len(group) {
+		return survivors
+	}
+	return d.dedupGroup(ctx, survivors)
+}
 
-		for i, decision := range decisions {
-			switch decision {
-			case DedupDecisionMerge, DedupDecisionKeepBoth:
-				if i == 0 || decision == DedupDecisionMerge {
-					result = append(result, group[i])
-				}
-			case DedupDecisionCreate:
+// resolveGroup asks the LLM how to handle a group of similar memories,
+// keeping the most important one when it can't.
+func (d *MemoryDeduper) resolveGroup(ctx context.Context, group []*ExtractedMemory) []*ExtractedMemory {
+	if len(group) <= 1 {
+		return group
+	}
+	if !d.useLLM {
+		return []*ExtractedMemory{d.simpleMerge(group)}
+	}
+
+	// Use LLM to decide how to handle the group
+	decisions, err := d.decideMergeOrDelete(ctx, group)
+	if err != nil {
+		// Fall back to simple merge
+		return []*ExtractedMemory{d.simpleMerge(group)}
+	}
+
+	var result []*ExtractedMemory
+	for i, decision := range decisions {
+		switch decision {
+		case DedupDecisionMerge, DedupDecisionKeepBoth:
+			if i == 0 || decision == DedupDecisionMerge {
 				result = append(result, group[i])
-			case DedupDecisionDelete:
-				// Skip this memory
 			}
+		case DedupDecisionCreate:
+			result = append(result, group[i])
+		case DedupDecisionDelete:
+			// Skip this memory
 		}
 	}
-
-	return result, nil
+	return result
 }
 
-// groupSimilar groups similar memories together.
-func (d *MemoryDeduper) groupSimilar(memories []*ExtractedMemory) [][]*ExtractedMemory {
+// groupSimilar groups similar memories together. vectors maps contents to
+// their embeddings; when nil, word overlap is used instead.
+func (d *MemoryDeduper) groupSimilar(memories []*ExtractedMemory, vectors map[string][]float64) [][]*ExtractedMemory {
 	var groups [][]*ExtractedMemory
 
 	for _, m := range memories {
 		added := false
 		for i, group := range groups {
 			// Check if this memory is similar to any in the group
-			if d.calculateSimilarity(m.Content, group[0].Content) >= d.threshold {
+			if d.similarity(m.Content, group[0].Content, vectors) >= d.threshold {
 				groups[i] = append(group, m)
 				added = true
 				break
@@ -106,7 +158,63 @@ func (d *MemoryDeduper) groupSimilar(memories []*ExtractedMemory) [][]*Extracted
 	return groups
 }
 
-// calculateSimilarity calculates cosine similarity between two strings.
+// similarity compares two contents by the cosine of their embeddings, or
+// by word overlap when vectors is nil.
+func (d *MemoryDeduper) similarity(a, b string, vectors map[string][]float64) float64 {
+	if vectors == nil {
+		return d.calculateSimilarity(a, b)
+	}
+	return utils.CosineSimilarity(vectors[a], vectors[b])
+}
+
+// embedContents embeds the distinct contents of memories in one request,
+// so each is embedded once per Dedup call. It returns nil when embeddings
+// are disabled or the provider cannot supply a non-zero one for every
+// content.
+func (d *MemoryDeduper) embedContents(ctx context.Context, memories []*ExtractedMemory) map[string][]float64 {
+	if !d.useEmbeddings || d.client == nil {
+		return nil
+	}
+
+	var contents []string
+	seen := make(map[string]bool)
+	for _, m := range memories {
+		if !seen[m.Content] {
+			seen[m.Content] = true
+			contents = append(contents, m.Content)
+		}
+	}
+
+	resp, err := d.client.Embed(ctx, &llm.EmbeddingRequest{Model: d.embeddingModel, Input: contents})
+	if err != nil || resp == nil || len(resp.Data) != len(contents) {
+		return nil
+	}
+	vectors := make(map[string][]float64, len(contents))
+	for _, e := range resp.Data {
+		// Zero vectors, as from a no-op embedder, cannot be compared
+		if e.Index < 0 || e.Index >= len(contents) || isZeroVector(e.Embedding) {
+			return nil
+		}
+		vectors[contents[e.Index]] = e.Embedding
+	}
+	if len(vectors) != len(contents) {
+		return nil
+	}
+	return vectors
+}
+
+// isZeroVector reports whether v is empty or all zeros.
+func isZeroVector(v []float64) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// calculateSimilarity calculates the Jaccard similarity of the words in
+// two strings.
 func (d *MemoryDeduper) calculateSimilarity(a, b string) float64 {
 	// Simple word-based similarity
 	wordsA := strings.Fields(strings.ToLower(a))
@@ -163,7 +271,7 @@ func (d *MemoryDeduper) decideMergeOrDelete(ctx context.Context, memories []*Ext
 	prompt := fmt.Sprintf(d.mergePromptTmpl, memList.String())
 
 	resp, err := d.client.Chat(ctx, &llm.ChatRequest{
-		Model:       "",
+		Model:       d.model,
 		Temperature: 0.3,
 		Messages: []llm.Message{
 			{Role: llm.RoleSystem, Content: "You are a memory deduplication assistant. Analyze the memories and decide how to handle duplicates."},
@@ -245,11 +353,32 @@ func (d *MemoryDeduper) MergeMemories(a, b *ExtractedMemory) (*ExtractedMemory,
 	// Calculate combined importance (slightly reduced to avoid over-weighting)
 	combinedImportance := math.Min(1.0, (a.Importance+b.Importance)*0.9)
 
+	// Keep the provenance of both memories
+	sources := append([]string{}, base.SourceMessageIDs...)
+	other := a
+	if base == a {
+		other = b
+	}
+	for _, id := range other.SourceMessageIDs {
+		found := false
+		for _, s := range sources {
+			if s == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sources = append(sources, id)
+		}
+	}
+
 	return &ExtractedMemory{
 		Content:    base.Content,
 		Importance: combinedImportance,
 		Category:   base.Category,
 		SessionID:  base.SessionID,
+		SourceMessageIDs: sources,
+		Evidence:   base.Evidence,
 		CreatedAt:  base.CreatedAt,
 	}, nil
 }
@@ -275,6 +404,12 @@ type DedupConfig struct {
 	Threshold      float64 // Similarity threshold (0-1)
 	UseLLM        bool     // Use LLM for merge decisions
 	MaxGroupSize  int      // Maximum memories to process in one group
+	// UseEmbeddings groups memories by the cosine similarity of their
+	// embeddings, falling back to word overlap when the provider cannot
+	// embed them.
+	UseEmbeddings  bool
+	EmbeddingModel string // Embedding model, the provider's default when empty
+	Model          string // LLM model for merge decisions, the provider's default when empty
 }
 
 // DefaultDedupConfig returns default deduplication configuration.
@@ -283,5 +418,6 @@ func DefaultDedupConfig() DedupConfig {
 		Threshold:     0.8,
 		UseLLM:       true,
 		MaxGroupSize: 10,
+		UseEmbeddings: true,
 	}
 }
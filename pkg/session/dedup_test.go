@@ -0,0 +1,210 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// mergeProvider tells the deduper to merge every group and records how
+// many memories each prompt listed.
+type mergeProvider struct {
+	llm.Provider
+	groupSizes []int
+	models     []string
+}
+
+func (p *mergeProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	p.models = append(p.models, req.Model)
+	prompt := req.Messages[len(req.Messages)-1].Content
+	p.groupSizes = append(p.groupSizes, strings.Count(prompt, "(importance:"))
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Content: "merge"}}}}, nil
+}
+
+// Embed fails like a provider without embeddings, so grouping falls back
+// to word overlap.
+func (p *mergeProvider) Embed(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	return nil, errors.New("embeddings not supported")
+}
+
+// vectorProvider embeds each input as its vector in vectors, recording the
+// inputs of every request.
+type vectorProvider struct {
+	llm.Provider
+	vectors map[string][]float64
+	inputs  [][]string
+}
+
+func (p *vectorProvider) Embed(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	inputs := req.Input.([]string)
+	p.inputs = append(p.inputs, inputs)
+	resp := &llm.EmbeddingResponse{}
+	for i, input := range inputs {
+		resp.Data = append(resp.Data, llm.Embedding{Embedding: p.vectors[input], Index: i})
+	}
+	return resp, nil
+}
+
+func nearIdenticalMemories(n int) []*ExtractedMemory {
+	memories := make([]*ExtractedMemory, n)
+	for i := range memories {
+		memories[i] = &ExtractedMemory{
+			Content:          "User prefers dark mode in every editor",
+			Importance:       0.5 + float64(i)/1000,
+			Category:         "preference",
+			SourceMessageIDs: []string{fmt.Sprintf("msg-%d", i)},
+		}
+	}
+	return memories
+}
+
+func TestMemoryDeduperMaxGroupSize(t *testing.T) {
+	provider := &mergeProvider{}
+	config := DefaultDedupConfig()
+	config.MaxGroupSize = 10
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	result, err := deduper.Dedup(context.Background(), nearIdenticalMemories(50))
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected the group merged into 1 memory, got %d", len(result))
+	}
+
+	// Five batches of 10, then one batch of their 5 survivors
+	want := []int{10, 10, 10, 10, 10, 5}
+	if fmt.Sprint(provider.groupSizes) != fmt.Sprint(want) {
+		t.Errorf("Expected LLM calls with %v memories, got %v", want, provider.groupSizes)
+	}
+}
+
+func TestMemoryDeduperUnboundedGroup(t *testing.T) {
+	provider := &mergeProvider{}
+	config := DefaultDedupConfig()
+	config.MaxGroupSize = 0
+	config.Model = "dedup-model"
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	result, err := deduper.Dedup(context.Background(), nearIdenticalMemories(50))
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	if len(result) != 1 || len(provider.groupSizes) != 1 || provider.groupSizes[0] != 50 {
+		t.Errorf("Expected one call with all 50 memories, got %v (%d results)", provider.groupSizes, len(result))
+	}
+	if len(provider.models) != 1 || provider.models[0] != "dedup-model" {
+		t.Errorf("Expected the configured model asked, got %v", provider.models)
+	}
+}
+
+func TestMemoryDeduperWithoutLLM(t *testing.T) {
+	provider := &mergeProvider{}
+	config := DefaultDedupConfig()
+	config.UseLLM = false
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	result, err := deduper.Dedup(context.Background(), nearIdenticalMemories(25))
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	if len(provider.groupSizes) != 0 {
+		t.Errorf("Expected no LLM calls, got %d", len(provider.groupSizes))
+	}
+	// The most important memory survives
+	if len(result) != 1 || result[0].SourceMessageIDs[0] != "msg-24" {
+		t.Errorf("Expected the most important memory, got %+v", result)
+	}
+}
+
+func TestMemoryDeduperGroupsByEmbedding(t *testing.T) {
+	provider := &vectorProvider{vectors: map[string][]float64{
+		"User likes Python":                      {0.9, 0.1, 0},
+		"The user is fond of Python programming": {0.85, 0.15, 0.05},
+		"User works on payments":                 {0, 0.2, 0.95},
+	}}
+	config := DefaultDedupConfig()
+	config.UseLLM = false
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	memories := []*ExtractedMemory{
+		{Content: "User likes Python", Importance: 0.6},
+		{Content: "The user is fond of Python programming", Importance: 0.8},
+		{Content: "User works on payments", Importance: 0.7},
+		{Content: "User likes Python", Importance: 0.5},
+	}
+	// Word overlap alone keeps the paraphrases apart
+	if sim := deduper.calculateSimilarity(memories[0].Content, memories[1].Content); sim >= config.Threshold {
+		t.Fatalf("Expected low word overlap for the paraphrase, got %.2f", sim)
+	}
+
+	result, err := deduper.Dedup(context.Background(), memories)
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected the Python memories merged, leaving 2, got %d", len(result))
+	}
+	if result[0].Content != "The user is fond of Python programming" || result[1].Content != "User works on payments" {
+		t.Errorf("Expected the most important Python memory and the payments memory, got %q and %q", result[0].Content, result[1].Content)
+	}
+	// Each distinct content is embedded once, in a single request
+	if len(provider.inputs) != 1 || len(provider.inputs[0]) != 3 {
+		t.Errorf("Expected one request embedding 3 distinct contents, got %v", provider.inputs)
+	}
+}
+
+func TestMemoryDeduperZeroVectorsFallBack(t *testing.T) {
+	// A no-op embedder returns zero vectors, which carry no similarity
+	provider := &vectorProvider{vectors: map[string][]float64{
+		"User prefers dark mode":            {0, 0, 0},
+		"User prefers dark mode in editors": {0, 0, 0},
+		"User works on payments":            {0, 0, 0},
+	}}
+	config := DefaultDedupConfig()
+	config.UseLLM = false
+	config.Threshold = 0.6
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	result, err := deduper.Dedup(context.Background(), []*ExtractedMemory{
+		{Content: "User prefers dark mode", Importance: 0.5},
+		{Content: "User prefers dark mode in editors", Importance: 0.7},
+		{Content: "User works on payments", Importance: 0.6},
+	})
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	// Word overlap still merges the dark mode memories
+	if len(provider.inputs) != 1 || len(result) != 2 {
+		t.Errorf("Expected word overlap to leave 2 memories, got %d", len(result))
+	}
+}
+
+func TestMemoryDeduperEmbeddingsDisabled(t *testing.T) {
+	provider := &vectorProvider{vectors: map[string][]float64{
+		"User likes Python":                      {1, 0},
+		"The user is fond of Python programming": {1, 0},
+	}}
+	config := DefaultDedupConfig()
+	config.UseLLM = false
+	config.UseEmbeddings = false
+	deduper := NewMemoryDeduperWithConfig(provider, config)
+
+	result, err := deduper.Dedup(context.Background(), []*ExtractedMemory{
+		{Content: "User likes Python"},
+		{Content: "The user is fond of Python programming"},
+	})
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+	if len(result) != 2 || len(provider.inputs) != 0 {
+		t.Errorf("Expected word overlap to keep both without embedding, got %d memories and %d requests", len(result), len(provider.inputs))
+	}
+}
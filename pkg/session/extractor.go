@@ -24,6 +24,10 @@ type ExtractedMemory struct {
 	Importance float64   `json:"importance"`
 	Category   string    `json:"category"`
 	SessionID  string    `json:"session_id"`
+	// SourceMessageIDs identifies the messages the memory was extracted from
+	SourceMessageIDs []string `json:"source_message_ids,omitempty"`
+	// Evidence quotes the conversation supporting the memory
+	Evidence   string    `json:"evidence,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
@@ -33,6 +37,10 @@ type ExtractorConfig struct {
 	MaxMemories    int       // Maximum memories to extract per batch
 	SessionID      string    // Session ID for extracted memories
 	UseNewCategories bool    // Use new 6-category system (profile, preference, entity, event, case, pattern)
+	Model          string    // LLM model to request; empty uses the provider default
+	// CategoryWeights scales the importance of memories extracted by
+	// category. Categories missing from the map use their default weight.
+	CategoryWeights map[Category]float64
 }
 
 // DefaultExtractorConfig returns default extractor configuration.
@@ -41,6 +49,7 @@ func DefaultExtractorConfig(sessionID string) ExtractorConfig {
 		MinImportance: 0.5,
 		MaxMemories:   10,
 		SessionID:     sessionID,
+		CategoryWeights: DefaultCategoryWeights(),
 	}
 }
 
@@ -52,10 +61,32 @@ type Summarizer interface {
 	Compress(ctx context.Context, messages []*Message, maxTokens int) (string, int64, error)
 }
 
+// SummaryStyle selects the form of a summary.
+type SummaryStyle string
+
+const (
+	// SummaryStyleProse is a short narrative of the conversation.
+	SummaryStyleProse SummaryStyle = "prose"
+	// SummaryStyleBullets lists key points and decisions, one per line.
+	SummaryStyleBullets SummaryStyle = "bullets"
+	// SummaryStyleActionItems lists only the follow-up tasks agreed on.
+	SummaryStyleActionItems SummaryStyle = "action_items"
+)
+
 // SummarizerConfig holds configuration for summarization.
 type SummarizerConfig struct {
 	MaxTokens      int   // Maximum tokens in summary
 	KeepRecentMsgs int   // Number of recent messages to keep unchanged
+	Model          string // LLM model to request; empty uses the provider default
+	// Style selects the form of the summary; empty means prose.
+	Style SummaryStyle
+	// TargetSentences asks for about this many sentences, or list items
+	// for the bullet and action item styles. Zero leaves the length to the
+	// style's default.
+	TargetSentences int
+	// TargetTokens asks for a summary of about this many tokens and takes
+	// precedence over TargetSentences. It is capped at MaxTokens.
+	TargetTokens int
 }
 
 // DefaultSummarizerConfig returns default summarizer configuration.
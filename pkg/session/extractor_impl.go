@@ -7,10 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // Memory categories (new 6-category system)
@@ -50,6 +52,12 @@ func NewLLMExtractor(client llm.Provider, config ExtractorConfig) *LLMExtractor
 	if config.MaxMemories == 0 {
 		config.MaxMemories = 10
 	}
+	// Copy the weights so later changes to the caller's map do not leak in
+	weights := DefaultCategoryWeights()
+	for cat, weight := range config.CategoryWeights {
+		weights[cat] = weight
+	}
+	config.CategoryWeights = weights
 
 	return &LLMExtractor{
 		client:  client,
@@ -58,6 +66,14 @@ func NewLLMExtractor(client llm.Provider, config ExtractorConfig) *LLMExtractor
 	}
 }
 
+// WithModel returns a copy of the extractor that requests model, leaving
+// e unchanged. An empty model uses the provider default.
+func (e *LLMExtractor) WithModel(model string) *LLMExtractor {
+	c := *e
+	c.config.Model = model
+	return &c
+}
+
 // Extract extracts memories from session messages using LLM.
 func (e *LLMExtractor) Extract(ctx context.Context, messages []*Message) ([]*ExtractedMemory, error) {
 	if len(messages) == 0 {
@@ -68,11 +84,11 @@ func (e *LLMExtractor) Extract(ctx context.Context, messages []*Message) ([]*Ext
 	content := e.formatMessages(messages)
 
 	// Build the prompt
-	prompt := fmt.Sprintf(e.promptTemplate, content)
+	prompt := fmt.Sprintf(e.promptTemplate, content) + citationInstructions
 
 	// Call LLM
 	resp, err := e.client.Chat(ctx, &llm.ChatRequest{
-		Model:       "",
+		Model:       e.config.Model,
 		Temperature: 0.3,
 		Messages: []llm.Message{
 			{Role: llm.RoleSystem, Content: "You are a memory extraction assistant. Extract important information from the conversation and return a JSON array."},
@@ -105,6 +121,7 @@ func (e *LLMExtractor) Extract(ctx context.Context, messages []*Message) ([]*Ext
 	var filtered []*ExtractedMemory
 	for _, m := range memories {
 		if m.Importance >= e.config.MinImportance {
+			m.SourceMessageIDs = resolveSources(m.SourceMessageIDs, messages)
 			m.SessionID = e.config.SessionID
 			m.CreatedAt = time.Now().UTC()
 			filtered = append(filtered, m)
@@ -117,12 +134,13 @@ func (e *LLMExtractor) Extract(ctx context.Context, messages []*Message) ([]*Ext
 	return filtered, nil
 }
 
-// formatMessages formats messages for the prompt.
+// formatMessages formats messages for the prompt, each prefixed with the
+// label the LLM cites it by.
 func (e *LLMExtractor) formatMessages(messages []*Message) string {
 	var sb strings.Builder
-	for _, msg := range messages {
+	for i, msg := range messages {
 		roleStr := string(msg.Role)
-		sb.WriteString(fmt.Sprintf("%s: %s\n", roleStr, msg.Content))
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", messageLabel(msg, i), roleStr, msg.Content))
 		if len(msg.ToolCalls) > 0 {
 			for _, tc := range msg.ToolCalls {
 				sb.WriteString(fmt.Sprintf("  Tool call: %s(%s)\n", tc.Function.Name, tc.Function.Arguments))
@@ -208,6 +226,42 @@ func (e *LLMExtractor) extractJSONFromMarkdown(response string) ([]*ExtractedMem
 	return memories, nil
 }
 
+// messageLabel is how a message is cited: its ID, or its 1-based turn
+// number when it has none.
+func messageLabel(msg *Message, i int) string {
+	if msg.ID != "" {
+		return msg.ID
+	}
+	return strconv.Itoa(i + 1)
+}
+
+// resolveSources keeps the cited labels that name one of messages,
+// dropping duplicates and citations the LLM made up.
+func resolveSources(cited []string, messages []*Message) []string {
+	labels := make(map[string]bool, len(messages))
+	for i, msg := range messages {
+		labels[messageLabel(msg, i)] = true
+	}
+
+	var sources []string
+	seen := make(map[string]bool)
+	for _, label := range cited {
+		label = strings.Trim(strings.TrimSpace(label), "[]")
+		if labels[label] && !seen[label] {
+			seen[label] = true
+			sources = append(sources, label)
+		}
+	}
+	return sources
+}
+
+// citationInstructions asks the LLM to cite the messages behind each memory.
+const citationInstructions = `
+
+Each message above starts with its ID in brackets. For every memory, also include:
+- source_message_ids: the IDs of the messages that support it
+- evidence: a short quote from those messages`
+
 const defaultMemoryExtractionPrompt = `Extract important information from the following conversation that should be remembered for future interactions.
 
 For each piece of information, extract:
@@ -226,22 +280,41 @@ Return a JSON array of memories. Example:
 
 Only return the JSON array, no other text.`
 
-// CategoryWeights defines importance weights for each category.
-var CategoryWeights = map[Category]float64{
-	CategoryProfile:   0.9,  // User profile is highly important
-	CategoryPreference: 0.8,  // Preferences are important
-	CategoryEntity:    0.7,  // Entities are moderately important
-	CategoryEvent:    0.6,  // Events are less important
-	CategoryCase:     0.7,  // Cases are moderately important
-	CategoryPattern:  0.5,  // Patterns are less critical
+// DefaultCategoryWeights returns the default importance weight of each
+// category.
+func DefaultCategoryWeights() map[Category]float64 {
+	return map[Category]float64{
+		CategoryProfile:   0.9,  // User profile is highly important
+		CategoryPreference: 0.8,  // Preferences are important
+		CategoryEntity:    0.7,  // Entities are moderately important
+		CategoryEvent:    0.6,  // Events are less important
+		CategoryCase:     0.7,  // Cases are moderately important
+		CategoryPattern:  0.5,  // Patterns are less critical
+	}
 }
 
-// GetCategoryImportance returns the base importance weight for a category.
+// defaultCategoryWeight is the weight of categories without one.
+const defaultCategoryWeight = 0.5
+
+// GetCategoryImportance returns the default base importance weight for a
+// category.
+//
+// Deprecated: Use LLMExtractor.GetCategoryImportance, which honours
+// ExtractorConfig.CategoryWeights.
 func GetCategoryImportance(cat Category) float64 {
-	if weight, ok := CategoryWeights[cat]; ok {
+	if weight, ok := DefaultCategoryWeights()[cat]; ok {
+		return weight
+	}
+	return defaultCategoryWeight
+}
+
+// GetCategoryImportance returns the base importance weight the extractor
+// applies to a category.
+func (e *LLMExtractor) GetCategoryImportance(cat Category) float64 {
+	if weight, ok := e.config.CategoryWeights[cat]; ok {
 		return weight
 	}
-	return 0.5 // Default weight
+	return GetCategoryImportance(cat)
 }
 
 // CategoryPrompts contains prompts for each memory category.
@@ -328,11 +401,11 @@ func (e *LLMExtractor) ExtractByCategory(ctx context.Context, messages []*Messag
 
 	// Format messages for the prompt
 	content := e.formatMessages(messages)
-	prompt := fmt.Sprintf(promptTemplate, content)
+	prompt := fmt.Sprintf(promptTemplate, content) + citationInstructions
 
 	// Call LLM
 	resp, err := e.client.Chat(ctx, &llm.ChatRequest{
-		Model:       "",
+		Model:       e.config.Model,
 		Temperature: 0.3,
 		Messages: []llm.Message{
 			{Role: llm.RoleSystem, Content: "You are a memory extraction assistant. Extract important information and return a JSON array."},
@@ -358,12 +431,13 @@ func (e *LLMExtractor) ExtractByCategory(ctx context.Context, messages []*Messag
 	}
 
 	// Apply category-specific importance weighting
-	baseWeight := GetCategoryImportance(category)
+	baseWeight := e.GetCategoryImportance(category)
 	var filtered []*ExtractedMemory
 	for _, m := range memories {
 		m.Category = string(category)
 		m.Importance = m.Importance * baseWeight // Apply category weight
 		if m.Importance >= e.config.MinImportance {
+			m.SourceMessageIDs = resolveSources(m.SourceMessageIDs, messages)
 			m.SessionID = e.config.SessionID
 			m.CreatedAt = time.Now().UTC()
 			filtered = append(filtered, m)
@@ -398,6 +472,8 @@ func (e *LLMExtractor) ExtractAllCategories(ctx context.Context, messages []*Mes
 type LLMSummarizer struct {
 	client llm.Provider
 	config SummarizerConfig
+	// stream records how the latest SummarizeStream ended
+	stream *streamStatus
 }
 
 // NewLLMSummarizer creates a new LLM-based summarizer.
@@ -411,41 +487,85 @@ func NewLLMSummarizer(client llm.Provider, config SummarizerConfig) *LLMSummariz
 	return &LLMSummarizer{
 		client: client,
 		config: config,
+		stream: &streamStatus{},
 	}
 }
 
+// WithModel returns a copy of the summarizer that requests model, leaving
+// s unchanged. An empty model uses the provider default.
+func (s *LLMSummarizer) WithModel(model string) *LLMSummarizer {
+	c := *s
+	c.config.Model = model
+	c.stream = &streamStatus{}
+	return &c
+}
+
 // Summarize creates a summary of messages.
 func (s *LLMSummarizer) Summarize(ctx context.Context, messages []*Message) (string, error) {
 	if len(messages) == 0 {
 		return "", nil
 	}
 
-	content := formatMessagesForSummary(messages)
+	resp, err := s.client.Chat(ctx, s.summaryRequest(messages))
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize: %w", err)
+	}
 
+	if len(resp.Choices) == 0 {
+		return "", nil
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// summaryRequest builds the chat request asking for a summary of messages.
+func (s *LLMSummarizer) summaryRequest(messages []*Message) *llm.ChatRequest {
 	prompt := fmt.Sprintf(`Summarize the following conversation concisely, capturing the key points and any important information:
 
 %s
 
-Provide a brief summary (2-3 sentences):`, content)
+%s:`, formatMessagesForSummary(messages), s.summaryInstruction())
 
-	resp, err := s.client.Chat(ctx, &llm.ChatRequest{
-		Model:       "",
+	return &llm.ChatRequest{
+		Model:       s.config.Model,
 		Temperature: 0.3,
 		Messages: []llm.Message{
 			{Role: llm.RoleSystem, Content: "You are a conversation summarization assistant."},
 			{Role: llm.RoleUser, Content: prompt},
 		},
 		MaxTokens: s.config.MaxTokens,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to summarize: %w", err)
 	}
+}
 
-	if len(resp.Choices) == 0 {
-		return "", nil
+// summaryInstruction describes the configured style and length of the
+// summary to the LLM.
+func (s *LLMSummarizer) summaryInstruction() string {
+	var instruction, unit string
+	switch s.config.Style {
+	case SummaryStyleBullets:
+		instruction = `Provide a summary as a bulleted list of the key points and decisions, one per line starting with "- "`
+		unit = "bullet points"
+	case SummaryStyleActionItems:
+		instruction = `List the action items agreed on, one per line starting with "- ", naming the owner and deadline when mentioned`
+		unit = "action items"
+	default:
+		instruction = "Provide a brief summary"
+		unit = "sentences"
+	}
+
+	switch {
+	case s.config.TargetTokens > 0:
+		tokens := s.config.TargetTokens
+		if tokens > s.config.MaxTokens {
+			tokens = s.config.MaxTokens
+		}
+		return fmt.Sprintf("%s (about %d tokens)", instruction, tokens)
+	case s.config.TargetSentences > 0:
+		return fmt.Sprintf("%s (%d %s)", instruction, s.config.TargetSentences, unit)
+	case unit == "sentences":
+		return instruction + " (2-3 sentences)"
 	}
-
-	return resp.Choices[0].Message.Content, nil
+	return instruction
 }
 
 // Compress compresses messages into a summary while keeping recent messages.
@@ -472,10 +592,15 @@ func (s *LLMSummarizer) Compress(ctx context.Context, messages []*Message, maxTo
 		return formatMessagesForSummary(olderMsgs), 0, nil
 	}
 
-	// Summarize older messages
+	// Summarize older messages, falling back to truncation when the LLM
+	// is unavailable
 	summary, err := s.Summarize(ctx, olderMsgs)
 	if err != nil {
-		return "", 0, err
+		if ctx.Err() != nil {
+			return "", 0, err
+		}
+		summary, tokensSaved := truncateMessages(olderMsgs, maxTokens)
+		return summary, tokensSaved, nil
 	}
 
 	// Calculate tokens saved
@@ -525,6 +650,7 @@ type AutoExtractor struct {
 	messages   []*Message
 	lastExtracted time.Time
 	interval   time.Duration
+	clock      utils.Clock
 }
 
 // SummarizerExtractor combines summarization and extraction.
@@ -539,6 +665,7 @@ func NewAutoExtractor(client llm.Provider, config Config) *AutoExtractor {
 		extractor: NewLLMExtractor(client, config.Extractor),
 		config:    config,
 		interval:  5 * time.Minute, // Extract every 5 minutes by default
+		clock:     utils.RealClock{},
 	}
 
 	// Create combined summarizer/extractor if possible
@@ -557,14 +684,14 @@ func (ae *AutoExtractor) AddMessage(ctx context.Context, msg *Message) ([]*Extra
 
 	// Check if we should extract memories
 	shouldExtract := len(ae.messages) >= ae.config.MaxMessages ||
-		time.Since(ae.lastExtracted) >= ae.interval
+		ae.clock.Now().Sub(ae.lastExtracted) >= ae.interval
 
 	if shouldExtract && ae.extractor != nil {
 		memories, err := ae.Extract(ctx)
 		if err != nil {
 			return nil, err
 		}
-		ae.lastExtracted = time.Now()
+		ae.lastExtracted = ae.clock.Now()
 		return memories, nil
 	}
 
@@ -608,6 +735,11 @@ func (ae *AutoExtractor) Clear() {
 	ae.messages = nil
 }
 
+// SetClock sets the clock used to schedule interval-based extraction.
+func (ae *AutoExtractor) SetClock(clock utils.Clock) {
+	ae.clock = clock
+}
+
 // SetInterval sets the extraction interval.
 func (ae *AutoExtractor) SetInterval(interval time.Duration) {
 	ae.interval = interval
@@ -653,6 +785,25 @@ func (d *Deduper) Dedup(memories []*ExtractedMemory) []*ExtractedMemory {
 	return result
 }
 
+// DedupAgainst removes duplicate memories and those similar to an existing
+// memory, leaving only the net-new ones.
+func (d *Deduper) DedupAgainst(existing []string, memories []*ExtractedMemory) []*ExtractedMemory {
+	var result []*ExtractedMemory
+	for _, m := range d.Dedup(memories) {
+		isDuplicate := false
+		for _, content := range existing {
+			if d.isSimilar(m.Content, content) {
+				isDuplicate = true
+				break
+			}
+		}
+		if !isDuplicate {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
 // isSimilar checks if two strings are similar (simple implementation).
 func (d *Deduper) isSimilar(a, b string) bool {
 	// Simple implementation: check if one is substring of another
@@ -5,15 +5,20 @@ package session
 
 import (
 	"context"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jqnote/goviking/pkg/llm"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 // MockLLMProvider is a mock LLM provider for testing.
 type MockLLMProvider struct {
 	responses map[string]*llm.ChatResponse
+	models    []string
+	requests  []*llm.ChatRequest
 }
 
 func NewMockLLMProvider() *MockLLMProvider {
@@ -23,6 +28,9 @@ func NewMockLLMProvider() *MockLLMProvider {
 }
 
 func (m *MockLLMProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	m.models = append(m.models, req.Model)
+	m.requests = append(m.requests, req)
+
 	// Return mock response based on request content
 	content := ""
 	for _, msg := range req.Messages {
@@ -179,6 +187,42 @@ func TestGetCategoryImportance(t *testing.T) {
 	}
 }
 
+func TestExtractByCategoryUsesInstanceWeights(t *testing.T) {
+	messages := []*Message{{Role: "user", Content: "I prefer tea", CreatedAt: time.Now()}}
+	newExtractor := func(weight float64) *LLMExtractor {
+		config := DefaultExtractorConfig("test-session")
+		config.MinImportance = 0.1
+		config.CategoryWeights = map[Category]float64{CategoryPreference: weight}
+		return NewLLMExtractor(NewMockLLMProvider(), config)
+	}
+	full, half := newExtractor(1.0), newExtractor(0.5)
+
+	ctx := context.Background()
+	for _, tt := range []struct {
+		extractor *LLMExtractor
+		expected  float64
+	}{
+		{full, 0.8},
+		{half, 0.4},
+	} {
+		memories, err := tt.extractor.ExtractByCategory(ctx, messages, CategoryPreference)
+		if err != nil {
+			t.Fatalf("ExtractByCategory failed: %v", err)
+		}
+		if len(memories) != 1 || math.Abs(memories[0].Importance-tt.expected) > 1e-9 {
+			t.Errorf("Expected one memory with importance %v, got %+v", tt.expected, memories)
+		}
+	}
+
+	// Categories left out of the config keep their default weight
+	if w := half.GetCategoryImportance(CategoryProfile); w != 0.9 {
+		t.Errorf("Expected default profile weight 0.9, got %v", w)
+	}
+	if w := half.GetCategoryImportance("unknown"); w != 0.5 {
+		t.Errorf("Expected default weight 0.5 for an unknown category, got %v", w)
+	}
+}
+
 func TestCategoryPromptsExist(t *testing.T) {
 	expectedCategories := []Category{
 		CategoryProfile, CategoryPreference, CategoryEntity,
@@ -206,6 +250,168 @@ func TestExtractorConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestLLMExtractorModel(t *testing.T) {
+	mock := NewMockLLMProvider()
+	config := DefaultExtractorConfig("test-session")
+	config.Model = "small-model"
+	extractor := NewLLMExtractor(mock, config)
+	messages := []*Message{{Role: "user", Content: "I prefer tea", CreatedAt: time.Now()}}
+
+	ctx := context.Background()
+	if _, err := extractor.Extract(ctx, messages); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if _, err := extractor.ExtractByCategory(ctx, messages, CategoryPreference); err != nil {
+		t.Fatalf("ExtractByCategory failed: %v", err)
+	}
+	if _, err := extractor.WithModel("large-model").Extract(ctx, messages); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	// The override leaves the original extractor's model alone
+	if _, err := extractor.Extract(ctx, messages); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	want := []string{"small-model", "small-model", "large-model", "small-model"}
+	if len(mock.models) != len(want) {
+		t.Fatalf("Expected %d requests, got %v", len(want), mock.models)
+	}
+	for i, model := range want {
+		if mock.models[i] != model {
+			t.Errorf("Expected request %d to use %q, got %q", i, model, mock.models[i])
+		}
+	}
+}
+
+// citingProvider replies to every chat with a fixed memory list and keeps
+// the last prompt.
+type citingProvider struct {
+	llm.Provider
+	reply  string
+	prompt string
+}
+
+func (p *citingProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	p.prompt = req.Messages[len(req.Messages)-1].Content
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Content: p.reply}}}}, nil
+}
+
+func TestLLMExtractorCitesSources(t *testing.T) {
+	provider := &citingProvider{reply: `[
+		{"content": "User drinks tea", "importance": 0.8, "category": "preference", "source_message_ids": ["msg-1", "[msg-3]", "msg-1"], "evidence": "I only drink tea"},
+		{"content": "User lives in Oslo", "importance": 0.7, "category": "fact", "source_message_ids": ["msg-2", "msg-9"], "evidence": "moved to Oslo"}
+	]`}
+	extractor := NewLLMExtractor(provider, DefaultExtractorConfig("test-session"))
+	messages := []*Message{
+		{ID: "msg-1", Role: RoleUser, Content: "I only drink tea"},
+		{ID: "msg-2", Role: RoleUser, Content: "I moved to Oslo last year"},
+		{ID: "msg-3", Role: RoleAssistant, Content: "Noted, tea it is"},
+	}
+
+	memories, err := extractor.Extract(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if !strings.Contains(provider.prompt, "[msg-2] user: I moved to Oslo") {
+		t.Errorf("Expected messages labelled by ID in the prompt, got %q", provider.prompt)
+	}
+	if len(memories) != 2 {
+		t.Fatalf("Expected 2 memories, got %d", len(memories))
+	}
+
+	// Duplicate and unknown citations are dropped
+	tests := []struct {
+		sources  []string
+		evidence string
+	}{
+		{[]string{"msg-1", "msg-3"}, "I only drink tea"},
+		{[]string{"msg-2"}, "moved to Oslo"},
+	}
+	for i, tt := range tests {
+		m := memories[i]
+		if strings.Join(m.SourceMessageIDs, ",") != strings.Join(tt.sources, ",") {
+			t.Errorf("Expected memory %d sources %v, got %v", i, tt.sources, m.SourceMessageIDs)
+		}
+		if m.Evidence != tt.evidence {
+			t.Errorf("Expected memory %d evidence %q, got %q", i, tt.evidence, m.Evidence)
+		}
+	}
+
+	// Messages without IDs are cited by turn number
+	provider.reply = `[{"content": "User drinks tea", "importance": 0.8, "source_message_ids": ["1"]}]`
+	memories, err = extractor.ExtractByCategory(context.Background(), []*Message{{Role: RoleUser, Content: "I only drink tea"}}, CategoryPreference)
+	if err != nil {
+		t.Fatalf("ExtractByCategory failed: %v", err)
+	}
+	if len(memories) != 1 || len(memories[0].SourceMessageIDs) != 1 || memories[0].SourceMessageIDs[0] != "1" {
+		t.Errorf("Expected a citation of turn 1, got %+v", memories)
+	}
+}
+
+func TestLLMSummarizerModel(t *testing.T) {
+	mock := NewMockLLMProvider()
+	config := DefaultSummarizerConfig()
+	config.Model = "summary-model"
+	summarizer := NewLLMSummarizer(mock, config)
+	messages := []*Message{{Role: "user", Content: "Let's plan the release", CreatedAt: time.Now()}}
+
+	ctx := context.Background()
+	if _, err := summarizer.Summarize(ctx, messages); err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if _, err := summarizer.WithModel("").Summarize(ctx, messages); err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+
+	if len(mock.models) != 2 || mock.models[0] != "summary-model" || mock.models[1] != "" {
+		t.Errorf("Expected the configured model then the provider default, got %q", mock.models)
+	}
+}
+
+func TestLLMSummarizerStyle(t *testing.T) {
+	messages := []*Message{{Role: "user", Content: "Alice will ship the release on Friday", CreatedAt: time.Now()}}
+	tests := []struct {
+		name   string
+		config SummarizerConfig
+		want   []string
+	}{
+		{"default", SummarizerConfig{}, []string{"brief summary (2-3 sentences)"}},
+		{"prose sentences", SummarizerConfig{Style: SummaryStyleProse, TargetSentences: 5}, []string{"brief summary (5 sentences)"}},
+		{"bullets", SummarizerConfig{Style: SummaryStyleBullets, TargetSentences: 4}, []string{"bulleted list", "(4 bullet points)"}},
+		{"action items", SummarizerConfig{Style: SummaryStyleActionItems}, []string{"action items agreed on", "owner and deadline"}},
+		{"tokens", SummarizerConfig{Style: SummaryStyleBullets, TargetTokens: 200, TargetSentences: 4}, []string{"bulleted list", "(about 200 tokens)"}},
+		{"tokens capped", SummarizerConfig{MaxTokens: 150, TargetTokens: 500}, []string{"(about 150 tokens)"}},
+	}
+
+	for _, tt := range tests {
+		mock := NewMockLLMProvider()
+		if _, err := NewLLMSummarizer(mock, tt.config).Summarize(context.Background(), messages); err != nil {
+			t.Fatalf("%s: Summarize failed: %v", tt.name, err)
+		}
+		if len(mock.requests) != 1 {
+			t.Fatalf("%s: Expected 1 request, got %d", tt.name, len(mock.requests))
+		}
+		req := mock.requests[0]
+		prompt := req.Messages[len(req.Messages)-1].Content
+		for _, want := range tt.want {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("%s: Expected the prompt to contain %q, got %q", tt.name, want, prompt)
+			}
+		}
+		if tt.config.Style == SummaryStyleBullets && strings.Contains(prompt, "brief summary") {
+			t.Errorf("%s: Expected no prose instruction for bullets, got %q", tt.name, prompt)
+		}
+
+		maxTokens := tt.config.MaxTokens
+		if maxTokens == 0 {
+			maxTokens = DefaultSummarizerConfig().MaxTokens
+		}
+		if req.MaxTokens != maxTokens {
+			t.Errorf("%s: Expected MaxTokens %d to cap the output, got %d", tt.name, maxTokens, req.MaxTokens)
+		}
+	}
+}
+
 func TestDeduper(t *testing.T) {
 	d := NewDeduper(0.8)
 
@@ -314,3 +520,41 @@ func TestAutoExtractorClear(t *testing.T) {
 		t.Errorf("Expected 0 messages after clear, got %d", len(ae.GetMessages()))
 	}
 }
+
+func TestAutoExtractorIntervalWithClock(t *testing.T) {
+	mock := NewMockLLMProvider()
+	config := Config{
+		MaxMessages: 100,
+		Extractor:   DefaultExtractorConfig("test"),
+		Summarizer:  DefaultSummarizerConfig(),
+	}
+
+	ae := NewAutoExtractor(mock, config)
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	ae.SetClock(clock)
+	ae.SetInterval(5 * time.Minute)
+
+	ctx := context.Background()
+	add := func(content string) []*ExtractedMemory {
+		memories, err := ae.AddMessage(ctx, &Message{Role: "user", Content: content, CreatedAt: clock.Now()})
+		if err != nil {
+			t.Fatalf("AddMessage failed: %v", err)
+		}
+		return memories
+	}
+
+	// Never extracted before, so the first message triggers extraction
+	if memories := add("first"); len(memories) == 0 {
+		t.Fatal("Expected extraction on first message")
+	}
+
+	clock.Advance(4 * time.Minute)
+	if memories := add("second"); memories != nil {
+		t.Errorf("Expected no extraction within the interval, got %d memories", len(memories))
+	}
+
+	clock.Advance(time.Minute)
+	if memories := add("third"); len(memories) == 0 {
+		t.Error("Expected extraction once the interval elapsed")
+	}
+}
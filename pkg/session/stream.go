@@ -0,0 +1,140 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// StreamingSummarizer streams summaries as the LLM produces them.
+// *LLMSummarizer implements this interface.
+type StreamingSummarizer interface {
+	// SummarizeStream sends the summary of messages in chunks, closing
+	// the channel when the summary is complete or the stream fails.
+	SummarizeStream(ctx context.Context, messages []*Message) (<-chan string, error)
+	// StreamErr returns the error that ended the latest stream early, or
+	// nil once it completed. It is set before the channel is closed.
+	StreamErr() error
+}
+
+// streamStatus holds the error that ended a stream.
+type streamStatus struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (st *streamStatus) set(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.err = err
+}
+
+func (st *streamStatus) get() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.err
+}
+
+// closedStream returns a channel with no chunks.
+func closedStream() <-chan string {
+	ch := make(chan string)
+	close(ch)
+	return ch
+}
+
+// SummarizeStream streams the summary of messages chunk by chunk. Failing
+// to open the stream is returned directly; a provider error or context
+// cancellation while streaming closes the channel, and StreamErr then
+// reports it.
+func (s *LLMSummarizer) SummarizeStream(ctx context.Context, messages []*Message) (<-chan string, error) {
+	s.stream.set(nil)
+	if len(messages) == 0 {
+		return closedStream(), nil
+	}
+
+	req := s.summaryRequest(messages)
+	req.Stream = true
+	stream, err := s.client.ChatStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize: %w", err)
+	}
+	if stream == nil {
+		return nil, fmt.Errorf("failed to summarize: provider returned no stream")
+	}
+
+	chunks := make(chan string)
+	go func() {
+		// The error is recorded before the channel closes
+		defer close(chunks)
+		defer stream.Close()
+
+		for {
+			if err := ctx.Err(); err != nil {
+				s.stream.set(err)
+				return
+			}
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				s.stream.set(fmt.Errorf("failed to summarize: %w", err))
+				return
+			}
+			// Keep-alives and events without text carry no choices
+			if resp == nil || len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+				continue
+			}
+
+			select {
+			case chunks <- resp.Choices[0].Delta.Content:
+			case <-ctx.Done():
+				s.stream.set(ctx.Err())
+				return
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// StreamErr returns the error that ended the latest SummarizeStream early,
+// or nil if it completed.
+func (s *LLMSummarizer) StreamErr() error {
+	return s.stream.get()
+}
+
+// SummarizeStream streams a summary of the accumulated messages. A
+// summarizer that cannot stream sends its whole summary as one chunk.
+func (ae *AutoExtractor) SummarizeStream(ctx context.Context) (<-chan string, error) {
+	if ae.summarizer == nil || len(ae.messages) == 0 {
+		return closedStream(), nil
+	}
+	if streamer, ok := ae.summarizer.(StreamingSummarizer); ok {
+		return streamer.SummarizeStream(ctx, ae.messages)
+	}
+
+	summary, err := ae.summarizer.Summarize(ctx, ae.messages)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(chan string, 1)
+	if summary != "" {
+		chunks <- summary
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+// StreamErr returns the error that ended the latest SummarizeStream early,
+// or nil if it completed.
+func (ae *AutoExtractor) StreamErr() error {
+	if streamer, ok := ae.summarizer.(StreamingSummarizer); ok {
+		return streamer.StreamErr()
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/llm"
+)
+
+// chunkStream emits each chunk as a delta, then err, or io.EOF when err is
+// nil. An empty chunk is sent as a response without choices.
+type chunkStream struct {
+	chunks []string
+	err    error
+	closed bool
+}
+
+func (s *chunkStream) Recv() (*llm.StreamResponse, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	if chunk == "" {
+		return &llm.StreamResponse{}, nil
+	}
+	return &llm.StreamResponse{Choices: []llm.StreamChoice{{Delta: llm.Message{Content: chunk}}}}, nil
+}
+
+func (s *chunkStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+// streamProvider answers every streaming chat with stream.
+type streamProvider struct {
+	llm.Provider
+	stream *chunkStream
+	reqs   []*llm.ChatRequest
+}
+
+func (p *streamProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (llm.StreamReader, error) {
+	p.reqs = append(p.reqs, req)
+	return p.stream, nil
+}
+
+// collect reads every chunk from a stream.
+func collect(chunks <-chan string) []string {
+	var got []string
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	return got
+}
+
+func TestLLMSummarizerSummarizeStream(t *testing.T) {
+	provider := &streamProvider{stream: &chunkStream{chunks: []string{"The user ", "", "prefers ", "dark mode."}}}
+	summarizer := NewLLMSummarizer(provider, DefaultSummarizerConfig())
+
+	chunks, err := summarizer.SummarizeStream(context.Background(), longConversation(3))
+	if err != nil {
+		t.Fatalf("SummarizeStream failed: %v", err)
+	}
+	got := collect(chunks)
+	if strings.Join(got, "") != "The user prefers dark mode." || len(got) != 3 {
+		t.Errorf("Expected 3 chunks of the summary, got %q", got)
+	}
+	if err := summarizer.StreamErr(); err != nil {
+		t.Errorf("Expected no stream error, got %v", err)
+	}
+	if !provider.stream.closed {
+		t.Error("Expected the stream to be closed")
+	}
+	if len(provider.reqs) != 1 || !provider.reqs[0].Stream {
+		t.Errorf("Expected one streaming request, got %+v", provider.reqs)
+	}
+}
+
+func TestLLMSummarizerSummarizeStreamProviderError(t *testing.T) {
+	provider := &streamProvider{stream: &chunkStream{chunks: []string{"The user "}, err: errors.New("connection reset")}}
+	summarizer := NewLLMSummarizer(provider, DefaultSummarizerConfig())
+
+	chunks, err := summarizer.SummarizeStream(context.Background(), longConversation(3))
+	if err != nil {
+		t.Fatalf("SummarizeStream failed: %v", err)
+	}
+	if got := collect(chunks); len(got) != 1 {
+		t.Errorf("Expected the chunk before the error, got %q", got)
+	}
+	if err := summarizer.StreamErr(); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the provider error, got %v", err)
+	}
+
+	// Failing to open the stream is returned directly
+	if _, err := NewLLMSummarizer(&downProvider{}, DefaultSummarizerConfig()).SummarizeStream(context.Background(), longConversation(3)); err == nil {
+		t.Error("Expected an error when the stream cannot be opened")
+	}
+}
+
+func TestLLMSummarizerSummarizeStreamCancel(t *testing.T) {
+	provider := &streamProvider{stream: &chunkStream{chunks: []string{"one ", "two ", "three"}}}
+	summarizer := NewLLMSummarizer(provider, DefaultSummarizerConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	chunks, err := summarizer.SummarizeStream(ctx, longConversation(3))
+	if err != nil {
+		t.Fatalf("SummarizeStream failed: %v", err)
+	}
+	if first := <-chunks; first != "one " {
+		t.Errorf("Expected the first chunk, got %q", first)
+	}
+	cancel()
+	collect(chunks)
+
+	if err := summarizer.StreamErr(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if !provider.stream.closed {
+		t.Error("Expected the stream to be closed after cancellation")
+	}
+}
+
+func TestAutoExtractorSummarizeStream(t *testing.T) {
+	provider := &streamProvider{stream: &chunkStream{chunks: []string{"Short ", "summary."}}}
+	ae := NewAutoExtractor(provider, Config{Summarizer: DefaultSummarizerConfig()})
+
+	chunks, err := ae.SummarizeStream(context.Background())
+	if err != nil {
+		t.Fatalf("SummarizeStream failed: %v", err)
+	}
+	if got := collect(chunks); len(got) != 0 {
+		t.Errorf("Expected no chunks without messages, got %q", got)
+	}
+
+	for _, msg := range longConversation(2) {
+		ae.messages = append(ae.messages, msg)
+	}
+	chunks, err = ae.SummarizeStream(context.Background())
+	if err != nil {
+		t.Fatalf("SummarizeStream failed: %v", err)
+	}
+	if got := strings.Join(collect(chunks), ""); got != "Short summary." {
+		t.Errorf("Expected the streamed summary, got %q", got)
+	}
+	if err := ae.StreamErr(); err != nil {
+		t.Errorf("Expected no stream error, got %v", err)
+	}
+}
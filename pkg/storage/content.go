@@ -0,0 +1,167 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DefaultContentInlineLimit is the largest content, in bytes, kept inline in
+// the contexts table when a content store is configured.
+const DefaultContentInlineLimit = 4096
+
+// ContentStore holds context content outside the metadata database. The
+// contexts row keeps only the reference returned by Put and a checksum.
+type ContentStore interface {
+	// Put stores a version of the content of context id and returns its
+	// reference. Every call returns a new reference and leaves the content
+	// stored under earlier ones in place.
+	Put(ctx context.Context, id string, data []byte) (string, error)
+
+	// Get returns the content stored under ref.
+	Get(ctx context.Context, ref string) ([]byte, error)
+
+	// Delete removes the content stored under ref. Deleting missing content
+	// is not an error.
+	Delete(ctx context.Context, ref string) error
+}
+
+// FileContentStore is a ContentStore that keeps each version of a
+// context's content in <dir>/<id>/content-<version>.md.
+type FileContentStore struct {
+	dir string
+}
+
+// NewFileContentStore creates a new FileContentStore rooted at dir.
+func NewFileContentStore(dir string) *FileContentStore {
+	return &FileContentStore{dir: dir}
+}
+
+// Put implements ContentStore.
+func (f *FileContentStore) Put(ctx context.Context, id string, data []byte) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid context id for content store: %q", id)
+	}
+
+	ref := id + "/content-" + uuid.New().String() + ".md"
+	path := filepath.Join(f.dir, filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	// Write through a temp file so readers never see partial content
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return ref, nil
+}
+
+// Get implements ContentStore.
+func (f *FileContentStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	path, err := f.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete implements ContentStore.
+func (f *FileContentStore) Delete(ctx context.Context, ref string) error {
+	path, err := f.resolve(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// Drop the per-context directory if it is now empty
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+// resolve maps ref to a path inside the store directory.
+func (f *FileContentStore) resolve(ref string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(ref))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid content reference: %q", ref)
+	}
+	return filepath.Join(f.dir, clean), nil
+}
+
+// inlineLimit returns the largest content cfg keeps in the contexts row.
+func inlineLimit(cfg Config) int {
+	if cfg.ContentInlineLimit > 0 {
+		return cfg.ContentInlineLimit
+	}
+	return DefaultContentInlineLimit
+}
+
+// offloadContent moves c's content to store when it is larger than limit,
+// setting c.ContentRef and c.ContentChecksum. It returns the content to
+// write to the contexts row. A nil store keeps all content inline.
+func offloadContent(ctx context.Context, store ContentStore, limit int, c *Context) (string, error) {
+	if store == nil || len(c.Content) <= limit {
+		c.ContentRef = ""
+		c.ContentChecksum = ""
+		return c.Content, nil
+	}
+
+	data := []byte(c.Content)
+	ref, err := store.Put(ctx, c.ID, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to store content: %w", err)
+	}
+	c.ContentRef = ref
+	c.ContentChecksum = contentChecksum(data)
+	return "", nil
+}
+
+// discardContent removes the content offloadContent stored for c, once the
+// row that would have referenced it was not written.
+func discardContent(ctx context.Context, store ContentStore, c *Context) {
+	if store != nil && c.ContentRef != "" {
+		store.Delete(ctx, c.ContentRef)
+	}
+}
+
+// resolveContent fills c.Content from store when it was externalized,
+// verifying its checksum.
+func resolveContent(ctx context.Context, store ContentStore, c *Context) error {
+	if c.ContentRef == "" {
+		return nil
+	}
+	if store == nil {
+		return fmt.Errorf("context %s has external content but no content store is configured", c.ID)
+	}
+
+	data, err := store.Get(ctx, c.ContentRef)
+	if err != nil {
+		return fmt.Errorf("failed to load content of context %s: %w", c.ID, err)
+	}
+	if sum := contentChecksum(data); sum != c.ContentChecksum {
+		return fmt.Errorf("content checksum mismatch for context %s", c.ID)
+	}
+	c.Content = string(data)
+	return nil
+}
+
+// contentChecksum returns the hex SHA-256 of data.
+func contentChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newContentTestStorage(t *testing.T, contentDir string) *SQLiteStorage {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.ContentDir = contentDir
+	cfg.ContentInlineLimit = 64
+	s, err := NewSQLiteStorage(cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// rowContentLength returns the bytes of content stored in the contexts row.
+func rowContentLength(t *testing.T, s *SQLiteStorage, id string) int {
+	t.Helper()
+	var n int
+	err := s.db.QueryRow("SELECT length(CAST(content AS BLOB)) FROM contexts WHERE id = ?", id).Scan(&n)
+	if err != nil {
+		t.Fatalf("failed to read row: %v", err)
+	}
+	return n
+}
+
+func newContentTestContext(id, content string) *Context {
+	now := time.Now().UTC()
+	return &Context{
+		ID:        id,
+		URI:       "viking://test/" + id,
+		Type:      ContextTypeFile,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestSQLiteStorage_ExternalContent(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := newContentTestStorage(t, dir)
+
+	large := strings.Repeat("large content ", 1000)
+	if err := s.CreateContext(ctx, newContentTestContext("big", large)); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	if err := s.CreateContext(ctx, newContentTestContext("small", "tiny")); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+
+	if n := rowContentLength(t, s, "big"); n != 0 {
+		t.Errorf("Expected empty content in row, got %d bytes", n)
+	}
+
+	got, err := s.GetContext(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if got.Content != large {
+		t.Errorf("Expected full content, got %d bytes", len(got.Content))
+	}
+	if !strings.HasPrefix(got.ContentRef, "big/") {
+		t.Errorf("Expected content ref under big/, got %q", got.ContentRef)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(got.ContentRef))); err != nil {
+		t.Errorf("Expected content file: %v", err)
+	}
+
+	small, err := s.GetContext(ctx, "small")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if small.Content != "tiny" || small.ContentRef != "" {
+		t.Errorf("Expected inline content, got %q (ref %q)", small.Content, small.ContentRef)
+	}
+
+	// Listing leaves external content unresolved
+	list, err := s.QueryContexts(ctx, QueryOptions{OrderBy: "id"})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(list) != 2 || list[0].Content != "" || list[0].ContentRef == "" {
+		t.Errorf("Expected unresolved external content in list, got %+v", list)
+	}
+
+	// Shrinking content moves it back inline and removes the file
+	got.Content = "short now"
+	if err := s.UpdateContext(ctx, got); err != nil {
+		t.Fatalf("failed to update context: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big")); !os.IsNotExist(err) {
+		t.Errorf("Expected content file to be removed, got %v", err)
+	}
+	updated, _ := s.GetContext(ctx, "big")
+	if updated.Content != "short now" || updated.ContentRef != "" {
+		t.Errorf("Expected inline content after update, got %q (ref %q)", updated.Content, updated.ContentRef)
+	}
+}
+
+func TestSQLiteStorage_ExternalContentChecksum(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := newContentTestStorage(t, dir)
+
+	c := newContentTestContext("big", strings.Repeat("x", 100))
+	if err := s.CreateContext(ctx, c); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(c.ContentRef)), []byte("tampered"), 0644); err != nil {
+		t.Fatalf("failed to modify content: %v", err)
+	}
+	if _, err := s.GetContext(ctx, "big"); err == nil {
+		t.Error("Expected checksum mismatch error")
+	}
+
+	if err := s.DeleteContext(ctx, "big"); err != nil {
+		t.Fatalf("failed to delete context: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big")); !os.IsNotExist(err) {
+		t.Errorf("Expected content directory to be removed, got %v", err)
+	}
+}
+
+// contentFiles lists the content files stored for context id.
+func contentFiles(t *testing.T, dir, id string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, id))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to list content: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestSQLiteStorage_ExternalContentVersions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := newContentTestStorage(t, dir)
+
+	first := strings.Repeat("first ", 100)
+	if err := s.CreateContext(ctx, newContentTestContext("big", first)); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+
+	// A duplicate ID fails without touching the stored content
+	if err := s.CreateContext(ctx, newContentTestContext("big", strings.Repeat("duplicate ", 100))); err == nil {
+		t.Fatal("Expected an error creating a duplicate context")
+	}
+	got, err := s.GetContext(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if got.Content != first {
+		t.Errorf("Expected the original content after a duplicate create, got %d bytes", len(got.Content))
+	}
+	if files := contentFiles(t, dir, "big"); len(files) != 1 {
+		t.Errorf("Expected 1 content file, got %v", files)
+	}
+
+	// An update that fails leaves the row and its content as they were
+	if _, err := s.db.Exec("CREATE TRIGGER fail_update BEFORE UPDATE ON contexts BEGIN SELECT RAISE(ABORT, 'update failed'); END"); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	got.Content = strings.Repeat("second ", 100)
+	if err := s.UpdateContext(ctx, got); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+	got, err = s.GetContext(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get context after a failed update: %v", err)
+	}
+	if got.Content != first {
+		t.Errorf("Expected the original content after a failed update, got %d bytes", len(got.Content))
+	}
+	if files := contentFiles(t, dir, "big"); len(files) != 1 {
+		t.Errorf("Expected 1 content file, got %v", files)
+	}
+
+	// An update that succeeds replaces the old version
+	if _, err := s.db.Exec("DROP TRIGGER fail_update"); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+	oldRef := got.ContentRef
+	second := strings.Repeat("second ", 100)
+	got.Content = second
+	if err := s.UpdateContext(ctx, got); err != nil {
+		t.Fatalf("failed to update context: %v", err)
+	}
+	if got.ContentRef == oldRef {
+		t.Errorf("Expected a new content ref, got %q again", oldRef)
+	}
+	got, err = s.GetContext(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if got.Content != second {
+		t.Errorf("Expected the updated content, got %d bytes", len(got.Content))
+	}
+	if files := contentFiles(t, dir, "big"); len(files) != 1 {
+		t.Errorf("Expected 1 content file, got %v", files)
+	}
+
+	// Updating a missing context stores nothing
+	missing := newContentTestContext("missing", strings.Repeat("gone ", 100))
+	if err := s.UpdateContext(ctx, missing); err != nil {
+		t.Fatalf("failed to update missing context: %v", err)
+	}
+	if files := contentFiles(t, dir, "missing"); len(files) != 0 {
+		t.Errorf("Expected no content files, got %v", files)
+	}
+}
+
+func TestSQLiteStorage_ExternalizeContent(t *testing.T) {
+	ctx := context.Background()
+	s := newContentTestStorage(t, "")
+
+	large := strings.Repeat("y", 500)
+	if err := s.CreateContext(ctx, newContentTestContext("big", large)); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	if err := s.CreateContext(ctx, newContentTestContext("small", "tiny")); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	if n := rowContentLength(t, s, "big"); n != len(large) {
+		t.Fatalf("Expected inline content without a store, got %d bytes", n)
+	}
+
+	if _, err := s.ExternalizeContent(ctx); err == nil {
+		t.Error("Expected error without a content store")
+	}
+
+	s.SetContentStore(NewFileContentStore(t.TempDir()))
+	moved, err := s.ExternalizeContent(ctx)
+	if err != nil {
+		t.Fatalf("failed to externalize content: %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("Expected 1 context moved, got %d", moved)
+	}
+	if n := rowContentLength(t, s, "big"); n != 0 {
+		t.Errorf("Expected empty content in row, got %d bytes", n)
+	}
+	got, err := s.GetContext(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if got.Content != large {
+		t.Errorf("Expected full content after migration, got %d bytes", len(got.Content))
+	}
+
+	moved, err = s.ExternalizeContent(ctx)
+	if err != nil || moved != 0 {
+		t.Errorf("Expected second run to move nothing, got %d, %v", moved, err)
+	}
+}
+
+func TestSQLiteStorage_AddsContentColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	s, err := NewSQLiteStorage(Config{DBPath: path, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	// Simulate a database created before the content columns existed
+	for _, col := range []string{"content", "content_ref", "content_checksum"} {
+		if _, err := s.db.Exec("ALTER TABLE contexts DROP COLUMN " + col); err != nil {
+			t.Fatalf("failed to drop column: %v", err)
+		}
+	}
+	s.Close()
+
+	s, err = NewSQLiteStorage(Config{DBPath: path, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer s.Close()
+	if err := s.CreateContext(context.Background(), newContentTestContext("a", "hello")); err != nil {
+		t.Fatalf("failed to create context after migration: %v", err)
+	}
+}
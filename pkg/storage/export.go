@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RecordKind identifies the entity held by an export record.
+type RecordKind string
+
+const (
+	RecordKindContext  RecordKind = "context"
+	RecordKindMemory   RecordKind = "memory"
+	RecordKindRelation RecordKind = "relation"
+)
+
+// ExportRecord is a single NDJSON line of a store export.
+type ExportRecord struct {
+	Kind     RecordKind     `json:"kind"`
+	Context  *Context       `json:"context,omitempty"`
+	Memory   *Memory        `json:"memory,omitempty"`
+	Relation *RelationEntry `json:"relation,omitempty"`
+}
+
+// ExportOptions controls which entities are exported.
+type ExportOptions struct {
+	IncludeMemories  bool
+	IncludeRelations bool
+}
+
+// ExportStats reports how many records of each kind were processed.
+type ExportStats struct {
+	Contexts  int `json:"contexts"`
+	Memories  int `json:"memories"`
+	Relations int `json:"relations"`
+}
+
+// Export streams every context, and optionally memories and relations, to w
+// as NDJSON. Rows are written as they are read, so memory use is constant.
+func Export(ctx context.Context, store StorageInterface, w io.Writer, opts ExportOptions) (*ExportStats, error) {
+	enc := json.NewEncoder(w)
+	stats := &ExportStats{}
+
+	err := store.IterateContexts(ctx, func(c *Context) error {
+		stats.Contexts++
+		return enc.Encode(ExportRecord{Kind: RecordKindContext, Context: c})
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to export contexts: %w", err)
+	}
+
+	if opts.IncludeMemories {
+		err := store.IterateMemories(ctx, func(m *Memory) error {
+			stats.Memories++
+			return enc.Encode(ExportRecord{Kind: RecordKindMemory, Memory: m})
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to export memories: %w", err)
+		}
+	}
+
+	if opts.IncludeRelations {
+		err := store.IterateRelations(ctx, func(r *RelationEntry) error {
+			stats.Relations++
+			return enc.Encode(ExportRecord{Kind: RecordKindRelation, Relation: r})
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to export relations: %w", err)
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	storage, err := NewSQLiteStorage(Config{
+		DBPath:          filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns:    5,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func seedTestStorage(t *testing.T, storage *SQLiteStorage, contexts, memories, relations int) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for i := 0; i < contexts; i++ {
+		err := storage.CreateContext(ctx, &Context{
+			ID:        fmt.Sprintf("ctx-%d", i),
+			URI:       fmt.Sprintf("viking://resources/doc%d", i),
+			Type:      ContextTypeFile,
+			Name:      fmt.Sprintf("doc%d", i),
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+	}
+	for i := 0; i < memories; i++ {
+		err := storage.CreateMemory(ctx, &Memory{
+			ID:        fmt.Sprintf("mem-%d", i),
+			SessionID: "session-1",
+			Content:   fmt.Sprintf("memory %d", i),
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("failed to create memory: %v", err)
+		}
+	}
+	for i := 0; i < relations; i++ {
+		err := storage.CreateRelation(ctx, &RelationEntry{
+			ID:        fmt.Sprintf("rel-%d", i),
+			URIs:      fmt.Sprintf(`["viking://resources/doc%d","viking://resources/doc%d"]`, i, i+1),
+			Reason:    "related",
+			CreatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("failed to create relation: %v", err)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStorage(t)
+	seedTestStorage(t, source, 25, 10, 5)
+
+	var buf bytes.Buffer
+	exported, err := Export(ctx, source, &buf, ExportOptions{IncludeMemories: true, IncludeRelations: true})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exported.Contexts != 25 || exported.Memories != 10 || exported.Relations != 5 {
+		t.Errorf("unexpected export stats: %+v", exported)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		lines++
+	}
+	if lines != 40 {
+		t.Errorf("expected 40 NDJSON lines, got %d", lines)
+	}
+
+	target := newTestStorage(t)
+	result, err := ImportStore(ctx, target, &buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportStore failed: %v", err)
+	}
+	if result.Imported != *exported {
+		t.Errorf("import stats %+v do not match export stats %+v", result.Imported, *exported)
+	}
+
+	contexts, err := target.QueryContexts(ctx, QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryContexts failed: %v", err)
+	}
+	if len(contexts) != 25 {
+		t.Errorf("expected 25 contexts after import, got %d", len(contexts))
+	}
+	memories, err := target.QueryMemories(ctx, QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryMemories failed: %v", err)
+	}
+	if len(memories) != 10 {
+		t.Errorf("expected 10 memories after import, got %d", len(memories))
+	}
+}
+
+func TestExportContextsOnly(t *testing.T) {
+	source := newTestStorage(t)
+	seedTestStorage(t, source, 3, 2, 1)
+
+	var buf bytes.Buffer
+	stats, err := Export(context.Background(), source, &buf, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if stats.Contexts != 3 || stats.Memories != 0 || stats.Relations != 0 {
+		t.Errorf("unexpected export stats: %+v", stats)
+	}
+}
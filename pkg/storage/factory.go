@@ -12,8 +12,8 @@ import (
 type BackendType string
 
 const (
-	BackendSQLite BackendType = "sqlite"
-	// BackendPostgres BackendType = "postgres"
+	BackendSQLite   BackendType = "sqlite"
+	BackendPostgres BackendType = "postgres"
 	// BackendMemory   BackendType = "memory"
 )
 
@@ -22,6 +22,8 @@ func NewStorage(backend BackendType, cfg Config) (StorageInterface, error) {
 	switch backend {
 	case BackendSQLite:
 		return NewSQLiteStorage(cfg)
+	case BackendPostgres:
+		return NewPostgresStorage(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported backend type: %s", backend)
 	}
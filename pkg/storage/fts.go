@@ -0,0 +1,202 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContextSearcher is implemented by storage backends with full-text search
+// over context names, abstracts and descriptions.
+type ContextSearcher interface {
+	// SearchContextsFTS returns the contexts matching query, best match
+	// first. Bare words must all match; "double quoted" words must match
+	// as a phrase. A limit of zero or less returns every match.
+	SearchContextsFTS(ctx context.Context, query string, limit int) ([]Context, error)
+}
+
+// ftsNameWeight ranks a match in a context's name above one in its
+// abstract or description.
+const ftsNameWeight = 2.0
+
+// ftsTriggers keep contexts_fts in sync with the contexts table.
+var ftsTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS contexts_fts_ai AFTER INSERT ON contexts BEGIN
+		INSERT INTO contexts_fts(rowid, name, abstract, description) VALUES (new.rowid, new.name, new.abstract, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS contexts_fts_ad AFTER DELETE ON contexts BEGIN
+		INSERT INTO contexts_fts(contexts_fts, rowid, name, abstract, description) VALUES ('delete', old.rowid, old.name, old.abstract, old.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS contexts_fts_au AFTER UPDATE ON contexts BEGIN
+		INSERT INTO contexts_fts(contexts_fts, rowid, name, abstract, description) VALUES ('delete', old.rowid, old.name, old.abstract, old.description);
+		INSERT INTO contexts_fts(rowid, name, abstract, description) VALUES (new.rowid, new.name, new.abstract, new.description);
+	END`,
+}
+
+// initFTS creates the contexts_fts FTS5 index over context names,
+// abstracts and descriptions, and the triggers that maintain it. The index
+// is rebuilt when its triggers are new, covering databases written before
+// it existed or while it was unavailable. Without FTS5 compiled into SQLite
+// the triggers are dropped so writes keep working, and search falls back
+// to substring matching.
+func (s *SQLiteStorage) initFTS() error {
+	var triggers int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'contexts_fts_%'`).Scan(&triggers); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS contexts_fts USING fts5(name, abstract, description, content='contexts', content_rowid='rowid')`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module") {
+			return fmt.Errorf("failed to create full-text index: %w", err)
+		}
+		for _, name := range []string{"contexts_fts_ai", "contexts_fts_ad", "contexts_fts_au"} {
+			if _, err := s.db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				return fmt.Errorf("failed to drop trigger %s: %w", name, err)
+			}
+		}
+		s.fts = false
+		return nil
+	}
+
+	for _, trigger := range ftsTriggers {
+		if _, err := s.db.Exec(trigger); err != nil {
+			return fmt.Errorf("failed to create full-text trigger: %w", err)
+		}
+	}
+	if triggers < len(ftsTriggers) {
+		if _, err := s.db.Exec(`INSERT INTO contexts_fts(contexts_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to rebuild full-text index: %w", err)
+		}
+	}
+	s.fts = true
+	return nil
+}
+
+// HasFTS reports whether full-text search uses the FTS5 index rather than
+// substring matching.
+func (s *SQLiteStorage) HasFTS() bool {
+	return s.fts
+}
+
+// SearchContextsFTS returns the contexts whose name, abstract or
+// description match query, ranked by BM25 with name matches weighted
+// higher. Without FTS5 it matches substrings and ranks by occurrence count
+// instead.
+func (s *SQLiteStorage) SearchContextsFTS(ctx context.Context, query string, limit int) ([]Context, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	if !s.fts {
+		return s.searchContextsLike(ctx, terms, limit)
+	}
+
+	sqlQuery := `SELECT ` + contextColumns + ` FROM contexts
+		JOIN (SELECT rowid AS fts_rowid, bm25(contexts_fts, ?, 1.0, 1.0) AS rank FROM contexts_fts WHERE contexts_fts MATCH ?) f
+		ON contexts.rowid = f.fts_rowid
+		ORDER BY f.rank, uri LIMIT ?`
+	rows, err := s.db.QueryContext(ctx, sqlQuery, ftsNameWeight, ftsQuery(terms), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := []Context{}
+	for rows.Next() {
+		c, err := scanContext(rows)
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, *c)
+	}
+	return contexts, rows.Err()
+}
+
+// searchContextsLike is SearchContextsFTS without an FTS5 index: every term
+// must occur, ignoring case, in the name, abstract or description.
+func (s *SQLiteStorage) searchContextsLike(ctx context.Context, terms []string, limit int) ([]Context, error) {
+	conds := make([]string, len(terms))
+	args := make([]interface{}, len(terms))
+	for i, term := range terms {
+		conds[i] = `(name || ' ' || abstract || ' ' || description) LIKE ? ESCAPE '\'`
+		args[i] = "%" + escapeLike(term) + "%"
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+contextColumns+" FROM contexts WHERE "+strings.Join(conds, " AND "), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := []Context{}
+	for rows.Next() {
+		c, err := scanContext(rows)
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(contexts))
+	for _, c := range contexts {
+		name := strings.ToLower(c.Name)
+		text := strings.ToLower(c.Abstract + " " + c.Description)
+		for _, term := range terms {
+			term = strings.ToLower(term)
+			scores[c.ID] += ftsNameWeight*float64(strings.Count(name, term)) + float64(strings.Count(text, term))
+		}
+	}
+	sort.SliceStable(contexts, func(i, j int) bool {
+		if scores[contexts[i].ID] != scores[contexts[j].ID] {
+			return scores[contexts[i].ID] > scores[contexts[j].ID]
+		}
+		return contexts[i].URI < contexts[j].URI
+	})
+	if limit > 0 && len(contexts) > limit {
+		contexts = contexts[:limit]
+	}
+	return contexts, nil
+}
+
+// searchTerms splits a search query into words and "double quoted"
+// phrases.
+func searchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		// Odd parts are inside quotes
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return terms
+}
+
+// ftsQuery quotes each term as an FTS5 string, so punctuation in the
+// user's query is never read as FTS5 syntax. Terms are implicitly ANDed.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// seedSearchContexts stores contexts for the full-text search tests.
+func seedSearchContexts(t *testing.T, storage *SQLiteStorage) {
+	t.Helper()
+	now := time.Now().UTC()
+	contexts := []Context{
+		{ID: "guide", URI: "viking://resources/goroutine-guide", Name: "Goroutine guide", Abstract: "Start a goroutine per request; each goroutine is cheap"},
+		{ID: "channels", URI: "viking://resources/channels", Name: "Channels", Abstract: "Channels connect concurrent goroutine code with buffered and unbuffered queues"},
+		{ID: "errors", URI: "viking://resources/errors", Name: "Error handling", Abstract: "Idiomatic error handling in Go", Description: "wrapping and sentinel values"},
+		{ID: "retries", URI: "viking://resources/retries", Name: "Retries", Abstract: "Handling of an error by retrying the request"},
+	}
+	for i := range contexts {
+		contexts[i].Type = ContextTypeFile
+		contexts[i].CreatedAt = now
+		contexts[i].UpdatedAt = now
+		if err := storage.CreateContext(context.Background(), &contexts[i]); err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+	}
+}
+
+// searchIDs runs a full-text search and returns the IDs found, in order.
+func searchIDs(t *testing.T, storage *SQLiteStorage, query string, limit int) []string {
+	t.Helper()
+	contexts, err := storage.SearchContextsFTS(context.Background(), query, limit)
+	if err != nil {
+		t.Fatalf("search %q failed: %v", query, err)
+	}
+	ids := []string{}
+	for _, c := range contexts {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestSQLiteStorage_SearchContextsFTS(t *testing.T) {
+	storage := newTestStorage(t)
+	seedSearchContexts(t, storage)
+	t.Logf("FTS5 available: %v", storage.HasFTS())
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// The name match with two mentions ranks above a single mention
+		{"goroutine", []string{"guide", "channels"}},
+		{`"error handling"`, []string{"errors"}},
+		{"error handling", []string{"errors", "retries"}},
+		{`"handling of an error"`, []string{"retries"}},
+		{"sentinel", []string{"errors"}},
+		{"goroutine sentinel", []string{}},
+		{"c++ (goroutine)", []string{}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(t, storage, tt.query, 0); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search %q: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	if got := searchIDs(t, storage, "goroutine", 1); !reflect.DeepEqual(got, []string{"guide"}) {
+		t.Errorf("expected the best match only with limit 1, got %v", got)
+	}
+	if _, err := storage.SearchContextsFTS(context.Background(), `  "" `, 0); err == nil {
+		t.Error("expected an error for an empty query")
+	}
+}
+
+func TestSQLiteStorage_SearchContextsFTSFollowsWrites(t *testing.T) {
+	storage := newTestStorage(t)
+	seedSearchContexts(t, storage)
+	ctx := context.Background()
+
+	c, err := storage.GetContext(ctx, "retries")
+	if err != nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	c.Abstract = "Backoff with jitter"
+	if err := storage.UpdateContext(ctx, c); err != nil {
+		t.Fatalf("failed to update context: %v", err)
+	}
+	if got := searchIDs(t, storage, "jitter", 0); !reflect.DeepEqual(got, []string{"retries"}) {
+		t.Errorf("expected the updated abstract to match, got %v", got)
+	}
+	if got := searchIDs(t, storage, "handling", 0); !reflect.DeepEqual(got, []string{"errors"}) {
+		t.Errorf("expected the old abstract to stop matching, got %v", got)
+	}
+
+	if err := storage.DeleteContext(ctx, "guide"); err != nil {
+		t.Fatalf("failed to delete context: %v", err)
+	}
+	if got := searchIDs(t, storage, "goroutine", 0); !reflect.DeepEqual(got, []string{"channels"}) {
+		t.Errorf("expected the deleted context gone, got %v", got)
+	}
+}
+
+func TestSQLiteStorage_SearchContextsFTSRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	cfg := DefaultConfig()
+	cfg.DBPath = path
+	storage, err := NewSQLiteStorage(cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	if !storage.HasFTS() {
+		storage.Close()
+		t.Skip("SQLite built without FTS5")
+	}
+
+	// Rows written while the index was unavailable are indexed on reopen
+	for _, name := range []string{"contexts_fts_ai", "contexts_fts_ad", "contexts_fts_au"} {
+		if _, err := storage.db.Exec("DROP TRIGGER " + name); err != nil {
+			t.Fatalf("failed to drop trigger: %v", err)
+		}
+	}
+	seedSearchContexts(t, storage)
+	storage.Close()
+
+	storage, err = NewSQLiteStorage(cfg)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer storage.Close()
+	if got := searchIDs(t, storage, "goroutine", 0); !reflect.DeepEqual(got, []string{"guide", "channels"}) {
+		t.Errorf("expected rebuilt index to match, got %v", got)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms(`go  "error   handling" c++ "" tail"`)
+	want := []string{"go", "error handling", "c++", "tail"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if q := ftsQuery([]string{"c++", `say "hi"`}); q != `"c++" "say ""hi"""` {
+		t.Errorf("unexpected FTS query %s", q)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ConflictPolicy decides what happens when an imported record already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing record and skips the imported one.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing record with the imported one.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// ImportOptions controls how records are restored.
+type ImportOptions struct {
+	OnConflict ConflictPolicy
+}
+
+// ImportResult reports the outcome of an import.
+type ImportResult struct {
+	Imported  ExportStats `json:"imported"`
+	Skipped   ExportStats `json:"skipped"`
+	Conflicts []string    `json:"conflicts,omitempty"` // IDs of skipped records
+}
+
+// ImportStore restores NDJSON records produced by Export, preserving IDs.
+// Contexts and memories are upserted by ID (and contexts also by URI) as
+// they are read; relations are buffered and rebuilt once every entity exists.
+func ImportStore(ctx context.Context, store StorageInterface, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", opts.OnConflict)
+	}
+
+	result := &ImportResult{}
+	var relations []*RelationEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return result, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+
+		var err error
+		switch {
+		case rec.Kind == RecordKindContext && rec.Context != nil:
+			err = importContext(ctx, store, rec.Context, opts, result)
+		case rec.Kind == RecordKindMemory && rec.Memory != nil:
+			err = importMemory(ctx, store, rec.Memory, opts, result)
+		case rec.Kind == RecordKindRelation && rec.Relation != nil:
+			relations = append(relations, rec.Relation)
+		default:
+			err = fmt.Errorf("unknown record kind %q", rec.Kind)
+		}
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+
+	if len(relations) > 0 {
+		if err := importRelations(ctx, store, relations, opts, result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// importContext upserts a context by ID, treating a different context at
+// the same URI as a conflict too.
+func importContext(ctx context.Context, store StorageInterface, c *Context, opts ImportOptions, result *ImportResult) error {
+	existing, err := store.GetContext(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		existing, err = contextByURI(ctx, store, c.URI)
+		if err != nil {
+			return err
+		}
+	}
+
+	if existing == nil {
+		if err := store.CreateContext(ctx, c); err != nil {
+			return err
+		}
+		result.Imported.Contexts++
+		return nil
+	}
+
+	if opts.OnConflict == ConflictSkip {
+		result.Skipped.Contexts++
+		result.Conflicts = append(result.Conflicts, c.ID)
+		return nil
+	}
+
+	if existing.ID == c.ID {
+		err = store.UpdateContext(ctx, c)
+	} else {
+		// Another context owns the URI; replace it so the imported ID survives
+		if err = store.DeleteContext(ctx, existing.ID); err == nil {
+			err = store.CreateContext(ctx, c)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	result.Imported.Contexts++
+	return nil
+}
+
+// contextByURI returns the context stored at uri, or nil if there is none.
+func contextByURI(ctx context.Context, store StorageInterface, uri string) (*Context, error) {
+	contexts, err := store.QueryContexts(ctx, QueryOptions{
+		Filter: &Filter{Op: "and", Conds: []FilterCondition{{Op: "must", Field: "uri", Value: uri}}},
+		Limit:  1,
+	})
+	if err != nil || len(contexts) == 0 {
+		return nil, err
+	}
+	return &contexts[0], nil
+}
+
+// importMemory upserts a memory by ID.
+func importMemory(ctx context.Context, store StorageInterface, m *Memory, opts ImportOptions, result *ImportResult) error {
+	existing, err := store.GetMemory(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case existing == nil:
+		err = store.CreateMemory(ctx, m)
+	case opts.OnConflict == ConflictSkip:
+		result.Skipped.Memories++
+		result.Conflicts = append(result.Conflicts, m.ID)
+		return nil
+	default:
+		err = store.UpdateMemory(ctx, m)
+	}
+	if err != nil {
+		return err
+	}
+	result.Imported.Memories++
+	return nil
+}
+
+// importRelations recreates buffered relations, resolving conflicts by ID.
+func importRelations(ctx context.Context, store StorageInterface, relations []*RelationEntry, opts ImportOptions, result *ImportResult) error {
+	existing := make(map[string]bool)
+	err := store.IterateRelations(ctx, func(r *RelationEntry) error {
+		existing[r.ID] = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load existing relations: %w", err)
+	}
+
+	for _, r := range relations {
+		if existing[r.ID] {
+			if opts.OnConflict == ConflictSkip {
+				result.Skipped.Relations++
+				result.Conflicts = append(result.Conflicts, r.ID)
+				continue
+			}
+			if err := store.DeleteRelation(ctx, r.ID); err != nil {
+				return fmt.Errorf("failed to replace relation %s: %w", r.ID, err)
+			}
+		}
+		if err := store.CreateRelation(ctx, r); err != nil {
+			return fmt.Errorf("failed to create relation %s: %w", r.ID, err)
+		}
+		existing[r.ID] = true
+		result.Imported.Relations++
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exportAll(t *testing.T, storage *SQLiteStorage) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), storage, &buf, ExportOptions{IncludeMemories: true, IncludeRelations: true})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportStoreRelationIntegrity(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStorage(t)
+	seedTestStorage(t, source, 4, 2, 3)
+
+	// Put relations first so they precede the contexts they reference
+	data := exportAll(t, source)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var reordered []string
+	for _, l := range lines {
+		if strings.Contains(l, `"kind":"relation"`) {
+			reordered = append(reordered, l)
+		}
+	}
+	for _, l := range lines {
+		if !strings.Contains(l, `"kind":"relation"`) {
+			reordered = append(reordered, l)
+		}
+	}
+
+	target := newTestStorage(t)
+	result, err := ImportStore(ctx, target, strings.NewReader(strings.Join(reordered, "\n")), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportStore failed: %v", err)
+	}
+	if result.Imported.Relations != 3 {
+		t.Fatalf("expected 3 relations imported, got %d", result.Imported.Relations)
+	}
+
+	var relations []RelationEntry
+	target.IterateRelations(ctx, func(r *RelationEntry) error {
+		relations = append(relations, *r)
+		return nil
+	})
+	for _, r := range relations {
+		var uris []string
+		if err := json.Unmarshal([]byte(r.URIs), &uris); err != nil {
+			t.Fatalf("relation %s has invalid URIs: %v", r.ID, err)
+		}
+		for _, uri := range uris {
+			c, err := contextByURI(ctx, target, uri)
+			if err != nil {
+				t.Fatalf("contextByURI failed: %v", err)
+			}
+			if c == nil {
+				t.Errorf("relation %s references missing context %s", r.ID, uri)
+			}
+		}
+	}
+
+	c, err := target.GetContext(ctx, "ctx-2")
+	if err != nil || c == nil {
+		t.Fatalf("expected context ID ctx-2 to be preserved, got %v (err %v)", c, err)
+	}
+}
+
+func TestImportStoreConflicts(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStorage(t)
+	seedTestStorage(t, source, 3, 1, 1)
+	data := exportAll(t, source)
+
+	target := newTestStorage(t)
+	seedTestStorage(t, target, 1, 0, 0)
+	renamed, _ := target.GetContext(ctx, "ctx-0")
+	renamed.Name = "local edit"
+	target.UpdateContext(ctx, renamed)
+
+	result, err := ImportStore(ctx, target, bytes.NewReader(data), ImportOptions{OnConflict: ConflictSkip})
+	if err != nil {
+		t.Fatalf("ImportStore failed: %v", err)
+	}
+	if result.Imported.Contexts != 2 || result.Skipped.Contexts != 1 {
+		t.Errorf("expected 2 imported and 1 skipped context, got %+v", result)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "ctx-0" {
+		t.Errorf("expected conflict on ctx-0, got %v", result.Conflicts)
+	}
+	if c, _ := target.GetContext(ctx, "ctx-0"); c.Name != "local edit" {
+		t.Errorf("skip should keep the local context, got name %q", c.Name)
+	}
+
+	result, err = ImportStore(ctx, target, bytes.NewReader(data), ImportOptions{OnConflict: ConflictOverwrite})
+	if err != nil {
+		t.Fatalf("ImportStore failed: %v", err)
+	}
+	if result.Imported.Contexts != 3 || len(result.Conflicts) != 0 {
+		t.Errorf("expected all 3 contexts overwritten, got %+v", result)
+	}
+	if c, _ := target.GetContext(ctx, "ctx-0"); c.Name != "doc0" {
+		t.Errorf("overwrite should restore the imported context, got name %q", c.Name)
+	}
+}
+
+func TestImportStoreUnknownPolicy(t *testing.T) {
+	_, err := ImportStore(context.Background(), newTestStorage(t), strings.NewReader(""), ImportOptions{OnConflict: "merge"})
+	if err == nil {
+		t.Error("expected error for unknown conflict policy")
+	}
+}
@@ -32,8 +32,15 @@ type Context struct {
 	Tags        string      `json:"tags" db:"tags"`
 	Abstract    string      `json:"abstract" db:"abstract"`
 	ActiveCount int64       `json:"active_count" db:"active_count"`
+	Content     string      `json:"content,omitempty" db:"content"`
 	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+
+	// ContentRef and ContentChecksum locate and verify content kept in a
+	// ContentStore instead of the contexts row. Both are empty for inline
+	// content.
+	ContentRef      string `json:"content_ref,omitempty" db:"content_ref"`
+	ContentChecksum string `json:"content_checksum,omitempty" db:"content_checksum"`
 }
 
 // Session represents a session in the database.
@@ -48,6 +55,9 @@ type Session struct {
 	SkillsUsed     int64     `json:"skills_used" db:"skills_used"`
 	MemoriesExtracted int64  `json:"memories_extracted" db:"memories_extracted"`
 	Summary        string    `json:"summary" db:"summary"`
+	// Metadata is a JSON object, e.g. the parent of a forked session.
+	// Empty means none.
+	Metadata       string    `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -70,6 +80,11 @@ type Memory struct {
 	Content     string    `json:"content" db:"content"`
 	Importance  float64   `json:"importance" db:"importance"`
 	Tags        string    `json:"tags" db:"tags"`
+	// SourceMessageIDs lists, comma-separated, the messages the memory was
+	// extracted from
+	SourceMessageIDs string `json:"source_message_ids" db:"source_message_ids"`
+	// Evidence quotes the conversation supporting the memory
+	Evidence    string    `json:"evidence" db:"evidence"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -104,5 +119,22 @@ type RelationEntry struct {
 	ID        string    `json:"id" db:"id"`
 	URIs      string    `json:"uris" db:"uris"` // JSON array of URIs
 	Reason    string    `json:"reason" db:"reason"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Bidirectional relations hold in both directions; otherwise the
+	// first URI is the source and the others its targets
+	Bidirectional bool      `json:"bidirectional" db:"bidirectional"`
+	Type          string    `json:"type,omitempty" db:"type"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditEntry records a mutation of a context, memory or session.
+type AuditEntry struct {
+	ID         string `json:"id" db:"id"`
+	Actor      string `json:"actor" db:"actor"`   // asserted by the caller, not authenticated
+	Action     string `json:"action" db:"action"` // "create", "update" or "delete"
+	EntityType string `json:"entity_type" db:"entity_type"`
+	EntityID   string `json:"entity_id" db:"entity_id"`
+	// Diff is a JSON object holding the before and after value of each
+	// changed field
+	Diff      string    `json:"diff,omitempty" db:"diff"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
 }
@@ -0,0 +1,975 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresStorage implements StorageInterface using PostgreSQL. Unlike
+// SQLite it allows concurrent writers, so several server instances can
+// share one database.
+type PostgresStorage struct {
+	db  *sql.DB
+	cfg Config
+
+	// content holds large context content outside the contexts table;
+	// nil keeps all content inline
+	content ContentStore
+}
+
+// NewPostgresStorage creates a new PostgreSQL storage instance connected
+// to cfg.DSN, creating any missing tables.
+func NewPostgresStorage(cfg Config) (*PostgresStorage, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("postgres storage requires a connection string")
+	}
+
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Configure connection pool
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Verify connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	storage := &PostgresStorage{
+		db:  db,
+		cfg: cfg,
+	}
+	if cfg.ContentDir != "" {
+		storage.content = NewFileContentStore(cfg.ContentDir)
+	}
+
+	// Initialize schema
+	if err := storage.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// initSchema creates all necessary tables. The columns mirror the SQLite
+// schema, with native timestamp and boolean types.
+func (s *PostgresStorage) initSchema() error {
+	schemas := []string{
+		`CREATE TABLE IF NOT EXISTS contexts (
+			id TEXT PRIMARY KEY,
+			uri TEXT UNIQUE NOT NULL,
+			type TEXT NOT NULL,
+			context_type TEXT,
+			parent_uri TEXT,
+			is_leaf BOOLEAN DEFAULT TRUE,
+			name TEXT,
+			description TEXT,
+			tags TEXT,
+			abstract TEXT,
+			active_count BIGINT DEFAULT 0,
+			content TEXT DEFAULT '',
+			content_ref TEXT DEFAULT '',
+			content_checksum TEXT DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_contexts_parent_uri ON contexts(parent_uri)`,
+		`CREATE INDEX IF NOT EXISTS idx_contexts_type ON contexts(type)`,
+
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			session_id TEXT UNIQUE NOT NULL,
+			user_id TEXT,
+			total_turns BIGINT DEFAULT 0,
+			total_tokens BIGINT DEFAULT 0,
+			compression_count BIGINT DEFAULT 0,
+			contexts_used BIGINT DEFAULT 0,
+			skills_used BIGINT DEFAULT 0,
+			memoies_extracted BIGINT DEFAULT 0,
+			summary TEXT,
+			metadata TEXT DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS metadata TEXT DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id)`,
+
+		// SQLite does not enforce foreign keys, and messages may be stored
+		// before their session, so session_id is not a foreign key here
+		// either
+		`CREATE TABLE IF NOT EXISTS session_messages (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			content TEXT NOT NULL,
+			order_index BIGINT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_messages_session_id ON session_messages(session_id)`,
+
+		`CREATE TABLE IF NOT EXISTS memories (
+			id TEXT PRIMARY KEY,
+			session_id TEXT,
+			user_id TEXT,
+			content TEXT NOT NULL,
+			importance DOUBLE PRECISION DEFAULT 0.0,
+			tags TEXT,
+			source_message_ids TEXT DEFAULT '',
+			evidence TEXT DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		// Columns added after the initial schema, for existing databases
+		`ALTER TABLE memories ADD COLUMN IF NOT EXISTS source_message_ids TEXT DEFAULT ''`,
+		`ALTER TABLE memories ADD COLUMN IF NOT EXISTS evidence TEXT DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_memories_session_id ON memories(session_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_memories_user_id ON memories(user_id)`,
+
+		`CREATE TABLE IF NOT EXISTS files (
+			id TEXT PRIMARY KEY,
+			uri TEXT UNIQUE NOT NULL,
+			name TEXT NOT NULL,
+			size BIGINT DEFAULT 0,
+			content_type TEXT,
+			checksum TEXT,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS usage_records (
+			id TEXT PRIMARY KEY,
+			session_id TEXT,
+			uri TEXT NOT NULL,
+			type TEXT NOT NULL,
+			contribution DOUBLE PRECISION DEFAULT 0.0,
+			input TEXT,
+			output TEXT,
+			success BOOLEAN DEFAULT TRUE,
+			timestamp TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_usage_records_session_id ON usage_records(session_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_usage_records_uri ON usage_records(uri)`,
+
+		`CREATE TABLE IF NOT EXISTS audit (
+			id TEXT PRIMARY KEY,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			diff TEXT,
+			timestamp TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit(entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON audit(timestamp)`,
+
+		`CREATE TABLE IF NOT EXISTS relations (
+			id TEXT PRIMARY KEY,
+			uris TEXT NOT NULL,
+			reason TEXT,
+			bidirectional BOOLEAN DEFAULT FALSE,
+			type TEXT DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+		`ALTER TABLE relations ADD COLUMN IF NOT EXISTS bidirectional BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE relations ADD COLUMN IF NOT EXISTS type TEXT DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_relations_uris ON relations(uris)`,
+
+		`CREATE TABLE IF NOT EXISTS context_snapshots (
+			key TEXT PRIMARY KEY,
+			data BYTEA NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+	}
+
+	for _, schema := range schemas {
+		if _, err := s.db.Exec(schema); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetContentStore sets where content larger than Config.ContentInlineLimit
+// is stored. A nil store keeps all content inline.
+func (s *PostgresStorage) SetContentStore(store ContentStore) {
+	s.content = store
+}
+
+// Close closes the database connection pool.
+func (s *PostgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks if the database is accessible.
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Transaction executes a function within a transaction.
+func (s *PostgresStorage) Transaction(ctx context.Context, fn func(tx interface{}) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("rollback failed: %v (original error: %w)", rbErr, err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// postgresFilterClause builds a WHERE clause with buildFilterClause and
+// rewrites it for PostgreSQL: ? placeholders become $1, $2, ... and LIKE
+// becomes ILIKE, matching SQLite's case-insensitive LIKE.
+func postgresFilterClause(filter *Filter) (string, []interface{}) {
+	clause, args := buildFilterClause(filter)
+	clause = strings.ReplaceAll(clause, " LIKE ", " ILIKE ")
+
+	var b strings.Builder
+	n := 0
+	for _, r := range clause {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), args
+}
+
+// selectQuery appends the filter, ordering and paging of opts to a SELECT
+// statement and returns it with its arguments.
+func selectQuery(query string, opts QueryOptions) (string, []interface{}) {
+	var args []interface{}
+	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
+		whereClause, filterArgs := postgresFilterClause(opts.Filter)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = filterArgs
+		}
+	}
+
+	if opts.OrderBy != "" {
+		orderDir := "ASC"
+		if opts.OrderDesc {
+			orderDir = "DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %s %s", opts.OrderBy, orderDir)
+	}
+
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+
+	if opts.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
+	}
+
+	return query, args
+}
+
+// count counts the rows of table matching the filter of opts.
+func (s *PostgresStorage) count(ctx context.Context, table string, opts QueryOptions) (int, error) {
+	query, args := selectQuery("SELECT COUNT(*) FROM "+table, QueryOptions{Filter: opts.Filter})
+	var n int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// =============================================================================
+// Context Operations
+// =============================================================================
+
+// scanPostgresContext reads a row selected with contextColumns.
+// Externalized content is left unresolved.
+func scanPostgresContext(row rowScanner) (*Context, error) {
+	var c Context
+	err := row.Scan(&c.ID, &c.URI, &c.Type, &c.ContextType, &c.ParentURI, &c.IsLeaf,
+		&c.Name, &c.Description, &c.Tags, &c.Abstract, &c.ActiveCount,
+		&c.Content, &c.ContentRef, &c.ContentChecksum, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// contentRef returns the stored content reference of context id.
+func (s *PostgresStorage) contentRef(ctx context.Context, id string) (string, error) {
+	var ref string
+	err := s.db.QueryRowContext(ctx, "SELECT content_ref FROM contexts WHERE id = $1", id).Scan(&ref)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ref, err
+}
+
+// CreateContext inserts a new context into the database. Content larger
+// than the inline limit goes to the content store when one is configured.
+func (s *PostgresStorage) CreateContext(ctx context.Context, c *Context) error {
+	content, err := offloadContent(ctx, s.content, inlineLimit(s.cfg), c)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO contexts (id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, content, content_ref, content_checksum, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err = s.db.ExecContext(ctx, query,
+		c.ID, c.URI, c.Type, c.ContextType, c.ParentURI, c.IsLeaf, c.Name,
+		c.Description, c.Tags, c.Abstract, c.ActiveCount,
+		content, c.ContentRef, c.ContentChecksum, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		discardContent(ctx, s.content, c)
+	}
+	return err
+}
+
+// GetContext retrieves a context by ID, loading externalized content.
+func (s *PostgresStorage) GetContext(ctx context.Context, id string) (*Context, error) {
+	query := `SELECT ` + contextColumns + ` FROM contexts WHERE id = $1`
+	c, err := scanPostgresContext(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveContent(ctx, s.content, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ContextExists reports whether a context with the given ID exists
+// without loading its columns.
+func (s *PostgresStorage) ContextExists(ctx context.Context, id string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM contexts WHERE id = $1 LIMIT 1", id).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateContext updates an existing context. New external content is
+// stored under a new reference, and the content it replaces is removed
+// only once the row no longer points at it.
+func (s *PostgresStorage) UpdateContext(ctx context.Context, c *Context) error {
+	content, err := offloadContent(ctx, s.content, inlineLimit(s.cfg), c)
+	if err != nil {
+		return err
+	}
+	oldRef, found, err := s.switchContext(ctx, c, content)
+	if err != nil || !found {
+		discardContent(ctx, s.content, c)
+		return err
+	}
+
+	if oldRef != "" && s.content != nil {
+		return s.content.Delete(ctx, oldRef)
+	}
+	return nil
+}
+
+// switchContext writes c to its row with the given inline content and
+// returns the content reference the row held before, reporting whether
+// there was a row to write.
+func (s *PostgresStorage) switchContext(ctx context.Context, c *Context, content string) (string, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	var oldRef string
+	err = tx.QueryRowContext(ctx, "SELECT content_ref FROM contexts WHERE id = $1 FOR UPDATE", c.ID).Scan(&oldRef)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	query := `UPDATE contexts SET uri = $1, type = $2, context_type = $3, parent_uri = $4, is_leaf = $5, name = $6, description = $7, tags = $8, abstract = $9, active_count = $10, content = $11, content_ref = $12, content_checksum = $13, updated_at = $14 WHERE id = $15`
+	_, err = tx.ExecContext(ctx, query,
+		c.URI, c.Type, c.ContextType, c.ParentURI, c.IsLeaf, c.Name,
+		c.Description, c.Tags, c.Abstract, c.ActiveCount,
+		content, c.ContentRef, c.ContentChecksum, c.UpdatedAt, c.ID)
+	if err != nil {
+		return "", false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+	return oldRef, true, nil
+}
+
+// DeleteContext deletes a context by ID along with any external content.
+func (s *PostgresStorage) DeleteContext(ctx context.Context, id string) error {
+	ref, err := s.contentRef(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM contexts WHERE id = $1", id); err != nil {
+		return err
+	}
+	if ref != "" && s.content != nil {
+		return s.content.Delete(ctx, ref)
+	}
+	return nil
+}
+
+// QueryContexts queries contexts with filter options. Externalized content
+// is not loaded; only ContentRef is set for those contexts.
+func (s *PostgresStorage) QueryContexts(ctx context.Context, opts QueryOptions) ([]Context, error) {
+	query, args := selectQuery("SELECT "+contextColumns+" FROM contexts", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []Context
+	for rows.Next() {
+		c, err := scanPostgresContext(rows)
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, *c)
+	}
+
+	return contexts, rows.Err()
+}
+
+// CountContexts counts the contexts matching the filter of opts, ignoring
+// its limit and offset.
+func (s *PostgresStorage) CountContexts(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "contexts", opts)
+}
+
+// =============================================================================
+// Session Operations
+// =============================================================================
+
+// sessionColumns lists the sessions columns in the order scanSession
+// reads them.
+const sessionColumns = "id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, metadata, created_at, updated_at"
+
+// scanSession reads a row selected with sessionColumns.
+func scanSession(row rowScanner) (*Session, error) {
+	var session Session
+	err := row.Scan(&session.ID, &session.SessionID, &session.UserID, &session.TotalTurns,
+		&session.TotalTokens, &session.CompressionCount, &session.ContextsUsed, &session.SkillsUsed,
+		&session.MemoriesExtracted, &session.Summary, &session.Metadata, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CreateSession inserts a new session into the database.
+func (s *PostgresStorage) CreateSession(ctx context.Context, session *Session) error {
+	query := `INSERT INTO sessions (` + sessionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	_, err := s.db.ExecContext(ctx, query,
+		session.ID, session.SessionID, session.UserID, session.TotalTurns, session.TotalTokens,
+		session.CompressionCount, session.ContextsUsed, session.SkillsUsed,
+		session.MemoriesExtracted, session.Summary, session.Metadata, session.CreatedAt, session.UpdatedAt)
+	return err
+}
+
+// GetSession retrieves a session by ID.
+func (s *PostgresStorage) GetSession(ctx context.Context, id string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	session, err := scanSession(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return session, err
+}
+
+// UpdateSession updates an existing session.
+func (s *PostgresStorage) UpdateSession(ctx context.Context, session *Session) error {
+	query := `UPDATE sessions SET session_id = $1, user_id = $2, total_turns = $3, total_tokens = $4, compression_count = $5, contexts_used = $6, skills_used = $7, memoies_extracted = $8, summary = $9, metadata = $10, updated_at = $11 WHERE id = $12`
+	_, err := s.db.ExecContext(ctx, query,
+		session.SessionID, session.UserID, session.TotalTurns, session.TotalTokens,
+		session.CompressionCount, session.ContextsUsed, session.SkillsUsed,
+		session.MemoriesExtracted, session.Summary, session.Metadata, session.UpdatedAt, session.ID)
+	return err
+}
+
+// DeleteSession deletes a session by ID together with its messages.
+// Memories extracted from the session are kept.
+func (s *PostgresStorage) DeleteSession(ctx context.Context, id string) error {
+	return s.Transaction(ctx, func(tx interface{}) error {
+		t := tx.(*sql.Tx)
+		// Messages reference the session by session_id rather than id
+		if _, err := t.ExecContext(ctx, "DELETE FROM session_messages WHERE session_id IN (SELECT session_id FROM sessions WHERE id = $1)", id); err != nil {
+			return err
+		}
+		_, err := t.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", id)
+		return err
+	})
+}
+
+// QuerySessions queries sessions with filter options.
+func (s *PostgresStorage) QuerySessions(ctx context.Context, opts QueryOptions) ([]Session, error) {
+	query, args := selectQuery("SELECT "+sessionColumns+" FROM sessions", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// CountSessions counts the sessions matching the filter of opts, ignoring
+// its limit and offset.
+func (s *PostgresStorage) CountSessions(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "sessions", opts)
+}
+
+// =============================================================================
+// SessionMessage Operations
+// =============================================================================
+
+// CreateSessionMessage inserts a new session message.
+func (s *PostgresStorage) CreateSessionMessage(ctx context.Context, msg *SessionMessage) error {
+	query := `INSERT INTO session_messages (id, session_id, role, content, order_index, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := s.db.ExecContext(ctx, query,
+		msg.ID, msg.SessionID, msg.Role, msg.Content, msg.OrderIndex, msg.CreatedAt)
+	return err
+}
+
+// GetSessionMessages retrieves all messages for a session.
+func (s *PostgresStorage) GetSessionMessages(ctx context.Context, sessionID string) ([]SessionMessage, error) {
+	query := `SELECT id, session_id, role, content, order_index, created_at FROM session_messages WHERE session_id = $1 ORDER BY order_index`
+	rows, err := s.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []SessionMessage
+	for rows.Next() {
+		var msg SessionMessage
+		err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content, &msg.OrderIndex, &msg.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// DeleteSessionMessages deletes all messages for a session.
+func (s *PostgresStorage) DeleteSessionMessages(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM session_messages WHERE session_id = $1", sessionID)
+	return err
+}
+
+// =============================================================================
+// Memory Operations
+// =============================================================================
+
+// memoryColumns lists the memories columns in the order scanMemory reads
+// them.
+const memoryColumns = "id, session_id, user_id, content, importance, tags, source_message_ids, evidence, created_at, updated_at"
+
+// scanMemory reads a row selected with memoryColumns.
+func scanMemory(row rowScanner) (*Memory, error) {
+	var memory Memory
+	err := row.Scan(&memory.ID, &memory.SessionID, &memory.UserID, &memory.Content,
+		&memory.Importance, &memory.Tags, &memory.SourceMessageIDs, &memory.Evidence, &memory.CreatedAt, &memory.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &memory, nil
+}
+
+// CreateMemory inserts a new memory.
+func (s *PostgresStorage) CreateMemory(ctx context.Context, memory *Memory) error {
+	query := `INSERT INTO memories (` + memoryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := s.db.ExecContext(ctx, query,
+		memory.ID, memory.SessionID, memory.UserID, memory.Content, memory.Importance,
+		memory.Tags, memory.SourceMessageIDs, memory.Evidence, memory.CreatedAt, memory.UpdatedAt)
+	return err
+}
+
+// GetMemory retrieves a memory by ID.
+func (s *PostgresStorage) GetMemory(ctx context.Context, id string) (*Memory, error) {
+	query := `SELECT ` + memoryColumns + ` FROM memories WHERE id = $1`
+	memory, err := scanMemory(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return memory, err
+}
+
+// UpdateMemory updates an existing memory.
+func (s *PostgresStorage) UpdateMemory(ctx context.Context, memory *Memory) error {
+	query := `UPDATE memories SET session_id = $1, user_id = $2, content = $3, importance = $4, tags = $5, source_message_ids = $6, evidence = $7, updated_at = $8 WHERE id = $9`
+	_, err := s.db.ExecContext(ctx, query,
+		memory.SessionID, memory.UserID, memory.Content, memory.Importance,
+		memory.Tags, memory.SourceMessageIDs, memory.Evidence, memory.UpdatedAt, memory.ID)
+	return err
+}
+
+// DeleteMemory deletes a memory by ID.
+func (s *PostgresStorage) DeleteMemory(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM memories WHERE id = $1", id)
+	return err
+}
+
+// QueryMemories queries memories with filter options.
+func (s *PostgresStorage) QueryMemories(ctx context.Context, opts QueryOptions) ([]Memory, error) {
+	query, args := selectQuery("SELECT "+memoryColumns+" FROM memories", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memories []Memory
+	for rows.Next() {
+		memory, err := scanMemory(rows)
+		if err != nil {
+			return nil, err
+		}
+		memories = append(memories, *memory)
+	}
+
+	return memories, rows.Err()
+}
+
+// CountMemories counts the memories matching the filter of opts, ignoring
+// its limit and offset.
+func (s *PostgresStorage) CountMemories(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "memories", opts)
+}
+
+// =============================================================================
+// File Operations
+// =============================================================================
+
+// fileColumns lists the files columns in the order scanFile reads them.
+const fileColumns = "id, uri, name, size, content_type, checksum, created_at, updated_at"
+
+// scanFile reads a row selected with fileColumns.
+func scanFile(row rowScanner) (*File, error) {
+	var file File
+	err := row.Scan(&file.ID, &file.URI, &file.Name, &file.Size, &file.ContentType,
+		&file.Checksum, &file.CreatedAt, &file.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// CreateFile inserts a new file.
+func (s *PostgresStorage) CreateFile(ctx context.Context, file *File) error {
+	query := `INSERT INTO files (` + fileColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := s.db.ExecContext(ctx, query,
+		file.ID, file.URI, file.Name, file.Size, file.ContentType,
+		file.Checksum, file.CreatedAt, file.UpdatedAt)
+	return err
+}
+
+// GetFile retrieves a file by ID.
+func (s *PostgresStorage) GetFile(ctx context.Context, id string) (*File, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE id = $1`
+	file, err := scanFile(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return file, err
+}
+
+// UpdateFile updates an existing file.
+func (s *PostgresStorage) UpdateFile(ctx context.Context, file *File) error {
+	query := `UPDATE files SET uri = $1, name = $2, size = $3, content_type = $4, checksum = $5, updated_at = $6 WHERE id = $7`
+	_, err := s.db.ExecContext(ctx, query,
+		file.URI, file.Name, file.Size, file.ContentType, file.Checksum, file.UpdatedAt, file.ID)
+	return err
+}
+
+// DeleteFile deletes a file by ID.
+func (s *PostgresStorage) DeleteFile(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM files WHERE id = $1", id)
+	return err
+}
+
+// QueryFiles queries files with filter options.
+func (s *PostgresStorage) QueryFiles(ctx context.Context, opts QueryOptions) ([]File, error) {
+	query, args := selectQuery("SELECT "+fileColumns+" FROM files", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []File
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, *file)
+	}
+
+	return files, rows.Err()
+}
+
+// =============================================================================
+// Usage Operations
+// =============================================================================
+
+// CreateUsage inserts a new usage record.
+func (s *PostgresStorage) CreateUsage(ctx context.Context, usage *Usage) error {
+	query := `INSERT INTO usage_records (id, session_id, uri, type, contribution, input, output, success, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := s.db.ExecContext(ctx, query,
+		usage.ID, usage.SessionID, usage.URI, usage.Type, usage.Contribution,
+		usage.Input, usage.Output, usage.Success, usage.Timestamp)
+	return err
+}
+
+// QueryUsage queries usage records with filter options.
+func (s *PostgresStorage) QueryUsage(ctx context.Context, opts QueryOptions) ([]Usage, error) {
+	query, args := selectQuery("SELECT id, session_id, uri, type, contribution, input, output, success, timestamp FROM usage_records", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []Usage
+	for rows.Next() {
+		var usage Usage
+		err := rows.Scan(&usage.ID, &usage.SessionID, &usage.URI, &usage.Type,
+			&usage.Contribution, &usage.Input, &usage.Output, &usage.Success, &usage.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	return usages, rows.Err()
+}
+
+// =============================================================================
+// Audit Operations
+// =============================================================================
+
+// CreateAudit inserts a new audit entry.
+func (s *PostgresStorage) CreateAudit(ctx context.Context, entry *AuditEntry) error {
+	query := `INSERT INTO audit (id, actor, action, entity_type, entity_id, diff, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.ExecContext(ctx, query,
+		entry.ID, entry.Actor, entry.Action, entry.EntityType, entry.EntityID,
+		entry.Diff, entry.Timestamp)
+	return err
+}
+
+// QueryAudit queries audit entries with filter options.
+func (s *PostgresStorage) QueryAudit(ctx context.Context, opts QueryOptions) ([]AuditEntry, error) {
+	query, args := selectQuery("SELECT id, actor, action, entity_type, entity_id, diff, timestamp FROM audit", opts)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var diff sql.NullString
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.EntityType,
+			&entry.EntityID, &diff, &entry.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		entry.Diff = diff.String
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// =============================================================================
+// Relation Operations
+// =============================================================================
+
+// CreateRelation inserts a new relation.
+func (s *PostgresStorage) CreateRelation(ctx context.Context, relation *RelationEntry) error {
+	query := `INSERT INTO relations (id, uris, reason, bidirectional, type, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := s.db.ExecContext(ctx, query,
+		relation.ID, relation.URIs, relation.Reason, relation.Bidirectional, relation.Type, relation.CreatedAt)
+	return err
+}
+
+// QueryRelations retrieves relations for a URI.
+func (s *PostgresStorage) QueryRelations(ctx context.Context, uri string) ([]RelationEntry, error) {
+	query := `SELECT id, uris, reason, bidirectional, type, created_at FROM relations WHERE uris LIKE $1`
+	rows, err := s.db.QueryContext(ctx, query, "%"+uri+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []RelationEntry
+	for rows.Next() {
+		var relation RelationEntry
+		if err := rows.Scan(&relation.ID, &relation.URIs, &relation.Reason, &relation.Bidirectional, &relation.Type, &relation.CreatedAt); err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, rows.Err()
+}
+
+// DeleteRelation deletes a relation by ID.
+func (s *PostgresStorage) DeleteRelation(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM relations WHERE id = $1", id)
+	return err
+}
+
+// =============================================================================
+// Streaming Operations
+// =============================================================================
+
+// IterateContexts calls fn for every context, reading rows from a cursor so
+// memory use stays constant regardless of table size. Externalized content
+// is loaded so that exports are self-contained.
+func (s *PostgresStorage) IterateContexts(ctx context.Context, fn func(*Context) error) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+contextColumns+" FROM contexts ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := scanPostgresContext(rows)
+		if err != nil {
+			return err
+		}
+		if err := resolveContent(ctx, s.content, c); err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// IterateMemories calls fn for every memory, reading rows from a cursor.
+func (s *PostgresStorage) IterateMemories(ctx context.Context, fn func(*Memory) error) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+memoryColumns+" FROM memories ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		memory, err := scanMemory(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(memory); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// IterateRelations calls fn for every relation, reading rows from a cursor.
+func (s *PostgresStorage) IterateRelations(ctx context.Context, fn func(*RelationEntry) error) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, uris, reason, bidirectional, type, created_at FROM relations ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation RelationEntry
+		if err := rows.Scan(&relation.ID, &relation.URIs, &relation.Reason, &relation.Bidirectional, &relation.Type, &relation.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(&relation); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// =============================================================================
+// Collection Management (for interface compatibility)
+// =============================================================================
+
+// CreateCollection creates a collection (no-op, tables are created on init).
+func (s *PostgresStorage) CreateCollection(name string, schema map[string]interface{}) error {
+	return nil
+}
+
+// DropCollection drops a collection (no-op, tables are never dropped).
+func (s *PostgresStorage) DropCollection(name string) error {
+	return nil
+}
+
+// CollectionExists checks if a collection exists.
+func (s *PostgresStorage) CollectionExists(name string) bool {
+	switch name {
+	case "contexts", "sessions", "memories", "files", "usage_records", "audit", "relations":
+		return true
+	default:
+		return false
+	}
+}
+
+// ListCollections lists all collections.
+func (s *PostgresStorage) ListCollections() ([]string, error) {
+	return []string{"contexts", "sessions", "memories", "files", "usage_records", "audit", "relations"}, nil
+}
+
+// Ensure PostgresStorage implements StorageInterface
+var _ StorageInterface = (*PostgresStorage)(nil)
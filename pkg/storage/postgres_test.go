@@ -0,0 +1,243 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build postgres
+// +build postgres
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestPostgres connects to the database in GOVIKING_TEST_POSTGRES_DSN,
+// for example postgres://postgres@localhost/postgres?sslmode=disable, and
+// returns a storage whose tables live in a fresh schema that is dropped
+// when the test ends.
+func newTestPostgres(t *testing.T) *PostgresStorage {
+	t.Helper()
+	dsn := os.Getenv("GOVIKING_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GOVIKING_TEST_POSTGRES_DSN not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("goviking_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return
+		}
+		defer db.Close()
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	// lib/pq passes unknown parameters on as run-time settings
+	switch {
+	case strings.Contains(dsn, "://") && strings.Contains(dsn, "?"):
+		dsn += "&search_path=" + schema
+	case strings.Contains(dsn, "://"):
+		dsn += "?search_path=" + schema
+	default:
+		dsn += " search_path=" + schema
+	}
+
+	cfg := DefaultConfig()
+	cfg.DSN = dsn
+	s, err := NewPostgresStorage(cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// testTime is a timestamp at PostgreSQL's microsecond precision.
+var testTime = time.Date(2026, 3, 1, 12, 30, 45, 123456000, time.UTC)
+
+func TestPostgresStorage_ContextCRUD(t *testing.T) {
+	s := newTestPostgres(t)
+	ctx := context.Background()
+
+	c := &Context{
+		ID:          "ctx-1",
+		URI:         "viking://resources/docs/guide.md",
+		Type:        ContextTypeFile,
+		ContextType: "resource",
+		ParentURI:   "viking://resources/docs",
+		IsLeaf:      true,
+		Name:        "Guide",
+		Abstract:    "A guide",
+		Content:     "hello",
+		CreatedAt:   testTime,
+		UpdatedAt:   testTime,
+	}
+	if err := s.CreateContext(ctx, c); err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+
+	got, err := s.GetContext(ctx, c.ID)
+	if err != nil || got == nil {
+		t.Fatalf("failed to get context: %v", err)
+	}
+	if got.URI != c.URI || !got.IsLeaf || got.Content != "hello" {
+		t.Errorf("unexpected context %+v", got)
+	}
+	if !got.CreatedAt.Equal(testTime) {
+		t.Errorf("expected created_at %v, got %v", testTime, got.CreatedAt)
+	}
+
+	exists, err := s.ContextExists(ctx, c.ID)
+	if err != nil || !exists {
+		t.Errorf("expected context to exist, got %v, %v", exists, err)
+	}
+
+	c.Description = "updated"
+	c.IsLeaf = false
+	if err := s.UpdateContext(ctx, c); err != nil {
+		t.Fatalf("failed to update context: %v", err)
+	}
+	got, _ = s.GetContext(ctx, c.ID)
+	if got.Description != "updated" || got.IsLeaf {
+		t.Errorf("expected updated context, got %+v", got)
+	}
+
+	if err := s.DeleteContext(ctx, c.ID); err != nil {
+		t.Fatalf("failed to delete context: %v", err)
+	}
+	if got, err := s.GetContext(ctx, c.ID); err != nil || got != nil {
+		t.Errorf("expected no context after delete, got %+v, %v", got, err)
+	}
+}
+
+func TestPostgresStorage_QueryFilters(t *testing.T) {
+	s := newTestPostgres(t)
+	ctx := context.Background()
+
+	for i, uri := range []string{"viking://resources/a", "viking://resources/b", "viking://user/memories/c"} {
+		c := &Context{
+			ID:        fmt.Sprintf("ctx-%d", i),
+			URI:       uri,
+			Type:      ContextTypeFile,
+			Name:      fmt.Sprintf("Context %d", i),
+			CreatedAt: testTime.Add(time.Duration(i) * time.Hour),
+			UpdatedAt: testTime,
+		}
+		if err := s.CreateContext(ctx, c); err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+	}
+
+	// Several conditions exercise the $n placeholder numbering
+	contexts, err := s.QueryContexts(ctx, QueryOptions{
+		Filter: &Filter{Op: "and", Conds: []FilterCondition{
+			{Op: "prefix", Field: "uri", Prefix: "VIKING://resources/"},
+			{Op: "range", Field: "created_at", GTE: testTime, LT: testTime.Add(2 * time.Hour)},
+			{Op: "contains", Field: "name", Substr: "context"},
+		}},
+		OrderBy:   "uri",
+		OrderDesc: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(contexts) != 2 || contexts[0].URI != "viking://resources/b" {
+		t.Errorf("expected resources b and a, got %+v", contexts)
+	}
+
+	// IN expands to one placeholder per value, numbered with the rest
+	contexts, err = s.QueryContexts(ctx, QueryOptions{
+		Filter: &Filter{Conds: []FilterCondition{
+			{Op: "in", Field: "id", Value: []string{"ctx-0", "ctx-1", "ctx-2"}},
+			{Op: "not", Field: "id", Value: "ctx-1"},
+		}},
+		OrderBy: "id",
+	})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(contexts) != 2 || contexts[0].ID != "ctx-0" || contexts[1].ID != "ctx-2" {
+		t.Errorf("expected ctx-0 and ctx-2, got %+v", contexts)
+	}
+
+	contexts, err = s.QueryContexts(ctx, QueryOptions{OrderBy: "uri", Limit: 1, Offset: 2})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(contexts) != 1 || contexts[0].URI != "viking://user/memories/c" {
+		t.Errorf("expected the third context, got %+v", contexts)
+	}
+}
+
+func TestPostgresStorage_SessionsAndMemories(t *testing.T) {
+	s := newTestPostgres(t)
+	ctx := context.Background()
+
+	session := &Session{ID: "1", SessionID: "sess-1", UserID: "alice", TotalTurns: 3, CreatedAt: testTime, UpdatedAt: testTime}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	for i, content := range []string{"hi", "hello"} {
+		msg := &SessionMessage{ID: fmt.Sprintf("m%d", i), SessionID: "sess-1", Role: "user", Content: content, OrderIndex: int64(i), CreatedAt: testTime}
+		if err := s.CreateSessionMessage(ctx, msg); err != nil {
+			t.Fatalf("failed to create message: %v", err)
+		}
+	}
+	memory := &Memory{ID: "mem-1", SessionID: "sess-1", UserID: "alice", Content: "likes tea", Importance: 0.7, CreatedAt: testTime, UpdatedAt: testTime}
+	if err := s.CreateMemory(ctx, memory); err != nil {
+		t.Fatalf("failed to create memory: %v", err)
+	}
+
+	sessions, err := s.QuerySessions(ctx, QueryOptions{Filter: &Filter{Conds: []FilterCondition{{Op: "must", Field: "user_id", Value: "alice"}}}})
+	if err != nil || len(sessions) != 1 || sessions[0].TotalTurns != 3 {
+		t.Errorf("expected alice's session, got %+v, %v", sessions, err)
+	}
+	messages, err := s.GetSessionMessages(ctx, "sess-1")
+	if err != nil || len(messages) != 2 || messages[1].Content != "hello" {
+		t.Errorf("expected 2 ordered messages, got %+v, %v", messages, err)
+	}
+	memories, err := s.QueryMemories(ctx, QueryOptions{Filter: &Filter{Conds: []FilterCondition{{Op: "range", Field: "importance", GTE: 0.5}}}})
+	if err != nil || len(memories) != 1 || memories[0].Content != "likes tea" {
+		t.Errorf("expected the important memory, got %+v, %v", memories, err)
+	}
+
+	usage := &Usage{ID: "u1", SessionID: "sess-1", URI: "viking://resources/a", Type: "context", Success: true, Timestamp: testTime}
+	if err := s.CreateUsage(ctx, usage); err != nil {
+		t.Fatalf("failed to create usage: %v", err)
+	}
+	usages, err := s.QueryUsage(ctx, QueryOptions{})
+	if err != nil || len(usages) != 1 || !usages[0].Success {
+		t.Errorf("expected a successful usage record, got %+v, %v", usages, err)
+	}
+
+	entry := &AuditEntry{ID: "a1", Actor: "alice", Action: "delete", EntityType: "memory", EntityID: "mem-1", Timestamp: testTime}
+	if err := s.CreateAudit(ctx, entry); err != nil {
+		t.Fatalf("failed to create audit entry: %v", err)
+	}
+	entries, err := s.QueryAudit(ctx, QueryOptions{Filter: &Filter{Conds: []FilterCondition{{Op: "must", Field: "actor", Value: "alice"}}}})
+	if err != nil || len(entries) != 1 || entries[0].EntityID != "mem-1" || entries[0].Diff != "" {
+		t.Errorf("expected alice's audit entry, got %+v, %v", entries, err)
+	}
+
+	relation := &RelationEntry{ID: "r1", URIs: "viking://resources/a,viking://resources/b", Reason: "related", CreatedAt: testTime}
+	if err := s.CreateRelation(ctx, relation); err != nil {
+		t.Fatalf("failed to create relation: %v", err)
+	}
+	relations, err := s.QueryRelations(ctx, "viking://resources/b")
+	if err != nil || len(relations) != 1 {
+		t.Errorf("expected 1 relation, got %+v, %v", relations, err)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jqnote/goviking/pkg/utils"
 )
 
 var (
@@ -20,6 +22,11 @@ var (
 	ErrMessageNotFound = errors.New("message not found")
 	// ErrDependencyNotMet is returned when dependency is not met.
 	ErrDependencyNotMet = errors.New("dependency not met")
+	// ErrCircularDependency is returned when a message's dependencies
+	// lead back to the message itself.
+	ErrCircularDependency = errors.New("circular dependency")
+	// ErrMessageExists is returned when a message ID is already in use.
+	ErrMessageExists = errors.New("message already exists")
 )
 
 // Message represents a queue message.
@@ -29,6 +36,12 @@ type Message struct {
 	Content    string         `json:"content"`
 	Payload    map[string]any `json:"payload"`
 	Dependencies []string      `json:"dependencies"`
+	// Priority orders ready messages; higher is dequeued first
+	Priority   int            `json:"priority"`
+	// MaxRetries is how many times a failed message is re-enqueued before
+	// it moves to the dead-letter queue; RetryCount counts the failures
+	MaxRetries int            `json:"max_retries"`
+	RetryCount int            `json:"retry_count"`
 	Status     MessageStatus  `json:"status"`
 	CreatedAt  time.Time      `json:"created_at"`
 	ProcessedAt *time.Time     `json:"processed_at,omitempty"`
@@ -44,12 +57,28 @@ const (
 	MessageStatusFailed    MessageStatus = "failed"
 )
 
+// DefaultMaxCompleted is how many completed messages a QueueManager
+// remembers by default once they have left their queue.
+const DefaultMaxCompleted = 10000
+
+// DeadLetterSuffix is appended to a queue's name to name its dead-letter
+// queue, which holds messages that failed more than MaxRetries times.
+const DeadLetterSuffix = ".dlq"
+
 // QueueManager manages message queues.
 type QueueManager struct {
 	queues    map[string]*Queue
 	mu        sync.RWMutex
 	handlers  map[string]MessageHandler
 	processor *MessageProcessor
+	clock     utils.Clock
+	// inflight holds dequeued messages until they are completed or failed
+	inflight map[string]*Message
+	// completed holds the IDs of completed messages no longer in a queue,
+	// up to maxCompleted of them; completedOrder lists them oldest first
+	completed      map[string]bool
+	completedOrder []string
+	maxCompleted   int
 }
 
 // Queue represents a message queue.
@@ -68,7 +97,51 @@ func NewQueueManager() *QueueManager {
 	return &QueueManager{
 		queues:   make(map[string]*Queue),
 		handlers: make(map[string]MessageHandler),
+		clock:    utils.RealClock{},
+		inflight: make(map[string]*Message),
+		completed: make(map[string]bool),
+		maxCompleted: DefaultMaxCompleted,
+	}
+}
+
+// SetMaxCompleted sets how many completed messages are remembered once
+// they have left their queue, the oldest being forgotten first. New
+// messages cannot depend on a forgotten message. 0 remembers them all.
+func (qm *QueueManager) SetMaxCompleted(n int) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+	qm.maxCompleted = n
+	qm.trimCompleted()
+}
+
+// rememberCompleted records that the message with the given ID completed
+// after leaving its queue. The caller must hold the lock.
+func (qm *QueueManager) rememberCompleted(id string) {
+	if qm.completed[id] {
+		return
 	}
+	qm.completed[id] = true
+	qm.completedOrder = append(qm.completedOrder, id)
+	qm.trimCompleted()
+}
+
+// trimCompleted forgets the oldest completed messages beyond maxCompleted.
+// The caller must hold the lock.
+func (qm *QueueManager) trimCompleted() {
+	if qm.maxCompleted <= 0 {
+		return
+	}
+	for len(qm.completedOrder) > qm.maxCompleted {
+		delete(qm.completed, qm.completedOrder[0])
+		qm.completedOrder = qm.completedOrder[1:]
+	}
+}
+
+// SetClock sets the clock used to timestamp queues and messages.
+func (qm *QueueManager) SetClock(clock utils.Clock) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+	qm.clock = clock
 }
 
 // CreateQueue creates a new queue.
@@ -80,17 +153,28 @@ func (qm *QueueManager) CreateQueue(ctx context.Context, name string) error {
 		return fmt.Errorf("queue %s already exists", name)
 	}
 
-	qm.queues[name] = &Queue{
+	qm.createQueue(name)
+	return nil
+}
+
+// createQueue adds an empty queue. The caller must hold the lock.
+func (qm *QueueManager) createQueue(name string) *Queue {
+	q := &Queue{
 		Name:      name,
 		Messages:  make([]*Message, 0),
 		MaxSize:   1000, // Default max size
-		CreatedAt: time.Now(),
+		CreatedAt: qm.clock.Now(),
 	}
-
-	return nil
+	qm.queues[name] = q
+	return q
 }
 
-// Enqueue adds a message to a queue.
+// Enqueue adds a message to a queue, giving it an ID unless it has one;
+// an ID already in use is rejected with ErrMessageExists. Each dependency
+// must be a message that is queued, in flight or completed, or Enqueue
+// returns ErrDependencyNotMet; Dequeue then holds the message back until
+// its dependencies complete. Dependencies leading back to the message are
+// rejected with ErrCircularDependency.
 func (qm *QueueManager) Enqueue(ctx context.Context, queue string, msg *Message) error {
 	qm.mu.Lock()
 	defer qm.mu.Unlock()
@@ -100,26 +184,79 @@ func (qm *QueueManager) Enqueue(ctx context.Context, queue string, msg *Message)
 		return ErrQueueNotFound
 	}
 
-	msg.ID = uuid.New().String()
-	msg.Queue = queue
-	msg.Status = MessageStatusPending
-	msg.CreatedAt = time.Now()
-
-	// Check dependencies
-	if len(msg.Dependencies) > 0 {
-		if !qm.dependenciesMet(ctx, msg.Dependencies) {
-			return ErrDependencyNotMet
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	} else if qm.knownMessage(msg.ID) {
+		return fmt.Errorf("%w: %s", ErrMessageExists, msg.ID)
+	}
+	if cycle := qm.dependencyCycle(msg); cycle != nil {
+		return fmt.Errorf("%w: %s", ErrCircularDependency, strings.Join(cycle, " -> "))
+	}
+	for _, dep := range msg.Dependencies {
+		if !qm.knownMessage(dep) {
+			return fmt.Errorf("%w: unknown message %s", ErrDependencyNotMet, dep)
 		}
 	}
+	msg.Queue = queue
+	msg.Status = MessageStatusPending
+	msg.CreatedAt = qm.clock.Now()
 
 	q.Messages = append(q.Messages, msg)
 	return nil
 }
 
+// knownMessage reports whether a message with the given ID is queued, in
+// flight or remembered as completed. The caller must hold the lock.
+func (qm *QueueManager) knownMessage(id string) bool {
+	if qm.completed[id] {
+		return true
+	}
+	msg, _, _ := qm.findMessage(id)
+	return msg != nil
+}
+
+// dependencyCycle returns the path of a dependency cycle through msg,
+// starting and ending with its ID, or nil when its dependencies do not
+// lead back to it. The caller must hold the lock.
+func (qm *QueueManager) dependencyCycle(msg *Message) []string {
+	deps := make(map[string][]string)
+	for _, q := range qm.queues {
+		for _, m := range q.Messages {
+			deps[m.ID] = m.Dependencies
+		}
+	}
+	for id, m := range qm.inflight {
+		deps[id] = m.Dependencies
+	}
+	deps[msg.ID] = msg.Dependencies
+
+	// Depth-first search from msg; visited nodes are known not to lead
+	// back to it
+	visited := make(map[string]bool)
+	var walk func(id string, path []string) []string
+	walk = func(id string, path []string) []string {
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			if dep == msg.ID {
+				return append(path, dep)
+			}
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if cycle := walk(dep, path); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(msg.ID, nil)
+}
+
 // dependenciesMet checks if all dependencies are met.
 func (qm *QueueManager) dependenciesMet(ctx context.Context, deps []string) bool {
 	for _, depID := range deps {
-		found := false
+		found := qm.completed[depID]
 		for _, q := range qm.queues {
 			for _, msg := range q.Messages {
 				if msg.ID == depID && msg.Status == MessageStatusCompleted {
@@ -138,7 +275,9 @@ func (qm *QueueManager) dependenciesMet(ctx context.Context, deps []string) bool
 	return true
 }
 
-// Dequeue removes and returns a message from the queue.
+// Dequeue removes and returns the highest-priority ready message from the
+// queue: pending, with its dependencies completed. Messages of equal
+// priority are dequeued oldest first.
 func (qm *QueueManager) Dequeue(ctx context.Context, queue string) (*Message, error) {
 	qm.mu.Lock()
 	defer qm.mu.Unlock()
@@ -148,19 +287,38 @@ func (qm *QueueManager) Dequeue(ctx context.Context, queue string) (*Message, er
 		return nil, ErrQueueNotFound
 	}
 
-	// Find next ready message
+	// Find the best ready message
+	next := -1
 	for i, msg := range q.Messages {
-		if msg.Status == MessageStatusPending {
-			if len(msg.Dependencies) == 0 || qm.dependenciesMet(ctx, msg.Dependencies) {
-				msg.Status = MessageStatusProcessing
-				// Remove from queue
-				q.Messages = append(q.Messages[:i], q.Messages[i+1:]...)
-				return msg, nil
-			}
+		if msg.Status != MessageStatusPending {
+			continue
+		}
+		if next >= 0 && !dequeuesBefore(msg, q.Messages[next]) {
+			continue
+		}
+		if len(msg.Dependencies) == 0 || qm.dependenciesMet(ctx, msg.Dependencies) {
+			next = i
 		}
 	}
+	if next < 0 {
+		return nil, nil // No ready messages
+	}
+
+	msg := q.Messages[next]
+	msg.Status = MessageStatusProcessing
+	// Remove from queue
+	q.Messages = append(q.Messages[:next], q.Messages[next+1:]...)
+	qm.inflight[msg.ID] = msg
+	return msg, nil
+}
 
-	return nil, nil // No ready messages
+// dequeuesBefore reports whether a is dequeued ahead of b: it has a higher
+// priority, or the same priority and an earlier creation time.
+func dequeuesBefore(a, b *Message) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
 }
 
 // Complete marks a message as completed.
@@ -168,35 +326,73 @@ func (qm *QueueManager) Complete(ctx context.Context, msgID string) error {
 	qm.mu.Lock()
 	defer qm.mu.Unlock()
 
-	for _, q := range qm.queues {
-		for _, msg := range q.Messages {
-			if msg.ID == msgID {
-				now := time.Now()
-				msg.Status = MessageStatusCompleted
-				msg.ProcessedAt = &now
-				return nil
-			}
-		}
+	msg, _, _ := qm.findMessage(msgID)
+	if msg == nil {
+		return ErrMessageNotFound
 	}
-
-	return ErrMessageNotFound
+	now := qm.clock.Now()
+	msg.Status = MessageStatusCompleted
+	msg.ProcessedAt = &now
+	if _, ok := qm.inflight[msgID]; ok {
+		delete(qm.inflight, msgID)
+		qm.rememberCompleted(msgID)
+	}
+	return nil
 }
 
-// Fail marks a message as failed.
+// Fail records a failed attempt at a message. The message is re-enqueued
+// as pending until it has failed more than MaxRetries times; then it is
+// marked failed and moved to its queue's dead-letter queue, which is
+// created on demand. Messages failing in a dead-letter queue stay there.
 func (qm *QueueManager) Fail(ctx context.Context, msgID string) error {
 	qm.mu.Lock()
 	defer qm.mu.Unlock()
 
+	msg, q, index := qm.findMessage(msgID)
+	if msg == nil {
+		return ErrMessageNotFound
+	}
+	delete(qm.inflight, msgID)
+	msg.RetryCount++
+
+	if msg.RetryCount <= msg.MaxRetries {
+		msg.Status = MessageStatusPending
+	} else {
+		msg.Status = MessageStatusFailed
+		if !strings.HasSuffix(msg.Queue, DeadLetterSuffix) {
+			if q != nil {
+				q.Messages = append(q.Messages[:index], q.Messages[index+1:]...)
+				q = nil
+			}
+			msg.Queue += DeadLetterSuffix
+		}
+	}
+
+	// Put messages taken off their queue back on one
+	if q == nil {
+		if q = qm.queues[msg.Queue]; q == nil {
+			q = qm.createQueue(msg.Queue)
+		}
+		q.Messages = append(q.Messages, msg)
+	}
+	return nil
+}
+
+// findMessage returns the message with the given ID, together with its
+// queue and index there, or with a nil queue when it is in flight. The
+// caller must hold the lock.
+func (qm *QueueManager) findMessage(msgID string) (*Message, *Queue, int) {
 	for _, q := range qm.queues {
-		for _, msg := range q.Messages {
+		for i, msg := range q.Messages {
 			if msg.ID == msgID {
-				msg.Status = MessageStatusFailed
-				return nil
+				return msg, q, i
 			}
 		}
 	}
-
-	return ErrMessageNotFound
+	if msg, ok := qm.inflight[msgID]; ok {
+		return msg, nil, -1
+	}
+	return nil, nil, -1
 }
 
 // GetQueueSize returns the size of a queue.
@@ -287,7 +483,7 @@ func (mp *MessageProcessor) processLoop(ctx context.Context, workerID int) {
 					continue
 				}
 
-				// Process message
+				// Process message; failures are retried or dead-lettered
 				if err := handler(ctx, msg); err != nil {
 					mp.queueManager.Fail(ctx, msg.ID)
 				} else {
@@ -0,0 +1,444 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+func TestQueueManagerUsesClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := utils.NewFakeClock(start)
+
+	qm := NewQueueManager()
+	qm.SetClock(clock)
+	ctx := context.Background()
+
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	msg := &Message{}
+	if err := qm.Enqueue(ctx, "jobs", msg); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if !msg.CreatedAt.Equal(start) {
+		t.Errorf("Expected CreatedAt %v, got %v", start, msg.CreatedAt)
+	}
+
+	clock.Advance(time.Minute)
+	if err := qm.Complete(ctx, msg.ID); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if want := start.Add(time.Minute); msg.ProcessedAt == nil || !msg.ProcessedAt.Equal(want) {
+		t.Errorf("Expected ProcessedAt %v, got %v", want, msg.ProcessedAt)
+	}
+}
+
+func TestQueueManagerDequeuesByPriority(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	qm := NewQueueManager()
+	qm.SetClock(clock)
+	ctx := context.Background()
+
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	for _, m := range []struct {
+		content  string
+		priority int
+	}{
+		{"bulk-1", 0},
+		{"urgent-1", 10},
+		{"bulk-2", 0},
+		{"normal", 5},
+		{"urgent-2", 10},
+	} {
+		if err := qm.Enqueue(ctx, "jobs", &Message{Content: m.content, Priority: m.priority}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		clock.Advance(time.Second)
+	}
+
+	want := []string{"urgent-1", "urgent-2", "normal", "bulk-1", "bulk-2"}
+	for i, content := range want {
+		msg, err := qm.Dequeue(ctx, "jobs")
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if msg == nil || msg.Content != content {
+			t.Fatalf("Dequeue %d: expected %s, got %+v", i, content, msg)
+		}
+		if msg.Status != MessageStatusProcessing {
+			t.Errorf("Expected %s to be processing, got %s", content, msg.Status)
+		}
+	}
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg != nil {
+		t.Errorf("Expected an empty queue, got %+v", msg)
+	}
+}
+
+// processOne runs handler over a single message with up to maxRetries
+// retries and waits until the message is completed or dead-lettered. It
+// returns the number of attempts made.
+func processOne(t *testing.T, maxRetries int, handler MessageHandler) (*QueueManager, int32) {
+	t.Helper()
+	qm := NewQueueManager()
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{Content: "work", MaxRetries: maxRetries}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	var attempts, completed atomic.Int32
+	mp := NewMessageProcessor(qm, 1)
+	mp.RegisterHandler("jobs", func(ctx context.Context, msg *Message) error {
+		attempts.Add(1)
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		completed.Add(1)
+		return nil
+	})
+	mp.Start(ctx)
+	defer mp.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if dlq, _ := qm.GetQueueSize(ctx, "jobs"+DeadLetterSuffix); dlq > 0 || completed.Load() > 0 {
+			return qm, attempts.Load()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Message neither completed nor dead-lettered after %d attempts", attempts.Load())
+	return nil, 0
+}
+
+func TestQueueManagerRetriesFailedMessages(t *testing.T) {
+	failures := 0
+	qm, attempts := processOne(t, 3, func(ctx context.Context, msg *Message) error {
+		if failures < 2 {
+			failures++
+			return errors.New("transient failure")
+		}
+		if msg.RetryCount != 2 {
+			t.Errorf("expected retry count 2 on the third attempt, got %d", msg.RetryCount)
+		}
+		return nil
+	})
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if _, err := qm.GetQueueSize(context.Background(), "jobs"+DeadLetterSuffix); err != ErrQueueNotFound {
+		t.Errorf("expected no dead-letter queue, got %v", err)
+	}
+}
+
+func TestQueueManagerDeadLettersExhaustedMessages(t *testing.T) {
+	qm, attempts := processOne(t, 2, func(ctx context.Context, msg *Message) error {
+		return errors.New("permanent failure")
+	})
+
+	// The first attempt plus two retries
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	ctx := context.Background()
+	if size, _ := qm.GetQueueSize(ctx, "jobs"); size != 0 {
+		t.Errorf("expected the message gone from its queue, got %d left", size)
+	}
+	qm.mu.RLock()
+	dead := qm.queues["jobs"+DeadLetterSuffix].Messages
+	qm.mu.RUnlock()
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 dead-lettered message, got %d", len(dead))
+	}
+	if msg := dead[0]; msg.Status != MessageStatusFailed || msg.RetryCount != 3 || msg.Queue != "jobs.dlq" || msg.Content != "work" {
+		t.Errorf("expected a failed message after 3 failures in jobs.dlq, got %+v", msg)
+	}
+}
+
+func TestQueueManagerRejectsCircularDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		// waiting are restored from a snapshot, which may hold messages
+		// depending on ones not yet enqueued
+		waiting []*Message
+		msg     *Message
+		cycle   string // expected in the error; empty for none
+	}{
+		{
+			name:    "two messages",
+			waiting: []*Message{{ID: "b", Dependencies: []string{"a"}}},
+			msg:     &Message{ID: "a", Dependencies: []string{"b"}},
+			cycle:   "a -> b -> a",
+		},
+		{
+			name: "three messages",
+			waiting: []*Message{
+				{ID: "b", Dependencies: []string{"c"}},
+				{ID: "c", Dependencies: []string{"a"}},
+			},
+			msg:   &Message{ID: "a", Dependencies: []string{"b"}},
+			cycle: "a -> b -> c -> a",
+		},
+		{
+			name:  "self",
+			msg:   &Message{ID: "a", Dependencies: []string{"a"}},
+			cycle: "a -> a",
+		},
+		{
+			name: "diamond",
+			waiting: []*Message{
+				{ID: "root"},
+				{ID: "left", Dependencies: []string{"root"}},
+				{ID: "right", Dependencies: []string{"root"}},
+			},
+			msg: &Message{ID: "join", Dependencies: []string{"left", "right"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Alternate queues so cycles span them
+			snapshot := queueSnapshot{Queues: []queueState{{Name: "jobs"}, {Name: "other"}}}
+			for i, msg := range tt.waiting {
+				msg.Status = MessageStatusPending
+				snapshot.Queues[i%2].Messages = append(snapshot.Queues[i%2].Messages, msg)
+			}
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				t.Fatalf("Failed to encode snapshot: %v", err)
+			}
+			qm := NewQueueManager()
+			ctx := context.Background()
+			if err := qm.RestoreQueues(bytes.NewReader(data)); err != nil {
+				t.Fatalf("RestoreQueues failed: %v", err)
+			}
+
+			err = qm.Enqueue(ctx, "jobs", tt.msg)
+			if tt.cycle == "" {
+				if err != nil {
+					t.Fatalf("Enqueue %s failed: %v", tt.msg.ID, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrCircularDependency) {
+				t.Fatalf("expected ErrCircularDependency for %s, got %v", tt.msg.ID, err)
+			}
+			if !strings.Contains(err.Error(), tt.cycle) {
+				t.Errorf("expected the cycle %q in %q", tt.cycle, err)
+			}
+			jobs, _ := qm.GetQueueSize(ctx, "jobs")
+			other, _ := qm.GetQueueSize(ctx, "other")
+			if jobs+other != len(tt.waiting) {
+				t.Errorf("expected %s not to be enqueued, got %d messages", tt.msg.ID, jobs+other)
+			}
+		})
+	}
+}
+
+func TestQueueManagerRejectsUnknownDependencies(t *testing.T) {
+	qm := NewQueueManager()
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	err := qm.Enqueue(ctx, "jobs", &Message{ID: "second", Dependencies: []string{"first"}})
+	if !errors.Is(err, ErrDependencyNotMet) {
+		t.Fatalf("expected ErrDependencyNotMet for an unknown dependency, got %v", err)
+	}
+	if size, _ := qm.GetQueueSize(ctx, "jobs"); size != 0 {
+		t.Errorf("expected nothing enqueued, got %d messages", size)
+	}
+}
+
+func TestQueueManagerRejectsDuplicateIDs(t *testing.T) {
+	qm := NewQueueManager()
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	if err := qm.CreateQueue(ctx, "other"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "a"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "other", &Message{ID: "a"}); !errors.Is(err, ErrMessageExists) {
+		t.Errorf("expected ErrMessageExists for a queued ID, got %v", err)
+	}
+
+	// In flight and completed IDs stay taken
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg == nil {
+		t.Fatal("expected a to be dequeued")
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "a"}); !errors.Is(err, ErrMessageExists) {
+		t.Errorf("expected ErrMessageExists for an in-flight ID, got %v", err)
+	}
+	if err := qm.Complete(ctx, "a"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "a"}); !errors.Is(err, ErrMessageExists) {
+		t.Errorf("expected ErrMessageExists for a completed ID, got %v", err)
+	}
+}
+
+func TestQueueManagerForgetsOldestCompleted(t *testing.T) {
+	qm := NewQueueManager()
+	qm.SetMaxCompleted(2)
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := qm.Enqueue(ctx, "jobs", &Message{ID: id}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		if msg, _ := qm.Dequeue(ctx, "jobs"); msg == nil || msg.ID != id {
+			t.Fatalf("expected %s, got %+v", id, msg)
+		}
+		if err := qm.Complete(ctx, id); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+	}
+
+	if len(qm.completed) != 2 || qm.completed["a"] {
+		t.Errorf("expected only b and c remembered, got %v", qm.completed)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "after-a", Dependencies: []string{"a"}}); !errors.Is(err, ErrDependencyNotMet) {
+		t.Errorf("expected ErrDependencyNotMet for a forgotten dependency, got %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "after-c", Dependencies: []string{"c"}}); err != nil {
+		t.Errorf("expected a remembered dependency to be met, got %v", err)
+	}
+}
+
+func TestQueueManagerWaitsForDependencies(t *testing.T) {
+	qm := NewQueueManager()
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "first"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "second", Dependencies: []string{"first"}, Priority: 10}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	msg, _ := qm.Dequeue(ctx, "jobs")
+	if msg == nil || msg.ID != "first" {
+		t.Fatalf("expected first while second waits on it, got %+v", msg)
+	}
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg != nil {
+		t.Fatalf("expected second to wait until first completes, got %+v", msg)
+	}
+	if err := qm.Complete(ctx, "first"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg == nil || msg.ID != "second" {
+		t.Errorf("expected second once first completed, got %+v", msg)
+	}
+}
+
+func TestQueueManagerSnapshotRoundTrip(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	qm := NewQueueManager()
+	qm.SetClock(clock)
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	for _, msg := range []*Message{
+		{ID: "done", Content: "done"},
+		{ID: "busy", Content: "busy", Priority: 5},
+		{ID: "waiting", Content: "waiting", Dependencies: []string{"done"}, Payload: map[string]any{"n": "1"}},
+	} {
+		clock.Advance(time.Second)
+		if err := qm.Enqueue(ctx, "jobs", msg); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	// busy is dequeued first; done is completed, busy is left processing
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg == nil || msg.ID != "busy" {
+		t.Fatalf("expected busy, got %+v", msg)
+	}
+	if msg, _ := qm.Dequeue(ctx, "jobs"); msg == nil || msg.ID != "done" {
+		t.Fatalf("expected done, got %+v", msg)
+	}
+	if err := qm.Complete(ctx, "done"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := qm.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	restored := NewQueueManager()
+	if err := restored.RestoreQueues(strings.NewReader(buf.String())); err != nil {
+		t.Fatalf("RestoreQueues failed: %v", err)
+	}
+
+	if size, _ := restored.GetQueueSize(ctx, "jobs"); size != 2 {
+		t.Errorf("expected busy and waiting restored, got %d messages", size)
+	}
+	msg, _ := restored.Dequeue(ctx, "jobs")
+	if msg == nil || msg.ID != "busy" || msg.Status != MessageStatusProcessing {
+		t.Fatalf("expected busy retried after restore, got %+v", msg)
+	}
+	msg, _ = restored.Dequeue(ctx, "jobs")
+	if msg == nil || msg.ID != "waiting" {
+		t.Fatalf("expected waiting with its dependency met, got %+v", msg)
+	}
+	if msg.Payload["n"] != "1" || len(msg.Dependencies) != 1 || !msg.CreatedAt.Equal(time.Date(2026, 1, 1, 0, 0, 3, 0, time.UTC)) {
+		t.Errorf("expected fields preserved, got %+v", msg)
+	}
+}
+
+func TestQueueAutoSaverSavesWhenDue(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	qm := NewQueueManager()
+	ctx := context.Background()
+	if err := qm.CreateQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("CreateQueue failed: %v", err)
+	}
+	if err := qm.Enqueue(ctx, "jobs", &Message{ID: "a"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "queues", "state.json")
+	saver := NewQueueAutoSaver(qm, path, time.Minute)
+	saver.SetClock(clock)
+	if saved, err := saver.SaveIfDue(); saved || err != nil {
+		t.Fatalf("expected no save before the interval, got %v, %v", saved, err)
+	}
+	clock.Advance(time.Minute)
+	if saved, err := saver.SaveIfDue(); !saved || err != nil {
+		t.Fatalf("expected a save after the interval, got %v, %v", saved, err)
+	}
+
+	restored := NewQueueManager()
+	if err := restored.LoadQueues(path); err != nil {
+		t.Fatalf("LoadQueues failed: %v", err)
+	}
+	if size, _ := restored.GetQueueSize(ctx, "jobs"); size != 1 {
+		t.Errorf("expected 1 message loaded, got %d", size)
+	}
+	if err := restored.LoadQueues(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("expected a missing snapshot to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jqnote/goviking/pkg/utils"
+)
+
+// queueSnapshot is the JSON form of a QueueManager's state.
+type queueSnapshot struct {
+	Queues []queueState `json:"queues"`
+	// Completed lists completed messages no longer in a queue, oldest
+	// first, so dependencies on them stay met
+	Completed []string `json:"completed,omitempty"`
+}
+
+// queueState is the JSON form of one queue.
+type queueState struct {
+	Name      string     `json:"name"`
+	MaxSize   int        `json:"max_size"`
+	CreatedAt time.Time  `json:"created_at"`
+	Messages  []*Message `json:"messages"`
+}
+
+// Snapshot writes every queue with its messages to w as JSON. Dequeued
+// messages not yet completed or failed follow those still queued.
+func (qm *QueueManager) Snapshot(w io.Writer) error {
+	qm.mu.RLock()
+	snapshot := queueSnapshot{Queues: make([]queueState, 0, len(qm.queues))}
+	index := make(map[string]int, len(qm.queues))
+	for _, q := range qm.queues {
+		index[q.Name] = len(snapshot.Queues)
+		snapshot.Queues = append(snapshot.Queues, queueState{
+			Name:      q.Name,
+			MaxSize:   q.MaxSize,
+			CreatedAt: q.CreatedAt,
+			Messages:  append([]*Message(nil), q.Messages...),
+		})
+	}
+	inflight := make([]*Message, 0, len(qm.inflight))
+	for _, msg := range qm.inflight {
+		inflight = append(inflight, msg)
+	}
+	sort.SliceStable(inflight, func(i, j int) bool { return inflight[i].CreatedAt.Before(inflight[j].CreatedAt) })
+	for _, msg := range inflight {
+		i, ok := index[msg.Queue]
+		if !ok {
+			i = len(snapshot.Queues)
+			index[msg.Queue] = i
+			snapshot.Queues = append(snapshot.Queues, queueState{Name: msg.Queue, CreatedAt: msg.CreatedAt})
+		}
+		snapshot.Queues[i].Messages = append(snapshot.Queues[i].Messages, msg)
+	}
+	snapshot.Completed = append(snapshot.Completed, qm.completedOrder...)
+
+	// Sort for stable output
+	sort.Slice(snapshot.Queues, func(i, j int) bool { return snapshot.Queues[i].Name < snapshot.Queues[j].Name })
+
+	// Encode under the lock so messages do not change underneath
+	defer qm.mu.RUnlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snapshot)
+}
+
+// RestoreQueues replaces every queue with those of a snapshot written by
+// Snapshot. Messages that were being processed are restored as pending so
+// they are retried.
+func (qm *QueueManager) RestoreQueues(r io.Reader) error {
+	var snapshot queueSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode queue snapshot: %w", err)
+	}
+
+	queues := make(map[string]*Queue, len(snapshot.Queues))
+	for _, state := range snapshot.Queues {
+		if state.Name == "" {
+			return fmt.Errorf("invalid queue snapshot: queue without a name")
+		}
+		if state.MaxSize <= 0 {
+			state.MaxSize = 1000
+		}
+		q := &Queue{
+			Name:      state.Name,
+			Messages:  make([]*Message, 0, len(state.Messages)),
+			MaxSize:   state.MaxSize,
+			CreatedAt: state.CreatedAt,
+		}
+		for _, msg := range state.Messages {
+			if msg == nil {
+				continue
+			}
+			msg.Queue = state.Name
+			if msg.Status == MessageStatusProcessing {
+				msg.Status = MessageStatusPending
+			}
+			q.Messages = append(q.Messages, msg)
+		}
+		queues[state.Name] = q
+	}
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+	qm.queues = queues
+	qm.inflight = make(map[string]*Message)
+	qm.completed = make(map[string]bool, len(snapshot.Completed))
+	qm.completedOrder = nil
+	for _, id := range snapshot.Completed {
+		qm.rememberCompleted(id)
+	}
+	return nil
+}
+
+// SaveQueues writes a snapshot to path, replacing any previous one only
+// once the new one is complete.
+func (qm *QueueManager) SaveQueues(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create queue snapshot directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create queue snapshot: %w", err)
+	}
+	if err := qm.Snapshot(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write queue snapshot: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadQueues restores the snapshot at path. A missing file leaves the
+// queues as they are.
+func (qm *QueueManager) LoadQueues(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open queue snapshot: %w", err)
+	}
+	defer f.Close()
+	return qm.RestoreQueues(f)
+}
+
+// QueueAutoSaver periodically saves a QueueManager's queues to a file, like
+// core.AutoSaver does for tiered contexts.
+type QueueAutoSaver struct {
+	qm       *QueueManager
+	path     string
+	interval time.Duration
+	clock    utils.Clock
+	nextSave time.Time
+	mu       sync.Mutex
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewQueueAutoSaver creates a QueueAutoSaver saving qm to path every
+// interval.
+func NewQueueAutoSaver(qm *QueueManager, path string, interval time.Duration) *QueueAutoSaver {
+	clock := utils.RealClock{}
+	return &QueueAutoSaver{
+		qm:       qm,
+		path:     path,
+		interval: interval,
+		clock:    clock,
+		nextSave: clock.Now().Add(interval),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// SetClock sets the clock used to decide when a save is due. The next save
+// is rescheduled one interval after the clock's current time.
+func (as *QueueAutoSaver) SetClock(clock utils.Clock) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.clock = clock
+	as.nextSave = clock.Now().Add(as.interval)
+}
+
+// SaveIfDue saves when at least one interval has passed since the last
+// scheduled save, reporting whether it saved.
+func (as *QueueAutoSaver) SaveIfDue() (bool, error) {
+	as.mu.Lock()
+	now := as.clock.Now()
+	if now.Before(as.nextSave) {
+		as.mu.Unlock()
+		return false, nil
+	}
+	// Keep to the original schedule unless saves fell behind
+	as.nextSave = as.nextSave.Add(as.interval)
+	if !as.nextSave.After(now) {
+		as.nextSave = now.Add(as.interval)
+	}
+	as.mu.Unlock()
+
+	return true, as.qm.SaveQueues(as.path)
+}
+
+// Start starts saving in the background.
+func (as *QueueAutoSaver) Start() {
+	as.mu.Lock()
+	as.nextSave = as.clock.Now().Add(as.interval)
+	as.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(as.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := as.SaveIfDue(); err != nil {
+					log.Printf("queue autosave: %v", err)
+				}
+			case <-as.stopCh:
+				// Do a final save before stopping
+				if err := as.qm.SaveQueues(as.path); err != nil {
+					log.Printf("queue autosave: final save: %v", err)
+				}
+				close(as.doneCh)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops saving after a final save.
+func (as *QueueAutoSaver) Stop() error {
+	close(as.stopCh)
+	<-as.doneCh
+	return nil
+}
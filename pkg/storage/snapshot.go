@@ -0,0 +1,158 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// ContextSnapshot is a serialized bundle of contexts, such as a session's
+// tiered context, stored under a key.
+type ContextSnapshot struct {
+	Key       string
+	Data      []byte
+	UpdatedAt time.Time
+}
+
+// SnapshotStore is implemented by storage backends that keep context
+// snapshots in the context_snapshots table.
+type SnapshotStore interface {
+	// SaveSnapshot creates or replaces the snapshot with the same key.
+	SaveSnapshot(ctx context.Context, snapshot *ContextSnapshot) error
+	// GetSnapshot returns the snapshot stored under key, or nil when
+	// there is none.
+	GetSnapshot(ctx context.Context, key string) (*ContextSnapshot, error)
+	DeleteSnapshot(ctx context.Context, key string) error
+}
+
+// SaveSnapshot creates or replaces the snapshot with the same key.
+func (s *SQLiteStorage) SaveSnapshot(ctx context.Context, snapshot *ContextSnapshot) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO context_snapshots (key, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		snapshot.Key, snapshot.Data, timeToString(snapshot.UpdatedAt))
+	return err
+}
+
+// GetSnapshot returns the snapshot stored under key, or nil when there is
+// none.
+func (s *SQLiteStorage) GetSnapshot(ctx context.Context, key string) (*ContextSnapshot, error) {
+	snapshot := &ContextSnapshot{Key: key}
+	var updatedAt string
+	err := s.db.QueryRowContext(ctx, "SELECT data, updated_at FROM context_snapshots WHERE key = ?", key).Scan(&snapshot.Data, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot.UpdatedAt = parseTime(updatedAt)
+	return snapshot, nil
+}
+
+// DeleteSnapshot removes the snapshot stored under key.
+func (s *SQLiteStorage) DeleteSnapshot(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM context_snapshots WHERE key = ?", key)
+	return err
+}
+
+// SaveSnapshot creates or replaces the snapshot with the same key.
+func (s *PostgresStorage) SaveSnapshot(ctx context.Context, snapshot *ContextSnapshot) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO context_snapshots (key, data, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`,
+		snapshot.Key, snapshot.Data, snapshot.UpdatedAt)
+	return err
+}
+
+// GetSnapshot returns the snapshot stored under key, or nil when there is
+// none.
+func (s *PostgresStorage) GetSnapshot(ctx context.Context, key string) (*ContextSnapshot, error) {
+	snapshot := &ContextSnapshot{Key: key}
+	err := s.db.QueryRowContext(ctx, "SELECT data, updated_at FROM context_snapshots WHERE key = $1", key).Scan(&snapshot.Data, &snapshot.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// DeleteSnapshot removes the snapshot stored under key.
+func (s *PostgresStorage) DeleteSnapshot(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM context_snapshots WHERE key = $1", key)
+	return err
+}
+
+// SnapshotBackend persists context bundles in a SnapshotStore, so a
+// session's tiered context lives in the same database as everything else.
+// It implements core.PersistenceBackend.
+type SnapshotBackend struct {
+	store SnapshotStore
+}
+
+// NewSnapshotBackend creates a persistence backend over store.
+func NewSnapshotBackend(store SnapshotStore) *SnapshotBackend {
+	return &SnapshotBackend{store: store}
+}
+
+// Save stores data under key.
+func (b *SnapshotBackend) Save(key string, data []byte) error {
+	snapshot := &ContextSnapshot{Key: key, Data: data, UpdatedAt: time.Now().UTC()}
+	if err := b.store.SaveSnapshot(context.Background(), snapshot); err != nil {
+		return fmt.Errorf("failed to save context snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load returns the data stored under key.
+func (b *SnapshotBackend) Load(key string) ([]byte, error) {
+	snapshot, err := b.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Data, nil
+}
+
+// Delete removes the data stored under key.
+func (b *SnapshotBackend) Delete(key string) error {
+	if err := b.store.DeleteSnapshot(context.Background(), key); err != nil {
+		return fmt.Errorf("failed to delete context snapshot: %w", err)
+	}
+	return nil
+}
+
+// Exists reports whether data is stored under key.
+func (b *SnapshotBackend) Exists(key string) (bool, error) {
+	snapshot, err := b.store.GetSnapshot(context.Background(), key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read context snapshot: %w", err)
+	}
+	return snapshot != nil, nil
+}
+
+// LastModified returns when the data under key was last saved.
+func (b *SnapshotBackend) LastModified(key string) (time.Time, error) {
+	snapshot, err := b.get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return snapshot.UpdatedAt, nil
+}
+
+// get returns the snapshot under key, or an error wrapping fs.ErrNotExist
+// when there is none.
+func (b *SnapshotBackend) get(key string) (*ContextSnapshot, error) {
+	snapshot, err := b.store.GetSnapshot(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read context snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("context snapshot %s: %w", key, fs.ErrNotExist)
+	}
+	return snapshot, nil
+}
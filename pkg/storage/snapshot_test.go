@@ -0,0 +1,89 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/jqnote/goviking/pkg/core"
+)
+
+func TestSnapshotBackend_RoundTripsTieredContext(t *testing.T) {
+	storage := newTestStorage(t)
+	backend := NewSnapshotBackend(storage)
+
+	tc := core.NewTieredContext()
+	for _, seed := range []struct {
+		uri  string
+		tier core.ContextTier
+	}{
+		{"viking://user/memories/profile", core.TierL0},
+		{"viking://resources/docs/guide", core.TierL1},
+		{"viking://resources/docs/archive", core.TierL2},
+	} {
+		ctx := core.NewContext(seed.uri)
+		ctx.Tier = seed.tier
+		ctx.Abstract = "abstract of " + seed.uri
+		ctx.Meta = map[string]any{"source": "test"}
+		tc.Add(ctx)
+	}
+
+	handler := core.NewPersistenceHandler(&core.PersistenceConfig{Backend: backend}, tc, "sess-1")
+	if handler.Exists() {
+		t.Fatal("expected no snapshot before saving")
+	}
+	if err := handler.Save(); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if !handler.Exists() {
+		t.Fatal("expected a snapshot after saving")
+	}
+	if modified, err := handler.GetLastModified(); err != nil || modified.IsZero() {
+		t.Errorf("expected a modification time, got %v, %v", modified, err)
+	}
+
+	restored := core.NewTieredContext()
+	if err := core.NewPersistenceHandler(&core.PersistenceConfig{Backend: backend}, restored, "sess-1").Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if restored.Count() != 3 {
+		t.Fatalf("expected 3 contexts, got %d", restored.Count())
+	}
+	for _, want := range tc.GetAll() {
+		got := restored.GetByURI(want.URI)
+		if got == nil {
+			t.Fatalf("expected %s to be restored", want.URI)
+		}
+		if got.Tier != want.Tier || got.Abstract != want.Abstract || got.Meta["source"] != "test" {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	}
+
+	// Other sessions are stored under their own key
+	other := core.NewTieredContext()
+	if err := core.NewPersistenceHandler(&core.PersistenceConfig{Backend: backend}, other, "sess-2").Load(); err != nil {
+		t.Fatalf("expected loading a missing snapshot to succeed, got %v", err)
+	}
+	if other.Count() != 0 {
+		t.Errorf("expected no contexts for another session, got %d", other.Count())
+	}
+
+	if err := handler.Delete(); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if handler.Exists() {
+		t.Error("expected the snapshot to be deleted")
+	}
+	if _, err := backend.Load("sess-1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist after delete, got %v", err)
+	}
+	if err := handler.Delete(); err != nil {
+		t.Errorf("expected deleting a missing snapshot to succeed, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -17,6 +18,13 @@ import (
 type SQLiteStorage struct {
 	db *sql.DB
 	cfg Config
+
+	// content holds large context content outside the contexts table;
+	// nil keeps all content inline
+	content ContentStore
+
+	// fts is set when the contexts_fts full-text index is available
+	fts bool
 }
 
 // NewSQLiteStorage creates a new SQLite storage instance.
@@ -40,6 +48,9 @@ func NewSQLiteStorage(cfg Config) (*SQLiteStorage, error) {
 		db:  db,
 		cfg: cfg,
 	}
+	if cfg.ContentDir != "" {
+		storage.content = NewFileContentStore(cfg.ContentDir)
+	}
 
 	// Initialize schema
 	if err := storage.initSchema(); err != nil {
@@ -65,6 +76,9 @@ func (s *SQLiteStorage) initSchema() error {
 			tags TEXT,
 			abstract TEXT,
 			active_count INTEGER DEFAULT 0,
+			content TEXT DEFAULT '',
+			content_ref TEXT DEFAULT '',
+			content_checksum TEXT DEFAULT '',
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		)`,
@@ -83,6 +97,7 @@ func (s *SQLiteStorage) initSchema() error {
 			skills_used INTEGER DEFAULT 0,
 			memoies_extracted INTEGER DEFAULT 0,
 			summary TEXT,
+			metadata TEXT DEFAULT '',
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		)`,
@@ -107,6 +122,8 @@ func (s *SQLiteStorage) initSchema() error {
 			content TEXT NOT NULL,
 			importance REAL DEFAULT 0.0,
 			tags TEXT,
+			source_message_ids TEXT DEFAULT '',
+			evidence TEXT DEFAULT '',
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		)`,
@@ -139,13 +156,33 @@ func (s *SQLiteStorage) initSchema() error {
 		`CREATE INDEX IF NOT EXISTS idx_usage_records_session_id ON usage_records(session_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_usage_records_uri ON usage_records(uri)`,
 
+		`CREATE TABLE IF NOT EXISTS audit (
+			id TEXT PRIMARY KEY,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			diff TEXT,
+			timestamp TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit(entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON audit(timestamp)`,
+
 		`CREATE TABLE IF NOT EXISTS relations (
 			id TEXT PRIMARY KEY,
 			uris TEXT NOT NULL,
 			reason TEXT,
+			bidirectional INTEGER DEFAULT 0,
+			type TEXT DEFAULT '',
 			created_at TEXT NOT NULL
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_relations_uris ON relations(uris)`,
+
+		`CREATE TABLE IF NOT EXISTS context_snapshots (
+			key TEXT PRIMARY KEY,
+			data BLOB NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
 	}
 
 	for _, schema := range schemas {
@@ -154,9 +191,74 @@ func (s *SQLiteStorage) initSchema() error {
 		}
 	}
 
+	// Columns added after the initial schema, for existing databases
+	if err := s.addMissingColumns("contexts", [][2]string{
+		{"content", "TEXT DEFAULT ''"},
+		{"content_ref", "TEXT DEFAULT ''"},
+		{"content_checksum", "TEXT DEFAULT ''"},
+	}); err != nil {
+		return err
+	}
+	if err := s.addMissingColumns("memories", [][2]string{
+		{"source_message_ids", "TEXT DEFAULT ''"},
+		{"evidence", "TEXT DEFAULT ''"},
+	}); err != nil {
+		return err
+	}
+	if err := s.addMissingColumns("sessions", [][2]string{
+		{"metadata", "TEXT DEFAULT ''"},
+	}); err != nil {
+		return err
+	}
+	if err := s.addMissingColumns("relations", [][2]string{
+		{"bidirectional", "INTEGER DEFAULT 0"},
+		{"type", "TEXT DEFAULT ''"},
+	}); err != nil {
+		return err
+	}
+
+	return s.initFTS()
+}
+
+// addMissingColumns adds each {name, definition} column that table lacks.
+func (s *SQLiteStorage) addMissingColumns(table string, columns [][2]string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	for _, col := range columns {
+		if existing[col[0]] {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col[0], col[1])); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, col[0], err)
+		}
+	}
 	return nil
 }
 
+// SetContentStore sets where content larger than Config.ContentInlineLimit
+// is stored. A nil store keeps all content inline.
+func (s *SQLiteStorage) SetContentStore(store ContentStore) {
+	s.content = store
+}
+
 // Close closes the database connection.
 func (s *SQLiteStorage) Close() error {
 	return s.db.Close()
@@ -167,9 +269,14 @@ func (s *SQLiteStorage) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
+// timeLayout formats stored times in UTC with every fractional digit kept,
+// so that they sort in time order as text. RFC3339Nano drops trailing zeros
+// and keeps the caller's zone, which misorders rows.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
 // timeToString converts time.Time to string for SQLite storage.
 func timeToString(t time.Time) string {
-	return t.Format(time.RFC3339Nano)
+	return t.UTC().Format(timeLayout)
 }
 
 // parseTime parses a string to time.Time.
@@ -185,14 +292,32 @@ func parseTime(s string) time.Time {
 	if t, err := time.Parse(time.RFC3339, s); err == nil {
 		return t
 	}
-	// Try SQLite default format
-	if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", s); err == nil {
-		return t
+	// Rows written before times were formatted explicitly hold the driver's
+	// timestamp format, or Go's default time string
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999 -0700 MST"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
 	}
 	// Fallback - return zero time
 	return time.Time{}
 }
 
+// limitClause returns the LIMIT and OFFSET clauses for opts. SQLite only
+// accepts OFFSET after a LIMIT, where -1 means no limit.
+func limitClause(opts QueryOptions) string {
+	var clause string
+	if opts.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	} else if opts.Offset > 0 {
+		clause += " LIMIT -1"
+	}
+	if opts.Offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", opts.Offset)
+	}
+	return clause
+}
+
 // Transaction executes a function within a transaction.
 func (s *SQLiteStorage) Transaction(ctx context.Context, fn func(tx interface{}) error) error {
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -215,62 +340,245 @@ func (s *SQLiteStorage) Transaction(ctx context.Context, fn func(tx interface{})
 // Context Operations
 // =============================================================================
 
-// CreateContext inserts a new context into the database.
-func (s *SQLiteStorage) CreateContext(ctx context.Context, c *Context) error {
-	query := `INSERT INTO contexts (id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.ExecContext(ctx, query,
-		c.ID, c.URI, c.Type, c.ContextType, c.ParentURI, c.IsLeaf, c.Name,
-		c.Description, c.Tags, c.Abstract, c.ActiveCount, c.CreatedAt, c.UpdatedAt)
-	return err
-}
+// contextColumns lists the contexts columns in the order scanContext reads
+// them.
+const contextColumns = "id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, content, content_ref, content_checksum, created_at, updated_at"
 
-// GetContext retrieves a context by ID.
-func (s *SQLiteStorage) GetContext(ctx context.Context, id string) (*Context, error) {
-	query := `SELECT id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, created_at, updated_at FROM contexts WHERE id = ?`
-	row := s.db.QueryRowContext(ctx, query, id)
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanContext reads a row selected with contextColumns. Externalized
+// content is left unresolved.
+func scanContext(row rowScanner) (*Context, error) {
 	var c Context
 	var isLeaf int
 	var createdAt, updatedAt string
 	err := row.Scan(&c.ID, &c.URI, &c.Type, &c.ContextType, &c.ParentURI, &isLeaf,
-		&c.Name, &c.Description, &c.Tags, &c.Abstract, &c.ActiveCount, &createdAt, &updatedAt)
+		&c.Name, &c.Description, &c.Tags, &c.Abstract, &c.ActiveCount,
+		&c.Content, &c.ContentRef, &c.ContentChecksum, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.IsLeaf = isLeaf == 1
+	c.CreatedAt = parseTime(createdAt)
+	c.UpdatedAt = parseTime(updatedAt)
+	return &c, nil
+}
+
+// contentInlineLimit returns the largest content kept in the contexts row.
+func (s *SQLiteStorage) contentInlineLimit() int {
+	return inlineLimit(s.cfg)
+}
+
+// storeContent moves c's content to the content store when it is too large
+// to keep inline, setting c.ContentRef and c.ContentChecksum. It returns
+// the content to write to the contexts row.
+func (s *SQLiteStorage) storeContent(ctx context.Context, c *Context) (string, error) {
+	return offloadContent(ctx, s.content, s.contentInlineLimit(), c)
+}
+
+// loadContent fills c.Content from the content store when it was
+// externalized, verifying its checksum.
+func (s *SQLiteStorage) loadContent(ctx context.Context, c *Context) error {
+	return resolveContent(ctx, s.content, c)
+}
+
+// contentRef returns the stored content reference of context id.
+func (s *SQLiteStorage) contentRef(ctx context.Context, id string) (string, error) {
+	var ref string
+	err := s.db.QueryRowContext(ctx, "SELECT content_ref FROM contexts WHERE id = ?", id).Scan(&ref)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ref, err
+}
+
+// CreateContext inserts a new context into the database. Content larger
+// than the inline limit goes to the content store when one is configured.
+func (s *SQLiteStorage) CreateContext(ctx context.Context, c *Context) error {
+	content, err := s.storeContent(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO contexts (id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, content, content_ref, content_checksum, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, query,
+		c.ID, c.URI, c.Type, c.ContextType, c.ParentURI, c.IsLeaf, c.Name,
+		c.Description, c.Tags, c.Abstract, c.ActiveCount,
+		content, c.ContentRef, c.ContentChecksum, timeToString(c.CreatedAt), timeToString(c.UpdatedAt))
+	if err != nil {
+		discardContent(ctx, s.content, c)
+	}
+	return err
+}
+
+// GetContext retrieves a context by ID, loading externalized content.
+func (s *SQLiteStorage) GetContext(ctx context.Context, id string) (*Context, error) {
+	query := `SELECT ` + contextColumns + ` FROM contexts WHERE id = ?`
+	c, err := scanContext(s.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	c.IsLeaf = isLeaf == 1
-	c.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-	c.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
-	return &c, nil
+	if err := s.loadContent(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// UpdateContext updates an existing context.
+// ContextExists reports whether a context with the given ID exists
+// without loading its columns.
+func (s *SQLiteStorage) ContextExists(ctx context.Context, id string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM contexts WHERE id = ? LIMIT 1", id).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateContext updates an existing context. New external content is
+// stored under a new reference, and the content it replaces is removed
+// only once the row no longer points at it.
 func (s *SQLiteStorage) UpdateContext(ctx context.Context, c *Context) error {
-	query := `UPDATE contexts SET uri = ?, type = ?, context_type = ?, parent_uri = ?, is_leaf = ?, name = ?, description = ?, tags = ?, abstract = ?, active_count = ?, updated_at = ? WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, query,
+	content, err := s.storeContent(ctx, c)
+	if err != nil {
+		return err
+	}
+	oldRef, found, err := s.switchContext(ctx, c, content)
+	if err != nil || !found {
+		discardContent(ctx, s.content, c)
+		return err
+	}
+
+	if oldRef != "" && s.content != nil {
+		return s.content.Delete(ctx, oldRef)
+	}
+	return nil
+}
+
+// switchContext writes c to its row with the given inline content and
+// returns the content reference the row held before, reporting whether
+// there was a row to write.
+func (s *SQLiteStorage) switchContext(ctx context.Context, c *Context, content string) (string, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	var oldRef string
+	err = tx.QueryRowContext(ctx, "SELECT content_ref FROM contexts WHERE id = ?", c.ID).Scan(&oldRef)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	query := `UPDATE contexts SET uri = ?, type = ?, context_type = ?, parent_uri = ?, is_leaf = ?, name = ?, description = ?, tags = ?, abstract = ?, active_count = ?, content = ?, content_ref = ?, content_checksum = ?, updated_at = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query,
 		c.URI, c.Type, c.ContextType, c.ParentURI, c.IsLeaf, c.Name,
-		c.Description, c.Tags, c.Abstract, c.ActiveCount, c.UpdatedAt, c.ID)
-	return err
+		c.Description, c.Tags, c.Abstract, c.ActiveCount,
+		content, c.ContentRef, c.ContentChecksum, timeToString(c.UpdatedAt), c.ID)
+	if err != nil {
+		return "", false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+	return oldRef, true, nil
 }
 
-// DeleteContext deletes a context by ID.
+// DeleteContext deletes a context by ID along with any external content.
 func (s *SQLiteStorage) DeleteContext(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM contexts WHERE id = ?", id)
-	return err
+	ref, err := s.contentRef(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM contexts WHERE id = ?", id); err != nil {
+		return err
+	}
+	if ref != "" && s.content != nil {
+		return s.content.Delete(ctx, ref)
+	}
+	return nil
+}
+
+// ExternalizeContent moves inline content larger than the inline limit to
+// the content store, leaving a reference in each row. It migrates rows
+// written before a content store was configured and returns how many
+// contexts were moved.
+func (s *SQLiteStorage) ExternalizeContent(ctx context.Context) (int, error) {
+	if s.content == nil {
+		return 0, fmt.Errorf("no content store configured")
+	}
+
+	// Collect IDs first so the cursor is closed before rows are rewritten
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id FROM contexts WHERE content_ref = '' AND length(CAST(content AS BLOB)) > ?",
+		s.contentInlineLimit())
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, id := range ids {
+		c, err := s.GetContext(ctx, id)
+		if err != nil {
+			return moved, err
+		}
+		if c == nil {
+			continue
+		}
+		if _, err := s.storeContent(ctx, c); err != nil {
+			return moved, err
+		}
+		_, err = s.db.ExecContext(ctx,
+			"UPDATE contexts SET content = '', content_ref = ?, content_checksum = ? WHERE id = ?",
+			c.ContentRef, c.ContentChecksum, id)
+		if err != nil {
+			discardContent(ctx, s.content, c)
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
 }
 
-// QueryContexts queries contexts with filter options.
+// QueryContexts queries contexts with filter options. Externalized content
+// is not loaded; only ContentRef is set for those contexts.
 func (s *SQLiteStorage) QueryContexts(ctx context.Context, opts QueryOptions) ([]Context, error) {
-	query := "SELECT id, uri, type, context_type, parent_uri, is_leaf, name, description, tags, abstract, active_count, created_at, updated_at FROM contexts"
+	query := "SELECT " + contextColumns + " FROM contexts"
 	args := []interface{}{}
 
 	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
 		whereClause, filterArgs := buildFilterClause(opts.Filter)
-		query += " WHERE " + whereClause
-		args = append(args, filterArgs...)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
 	}
 
 	if opts.OrderBy != "" {
@@ -281,13 +589,7 @@ func (s *SQLiteStorage) QueryContexts(ctx context.Context, opts QueryOptions) ([
 		query += fmt.Sprintf(" ORDER BY %s %s", opts.OrderBy, orderDir)
 	}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-	}
-
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
-	}
+	query += limitClause(opts)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -297,84 +599,93 @@ func (s *SQLiteStorage) QueryContexts(ctx context.Context, opts QueryOptions) ([
 
 	var contexts []Context
 	for rows.Next() {
-		var c Context
-		var isLeaf int
-		var createdAt, updatedAt string
-		err := rows.Scan(&c.ID, &c.URI, &c.Type, &c.ContextType, &c.ParentURI, &isLeaf,
-			&c.Name, &c.Description, &c.Tags, &c.Abstract, &c.ActiveCount, &createdAt, &updatedAt)
+		c, err := scanContext(rows)
 		if err != nil {
 			return nil, err
 		}
-		c.IsLeaf = isLeaf == 1
-		c.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-		c.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
-		contexts = append(contexts, c)
+		contexts = append(contexts, *c)
 	}
 
 	return contexts, rows.Err()
 }
 
+// CountContexts counts the contexts matching the filter of opts, ignoring
+// its limit and offset.
+func (s *SQLiteStorage) CountContexts(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "contexts", opts)
+}
+
 // =============================================================================
 // Session Operations
 // =============================================================================
 
 // CreateSession inserts a new session into the database.
 func (s *SQLiteStorage) CreateSession(ctx context.Context, session *Session) error {
-	query := `INSERT INTO sessions (id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO sessions (id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
 		session.ID, session.SessionID, session.UserID, session.TotalTurns, session.TotalTokens,
 		session.CompressionCount, session.ContextsUsed, session.SkillsUsed,
-		session.MemoriesExtracted, session.Summary, session.CreatedAt, session.UpdatedAt)
+		session.MemoriesExtracted, session.Summary, session.Metadata, timeToString(session.CreatedAt), timeToString(session.UpdatedAt))
 	return err
 }
 
 // GetSession retrieves a session by ID.
 func (s *SQLiteStorage) GetSession(ctx context.Context, id string) (*Session, error) {
-	query := `SELECT id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, created_at, updated_at FROM sessions WHERE id = ?`
+	query := `SELECT id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, metadata, created_at, updated_at FROM sessions WHERE id = ?`
 	row := s.db.QueryRowContext(ctx, query, id)
 
 	var session Session
 	var createdAt, updatedAt string
 	err := row.Scan(&session.ID, &session.SessionID, &session.UserID, &session.TotalTurns,
 		&session.TotalTokens, &session.CompressionCount, &session.ContextsUsed, &session.SkillsUsed,
-		&session.MemoriesExtracted, &session.Summary, &createdAt, &updatedAt)
+		&session.MemoriesExtracted, &session.Summary, &session.Metadata, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	session.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-	session.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+	session.CreatedAt = parseTime(createdAt)
+	session.UpdatedAt = parseTime(updatedAt)
 	return &session, nil
 }
 
 // UpdateSession updates an existing session.
 func (s *SQLiteStorage) UpdateSession(ctx context.Context, session *Session) error {
-	query := `UPDATE sessions SET session_id = ?, user_id = ?, total_turns = ?, total_tokens = ?, compression_count = ?, contexts_used = ?, skills_used = ?, memoies_extracted = ?, summary = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE sessions SET session_id = ?, user_id = ?, total_turns = ?, total_tokens = ?, compression_count = ?, contexts_used = ?, skills_used = ?, memoies_extracted = ?, summary = ?, metadata = ?, updated_at = ? WHERE id = ?`
 	_, err := s.db.ExecContext(ctx, query,
 		session.SessionID, session.UserID, session.TotalTurns, session.TotalTokens,
 		session.CompressionCount, session.ContextsUsed, session.SkillsUsed,
-		session.MemoriesExtracted, session.Summary, session.UpdatedAt, session.ID)
+		session.MemoriesExtracted, session.Summary, session.Metadata, timeToString(session.UpdatedAt), session.ID)
 	return err
 }
 
-// DeleteSession deletes a session by ID.
+// DeleteSession deletes a session by ID together with its messages.
+// Memories extracted from the session are kept.
 func (s *SQLiteStorage) DeleteSession(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
-	return err
+	return s.Transaction(ctx, func(tx interface{}) error {
+		t := tx.(*sql.Tx)
+		// Messages reference the session by session_id rather than id
+		if _, err := t.ExecContext(ctx, "DELETE FROM session_messages WHERE session_id IN (SELECT session_id FROM sessions WHERE id = ?)", id); err != nil {
+			return err
+		}
+		_, err := t.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+		return err
+	})
 }
 
 // QuerySessions queries sessions with filter options.
 func (s *SQLiteStorage) QuerySessions(ctx context.Context, opts QueryOptions) ([]Session, error) {
-	query := "SELECT id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, created_at, updated_at FROM sessions"
+	query := "SELECT id, session_id, user_id, total_turns, total_tokens, compression_count, contexts_used, skills_used, memoies_extracted, summary, metadata, created_at, updated_at FROM sessions"
 	args := []interface{}{}
 
 	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
 		whereClause, filterArgs := buildFilterClause(opts.Filter)
-		query += " WHERE " + whereClause
-		args = append(args, filterArgs...)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
 	}
 
 	if opts.OrderBy != "" {
@@ -385,9 +696,7 @@ func (s *SQLiteStorage) QuerySessions(ctx context.Context, opts QueryOptions) ([
 		query += fmt.Sprintf(" ORDER BY %s %s", opts.OrderBy, orderDir)
 	}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-	}
+	query += limitClause(opts)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -401,18 +710,24 @@ func (s *SQLiteStorage) QuerySessions(ctx context.Context, opts QueryOptions) ([
 		var createdAt, updatedAt string
 		err := rows.Scan(&session.ID, &session.SessionID, &session.UserID, &session.TotalTurns,
 			&session.TotalTokens, &session.CompressionCount, &session.ContextsUsed, &session.SkillsUsed,
-			&session.MemoriesExtracted, &session.Summary, &createdAt, &updatedAt)
+			&session.MemoriesExtracted, &session.Summary, &session.Metadata, &createdAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
-		session.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-		session.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+		session.CreatedAt = parseTime(createdAt)
+		session.UpdatedAt = parseTime(updatedAt)
 		sessions = append(sessions, session)
 	}
 
 	return sessions, rows.Err()
 }
 
+// CountSessions counts the sessions matching the filter of opts, ignoring
+// its limit and offset.
+func (s *SQLiteStorage) CountSessions(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "sessions", opts)
+}
+
 // =============================================================================
 // SessionMessage Operations
 // =============================================================================
@@ -422,7 +737,7 @@ func (s *SQLiteStorage) CreateSessionMessage(ctx context.Context, msg *SessionMe
 	query := `INSERT INTO session_messages (id, session_id, role, content, order_index, created_at)
 		VALUES (?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
-		msg.ID, msg.SessionID, msg.Role, msg.Content, msg.OrderIndex, msg.CreatedAt)
+		msg.ID, msg.SessionID, msg.Role, msg.Content, msg.OrderIndex, timeToString(msg.CreatedAt))
 	return err
 }
 
@@ -443,7 +758,7 @@ func (s *SQLiteStorage) GetSessionMessages(ctx context.Context, sessionID string
 		if err != nil {
 			return nil, err
 		}
-		msg.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
+		msg.CreatedAt = parseTime(createdAt)
 		messages = append(messages, msg)
 	}
 
@@ -462,40 +777,40 @@ func (s *SQLiteStorage) DeleteSessionMessages(ctx context.Context, sessionID str
 
 // CreateMemory inserts a new memory.
 func (s *SQLiteStorage) CreateMemory(ctx context.Context, memory *Memory) error {
-	query := `INSERT INTO memories (id, session_id, user_id, content, importance, tags, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO memories (id, session_id, user_id, content, importance, tags, source_message_ids, evidence, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
 		memory.ID, memory.SessionID, memory.UserID, memory.Content, memory.Importance,
-		memory.Tags, memory.CreatedAt, memory.UpdatedAt)
+		memory.Tags, memory.SourceMessageIDs, memory.Evidence, timeToString(memory.CreatedAt), timeToString(memory.UpdatedAt))
 	return err
 }
 
 // GetMemory retrieves a memory by ID.
 func (s *SQLiteStorage) GetMemory(ctx context.Context, id string) (*Memory, error) {
-	query := `SELECT id, session_id, user_id, content, importance, tags, created_at, updated_at FROM memories WHERE id = ?`
+	query := `SELECT id, session_id, user_id, content, importance, tags, source_message_ids, evidence, created_at, updated_at FROM memories WHERE id = ?`
 	row := s.db.QueryRowContext(ctx, query, id)
 
 	var memory Memory
 	var createdAt, updatedAt string
 	err := row.Scan(&memory.ID, &memory.SessionID, &memory.UserID, &memory.Content,
-		&memory.Importance, &memory.Tags, &createdAt, &updatedAt)
+		&memory.Importance, &memory.Tags, &memory.SourceMessageIDs, &memory.Evidence, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	memory.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-	memory.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+	memory.CreatedAt = parseTime(createdAt)
+	memory.UpdatedAt = parseTime(updatedAt)
 	return &memory, nil
 }
 
 // UpdateMemory updates an existing memory.
 func (s *SQLiteStorage) UpdateMemory(ctx context.Context, memory *Memory) error {
-	query := `UPDATE memories SET session_id = ?, user_id = ?, content = ?, importance = ?, tags = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE memories SET session_id = ?, user_id = ?, content = ?, importance = ?, tags = ?, source_message_ids = ?, evidence = ?, updated_at = ? WHERE id = ?`
 	_, err := s.db.ExecContext(ctx, query,
 		memory.SessionID, memory.UserID, memory.Content, memory.Importance,
-		memory.Tags, memory.UpdatedAt, memory.ID)
+		memory.Tags, memory.SourceMessageIDs, memory.Evidence, timeToString(memory.UpdatedAt), memory.ID)
 	return err
 }
 
@@ -507,13 +822,15 @@ func (s *SQLiteStorage) DeleteMemory(ctx context.Context, id string) error {
 
 // QueryMemories queries memories with filter options.
 func (s *SQLiteStorage) QueryMemories(ctx context.Context, opts QueryOptions) ([]Memory, error) {
-	query := "SELECT id, session_id, user_id, content, importance, tags, created_at, updated_at FROM memories"
+	query := "SELECT id, session_id, user_id, content, importance, tags, source_message_ids, evidence, created_at, updated_at FROM memories"
 	args := []interface{}{}
 
 	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
 		whereClause, filterArgs := buildFilterClause(opts.Filter)
-		query += " WHERE " + whereClause
-		args = append(args, filterArgs...)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
 	}
 
 	if opts.OrderBy != "" {
@@ -524,9 +841,7 @@ func (s *SQLiteStorage) QueryMemories(ctx context.Context, opts QueryOptions) ([
 		query += fmt.Sprintf(" ORDER BY %s %s", opts.OrderBy, orderDir)
 	}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-	}
+	query += limitClause(opts)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -539,18 +854,24 @@ func (s *SQLiteStorage) QueryMemories(ctx context.Context, opts QueryOptions) ([
 		var memory Memory
 		var createdAt, updatedAt string
 		err := rows.Scan(&memory.ID, &memory.SessionID, &memory.UserID, &memory.Content,
-			&memory.Importance, &memory.Tags, &createdAt, &updatedAt)
+			&memory.Importance, &memory.Tags, &memory.SourceMessageIDs, &memory.Evidence, &createdAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
-		memory.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-		memory.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+		memory.CreatedAt = parseTime(createdAt)
+		memory.UpdatedAt = parseTime(updatedAt)
 		memories = append(memories, memory)
 	}
 
 	return memories, rows.Err()
 }
 
+// CountMemories counts the memories matching the filter of opts, ignoring
+// its limit and offset.
+func (s *SQLiteStorage) CountMemories(ctx context.Context, opts QueryOptions) (int, error) {
+	return s.count(ctx, "memories", opts)
+}
+
 // =============================================================================
 // File Operations
 // =============================================================================
@@ -561,7 +882,7 @@ func (s *SQLiteStorage) CreateFile(ctx context.Context, file *File) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
 		file.ID, file.URI, file.Name, file.Size, file.ContentType,
-		file.Checksum, file.CreatedAt, file.UpdatedAt)
+		file.Checksum, timeToString(file.CreatedAt), timeToString(file.UpdatedAt))
 	return err
 }
 
@@ -580,8 +901,8 @@ func (s *SQLiteStorage) GetFile(ctx context.Context, id string) (*File, error) {
 	if err != nil {
 		return nil, err
 	}
-	file.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-	file.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+	file.CreatedAt = parseTime(createdAt)
+	file.UpdatedAt = parseTime(updatedAt)
 	return &file, nil
 }
 
@@ -589,7 +910,7 @@ func (s *SQLiteStorage) GetFile(ctx context.Context, id string) (*File, error) {
 func (s *SQLiteStorage) UpdateFile(ctx context.Context, file *File) error {
 	query := `UPDATE files SET uri = ?, name = ?, size = ?, content_type = ?, checksum = ?, updated_at = ? WHERE id = ?`
 	_, err := s.db.ExecContext(ctx, query,
-		file.URI, file.Name, file.Size, file.ContentType, file.Checksum, file.UpdatedAt, file.ID)
+		file.URI, file.Name, file.Size, file.ContentType, file.Checksum, timeToString(file.UpdatedAt), file.ID)
 	return err
 }
 
@@ -606,8 +927,10 @@ func (s *SQLiteStorage) QueryFiles(ctx context.Context, opts QueryOptions) ([]Fi
 
 	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
 		whereClause, filterArgs := buildFilterClause(opts.Filter)
-		query += " WHERE " + whereClause
-		args = append(args, filterArgs...)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
 	}
 
 	if opts.OrderBy != "" {
@@ -637,8 +960,8 @@ func (s *SQLiteStorage) QueryFiles(ctx context.Context, opts QueryOptions) ([]Fi
 		if err != nil {
 			return nil, err
 		}
-		file.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
-		file.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", updatedAt)
+		file.CreatedAt = parseTime(createdAt)
+		file.UpdatedAt = parseTime(updatedAt)
 		files = append(files, file)
 	}
 
@@ -655,7 +978,7 @@ func (s *SQLiteStorage) CreateUsage(ctx context.Context, usage *Usage) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
 		usage.ID, usage.SessionID, usage.URI, usage.Type, usage.Contribution,
-		usage.Input, usage.Output, usage.Success, usage.Timestamp)
+		usage.Input, usage.Output, usage.Success, timeToString(usage.Timestamp))
 	return err
 }
 
@@ -666,8 +989,10 @@ func (s *SQLiteStorage) QueryUsage(ctx context.Context, opts QueryOptions) ([]Us
 
 	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
 		whereClause, filterArgs := buildFilterClause(opts.Filter)
-		query += " WHERE " + whereClause
-		args = append(args, filterArgs...)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
 	}
 
 	if opts.OrderBy != "" {
@@ -699,28 +1024,90 @@ func (s *SQLiteStorage) QueryUsage(ctx context.Context, opts QueryOptions) ([]Us
 			return nil, err
 		}
 		usage.Success = success == 1
-		usage.Timestamp, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", timestamp)
+		usage.Timestamp = parseTime(timestamp)
 		usages = append(usages, usage)
 	}
 
 	return usages, rows.Err()
 }
 
+// =============================================================================
+// Audit Operations
+// =============================================================================
+
+// CreateAudit inserts a new audit entry.
+func (s *SQLiteStorage) CreateAudit(ctx context.Context, entry *AuditEntry) error {
+	query := `INSERT INTO audit (id, actor, action, entity_type, entity_id, diff, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query,
+		entry.ID, entry.Actor, entry.Action, entry.EntityType, entry.EntityID,
+		entry.Diff, timeToString(entry.Timestamp))
+	return err
+}
+
+// QueryAudit queries audit entries with filter options.
+func (s *SQLiteStorage) QueryAudit(ctx context.Context, opts QueryOptions) ([]AuditEntry, error) {
+	query := "SELECT id, actor, action, entity_type, entity_id, diff, timestamp FROM audit"
+	args := []interface{}{}
+
+	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
+		whereClause, filterArgs := buildFilterClause(opts.Filter)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = append(args, filterArgs...)
+		}
+	}
+
+	if opts.OrderBy != "" {
+		orderDir := "ASC"
+		if opts.OrderDesc {
+			orderDir = "DESC"
+		}
+		// Entries written in the same instant keep their insertion order
+		query += fmt.Sprintf(" ORDER BY %s %s, rowid %s", opts.OrderBy, orderDir, orderDir)
+	}
+
+	query += limitClause(opts)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var diff sql.NullString
+		var timestamp string
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.EntityType,
+			&entry.EntityID, &diff, &timestamp)
+		if err != nil {
+			return nil, err
+		}
+		entry.Diff = diff.String
+		entry.Timestamp = parseTime(timestamp)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
 // =============================================================================
 // Relation Operations
 // =============================================================================
 
 // CreateRelation inserts a new relation.
 func (s *SQLiteStorage) CreateRelation(ctx context.Context, relation *RelationEntry) error {
-	query := `INSERT INTO relations (id, uris, reason, created_at) VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO relations (id, uris, reason, bidirectional, type, created_at) VALUES (?, ?, ?, ?, ?, ?)`
 	_, err := s.db.ExecContext(ctx, query,
-		relation.ID, relation.URIs, relation.Reason, relation.CreatedAt)
+		relation.ID, relation.URIs, relation.Reason, relation.Bidirectional, relation.Type, timeToString(relation.CreatedAt))
 	return err
 }
 
 // QueryRelations retrieves relations for a URI.
 func (s *SQLiteStorage) QueryRelations(ctx context.Context, uri string) ([]RelationEntry, error) {
-	query := `SELECT id, uris, reason, created_at FROM relations WHERE uris LIKE ?`
+	query := `SELECT id, uris, reason, bidirectional, type, created_at FROM relations WHERE uris LIKE ?`
 	rows, err := s.db.QueryContext(ctx, query, "%"+uri+"%")
 	if err != nil {
 		return nil, err
@@ -731,11 +1118,11 @@ func (s *SQLiteStorage) QueryRelations(ctx context.Context, uri string) ([]Relat
 	for rows.Next() {
 		var relation RelationEntry
 		var createdAt string
-		err := rows.Scan(&relation.ID, &relation.URIs, &relation.Reason, &createdAt)
+		err := rows.Scan(&relation.ID, &relation.URIs, &relation.Reason, &relation.Bidirectional, &relation.Type, &createdAt)
 		if err != nil {
 			return nil, err
 		}
-		relation.CreatedAt, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", createdAt)
+		relation.CreatedAt = parseTime(createdAt)
 		relations = append(relations, relation)
 	}
 
@@ -748,6 +1135,88 @@ func (s *SQLiteStorage) DeleteRelation(ctx context.Context, id string) error {
 	return err
 }
 
+// =============================================================================
+// Streaming Operations
+// =============================================================================
+
+// IterateContexts calls fn for every context, reading rows from a cursor so
+// memory use stays constant regardless of table size. Externalized content
+// is loaded so that exports are self-contained.
+func (s *SQLiteStorage) IterateContexts(ctx context.Context, fn func(*Context) error) error {
+	query := "SELECT " + contextColumns + " FROM contexts ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := scanContext(rows)
+		if err != nil {
+			return err
+		}
+		if err := s.loadContent(ctx, c); err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// IterateMemories calls fn for every memory, reading rows from a cursor.
+func (s *SQLiteStorage) IterateMemories(ctx context.Context, fn func(*Memory) error) error {
+	query := "SELECT id, session_id, user_id, content, importance, tags, source_message_ids, evidence, created_at, updated_at FROM memories ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var memory Memory
+		var createdAt, updatedAt string
+		err := rows.Scan(&memory.ID, &memory.SessionID, &memory.UserID, &memory.Content,
+			&memory.Importance, &memory.Tags, &memory.SourceMessageIDs, &memory.Evidence, &createdAt, &updatedAt)
+		if err != nil {
+			return err
+		}
+		memory.CreatedAt = parseTime(createdAt)
+		memory.UpdatedAt = parseTime(updatedAt)
+		if err := fn(&memory); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// IterateRelations calls fn for every relation, reading rows from a cursor.
+func (s *SQLiteStorage) IterateRelations(ctx context.Context, fn func(*RelationEntry) error) error {
+	query := "SELECT id, uris, reason, bidirectional, type, created_at FROM relations ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation RelationEntry
+		var createdAt string
+		if err := rows.Scan(&relation.ID, &relation.URIs, &relation.Reason, &relation.Bidirectional, &relation.Type, &createdAt); err != nil {
+			return err
+		}
+		relation.CreatedAt = parseTime(createdAt)
+		if err := fn(&relation); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // =============================================================================
 // Collection Management (for interface compatibility)
 // =============================================================================
@@ -767,7 +1236,7 @@ func (s *SQLiteStorage) DropCollection(name string) error {
 // CollectionExists checks if a collection exists.
 func (s *SQLiteStorage) CollectionExists(name string) bool {
 	switch name {
-	case "contexts", "sessions", "memories", "files", "usage_records", "relations":
+	case "contexts", "sessions", "memories", "files", "usage_records", "audit", "relations":
 		return true
 	default:
 		return false
@@ -776,55 +1245,53 @@ func (s *SQLiteStorage) CollectionExists(name string) bool {
 
 // ListCollections lists all collections.
 func (s *SQLiteStorage) ListCollections() ([]string, error) {
-	return []string{"contexts", "sessions", "memories", "files", "usage_records", "relations"}, nil
+	return []string{"contexts", "sessions", "memories", "files", "usage_records", "audit", "relations"}, nil
 }
 
 // =============================================================================
 // Helper Functions
 // =============================================================================
 
+// count counts the rows of table matching the filter of opts.
+func (s *SQLiteStorage) count(ctx context.Context, table string, opts QueryOptions) (int, error) {
+	query := "SELECT COUNT(*) FROM " + table
+	var args []interface{}
+	if opts.Filter != nil && len(opts.Filter.Conds) > 0 {
+		whereClause, filterArgs := buildFilterClause(opts.Filter)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+			args = filterArgs
+		}
+	}
+
+	var n int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
 // buildFilterClause builds a SQL WHERE clause from filter conditions.
+// Conditions with an "and" or "or" op are sub-groups and are emitted in
+// parentheses, so groups may nest to any depth.
 func buildFilterClause(filter *Filter) (string, []interface{}) {
-	if filter == nil || len(filter.Conds) == 0 {
+	if filter == nil {
 		return "", nil
 	}
+	return buildFilterGroup(filter.Op, filter.Conds)
+}
 
+// buildFilterGroup joins the clauses of conds with the op connector,
+// "and" unless op is "or". Conditions that produce no clause are skipped.
+func buildFilterGroup(op string, conds []FilterCondition) (string, []interface{}) {
 	var clauses []string
 	var args []interface{}
 
-	for _, cond := range filter.Conds {
-		switch cond.Op {
-		case "must":
-			// Exact match
-			clauses = append(clauses, fmt.Sprintf("%s = ?", cond.Field))
-			args = append(args, cond.Value)
-		case "range":
-			// Range query
-			if cond.GTE != nil {
-				clauses = append(clauses, fmt.Sprintf("%s >= ?", cond.Field))
-				args = append(args, cond.GTE)
-			}
-			if cond.GT != nil {
-				clauses = append(clauses, fmt.Sprintf("%s > ?", cond.Field))
-				args = append(args, cond.GT)
-			}
-			if cond.LTE != nil {
-				clauses = append(clauses, fmt.Sprintf("%s <= ?", cond.Field))
-				args = append(args, cond.LTE)
-			}
-			if cond.LT != nil {
-				clauses = append(clauses, fmt.Sprintf("%s < ?", cond.Field))
-				args = append(args, cond.LT)
-			}
-		case "prefix":
-			// Prefix match (LIKE)
-			clauses = append(clauses, fmt.Sprintf("%s LIKE ?", cond.Field))
-			args = append(args, cond.Prefix+"%")
-		case "contains":
-			// Contains substring
-			clauses = append(clauses, fmt.Sprintf("%s LIKE ?", cond.Field))
-			args = append(args, "%"+cond.Substr+"%")
+	for _, cond := range conds {
+		clause, condArgs := buildCondition(cond)
+		if clause == "" {
+			continue
 		}
+		clauses = append(clauses, clause)
+		args = append(args, condArgs...)
 	}
 
 	if len(clauses) == 0 {
@@ -832,12 +1299,93 @@ func buildFilterClause(filter *Filter) (string, []interface{}) {
 	}
 
 	connector := " AND "
-	if filter.Op == "or" {
+	if op == "or" {
 		connector = " OR "
 	}
 
 	return strings.Join(clauses, connector), args
 }
 
+// buildCondition builds the clause of a single filter condition. Clauses
+// that join several terms are parenthesized so they can be combined with
+// either connector.
+func buildCondition(cond FilterCondition) (string, []interface{}) {
+	switch cond.Op {
+	case "and", "or":
+		// Sub-group with its own connector
+		clause, args := buildFilterGroup(cond.Op, cond.Conds)
+		if clause == "" {
+			return "", nil
+		}
+		return "(" + clause + ")", args
+	case "must":
+		// Exact match
+		return fmt.Sprintf("%s = ?", cond.Field), []interface{}{cond.Value}
+	case "range":
+		// Range query
+		var clauses []string
+		var args []interface{}
+		if cond.GTE != nil {
+			clauses = append(clauses, fmt.Sprintf("%s >= ?", cond.Field))
+			args = append(args, cond.GTE)
+		}
+		if cond.GT != nil {
+			clauses = append(clauses, fmt.Sprintf("%s > ?", cond.Field))
+			args = append(args, cond.GT)
+		}
+		if cond.LTE != nil {
+			clauses = append(clauses, fmt.Sprintf("%s <= ?", cond.Field))
+			args = append(args, cond.LTE)
+		}
+		if cond.LT != nil {
+			clauses = append(clauses, fmt.Sprintf("%s < ?", cond.Field))
+			args = append(args, cond.LT)
+		}
+		switch len(clauses) {
+		case 0:
+			return "", nil
+		case 1:
+			return clauses[0], args
+		}
+		return "(" + strings.Join(clauses, " AND ") + ")", args
+	case "prefix":
+		// Prefix match (LIKE)
+		return fmt.Sprintf("%s LIKE ?", cond.Field), []interface{}{cond.Prefix + "%"}
+	case "contains":
+		// Contains substring
+		return fmt.Sprintf("%s LIKE ?", cond.Field), []interface{}{"%" + cond.Substr + "%"}
+	case "in":
+		// Any of the values in a slice, one placeholder each
+		values := filterValues(cond.Value)
+		if len(values) == 0 {
+			// Nothing is in an empty list, and IN () is invalid SQL
+			return "1 = 0", nil
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("%s IN (%s)", cond.Field, placeholders), values
+	case "not", "neq":
+		// Inequality, keeping rows where the field is unset
+		return fmt.Sprintf("(%s IS NULL OR %s <> ?)", cond.Field, cond.Field), []interface{}{cond.Value}
+	}
+	return "", nil
+}
+
+// filterValues returns the elements of an "in" condition's value, which
+// may be a slice of any type. Any other value is a one-element list.
+func filterValues(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
 // Ensure SQLiteStorage implements StorageInterface
 var _ StorageInterface = (*SQLiteStorage)(nil)
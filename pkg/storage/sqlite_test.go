@@ -8,7 +8,10 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,6 +74,22 @@ func TestSQLiteStorage_ContextCRUD(t *testing.T) {
 		t.Errorf("expected URI %s, got %s", testContext.URI, retrieved.URI)
 	}
 
+	// Test ContextExists
+	exists, err := storage.ContextExists(ctx, testContext.ID)
+	if err != nil {
+		t.Fatalf("failed to check context exists: %v", err)
+	}
+	if !exists {
+		t.Error("expected context to exist")
+	}
+	exists, err = storage.ContextExists(ctx, "missing")
+	if err != nil {
+		t.Fatalf("failed to check missing context: %v", err)
+	}
+	if exists {
+		t.Error("expected missing context not to exist")
+	}
+
 	// Test UpdateContext
 	testContext.Description = "Updated description"
 	if err := storage.UpdateContext(ctx, testContext); err != nil {
@@ -134,6 +153,7 @@ func TestSQLiteStorage_SessionCRUD(t *testing.T) {
 		SkillsUsed:       3,
 		MemoriesExtracted: 4,
 		Summary:          "Test session summary",
+		Metadata:         `{"parent_session_id":"parent"}`,
 		CreatedAt:        time.Now().UTC(),
 		UpdatedAt:        time.Now().UTC(),
 	}
@@ -153,6 +173,9 @@ func TestSQLiteStorage_SessionCRUD(t *testing.T) {
 	if retrieved.SessionID != testSession.SessionID {
 		t.Errorf("expected SessionID %s, got %s", testSession.SessionID, retrieved.SessionID)
 	}
+	if retrieved.Metadata != testSession.Metadata {
+		t.Errorf("expected Metadata %s, got %s", testSession.Metadata, retrieved.Metadata)
+	}
 
 	// Test QuerySessions
 	sessions, err := storage.QuerySessions(ctx, QueryOptions{
@@ -172,6 +195,240 @@ func TestSQLiteStorage_SessionCRUD(t *testing.T) {
 	}
 }
 
+func TestSQLiteStorage_DeleteSessionDeletesMessages(t *testing.T) {
+	storage, err := NewSQLiteStorage(Config{DBPath: filepath.Join(t.TempDir(), "sessions.db"), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, id := range []string{"s1", "s2"} {
+		if err := storage.CreateSession(ctx, &Session{ID: id, SessionID: "session-" + id, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			msg := &SessionMessage{ID: uuid.New().String(), SessionID: "session-" + id, Role: "user", Content: "hi", OrderIndex: int64(i), CreatedAt: now}
+			if err := storage.CreateSessionMessage(ctx, msg); err != nil {
+				t.Fatalf("failed to create message: %v", err)
+			}
+		}
+	}
+
+	if err := storage.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	if messages, err := storage.GetSessionMessages(ctx, "session-s1"); err != nil || len(messages) != 0 {
+		t.Errorf("expected no messages left for the deleted session, got %d (%v)", len(messages), err)
+	}
+	if messages, err := storage.GetSessionMessages(ctx, "session-s2"); err != nil || len(messages) != 2 {
+		t.Errorf("expected the other session to keep 2 messages, got %d (%v)", len(messages), err)
+	}
+}
+
+func TestSQLiteStorage_CountIgnoresLimitAndOffset(t *testing.T) {
+	storage, err := NewSQLiteStorage(Config{DBPath: filepath.Join(t.TempDir(), "count.db"), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		user := "alice"
+		if i == 4 {
+			user = "bob"
+		}
+		id := string(rune('a' + i))
+		if err := storage.CreateContext(ctx, &Context{ID: id, URI: "viking://test/" + id, Type: ContextTypeFile, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+		if err := storage.CreateSession(ctx, &Session{ID: id, SessionID: "session-" + id, UserID: user, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		if err := storage.CreateMemory(ctx, &Memory{ID: id, UserID: user, Content: "memory " + id, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("failed to create memory: %v", err)
+		}
+	}
+
+	alice := &Filter{Conds: []FilterCondition{{Op: "must", Field: "user_id", Value: "alice"}}}
+	page := QueryOptions{Filter: alice, OrderBy: "id", Limit: 2, Offset: 3}
+	sessions, err := storage.QuerySessions(ctx, page)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "d" {
+		t.Errorf("expected the last alice session on the page, got %+v (%v)", sessions, err)
+	}
+	memories, err := storage.QueryMemories(ctx, page)
+	if err != nil || len(memories) != 1 || memories[0].ID != "d" {
+		t.Errorf("expected the last alice memory on the page, got %+v (%v)", memories, err)
+	}
+
+	counts := []struct {
+		name  string
+		count func(context.Context, QueryOptions) (int, error)
+		opts  QueryOptions
+		want  int
+	}{
+		{"contexts", storage.CountContexts, QueryOptions{Limit: 2, Offset: 1}, 5},
+		{"sessions", storage.CountSessions, page, 4},
+		{"memories", storage.CountMemories, page, 4},
+		{"all memories", storage.CountMemories, QueryOptions{}, 5},
+	}
+	for _, tt := range counts {
+		if n, err := tt.count(ctx, tt.opts); err != nil || n != tt.want {
+			t.Errorf("%s: expected count %d, got %d (%v)", tt.name, tt.want, n, err)
+		}
+	}
+}
+
+func TestSQLiteStorage_FilterInAndNot(t *testing.T) {
+	storage, err := NewSQLiteStorage(Config{DBPath: filepath.Join(t.TempDir(), "filter.db"), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		c := &Context{ID: id, URI: "viking://test/" + id, Type: ContextTypeFile, ContextType: "type-" + id, CreatedAt: now, UpdatedAt: now}
+		if err := storage.CreateContext(ctx, c); err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   string
+	}{
+		{"in strings", &Filter{Conds: []FilterCondition{{Op: "in", Field: "context_type", Value: []string{"type-a", "type-c"}}}}, "a,c"},
+		{"in interfaces", &Filter{Conds: []FilterCondition{{Op: "in", Field: "id", Value: []interface{}{"b", "d", "z"}}}}, "b,d"},
+		{"empty in", &Filter{Conds: []FilterCondition{{Op: "in", Field: "id", Value: []string{}}}}, ""},
+		{"empty in or", &Filter{Op: "or", Conds: []FilterCondition{
+			{Op: "in", Field: "id", Value: []string{}},
+			{Op: "must", Field: "id", Value: "a"},
+		}}, "a"},
+		{"not", &Filter{Conds: []FilterCondition{{Op: "not", Field: "context_type", Value: "type-b"}}}, "a,c,d"},
+		{"in and neq", &Filter{Conds: []FilterCondition{
+			{Op: "in", Field: "id", Value: []string{"a", "b", "c"}},
+			{Op: "neq", Field: "id", Value: "b"},
+		}}, "a,c"},
+	}
+	for _, tt := range tests {
+		contexts, err := storage.QueryContexts(ctx, QueryOptions{Filter: tt.filter, OrderBy: "id"})
+		if err != nil {
+			t.Fatalf("%s: failed to query contexts: %v", tt.name, err)
+		}
+		var ids []string
+		for _, c := range contexts {
+			ids = append(ids, c.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+		if n, err := storage.CountContexts(ctx, QueryOptions{Filter: tt.filter}); err != nil || n != len(ids) {
+			t.Errorf("%s: expected count %d, got %d (%v)", tt.name, len(ids), n, err)
+		}
+	}
+}
+
+func TestBuildFilterClauseNested(t *testing.T) {
+	filter := &Filter{Op: "and", Conds: []FilterCondition{
+		{Op: "or", Conds: []FilterCondition{
+			{Op: "must", Field: "context_type", Value: "code"},
+			{Op: "must", Field: "context_type", Value: "document"},
+		}},
+		{Op: "range", Field: "active_count", GT: 0},
+		{Op: "or", Conds: []FilterCondition{
+			{Op: "prefix", Field: "uri", Prefix: "viking://resources/"},
+			{Op: "range", Field: "created_at", GTE: "a", LT: "b"},
+		}},
+		{Op: "and", Conds: nil},
+	}}
+
+	clause, args := buildFilterClause(filter)
+	want := "(context_type = ? OR context_type = ?) AND active_count > ? AND (uri LIKE ? OR (created_at >= ? AND created_at < ?))"
+	if clause != want {
+		t.Errorf("expected %q, got %q", want, clause)
+	}
+	if len(args) != 6 || args[0] != "code" || args[1] != "document" || args[3] != "viking://resources/%" {
+		t.Errorf("expected args in clause order, got %v", args)
+	}
+
+	// The flat form is unchanged
+	clause, _ = buildFilterClause(&Filter{Op: "or", Conds: []FilterCondition{
+		{Op: "must", Field: "id", Value: "a"},
+		{Op: "must", Field: "id", Value: "b"},
+	}})
+	if clause != "id = ? OR id = ?" {
+		t.Errorf("expected a flat clause, got %q", clause)
+	}
+}
+
+func TestSQLiteStorage_QueryNestedFilter(t *testing.T) {
+	storage, err := NewSQLiteStorage(Config{DBPath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, c := range []Context{
+		{ID: "a", ContextType: "code", ActiveCount: 2},
+		{ID: "b", ContextType: "code", ActiveCount: 0},
+		{ID: "c", ContextType: "document", ActiveCount: 5},
+		{ID: "d", ContextType: "image", ActiveCount: 3},
+	} {
+		c.URI = "viking://test/" + c.ID
+		c.Type = ContextTypeFile
+		c.CreatedAt, c.UpdatedAt = now, now
+		if err := storage.CreateContext(ctx, &c); err != nil {
+			t.Fatalf("failed to create context: %v", err)
+		}
+	}
+
+	// (type=code OR type=document) AND active_count>0
+	filter := &Filter{Conds: []FilterCondition{
+		{Op: "or", Conds: []FilterCondition{
+			{Op: "must", Field: "context_type", Value: "code"},
+			{Op: "must", Field: "context_type", Value: "document"},
+		}},
+		{Op: "range", Field: "active_count", GT: 0},
+	}}
+	contexts, err := storage.QueryContexts(ctx, QueryOptions{Filter: filter, OrderBy: "id"})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(contexts) != 2 || contexts[0].ID != "a" || contexts[1].ID != "c" {
+		t.Errorf("expected contexts a and c, got %+v", contexts)
+	}
+
+	// An OR of ANDs
+	filter = &Filter{Op: "or", Conds: []FilterCondition{
+		{Op: "and", Conds: []FilterCondition{
+			{Op: "must", Field: "context_type", Value: "code"},
+			{Op: "range", Field: "active_count", LT: 1},
+		}},
+		{Op: "must", Field: "context_type", Value: "image"},
+	}}
+	contexts, err = storage.QueryContexts(ctx, QueryOptions{Filter: filter, OrderBy: "id"})
+	if err != nil {
+		t.Fatalf("failed to query contexts: %v", err)
+	}
+	if len(contexts) != 2 || contexts[0].ID != "b" || contexts[1].ID != "d" {
+		t.Errorf("expected contexts b and d, got %+v", contexts)
+	}
+
+	// A filter whose only group is empty matches everything
+	n, err := storage.CountContexts(ctx, QueryOptions{Filter: &Filter{Conds: []FilterCondition{{Op: "or"}}}})
+	if err != nil || n != 4 {
+		t.Errorf("expected 4 contexts for an empty group, got %d (%v)", n, err)
+	}
+}
+
 func TestSQLiteStorage_QueryContexts(t *testing.T) {
 	// Create temp file for test database
 	tmpFile, err := os.CreateTemp("", "test-*.db")
@@ -234,3 +491,266 @@ func TestSQLiteStorage_QueryContexts(t *testing.T) {
 		t.Errorf("expected 5 contexts, got %d", len(contexts))
 	}
 }
+
+func TestSQLiteStorage_TimeRoundTrip(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// A non-UTC zone and sub-microsecond precision exercise the format
+	created := time.Date(2026, 3, 14, 9, 26, 53, 589793238, time.FixedZone("CST", 8*3600))
+	updated := created.Add(90 * time.Minute)
+
+	assertTimes := func(kind string, gotCreated, gotUpdated time.Time) {
+		t.Helper()
+		if d := gotCreated.Sub(created); d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("%s: CreatedAt = %v; want %v", kind, gotCreated, created)
+		}
+		if gotUpdated.IsZero() {
+			return
+		}
+		if d := gotUpdated.Sub(updated); d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("%s: UpdatedAt = %v; want %v", kind, gotUpdated, updated)
+		}
+	}
+
+	c := &Context{ID: "ctx-1", URI: "viking://resources/time", Type: ContextTypeFile, CreatedAt: created, UpdatedAt: updated}
+	if err := s.CreateContext(ctx, c); err != nil {
+		t.Fatalf("CreateContext failed: %v", err)
+	}
+	gotContext, err := s.GetContext(ctx, c.ID)
+	if err != nil || gotContext == nil {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+	assertTimes("context", gotContext.CreatedAt, gotContext.UpdatedAt)
+
+	sess := &Session{ID: "sess-row-1", SessionID: "sess-1", CreatedAt: created, UpdatedAt: updated}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	gotSession, err := s.GetSession(ctx, sess.ID)
+	if err != nil || gotSession == nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	assertTimes("session", gotSession.CreatedAt, gotSession.UpdatedAt)
+
+	msg := &SessionMessage{ID: "msg-1", SessionID: "sess-1", Role: "user", Content: "hi", CreatedAt: created}
+	if err := s.CreateSessionMessage(ctx, msg); err != nil {
+		t.Fatalf("CreateSessionMessage failed: %v", err)
+	}
+	messages, err := s.GetSessionMessages(ctx, "sess-1")
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("GetSessionMessages failed: %v (%d messages)", err, len(messages))
+	}
+	assertTimes("session message", messages[0].CreatedAt, time.Time{})
+
+	m := &Memory{ID: "mem-1", SessionID: "sess-1", Content: "likes tea", CreatedAt: created, UpdatedAt: updated}
+	if err := s.CreateMemory(ctx, m); err != nil {
+		t.Fatalf("CreateMemory failed: %v", err)
+	}
+	gotMemory, err := s.GetMemory(ctx, m.ID)
+	if err != nil || gotMemory == nil {
+		t.Fatalf("GetMemory failed: %v", err)
+	}
+	assertTimes("memory", gotMemory.CreatedAt, gotMemory.UpdatedAt)
+
+	f := &File{ID: "file-1", URI: "viking://resources/time.txt", Name: "time.txt", CreatedAt: created, UpdatedAt: updated}
+	if err := s.CreateFile(ctx, f); err != nil {
+		t.Fatalf("CreateFile failed: %v", err)
+	}
+	gotFile, err := s.GetFile(ctx, f.ID)
+	if err != nil || gotFile == nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	assertTimes("file", gotFile.CreatedAt, gotFile.UpdatedAt)
+
+	u := &Usage{ID: "usage-1", SessionID: "sess-1", URI: c.URI, Type: "context", Timestamp: created}
+	if err := s.CreateUsage(ctx, u); err != nil {
+		t.Fatalf("CreateUsage failed: %v", err)
+	}
+	usage, err := s.QueryUsage(ctx, QueryOptions{})
+	if err != nil || len(usage) != 1 {
+		t.Fatalf("QueryUsage failed: %v (%d records)", err, len(usage))
+	}
+	assertTimes("usage", usage[0].Timestamp, time.Time{})
+
+	r := &RelationEntry{ID: "rel-1", URIs: `["viking://resources/time"]`, Reason: "test", CreatedAt: created}
+	if err := s.CreateRelation(ctx, r); err != nil {
+		t.Fatalf("CreateRelation failed: %v", err)
+	}
+	relations, err := s.QueryRelations(ctx, "viking://resources/time")
+	if err != nil || len(relations) != 1 {
+		t.Fatalf("QueryRelations failed: %v (%d relations)", err, len(relations))
+	}
+	assertTimes("relation", relations[0].CreatedAt, time.Time{})
+}
+
+func TestSQLiteStorage_MemoryProvenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	s, err := NewSQLiteStorage(Config{DBPath: path, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	// Simulate a database created before memories recorded their sources
+	for _, col := range []string{"source_message_ids", "evidence"} {
+		if _, err := s.db.Exec("ALTER TABLE memories DROP COLUMN " + col); err != nil {
+			t.Fatalf("failed to drop column: %v", err)
+		}
+	}
+	s.Close()
+
+	s, err = NewSQLiteStorage(Config{DBPath: path, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	m := &Memory{ID: "mem-1", Content: "likes tea", SourceMessageIDs: "msg-1,msg-3", Evidence: "I'll have tea", CreatedAt: now, UpdatedAt: now}
+	if err := s.CreateMemory(ctx, m); err != nil {
+		t.Fatalf("CreateMemory failed: %v", err)
+	}
+	got, err := s.GetMemory(ctx, m.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetMemory failed: %v", err)
+	}
+	if got.SourceMessageIDs != "msg-1,msg-3" || got.Evidence != "I'll have tea" {
+		t.Errorf("expected the memory's provenance, got %+v", got)
+	}
+
+	m.SourceMessageIDs = "msg-4"
+	if err := s.UpdateMemory(ctx, m); err != nil {
+		t.Fatalf("UpdateMemory failed: %v", err)
+	}
+	memories, err := s.QueryMemories(ctx, QueryOptions{})
+	if err != nil || len(memories) != 1 || memories[0].SourceMessageIDs != "msg-4" {
+		t.Errorf("expected the updated sources, got %+v, %v", memories, err)
+	}
+}
+
+func TestSQLiteStorage_Audit(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, action := range []string{"create", "update", "delete"} {
+		entry := &AuditEntry{
+			ID:         uuid.New().String(),
+			Actor:      "alice",
+			Action:     action,
+			EntityType: "context",
+			EntityID:   "ctx-1",
+			Diff:       `{"name":{"after":"Guide"}}`,
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := s.CreateAudit(ctx, entry); err != nil {
+			t.Fatalf("CreateAudit failed: %v", err)
+		}
+	}
+
+	entries, err := s.QueryAudit(ctx, QueryOptions{
+		Filter:    &Filter{Conds: []FilterCondition{{Op: "must", Field: "entity_id", Value: "ctx-1"}}},
+		OrderBy:   "timestamp",
+		OrderDesc: true,
+		Offset:    1,
+	})
+	if err != nil {
+		t.Fatalf("QueryAudit failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "update" || entries[1].Action != "create" {
+		t.Fatalf("expected update then create, got %+v", entries)
+	}
+	if got := entries[1]; got.Actor != "alice" || got.EntityType != "context" || got.Diff == "" || !got.Timestamp.Equal(base) {
+		t.Errorf("unexpected audit entry %+v", got)
+	}
+}
+
+func TestSQLiteStorage_AuditOrderWithinSecond(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// Trailing zeros of RFC3339Nano would sort .1 after .15 and the whole
+	// second after both
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, 100 * time.Millisecond, 150 * time.Millisecond, 150 * time.Millisecond}
+	for i, offset := range offsets {
+		entry := &AuditEntry{
+			ID:         fmt.Sprintf("e%d", i),
+			Actor:      "alice",
+			Action:     "update",
+			EntityType: "context",
+			EntityID:   "ctx-1",
+			Timestamp:  base.Add(offset),
+		}
+		if err := s.CreateAudit(ctx, entry); err != nil {
+			t.Fatalf("CreateAudit failed: %v", err)
+		}
+	}
+
+	entries, err := s.QueryAudit(ctx, QueryOptions{OrderBy: "timestamp", OrderDesc: true})
+	if err != nil {
+		t.Fatalf("QueryAudit failed: %v", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	if got := strings.Join(ids, ","); got != "e3,e2,e1,e0" {
+		t.Errorf("expected newest first with later writes first on ties, got %s", got)
+	}
+	if !entries[3].Timestamp.Equal(base) || !entries[0].Timestamp.Equal(base.Add(150*time.Millisecond)) {
+		t.Errorf("expected timestamps to round-trip, got %v and %v", entries[3].Timestamp, entries[0].Timestamp)
+	}
+}
+
+func TestSQLiteStorage_ContextOrderAcrossZones(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// A whole second must sort before its fractions, and a +08:00 time
+	// between the UTC times around it
+	base := time.Date(2026, 3, 1, 12, 0, 5, 0, time.UTC)
+	created := []time.Time{
+		base,
+		base.Add(300 * time.Millisecond),
+		base.Add(time.Hour).In(time.FixedZone("CST", 8*3600)),
+		base.Add(2 * time.Hour),
+	}
+	for _, i := range []int{3, 1, 2, 0} {
+		c := &Context{
+			ID:        fmt.Sprintf("ctx-%d", i),
+			URI:       fmt.Sprintf("viking://resources/order/%d", i),
+			Type:      ContextTypeFile,
+			CreatedAt: created[i],
+			UpdatedAt: created[i],
+		}
+		if err := s.CreateContext(ctx, c); err != nil {
+			t.Fatalf("CreateContext failed: %v", err)
+		}
+	}
+
+	contexts, err := s.QueryContexts(ctx, QueryOptions{OrderBy: "created_at"})
+	if err != nil {
+		t.Fatalf("QueryContexts failed: %v", err)
+	}
+	var ids []string
+	for _, c := range contexts {
+		ids = append(ids, c.ID)
+	}
+	if got := strings.Join(ids, ","); got != "ctx-0,ctx-1,ctx-2,ctx-3" {
+		t.Errorf("expected contexts in creation order, got %s", got)
+	}
+}
+
+func TestParseTimeLegacyFormats(t *testing.T) {
+	want := time.Date(2026, 3, 14, 9, 26, 53, 500000000, time.UTC)
+	for _, s := range []string{
+		"2026-03-14T09:26:53.5Z",
+		"2026-03-14 09:26:53.5+00:00",
+		"2026-03-14 09:26:53.5 +0000 UTC",
+	} {
+		if got := parseTime(s); !got.Equal(want) {
+			t.Errorf("parseTime(%q) = %v; want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifies spans emitted by this package.
+const tracerName = "github.com/jqnote/goviking/pkg/storage"
+
+// Span attribute keys.
+const (
+	AttrOperation   = attribute.Key("storage.operation")
+	AttrID          = attribute.Key("storage.id")
+	AttrURI         = attribute.Key("storage.uri")
+	AttrResultCount = attribute.Key("storage.result_count")
+	AttrResultURIs  = attribute.Key("storage.result_uris")
+)
+
+// TracingStorage wraps a StorageInterface and emits a span for each query
+// method. Write methods pass straight through to the wrapped storage.
+type TracingStorage struct {
+	StorageInterface
+	tracer trace.Tracer
+}
+
+// NewTracingStorage creates a TracingStorage. A nil provider records
+// nothing.
+func NewTracingStorage(inner StorageInterface, tp trace.TracerProvider) *TracingStorage {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return &TracingStorage{
+		StorageInterface: inner,
+		tracer:           tp.Tracer(tracerName),
+	}
+}
+
+// start begins a span named "storage.<op>".
+func (t *TracingStorage) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, AttrOperation.String(op))
+	return t.tracer.Start(ctx, "storage."+op, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the result count and any error on span, then ends it.
+func endSpan(span trace.Span, count int, err error) {
+	span.SetAttributes(AttrResultCount.Int(count))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// found returns 1 if v is non-nil, for result counts of Get methods.
+func found[T any](v *T) int {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// GetContext implements StorageInterface.
+func (t *TracingStorage) GetContext(ctx context.Context, id string) (*Context, error) {
+	ctx, span := t.start(ctx, "GetContext", AttrID.String(id))
+	c, err := t.StorageInterface.GetContext(ctx, id)
+	if c != nil {
+		span.SetAttributes(AttrResultURIs.StringSlice([]string{c.URI}))
+	}
+	endSpan(span, found(c), err)
+	return c, err
+}
+
+// ContextExists implements StorageInterface.
+func (t *TracingStorage) ContextExists(ctx context.Context, id string) (bool, error) {
+	ctx, span := t.start(ctx, "ContextExists", AttrID.String(id))
+	exists, err := t.StorageInterface.ContextExists(ctx, id)
+	count := 0
+	if exists {
+		count = 1
+	}
+	endSpan(span, count, err)
+	return exists, err
+}
+
+// QueryContexts implements StorageInterface.
+func (t *TracingStorage) QueryContexts(ctx context.Context, opts QueryOptions) ([]Context, error) {
+	ctx, span := t.start(ctx, "QueryContexts")
+	contexts, err := t.StorageInterface.QueryContexts(ctx, opts)
+	uris := make([]string, len(contexts))
+	for i, c := range contexts {
+		uris[i] = c.URI
+	}
+	span.SetAttributes(AttrResultURIs.StringSlice(uris))
+	endSpan(span, len(contexts), err)
+	return contexts, err
+}
+
+// CountContexts implements StorageInterface.
+func (t *TracingStorage) CountContexts(ctx context.Context, opts QueryOptions) (int, error) {
+	ctx, span := t.start(ctx, "CountContexts")
+	n, err := t.StorageInterface.CountContexts(ctx, opts)
+	endSpan(span, n, err)
+	return n, err
+}
+
+// GetSession implements StorageInterface.
+func (t *TracingStorage) GetSession(ctx context.Context, id string) (*Session, error) {
+	ctx, span := t.start(ctx, "GetSession", AttrID.String(id))
+	session, err := t.StorageInterface.GetSession(ctx, id)
+	endSpan(span, found(session), err)
+	return session, err
+}
+
+// QuerySessions implements StorageInterface.
+func (t *TracingStorage) QuerySessions(ctx context.Context, opts QueryOptions) ([]Session, error) {
+	ctx, span := t.start(ctx, "QuerySessions")
+	sessions, err := t.StorageInterface.QuerySessions(ctx, opts)
+	endSpan(span, len(sessions), err)
+	return sessions, err
+}
+
+// CountSessions implements StorageInterface.
+func (t *TracingStorage) CountSessions(ctx context.Context, opts QueryOptions) (int, error) {
+	ctx, span := t.start(ctx, "CountSessions")
+	n, err := t.StorageInterface.CountSessions(ctx, opts)
+	endSpan(span, n, err)
+	return n, err
+}
+
+// GetSessionMessages implements StorageInterface.
+func (t *TracingStorage) GetSessionMessages(ctx context.Context, sessionID string) ([]SessionMessage, error) {
+	ctx, span := t.start(ctx, "GetSessionMessages", AttrID.String(sessionID))
+	messages, err := t.StorageInterface.GetSessionMessages(ctx, sessionID)
+	endSpan(span, len(messages), err)
+	return messages, err
+}
+
+// GetMemory implements StorageInterface.
+func (t *TracingStorage) GetMemory(ctx context.Context, id string) (*Memory, error) {
+	ctx, span := t.start(ctx, "GetMemory", AttrID.String(id))
+	memory, err := t.StorageInterface.GetMemory(ctx, id)
+	endSpan(span, found(memory), err)
+	return memory, err
+}
+
+// QueryMemories implements StorageInterface.
+func (t *TracingStorage) QueryMemories(ctx context.Context, opts QueryOptions) ([]Memory, error) {
+	ctx, span := t.start(ctx, "QueryMemories")
+	memories, err := t.StorageInterface.QueryMemories(ctx, opts)
+	endSpan(span, len(memories), err)
+	return memories, err
+}
+
+// CountMemories implements StorageInterface.
+func (t *TracingStorage) CountMemories(ctx context.Context, opts QueryOptions) (int, error) {
+	ctx, span := t.start(ctx, "CountMemories")
+	n, err := t.StorageInterface.CountMemories(ctx, opts)
+	endSpan(span, n, err)
+	return n, err
+}
+
+// GetFile implements StorageInterface.
+func (t *TracingStorage) GetFile(ctx context.Context, id string) (*File, error) {
+	ctx, span := t.start(ctx, "GetFile", AttrID.String(id))
+	file, err := t.StorageInterface.GetFile(ctx, id)
+	if file != nil {
+		span.SetAttributes(AttrResultURIs.StringSlice([]string{file.URI}))
+	}
+	endSpan(span, found(file), err)
+	return file, err
+}
+
+// QueryFiles implements StorageInterface.
+func (t *TracingStorage) QueryFiles(ctx context.Context, opts QueryOptions) ([]File, error) {
+	ctx, span := t.start(ctx, "QueryFiles")
+	files, err := t.StorageInterface.QueryFiles(ctx, opts)
+	uris := make([]string, len(files))
+	for i, f := range files {
+		uris[i] = f.URI
+	}
+	span.SetAttributes(AttrResultURIs.StringSlice(uris))
+	endSpan(span, len(files), err)
+	return files, err
+}
+
+// QueryUsage implements StorageInterface.
+func (t *TracingStorage) QueryUsage(ctx context.Context, opts QueryOptions) ([]Usage, error) {
+	ctx, span := t.start(ctx, "QueryUsage")
+	usage, err := t.StorageInterface.QueryUsage(ctx, opts)
+	endSpan(span, len(usage), err)
+	return usage, err
+}
+
+// QueryAudit implements StorageInterface.
+func (t *TracingStorage) QueryAudit(ctx context.Context, opts QueryOptions) ([]AuditEntry, error) {
+	ctx, span := t.start(ctx, "QueryAudit")
+	entries, err := t.StorageInterface.QueryAudit(ctx, opts)
+	endSpan(span, len(entries), err)
+	return entries, err
+}
+
+// QueryRelations implements StorageInterface.
+func (t *TracingStorage) QueryRelations(ctx context.Context, uri string) ([]RelationEntry, error) {
+	ctx, span := t.start(ctx, "QueryRelations", AttrURI.String(uri))
+	relations, err := t.StorageInterface.QueryRelations(ctx, uri)
+	endSpan(span, len(relations), err)
+	return relations, err
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build sqlite3
+// +build sqlite3
+
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingStorageEmitsSpans(t *testing.T) {
+	inner := newTestStorage(t)
+	seedTestStorage(t, inner, 2, 1, 0)
+
+	recorder := tracetest.NewSpanRecorder()
+	store := NewTracingStorage(inner, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+	if _, err := store.QueryContexts(ctx, QueryOptions{OrderBy: "id"}); err != nil {
+		t.Fatalf("QueryContexts failed: %v", err)
+	}
+	if _, err := store.GetMemory(ctx, "mem-0"); err != nil {
+		t.Fatalf("GetMemory failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	attrs := func(i int) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range spans[i].Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	if spans[0].Name() != "storage.QueryContexts" {
+		t.Errorf("Expected storage.QueryContexts, got %s", spans[0].Name())
+	}
+	query := attrs(0)
+	if query[AttrResultCount].AsInt64() != 2 {
+		t.Errorf("Expected result count 2, got %d", query[AttrResultCount].AsInt64())
+	}
+	wantURIs := []string{"viking://resources/doc0", "viking://resources/doc1"}
+	if got := query[AttrResultURIs].AsStringSlice(); !reflect.DeepEqual(got, wantURIs) {
+		t.Errorf("Expected URIs %v, got %v", wantURIs, got)
+	}
+
+	if spans[1].Name() != "storage.GetMemory" {
+		t.Errorf("Expected storage.GetMemory, got %s", spans[1].Name())
+	}
+	get := attrs(1)
+	if get[AttrID].AsString() != "mem-0" || get[AttrResultCount].AsInt64() != 1 {
+		t.Errorf("Expected id mem-0 with 1 result, got %v", get)
+	}
+}
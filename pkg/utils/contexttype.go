@@ -0,0 +1,47 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Context type names shared by the core, retrieval and storage packages.
+// Each package declares its own typed constants from these values.
+const (
+	ContextTypeMemory   = "memory"
+	ContextTypeResource = "resource"
+	ContextTypeSkill    = "skill"
+)
+
+// ErrInvalidContextType is returned when a context type is not one of the
+// known names.
+var ErrInvalidContextType = errors.New("invalid context type")
+
+// ContextTypes returns the known context type names.
+func ContextTypes() []string {
+	return []string{ContextTypeMemory, ContextTypeResource, ContextTypeSkill}
+}
+
+// IsValidContextType reports whether s is a known context type name. Names
+// are lowercase; use ParseContextType to accept other spellings.
+func IsValidContextType(s string) bool {
+	switch s {
+	case ContextTypeMemory, ContextTypeResource, ContextTypeSkill:
+		return true
+	}
+	return false
+}
+
+// ParseContextType returns the canonical name for s, ignoring case and
+// surrounding space, or ErrInvalidContextType if it is unknown.
+func ParseContextType(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if !IsValidContextType(name) {
+		return "", fmt.Errorf("%w: %q (want one of %s)", ErrInvalidContextType, s, strings.Join(ContextTypes(), ", "))
+	}
+	return name, nil
+}
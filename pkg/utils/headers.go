@@ -0,0 +1,17 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+// HTTP headers shared by the server and the client SDK.
+const (
+	// HeaderUser and HeaderSession carry the default user and session a
+	// client identifies itself with. Both are asserted by the caller and
+	// not authenticated.
+	HeaderUser    = "X-User-ID"
+	HeaderSession = "X-Session-ID"
+
+	// HeaderTotalCount carries the number of matching items of a list
+	// response, regardless of limit and offset.
+	HeaderTotalCount = "X-Total-Count"
+)
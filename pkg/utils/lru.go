@@ -0,0 +1,87 @@
+// Copyright (c) 2026 Beijing Volcano Engine Technology Co., Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"container/list"
+	"time"
+)
+
+// lruEntry is a cached value and when it expires.
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// LRU holds up to a capacity of values by key, evicting the least recently
+// used beyond it. Values expire at the time they were put with; a zero
+// time never expires. It is not safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	capacity int
+	entries  map[K]*list.Element
+	order    *list.List // most recently used first
+}
+
+// NewLRU creates an empty LRU holding up to capacity values. A capacity of
+// zero or less holds nothing.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		entries:  make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value under key unless it has expired by now, marking
+// it most recently used. Expired values are dropped.
+func (c *LRU[K, V]) Get(key K, now time.Time) (V, bool) {
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			return entry.value, true
+		}
+		c.remove(elem)
+	}
+	var zero V
+	return zero, false
+}
+
+// Put stores value under key until expires, evicting the least recently
+// used values beyond the capacity.
+func (c *LRU[K, V]) Put(key K, value V, expires time.Time) {
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Remove drops the value under key, if any.
+func (c *LRU[K, V]) Remove(key K) {
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of values, including expired ones not yet
+// dropped.
+func (c *LRU[K, V]) Len() int {
+	return c.order.Len()
+}
+
+// Clear drops every value.
+func (c *LRU[K, V]) Clear() {
+	c.entries = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+// remove drops a cached value.
+func (c *LRU[K, V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[K, V]).key)
+}
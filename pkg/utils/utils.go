@@ -7,6 +7,9 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -47,6 +50,19 @@ func ParseTime(s string) (time.Time, error) {
 	return time.Parse(time.RFC3339, s)
 }
 
+// ParseDuration parses a duration like time.ParseDuration and additionally
+// accepts whole days with a "d" suffix, e.g. "90d".
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
 // TruncateString truncates a string to max length.
 func TruncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -97,3 +113,21 @@ func Unique(slice []string) []string {
 	}
 	return result
 }
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 when
+// their lengths differ or either is empty or zero.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
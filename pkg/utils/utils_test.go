@@ -4,7 +4,10 @@
 package utils
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateID(t *testing.T) {
@@ -40,6 +43,35 @@ func TestNowMillis(t *testing.T) {
 	}
 }
 
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"0d", 0},
+		{"36h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		result, err := ParseDuration(tt.input)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("ParseDuration(%q) = %v; want %v", tt.input, result, tt.expected)
+		}
+	}
+
+	for _, input := range []string{"d", "-1d", "1.5d", "soon"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", input)
+		}
+	}
+}
+
 func TestTruncateString(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -102,3 +134,85 @@ func TestUnique(t *testing.T) {
 		t.Errorf("Expected 3 unique items, got %d", len(result))
 	}
 }
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if !clock.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, clock.Now())
+	}
+
+	clock.Advance(time.Hour)
+	if want := start.Add(time.Hour); !clock.Now().Equal(want) {
+		t.Errorf("Expected %v after Advance, got %v", want, clock.Now())
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("Expected %v after Set, got %v", start, clock.Now())
+	}
+}
+
+func TestParseContextType(t *testing.T) {
+	for _, name := range ContextTypes() {
+		if !IsValidContextType(name) {
+			t.Errorf("Expected %q to be valid", name)
+		}
+		got, err := ParseContextType(strings.ToUpper(name))
+		if err != nil || got != name {
+			t.Errorf("Expected %q, got %q, %v", name, got, err)
+		}
+	}
+
+	if IsValidContextType("Memory") {
+		t.Error("Expected non-canonical spelling to be invalid")
+	}
+	for _, name := range []string{"", "document", "memories"} {
+		if _, err := ParseContextType(name); !errors.Is(err, ErrInvalidContextType) {
+			t.Errorf("Expected ErrInvalidContextType for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU[string, int](2)
+
+	c.Put("a", 1, time.Time{})
+	c.Put("b", 2, now.Add(time.Minute))
+	if _, ok := c.Get("a", now); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	c.Put("c", 3, time.Time{})
+	if _, ok := c.Get("b", now); ok {
+		t.Error("Expected the least recently used b to be evicted")
+	}
+	if v, ok := c.Get("a", now.Add(time.Hour)); !ok || v != 1 {
+		t.Errorf("Expected a zero expiry never to expire, got %d, %v", v, ok)
+	}
+
+	c.Put("b", 2, now.Add(time.Minute))
+	if _, ok := c.Get("b", now.Add(time.Minute)); ok {
+		t.Error("Expected b to expire")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected the expired value to be dropped, got %d values", c.Len())
+	}
+
+	c.Remove("a")
+	if _, ok := c.Get("a", now); ok {
+		t.Error("Expected a to be removed")
+	}
+	c.Put("d", 4, time.Time{})
+	c.Clear()
+	if c.Len() != 0 {
+		t.Errorf("Expected an empty cache after Clear, got %d values", c.Len())
+	}
+
+	empty := NewLRU[string, int](0)
+	empty.Put("a", 1, time.Time{})
+	if empty.Len() != 0 {
+		t.Error("Expected a zero capacity to hold nothing")
+	}
+}